@@ -0,0 +1,76 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"aggregator_db/internal/config"
+	"aggregator_db/internal/devmode"
+	"aggregator_db/internal/exchange"
+	httpHandler "aggregator_db/internal/handler/http"
+	"aggregator_db/internal/ratelimit"
+	"aggregator_db/internal/repository/memory"
+	"aggregator_db/internal/service"
+	"aggregator_db/pkg/domain"
+	"aggregator_db/pkg/events"
+	"aggregator_db/pkg/mailer"
+)
+
+// newDemoServices собирает сервисы API поверх репозиториев в памяти и заполняет
+// их демо-данными devmode.Seed: сервер не подключается ни к одной базе, а данные
+// живут до его остановки. Курсы валют - только статические (EXCHANGE_STATIC_RATES),
+// письма пишутся в лог, фоновые задачи и ручки, которым нужен Postgres (журнал
+// аудита, лента изменений, переходы схемы), не включаются.
+func newDemoServices(ctx context.Context, cfg *config.Config, meta httpHandler.Services, logger *slog.Logger) (httpHandler.Services, error) {
+	staticRates, err := exchange.ParseRates(cfg.Exchange.StaticRates)
+	if err != nil {
+		return httpHandler.Services{}, err
+	}
+	rates := exchange.NewPinnedProvider(exchange.NewStaticProvider(domain.DefaultCurrency, staticRates))
+
+	repo := memory.NewSubscriptionRepository()
+	if err := devmode.Seed(ctx, repo, logger); err != nil {
+		return httpHandler.Services{}, err
+	}
+	users := memory.NewUserRepository(repo)
+	tenantRepo := memory.NewTenantRepository()
+	publisher := events.NewValidatingPublisher(events.NewLogPublisher(logger), meta.EventSchemas)
+
+	subscriptions := service.NewSubscriptionService(repo, memory.NewTransactor(), memory.NewServiceAliasRepository(), publisher, rates, logger)
+	notificationSettings := memory.NewNotificationSettingsRepository()
+	notifications := service.NewNotificationService(repo, notificationSettings, publisher,
+		mailer.NewLogSender(logger), cfg.Notifications.SpendAlertThresholdPercent, logger)
+	alerts := service.NewAlertService(memory.NewAlertRepository(), notificationSettings, users, notifications, logger)
+	budgets := service.NewBudgetService(memory.NewBudgetRepository(), users, subscriptions, publisher, alerts, logger)
+	tenants := service.NewTenantService(tenantRepo, memory.NewTenantProvisioner(), repo, logger)
+	usage := service.NewUsageService(memory.NewUsageRepository())
+	limiter := ratelimit.NewLimiter(time.Minute)
+
+	services := meta
+	services.Subscriptions = subscriptions
+	services.Notifications = notifications
+	services.Users = service.NewUserService(users, nil, logger)
+	services.Budgets = budgets
+	services.Alerts = alerts
+	services.Duplicates = service.NewDuplicateService(memory.NewDuplicateRepository(), repo, users, logger)
+	services.Nudges = service.NewNudgeService(memory.NewNudgeRepository(), repo, tenantRepo, domain.NudgeRules{Enabled: domain.NudgeKinds}, logger)
+	services.DataRepair = service.NewDataRepairService(memory.NewDataRepairRepository(repo), nil, domain.DataFixes{}, cfg.DataRepair.BatchSize, logger)
+	services.NotificationPreview = service.NewNotificationPreviewService(users, notifications, budgets)
+	services.Tenants = tenants
+	services.Usage = usage
+	exportJobs := memory.NewExportJobRepository()
+	services.Statements = service.NewStatementService(repo, notificationSettings, users, memory.NewStatementClosingRepository(),
+		exportJobs, tenantRepo, notifications, logger)
+	services.Exports = service.NewExportService(exportJobs, usage, notifications, services.Statements,
+		service.ExportOptions{
+			PublicURL:          cfg.Exports.PublicURL,
+			LinkTTL:            cfg.Exports.LinkTTL,
+			MaxAttachmentBytes: cfg.Exports.MaxAttachmentBytes,
+		}, logger)
+	services.Developer = service.NewDeveloperService(memory.NewDeveloperAppRepository(), usage, limiter, cfg.Developer.RateLimitPerMinute, logger)
+	services.Limiter = limiter
+	services.Limits = service.NewLimitsService(limiter, tenants, cfg.ServiceKeyRateLimitPerMinute)
+	services.APIKeys = service.NewAPIKeyService(memory.NewAPIKeyRepository(), logger)
+	return services, nil
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"aggregator_db/internal/config"
+	"aggregator_db/internal/devmode"
+	httpHandler "aggregator_db/internal/handler/http"
+	"aggregator_db/pkg/domain"
+	"github.com/gin-gonic/gin"
+)
+
+func TestDemoServices(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	cfg := &config.Config{}
+
+	services, err := newDemoServices(context.Background(), cfg, httpHandler.Services{}, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	router := httpHandler.SetupRouter(cfg, services, logger)
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/subscriptions/calculate?start_period=01-2025&end_period=12-2025&user_ids="+
+		devmode.DemoUserID.String()+","+devmode.DemoUser2ID.String(), nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", rec.Code, rec.Body.String())
+	}
+	var total domain.CalculateTotalResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &total); err != nil {
+		t.Fatal(err)
+	}
+	if len(total.ByUser) != 2 || total.ByUser[0].TotalCost.IsZero() || total.ByUser[1].TotalCost.IsZero() {
+		t.Errorf("demo data is not seeded: %s", rec.Body.String())
+	}
+}
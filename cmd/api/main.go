@@ -1,23 +1,59 @@
 package main
 
 import (
+	"cmp"
 	"context"
+	"database/sql"
+	"flag"
 	"fmt"
 	"log"
+	"log/slog"
 	"net/http"
 	"os"
 	"os/signal"
+	"slices"
+	"strconv"
 	"syscall"
 	"time"
 
+	"aggregator_db/internal/changefeed"
+	"aggregator_db/internal/changelog"
+	"aggregator_db/internal/clients"
+	"aggregator_db/internal/clock"
 	"aggregator_db/internal/config"
+	"aggregator_db/internal/devmode"
+	"aggregator_db/internal/diagnostics"
+	"aggregator_db/internal/exchange"
+	"aggregator_db/internal/experiment"
 	httpHandler "aggregator_db/internal/handler/http"
+	"aggregator_db/internal/metering"
+	"aggregator_db/internal/middleware"
+	"aggregator_db/internal/migrator"
+	"aggregator_db/internal/ratelimit"
+	"aggregator_db/internal/repository/cached"
+	"aggregator_db/internal/repository/instrumented"
+	"aggregator_db/internal/repository/mysql"
 	"aggregator_db/internal/repository/postgres"
+	"aggregator_db/internal/retryafter"
+	"aggregator_db/internal/scheduler"
 	"aggregator_db/internal/service"
+	"aggregator_db/internal/shadow"
+	"aggregator_db/internal/slo"
+	"aggregator_db/internal/writequeue"
+	"aggregator_db/pkg/domain"
+	"aggregator_db/pkg/errortracker"
+	"aggregator_db/pkg/events"
+	"aggregator_db/pkg/httpclient"
 	"aggregator_db/pkg/logger"
+	"aggregator_db/pkg/mailer"
+	"aggregator_db/pkg/metrics"
+	"aggregator_db/pkg/region"
+	"aggregator_db/pkg/store"
+	"aggregator_db/pkg/tlscert"
+	"aggregator_db/pkg/tracing"
 	"github.com/jackc/pgx/v5/pgxpool"
 
-	_ "aggregator_db/docs"
+	"aggregator_db/docs"
 )
 
 // @title           Subscription Service API
@@ -36,6 +72,10 @@ import (
 
 // @schemes http https
 func main() {
+	devMode := flag.Bool("dev", false, "локальный режим: встроенный Postgres, автомиграции и демо-данные")
+	demoMode := flag.Bool("demo", false, "демо-режим без баз: хранилища в памяти с демо-данными")
+	flag.Parse()
+
 	// Загрузка конфигурации
 	cfg, err := config.Load()
 	if err != nil {
@@ -44,12 +84,115 @@ func main() {
 
 	// Инициализация логгера
 	appLogger := logger.New(cfg.LogLevel)
+	// migrate up | down [N] | status - работа со схемой без запуска сервера
+	if flag.Arg(0) == "migrate" {
+		if err := runMigrate(context.Background(), cfg, flag.Args()[1:], appLogger); err != nil {
+			appLogger.Error("Migration failed", "error", err.Error())
+			os.Exit(1)
+		}
+		return
+	}
+	// Регион помечает записи, события, метрики и логи этого развертывания
+	if cfg.Region.Name != "" {
+		region.Set(cfg.Region.Name)
+		appLogger = appLogger.With("region", cfg.Region.Name)
+		metrics.SetConstLabels("region", cfg.Region.Name)
+	}
+	// Трекер получает логгер без своего обработчика, иначе неудачная отправка
+	// отчета сама станет отчетом
+	var errorTracker *errortracker.Client
+	if cfg.Errors.DSN != "" {
+		errorTracker, err = errortracker.New(errortracker.Config{
+			DSN:         cfg.Errors.DSN,
+			Environment: cfg.Errors.Environment,
+			Release:     cfg.Errors.Release,
+		}, httpclient.New(httpclient.DefaultConfig("error_tracker"), appLogger), appLogger)
+		if err != nil {
+			log.Fatalf("Failed to configure error tracker: %v", err)
+		}
+		appLogger = slog.New(errortracker.NewLogHandler(appLogger.Handler(), errorTracker))
+	}
+	conflictResolver, err := region.ResolverFor(cfg.Region.ConflictPolicy)
+	if err != nil {
+		log.Fatalf("Failed to configure region: %v", err)
+	}
 	appLogger.Info("Starting subscription service",
 		"port", cfg.ServerPort,
 	)
 
+	// Замороженное или сдвинутое время - только для тестов и staging
+	processClock, err := clock.Parse(cfg.Clock.Time, clock.System{})
+	if err != nil {
+		log.Fatalf("Failed to configure clock: %v", err)
+	}
+	clock.Set(processClock)
+	if cfg.Clock.Time != "" || cfg.Clock.HeaderEnabled {
+		appLogger.Warn("Time travel enabled", "clock", cfg.Clock.Time, "header", cfg.Clock.HeaderEnabled, "now", clock.Now(context.Background()))
+	}
+	tracing.SetExporter(tracing.NewLogExporter(appLogger))
+
+	eventSchemas, err := events.LoadRegistry()
+	if err != nil {
+		appLogger.Error("Failed to load event schemas", "error", err.Error())
+		os.Exit(1)
+	}
+	apiChangelog, err := changelog.Load()
+	if err != nil {
+		appLogger.Error("Failed to load API changelog", "error", err.Error())
+		os.Exit(1)
+	}
+	clientCatalog, err := clients.NewCatalog([]byte(docs.SwaggerInfo.ReadDoc()), apiChangelog.CurrentVersion(), cfg.ClientsDir)
+	if err != nil {
+		appLogger.Error("Failed to load API clients", "error", err.Error())
+		os.Exit(1)
+	}
+	configuredDeprecations, err := domain.ParseDeprecations(cfg.Deprecation.Surfaces)
+	if err != nil {
+		appLogger.Error("Failed to configure deprecations", "error", err.Error())
+		os.Exit(1)
+	}
+	// Настройка идет после журнала и переопределяет даты его поверхностей
+	deprecations := slices.Concat(apiChangelog.Deprecations(), configuredDeprecations)
+
+	// Свои валюты регистрируются до разбора курсов: без этого их коды считаются неизвестными
+	if err := domain.RegisterCurrencies(cfg.Exchange.CustomCurrencies); err != nil {
+		appLogger.Error("Failed to register custom currencies", "error", err.Error())
+		os.Exit(1)
+	}
+
+	// Демо-режим не подключается к базам: данные в памяти и пропадают при остановке
+	if *demoMode {
+		if *devMode {
+			appLogger.Error("Flags -dev and -demo are mutually exclusive")
+			os.Exit(1)
+		}
+		services, err := newDemoServices(context.Background(), cfg, httpHandler.Services{
+			EventSchemas: eventSchemas,
+			Changelog:    apiChangelog,
+			Clients:      clientCatalog,
+			Deprecations: deprecations,
+		}, appLogger)
+		if err != nil {
+			appLogger.Error("Failed to start demo mode", "error", err.Error())
+			os.Exit(1)
+		}
+		appLogger.Warn("Demo mode enabled, data is kept in memory")
+		serve(cfg, httpHandler.SetupRouter(cfg, services, appLogger), appLogger)
+		appLogger.Info("Server exited")
+		return
+	}
+
+	if *devMode {
+		stopDB, err := devmode.StartDatabase(context.Background(), cfg, appLogger)
+		if err != nil {
+			appLogger.Error("Failed to start dev database", "error", err.Error())
+			os.Exit(1)
+		}
+		defer stopDB()
+	}
+
 	// Подключение к БД
-	dbPool, err := pgxpool.New(context.Background(), cfg.DSN())
+	dbPool, err := newDBPool(cfg, cfg.DSN(), appLogger)
 	if err != nil {
 		appLogger.Error("Failed to connect to database", "error", err.Error())
 		os.Exit(1)
@@ -63,40 +206,682 @@ func main() {
 	}
 	appLogger.Info("Successfully connected to database")
 
+	// Миграции идут под advisory lock, поэтому реплики могут стартовать одновременно
+	migrations := migrator.Source(cfg.MigrationsDir)
+	if *devMode || cfg.MigrateOnStart {
+		if err := migrator.Migrate(context.Background(), dbPool, migrations, appLogger); err != nil {
+			appLogger.Error("Failed to migrate database", "error", err.Error())
+			os.Exit(1)
+		}
+	}
+
+	// Репозитории данных ходят через роутер тенантов, реестр тенантов - в базовый пул
+	tenantRouter := postgres.NewTenantRouter(dbPool, cfg.Tenancy.PoolMaxConns)
+	defer tenantRouter.Close()
+
+	// С репликой чтения, допускающие отставание, уходят в нее; схемы тенантов на
+	// реплике те же, что в основной базе
+	var dataDB postgres.DB = tenantRouter
+	var replicaPool *pgxpool.Pool
+	if cfg.DBConfig.ReplicaDSN != "" {
+		replicaPool, err = newDBPool(cfg, cfg.DBConfig.ReplicaDSN, appLogger)
+		if err != nil {
+			appLogger.Error("Failed to configure read replica", "error", err.Error())
+			os.Exit(1)
+		}
+		defer replicaPool.Close()
+		replicaRouter := postgres.NewTenantRouter(replicaPool, cfg.Tenancy.PoolMaxConns)
+		defer replicaRouter.Close()
+		dataDB = postgres.NewReplicaRouter(tenantRouter, replicaRouter, cfg.DBConfig.ReplicaRetryInterval, appLogger)
+		appLogger.Info("Read replica enabled")
+	}
+
+	// В отладочном режиме репозитории данных снимают планы запросов помеченных HTTP-запросов
+	var queryDiagnostics *diagnostics.Recorder
+	if cfg.DBConfig.ExplainMode != middleware.ExplainOff {
+		dataDB = postgres.NewExplainDB(dataDB, appLogger)
+		queryDiagnostics = diagnostics.NewRecorder(100)
+		appLogger.Warn("Query EXPLAIN capture enabled", "mode", cfg.DBConfig.ExplainMode)
+	}
+	// Репозитории данных участвуют в единицах работы сервисов (postgres.Transactor)
+	// Повторы после временных ошибок базы: вызовов репозитория подписок и единиц работы целиком
+	dbRetry := postgres.RetryPolicy{
+		MaxRetries: cfg.DBConfig.MaxRetries,
+		Backoff:    cfg.DBConfig.RetryBackoff,
+		MaxBackoff: cfg.DBConfig.RetryMaxBackoff,
+	}
+	unitOfWork := postgres.NewUnitOfWork(dataDB, dbRetry, appLogger)
+	dataDB = unitOfWork
+
 	// Инициализация слоев приложения
-	subscriptionRepo := postgres.NewSubscriptionRepository(dbPool)
-	subscriptionService := service.NewSubscriptionService(subscriptionRepo, appLogger)
+	// Подсказки Retry-After учитывают недоступность базы и открытый breaker провайдера курсов
+	retryPolicy := retryafter.NewPolicy(cfg.RetryAfter.Min, cfg.RetryAfter.Max, cfg.RetryAfter.MaxInFlight)
+	dbOutage := retryafter.NewOutage(cfg.RetryAfter.Min, cfg.RetryAfter.Max)
+	retryPolicy.Register("database", dbOutage)
+	// Фазы переходов схемы определяют, в какие колонки репозитории пишут даты и цену
+	schemaMigrations, err := domain.ParseSchemaMigrations(cfg.Migrations.Dates, cfg.Migrations.Money)
+	if err != nil {
+		appLogger.Error("Failed to configure schema migrations", "error", err.Error())
+		os.Exit(1)
+	}
+	fieldVisibility, err := domain.ParseFieldVisibility(cfg.FieldVisibility)
+	if err != nil {
+		appLogger.Error("Failed to configure field visibility", "error", err.Error())
+		os.Exit(1)
+	}
+	// Коммиты подписок будят ожидающих ленту изменений этой реплики
+	changeBus := changefeed.NewBus()
+	// Пока помесячные итоги - новая реализация в эксперименте, текущая считает без них
+	monthlyTotalsReads := cfg.MonthlyTotals.Enabled && cfg.Experiment.Candidate != "monthly_totals"
+	var subscriptionStore store.SubscriptionRepository = postgres.NewSubscriptionRepository(postgres.NotifyOnCommit(dataDB, changeBus.Notify), schemaMigrations, monthlyTotalsReads)
+	var subscriptionTx postgres.Transactor = unitOfWork
+	// С DB_DRIVER=mysql подписки с историей, скидками и ценами живут в MySQL, а
+	// единицы работы сервиса подписок - транзакции MySQL; остальное остается в Postgres
+	var mysqlDB *sql.DB
+	if cfg.DBConfig.Driver == "mysql" {
+		if schemaMigrations.Enabled() {
+			appLogger.Error("Schema migration dual write is not supported with DB_DRIVER=mysql")
+			os.Exit(1)
+		}
+		mysqlDB, err = openMySQL(context.Background(), cfg, *devMode || cfg.MigrateOnStart, appLogger)
+		if err != nil {
+			appLogger.Error("Failed to connect to MySQL", "error", err.Error())
+			os.Exit(1)
+		}
+		defer mysqlDB.Close()
+		mysqlUnit := mysql.NewUnitOfWork(mysqlDB, dbRetry, appLogger)
+		subscriptionStore = mysql.NewSubscriptionRepository(mysqlUnit)
+		subscriptionTx = mysqlUnit
+		appLogger.Info("Subscriptions are stored in MySQL")
+	}
+	// Реализации расчета в эксперименте читают подписки из Postgres
+	var calculateExperiment *experiment.Calculate
+	if cfg.Experiment.Candidate != "" && mysqlDB == nil {
+		calculateExperiment = experiment.NewCalculate(subscriptionStore,
+			postgres.NewSubscriptionRepository(dataDB, schemaMigrations, true),
+			experiment.Options{
+				Name:           cfg.Experiment.Candidate,
+				Percent:        cfg.Experiment.Percent,
+				ComparePercent: cfg.Experiment.ComparePercent,
+				Concurrency:    cfg.Experiment.Concurrency,
+				Timeout:        cfg.Experiment.Timeout,
+			}, appLogger)
+		subscriptionStore = calculateExperiment
+		appLogger.Info("Calculate experiment enabled",
+			"candidate", cfg.Experiment.Candidate,
+			"percent", cfg.Experiment.Percent,
+			"compare_percent", cfg.Experiment.ComparePercent,
+		)
+	}
+	// Кэш сбрасывается по коммиту единиц работы Postgres, поэтому для MySQL не включается
+	var subscriptionCache postgres.SubscriptionCache
+	if cfg.DBConfig.Driver != "mysql" && cfg.DBConfig.CalculateCacheTTL > 0 && cfg.DBConfig.CalculateCacheMaxEntries > 0 {
+		cache := cached.NewSubscriptionRepository(subscriptionStore, cached.Options{
+			TTL:        cfg.DBConfig.CalculateCacheTTL,
+			MaxEntries: cfg.DBConfig.CalculateCacheMaxEntries,
+		})
+		subscriptionStore, subscriptionCache = cache, cache
+	}
+	subscriptionRepo := instrumented.NewSubscriptionRepository(
+		subscriptionStore,
+		appLogger,
+		instrumented.Options{
+			SlowQueryThreshold: cfg.DBConfig.SlowQueryThreshold,
+			Retry:              dbRetry,
+			Outage:             dbOutage,
+		},
+	)
+	// Учет потребления API работает на каждой реплике и сбрасывается в public.usage_records
+	usageRepo := postgres.NewUsageRepository(dbPool)
+	meter := metering.NewMeter(usageRepo, appLogger)
+	meterCtx, stopMeter := context.WithCancel(context.Background())
+	meterDone := make(chan struct{})
+	go func() {
+		defer close(meterDone)
+		meter.Run(meterCtx, cfg.Metering.FlushInterval)
+	}()
+
+	eventPublisher := events.NewLogPublisher(appLogger)
+	var webhookClient *httpclient.Client
+	if cfg.Events.WebhookURL != "" {
+		webhookClient = httpclient.New(httpclient.DefaultConfig("events_webhook"), appLogger)
+		eventPublisher = metering.NewPublisher(
+			events.NewWebhookPublisher(webhookClient, cfg.Events.WebhookURL, cfg.Events.WebhookSecret),
+			meter,
+		)
+	}
+	// Событие, не прошедшее проверку схемой, не публикуется, а ошибка попадает в лог
+	eventPublisher = events.NewValidatingPublisher(eventPublisher, eventSchemas)
+	exchangeClient := httpclient.New(httpclient.DefaultConfig("exchange_rates"), appLogger)
+	retryPolicy.Register("exchange_rates", exchangeClient)
+	exchangeRates, err := newExchangeProvider(cfg.Exchange, exchangeClient, appLogger)
+	if err != nil {
+		appLogger.Error("Failed to configure exchange rates", "error", err.Error())
+		os.Exit(1)
+	}
+	subscriptionService := service.NewSubscriptionService(subscriptionRepo, subscriptionTx, postgres.NewServiceAliasRepository(dataDB), eventPublisher, exchangeRates, appLogger)
+
+	if *devMode {
+		if err := devmode.Seed(context.Background(), subscriptionRepo, appLogger); err != nil {
+			appLogger.Error("Failed to seed dev database", "error", err.Error())
+			os.Exit(1)
+		}
+	}
+
+	// Без SMTP письма только пишутся в лог
+	var mailSender mailer.Sender = mailer.NewLogSender(appLogger)
+	if cfg.Mail.SMTPHost != "" {
+		mailSender = mailer.NewSMTPSender(mailer.Config{
+			Host:     cfg.Mail.SMTPHost,
+			Port:     cfg.Mail.SMTPPort,
+			Username: cfg.Mail.Username,
+			Password: cfg.Mail.Password,
+			From:     cfg.Mail.From,
+		})
+	}
+
+	notificationSettingsRepo := postgres.NewNotificationSettingsRepository(dataDB)
+	notificationService := service.NewNotificationService(
+		subscriptionRepo,
+		notificationSettingsRepo,
+		eventPublisher,
+		mailSender,
+		cfg.Notifications.SpendAlertThresholdPercent,
+		appLogger,
+	)
+
+	tenantProvisioner := postgres.NewTenantProvisioner(tenantRouter, migrations, appLogger)
+	tenantRepo := postgres.NewTenantRepository(dbPool)
+	tenantService := service.NewTenantService(
+		tenantRepo,
+		tenantProvisioner,
+		subscriptionRepo,
+		appLogger,
+	)
+
+	// Очередь записей на время недоступности базы повторяется на каждой реплике независимо от планировщика
+	var writeQueueService *service.WriteQueueService
+	writeQueueCtx, stopWriteQueue := context.WithCancel(context.Background())
+	writeQueueDone := make(chan struct{})
+	if cfg.WriteQueue.Path != "" {
+		queue, err := writequeue.Open(cfg.WriteQueue.Path, cfg.WriteQueue.MaxEntries)
+		if err != nil {
+			appLogger.Error("Failed to open write queue", "error", err.Error())
+			os.Exit(1)
+		}
+		defer queue.Close()
+		writeQueueService = service.NewWriteQueueService(subscriptionService, queue, tenantService.Get, appLogger)
+		go func() {
+			defer close(writeQueueDone)
+			writeQueueService.Run(writeQueueCtx, cfg.WriteQueue.ReplayInterval)
+		}()
+	} else {
+		close(writeQueueDone)
+	}
+
+	// Без схемы песочницы не работают только запросы с ключами sandbox, поэтому сервис стартует
+	sandboxService := service.NewSandboxService(tenantProvisioner, subscriptionCache, appLogger)
+	if err := sandboxService.Prepare(context.Background()); err != nil {
+		appLogger.Error("Failed to prepare sandbox schema", "error", err.Error())
+	}
+
+	userRepo := postgres.NewUserRepository(dataDB)
+	alertService := service.NewAlertService(postgres.NewAlertRepository(dataDB), notificationSettingsRepo, userRepo, notificationService, appLogger)
+	budgetService := service.NewBudgetService(postgres.NewBudgetRepository(dataDB), userRepo, subscriptionService, eventPublisher, alertService, appLogger)
+	duplicateService := service.NewDuplicateService(postgres.NewDuplicateRepository(dataDB), subscriptionRepo, userRepo, appLogger)
+
+	nudgeKinds, err := domain.ParseNudgeKinds(cfg.Nudges.Rules)
+	if err != nil {
+		appLogger.Error("Failed to configure nudge rules", "error", err.Error())
+		os.Exit(1)
+	}
+	nudgeService := service.NewNudgeService(postgres.NewNudgeRepository(dbPool), subscriptionRepo, tenantRepo, domain.NudgeRules{
+		Enabled:               nudgeKinds,
+		ChurnMinCancellations: cfg.Nudges.ChurnMinCancellations,
+		ChurnRisePercent:      cfg.Nudges.ChurnRisePercent,
+		FutureStartMonths:     cfg.Nudges.FutureStartMonths,
+	}, appLogger)
+
+	var replicationService *service.ReplicationService
+	if cfg.Region.Name != "" {
+		replicationService = service.NewReplicationService(subscriptionRepo, conflictResolver, appLogger)
+	}
+
+	dataFixes, err := domain.ParseDataFixes(cfg.DataRepair.Fixes)
+	if err != nil {
+		appLogger.Error("Failed to configure data repair", "error", err.Error())
+		os.Exit(1)
+	}
+	// Проверки целостности читают таблицы подписок Postgres, в MySQL их нет
+	var dataRepairService *service.DataRepairService
+	if mysqlDB == nil {
+		dataRepairService = service.NewDataRepairService(postgres.NewDataRepairRepository(dataDB, schemaMigrations), subscriptionCache, dataFixes, cfg.DataRepair.BatchSize, appLogger)
+	}
+
+	var schemaMigrationService *service.SchemaMigrationService
+	if schemaMigrations.Enabled() {
+		schemaMigrationService = service.NewSchemaMigrationService(postgres.NewSchemaMigrationRepository(dataDB, schemaMigrations),
+			subscriptionCache, schemaMigrations, cfg.Migrations.BatchSize, appLogger)
+		appLogger.Info("Schema migration dual write enabled", "dates", schemaMigrations.Dates, "money", schemaMigrations.Money)
+	}
+
+	usageService := service.NewUsageService(usageRepo)
+	exportJobRepo := postgres.NewExportJobRepository(dbPool)
+	statementService := service.NewStatementService(subscriptionRepo, notificationSettingsRepo, userRepo,
+		postgres.NewStatementClosingRepository(dataDB), exportJobRepo, tenantRepo, notificationService, appLogger)
+	exportService := service.NewExportService(exportJobRepo, usageService, notificationService, statementService,
+		service.ExportOptions{
+			PublicURL:          cfg.Exports.PublicURL,
+			LinkTTL:            cfg.Exports.LinkTTL,
+			MaxAttachmentBytes: cfg.Exports.MaxAttachmentBytes,
+		}, appLogger)
+
+	// Запросы к целям считаются на каждой реплике, поэтому сгорание бюджета
+	// проверяется вне планировщика, который работает на одной
+	slos, err := domain.ParseSLOs(cfg.SLO.Objectives)
+	if err != nil {
+		appLogger.Error("Failed to configure SLO objectives", "error", err.Error())
+		os.Exit(1)
+	}
+	var sloService *service.SLOService
+	sloCtx, stopSLO := context.WithCancel(context.Background())
+	sloDone := make(chan struct{})
+	if len(slos) > 0 {
+		tracker := slo.NewTracker(slos, domain.DefaultBurnRateRules(cfg.SLO.FastBurnRate, cfg.SLO.SlowBurnRate))
+		sloService = service.NewSLOService(tracker, eventPublisher, appLogger)
+		go func() {
+			defer close(sloDone)
+			sloService.Run(sloCtx, cfg.SLO.CheckInterval)
+		}()
+	} else {
+		close(sloDone)
+	}
+
+	// Фоновые задачи
+	schedulerCtx, stopScheduler := context.WithCancel(context.Background())
+	schedulerDone := make(chan struct{})
+	if cfg.Scheduler.Enabled {
+		jobs := scheduler.New(appLogger)
+		jobs.Add(scheduler.Job{
+			Name:     "spend_comparison",
+			Interval: cfg.Scheduler.SpendComparisonInterval,
+			Run:      notificationService.CompareSpend,
+		})
+		jobs.Add(scheduler.Job{
+			Name:     "subscription_renewal",
+			Interval: cfg.Scheduler.RenewalInterval,
+			Run:      subscriptionService.RenewSubscriptions,
+		})
+		jobs.Add(scheduler.Job{
+			Name:     "budget_alerts",
+			Interval: cfg.Scheduler.BudgetCheckInterval,
+			Run:      budgetService.CheckBudgets,
+		})
+		jobs.Add(scheduler.Job{
+			Name:     "duplicate_scan",
+			Interval: cfg.Scheduler.DuplicateScanInterval,
+			Run:      duplicateService.Detect,
+		})
+		jobs.Add(scheduler.Job{
+			Name:     "admin_nudges",
+			Interval: cfg.Nudges.Interval,
+			Run:      nudgeService.Evaluate,
+		})
+		jobs.Add(scheduler.Job{
+			Name:     "sandbox_reset",
+			Interval: cfg.Sandbox.ResetInterval,
+			Run:      sandboxService.Reset,
+		})
+		jobs.Add(scheduler.Job{
+			Name:     "export_delivery",
+			Interval: cfg.Exports.DeliveryInterval,
+			Run:      exportService.Deliver,
+		})
+		jobs.Add(scheduler.Job{
+			Name:     "monthly_statements",
+			Interval: cfg.Scheduler.StatementInterval,
+			Run:      statementService.Close,
+		})
+		jobs.Add(scheduler.Job{
+			Name:     "alert_escalation",
+			Interval: cfg.Scheduler.AlertEscalationInterval,
+			Run:      alertService.Escalate,
+		})
+		if len(cfg.BI.URLs) > 0 {
+			biExport := newBIExportService(cfg.BI, subscriptionRepo, tenantRepo, eventSchemas, appLogger)
+			jobs.Add(scheduler.Job{
+				Name:     "bi_export",
+				Interval: cfg.BI.Interval,
+				Run:      biExport.Push,
+			})
+		}
+		// Итоги ведутся в Postgres; с DB_DRIVER=mysql подписки в нем не хранятся
+		if cfg.MonthlyTotals.Enabled && mysqlDB == nil {
+			monthlyTotals := service.NewMonthlyTotalsService(postgres.NewMonthlyTotalsRepository(dataDB), tenantRepo,
+				cfg.MonthlyTotals.BatchSize, appLogger)
+			jobs.Add(scheduler.Job{
+				Name:     "monthly_totals",
+				Interval: cfg.MonthlyTotals.Interval,
+				Run:      monthlyTotals.Refresh,
+			})
+		}
+		if schemaMigrationService != nil {
+			jobs.Add(scheduler.Job{
+				Name:     "schema_migration_verify",
+				Interval: cfg.Migrations.VerifyInterval,
+				Run:      schemaMigrationService.VerifyJob,
+			})
+		}
+		go func() {
+			defer close(schedulerDone)
+			jobs.Run(schedulerCtx)
+		}()
+	} else {
+		close(schedulerDone)
+	}
 
 	// Настройка роутера
-	router := httpHandler.SetupRouter(subscriptionService, appLogger)
+	auditRepo := postgres.NewAuditRepository(dataDB)
+	limiter := ratelimit.NewLimiter(time.Minute)
+	developerService := service.NewDeveloperService(postgres.NewDeveloperAppRepository(dbPool), usageService,
+		limiter, cfg.Developer.RateLimitPerMinute, appLogger)
+	// Лента изменений строится по журналу аудита Postgres, а репозиторий MySQL его не пишет
+	var changesService *service.ChangesService
+	if mysqlDB == nil {
+		changesService = service.NewChangesService(auditRepo, changeBus, cfg.Changes.MaxWait, cfg.Changes.PollInterval)
+	}
+	router := httpHandler.SetupRouter(cfg, httpHandler.Services{
+		SystemHealth:        newSystemHealthService(dbPool, writeQueueService, exportService, webhookClient),
+		Readiness:           newReadinessService(cfg, dbPool, replicaPool, mysqlDB, exchangeRates),
+		SLO:                 sloService,
+		ErrorTracker:        errorTracker,
+		Shadow:              newShadower(cfg.Shadow, appLogger),
+		CalculateExperiment: calculateExperiment,
+		Subscriptions:       subscriptionService,
+		Notifications:       notificationService,
+		Users:               service.NewUserService(userRepo, subscriptionCache, appLogger),
+		FieldVisibility:     fieldVisibility,
+		Budgets:             budgetService,
+		Duplicates:          duplicateService,
+		Nudges:              nudgeService,
+		DataRepair:          dataRepairService,
+		Audit:               service.NewAuditService(auditRepo),
+		Changes:             changesService,
+		SchemaMigration:     schemaMigrationService,
+		NotificationPreview: service.NewNotificationPreviewService(userRepo, notificationService, budgetService),
+		Diagnostics:         queryDiagnostics,
+		Replication:         replicationService,
+		Tenants:             tenantService,
+		Meter:               meter,
+		Usage:               usageService,
+		Exports:             exportService,
+		Statements:          statementService,
+		Alerts:              alertService,
+		Developer:           developerService,
+		Limiter:             limiter,
+		Limits:              service.NewLimitsService(limiter, tenantService, cfg.ServiceKeyRateLimitPerMinute),
+		APIKeys:             service.NewAPIKeyService(postgres.NewAPIKeyRepository(dbPool), appLogger),
+		WriteQueue:          writeQueueService,
+		RetryAfter:          retryPolicy,
+		EventSchemas:        eventSchemas,
+		Changelog:           apiChangelog,
+		Clients:             clientCatalog,
+		Deprecations:        deprecations,
+	}, appLogger)
+
+	serve(cfg, router, appLogger)
+
+	stopScheduler()
+	<-schedulerDone
+
+	stopWriteQueue()
+	<-writeQueueDone
+
+	stopSLO()
+	<-sloDone
+
+	stopMeter()
+	<-meterDone
+
+	if errorTracker != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		errorTracker.Close(ctx)
+	}
 
-	// Graceful shutdown
+	appLogger.Info("Server exited")
+}
+
+// serve обслуживает handler на SERVER_PORT (по HTTPS, если задан сертификат) до
+// SIGINT или SIGTERM, затем дожидается текущих запросов, но не дольше 5 секунд.
+func serve(cfg *config.Config, handler http.Handler, logger *slog.Logger) {
 	srv := &http.Server{
 		Addr:    fmt.Sprintf(":%s", cfg.ServerPort),
-		Handler: router,
+		Handler: handler,
+	}
+
+	var certs *tlscert.Reloader
+	if cfg.TLS.Enabled() {
+		var err error
+		certs, err = tlscert.New(cfg.TLS.CertFile, cfg.TLS.KeyFile)
+		if err != nil {
+			logger.Error("Failed to load TLS certificate", "error", err.Error())
+			os.Exit(1)
+		}
+		srv.TLSConfig = certs.Config()
 	}
 
 	go func() {
-		appLogger.Info("Server is running", "port", cfg.ServerPort)
-		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			appLogger.Error("Failed to start server", "error", err.Error())
+		logger.Info("Server is running", "port", cfg.ServerPort, "tls", certs != nil)
+		serve := srv.ListenAndServe
+		if certs != nil {
+			// Сертификат берется из TLSConfig.GetCertificate, поэтому файлы не передаются
+			serve = func() error { return srv.ListenAndServeTLS("", "") }
+		}
+		if err := serve(); err != nil && err != http.ErrServerClosed {
+			logger.Error("Failed to start server", "error", err.Error())
 			os.Exit(1)
 		}
 	}()
 
+	if certs != nil && cfg.TLS.ReloadOnSIGHUP {
+		hup := make(chan os.Signal, 1)
+		signal.Notify(hup, syscall.SIGHUP)
+		go func() {
+			for range hup {
+				if err := certs.Reload(); err != nil {
+					logger.Error("Failed to reload TLS certificate", "error", err.Error())
+					continue
+				}
+				logger.Info("TLS certificate reloaded", "cert_file", cfg.TLS.CertFile)
+			}
+		}()
+	}
+
+	// Снимок горутин и кучи по kill -USR1 <pid>, когда HTTP уже не отвечает
+	if cfg.Debug.PprofEnabled {
+		usr1 := make(chan os.Signal, 1)
+		signal.Notify(usr1, syscall.SIGUSR1)
+		go func() {
+			for range usr1 {
+				dump, err := diagnostics.WriteRuntimeDump(cfg.Debug.DumpDir, clock.Now(context.Background()))
+				if err != nil {
+					logger.Error("Failed to write runtime dump", "error", err.Error())
+					continue
+				}
+				logger.Info("Runtime dump written", "goroutines_file", dump.GoroutinesFile, "heap_file", dump.HeapFile)
+			}
+		}()
+	}
+
 	// Ожидание сигнала завершения
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 	<-quit
 
-	appLogger.Info("Shutting down server...")
+	logger.Info("Shutting down server...")
 
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
 	if err := srv.Shutdown(ctx); err != nil {
-		appLogger.Error("Server forced to shutdown", "error", err.Error())
+		logger.Error("Server forced to shutdown", "error", err.Error())
 	}
+}
 
-	appLogger.Info("Server exited")
+// newShadower включает повтор запросов на теневое развертывание, если задан SHADOW_URL.
+// Клиент без повторов: повтор важен только как сравнение, а не как доставка.
+func newShadower(cfg config.ShadowConfig, logger *slog.Logger) *shadow.Shadower {
+	if cfg.URL == "" {
+		return nil
+	}
+	clientCfg := httpclient.DefaultConfig("shadow")
+	clientCfg.Timeout = cfg.Timeout
+	clientCfg.MaxRetries = 0
+	return shadow.New(cfg.URL, shadow.Options{
+		Percent:     cfg.Percent,
+		Ignore:      cfg.IgnoreFields,
+		Concurrency: cfg.Concurrency,
+		Timeout:     cfg.Timeout,
+	}, httpclient.New(clientCfg, logger), logger)
+}
+
+// newDBPool создает пул соединений с настройками DB_MAX_CONNS и соседних; с
+// DB_SLOW_STATEMENT_THRESHOLD каждый запрос дольше порога попадает в лог. Пулы
+// тенантов наследуют трассировщик, время жизни соединений и порог нечеткого поиска
+// от этого пула.
+func newDBPool(cfg *config.Config, dsn string, logger *slog.Logger) (*pgxpool.Pool, error) {
+	poolCfg, err := pgxpool.ParseConfig(dsn)
+	if err != nil {
+		return nil, err
+	}
+	pool := cfg.DBConfig.Pool
+	if pool.MaxConns > 0 {
+		poolCfg.MaxConns = int32(pool.MaxConns)
+	}
+	if pool.MinConns > 0 {
+		poolCfg.MinConns = int32(pool.MinConns)
+	}
+	if pool.MaxConnLifetime > 0 {
+		poolCfg.MaxConnLifetime = pool.MaxConnLifetime
+	}
+	if pool.MaxConnIdleTime > 0 {
+		poolCfg.MaxConnIdleTime = pool.MaxConnIdleTime
+	}
+	if pool.HealthCheckPeriod > 0 {
+		poolCfg.HealthCheckPeriod = pool.HealthCheckPeriod
+	}
+	if pool.ConnectTimeout > 0 {
+		poolCfg.ConnConfig.ConnectTimeout = pool.ConnectTimeout
+	}
+	if cfg.DBConfig.SlowStatementThreshold > 0 {
+		poolCfg.ConnConfig.Tracer = postgres.NewSlowQueryTracer(cfg.DBConfig.SlowStatementThreshold, logger)
+	}
+	// Порог оператора % из pg_trgm для поиска match=fuzzy
+	poolCfg.ConnConfig.RuntimeParams[postgres.SimilarityThresholdParam] = strconv.FormatFloat(cfg.DBConfig.SearchSimilarityThreshold, 'f', -1, 64)
+	return pgxpool.NewWithConfig(context.Background(), poolCfg)
+}
+
+// newSystemHealthService собирает проверки сводной оценки состояния. Пороги подобраны
+// под алерты: degraded - стоит посмотреть, critical - сервис не справляется.
+// openMySQL подключается к MySQL подписок и при migrate применяет ее миграции
+// под GET_LOCK, как migrator.Migrate для Postgres.
+func openMySQL(ctx context.Context, cfg *config.Config, migrate bool, logger *slog.Logger) (*sql.DB, error) {
+	db, err := mysql.Open(ctx, cfg.DBConfig.MySQLDSN)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.DBConfig.Pool.MaxConns > 0 {
+		db.SetMaxOpenConns(cfg.DBConfig.Pool.MaxConns)
+	}
+	if cfg.DBConfig.Pool.MaxConnLifetime > 0 {
+		db.SetConnMaxLifetime(cfg.DBConfig.Pool.MaxConnLifetime)
+	}
+	if cfg.DBConfig.Pool.MaxConnIdleTime > 0 {
+		db.SetConnMaxIdleTime(cfg.DBConfig.Pool.MaxConnIdleTime)
+	}
+	if migrate {
+		if err := mysql.Migrate(ctx, db, mysql.Migrations(), logger); err != nil {
+			_ = db.Close()
+			return nil, err
+		}
+	}
+	return db, nil
+}
+
+func newSystemHealthService(db *pgxpool.Pool, writeQueue *service.WriteQueueService, exports *service.ExportService, webhook *httpclient.Client) *service.SystemHealthService {
+	checks := []service.HealthCheck{
+		service.DatabaseLatencyCheck(db.Ping, domain.HealthThresholds{Warn: 50, Critical: 500}),
+		service.BacklogCheck("export_jobs", exports.Backlog, domain.HealthThresholds{Warn: 20, Critical: 200}),
+		service.RatioCheck("exchange_rates_cache_hit_rate", func() (float64, float64) {
+			hits, misses := exchange.CacheCounts()
+			return hits, hits + misses
+		}, 15*time.Minute, domain.HealthThresholds{Warn: 80, Critical: 20}),
+		service.RatioCheck("http_error_rate", middleware.RequestCounts, 5*time.Minute, domain.HealthThresholds{Warn: 1, Critical: 10}),
+	}
+	if writeQueue != nil {
+		checks = append(checks, service.BacklogCheck("write_queue", writeQueue.Backlog, domain.HealthThresholds{Warn: 1, Critical: 1000}))
+	}
+	if webhook != nil {
+		checks = append(checks, service.CircuitCheck("events_webhook", webhook.RetryAfter))
+	}
+	return service.NewSystemHealthService(checks...)
+}
+
+// newBIExportService создает выгрузку сводок с отдельным публикатором на каждый адрес
+// BI_WEBHOOK_URLS: сводка проверяется схемой bi.daily_totals до отправки.
+func newBIExportService(cfg config.BIConfig, subs postgres.SubscriptionRepository, tenants postgres.TenantRepository,
+	schemas *events.Registry, logger *slog.Logger) *service.BIExportService {
+	client := httpclient.New(httpclient.DefaultConfig("bi_webhook"), logger)
+	endpoints := make([]events.Publisher, len(cfg.URLs))
+	for i, url := range cfg.URLs {
+		endpoints[i] = events.NewValidatingPublisher(events.NewWebhookPublisher(client, url, cfg.Secret), schemas)
+	}
+	return service.NewBIExportService(subs, tenants, endpoints, logger)
+}
+
+// newReadinessService собирает проверки /readyz: без базы и ее актуальной схемы
+// реплика не обслуживает запросы, а курсы нужны только для пересчета валют.
+// Без реплики базы чтения идут в основную, поэтому и она не обязательна.
+func newReadinessService(cfg *config.Config, db, replica *pgxpool.Pool, mysqlDB *sql.DB, rates exchange.Provider) *service.ReadinessService {
+	checks := []service.ReadinessCheck{
+		{Name: "database", Required: true, Check: db.Ping},
+		{Name: "migrations", Required: true, Check: func(ctx context.Context) error {
+			return migrator.CheckApplied(ctx, db, migrator.Source(cfg.MigrationsDir))
+		}},
+		{Name: "exchange_rates", Check: func(ctx context.Context) error {
+			_, err := rates.Rates(ctx)
+			return err
+		}},
+	}
+	if replica != nil {
+		checks = append(checks, service.ReadinessCheck{Name: "database_replica", Check: replica.Ping})
+	}
+	if mysqlDB != nil {
+		checks = append(checks, service.ReadinessCheck{Name: "mysql", Required: true, Check: mysqlDB.PingContext})
+	}
+	return service.NewReadinessService(cfg.ReadinessTimeout, checks...)
+}
+
+// newExchangeProvider собирает провайдер курсов: банк с кэшем, а при его недоступности - статические курсы.
+// Поверх них накладываются курсы, закрепленные тенантом.
+func newExchangeProvider(cfg config.ExchangeConfig, client *httpclient.Client, appLogger *slog.Logger) (exchange.Provider, error) {
+	staticRates, err := exchange.ParseRates(cfg.StaticRates)
+	if err != nil {
+		return nil, err
+	}
+	static := exchange.NewStaticProvider(domain.DefaultCurrency, staticRates)
+
+	var bank exchange.Provider
+	switch cfg.Provider {
+	case "cbr":
+		bank = exchange.NewCBRProvider(client, cmp.Or(cfg.URL, exchange.DefaultCBRURL))
+	case "ecb":
+		bank = exchange.NewECBProvider(client, cmp.Or(cfg.URL, exchange.DefaultECBURL))
+	case "static":
+		return exchange.NewPinnedProvider(static), nil
+	default:
+		return nil, fmt.Errorf("unknown exchange rates provider %q", cfg.Provider)
+	}
+	return exchange.NewPinnedProvider(exchange.NewFallbackProvider(appLogger, exchange.NewCachedProvider(bank, cfg.CacheTTL), static)), nil
 }
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"strconv"
+	"text/tabwriter"
+
+	"aggregator_db/internal/config"
+	"aggregator_db/internal/migrator"
+)
+
+const migrateUsage = "usage: migrate up | down [N] | status"
+
+// runMigrate выполняет подкоманду migrate к основной базе и завершается, не
+// запуская сервер: migrate up применяет все миграции, migrate down [N]
+// откатывает N последних (по умолчанию одну), migrate status печатает версию базы.
+func runMigrate(ctx context.Context, cfg *config.Config, args []string, logger *slog.Logger) error {
+	if len(args) == 0 {
+		return errors.New(migrateUsage)
+	}
+
+	dbPool, err := newDBPool(cfg, cfg.DSN(), logger)
+	if err != nil {
+		return err
+	}
+	defer dbPool.Close()
+	files := migrator.Source(cfg.MigrationsDir)
+
+	switch args[0] {
+	case "up":
+		if err := migrator.Migrate(ctx, dbPool, files, logger); err != nil {
+			return err
+		}
+		// Схема MySQL подписок поддерживает только накат
+		if cfg.DBConfig.Driver == "mysql" {
+			mysqlDB, err := openMySQL(ctx, cfg, true, logger)
+			if err != nil {
+				return err
+			}
+			return mysqlDB.Close()
+		}
+		return nil
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			if steps, err = strconv.Atoi(args[1]); err != nil || steps <= 0 {
+				return fmt.Errorf("invalid number of migrations to revert %q, %s", args[1], migrateUsage)
+			}
+		}
+		return migrator.Down(ctx, dbPool, files, steps, logger)
+	case "status":
+		status, err := migrator.GetStatus(ctx, dbPool, files)
+		if err != nil {
+			return err
+		}
+		out := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintf(out, "version %d, dirty %t, pending %d\n", status.Version, status.Dirty, status.Pending())
+		for _, m := range status.Migrations {
+			state := "pending"
+			if m.Applied {
+				state = "applied"
+			}
+			fmt.Fprintf(out, "%d\t%s\t%s\n", m.Version, state, m.Name)
+		}
+		return out.Flush()
+	default:
+		return fmt.Errorf("unknown migrate command %q, %s", args[0], migrateUsage)
+	}
+}
@@ -0,0 +1,55 @@
+// Команда apikey выпускает ключ внутреннего сервиса напрямую в базе, без
+// админского API: go run ./cmd/apikey -name monthly-aggregator -scope read
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"log/slog"
+	"os"
+
+	"aggregator_db/internal/config"
+	"aggregator_db/internal/repository/postgres"
+	"aggregator_db/internal/service"
+	"aggregator_db/pkg/domain"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+func main() {
+	var req domain.CreateAPIKeyRequest
+	var scope string
+	flag.StringVar(&req.Name, "name", "", "название сервиса, которому выдается ключ")
+	flag.StringVar(&scope, "scope", string(domain.APIKeyScopeRead), "права ключа: read или read_write")
+	flag.Parse()
+
+	req.Scope = domain.APIKeyScope(scope)
+	if req.Name == "" || len(req.Name) > 100 {
+		log.Fatal("-name is required, at most 100 characters")
+	}
+	if req.Scope != domain.APIKeyScopeRead && req.Scope != domain.APIKeyScopeReadWrite {
+		log.Fatalf("invalid -scope %q, expected read or read_write", scope)
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("Failed to load config: %v", err)
+	}
+
+	ctx := context.Background()
+	dbPool, err := pgxpool.New(ctx, cfg.DSN())
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+	defer dbPool.Close()
+
+	logger := slog.New(slog.NewTextHandler(os.Stderr, nil))
+	credentials, err := service.NewAPIKeyService(postgres.NewAPIKeyRepository(dbPool), logger).Create(ctx, req)
+	if err != nil {
+		log.Fatalf("Failed to create api key: %v", err)
+	}
+
+	// Ключ - единственное, что пишется в stdout, чтобы его было удобно сохранить в секрет
+	fmt.Println(credentials.APIKey)
+}
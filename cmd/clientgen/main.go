@@ -0,0 +1,31 @@
+// Команда clientgen записывает в каталог спецификацию OpenAPI, конфигурации
+// openapi-generator и generate.sh - те же файлы, что раздает /api/v1/clients.
+// Сборка образа запускает ее после swag init, а затем generate.sh:
+// go run ./cmd/clientgen -out build/clients
+package main
+
+import (
+	"flag"
+	"log"
+
+	"aggregator_db/docs"
+	"aggregator_db/internal/changelog"
+	"aggregator_db/internal/clients"
+)
+
+func main() {
+	out := flag.String("out", "build/clients", "каталог для файлов сборки клиентов")
+	flag.Parse()
+
+	apiChangelog, err := changelog.Load()
+	if err != nil {
+		log.Fatalf("Failed to load API changelog: %v", err)
+	}
+	catalog, err := clients.NewCatalog([]byte(docs.SwaggerInfo.ReadDoc()), apiChangelog.CurrentVersion(), "")
+	if err != nil {
+		log.Fatalf("Failed to build client catalog: %v", err)
+	}
+	if err := catalog.WriteInputs(*out); err != nil {
+		log.Fatalf("Failed to write client inputs: %v", err)
+	}
+}
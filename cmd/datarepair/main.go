@@ -0,0 +1,92 @@
+// Команда datarepair проверяет целостность подписок напрямую в базе, без админского
+// API: go run ./cmd/datarepair печатает отчет, с -apply применяет исправления из
+// DATA_REPAIR_FIXES: go run ./cmd/datarepair -apply -kinds end_before_start -tenant acme
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"log"
+	"log/slog"
+	"os"
+	"strings"
+
+	"aggregator_db/internal/config"
+	"aggregator_db/internal/repository/postgres"
+	"aggregator_db/internal/service"
+	"aggregator_db/internal/tenancy"
+	"aggregator_db/pkg/domain"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+func main() {
+	var req domain.DataRepairRequest
+	var apply bool
+	var kinds, tenantID string
+	flag.BoolVar(&apply, "apply", false, "применить исправления; без флага только отчет")
+	flag.StringVar(&kinds, "kinds", "", "исправляемые виды нарушений через запятую; по умолчанию все настроенные")
+	flag.IntVar(&req.BatchSize, "batch", 0, "подписок в одной транзакции; по умолчанию DATA_REPAIR_BATCH_SIZE")
+	flag.StringVar(&tenantID, "tenant", "", "ID тенанта; по умолчанию основная база")
+	flag.Parse()
+
+	for _, kind := range strings.Split(kinds, ",") {
+		if kind = strings.TrimSpace(kind); kind != "" {
+			req.Kinds = append(req.Kinds, domain.DataIssueKind(kind))
+		}
+	}
+	if req.BatchSize < 0 {
+		log.Fatal("-batch must be positive")
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("Failed to load config: %v", err)
+	}
+	fixes, err := domain.ParseDataFixes(cfg.DataRepair.Fixes)
+	if err != nil {
+		log.Fatalf("Failed to configure data repair: %v", err)
+	}
+	schemaMigrations, err := domain.ParseSchemaMigrations(cfg.Migrations.Dates, cfg.Migrations.Money)
+	if err != nil {
+		log.Fatalf("Failed to configure schema migrations: %v", err)
+	}
+
+	ctx := context.Background()
+	dbPool, err := pgxpool.New(ctx, cfg.DSN())
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+	defer dbPool.Close()
+
+	tenantRouter := postgres.NewTenantRouter(dbPool, cfg.Tenancy.PoolMaxConns)
+	defer tenantRouter.Close()
+	if tenantID != "" {
+		tenant, err := postgres.NewTenantRepository(dbPool).Get(ctx, tenantID)
+		if err != nil {
+			log.Fatalf("Failed to load tenant %q: %v", tenantID, err)
+		}
+		ctx = tenancy.WithTenant(ctx, tenant)
+	}
+
+	logger := slog.New(slog.NewTextHandler(os.Stderr, nil))
+	// Кэш расчетов живет в процессах API: исправленные отсюда подписки он увидит по истечении TTL
+	repairService := service.NewDataRepairService(postgres.NewDataRepairRepository(tenantRouter, schemaMigrations), nil, fixes, cfg.DataRepair.BatchSize, logger)
+
+	var report *domain.DataIssueReport
+	if apply {
+		report, err = repairService.Repair(ctx, req)
+	} else {
+		report, err = repairService.Scan(ctx)
+	}
+	if err != nil {
+		log.Fatalf("Failed to check data: %v", err)
+	}
+
+	// Отчет - единственное, что пишется в stdout, чтобы его можно было сохранить или разобрать jq
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(report); err != nil {
+		log.Fatalf("Failed to write report: %v", err)
+	}
+}
@@ -0,0 +1,378 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"math/rand"
+	"net/http"
+	"os"
+	"os/signal"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var services = []string{"Yandex Plus", "Netflix", "Spotify", "Kinopoisk", "Okko", "Ivi", "Apple Music", "YouTube Premium"}
+
+type config struct {
+	target      string
+	rps         int
+	concurrency int
+	duration    time.Duration
+	users       int
+	dataset     int
+	mix         map[string]int
+}
+
+func main() {
+	var cfg config
+	var mix string
+
+	flag.StringVar(&cfg.target, "target", "http://localhost:8080", "базовый URL сервиса")
+	flag.IntVar(&cfg.rps, "rps", 50, "целевое количество запросов в секунду")
+	flag.IntVar(&cfg.concurrency, "concurrency", 10, "количество параллельных воркеров")
+	flag.DurationVar(&cfg.duration, "duration", 30*time.Second, "длительность прогона")
+	flag.IntVar(&cfg.users, "users", 50, "количество пользователей в наборе данных")
+	flag.IntVar(&cfg.dataset, "dataset", 500, "количество подписок, создаваемых перед прогоном")
+	flag.StringVar(&mix, "mix", "create=10,get=30,list=25,calculate=20,update=10,delete=5", "доли операций в трафике")
+	flag.Parse()
+
+	parsedMix, err := parseMix(mix)
+	if err != nil {
+		log.Fatalf("invalid -mix: %v", err)
+	}
+	cfg.mix = parsedMix
+
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
+
+	// Без повторов и circuit breaker: нам нужны честные задержки и ошибки
+	client := &http.Client{
+		Timeout: 10 * time.Second,
+		Transport: &http.Transport{
+			MaxIdleConns:        cfg.concurrency * 2,
+			MaxIdleConnsPerHost: cfg.concurrency * 2,
+		},
+	}
+
+	r := &runner{cfg: cfg, client: client, stats: newStats()}
+	for i := 0; i < cfg.users; i++ {
+		r.users = append(r.users, uuid.New())
+	}
+
+	log.Printf("seeding %d subscriptions for %d users", cfg.dataset, cfg.users)
+	if err := r.seed(ctx); err != nil {
+		log.Fatalf("seed failed: %v", err)
+	}
+
+	log.Printf("running %s at %d rps with %d workers against %s", cfg.duration, cfg.rps, cfg.concurrency, cfg.target)
+	r.stats = newStats()
+	r.run(ctx)
+
+	r.stats.report(os.Stdout)
+}
+
+func parseMix(value string) (map[string]int, error) {
+	known := map[string]bool{"create": true, "get": true, "list": true, "calculate": true, "update": true, "delete": true}
+	mix := make(map[string]int)
+	for _, part := range strings.Split(value, ",") {
+		name, weight, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok || !known[name] {
+			return nil, fmt.Errorf("unknown operation %q", part)
+		}
+		w, err := strconv.Atoi(weight)
+		if err != nil || w < 0 {
+			return nil, fmt.Errorf("invalid weight for %s", name)
+		}
+		mix[name] = w
+	}
+	return mix, nil
+}
+
+type runner struct {
+	cfg    config
+	client *http.Client
+	stats  *stats
+	users  []uuid.UUID
+
+	mu  sync.Mutex
+	ids []uuid.UUID
+}
+
+func (r *runner) seed(ctx context.Context) error {
+	for i := 0; i < r.cfg.dataset; i++ {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if err := r.create(ctx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *runner) run(ctx context.Context) {
+	ctx, cancel := context.WithTimeout(ctx, r.cfg.duration)
+	defer cancel()
+
+	ops := make(chan string)
+	var wg sync.WaitGroup
+	for i := 0; i < r.cfg.concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for op := range ops {
+				r.execute(ctx, op)
+			}
+		}()
+	}
+
+	ticker := time.NewTicker(time.Second / time.Duration(max(r.cfg.rps, 1)))
+	defer ticker.Stop()
+
+loop:
+	for {
+		select {
+		case <-ctx.Done():
+			break loop
+		case <-ticker.C:
+			select {
+			case ops <- r.pick():
+			default:
+				// Все воркеры заняты - фиксируем недобор нагрузки
+				r.stats.dropped()
+			}
+		}
+	}
+
+	close(ops)
+	wg.Wait()
+}
+
+func (r *runner) pick() string {
+	total := 0
+	for _, w := range r.cfg.mix {
+		total += w
+	}
+	n := rand.Intn(max(total, 1))
+	for _, op := range []string{"create", "get", "list", "calculate", "update", "delete"} {
+		n -= r.cfg.mix[op]
+		if n < 0 {
+			return op
+		}
+	}
+	return "get"
+}
+
+func (r *runner) execute(ctx context.Context, op string) {
+	var err error
+	switch op {
+	case "create":
+		err = r.create(ctx)
+	case "get":
+		err = r.withID(func(id uuid.UUID) error {
+			return r.do(ctx, op, http.MethodGet, "/api/v1/subscriptions/"+id.String(), nil, nil)
+		})
+	case "list":
+		err = r.do(ctx, op, http.MethodGet, fmt.Sprintf("/api/v1/subscriptions?limit=20&user_id=%s", r.randomUser()), nil, nil)
+	case "calculate":
+		err = r.do(ctx, op, http.MethodGet, fmt.Sprintf("/api/v1/subscriptions/calculate?start_period=01-2025&end_period=12-2025&user_id=%s", r.randomUser()), nil, nil)
+	case "update":
+		err = r.withID(func(id uuid.UUID) error {
+			return r.do(ctx, op, http.MethodPatch, "/api/v1/subscriptions/"+id.String(), map[string]interface{}{"price": randomPrice()}, nil)
+		})
+	case "delete":
+		id, ok := r.takeID()
+		if !ok {
+			return
+		}
+		err = r.do(ctx, op, http.MethodDelete, "/api/v1/subscriptions/"+id.String(), nil, nil)
+	}
+	if err != nil && ctx.Err() == nil {
+		log.Printf("%s: %v", op, err)
+	}
+}
+
+// randomPrice возвращает цену с копейками, чтобы нагрузка шла через разбор Money.
+func randomPrice() map[string]string {
+	return map[string]string{"amount": fmt.Sprintf("%d.%02d", 100+rand.Intn(900), rand.Intn(100)), "currency": "RUB"}
+}
+
+func (r *runner) create(ctx context.Context) error {
+	start := time.Date(2024+rand.Intn(2), time.Month(1+rand.Intn(12)), 1, 0, 0, 0, 0, time.UTC)
+	body := map[string]interface{}{
+		"service_name": services[rand.Intn(len(services))],
+		"price":        randomPrice(),
+		"user_id":      r.randomUser(),
+		"start_date":   start.Format("01-2006"),
+	}
+
+	var created struct {
+		ID uuid.UUID `json:"id"`
+	}
+	if err := r.do(ctx, "create", http.MethodPost, "/api/v1/subscriptions", body, &created); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	r.ids = append(r.ids, created.ID)
+	r.mu.Unlock()
+	return nil
+}
+
+func (r *runner) randomUser() uuid.UUID {
+	return r.users[rand.Intn(len(r.users))]
+}
+
+func (r *runner) withID(fn func(id uuid.UUID) error) error {
+	r.mu.Lock()
+	if len(r.ids) == 0 {
+		r.mu.Unlock()
+		return nil
+	}
+	id := r.ids[rand.Intn(len(r.ids))]
+	r.mu.Unlock()
+	return fn(id)
+}
+
+func (r *runner) takeID() (uuid.UUID, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.ids) == 0 {
+		return uuid.Nil, false
+	}
+	i := rand.Intn(len(r.ids))
+	id := r.ids[i]
+	r.ids[i] = r.ids[len(r.ids)-1]
+	r.ids = r.ids[:len(r.ids)-1]
+	return id, true
+}
+
+func (r *runner) do(ctx context.Context, op, method, path string, body, out interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		payload, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(payload)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, r.cfg.target+path, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	start := time.Now()
+	resp, err := r.client.Do(req)
+	elapsed := time.Since(start)
+	if err != nil {
+		// Запросы, оборванные окончанием прогона, в статистику не попадают
+		if ctx.Err() == nil {
+			r.stats.record(op, elapsed, 0)
+		}
+		return err
+	}
+	defer resp.Body.Close()
+	r.stats.record(op, elapsed, resp.StatusCode)
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		_, _ = io.Copy(io.Discard, resp.Body)
+		return fmt.Errorf("%s %s: status %d", method, path, resp.StatusCode)
+	}
+	if out != nil {
+		return json.NewDecoder(resp.Body).Decode(out)
+	}
+	_, _ = io.Copy(io.Discard, resp.Body)
+	return nil
+}
+
+type opStats struct {
+	latencies []time.Duration
+	errors    int
+	statuses  map[int]int
+}
+
+type stats struct {
+	mu      sync.Mutex
+	started time.Time
+	ops     map[string]*opStats
+	skipped int
+}
+
+func newStats() *stats {
+	return &stats{started: time.Now(), ops: make(map[string]*opStats)}
+}
+
+func (s *stats) record(op string, latency time.Duration, status int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	st, ok := s.ops[op]
+	if !ok {
+		st = &opStats{statuses: make(map[int]int)}
+		s.ops[op] = st
+	}
+	st.latencies = append(st.latencies, latency)
+	st.statuses[status]++
+	// 404 на get/update/delete ожидаем при гонках с delete, ошибкой не считаем
+	if status == 0 || status >= http.StatusInternalServerError || (status >= http.StatusBadRequest && status != http.StatusNotFound) {
+		st.errors++
+	}
+}
+
+func (s *stats) dropped() {
+	s.mu.Lock()
+	s.skipped++
+	s.mu.Unlock()
+}
+
+func (s *stats) report(w io.Writer) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	elapsed := time.Since(s.started)
+	names := make([]string, 0, len(s.ops))
+	for name := range s.ops {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	fmt.Fprintf(w, "\n%-10s %8s %8s %8s %10s %10s %10s %10s\n", "op", "count", "rps", "errors", "p50", "p90", "p99", "max")
+	total, totalErrors := 0, 0
+	for _, name := range names {
+		st := s.ops[name]
+		sort.Slice(st.latencies, func(i, j int) bool { return st.latencies[i] < st.latencies[j] })
+		count := len(st.latencies)
+		total += count
+		totalErrors += st.errors
+		fmt.Fprintf(w, "%-10s %8d %8.1f %7.2f%% %10s %10s %10s %10s\n",
+			name, count, float64(count)/elapsed.Seconds(), 100*float64(st.errors)/float64(count),
+			percentile(st.latencies, 0.50), percentile(st.latencies, 0.90), percentile(st.latencies, 0.99), st.latencies[count-1],
+		)
+	}
+
+	if total > 0 {
+		fmt.Fprintf(w, "\ntotal: %d requests in %s (%.1f rps), error rate %.2f%%, dropped ticks %d\n",
+			total, elapsed.Round(time.Millisecond), float64(total)/elapsed.Seconds(), 100*float64(totalErrors)/float64(total), s.skipped)
+	}
+}
+
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	idx := int(float64(len(sorted)-1) * p)
+	return sorted[idx].Round(time.Microsecond)
+}
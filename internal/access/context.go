@@ -0,0 +1,44 @@
+// Package access передает через context.Context, от чьего имени выполняется запрос.
+package access
+
+import (
+	"context"
+
+	"aggregator_db/pkg/domain"
+	"github.com/google/uuid"
+)
+
+// Principal - пользователь запроса и его роль. Ключи внутренних сервисов
+// действуют с ролью admin и без UserID.
+type Principal struct {
+	UserID uuid.UUID
+	Role   domain.Role
+}
+
+func (p *Principal) IsAdmin() bool {
+	return p.Role == domain.RoleAdmin
+}
+
+// CanAccess сообщает, может ли принципал видеть и менять данные пользователя userID.
+func (p *Principal) CanAccess(userID uuid.UUID) bool {
+	return p.IsAdmin() || p.UserID == userID
+}
+
+// CanRead сообщает, может ли принципал видеть данные пользователя userID: поддержка
+// читает данные всех пользователей, но меняет только свои.
+func (p *Principal) CanRead(userID uuid.UUID) bool {
+	return p.Role == domain.RoleSupport || p.CanAccess(userID)
+}
+
+type contextKey struct{}
+
+func WithPrincipal(ctx context.Context, principal *Principal) context.Context {
+	return context.WithValue(ctx, contextKey{}, principal)
+}
+
+// FromContext возвращает принципала запроса или nil, если проверка доступа
+// выключена (RBAC_ENABLED=false).
+func FromContext(ctx context.Context) *Principal {
+	principal, _ := ctx.Value(contextKey{}).(*Principal)
+	return principal
+}
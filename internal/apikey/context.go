@@ -0,0 +1,21 @@
+// Package apikey передает ключ внутреннего сервиса через context.Context.
+package apikey
+
+import (
+	"context"
+
+	"aggregator_db/pkg/domain"
+)
+
+type contextKey struct{}
+
+func WithKey(ctx context.Context, key *domain.APIKey) context.Context {
+	return context.WithValue(ctx, contextKey{}, key)
+}
+
+// FromContext возвращает ключ сервиса, от имени которого выполняется запрос,
+// или nil для запросов без ключа сервиса.
+func FromContext(ctx context.Context) *domain.APIKey {
+	key, _ := ctx.Value(contextKey{}).(*domain.APIKey)
+	return key
+}
@@ -0,0 +1,30 @@
+// Package audit передает через context.Context, кто и в каком запросе меняет данные:
+// репозитории пишут это в журнал изменений вместе с самим изменением.
+package audit
+
+import "context"
+
+// SystemActor - исполнитель изменений вне HTTP-запросов: задач планировщика,
+// повтора отложенных записей, репликации из других регионов.
+const SystemActor = "system"
+
+// Request - исполнитель изменения и ID запроса (X-Request-ID).
+type Request struct {
+	Actor     string
+	RequestID string
+}
+
+type contextKey struct{}
+
+func WithRequest(ctx context.Context, request Request) context.Context {
+	return context.WithValue(ctx, contextKey{}, request)
+}
+
+// FromContext возвращает исполнителя запроса; вне HTTP-запроса - SystemActor без ID запроса.
+func FromContext(ctx context.Context) Request {
+	request, ok := ctx.Value(contextKey{}).(Request)
+	if !ok {
+		return Request{Actor: SystemActor}
+	}
+	return request
+}
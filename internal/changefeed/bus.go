@@ -0,0 +1,33 @@
+// Package changefeed будит ожидающих ленту изменений подписок, когда в этом
+// процессе закоммичено изменение. Изменения с других реплик ожидающие находят
+// сами, периодически перечитывая журнал.
+package changefeed
+
+import "sync"
+
+// Bus - широковещательный сигнал "что-то изменилось" без содержимого: содержимое
+// ожидающие читают из журнала изменений.
+type Bus struct {
+	mu      sync.Mutex
+	changed chan struct{}
+}
+
+func NewBus() *Bus {
+	return &Bus{changed: make(chan struct{})}
+}
+
+// Changed возвращает канал, который закроется при следующем Notify. Канал нужно
+// получить до чтения журнала, иначе изменение между чтением и ожиданием потеряется.
+func (b *Bus) Changed() <-chan struct{} {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.changed
+}
+
+// Notify будит всех, кто ждет на канале из Changed.
+func (b *Bus) Notify() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	close(b.changed)
+	b.changed = make(chan struct{})
+}
@@ -0,0 +1,199 @@
+// Package changelog - журнал изменений API, встроенный в бинарник: версии с
+// затронутыми ручками, признаком несовместимости и датами удаления устаревшего.
+package changelog
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"time"
+
+	"aggregator_db/pkg/domain"
+)
+
+// Новая версия API добавляется записью в начало changelog.json.
+//
+//go:embed changelog.json
+var changelogFile []byte
+
+var (
+	versionPattern  = regexp.MustCompile(`^([0-9]+)\.([0-9]+)\.([0-9]+)$`)
+	endpointPattern = regexp.MustCompile(`^(GET|POST|PUT|PATCH|DELETE) (/[A-Za-z0-9_{}/.-]*)$`)
+)
+
+// Changelog - разобранный журнал изменений.
+type Changelog struct {
+	entries      []domain.ChangelogEntry
+	deprecations []domain.Deprecation
+}
+
+// Load читает встроенный журнал и проверяет его: версии идут от новых к старым,
+// даты в формате YYYY-MM-DD, у устаревших ручек есть дата удаления.
+func Load() (*Changelog, error) {
+	return parse(changelogFile)
+}
+
+func parse(raw []byte) (*Changelog, error) {
+	var file struct {
+		Entries []domain.ChangelogEntry `json:"entries"`
+	}
+	if err := json.Unmarshal(raw, &file); err != nil {
+		return nil, fmt.Errorf("changelog: %w", err)
+	}
+	if len(file.Entries) == 0 {
+		return nil, fmt.Errorf("changelog: no entries")
+	}
+
+	log := &Changelog{entries: file.Entries}
+	var previous []int
+	for i := range log.entries {
+		entry := &log.entries[i]
+		version, err := parseVersion(entry.Version)
+		if err != nil {
+			return nil, fmt.Errorf("changelog: %w", err)
+		}
+		if previous != nil && compareVersions(version, previous) >= 0 {
+			return nil, fmt.Errorf("changelog: version %s must be older than the entry above it", entry.Version)
+		}
+		previous = version
+
+		date, err := time.Parse(time.DateOnly, entry.Date)
+		if err != nil {
+			return nil, fmt.Errorf("changelog %s: invalid date %q, expected YYYY-MM-DD", entry.Version, entry.Date)
+		}
+		for j := range entry.Changes {
+			change := &entry.Changes[j]
+			if err := validateChange(change); err != nil {
+				return nil, fmt.Errorf("changelog %s: %w", entry.Version, err)
+			}
+			if change.Endpoints == nil {
+				change.Endpoints = []string{}
+			}
+			entry.Breaking = entry.Breaking || change.Breaking
+
+			if change.Kind != domain.ChangeDeprecated {
+				continue
+			}
+			sunset, err := time.Parse(time.DateOnly, change.Sunset)
+			if err != nil {
+				return nil, fmt.Errorf("changelog %s: deprecation needs a sunset date, expected YYYY-MM-DD", entry.Version)
+			}
+			for _, endpoint := range change.Endpoints {
+				deprecation := domain.Deprecation{Route: endpoint, Param: change.Param, Since: date, Sunset: sunset}
+				if err := deprecation.Validate(); err != nil {
+					return nil, fmt.Errorf("changelog %s: %w", entry.Version, err)
+				}
+				log.deprecations = append(log.deprecations, deprecation)
+			}
+		}
+	}
+	return log, nil
+}
+
+func validateChange(change *domain.APIChange) error {
+	switch change.Kind {
+	case domain.ChangeAdded, domain.ChangeChanged, domain.ChangeDeprecated, domain.ChangeRemoved, domain.ChangeFixed:
+	default:
+		return fmt.Errorf("unknown change kind %q", change.Kind)
+	}
+	if change.Description == "" {
+		return fmt.Errorf("%s change without description", change.Kind)
+	}
+	if change.Kind == domain.ChangeRemoved && !change.Breaking {
+		return fmt.Errorf("removal must be marked breaking")
+	}
+	if change.Kind == domain.ChangeDeprecated && len(change.Endpoints) == 0 {
+		return fmt.Errorf("deprecation without endpoints")
+	}
+	if change.Sunset != "" && change.Kind != domain.ChangeDeprecated {
+		return fmt.Errorf("sunset is only valid for deprecations")
+	}
+	for _, endpoint := range change.Endpoints {
+		if !endpointPattern.MatchString(endpoint) {
+			return fmt.Errorf("invalid endpoint %q, expected \"METHOD /path\"", endpoint)
+		}
+	}
+	return nil
+}
+
+func parseVersion(version string) ([]int, error) {
+	match := versionPattern.FindStringSubmatch(version)
+	if match == nil {
+		return nil, fmt.Errorf("invalid version %q, expected MAJOR.MINOR.PATCH", version)
+	}
+	parsed := make([]int, 3)
+	for i := range parsed {
+		parsed[i], _ = strconv.Atoi(match[i+1])
+	}
+	return parsed, nil
+}
+
+func compareVersions(a, b []int) int {
+	for i := range a {
+		if a[i] != b[i] {
+			return a[i] - b[i]
+		}
+	}
+	return 0
+}
+
+// CurrentVersion - последняя версия API.
+func (c *Changelog) CurrentVersion() string {
+	return c.entries[0].Version
+}
+
+// List возвращает версии под фильтры query, новые сверху. С Endpoint в версиях
+// остаются только изменения этой ручки.
+func (c *Changelog) List(query domain.ChangelogQuery) (*domain.Changelog, error) {
+	var since []int
+	if query.Since != "" {
+		var err error
+		if since, err = parseVersion(query.Since); err != nil {
+			return nil, err
+		}
+	}
+
+	result := &domain.Changelog{CurrentVersion: c.CurrentVersion(), Entries: make([]domain.ChangelogEntry, 0)}
+	for _, entry := range c.entries {
+		if since != nil {
+			version, _ := parseVersion(entry.Version)
+			if compareVersions(version, since) <= 0 {
+				break
+			}
+		}
+		if query.Endpoint != "" {
+			entry = filterEndpoint(entry, query.Endpoint)
+			if len(entry.Changes) == 0 {
+				continue
+			}
+		}
+		if query.Breaking && !entry.Breaking {
+			continue
+		}
+		result.Entries = append(result.Entries, entry)
+	}
+	return result, nil
+}
+
+func filterEndpoint(entry domain.ChangelogEntry, endpoint string) domain.ChangelogEntry {
+	changes := make([]domain.APIChange, 0, len(entry.Changes))
+	entry.Breaking = false
+	for _, change := range entry.Changes {
+		for _, e := range change.Endpoints {
+			if e == endpoint {
+				changes = append(changes, change)
+				entry.Breaking = entry.Breaking || change.Breaking
+				break
+			}
+		}
+	}
+	entry.Changes = changes
+	return entry
+}
+
+// Deprecations возвращает устаревшие ручки и параметры из журнала.
+func (c *Changelog) Deprecations() []domain.Deprecation {
+	return c.deprecations
+}
@@ -0,0 +1,146 @@
+package changelog
+
+import (
+	"strings"
+	"testing"
+
+	"aggregator_db/pkg/domain"
+)
+
+func TestLoad(t *testing.T) {
+	log, err := Load()
+	if err != nil {
+		t.Fatalf("embedded changelog: %v", err)
+	}
+	if log.CurrentVersion() == "" {
+		t.Error("current version is empty")
+	}
+}
+
+func TestParseRejectsInvalidChangelog(t *testing.T) {
+	tests := []struct {
+		name string
+		raw  string
+		want string
+	}{
+		{name: "empty", raw: `{"entries": []}`, want: "no entries"},
+		{
+			name: "version order",
+			raw: `{"entries": [
+                {"version": "1.0.0", "date": "2025-01-01", "changes": []},
+                {"version": "1.1.0", "date": "2025-02-01", "changes": []}]}`,
+			want: "must be older",
+		},
+		{name: "version format", raw: `{"entries": [{"version": "v1", "date": "2025-01-01"}]}`, want: "MAJOR.MINOR.PATCH"},
+		{name: "date format", raw: `{"entries": [{"version": "1.0.0", "date": "01.01.2025"}]}`, want: "YYYY-MM-DD"},
+		{
+			name: "unknown kind",
+			raw:  `{"entries": [{"version": "1.0.0", "date": "2025-01-01", "changes": [{"kind": "renamed", "description": "x"}]}]}`,
+			want: "unknown change kind",
+		},
+		{
+			name: "removal not breaking",
+			raw:  `{"entries": [{"version": "1.0.0", "date": "2025-01-01", "changes": [{"kind": "removed", "description": "x", "endpoints": ["GET /health"]}]}]}`,
+			want: "breaking",
+		},
+		{
+			name: "endpoint format",
+			raw:  `{"entries": [{"version": "1.0.0", "date": "2025-01-01", "changes": [{"kind": "added", "description": "x", "endpoints": ["/api/v1/subscriptions"]}]}]}`,
+			want: "METHOD /path",
+		},
+		{
+			name: "sunset before deprecation",
+			raw: `{"entries": [{"version": "1.0.0", "date": "2025-01-01", "changes": [
+                {"kind": "deprecated", "description": "x", "endpoints": ["GET /health"], "sunset": "2024-12-01"}]}]}`,
+			want: "must be after",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parse([]byte(tt.raw))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("err = %v, want containing %q", err, tt.want)
+			}
+		})
+	}
+}
+
+const testChangelog = `{"entries": [
+    {"version": "1.2.0", "date": "2025-03-01", "summary": "Устаревший параметр", "changes": [
+        {"kind": "deprecated", "description": "userId заменен на user_id", "param": "userId", "sunset": "2026-03-01",
+         "endpoints": ["GET /api/v1/subscriptions", "GET /api/v1/subscriptions/calculate"]},
+        {"kind": "deprecated", "description": "Ручка устарела", "sunset": "2026-01-01", "endpoints": ["GET /api/v1/subscriptions/{id}/old"]}
+    ]},
+    {"version": "1.1.0", "date": "2025-02-01", "summary": "Пагинация", "changes": [
+        {"kind": "changed", "description": "Конверт пагинации", "endpoints": ["GET /api/v1/subscriptions"], "breaking": true},
+        {"kind": "added", "description": "Расчет суммы", "endpoints": ["GET /api/v1/subscriptions/calculate"]}
+    ]},
+    {"version": "1.0.0", "date": "2025-01-01", "summary": "Первая версия", "changes": []}
+]}`
+
+func TestList(t *testing.T) {
+	log, err := parse([]byte(testChangelog))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		query    domain.ChangelogQuery
+		versions []string
+		changes  int
+	}{
+		{name: "all", versions: []string{"1.2.0", "1.1.0", "1.0.0"}, changes: 4},
+		{name: "since", query: domain.ChangelogQuery{Since: "1.1.0"}, versions: []string{"1.2.0"}, changes: 2},
+		{name: "breaking", query: domain.ChangelogQuery{Breaking: true}, versions: []string{"1.1.0"}, changes: 2},
+		{
+			name:     "endpoint",
+			query:    domain.ChangelogQuery{Endpoint: "GET /api/v1/subscriptions/calculate"},
+			versions: []string{"1.2.0", "1.1.0"},
+			changes:  2,
+		},
+		// Несовместимое изменение 1.1.0 касается другой ручки
+		{name: "breaking endpoint", query: domain.ChangelogQuery{Breaking: true, Endpoint: "GET /api/v1/subscriptions/calculate"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := log.List(tt.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result.CurrentVersion != "1.2.0" {
+				t.Errorf("current version = %s, want 1.2.0", result.CurrentVersion)
+			}
+			var versions []string
+			changes := 0
+			for _, entry := range result.Entries {
+				versions = append(versions, entry.Version)
+				changes += len(entry.Changes)
+			}
+			if strings.Join(versions, ",") != strings.Join(tt.versions, ",") || changes != tt.changes {
+				t.Errorf("versions = %v with %d changes, want %v with %d", versions, changes, tt.versions, tt.changes)
+			}
+		})
+	}
+
+	if _, err := log.List(domain.ChangelogQuery{Since: "latest"}); err == nil {
+		t.Error("invalid since is accepted")
+	}
+}
+
+func TestDeprecations(t *testing.T) {
+	log, err := parse([]byte(testChangelog))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var surfaces []string
+	for _, deprecation := range log.Deprecations() {
+		surfaces = append(surfaces, deprecation.Surface()+" "+deprecation.Sunset.Format("2006-01-02"))
+	}
+	want := "GET /api/v1/subscriptions?userId 2026-03-01,GET /api/v1/subscriptions/calculate?userId 2026-03-01," +
+		"GET /api/v1/subscriptions/{id}/old 2026-01-01"
+	if got := strings.Join(surfaces, ","); got != want {
+		t.Errorf("deprecations = %s, want %s", got, want)
+	}
+}
@@ -0,0 +1,139 @@
+// Package clients - файлы для сборки клиентов API на TypeScript и Python:
+// спецификация OpenAPI этой сборки, конфигурации openapi-generator и готовые
+// пакеты, если их собрали вместе с образом. Спецификация берется из тех же
+// аннотаций хендлеров, что и /swagger, поэтому клиенты не отстают от API.
+package clients
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"embed"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"aggregator_db/pkg/domain"
+)
+
+//go:embed generator
+var generatorFiles embed.FS
+
+// Languages - языки клиентов; для каждого есть generator/<язык>.yaml.
+var Languages = []string{"typescript", "python"}
+
+// SpecName - имя спецификации; на него ссылается inputSpec в конфигурациях.
+const SpecName = "openapi.json"
+
+var ErrArtifactNotFound = errors.New("client artifact not found")
+
+// Artifact - файл каталога вместе с содержимым.
+type Artifact struct {
+	domain.ClientArtifact
+	Content []byte
+}
+
+// Catalog - файлы клиентов, собранные при старте сервиса.
+type Catalog struct {
+	version   string
+	artifacts []*Artifact
+}
+
+// NewCatalog собирает каталог из спецификации spec, версии API version и готовых
+// пакетов <язык>.tgz из каталога packagesDir. Отсутствие packagesDir не ошибка:
+// без пакетов клиенты собираются скриптом generate.sh из остальных файлов.
+func NewCatalog(spec []byte, version, packagesDir string) (*Catalog, error) {
+	if !json.Valid(spec) {
+		return nil, fmt.Errorf("clients: openapi spec is not valid JSON")
+	}
+
+	catalog := &Catalog{version: version}
+	catalog.add(SpecName, domain.ClientArtifactSpec, "", "application/json", spec)
+	for _, lang := range Languages {
+		raw, err := generatorFiles.ReadFile("generator/" + lang + ".yaml")
+		if err != nil {
+			return nil, fmt.Errorf("clients: %w", err)
+		}
+		config := bytes.ReplaceAll(raw, []byte("{{version}}"), []byte(version))
+		catalog.add(lang+".yaml", domain.ClientArtifactConfig, lang, "application/yaml", config)
+	}
+	script, err := generatorFiles.ReadFile("generator/generate.sh")
+	if err != nil {
+		return nil, fmt.Errorf("clients: %w", err)
+	}
+	catalog.add("generate.sh", domain.ClientArtifactScript, "", "text/x-shellscript", script)
+
+	if packagesDir == "" {
+		return catalog, nil
+	}
+	for _, lang := range Languages {
+		content, err := os.ReadFile(filepath.Join(packagesDir, lang+".tgz"))
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("clients: %w", err)
+		}
+		catalog.add(lang+".tgz", domain.ClientArtifactPackage, lang, "application/gzip", content)
+	}
+	return catalog, nil
+}
+
+func (c *Catalog) add(name string, kind domain.ClientArtifactKind, lang, contentType string, content []byte) {
+	sum := sha256.Sum256(content)
+	c.artifacts = append(c.artifacts, &Artifact{
+		ClientArtifact: domain.ClientArtifact{
+			Name:        name,
+			Kind:        kind,
+			Language:    lang,
+			ContentType: contentType,
+			Size:        len(content),
+			SHA256:      hex.EncodeToString(sum[:]),
+		},
+		Content: content,
+	})
+}
+
+// List возвращает описание файлов каталога без содержимого.
+func (c *Catalog) List() domain.ClientArtifacts {
+	result := domain.ClientArtifacts{APIVersion: c.version, Artifacts: make([]domain.ClientArtifact, 0, len(c.artifacts))}
+	for _, artifact := range c.artifacts {
+		result.Artifacts = append(result.Artifacts, artifact.ClientArtifact)
+	}
+	return result
+}
+
+// Get возвращает файл по имени; неизвестное имя - ErrArtifactNotFound.
+func (c *Catalog) Get(name string) (*Artifact, error) {
+	i := slices.IndexFunc(c.artifacts, func(a *Artifact) bool { return a.Name == name })
+	if i < 0 {
+		return nil, fmt.Errorf("%w: %s", ErrArtifactNotFound, name)
+	}
+	return c.artifacts[i], nil
+}
+
+// WriteInputs записывает в dir спецификацию, конфигурации и скрипт - все, что
+// нужно generate.sh. Так сборка образа получает ровно те файлы, что раздает сервис.
+func (c *Catalog) WriteInputs(dir string) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	for _, artifact := range c.artifacts {
+		if artifact.Kind == domain.ClientArtifactPackage {
+			continue
+		}
+		mode := fs.FileMode(0o644)
+		if strings.HasSuffix(artifact.Name, ".sh") {
+			mode = 0o755
+		}
+		if err := os.WriteFile(filepath.Join(dir, artifact.Name), artifact.Content, mode); err != nil {
+			return err
+		}
+	}
+	return nil
+}
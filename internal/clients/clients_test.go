@@ -0,0 +1,68 @@
+package clients
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"aggregator_db/pkg/domain"
+)
+
+func TestNewCatalog(t *testing.T) {
+	packages := t.TempDir()
+	if err := os.WriteFile(filepath.Join(packages, "python.tgz"), []byte("package"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	catalog, err := NewCatalog([]byte(`{"swagger":"2.0"}`), "1.10.0", packages)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	list := catalog.List()
+	var names []string
+	for _, artifact := range list.Artifacts {
+		names = append(names, artifact.Name)
+	}
+	if got, want := strings.Join(names, ","), "openapi.json,typescript.yaml,python.yaml,generate.sh,python.tgz"; got != want {
+		t.Errorf("artifacts = %s, want %s", got, want)
+	}
+
+	config, err := catalog.Get("typescript.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(config.Content), `npmVersion: "1.10.0"`) || strings.Contains(string(config.Content), "{{version}}") {
+		t.Errorf("version is not rendered:\n%s", config.Content)
+	}
+
+	pkg, err := catalog.Get("python.tgz")
+	if err != nil || pkg.Kind != domain.ClientArtifactPackage || pkg.Size != len("package") {
+		t.Errorf("Get(python.tgz) = %+v, %v", pkg, err)
+	}
+	if _, err := catalog.Get("go.tgz"); !errors.Is(err, ErrArtifactNotFound) {
+		t.Errorf("Get(go.tgz) error = %v, want ErrArtifactNotFound", err)
+	}
+
+	if _, err := NewCatalog([]byte("not json"), "1.10.0", ""); err == nil {
+		t.Error("invalid spec accepted")
+	}
+}
+
+func TestWriteInputs(t *testing.T) {
+	catalog, err := NewCatalog([]byte(`{}`), "1.10.0", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	if err := catalog.WriteInputs(dir); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{SpecName, "typescript.yaml", "python.yaml", "generate.sh"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+			t.Errorf("%s: %v", name, err)
+		}
+	}
+}
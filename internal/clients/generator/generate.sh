@@ -0,0 +1,15 @@
+#!/bin/sh
+# Собирает клиентские пакеты из openapi.json и конфигураций в текущем каталоге:
+#   sh generate.sh [каталог для пакетов]
+# OPENAPI_GENERATOR - команда openapi-generator-cli, по умолчанию из PATH.
+set -eu
+
+out="${1:-packages}"
+generator="${OPENAPI_GENERATOR:-openapi-generator-cli}"
+mkdir -p "$out"
+
+for lang in typescript python; do
+	rm -rf "$lang"
+	$generator generate -c "$lang.yaml"
+	tar -czf "$out/$lang.tgz" "$lang"
+done
@@ -0,0 +1,99 @@
+// Package clock - источник текущего времени для сервисов. На staging и в тестах
+// время можно заморозить или сдвинуть для всего процесса (CLOCK) или для одного
+// запроса (заголовок X-Debug-Time), чтобы детерминированно проверять истечение
+// подписок, напоминания и прогнозы.
+package clock
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// Clock возвращает текущее время.
+type Clock interface {
+	Now() time.Time
+}
+
+// System - системные часы.
+type System struct{}
+
+func (System) Now() time.Time {
+	return time.Now()
+}
+
+// Frozen всегда возвращает одно и то же время.
+type Frozen struct {
+	At time.Time
+}
+
+func (c Frozen) Now() time.Time {
+	return c.At
+}
+
+// Shifted идет вместе с Base, но сдвинут на By.
+type Shifted struct {
+	Base Clock
+	By   time.Duration
+}
+
+func (c Shifted) Now() time.Time {
+	return c.Base.Now().Add(c.By)
+}
+
+type holder struct {
+	clock Clock
+}
+
+var current atomic.Pointer[holder]
+
+// Set задает часы процесса; вызывается при старте.
+func Set(c Clock) {
+	current.Store(&holder{clock: c})
+}
+
+// Process возвращает часы процесса, по умолчанию системные.
+func Process() Clock {
+	if h := current.Load(); h != nil {
+		return h.clock
+	}
+	return System{}
+}
+
+type clockKey struct{}
+
+// WithClock подменяет часы для операций с контекстом ctx.
+func WithClock(ctx context.Context, c Clock) context.Context {
+	return context.WithValue(ctx, clockKey{}, c)
+}
+
+// Now возвращает текущее время в UTC по часам контекста, а без них - по часам процесса.
+func Now(ctx context.Context) time.Time {
+	if c, ok := ctx.Value(clockKey{}).(Clock); ok {
+		return c.Now().UTC()
+	}
+	return Process().Now().UTC()
+}
+
+// Parse разбирает настройку часов: пустая строка - base без изменений, время в
+// RFC 3339 - замороженные часы, длительность со знаком (+720h, -24h) - сдвиг base.
+func Parse(value string, base Clock) (Clock, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return base, nil
+	}
+	if strings.HasPrefix(value, "+") || strings.HasPrefix(value, "-") {
+		shift, err := time.ParseDuration(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid clock shift %q: %w", value, err)
+		}
+		return Shifted{Base: base, By: shift}, nil
+	}
+	at, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid clock %q, expected RFC 3339 time or signed duration", value)
+	}
+	return Frozen{At: at.UTC()}, nil
+}
@@ -3,47 +3,1026 @@ package config
 import (
 	"fmt"
 	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 )
 
 type Config struct {
-	ServerPort string
-	DBConfig   DatabaseConfig
-	LogLevel   string
+	ServerPort    string
+	DBConfig      DatabaseConfig
+	LogLevel      string
+	AdminToken    string
+	Dev           DevConfig
+	Events        EventsConfig
+	Scheduler     SchedulerConfig
+	Notifications NotificationsConfig
+	Tenancy       TenancyConfig
+	Metering      MeteringConfig
+	Developer     DeveloperConfig
+	Sandbox       SandboxConfig
+	Mail          MailConfig
+	Exports       ExportsConfig
+	Nudges        NudgesConfig
+	DataRepair    DataRepairConfig
+	Exchange      ExchangeConfig
+	WriteQueue    WriteQueueConfig
+	RetryAfter    RetryAfterConfig
+	Region        RegionConfig
+	// MigrationsDir - каталог с *.up.sql вместо встроенных в бинарник миграций;
+	// из них мигрируются база при старте, dev-база и схемы новых тенантов
+	MigrationsDir string
+	// MigrateOnStart применяет миграции к основной базе при старте
+	MigrateOnStart bool
+	// ClientsDir - каталог с собранными пакетами клиентов API (typescript.tgz,
+	// python.tgz), которые раздает /api/v1/clients
+	ClientsDir string
+	// ServiceKeyRateLimitPerMinute ограничивает запросы каждого ключа внутреннего
+	// сервиса в минуту на реплике; 0 - без лимита
+	ServiceKeyRateLimitPerMinute int
+	// ReadinessTimeout ограничивает каждую проверку зависимости в /readyz
+	ReadinessTimeout time.Duration
+	// ServerTiming добавляет к ответам заголовок Server-Timing с разбивкой времени запроса
+	ServerTiming bool
+	// RBACEnabled требует X-User-ID и ограничивает пользователей с ролью user их данными
+	RBACEnabled bool
+	// FieldVisibility - поля ответов, скрытые от ролей, если тенант не задал своих
+	// правил: "support=money,notes; user=backfill_note"
+	FieldVisibility string
+	// DisabledEndpoints - выключенные ручки: "DELETE /api/v1/subscriptions/{id}",
+	// без метода - все методы пути, "/*" в конце - все пути под префиксом ("/swagger/*")
+	DisabledEndpoints []string
+	Clock             ClockConfig
+	Compression       CompressionConfig
+	TLS               TLSConfig
+	Debug             DebugConfig
+	SLO               SLOConfig
+	Errors            ErrorTrackingConfig
+	Shadow            ShadowConfig
+	Migrations        SchemaMigrationConfig
+	BI                BIConfig
+	Deprecation       DeprecationConfig
+	Changes           ChangesConfig
+	MonthlyTotals     MonthlyTotalsConfig
+	Experiment        CalculateExperimentConfig
+}
+
+// MonthlyTotalsConfig - помесячные итоги подписок для расчета стоимости и сравнения
+// год к году. С Enabled прошлые месяцы берутся из итогов; задача планировщика раз
+// в Interval добавляет закончившиеся месяцы и пересчитывает до BatchSize
+// пользователей с изменившимися подписками за транзакцию.
+type MonthlyTotalsConfig struct {
+	Enabled   bool
+	Interval  time.Duration
+	BatchSize int
+}
+
+// ChangesConfig - лента изменений подписок (GET /subscriptions/changes). MaxWait
+// ограничивает ожидание изменений в одном запросе; PollInterval - как часто
+// ожидающий перечитывает журнал, чтобы заметить записи других реплик: о своих
+// реплика узнает сразу после коммита.
+type ChangesConfig struct {
+	MaxWait      time.Duration
+	PollInterval time.Duration
+}
+
+// DeprecationConfig - устаревшие ручки, параметры и поля. Surfaces дополняет
+// устаревшее из журнала изменений API (и переопределяет его даты) через ";":
+// "PUT /api/v1/subscriptions/{id} field=price since=2026-01-12 sunset=2026-12-01".
+// HeadersEnabled добавляет к их ответам заголовки Deprecation, Sunset и Link;
+// обращения считаются в метрике и без заголовков.
+type DeprecationConfig struct {
+	HeadersEnabled bool
+	Surfaces       string
+}
+
+// BIConfig - ежедневные сводки по тенантам (число подписок и траты месяца) для
+// внешних BI-систем. Сводка отправляется событием bi.daily_totals на каждый адрес
+// из URLs раз в Interval; без URLs выгрузка выключена.
+type BIConfig struct {
+	URLs []string
+	// Secret включает HMAC-подпись доставок, как у EVENTS_WEBHOOK_SECRET
+	Secret   string
+	Interval time.Duration
+}
+
+// SchemaMigrationConfig - фазы переходов колонок подписок без остановки (off,
+// dual_write, dual_read): Dates - месяцы MM-YYYY в даты, Money - цена в минорных
+// единицах в десятичную сумму. Пока переход пишет новые колонки, задача планировщика
+// раз в VerifyInterval сверяет их со старыми пачками по BatchSize подписок.
+type SchemaMigrationConfig struct {
+	Dates          string
+	Money          string
+	VerifyInterval time.Duration
+	BatchSize      int
+}
+
+// CalculateExperimentConfig - постепенный переход расчета стоимости на новую
+// реализацию Candidate (monthly_totals - расчет по помесячным итогам). Percent
+// расчетов отдает новая реализация, остальные - текущая; ComparePercent расчетов
+// повторяется другой реализацией в фоне со сравнением, не больше Concurrency
+// сразу. Без Candidate эксперимент выключен.
+type CalculateExperimentConfig struct {
+	Candidate      string
+	Percent        float64
+	ComparePercent float64
+	Concurrency    int
+	Timeout        time.Duration
+}
+
+// ShadowConfig - повтор доли GET-запросов на теневое развертывание (новая схема БД,
+// новый код) со сравнением ответов. Без URL режим выключен. IgnoreFields - поля JSON,
+// которые не сравниваются; Concurrency ограничивает число повторов в полете.
+type ShadowConfig struct {
+	URL          string
+	Percent      float64
+	IgnoreFields []string
+	Timeout      time.Duration
+	Concurrency  int
+}
+
+// ErrorTrackingConfig - отчеты о паниках, ответах 500 и записях лога уровня error
+// в Sentry-совместимый трекер. Без DSN отчеты не отправляются.
+type ErrorTrackingConfig struct {
+	DSN         string
+	Environment string
+	Release     string
+}
+
+// SLOConfig - цели маршрутов и оповещения о сгорании бюджета ошибок. Objectives -
+// цели через ";": "GET /api/v1/subscriptions/calculate availability=99.9 latency=300ms@99".
+// FastBurnRate - порог для окон 1h/5m (page), SlowBurnRate - для 6h/30m (ticket).
+type SLOConfig struct {
+	Objectives    string
+	FastBurnRate  float64
+	SlowBurnRate  float64
+	CheckInterval time.Duration
+}
+
+// DebugConfig - профилирование в продакшене. PprofEnabled открывает /debug/pprof и
+// POST /debug/dump за токеном администратора; DumpDir - каталог для снимков горутин
+// и кучи, их же по SIGUSR1 пишет процесс. MetricsUIEnabled открывает дашборд
+// /admin/metrics-ui с графиками по /metrics.
+type DebugConfig struct {
+	PprofEnabled     bool
+	DumpDir          string
+	MetricsUIEnabled bool
+}
+
+// TLSConfig - HTTPS без обратного прокси. Сервер слушает HTTPS, если заданы оба файла;
+// ReloadOnSIGHUP перечитывает сертификат и ключ по SIGHUP без перезапуска.
+type TLSConfig struct {
+	CertFile       string
+	KeyFile        string
+	ReloadOnSIGHUP bool
+}
+
+// Enabled сообщает, что сервер должен слушать HTTPS.
+func (c TLSConfig) Enabled() bool {
+	return c.CertFile != ""
+}
+
+// CompressionConfig - сжатие ответов gzip. Сжимаются ответы типов ContentTypes
+// размером от MinSize байт, если клиент передал Accept-Encoding: gzip.
+type CompressionConfig struct {
+	Enabled      bool
+	MinSize      int
+	ContentTypes []string
+}
+
+// ClockConfig - часы сервиса для тестов и staging. Time - время процесса: пусто -
+// системное, RFC 3339 - замороженное, длительность со знаком (+720h) - сдвиг.
+// HeaderEnabled разрешает администратору задавать время запроса заголовком X-Debug-Time.
+type ClockConfig struct {
+	Time          string
+	HeaderEnabled bool
+}
+
+// MeteringConfig - учет потребления API. Счетчики копятся в памяти и
+// сбрасываются в базу раз в FlushInterval.
+type MeteringConfig struct {
+	FlushInterval time.Duration
+}
+
+// DeveloperConfig - портал разработчиков. RateLimitPerMinute выдается новым
+// приложениям и считается на каждой реплике отдельно.
+type DeveloperConfig struct {
+	RateLimitPerMinute int
+}
+
+// SandboxConfig - песочница для ключей приложений. Данные сбрасываются
+// задачей планировщика раз в ResetInterval.
+type SandboxConfig struct {
+	ResetInterval time.Duration
+}
+
+// MailConfig - SMTP для писем. Без SMTPHost письма только пишутся в лог.
+type MailConfig struct {
+	SMTPHost string
+	SMTPPort string
+	Username string
+	Password string
+	From     string
+}
+
+// ExportsConfig - доставка выгрузок на почту. Выгрузки формирует задача
+// планировщика раз в DeliveryInterval, поэтому без SCHEDULER_ENABLED они не уходят.
+type ExportsConfig struct {
+	DeliveryInterval time.Duration
+	// LinkTTL - сколько живет ссылка на скачивание
+	LinkTTL time.Duration
+	// MaxAttachmentBytes - файлы больше отправляются ссылкой, даже если запрошено вложение
+	MaxAttachmentBytes int
+	// PublicURL - адрес сервиса для ссылок в письмах
+	PublicURL string
+}
+
+// NudgesConfig - подсказки администраторам. Правила (через запятую: rising_churn,
+// irregular_data) проверяет задача планировщика раз в Interval.
+type NudgesConfig struct {
+	Interval              time.Duration
+	Rules                 string
+	ChurnMinCancellations int
+	ChurnRisePercent      int
+	FutureStartMonths     int
+}
+
+// DataRepairConfig - исправления данных подписок. Fixes - через запятую пары
+// нарушение=исправление (end_before_start=clear_end_date); без пары нарушение только
+// попадает в отчет.
+type DataRepairConfig struct {
+	Fixes     string
+	BatchSize int
+}
+
+// ExchangeConfig - курсы для пересчета сумм в target_currency. Provider - cbr, ecb
+// или static; при недоступности банка используются StaticRates (к рублю, "USD=92.5,EUR=100.1").
+// CustomCurrencies - валюты вне встроенного списка с числом знаков: "BTC:8,ETH:8".
+type ExchangeConfig struct {
+	Provider         string
+	URL              string
+	StaticRates      string
+	CacheTTL         time.Duration
+	CustomCurrencies string
+}
+
+// WriteQueueConfig - прием записей при недоступной базе. Без Path режим выключен.
+// Очередь у каждой реплики своя, поэтому Path должен указывать на постоянный диск.
+type WriteQueueConfig struct {
+	Path           string
+	ReplayInterval time.Duration
+	MaxEntries     int
+}
+
+// RetryAfterConfig - подсказки Retry-After в ответах 429 и 5xx. При недоступной базе
+// подсказка удваивается от Min с каждой неудачей подряд; иначе растет от Min до Max
+// с числом одновременных запросов, достигая Max при MaxInFlight.
+type RetryAfterConfig struct {
+	Min         time.Duration
+	Max         time.Duration
+	MaxInFlight int
+}
+
+// RegionConfig - развертывание в нескольких регионах с двунаправленной репликацией.
+// Name помечает записи, события, метрики и логи; без него ручка приема изменений
+// из других регионов выключена. ConflictPolicy - last_writer_wins или local_wins.
+type RegionConfig struct {
+	Name           string
+	ConflictPolicy string
+}
+
+// TenancyConfig - изоляция enterprise-тенантов. Для каждого тенанта открывается
+// отдельный пул соединений, поэтому его размер ограничен отдельно.
+type TenancyConfig struct {
+	PoolMaxConns int
+}
+
+// SchedulerConfig - фоновые задачи. По умолчанию выключены: при нескольких
+// репликах планировщик должен работать только на одной.
+type SchedulerConfig struct {
+	Enabled                 bool
+	SpendComparisonInterval time.Duration
+	RenewalInterval         time.Duration
+	BudgetCheckInterval     time.Duration
+	DuplicateScanInterval   time.Duration
+	// StatementInterval - как часто проверять, закрыт ли прошлый месяц ежемесячных выписок
+	StatementInterval time.Duration
+	// AlertEscalationInterval - как часто эскалировать неподтвержденные критические уведомления
+	AlertEscalationInterval time.Duration
+}
+
+type NotificationsConfig struct {
+	// SpendAlertThresholdPercent - порог изменения трат, если пользователь не задал свой
+	SpendAlertThresholdPercent int
+}
+
+// EventsConfig - доставка доменных событий. Без WebhookURL события только пишутся в лог.
+type EventsConfig struct {
+	WebhookURL string
+	// WebhookSecret включает HMAC-подпись доставок в заголовке X-Webhook-Signature
+	WebhookSecret string
+}
+
+// DevConfig - настройки локального режима разработки (go run ./cmd/api --dev).
+type DevConfig struct {
+	EmbeddedPostgres bool
+	EmbeddedPort     string
+	DataDir          string
 }
 
 type DatabaseConfig struct {
+	// Driver - хранилище подписок: postgres или mysql. Остальные данные всегда в Postgres
+	Driver string
+	// MySQLDSN - строка подключения go-sql-driver/mysql; обязательна при Driver=mysql
+	MySQLDSN string
+
 	Host     string
 	Port     string
 	User     string
 	Password string
 	DBName   string
 	SSLMode  string
+
+	SlowQueryThreshold time.Duration
+	// SlowStatementThreshold - порог отдельного SQL-запроса для лога медленных запросов; 0 - выключено
+	SlowStatementThreshold time.Duration
+	MaxRetries             int
+	RetryBackoff           time.Duration
+	// RetryMaxBackoff ограничивает паузу между повторами, которая удваивается с каждым повтором
+	RetryMaxBackoff time.Duration
+	// ExplainMode - снятие EXPLAIN (ANALYZE) запросов: off, header (по X-Debug-Explain) или all
+	ExplainMode string
+	// ReplicaDSN - строка подключения к реплике только для чтения; пусто - реплики нет
+	ReplicaDSN string
+	// ReplicaRetryInterval - сколько чтения идут в основную базу после отказа реплики
+	ReplicaRetryInterval time.Duration
+	// CalculateCacheTTL - сколько живет расчет стоимости в кэше; 0 - кэш выключен
+	CalculateCacheTTL time.Duration
+	// CalculateCacheMaxEntries - сколько расчетов стоимости хранит кэш
+	CalculateCacheMaxEntries int
+	// SearchSimilarityThreshold - порог похожести нечеткого поиска (match=fuzzy) от 0 до 1;
+	// задается соединениям пулов параметром pg_trgm.similarity_threshold
+	SearchSimilarityThreshold float64
+	Pool                      PoolConfig
 }
 
+// PoolConfig - настройки пулов pgxpool основной базы и реплики; нулевое значение
+// оставляет значение pgx по умолчанию.
+type PoolConfig struct {
+	MaxConns int
+	MinConns int
+	// MaxConnLifetime - через сколько соединение закрывается и открывается заново
+	MaxConnLifetime time.Duration
+	// MaxConnIdleTime - через сколько простоя закрывается соединение сверх MinConns
+	MaxConnIdleTime time.Duration
+	// HealthCheckPeriod - как часто пул проверяет простаивающие соединения
+	HealthCheckPeriod time.Duration
+	// ConnectTimeout ограничивает открытие соединения
+	ConnectTimeout time.Duration
+}
+
+// endpointPattern - запись DISABLED_ENDPOINTS: необязательный метод и путь.
+var endpointPattern = regexp.MustCompile(`^((GET|POST|PUT|PATCH|DELETE) )?/[A-Za-z0-9_{}/.*-]*$`)
+
 func Load() (*Config, error) {
-	if err := godotenv.Load(); err != nil {
-		// В продакшене .env может отсутствовать
-		fmt.Println("Warning: .env file not found")
+	// В продакшене .env может отсутствовать: настройки приходят из окружения
+	_ = godotenv.Load()
+
+	slowQueryThreshold, err := getEnvDuration("DB_SLOW_QUERY_THRESHOLD", 200*time.Millisecond)
+	if err != nil {
+		return nil, err
+	}
+	slowStatementThreshold, err := getEnvDuration("DB_SLOW_STATEMENT_THRESHOLD", 500*time.Millisecond)
+	if err != nil {
+		return nil, err
+	}
+	if slowStatementThreshold < 0 {
+		return nil, fmt.Errorf("invalid DB_SLOW_STATEMENT_THRESHOLD: %s, expected a non-negative duration", slowStatementThreshold)
+	}
+	maxRetries, err := getEnvInt("DB_MAX_RETRIES", 2)
+	if err != nil {
+		return nil, err
+	}
+	retryBackoff, err := getEnvDuration("DB_RETRY_BACKOFF", 50*time.Millisecond)
+	if err != nil {
+		return nil, err
+	}
+	retryMaxBackoff, err := getEnvDuration("DB_RETRY_MAX_BACKOFF", time.Second)
+	if err != nil {
+		return nil, err
+	}
+	if maxRetries < 0 {
+		return nil, fmt.Errorf("invalid DB_MAX_RETRIES: %d, expected a non-negative number", maxRetries)
+	}
+	if retryMaxBackoff < 0 {
+		return nil, fmt.Errorf("invalid DB_RETRY_MAX_BACKOFF: %s, expected a non-negative duration", retryMaxBackoff)
+	}
+	replicaRetryInterval, err := getEnvDuration("DB_REPLICA_RETRY_INTERVAL", 10*time.Second)
+	if err != nil {
+		return nil, err
+	}
+	calculateCacheTTL, err := getEnvDuration("CALCULATE_CACHE_TTL", 30*time.Second)
+	if err != nil {
+		return nil, err
+	}
+	calculateCacheMaxEntries, err := getEnvInt("CALCULATE_CACHE_MAX_ENTRIES", 10000)
+	if err != nil {
+		return nil, err
+	}
+	if calculateCacheTTL < 0 || calculateCacheMaxEntries < 0 {
+		return nil, fmt.Errorf("invalid CALCULATE_CACHE_TTL or CALCULATE_CACHE_MAX_ENTRIES: expected non-negative values")
+	}
+	searchSimilarityThreshold, err := getEnvFloat("DB_SEARCH_SIMILARITY_THRESHOLD", 0.3)
+	if err != nil {
+		return nil, err
+	}
+	if searchSimilarityThreshold <= 0 || searchSimilarityThreshold > 1 {
+		return nil, fmt.Errorf("invalid DB_SEARCH_SIMILARITY_THRESHOLD: %v, expected a value in (0, 1]", searchSimilarityThreshold)
+	}
+	pool, err := loadPoolConfig()
+	if err != nil {
+		return nil, err
+	}
+	driver := getEnv("DB_DRIVER", "postgres")
+	if driver != "postgres" && driver != "mysql" {
+		return nil, fmt.Errorf("invalid DB_DRIVER: %s, expected postgres or mysql", driver)
+	}
+	mysqlDSN := getEnv("DB_MYSQL_DSN", "")
+	if driver == "mysql" && mysqlDSN == "" {
+		return nil, fmt.Errorf("DB_MYSQL_DSN is required when DB_DRIVER is mysql")
+	}
+	explainMode := getEnv("DB_EXPLAIN", "off")
+	if explainMode != "off" && explainMode != "header" && explainMode != "all" {
+		return nil, fmt.Errorf("invalid DB_EXPLAIN: %q, expected off, header or all", explainMode)
+	}
+
+	embeddedPostgres, err := getEnvBool("DEV_EMBEDDED_POSTGRES", true)
+	if err != nil {
+		return nil, err
+	}
+
+	schedulerEnabled, err := getEnvBool("SCHEDULER_ENABLED", false)
+	if err != nil {
+		return nil, err
+	}
+	spendComparisonInterval, err := getEnvDuration("SPEND_COMPARISON_INTERVAL", 24*time.Hour)
+	if err != nil {
+		return nil, err
+	}
+	renewalInterval, err := getEnvDuration("RENEWAL_INTERVAL", time.Hour)
+	if err != nil {
+		return nil, err
+	}
+	budgetCheckInterval, err := getEnvDuration("BUDGET_CHECK_INTERVAL", time.Hour)
+	if err != nil {
+		return nil, err
+	}
+	duplicateScanInterval, err := getEnvDuration("DUPLICATE_SCAN_INTERVAL", 24*time.Hour)
+	if err != nil {
+		return nil, err
+	}
+	statementInterval, err := getEnvDuration("STATEMENT_INTERVAL", time.Hour)
+	if err != nil {
+		return nil, err
+	}
+	alertEscalationInterval, err := getEnvDuration("ALERT_ESCALATION_INTERVAL", 15*time.Minute)
+	if err != nil {
+		return nil, err
+	}
+	spendAlertThreshold, err := getEnvInt("SPEND_ALERT_THRESHOLD_PERCENT", 20)
+	if err != nil {
+		return nil, err
+	}
+
+	tenantPoolMaxConns, err := getEnvInt("TENANT_POOL_MAX_CONNS", 4)
+	if err != nil {
+		return nil, err
+	}
+
+	meteringFlushInterval, err := getEnvDuration("METERING_FLUSH_INTERVAL", 30*time.Second)
+	if err != nil {
+		return nil, err
+	}
+
+	developerRateLimit, err := getEnvInt("DEVELOPER_RATE_LIMIT_PER_MINUTE", 60)
+	if err != nil {
+		return nil, err
+	}
+	serviceKeyRateLimit, err := getEnvInt("SERVICE_KEY_RATE_LIMIT_PER_MINUTE", 0)
+	if err != nil {
+		return nil, err
+	}
+	if serviceKeyRateLimit < 0 {
+		return nil, fmt.Errorf("invalid SERVICE_KEY_RATE_LIMIT_PER_MINUTE: %d, expected a non-negative number", serviceKeyRateLimit)
+	}
+
+	sandboxResetInterval, err := getEnvDuration("SANDBOX_RESET_INTERVAL", 24*time.Hour)
+	if err != nil {
+		return nil, err
+	}
+
+	exportDeliveryInterval, err := getEnvDuration("EXPORT_DELIVERY_INTERVAL", time.Minute)
+	if err != nil {
+		return nil, err
+	}
+	exportLinkTTL, err := getEnvDuration("EXPORT_LINK_TTL", 72*time.Hour)
+	if err != nil {
+		return nil, err
+	}
+	exportMaxAttachment, err := getEnvInt("EXPORT_MAX_ATTACHMENT_BYTES", 5<<20)
+	if err != nil {
+		return nil, err
+	}
+
+	nudgeInterval, err := getEnvDuration("NUDGE_INTERVAL", 24*time.Hour)
+	if err != nil {
+		return nil, err
+	}
+	nudgeChurnMin, err := getEnvInt("NUDGE_CHURN_MIN_CANCELLATIONS", 5)
+	if err != nil {
+		return nil, err
+	}
+	nudgeChurnRise, err := getEnvInt("NUDGE_CHURN_RISE_PERCENT", 50)
+	if err != nil {
+		return nil, err
+	}
+	nudgeFutureStart, err := getEnvInt("NUDGE_FUTURE_START_MONTHS", 12)
+	if err != nil {
+		return nil, err
+	}
+
+	dataRepairBatch, err := getEnvInt("DATA_REPAIR_BATCH_SIZE", 500)
+	if err != nil {
+		return nil, err
+	}
+	if dataRepairBatch <= 0 {
+		return nil, fmt.Errorf("invalid DATA_REPAIR_BATCH_SIZE: %d, expected a positive number", dataRepairBatch)
+	}
+
+	exchangeCacheTTL, err := getEnvDuration("EXCHANGE_RATES_CACHE_TTL", time.Hour)
+	if err != nil {
+		return nil, err
+	}
+
+	writeQueueReplayInterval, err := getEnvDuration("WRITE_QUEUE_REPLAY_INTERVAL", 5*time.Second)
+	if err != nil {
+		return nil, err
+	}
+	writeQueueMaxEntries, err := getEnvInt("WRITE_QUEUE_MAX_ENTRIES", 10000)
+	if err != nil {
+		return nil, err
+	}
+
+	retryAfterMin, err := getEnvDuration("RETRY_AFTER_MIN", time.Second)
+	if err != nil {
+		return nil, err
+	}
+	retryAfterMax, err := getEnvDuration("RETRY_AFTER_MAX", time.Minute)
+	if err != nil {
+		return nil, err
+	}
+	retryAfterMaxInFlight, err := getEnvInt("RETRY_AFTER_MAX_IN_FLIGHT", 200)
+	if err != nil {
+		return nil, err
+	}
+
+	regionName := getEnv("REGION", "")
+	if len(regionName) > 32 {
+		return nil, fmt.Errorf("invalid REGION: %q, expected at most 32 characters", regionName)
+	}
+
+	serverTiming, err := getEnvBool("SERVER_TIMING_ENABLED", true)
+	if err != nil {
+		return nil, err
+	}
+	migrateOnStart, err := getEnvBool("MIGRATE_ON_START", false)
+	if err != nil {
+		return nil, err
+	}
+	deprecationHeaders, err := getEnvBool("DEPRECATION_HEADERS_ENABLED", false)
+	if err != nil {
+		return nil, err
+	}
+	rbacEnabled, err := getEnvBool("RBAC_ENABLED", false)
+	if err != nil {
+		return nil, err
+	}
+	clockHeaderEnabled, err := getEnvBool("CLOCK_HEADER_ENABLED", false)
+	if err != nil {
+		return nil, err
+	}
+	compressionEnabled, err := getEnvBool("COMPRESSION_ENABLED", true)
+	if err != nil {
+		return nil, err
+	}
+	compressionMinSize, err := getEnvInt("COMPRESSION_MIN_SIZE", 1024)
+	if err != nil {
+		return nil, err
+	}
+	tlsCertFile, tlsKeyFile := getEnv("TLS_CERT_FILE", ""), getEnv("TLS_KEY_FILE", "")
+	if (tlsCertFile == "") != (tlsKeyFile == "") {
+		return nil, fmt.Errorf("TLS_CERT_FILE and TLS_KEY_FILE must be set together")
+	}
+	tlsReload, err := getEnvBool("TLS_RELOAD_ON_SIGHUP", false)
+	if err != nil {
+		return nil, err
+	}
+	pprofEnabled, err := getEnvBool("PPROF_ENABLED", false)
+	if err != nil {
+		return nil, err
+	}
+	metricsUIEnabled, err := getEnvBool("METRICS_UI_ENABLED", false)
+	if err != nil {
+		return nil, err
+	}
+	sloFastBurnRate, err := getEnvFloat("SLO_FAST_BURN_RATE", 14.4)
+	if err != nil {
+		return nil, err
+	}
+	sloSlowBurnRate, err := getEnvFloat("SLO_SLOW_BURN_RATE", 6)
+	if err != nil {
+		return nil, err
+	}
+	if sloFastBurnRate <= 0 || sloSlowBurnRate <= 0 {
+		return nil, fmt.Errorf("invalid SLO_FAST_BURN_RATE/SLO_SLOW_BURN_RATE: %v/%v, expected positive numbers", sloFastBurnRate, sloSlowBurnRate)
+	}
+	sloCheckInterval, err := getEnvDuration("SLO_CHECK_INTERVAL", time.Minute)
+	if err != nil {
+		return nil, err
+	}
+	if sloCheckInterval <= 0 {
+		return nil, fmt.Errorf("invalid SLO_CHECK_INTERVAL: %s, expected a positive duration", sloCheckInterval)
+	}
+	shadowPercent, err := getEnvFloat("SHADOW_PERCENT", 1)
+	if err != nil {
+		return nil, err
+	}
+	if shadowPercent < 0 || shadowPercent > 100 {
+		return nil, fmt.Errorf("invalid SHADOW_PERCENT: %v, expected a percent between 0 and 100", shadowPercent)
+	}
+	shadowTimeout, err := getEnvDuration("SHADOW_TIMEOUT", 5*time.Second)
+	if err != nil {
+		return nil, err
+	}
+	shadowConcurrency, err := getEnvInt("SHADOW_CONCURRENCY", 8)
+	if err != nil {
+		return nil, err
+	}
+	if shadowConcurrency <= 0 {
+		return nil, fmt.Errorf("invalid SHADOW_CONCURRENCY: %d, expected a positive number", shadowConcurrency)
+	}
+	readinessTimeout, err := getEnvDuration("READINESS_TIMEOUT", time.Second)
+	if err != nil {
+		return nil, err
+	}
+	if readinessTimeout <= 0 {
+		return nil, fmt.Errorf("invalid READINESS_TIMEOUT: %s, expected a positive duration", readinessTimeout)
+	}
+	migrationVerifyInterval, err := getEnvDuration("MIGRATION_VERIFY_INTERVAL", time.Hour)
+	if err != nil {
+		return nil, err
+	}
+	if migrationVerifyInterval <= 0 {
+		return nil, fmt.Errorf("invalid MIGRATION_VERIFY_INTERVAL: %s, expected a positive duration", migrationVerifyInterval)
+	}
+	migrationBatchSize, err := getEnvInt("MIGRATION_BATCH_SIZE", 1000)
+	if err != nil {
+		return nil, err
+	}
+	if migrationBatchSize <= 0 {
+		return nil, fmt.Errorf("invalid MIGRATION_BATCH_SIZE: %d, expected a positive number", migrationBatchSize)
+	}
+	biInterval, err := getEnvDuration("BI_EXPORT_INTERVAL", 24*time.Hour)
+	if err != nil {
+		return nil, err
+	}
+	if biInterval <= 0 {
+		return nil, fmt.Errorf("invalid BI_EXPORT_INTERVAL: %s, expected a positive duration", biInterval)
+	}
+	changesMaxWait, err := getEnvDuration("CHANGES_MAX_WAIT", time.Minute)
+	if err != nil {
+		return nil, err
+	}
+	if changesMaxWait < 0 {
+		return nil, fmt.Errorf("invalid CHANGES_MAX_WAIT: %s, expected a non-negative duration", changesMaxWait)
+	}
+	changesPollInterval, err := getEnvDuration("CHANGES_POLL_INTERVAL", 2*time.Second)
+	if err != nil {
+		return nil, err
+	}
+	if changesPollInterval <= 0 {
+		return nil, fmt.Errorf("invalid CHANGES_POLL_INTERVAL: %s, expected a positive duration", changesPollInterval)
+	}
+	var biURLs []string
+	for _, url := range strings.Split(getEnv("BI_WEBHOOK_URLS", ""), ",") {
+		if url = strings.TrimSpace(url); url != "" {
+			biURLs = append(biURLs, url)
+		}
+	}
+	var shadowIgnore []string
+	for _, field := range strings.Split(getEnv("SHADOW_IGNORE_FIELDS", ""), ",") {
+		if field = strings.TrimSpace(field); field != "" {
+			shadowIgnore = append(shadowIgnore, field)
+		}
+	}
+	monthlyTotalsEnabled, err := getEnvBool("MONTHLY_TOTALS_ENABLED", false)
+	if err != nil {
+		return nil, err
+	}
+	monthlyTotalsInterval, err := getEnvDuration("MONTHLY_TOTALS_INTERVAL", time.Minute)
+	if err != nil {
+		return nil, err
+	}
+	if monthlyTotalsInterval <= 0 {
+		return nil, fmt.Errorf("invalid MONTHLY_TOTALS_INTERVAL: %s, expected a positive duration", monthlyTotalsInterval)
+	}
+	monthlyTotalsBatch, err := getEnvInt("MONTHLY_TOTALS_BATCH_SIZE", 500)
+	if err != nil {
+		return nil, err
+	}
+	if monthlyTotalsBatch <= 0 {
+		return nil, fmt.Errorf("invalid MONTHLY_TOTALS_BATCH_SIZE: %d, expected a positive number", monthlyTotalsBatch)
+	}
+	experimentCandidate := getEnv("CALCULATE_EXPERIMENT", "")
+	switch experimentCandidate {
+	case "":
+	case "monthly_totals":
+		if !monthlyTotalsEnabled {
+			return nil, fmt.Errorf("invalid CALCULATE_EXPERIMENT: monthly_totals requires MONTHLY_TOTALS_ENABLED")
+		}
+	default:
+		return nil, fmt.Errorf("invalid CALCULATE_EXPERIMENT: %q, expected monthly_totals", experimentCandidate)
+	}
+	experimentPercent, err := getEnvFloat("CALCULATE_EXPERIMENT_PERCENT", 0)
+	if err != nil {
+		return nil, err
+	}
+	if experimentPercent < 0 || experimentPercent > 100 {
+		return nil, fmt.Errorf("invalid CALCULATE_EXPERIMENT_PERCENT: %v, expected a percent between 0 and 100", experimentPercent)
+	}
+	experimentComparePercent, err := getEnvFloat("CALCULATE_EXPERIMENT_COMPARE_PERCENT", 100)
+	if err != nil {
+		return nil, err
+	}
+	if experimentComparePercent < 0 || experimentComparePercent > 100 {
+		return nil, fmt.Errorf("invalid CALCULATE_EXPERIMENT_COMPARE_PERCENT: %v, expected a percent between 0 and 100", experimentComparePercent)
+	}
+	experimentConcurrency, err := getEnvInt("CALCULATE_EXPERIMENT_CONCURRENCY", 4)
+	if err != nil {
+		return nil, err
+	}
+	if experimentConcurrency <= 0 {
+		return nil, fmt.Errorf("invalid CALCULATE_EXPERIMENT_CONCURRENCY: %d, expected a positive number", experimentConcurrency)
+	}
+	experimentTimeout, err := getEnvDuration("CALCULATE_EXPERIMENT_TIMEOUT", 30*time.Second)
+	if err != nil {
+		return nil, err
+	}
+	if experimentTimeout <= 0 {
+		return nil, fmt.Errorf("invalid CALCULATE_EXPERIMENT_TIMEOUT: %s, expected a positive duration", experimentTimeout)
+	}
+	var disabledEndpoints []string
+	for _, endpoint := range strings.Split(getEnv("DISABLED_ENDPOINTS", ""), ",") {
+		if endpoint = strings.TrimSpace(endpoint); endpoint == "" {
+			continue
+		}
+		if !endpointPattern.MatchString(endpoint) {
+			return nil, fmt.Errorf("invalid DISABLED_ENDPOINTS entry %q, expected \"[METHOD] /path\"", endpoint)
+		}
+		disabledEndpoints = append(disabledEndpoints, endpoint)
+	}
+	var compressionTypes []string
+	for _, contentType := range strings.Split(getEnv("COMPRESSION_CONTENT_TYPES", "application/json"), ",") {
+		if contentType = strings.TrimSpace(contentType); contentType != "" {
+			compressionTypes = append(compressionTypes, contentType)
+		}
 	}
 
 	config := &Config{
-		ServerPort: getEnv("SERVER_PORT", "8080"),
-		LogLevel:   getEnv("LOG_LEVEL", "info"),
+		ServerPort:                   getEnv("SERVER_PORT", "8080"),
+		LogLevel:                     getEnv("LOG_LEVEL", "info"),
+		AdminToken:                   getEnv("ADMIN_TOKEN", ""),
+		ServerTiming:                 serverTiming,
+		RBACEnabled:                  rbacEnabled,
+		FieldVisibility:              getEnv("FIELD_VISIBILITY", "support=money"),
+		MigrationsDir:                getEnv("MIGRATIONS_DIR", ""),
+		MigrateOnStart:               migrateOnStart,
+		ClientsDir:                   getEnv("CLIENTS_DIR", "clients"),
+		ReadinessTimeout:             readinessTimeout,
+		ServiceKeyRateLimitPerMinute: serviceKeyRateLimit,
+		DisabledEndpoints:            disabledEndpoints,
+		Compression: CompressionConfig{
+			Enabled:      compressionEnabled,
+			MinSize:      compressionMinSize,
+			ContentTypes: compressionTypes,
+		},
+		TLS: TLSConfig{
+			CertFile:       tlsCertFile,
+			KeyFile:        tlsKeyFile,
+			ReloadOnSIGHUP: tlsReload,
+		},
+		Debug: DebugConfig{
+			PprofEnabled:     pprofEnabled,
+			DumpDir:          getEnv("DEBUG_DUMP_DIR", filepath.Join(os.TempDir(), "aggregator-dumps")),
+			MetricsUIEnabled: metricsUIEnabled,
+		},
+		Migrations: SchemaMigrationConfig{
+			Dates:          getEnv("MIGRATION_DATES", "off"),
+			Money:          getEnv("MIGRATION_MONEY", "off"),
+			VerifyInterval: migrationVerifyInterval,
+			BatchSize:      migrationBatchSize,
+		},
+		Deprecation: DeprecationConfig{
+			HeadersEnabled: deprecationHeaders,
+			Surfaces:       getEnv("DEPRECATIONS", ""),
+		},
+		Changes: ChangesConfig{
+			MaxWait:      changesMaxWait,
+			PollInterval: changesPollInterval,
+		},
+		MonthlyTotals: MonthlyTotalsConfig{
+			Enabled:   monthlyTotalsEnabled,
+			Interval:  monthlyTotalsInterval,
+			BatchSize: monthlyTotalsBatch,
+		},
+		Experiment: CalculateExperimentConfig{
+			Candidate:      experimentCandidate,
+			Percent:        experimentPercent,
+			ComparePercent: experimentComparePercent,
+			Concurrency:    experimentConcurrency,
+			Timeout:        experimentTimeout,
+		},
+		BI: BIConfig{
+			URLs:     biURLs,
+			Secret:   getEnv("BI_WEBHOOK_SECRET", ""),
+			Interval: biInterval,
+		},
+		Shadow: ShadowConfig{
+			URL:          getEnv("SHADOW_URL", ""),
+			Percent:      shadowPercent,
+			IgnoreFields: shadowIgnore,
+			Timeout:      shadowTimeout,
+			Concurrency:  shadowConcurrency,
+		},
+		Errors: ErrorTrackingConfig{
+			DSN:         getEnv("SENTRY_DSN", ""),
+			Environment: getEnv("SENTRY_ENVIRONMENT", "production"),
+			Release:     getEnv("SENTRY_RELEASE", ""),
+		},
+		SLO: SLOConfig{
+			Objectives:    getEnv("SLO_OBJECTIVES", ""),
+			FastBurnRate:  sloFastBurnRate,
+			SlowBurnRate:  sloSlowBurnRate,
+			CheckInterval: sloCheckInterval,
+		},
+		Clock: ClockConfig{
+			Time:          getEnv("CLOCK", ""),
+			HeaderEnabled: clockHeaderEnabled,
+		},
+		Tenancy: TenancyConfig{
+			PoolMaxConns: tenantPoolMaxConns,
+		},
+		Metering: MeteringConfig{
+			FlushInterval: meteringFlushInterval,
+		},
+		Developer: DeveloperConfig{
+			RateLimitPerMinute: developerRateLimit,
+		},
+		Sandbox: SandboxConfig{
+			ResetInterval: sandboxResetInterval,
+		},
+		Mail: MailConfig{
+			SMTPHost: getEnv("SMTP_HOST", ""),
+			SMTPPort: getEnv("SMTP_PORT", "587"),
+			Username: getEnv("SMTP_USERNAME", ""),
+			Password: getEnv("SMTP_PASSWORD", ""),
+			From:     getEnv("MAIL_FROM", "noreply@example.com"),
+		},
+		Exports: ExportsConfig{
+			DeliveryInterval:   exportDeliveryInterval,
+			LinkTTL:            exportLinkTTL,
+			MaxAttachmentBytes: exportMaxAttachment,
+			PublicURL:          getEnv("PUBLIC_URL", "http://localhost:8080"),
+		},
+		Nudges: NudgesConfig{
+			Interval:              nudgeInterval,
+			Rules:                 getEnv("NUDGE_RULES", "rising_churn,irregular_data"),
+			ChurnMinCancellations: nudgeChurnMin,
+			ChurnRisePercent:      nudgeChurnRise,
+			FutureStartMonths:     nudgeFutureStart,
+		},
+		DataRepair: DataRepairConfig{
+			Fixes:     getEnv("DATA_REPAIR_FIXES", ""),
+			BatchSize: dataRepairBatch,
+		},
+		Exchange: ExchangeConfig{
+			Provider:         getEnv("EXCHANGE_RATES_PROVIDER", "cbr"),
+			URL:              getEnv("EXCHANGE_RATES_URL", ""),
+			StaticRates:      getEnv("EXCHANGE_STATIC_RATES", ""),
+			CacheTTL:         exchangeCacheTTL,
+			CustomCurrencies: getEnv("CUSTOM_CURRENCIES", ""),
+		},
+		WriteQueue: WriteQueueConfig{
+			Path:           getEnv("WRITE_QUEUE_PATH", ""),
+			ReplayInterval: writeQueueReplayInterval,
+			MaxEntries:     writeQueueMaxEntries,
+		},
+		RetryAfter: RetryAfterConfig{
+			Min:         retryAfterMin,
+			Max:         retryAfterMax,
+			MaxInFlight: retryAfterMaxInFlight,
+		},
+		Region: RegionConfig{
+			Name:           regionName,
+			ConflictPolicy: getEnv("REGION_CONFLICT_POLICY", "last_writer_wins"),
+		},
+		Events: EventsConfig{
+			WebhookURL:    getEnv("EVENTS_WEBHOOK_URL", ""),
+			WebhookSecret: getEnv("EVENTS_WEBHOOK_SECRET", ""),
+		},
+		Scheduler: SchedulerConfig{
+			Enabled:                 schedulerEnabled,
+			SpendComparisonInterval: spendComparisonInterval,
+			RenewalInterval:         renewalInterval,
+			BudgetCheckInterval:     budgetCheckInterval,
+			DuplicateScanInterval:   duplicateScanInterval,
+			StatementInterval:       statementInterval,
+			AlertEscalationInterval: alertEscalationInterval,
+		},
+		Notifications: NotificationsConfig{
+			SpendAlertThresholdPercent: spendAlertThreshold,
+		},
+		Dev: DevConfig{
+			EmbeddedPostgres: embeddedPostgres,
+			EmbeddedPort:     getEnv("DEV_DB_PORT", "5433"),
+			DataDir:          getEnv("DEV_DATA_DIR", ""),
+		},
 		DBConfig: DatabaseConfig{
+			Driver:   driver,
+			MySQLDSN: mysqlDSN,
 			Host:     getEnv("DB_HOST", "localhost"),
 			Port:     getEnv("DB_PORT", "5432"),
 			User:     getEnv("DB_USER", "postgres"),
 			Password: getEnv("DB_PASSWORD", "postgres"),
 			DBName:   getEnv("DB_NAME", "subscriptions"),
 			SSLMode:  getEnv("DB_SSLMODE", "disable"),
+
+			SlowQueryThreshold:        slowQueryThreshold,
+			SlowStatementThreshold:    slowStatementThreshold,
+			MaxRetries:                maxRetries,
+			RetryBackoff:              retryBackoff,
+			RetryMaxBackoff:           retryMaxBackoff,
+			ExplainMode:               explainMode,
+			ReplicaDSN:                getEnv("DB_REPLICA_DSN", ""),
+			ReplicaRetryInterval:      replicaRetryInterval,
+			CalculateCacheTTL:         calculateCacheTTL,
+			CalculateCacheMaxEntries:  calculateCacheMaxEntries,
+			SearchSimilarityThreshold: searchSimilarityThreshold,
+			Pool:                      pool,
 		},
 	}
 
 	return config, nil
 }
 
+func loadPoolConfig() (PoolConfig, error) {
+	var pool PoolConfig
+	var err error
+	if pool.MaxConns, err = getEnvInt("DB_MAX_CONNS", 0); err != nil {
+		return pool, err
+	}
+	if pool.MinConns, err = getEnvInt("DB_MIN_CONNS", 0); err != nil {
+		return pool, err
+	}
+	if pool.MaxConns < 0 || pool.MinConns < 0 {
+		return pool, fmt.Errorf("invalid DB_MAX_CONNS or DB_MIN_CONNS: expected non-negative numbers")
+	}
+	if pool.MaxConns > 0 && pool.MinConns > pool.MaxConns {
+		return pool, fmt.Errorf("invalid DB_MIN_CONNS: %d, expected at most DB_MAX_CONNS (%d)", pool.MinConns, pool.MaxConns)
+	}
+	for _, setting := range []struct {
+		key    string
+		target *time.Duration
+	}{
+		{"DB_MAX_CONN_LIFETIME", &pool.MaxConnLifetime},
+		{"DB_MAX_CONN_IDLE_TIME", &pool.MaxConnIdleTime},
+		{"DB_HEALTH_CHECK_PERIOD", &pool.HealthCheckPeriod},
+		{"DB_CONNECT_TIMEOUT", &pool.ConnectTimeout},
+	} {
+		if *setting.target, err = getEnvDuration(setting.key, 0); err != nil {
+			return pool, err
+		}
+		if *setting.target < 0 {
+			return pool, fmt.Errorf("invalid %s: %s, expected a non-negative duration", setting.key, *setting.target)
+		}
+	}
+	return pool, nil
+}
+
 func (c *Config) DSN() string {
 	return fmt.Sprintf(
 		"host=%s port=%s user=%s password=%s dbname=%s sslmode=%s",
@@ -62,3 +1041,51 @@ func getEnv(key, defaultValue string) string {
 	}
 	return defaultValue
 }
+
+func getEnvInt(key string, defaultValue int) (int, error) {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue, nil
+	}
+	parsed, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s: %w", key, err)
+	}
+	return parsed, nil
+}
+
+func getEnvDuration(key string, defaultValue time.Duration) (time.Duration, error) {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue, nil
+	}
+	parsed, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s: %w", key, err)
+	}
+	return parsed, nil
+}
+
+func getEnvFloat(key string, defaultValue float64) (float64, error) {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue, nil
+	}
+	parsed, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s: %w", key, err)
+	}
+	return parsed, nil
+}
+
+func getEnvBool(key string, defaultValue bool) (bool, error) {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue, nil
+	}
+	parsed, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, fmt.Errorf("invalid %s: %w", key, err)
+	}
+	return parsed, nil
+}
@@ -0,0 +1,21 @@
+// Package developer передает приложение портала разработчиков через context.Context.
+package developer
+
+import (
+	"context"
+
+	"aggregator_db/pkg/domain"
+)
+
+type contextKey struct{}
+
+func WithApp(ctx context.Context, app *domain.DeveloperApp) context.Context {
+	return context.WithValue(ctx, contextKey{}, app)
+}
+
+// FromContext возвращает приложение, от имени которого выполняется запрос,
+// или nil для запросов без X-API-Key.
+func FromContext(ctx context.Context) *domain.DeveloperApp {
+	app, _ := ctx.Value(contextKey{}).(*domain.DeveloperApp)
+	return app
+}
@@ -0,0 +1,94 @@
+package devmode
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"aggregator_db/internal/config"
+	embeddedpostgres "github.com/fergusstrange/embedded-postgres"
+	"github.com/jackc/pgx/v5"
+)
+
+// StartDatabase готовит БД для локальной разработки: поднимает встроенный
+// Postgres (DEV_EMBEDDED_POSTGRES=true) либо создает отдельную базу на
+// уже запущенном сервере. Конфигурация подключения обновляется на месте.
+func StartDatabase(ctx context.Context, cfg *config.Config, logger *slog.Logger) (stop func(), err error) {
+	if !cfg.Dev.EmbeddedPostgres {
+		return func() {}, provisionDatabase(ctx, cfg, logger)
+	}
+
+	port, err := strconv.ParseUint(cfg.Dev.EmbeddedPort, 10, 32)
+	if err != nil {
+		return nil, fmt.Errorf("invalid DEV_DB_PORT: %w", err)
+	}
+
+	dataDir := cfg.Dev.DataDir
+	if dataDir == "" {
+		dataDir = filepath.Join(os.TempDir(), "subscription_service_dev")
+	}
+
+	db := embeddedpostgres.NewDatabase(embeddedpostgres.DefaultConfig().
+		Port(uint32(port)).
+		Username("postgres").
+		Password("postgres").
+		Database("subscriptions").
+		RuntimePath(filepath.Join(dataDir, "runtime")).
+		DataPath(filepath.Join(dataDir, "data")).
+		Logger(slogWriter{logger: logger}))
+
+	logger.Info("Starting embedded postgres", "port", port, "data_dir", dataDir)
+	if err := db.Start(); err != nil {
+		return nil, fmt.Errorf("start embedded postgres: %w", err)
+	}
+
+	cfg.DBConfig.Host = "localhost"
+	cfg.DBConfig.Port = cfg.Dev.EmbeddedPort
+	cfg.DBConfig.User = "postgres"
+	cfg.DBConfig.Password = "postgres"
+	cfg.DBConfig.DBName = "subscriptions"
+	cfg.DBConfig.SSLMode = "disable"
+
+	return func() {
+		if err := db.Stop(); err != nil {
+			logger.Error("Failed to stop embedded postgres", "error", err.Error())
+		}
+	}, nil
+}
+
+// provisionDatabase создает базу DB_NAME на существующем сервере, если ее нет.
+func provisionDatabase(ctx context.Context, cfg *config.Config, logger *slog.Logger) error {
+	admin := *cfg
+	admin.DBConfig.DBName = "postgres"
+
+	conn, err := pgx.Connect(ctx, admin.DSN())
+	if err != nil {
+		return fmt.Errorf("connect to maintenance database: %w", err)
+	}
+	defer conn.Close(ctx)
+
+	var exists bool
+	err = conn.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM pg_database WHERE datname = $1)`, cfg.DBConfig.DBName).Scan(&exists)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return nil
+	}
+
+	logger.Info("Provisioning dev database", "name", cfg.DBConfig.DBName)
+	_, err = conn.Exec(ctx, "CREATE DATABASE "+pgx.Identifier{cfg.DBConfig.DBName}.Sanitize())
+	return err
+}
+
+type slogWriter struct {
+	logger *slog.Logger
+}
+
+func (w slogWriter) Write(p []byte) (int, error) {
+	w.logger.Debug("embedded postgres", "output", string(p))
+	return len(p), nil
+}
@@ -0,0 +1,50 @@
+package devmode
+
+import (
+	"context"
+	"log/slog"
+
+	"aggregator_db/internal/clock"
+	"aggregator_db/internal/repository/postgres"
+	"aggregator_db/pkg/domain"
+	"github.com/google/uuid"
+)
+
+// Демо-пользователи с фиксированными ID, чтобы ими было удобно пользоваться в Swagger.
+var (
+	DemoUserID  = uuid.MustParse("60601fee-2bf1-4721-ae6f-7636e79a0cba")
+	DemoUser2ID = uuid.MustParse("0b9f5c9a-3c8e-4b59-9d1d-2b6f9c1f0a11")
+)
+
+// Seed заполняет пустую базу демонстрационными подписками.
+func Seed(ctx context.Context, repo postgres.SubscriptionRepository, logger *slog.Logger) error {
+	existing, err := repo.Count(ctx, domain.ListSubscriptionsQuery{})
+	if err != nil {
+		return err
+	}
+	if existing > 0 {
+		return nil
+	}
+
+	endDate := "12-2025"
+	now := clock.Now(ctx)
+	demo := []domain.Subscription{
+		{ServiceName: "Yandex Plus", Price: domain.NewMoney(40000, domain.DefaultCurrency), UserID: DemoUserID, StartDate: "07-2025"},
+		{ServiceName: "Netflix", Price: domain.NewMoney(90000, domain.DefaultCurrency), UserID: DemoUserID, StartDate: "01-2025", EndDate: &endDate},
+		{ServiceName: "Spotify", Price: domain.NewMoney(30000, domain.DefaultCurrency), UserID: DemoUserID, StartDate: "03-2024"},
+		{ServiceName: "Kinopoisk", Price: domain.NewMoney(25000, domain.DefaultCurrency), UserID: DemoUser2ID, StartDate: "05-2025"},
+		{ServiceName: "Okko", Price: domain.NewMoney(19999, domain.DefaultCurrency), UserID: DemoUser2ID, StartDate: "09-2024", EndDate: &endDate},
+	}
+
+	for i := range demo {
+		demo[i].ID = uuid.New()
+		demo[i].CreatedAt = now
+		demo[i].UpdatedAt = now
+		if err := repo.Create(ctx, &demo[i]); err != nil {
+			return err
+		}
+	}
+
+	logger.Info("Seeded demo data", "subscriptions", len(demo), "user_id", DemoUserID.String())
+	return nil
+}
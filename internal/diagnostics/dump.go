@@ -0,0 +1,56 @@
+package diagnostics
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/pprof"
+	"time"
+
+	"aggregator_db/pkg/domain"
+)
+
+// WriteRuntimeDump сохраняет в dir стеки всех горутин (текстом) и профиль кучи
+// (для go tool pprof). Перед снятием профиля кучи запускается сборка мусора, чтобы
+// профиль показывал живые объекты, а не мусор с прошлого цикла.
+func WriteRuntimeDump(dir string, now time.Time) (*domain.RuntimeDump, error) {
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return nil, fmt.Errorf("create dump dir: %w", err)
+	}
+	stamp := now.UTC().Format("20060102T150405Z")
+	dump := &domain.RuntimeDump{
+		GoroutinesFile: filepath.Join(dir, "goroutines-"+stamp+".txt"),
+		HeapFile:       filepath.Join(dir, "heap-"+stamp+".pb.gz"),
+		Goroutines:     runtime.NumGoroutine(),
+		CreatedAt:      now,
+	}
+
+	if err := writeFile(dump.GoroutinesFile, func(w io.Writer) error {
+		return pprof.Lookup("goroutine").WriteTo(w, 2)
+	}); err != nil {
+		return nil, err
+	}
+
+	runtime.GC()
+	var stats runtime.MemStats
+	runtime.ReadMemStats(&stats)
+	dump.HeapAllocBytes = stats.HeapAlloc
+	if err := writeFile(dump.HeapFile, pprof.WriteHeapProfile); err != nil {
+		return nil, err
+	}
+	return dump, nil
+}
+
+func writeFile(path string, write func(w io.Writer) error) error {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o640)
+	if err != nil {
+		return fmt.Errorf("create %s: %w", path, err)
+	}
+	if err := write(f); err != nil {
+		f.Close()
+		return fmt.Errorf("write %s: %w", path, err)
+	}
+	return f.Close()
+}
@@ -0,0 +1,30 @@
+package diagnostics
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWriteRuntimeDump(t *testing.T) {
+	dir := t.TempDir() + "/dumps"
+	dump, err := WriteRuntimeDump(dir, time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	goroutines, err := os.ReadFile(dump.GoroutinesFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(goroutines), "TestWriteRuntimeDump") {
+		t.Errorf("goroutine dump does not contain the test goroutine")
+	}
+	if info, err := os.Stat(dump.HeapFile); err != nil || info.Size() == 0 {
+		t.Errorf("heap profile is missing or empty: %v", err)
+	}
+	if !strings.HasSuffix(dump.HeapFile, "heap-20261016T120000Z.pb.gz") || dump.Goroutines == 0 {
+		t.Errorf("unexpected dump %+v", dump)
+	}
+}
@@ -0,0 +1,44 @@
+// Package diagnostics хранит отладочные данные последних запросов для админских ручек.
+// Записи живут в памяти реплики, поэтому запрос нужно искать на той реплике, что его обработала.
+package diagnostics
+
+import (
+	"sync"
+
+	"aggregator_db/pkg/domain"
+)
+
+// Recorder - кольцевой буфер последних size запросов с планами.
+type Recorder struct {
+	mu      sync.Mutex
+	size    int
+	entries []domain.RequestDiagnostics
+}
+
+func NewRecorder(size int) *Recorder {
+	return &Recorder{size: size}
+}
+
+func (r *Recorder) Add(entry domain.RequestDiagnostics) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.entries = append(r.entries, entry)
+	if len(r.entries) > r.size {
+		r.entries = r.entries[len(r.entries)-r.size:]
+	}
+}
+
+// List возвращает записи от новых к старым; с непустым traceID - только записи этого трейса.
+func (r *Recorder) List(traceID string) []domain.RequestDiagnostics {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	result := make([]domain.RequestDiagnostics, 0, len(r.entries))
+	for i := len(r.entries) - 1; i >= 0; i-- {
+		if traceID == "" || r.entries[i].TraceID == traceID {
+			result = append(result, r.entries[i])
+		}
+	}
+	return result
+}
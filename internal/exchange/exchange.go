@@ -0,0 +1,209 @@
+package exchange
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/big"
+	"strings"
+	"sync"
+	"time"
+
+	"aggregator_db/pkg/domain"
+	"aggregator_db/pkg/metrics"
+)
+
+var ErrRateUnavailable = errors.New("exchange rate unavailable")
+
+var cacheLookups = metrics.NewCounterVec(
+	"exchange_rates_cache_total",
+	"Обращения к кэшу курсов валют",
+	"result",
+)
+
+// CacheCounts - попадания в кэш курсов и промахи с момента старта.
+func CacheCounts() (hits, misses float64) {
+	return cacheLookups.Value("hit"), cacheLookups.Value("miss")
+}
+
+// Rates - курсы на дату: Values[c] - стоимость единицы валюты c в базовой валюте.
+type Rates struct {
+	Source string
+	Base   domain.Currency
+	// Date - дата курсов, YYYY-MM-DD
+	Date   string
+	Values map[domain.Currency]*big.Rat
+}
+
+// Provider отдает актуальные курсы.
+type Provider interface {
+	Rates(ctx context.Context) (*Rates, error)
+}
+
+func (r *Rates) value(currency domain.Currency) (*big.Rat, error) {
+	if currency == r.Base {
+		return big.NewRat(1, 1), nil
+	}
+	if v, ok := r.Values[currency]; ok && v.Sign() > 0 {
+		return v, nil
+	}
+	return nil, fmt.Errorf("%w: %s", ErrRateUnavailable, currency)
+}
+
+// Rate - сколько единиц валюты to стоит единица валюты from.
+func (r *Rates) Rate(from, to domain.Currency) (*big.Rat, error) {
+	fromValue, err := r.value(from)
+	if err != nil {
+		return nil, err
+	}
+	toValue, err := r.value(to)
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Rat).Quo(fromValue, toValue), nil
+}
+
+// Convert пересчитывает сумму в валюту to через базовую валюту.
+// Результат округляется до минорной единицы, половина - от нуля.
+func (r *Rates) Convert(m domain.Money, to domain.Currency) (domain.Money, error) {
+	if m.Currency == to {
+		return m, nil
+	}
+	rate, err := r.Rate(m.Currency, to)
+	if err != nil {
+		return domain.Money{}, err
+	}
+
+	amount := new(big.Rat).SetInt64(m.Amount)
+	amount.Mul(amount, rate)
+	amount.Mul(amount, pow10(to.Exponent()))
+	amount.Quo(amount, pow10(m.Currency.Exponent()))
+	return domain.NewMoney(roundHalfAway(amount), to), nil
+}
+
+// ConvertTotals пересчитывает суммы по валютам в валюту to и складывает их.
+func (r *Rates) ConvertTotals(totals domain.Totals, to domain.Currency) (domain.Money, error) {
+	sum := domain.NewMoney(0, to)
+	for _, m := range totals.List() {
+		converted, err := r.Convert(m, to)
+		if err != nil {
+			return domain.Money{}, err
+		}
+		sum.Amount += converted.Amount
+	}
+	return sum, nil
+}
+
+func pow10(exp int) *big.Rat {
+	return new(big.Rat).SetInt(new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(exp)), nil))
+}
+
+func roundHalfAway(x *big.Rat) int64 {
+	num := new(big.Int).Abs(x.Num())
+	den := x.Denom()
+	q, rem := new(big.Int).QuoRem(num, den, new(big.Int))
+	if rem.Mul(rem, big.NewInt(2)).Cmp(den) >= 0 {
+		q.Add(q, big.NewInt(1))
+	}
+	if x.Sign() < 0 {
+		q.Neg(q)
+	}
+	return q.Int64()
+}
+
+// ParseRates разбирает список курсов вида "USD=92.5,EUR=100.1".
+func ParseRates(s string) (map[domain.Currency]*big.Rat, error) {
+	values := make(map[domain.Currency]*big.Rat)
+	for _, pair := range strings.Split(s, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		code, rate, ok := strings.Cut(pair, "=")
+		currency := domain.Currency(strings.ToUpper(strings.TrimSpace(code)))
+		if !ok || !currency.Valid() {
+			return nil, fmt.Errorf("invalid exchange rate %q", pair)
+		}
+		value, ok := new(big.Rat).SetString(strings.TrimSpace(rate))
+		if !ok || value.Sign() <= 0 {
+			return nil, fmt.Errorf("invalid exchange rate %q", pair)
+		}
+		values[currency] = value
+	}
+	return values, nil
+}
+
+// FormatRate выводит курс десятичной строкой с шестью знаками.
+func FormatRate(rate *big.Rat) string {
+	return rate.FloatString(6)
+}
+
+type staticProvider struct {
+	rates *Rates
+}
+
+// NewStaticProvider отдает курсы из конфигурации: Values относительно base.
+func NewStaticProvider(base domain.Currency, values map[domain.Currency]*big.Rat) Provider {
+	return &staticProvider{rates: &Rates{Source: "static", Base: base, Values: values}}
+}
+
+func (p *staticProvider) Rates(context.Context) (*Rates, error) {
+	return p.rates, nil
+}
+
+type fallbackProvider struct {
+	providers []Provider
+	logger    *slog.Logger
+}
+
+// NewFallbackProvider опрашивает провайдеров по порядку и отдает первые полученные курсы.
+func NewFallbackProvider(logger *slog.Logger, providers ...Provider) Provider {
+	return &fallbackProvider{providers: providers, logger: logger}
+}
+
+func (p *fallbackProvider) Rates(ctx context.Context) (*Rates, error) {
+	errs := make([]error, 0, len(p.providers))
+	for _, provider := range p.providers {
+		rates, err := provider.Rates(ctx)
+		if err == nil {
+			return rates, nil
+		}
+		p.logger.WarnContext(ctx, "exchange rate provider failed", slog.String("error", err.Error()))
+		errs = append(errs, err)
+	}
+	return nil, fmt.Errorf("%w: %w", ErrRateUnavailable, errors.Join(errs...))
+}
+
+type cachedProvider struct {
+	next Provider
+	ttl  time.Duration
+	now  func() time.Time
+
+	mu        sync.Mutex
+	rates     *Rates
+	fetchedAt time.Time
+}
+
+// NewCachedProvider хранит полученные курсы ttl: банки обновляют их раз в день.
+func NewCachedProvider(next Provider, ttl time.Duration) Provider {
+	return &cachedProvider{next: next, ttl: ttl, now: time.Now}
+}
+
+func (p *cachedProvider) Rates(ctx context.Context) (*Rates, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := p.now()
+	if p.rates != nil && now.Sub(p.fetchedAt) < p.ttl {
+		cacheLookups.Inc("hit")
+		return p.rates, nil
+	}
+	cacheLookups.Inc("miss")
+	rates, err := p.next.Rates(ctx)
+	if err != nil {
+		return nil, err
+	}
+	p.rates, p.fetchedAt = rates, now
+	return rates, nil
+}
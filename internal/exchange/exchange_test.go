@@ -0,0 +1,280 @@
+package exchange
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"aggregator_db/internal/tenancy"
+	"aggregator_db/pkg/domain"
+	"aggregator_db/pkg/httpclient"
+)
+
+func TestConvert(t *testing.T) {
+	rates := &Rates{Base: "RUB", Values: map[domain.Currency]*big.Rat{
+		"USD": big.NewRat(181, 2),
+		"JPY": big.NewRat(61, 100),
+	}}
+
+	cases := []struct {
+		from domain.Money
+		to   domain.Currency
+		want domain.Money
+	}{
+		{domain.NewMoney(999, "USD"), "RUB", domain.NewMoney(90410, "RUB")},
+		// 29.97 * 90.5 = 2712.285: половина копейки округляется вверх
+		{domain.NewMoney(2997, "USD"), "RUB", domain.NewMoney(271229, "RUB")},
+		{domain.NewMoney(9050, "RUB"), "USD", domain.NewMoney(100, "USD")},
+		// У иены нет минорных единиц
+		{domain.NewMoney(1000, "USD"), "JPY", domain.NewMoney(1484, "JPY")},
+		{domain.NewMoney(-2997, "USD"), "RUB", domain.NewMoney(-271229, "RUB")},
+		{domain.NewMoney(500, "USD"), "USD", domain.NewMoney(500, "USD")},
+	}
+	for _, tc := range cases {
+		got, err := rates.Convert(tc.from, tc.to)
+		if err != nil {
+			t.Fatalf("Convert(%s, %s): %v", tc.from, tc.to, err)
+		}
+		if got != tc.want {
+			t.Errorf("Convert(%s, %s) = %s, want %s", tc.from, tc.to, got, tc.want)
+		}
+	}
+
+	if _, err := rates.Convert(domain.NewMoney(100, "EUR"), "RUB"); !errors.Is(err, ErrRateUnavailable) {
+		t.Errorf("got %v for missing rate, want ErrRateUnavailable", err)
+	}
+}
+
+func TestConvertTotals(t *testing.T) {
+	rates := &Rates{Base: "RUB", Values: map[domain.Currency]*big.Rat{"USD": big.NewRat(90, 1)}}
+	totals := domain.Totals{"RUB": 59997, "USD": 2997}
+
+	got, err := rates.ConvertTotals(totals, "RUB")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := domain.NewMoney(59997+269730, "RUB"); got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+}
+
+func TestParseRates(t *testing.T) {
+	values, err := ParseRates(" usd=92.5, EUR=100.1 ,")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if values["USD"].Cmp(big.NewRat(185, 2)) != 0 || values["EUR"].Cmp(big.NewRat(1001, 10)) != 0 {
+		t.Errorf("unexpected rates %v", values)
+	}
+
+	for _, bad := range []string{"USD", "XXX=1", "USD=abc", "USD=0", "USD=-1"} {
+		if _, err := ParseRates(bad); err == nil {
+			t.Errorf("ParseRates(%q): expected error", bad)
+		}
+	}
+}
+
+func newTestClient() *httpclient.Client {
+	cfg := httpclient.DefaultConfig("exchange_test")
+	cfg.MaxRetries = 0
+	return httpclient.New(cfg, slog.New(slog.NewTextHandler(io.Discard, nil)))
+}
+
+func TestCBRProvider(t *testing.T) {
+	// Ответ ЦБ в windows-1251: названия валют не в ASCII
+	body := []byte("<?xml version=\"1.0\" encoding=\"windows-1251\"?>" +
+		"<ValCurs Date=\"16.10.2026\" name=\"Foreign Currency Market\">" +
+		"<Valute ID=\"R01235\"><NumCode>840</NumCode><CharCode>USD</CharCode><Nominal>1</Nominal><Name>\xc4\xee\xeb\xeb\xe0\xf0 \xd1\xd8\xc0</Name><Value>92,5058</Value></Valute>" +
+		"<Valute ID=\"R01820\"><NumCode>392</NumCode><CharCode>JPY</CharCode><Nominal>100</Nominal><Name>\xc8\xe5\xed</Name><Value>61,0000</Value></Valute>" +
+		"<Valute ID=\"R01010\"><NumCode>036</NumCode><CharCode>AUD</CharCode><Nominal>1</Nominal><Name>A</Name><Value>60,1</Value></Valute>" +
+		"</ValCurs>")
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/xml; charset=windows-1251")
+		_, _ = w.Write(body)
+	}))
+	defer srv.Close()
+
+	rates, err := NewCBRProvider(newTestClient(), srv.URL).Rates(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rates.Base != "RUB" || rates.Date != "2026-10-16" || rates.Source != "cbr" {
+		t.Errorf("unexpected rates header %+v", rates)
+	}
+	if rates.Values["USD"].Cmp(big.NewRat(925058, 10000)) != 0 {
+		t.Errorf("USD = %s, want 92.5058", rates.Values["USD"].FloatString(4))
+	}
+	// Курс иены дается за 100 единиц
+	if rates.Values["JPY"].Cmp(big.NewRat(61, 100)) != 0 {
+		t.Errorf("JPY = %s, want 0.61", rates.Values["JPY"].FloatString(4))
+	}
+	if _, ok := rates.Values["AUD"]; ok {
+		t.Error("unsupported currency must be skipped")
+	}
+}
+
+func TestECBProvider(t *testing.T) {
+	body := `<?xml version="1.0" encoding="UTF-8"?>
+<gesmes:Envelope xmlns:gesmes="http://www.gesmes.org/xml/2002-08-01" xmlns="http://www.ecb.int/vocabulary/2002-08-01/eurofxref">
+	<gesmes:subject>Reference rates</gesmes:subject>
+	<Cube>
+		<Cube time="2026-10-15">
+			<Cube currency="USD" rate="1.25"/>
+			<Cube currency="JPY" rate="160.00"/>
+		</Cube>
+	</Cube>
+</gesmes:Envelope>`
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = io.WriteString(w, body)
+	}))
+	defer srv.Close()
+
+	rates, err := NewECBProvider(newTestClient(), srv.URL).Rates(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rates.Base != "EUR" || rates.Date != "2026-10-15" {
+		t.Errorf("unexpected rates header %+v", rates)
+	}
+	got, err := rates.Convert(domain.NewMoney(1000, "USD"), "EUR")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := domain.NewMoney(800, "EUR"); got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+}
+
+type failingProvider struct {
+	calls int
+}
+
+func (p *failingProvider) Rates(context.Context) (*Rates, error) {
+	p.calls++
+	return nil, errors.New("bank is down")
+}
+
+type countingProvider struct {
+	calls int
+}
+
+func (p *countingProvider) Rates(context.Context) (*Rates, error) {
+	p.calls++
+	return &Rates{Source: "bank", Base: "RUB"}, nil
+}
+
+func TestFallbackProvider(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	static := NewStaticProvider("RUB", map[domain.Currency]*big.Rat{"USD": big.NewRat(90, 1)})
+
+	rates, err := NewFallbackProvider(logger, &failingProvider{}, static).Rates(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rates.Source != "static" {
+		t.Errorf("got rates from %q, want static", rates.Source)
+	}
+
+	if _, err := NewFallbackProvider(logger, &failingProvider{}).Rates(context.Background()); !errors.Is(err, ErrRateUnavailable) {
+		t.Errorf("got %v, want ErrRateUnavailable", err)
+	}
+}
+
+func TestCachedProvider(t *testing.T) {
+	next := &countingProvider{}
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	cached := NewCachedProvider(next, time.Hour).(*cachedProvider)
+	cached.now = func() time.Time { return now }
+
+	for i := 0; i < 3; i++ {
+		if _, err := cached.Rates(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if next.calls != 1 {
+		t.Errorf("got %d calls within ttl, want 1", next.calls)
+	}
+
+	now = now.Add(time.Hour)
+	if _, err := cached.Rates(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if next.calls != 2 {
+		t.Errorf("got %d calls after ttl, want 2", next.calls)
+	}
+
+	// Ошибка не кэшируется
+	failing := &failingProvider{}
+	cachedFailing := NewCachedProvider(failing, time.Hour)
+	for i := 0; i < 2; i++ {
+		if _, err := cachedFailing.Rates(context.Background()); err == nil {
+			t.Fatal("expected error")
+		}
+	}
+	if failing.calls != 2 {
+		t.Errorf("got %d calls, want 2", failing.calls)
+	}
+}
+
+func TestPinnedProvider(t *testing.T) {
+	if err := domain.RegisterCurrency("XBT", 8); err != nil {
+		t.Fatal(err)
+	}
+	bank := NewStaticProvider("EUR", map[domain.Currency]*big.Rat{"RUB": big.NewRat(1, 100), "USD": big.NewRat(9, 10)})
+	provider := NewPinnedProvider(bank)
+	ctx := tenancy.WithTenant(context.Background(), &domain.Tenant{ID: "acme", PinnedRates: &domain.PinnedRates{
+		Base: "RUB", Version: 2, Values: map[domain.Currency]string{"XBT": "5400000", "USD": "95"},
+	}})
+
+	rates, err := provider.Rates(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rates.Source != "static+pinned:v2" {
+		t.Errorf("got source %q, want static+pinned:v2", rates.Source)
+	}
+	cases := []struct {
+		from domain.Money
+		to   domain.Currency
+		want domain.Money
+	}{
+		{domain.NewMoney(1_000_000, "XBT"), "RUB", domain.NewMoney(5_400_000, "RUB")},
+		// Закрепленный курс доллара заменяет банковский
+		{domain.NewMoney(100, "USD"), "RUB", domain.NewMoney(9500, "RUB")},
+		// Евро есть только у банка: 1 EUR = 100 RUB
+		{domain.NewMoney(100, "EUR"), "RUB", domain.NewMoney(10000, "RUB")},
+	}
+	for _, tc := range cases {
+		got, err := rates.Convert(tc.from, tc.to)
+		if err != nil {
+			t.Fatalf("Convert(%s, %s): %v", tc.from, tc.to, err)
+		}
+		if got != tc.want {
+			t.Errorf("Convert(%s, %s) = %s, want %s", tc.from, tc.to, got, tc.want)
+		}
+	}
+
+	// Без банка пересчет идет по одним закрепленным курсам
+	rates, err = NewPinnedProvider(&failingProvider{}).Rates(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := rates.Convert(domain.NewMoney(100, "USD"), "RUB"); err != nil || got != domain.NewMoney(9500, "RUB") {
+		t.Errorf("got %s, %v without bank, want 95.00 RUB", got, err)
+	}
+
+	// Запросы без тенанта получают курсы банка как есть
+	rates, err = provider.Rates(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rates.Source != "static" {
+		t.Errorf("got source %q without tenant, want static", rates.Source)
+	}
+}
@@ -0,0 +1,147 @@
+package exchange
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"strings"
+	"time"
+
+	"aggregator_db/pkg/domain"
+	"aggregator_db/pkg/httpclient"
+)
+
+const (
+	// DefaultCBRURL - ежедневные курсы ЦБ РФ к рублю
+	DefaultCBRURL = "https://www.cbr.ru/scripts/XML_daily.asp"
+	// DefaultECBURL - ежедневные курсы ЕЦБ к евро
+	DefaultECBURL = "https://www.ecb.europa.eu/stats/eurofxref/eurofxref-daily.xml"
+)
+
+type cbrProvider struct {
+	client *httpclient.Client
+	url    string
+}
+
+// NewCBRProvider берет курсы ЦБ РФ. Базовая валюта - RUB.
+func NewCBRProvider(client *httpclient.Client, url string) Provider {
+	return &cbrProvider{client: client, url: url}
+}
+
+type cbrValCurs struct {
+	Date    string `xml:"Date,attr"`
+	Valutes []struct {
+		CharCode string `xml:"CharCode"`
+		Nominal  string `xml:"Nominal"`
+		Value    string `xml:"Value"`
+	} `xml:"Valute"`
+}
+
+func (p *cbrProvider) Rates(ctx context.Context) (*Rates, error) {
+	var doc cbrValCurs
+	if err := fetchXML(ctx, p.client, p.url, &doc); err != nil {
+		return nil, fmt.Errorf("cbr rates: %w", err)
+	}
+
+	date, err := time.Parse("02.01.2006", doc.Date)
+	if err != nil {
+		return nil, fmt.Errorf("cbr rates: invalid date %q", doc.Date)
+	}
+	rates := &Rates{Source: "cbr", Base: "RUB", Date: date.Format(domain.CalendarDateLayout), Values: make(map[domain.Currency]*big.Rat)}
+	for _, v := range doc.Valutes {
+		currency := domain.Currency(v.CharCode)
+		if !currency.Valid() {
+			continue
+		}
+		// Курс дается за Nominal единиц валюты с запятой в дробной части
+		value, ok := new(big.Rat).SetString(strings.Replace(v.Value, ",", ".", 1))
+		nominal, nominalOK := new(big.Rat).SetString(v.Nominal)
+		if !ok || !nominalOK || nominal.Sign() <= 0 {
+			return nil, fmt.Errorf("cbr rates: invalid rate for %s", v.CharCode)
+		}
+		rates.Values[currency] = value.Quo(value, nominal)
+	}
+	return rates, nil
+}
+
+type ecbProvider struct {
+	client *httpclient.Client
+	url    string
+}
+
+// NewECBProvider берет курсы ЕЦБ. Базовая валюта - EUR; рубля в них нет.
+func NewECBProvider(client *httpclient.Client, url string) Provider {
+	return &ecbProvider{client: client, url: url}
+}
+
+type ecbEnvelope struct {
+	Cube struct {
+		Time  string `xml:"time,attr"`
+		Rates []struct {
+			Currency string `xml:"currency,attr"`
+			Rate     string `xml:"rate,attr"`
+		} `xml:"Cube"`
+	} `xml:"Cube>Cube"`
+}
+
+func (p *ecbProvider) Rates(ctx context.Context) (*Rates, error) {
+	var doc ecbEnvelope
+	if err := fetchXML(ctx, p.client, p.url, &doc); err != nil {
+		return nil, fmt.Errorf("ecb rates: %w", err)
+	}
+
+	rates := &Rates{Source: "ecb", Base: "EUR", Date: doc.Cube.Time, Values: make(map[domain.Currency]*big.Rat)}
+	for _, r := range doc.Cube.Rates {
+		currency := domain.Currency(r.Currency)
+		if !currency.Valid() {
+			continue
+		}
+		// ЕЦБ публикует, сколько единиц валюты стоит один евро
+		perEuro, ok := new(big.Rat).SetString(r.Rate)
+		if !ok || perEuro.Sign() <= 0 {
+			return nil, fmt.Errorf("ecb rates: invalid rate for %s", r.Currency)
+		}
+		rates.Values[currency] = perEuro.Inv(perEuro)
+	}
+	return rates, nil
+}
+
+func fetchXML(ctx context.Context, client *httpclient.Client, url string, v any) error {
+	resp, err := client.Get(ctx, url)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		_, _ = io.Copy(io.Discard, resp.Body)
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+
+	decoder := xml.NewDecoder(resp.Body)
+	decoder.CharsetReader = asciiCharsetReader
+	return decoder.Decode(v)
+}
+
+// asciiCharsetReader пропускает документы в windows-1251 (так отвечает ЦБ): нужные поля
+// состоят из ASCII, а остальные байты заменяются на '?'.
+func asciiCharsetReader(_ string, input io.Reader) (io.Reader, error) {
+	return &asciiReader{r: input}, nil
+}
+
+type asciiReader struct {
+	r io.Reader
+}
+
+func (a *asciiReader) Read(p []byte) (int, error) {
+	n, err := a.r.Read(p)
+	for i := 0; i < n; i++ {
+		if p[i] >= 0x80 {
+			p[i] = '?'
+		}
+	}
+	return n, err
+}
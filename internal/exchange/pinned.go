@@ -0,0 +1,56 @@
+package exchange
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+
+	"aggregator_db/internal/tenancy"
+	"aggregator_db/pkg/domain"
+)
+
+type pinnedProvider struct {
+	next Provider
+}
+
+// NewPinnedProvider накладывает на курсы next курсы, закрепленные тенантом запроса
+// (domain.PinnedRates). Без тенанта или закрепленных курсов отдает курсы next как есть.
+func NewPinnedProvider(next Provider) Provider {
+	return &pinnedProvider{next: next}
+}
+
+func (p *pinnedProvider) Rates(ctx context.Context) (*Rates, error) {
+	tenant := tenancy.FromContext(ctx)
+	if tenant == nil || tenant.PinnedRates == nil || len(tenant.PinnedRates.Values) == 0 {
+		return p.next.Rates(ctx)
+	}
+	pinned := tenant.PinnedRates
+	values, err := pinned.Rates()
+	if err != nil {
+		return nil, err
+	}
+	source := fmt.Sprintf("pinned:v%d", pinned.Version)
+
+	rates, err := p.next.Rates(ctx)
+	if err != nil {
+		// Пересчет между валютами с закрепленными курсами не зависит от провайдера
+		return &Rates{Source: source, Base: pinned.Base, Date: pinned.UpdatedAt.Format(domain.CalendarDateLayout), Values: values}, nil
+	}
+
+	// Курсы провайдера переводятся в базовую валюту закрепленных, и закрепленные
+	// заменяют их; меняется копия, потому что курсы провайдера общие для всех запросов
+	base, err := rates.value(pinned.Base)
+	if err != nil {
+		return nil, fmt.Errorf("pinned rates in %s: %w", pinned.Base, err)
+	}
+	merged := &Rates{Source: rates.Source + "+" + source, Base: pinned.Base, Date: rates.Date,
+		Values: make(map[domain.Currency]*big.Rat, len(rates.Values)+len(values)+1)}
+	merged.Values[rates.Base] = new(big.Rat).Inv(base)
+	for currency, value := range rates.Values {
+		merged.Values[currency] = new(big.Rat).Quo(value, base)
+	}
+	for currency, value := range values {
+		merged.Values[currency] = value
+	}
+	return merged, nil
+}
@@ -0,0 +1,309 @@
+package experiment
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"maps"
+	"math/rand/v2"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"aggregator_db/internal/repository/postgres"
+	"aggregator_db/internal/tenancy"
+	"aggregator_db/pkg/domain"
+	"aggregator_db/pkg/metrics"
+	"github.com/google/uuid"
+)
+
+// maxMismatches - сколько последних расхождений хранит отчет.
+const maxMismatches = 50
+
+var (
+	served = metrics.NewCounterVec(
+		"calculate_experiment_served_total",
+		"Расчеты стоимости по реализации, которая их отдала: control или candidate",
+		"variant",
+	)
+	comparisons = metrics.NewCounterVec(
+		"calculate_experiment_comparisons_total",
+		"Сравнения реализаций расчета стоимости: match, mismatch, error или dropped",
+		"result",
+	)
+)
+
+// Options - настройки эксперимента.
+type Options struct {
+	// Name - название новой реализации в отчете и логе
+	Name string
+	// Percent - доля расчетов, которые отдает новая реализация
+	Percent float64
+	// ComparePercent - доля расчетов, которые повторяются другой реализацией для сравнения
+	ComparePercent float64
+	// Concurrency ограничивает число сравнений в полете
+	Concurrency int
+	Timeout     time.Duration
+}
+
+// Calculate отдает долю расчетов стоимости (CalculateTotal и CalculateTotalByUser)
+// новой реализации, а остальные - текущей. Доля ComparePercent расчетов после
+// ответа повторяется в фоне другой реализацией; расхождения пишутся в лог и в
+// отчет. Сравнение не задерживает ответ, а если все слоты заняты, не проводится.
+// Остальные методы идут в текущую реализацию.
+type Calculate struct {
+	postgres.SubscriptionRepository
+	candidate postgres.SubscriptionRepository
+	opts      Options
+	slots     chan struct{}
+	logger    *slog.Logger
+	sample    func() float64
+	since     time.Time
+
+	mu         sync.Mutex
+	report     domain.CalculateExperimentReport
+	mismatches []domain.CalculateMismatch
+}
+
+func NewCalculate(control, candidate postgres.SubscriptionRepository, opts Options, logger *slog.Logger) *Calculate {
+	return &Calculate{
+		SubscriptionRepository: control,
+		candidate:              candidate,
+		opts:                   opts,
+		slots:                  make(chan struct{}, opts.Concurrency),
+		logger:                 logger,
+		sample:                 func() float64 { return rand.Float64() * 100 },
+		since:                  time.Now().UTC(),
+		report:                 domain.CalculateExperimentReport{Served: make(map[domain.ExperimentVariant]int64)},
+	}
+}
+
+func (c *Calculate) CalculateTotal(ctx context.Context, req domain.CalculateTotalRequest) (domain.Totals, error) {
+	variant, primary, other := c.route()
+	totals, err := primary.CalculateTotal(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	result := maps.Clone(totals)
+	c.compare(ctx, variant, req, func(ctx context.Context) (*domain.CalculateMismatch, error) {
+		again, err := other.CalculateTotal(ctx, req)
+		if err != nil {
+			return nil, err
+		}
+		control, candidate := ordered(variant, result, again)
+		if equalTotals(control, candidate) {
+			return nil, nil
+		}
+		return &domain.CalculateMismatch{Method: "total", Control: control, Candidate: candidate}, nil
+	})
+	return totals, nil
+}
+
+func (c *Calculate) CalculateTotalByUser(ctx context.Context, req domain.CalculateTotalRequest) (map[uuid.UUID]domain.Totals, error) {
+	variant, primary, other := c.route()
+	byUser, err := primary.CalculateTotalByUser(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	result := make(map[uuid.UUID]domain.Totals, len(byUser))
+	for user, totals := range byUser {
+		result[user] = maps.Clone(totals)
+	}
+	c.compare(ctx, variant, req, func(ctx context.Context) (*domain.CalculateMismatch, error) {
+		again, err := other.CalculateTotalByUser(ctx, req)
+		if err != nil {
+			return nil, err
+		}
+		control, candidate := ordered(variant, result, again)
+		users := slices.Collect(maps.Keys(control))
+		for user := range candidate {
+			if _, ok := control[user]; !ok {
+				users = append(users, user)
+			}
+		}
+		slices.SortFunc(users, func(a, b uuid.UUID) int { return strings.Compare(a.String(), b.String()) })
+
+		var mismatch *domain.CalculateMismatch
+		for _, user := range users {
+			if equalTotals(control[user], candidate[user]) {
+				continue
+			}
+			if mismatch == nil {
+				mismatch = &domain.CalculateMismatch{Method: "by_user", UserID: &user, Control: control[user], Candidate: candidate[user]}
+			}
+			mismatch.DifferingUsers++
+		}
+		return mismatch, nil
+	})
+	return byUser, nil
+}
+
+// route выбирает реализацию, которая отдаст расчет, и ту, что его повторит.
+func (c *Calculate) route() (domain.ExperimentVariant, postgres.SubscriptionRepository, postgres.SubscriptionRepository) {
+	variant, primary, other := domain.ExperimentControl, c.SubscriptionRepository, c.candidate
+	if c.sample() < c.opts.Percent {
+		variant, primary, other = domain.ExperimentCandidate, c.candidate, c.SubscriptionRepository
+	}
+	served.Inc(string(variant))
+	c.mu.Lock()
+	c.report.Served[variant]++
+	c.mu.Unlock()
+	return variant, primary, other
+}
+
+// compare повторяет расчет другой реализацией в фоне. Расчеты внутри единицы
+// работы не повторяются: ее транзакция занята запросом и закончится вместе с ним.
+func (c *Calculate) compare(ctx context.Context, variant domain.ExperimentVariant, req domain.CalculateTotalRequest, run func(ctx context.Context) (*domain.CalculateMismatch, error)) {
+	if postgres.InTx(ctx) || c.sample() >= c.opts.ComparePercent {
+		return
+	}
+	select {
+	case c.slots <- struct{}{}:
+	default:
+		comparisons.Inc("dropped")
+		c.mu.Lock()
+		c.report.Dropped++
+		c.mu.Unlock()
+		return
+	}
+
+	// Повтор читает с той же свежестью, что и расчет, но не отменяется вместе с
+	// запросом и не влияет на отставание его ответа
+	freshness := domain.FreshnessStrong
+	if postgres.ToleratesLag(ctx) {
+		freshness = domain.FreshnessEventual
+	}
+	compareCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), c.opts.Timeout)
+	compareCtx, _ = postgres.WithFreshness(compareCtx, freshness)
+	tenant := ""
+	if t := tenancy.FromContext(ctx); t != nil {
+		tenant = t.ID
+	}
+
+	go func() {
+		defer func() { <-c.slots }()
+		defer cancel()
+
+		mismatch, err := run(compareCtx)
+		if err != nil {
+			comparisons.Inc("error")
+			c.mu.Lock()
+			c.report.Failed++
+			c.mu.Unlock()
+			c.logger.Warn("calculate experiment comparison failed",
+				slog.String("candidate", c.opts.Name),
+				slog.String("tenant_id", tenant),
+				slog.String("error", err.Error()),
+			)
+			return
+		}
+		if mismatch == nil {
+			comparisons.Inc("match")
+			c.mu.Lock()
+			c.report.Compared++
+			c.report.Matched++
+			c.mu.Unlock()
+			return
+		}
+
+		mismatch.At = time.Now().UTC()
+		mismatch.TenantID = tenant
+		mismatch.Served = variant
+		mismatch.Query = requestQuery(req)
+		comparisons.Inc("mismatch")
+		c.mu.Lock()
+		c.report.Compared++
+		c.report.Mismatched++
+		c.mismatches = append(c.mismatches, *mismatch)
+		if len(c.mismatches) > maxMismatches {
+			c.mismatches = c.mismatches[len(c.mismatches)-maxMismatches:]
+		}
+		c.mu.Unlock()
+		c.logger.Warn("calculate experiment results differ",
+			slog.String("candidate", c.opts.Name),
+			slog.String("tenant_id", tenant),
+			slog.String("method", mismatch.Method),
+			slog.String("served", string(variant)),
+			slog.String("query", mismatch.Query),
+			slog.String("control", fmt.Sprint(mismatch.Control)),
+			slog.String("candidate_totals", fmt.Sprint(mismatch.Candidate)),
+			slog.Int("differing_users", mismatch.DifferingUsers),
+		)
+	}()
+}
+
+// Report возвращает итоги эксперимента на этой реплике.
+func (c *Calculate) Report() domain.CalculateExperimentReport {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	report := c.report
+	report.Candidate = c.opts.Name
+	report.Percent = c.opts.Percent
+	report.ComparePercent = c.opts.ComparePercent
+	report.Since = c.since
+	report.Served = maps.Clone(c.report.Served)
+	if report.Compared > 0 {
+		report.MismatchRate = float64(report.Mismatched) / float64(report.Compared)
+	}
+	report.Mismatches = make([]domain.CalculateMismatch, 0, len(c.mismatches))
+	for i := len(c.mismatches) - 1; i >= 0; i-- {
+		report.Mismatches = append(report.Mismatches, c.mismatches[i])
+	}
+	return report
+}
+
+// ordered раскладывает результаты отдавшей и повторившей реализаций по control и candidate.
+func ordered[T any](variant domain.ExperimentVariant, served, again T) (control, candidate T) {
+	if variant == domain.ExperimentCandidate {
+		return again, served
+	}
+	return served, again
+}
+
+// equalTotals сравнивает суммы по валютам; отсутствующая валюта равна нулю.
+func equalTotals(a, b domain.Totals) bool {
+	for currency, amount := range a {
+		if b[currency] != amount {
+			return false
+		}
+	}
+	for currency, amount := range b {
+		if a[currency] != amount {
+			return false
+		}
+	}
+	return true
+}
+
+// requestQuery записывает параметры расчета так же, как их принимает
+// GET /subscriptions/calculate.
+func requestQuery(req domain.CalculateTotalRequest) string {
+	params := url.Values{}
+	params.Set("start_period", req.StartPeriod)
+	params.Set("end_period", req.EndPeriod)
+	if req.UserID != nil {
+		params.Set("user_id", req.UserID.String())
+	}
+	for _, user := range req.UserIDs {
+		params.Add("user_id", user.String())
+	}
+	if req.ServiceName != nil {
+		params.Set("service_name", *req.ServiceName)
+	}
+	if req.ExcludeInactive {
+		params.Set("exclude_inactive", "true")
+	}
+	if req.Currency != "" {
+		params.Set("currency", string(req.Currency))
+	}
+	if req.TargetCurrency != "" {
+		params.Set("target_currency", string(req.TargetCurrency))
+	}
+	for _, tag := range req.Tags {
+		params.Add("tag", tag)
+	}
+	return params.Encode()
+}
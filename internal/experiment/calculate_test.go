@@ -0,0 +1,111 @@
+package experiment
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"aggregator_db/internal/repository/memory"
+	"aggregator_db/internal/repository/postgres"
+	"aggregator_db/pkg/domain"
+	"github.com/google/uuid"
+)
+
+func TestCalculate(t *testing.T) {
+	ctx := context.Background()
+	control, candidate := memory.NewSubscriptionRepository(), memory.NewSubscriptionRepository()
+	alice, bob := uuid.New(), uuid.New()
+	subscribe := func(repo postgres.SubscriptionRepository, user uuid.UUID, price int64) {
+		t.Helper()
+		sub := &domain.Subscription{ID: uuid.New(), UserID: user, ServiceName: "Netflix", Price: domain.NewMoney(price, domain.DefaultCurrency), StartDate: "01-2025"}
+		if err := repo.Create(ctx, sub); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, repo := range []postgres.SubscriptionRepository{control, candidate} {
+		subscribe(repo, alice, 100)
+		subscribe(repo, bob, 200)
+	}
+	// Новая реализация ошибается в расчете Боба
+	subscribe(candidate, bob, 1)
+
+	calculate := NewCalculate(control, candidate, Options{Name: "test", Percent: 50, ComparePercent: 100, Concurrency: 1, Timeout: time.Second},
+		slog.New(slog.NewTextHandler(io.Discard, nil)))
+	samples := []float64{}
+	calculate.sample = func() float64 {
+		next := samples[0]
+		samples = samples[1:]
+		return next
+	}
+	compared := func(want int64) domain.CalculateExperimentReport {
+		t.Helper()
+		deadline := time.Now().Add(time.Second)
+		for {
+			report := calculate.Report()
+			if report.Compared+report.Failed >= want || time.Now().After(deadline) {
+				return report
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}
+	period := domain.CalculateTotalRequest{StartPeriod: "01-2025", EndPeriod: "01-2025"}
+
+	// Расчет Алисы отдает текущая реализация, и новая с ней согласна
+	samples = []float64{70, 0}
+	req := period
+	req.UserID = &alice
+	totals, err := calculate.CalculateTotal(ctx, req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := totals.Get(domain.DefaultCurrency).Amount; got != 100 {
+		t.Errorf("alice total = %d, want 100", got)
+	}
+	if report := compared(1); report.Matched != 1 || report.Mismatched != 0 {
+		t.Fatalf("report after alice = %+v, want one match", report)
+	}
+
+	// Расчет тенанта отдает новая реализация, а сравнение находит расхождение
+	samples = []float64{10, 0}
+	if totals, err = calculate.CalculateTotal(ctx, period); err != nil {
+		t.Fatal(err)
+	}
+	if got := totals.Get(domain.DefaultCurrency).Amount; got != 301 {
+		t.Errorf("candidate tenant total = %d, want 301", got)
+	}
+	report := compared(2)
+	if report.Mismatched != 1 || len(report.Mismatches) != 1 {
+		t.Fatalf("report after tenant total = %+v, want one mismatch", report)
+	}
+	mismatch := report.Mismatches[0]
+	if mismatch.Served != domain.ExperimentCandidate || mismatch.Control[domain.DefaultCurrency] != 300 || mismatch.Candidate[domain.DefaultCurrency] != 301 {
+		t.Errorf("mismatch = %+v, want control 300 and candidate 301 served by candidate", mismatch)
+	}
+	if mismatch.Query != "end_period=01-2025&start_period=01-2025" {
+		t.Errorf("mismatch query = %q", mismatch.Query)
+	}
+
+	// Подытоги пользователей указывают, кто из них разошелся
+	samples = []float64{70, 0}
+	req = period
+	req.UserIDs = []uuid.UUID{alice, bob}
+	if _, err := calculate.CalculateTotalByUser(ctx, req); err != nil {
+		t.Fatal(err)
+	}
+	report = compared(3)
+	if report.Mismatched != 2 || report.Mismatches[0].UserID == nil || *report.Mismatches[0].UserID != bob || report.Mismatches[0].DifferingUsers != 1 {
+		t.Fatalf("report after by_user = %+v, want bob's mismatch first", report)
+	}
+
+	// Без выборки для сравнения расчет не повторяется
+	samples = []float64{70, 100}
+	if _, err := calculate.CalculateTotal(ctx, period); err != nil {
+		t.Fatal(err)
+	}
+	report = calculate.Report()
+	if report.Served[domain.ExperimentControl] != 3 || report.Served[domain.ExperimentCandidate] != 1 || report.Compared != 3 {
+		t.Errorf("report = %+v, want 3 control, 1 candidate, 3 compared", report)
+	}
+}
@@ -0,0 +1,44 @@
+package http
+
+import (
+	"context"
+	"net/http"
+
+	"aggregator_db/internal/access"
+	"aggregator_db/internal/middleware"
+	"aggregator_db/internal/service"
+	"aggregator_db/pkg/domain"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// authorizeUser проверяет, что пользователь запроса может создавать данные
+// пользователя userID из тела запроса; при отказе ответ 403 уже отправлен.
+// Фильтры user_id из query и ID в пути проверяют middleware.ScopeUserID и middleware.Owner.
+func authorizeUser(c *gin.Context, userID uuid.UUID) bool {
+	if principal := access.FromContext(c.Request.Context()); principal != nil && !principal.CanAccess(userID) {
+		respondErrorCode(c, http.StatusForbidden, domain.CodeAccessDenied, "access denied")
+		return false
+	}
+	return true
+}
+
+func subscriptionOwner(subscriptions *service.SubscriptionService) middleware.OwnerResolver {
+	return func(ctx context.Context, id uuid.UUID) (uuid.UUID, error) {
+		sub, err := subscriptions.GetByID(ctx, id)
+		if err != nil {
+			return uuid.Nil, err
+		}
+		return sub.UserID, nil
+	}
+}
+
+func budgetOwner(budgets *service.BudgetService) middleware.OwnerResolver {
+	return func(ctx context.Context, id uuid.UUID) (uuid.UUID, error) {
+		budget, err := budgets.Get(ctx, id)
+		if err != nil {
+			return uuid.Nil, err
+		}
+		return budget.UserID, nil
+	}
+}
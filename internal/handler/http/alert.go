@@ -0,0 +1,92 @@
+package http
+
+import (
+	"net/http"
+
+	"aggregator_db/internal/access"
+	"aggregator_db/internal/service"
+	"aggregator_db/pkg/domain"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type AlertHandler struct {
+	service *service.AlertService
+}
+
+func NewAlertHandler(service *service.AlertService) *AlertHandler {
+	return &AlertHandler{service: service}
+}
+
+// ListAlerts godoc
+// @Summary      Критические уведомления пользователя
+// @Description  Уведомления, которые ждут подтверждения, и уже подтвержденные, новые сверху. Уведомление заводится для budget.exceeded, если в настройках уведомлений задана цепочка escalation; step - сколько ее шагов уже выполнено
+// @Tags         users
+// @Produce      json
+// @Param        id path string true "ID пользователя" Format(uuid)
+// @Success      200 {array} domain.Alert
+// @Failure      400 {object} domain.ErrorResponse
+// @Failure      404 {object} domain.ErrorResponse
+// @Failure      500 {object} domain.ErrorResponse
+// @Router       /users/{id}/alerts [get]
+func (h *AlertHandler) ListAlerts(c *gin.Context) {
+	userID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		respondBadRequest(c, errInvalidUserID)
+		return
+	}
+
+	alerts, err := h.service.List(c.Request.Context(), userID)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, alerts)
+}
+
+// AcknowledgeAlert godoc
+// @Summary      Подтвердить уведомление
+// @Description  Останавливает эскалацию уведомления. Подтвердить может владелец, получатель любого шага цепочки или администратор; повторное подтверждение не меняет время и автора
+// @Tags         users
+// @Produce      json
+// @Param        id path string true "ID уведомления" Format(uuid)
+// @Success      200 {object} domain.Alert
+// @Failure      400 {object} domain.ErrorResponse
+// @Failure      403 {object} domain.ErrorResponse
+// @Failure      404 {object} domain.ErrorResponse
+// @Failure      500 {object} domain.ErrorResponse
+// @Router       /alerts/{id}/acknowledge [post]
+func (h *AlertHandler) AcknowledgeAlert(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		respondErrorCode(c, http.StatusBadRequest, domain.CodeInvalidID, "invalid alert id")
+		return
+	}
+
+	ctx := c.Request.Context()
+	alert, err := h.service.Get(ctx, id)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	var by *uuid.UUID
+	if principal := access.FromContext(ctx); principal != nil {
+		if !principal.IsAdmin() && !alert.Notifies(principal.UserID) {
+			respondErrorCode(c, http.StatusForbidden, domain.CodeAccessDenied, "access denied")
+			return
+		}
+		if principal.UserID != uuid.Nil {
+			by = &principal.UserID
+		}
+	}
+
+	alert, err = h.service.Acknowledge(ctx, id, by)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, alert)
+}
@@ -0,0 +1,46 @@
+package http
+
+import (
+	"net/http"
+
+	"aggregator_db/pkg/domain"
+	"github.com/gin-gonic/gin"
+)
+
+// YearOverYear godoc
+// @Summary      Сравнение трат год к году
+// @Description  Траты по каждому месяцу года year и того же месяца предыдущего года с абсолютным и процентным изменением. Всегда 12 месяцев; delta_percent равен null, если в прошлом году трат в месяце не было
+// @Tags         analytics
+// @Produce      json
+// @Param        year query int true "Год сравнения (сравнивается с year-1)"
+// @Param        user_id query string false "ID пользователя (устаревший вариант: userId); пустое значение отклоняется с 400" Format(uuid)
+// @Param        service_name query string false "Название сервиса (с учетом транслитерации и алиасов)"
+// @Param        currency query string false "Валюта сравнения, подписки в других валютах не учитываются (по умолчанию RUB)"
+// @Param        tag query []string false "Учитывать только подписки со всеми указанными метками" collectionFormat(multi)
+// @Success      200 {object} domain.YearOverYearResponse
+// @Failure      400 {object} domain.ErrorResponse
+// @Failure      500 {object} domain.ErrorResponse
+// @Failure      503 {object} domain.ErrorResponse
+// @Router       /analytics/yoy [get]
+func (h *SubscriptionHandler) YearOverYear(c *gin.Context) {
+	var req domain.YearOverYearRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		respondBadRequest(c, err)
+		return
+	}
+
+	userID, err := parseUserIDQuery(c)
+	if err != nil {
+		respondBadRequest(c, err)
+		return
+	}
+	req.UserID = userID
+
+	result, err := h.service.YearOverYear(c.Request.Context(), req)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
@@ -0,0 +1,97 @@
+package http
+
+import (
+	"net/http"
+
+	"aggregator_db/internal/service"
+	"aggregator_db/pkg/domain"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type APIKeyHandler struct {
+	service *service.APIKeyService
+}
+
+func NewAPIKeyHandler(service *service.APIKeyService) *APIKeyHandler {
+	return &APIKeyHandler{service: service}
+}
+
+// CreateAPIKey godoc
+// @Summary      Выпустить ключ сервиса
+// @Description  Ключ для вызовов API внутренними сервисами в заголовке X-API-Key. scope=read разрешает только GET, HEAD и OPTIONS, read_write - любые запросы. Ключ показывается только в этом ответе
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        X-Admin-Token header string true "Токен администратора"
+// @Param        key body domain.CreateAPIKeyRequest true "Ключ"
+// @Success      201 {object} domain.APIKeyCredentials
+// @Failure      400 {object} domain.ErrorResponse
+// @Failure      401 {object} domain.ErrorResponse
+// @Failure      403 {object} domain.ErrorResponse
+// @Failure      500 {object} domain.ErrorResponse
+// @Router       /admin/api-keys [post]
+func (h *APIKeyHandler) CreateAPIKey(c *gin.Context) {
+	var req domain.CreateAPIKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBadRequest(c, err)
+		return
+	}
+
+	credentials, err := h.service.Create(c.Request.Context(), req)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, credentials)
+}
+
+// ListAPIKeys godoc
+// @Summary      Ключи сервисов
+// @Description  Возвращает выпущенные ключи, включая отозванные, без самих ключей
+// @Tags         admin
+// @Produce      json
+// @Param        X-Admin-Token header string true "Токен администратора"
+// @Success      200 {array} domain.APIKey
+// @Failure      401 {object} domain.ErrorResponse
+// @Failure      403 {object} domain.ErrorResponse
+// @Failure      500 {object} domain.ErrorResponse
+// @Router       /admin/api-keys [get]
+func (h *APIKeyHandler) ListAPIKeys(c *gin.Context) {
+	keys, err := h.service.List(c.Request.Context())
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, keys)
+}
+
+// RevokeAPIKey godoc
+// @Summary      Отозвать ключ сервиса
+// @Description  Запросы с отозванным ключом сразу получают 401
+// @Tags         admin
+// @Produce      json
+// @Param        X-Admin-Token header string true "Токен администратора"
+// @Param        id path string true "ID ключа" Format(uuid)
+// @Success      200 {object} domain.SuccessResponse
+// @Failure      400 {object} domain.ErrorResponse
+// @Failure      401 {object} domain.ErrorResponse
+// @Failure      403 {object} domain.ErrorResponse
+// @Failure      404 {object} domain.ErrorResponse
+// @Router       /admin/api-keys/{id} [delete]
+func (h *APIKeyHandler) RevokeAPIKey(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		respondErrorCode(c, http.StatusBadRequest, domain.CodeInvalidID, "invalid api key id")
+		return
+	}
+
+	if err := h.service.Revoke(c.Request.Context(), id); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, domain.SuccessResponse{Message: "api key revoked"})
+}
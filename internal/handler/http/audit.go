@@ -0,0 +1,53 @@
+package http
+
+import (
+	"net/http"
+
+	"aggregator_db/internal/service"
+	"aggregator_db/pkg/domain"
+	"github.com/gin-gonic/gin"
+)
+
+type AuditHandler struct {
+	service *service.AuditService
+}
+
+func NewAuditHandler(service *service.AuditService) *AuditHandler {
+	return &AuditHandler{service: service}
+}
+
+// ListAudit godoc
+// @Summary      Журнал изменений
+// @Description  Изменения подписок, скидок, пользователей и бюджетов тенанта, новые сверху: кто изменил (actor), в каком запросе (X-Request-ID) и строка до и после изменения. Запись делается в транзакции изменения. Фильтры объединяются через AND
+// @Tags         admin
+// @Produce      json
+// @Param        X-Admin-Token header string true "Токен администратора"
+// @Param        entity query string false "Сущность" Enums(subscriptions, subscription_discounts, users, budgets)
+// @Param        entity_id query string false "ID строки"
+// @Param        action query string false "Вид изменения" Enums(create, update, delete)
+// @Param        actor query string false "Исполнитель: user:<id>, api_key:<имя>, app:<id>, admin, anonymous или system"
+// @Param        request_id query string false "ID запроса"
+// @Param        from query string false "Начало периода, RFC 3339"
+// @Param        to query string false "Конец периода, не включается, RFC 3339"
+// @Param        limit query int false "Размер страницы" default(100)
+// @Param        offset query int false "Смещение"
+// @Success      200 {array} domain.AuditEntry
+// @Failure      400 {object} domain.ErrorResponse
+// @Failure      401 {object} domain.ErrorResponse
+// @Failure      500 {object} domain.ErrorResponse
+// @Router       /admin/audit [get]
+func (h *AuditHandler) ListAudit(c *gin.Context) {
+	var query domain.ListAuditQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		respondBadRequest(c, err)
+		return
+	}
+
+	entries, err := h.service.List(c.Request.Context(), query)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, entries)
+}
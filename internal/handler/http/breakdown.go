@@ -0,0 +1,96 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"aggregator_db/pkg/domain"
+	"github.com/gin-gonic/gin"
+)
+
+// CalculateBreakdown godoc
+// @Summary      Помесячная разбивка стоимости
+// @Description  Стоимость каждого месяца периода с разбивкой по классам месяцев, страницами по limit месяцев. Следующая страница запрашивается с теми же параметрами и continuation из next_continuation.
+// @Description  С заголовком Accept: application/x-ndjson месяцы отдаются потоком по мере расчета (chunked), по строке JSON на месяц; последняя строка содержит next_continuation, а при сбое посреди потока - error и токен для продолжения с первого неотправленного месяца
+// @Tags         subscriptions
+// @Produce      json
+// @Produce      application/x-ndjson
+// @Param        user_id query string false "ID пользователя (устаревший вариант: userId); пустое значение отклоняется с 400" Format(uuid)
+// @Param        service_name query string false "Название сервиса (с учетом транслитерации и алиасов)"
+// @Param        start_period query string true "Начало периода" Format(MM-YYYY)
+// @Param        end_period query string true "Конец периода" Format(MM-YYYY)
+// @Param        exclude_inactive query bool false "Не учитывать месяцы, когда подписка была на паузе или отменена"
+// @Param        currency query string false "Валюта суммы, подписки в других валютах не учитываются (по умолчанию RUB)"
+// @Param        target_currency query string false "Пересчитать подписки во всех валютах в эту валюту по текущему курсу"
+// @Param        tag query []string false "Учитывать только подписки со всеми указанными метками" collectionFormat(multi)
+// @Param        include_items query bool false "Добавить в каждый месяц оплаченные месяцы подписок, из которых сложилась его сумма (items), до 5000 на страницу"
+// @Param        limit query int false "Месяцев на странице (1-120, по умолчанию 12)"
+// @Param        continuation query string false "Токен продолжения из next_continuation"
+// @Success      200 {object} domain.CalculateBreakdownResponse
+// @Failure      400 {object} domain.ErrorResponse
+// @Failure      500 {object} domain.ErrorResponse
+// @Failure      503 {object} domain.ErrorResponse
+// @Router       /subscriptions/calculate/breakdown [get]
+func (h *SubscriptionHandler) CalculateBreakdown(c *gin.Context) {
+	var req domain.CalculateBreakdownRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		respondBadRequest(c, err)
+		return
+	}
+
+	userID, err := parseUserIDQuery(c)
+	if err != nil {
+		respondBadRequest(c, err)
+		return
+	}
+	req.UserID = userID
+
+	if strings.Contains(c.GetHeader("Accept"), domain.BreakdownStreamContentType) {
+		h.streamBreakdown(c, req)
+		return
+	}
+
+	result, err := h.service.CalculateBreakdown(c.Request.Context(), req)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// streamBreakdown пишет месяцы по мере расчета и сбрасывает каждую строку клиенту.
+// Пока ничего не отправлено, ошибка отдается обычным ответом с кодом.
+func (h *SubscriptionHandler) streamBreakdown(c *gin.Context, req domain.CalculateBreakdownRequest) {
+	enc := json.NewEncoder(c.Writer)
+	started := false
+	start := func() {
+		if !started {
+			c.Header("Content-Type", domain.BreakdownStreamContentType)
+			c.Status(http.StatusOK)
+			started = true
+		}
+	}
+
+	next, err := h.service.StreamBreakdown(c.Request.Context(), req, func(month domain.MonthBreakdown) error {
+		start()
+		if err := enc.Encode(month); err != nil {
+			return err
+		}
+		c.Writer.Flush()
+		return nil
+	})
+	if err != nil && !started {
+		respondError(c, err)
+		return
+	}
+
+	start()
+	end := domain.BreakdownStreamEnd{NextContinuation: next}
+	if err != nil {
+		end.Error = err.Error()
+	}
+	_ = enc.Encode(end)
+	c.Writer.Flush()
+}
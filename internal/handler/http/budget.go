@@ -0,0 +1,208 @@
+package http
+
+import (
+	"net/http"
+
+	"aggregator_db/internal/service"
+	"aggregator_db/pkg/domain"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type BudgetHandler struct {
+	service *service.BudgetService
+}
+
+func NewBudgetHandler(service *service.BudgetService) *BudgetHandler {
+	return &BudgetHandler{service: service}
+}
+
+// requiredUserID читает обязательный user_id из query; при ошибке ответ уже отправлен.
+func requiredUserID(c *gin.Context) (uuid.UUID, bool) {
+	userID, err := parseUserIDQuery(c)
+	if err != nil {
+		respondBadRequest(c, err)
+		return uuid.Nil, false
+	}
+	if userID == nil {
+		respondErrorCode(c, http.StatusBadRequest, domain.CodeValidationFailed, "user_id is required")
+		return uuid.Nil, false
+	}
+	return *userID, true
+}
+
+// CreateBudget godoc
+// @Summary      Создать конверт бюджета
+// @Description  Месячный лимит трат на категорию: в конверт входят подписки пользователя с меткой category в валюте лимита. У пользователя один конверт на категорию
+// @Tags         budgets
+// @Accept       json
+// @Produce      json
+// @Param        budget body domain.CreateBudgetRequest true "Конверт"
+// @Success      201 {object} domain.Budget
+// @Failure      400 {object} domain.ErrorResponse
+// @Failure      404 {object} domain.ErrorResponse
+// @Failure      409 {object} domain.ErrorResponse
+// @Failure      500 {object} domain.ErrorResponse
+// @Router       /budgets [post]
+func (h *BudgetHandler) CreateBudget(c *gin.Context) {
+	var req domain.CreateBudgetRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBadRequest(c, err)
+		return
+	}
+	if !authorizeUser(c, req.UserID) {
+		return
+	}
+
+	budget, err := h.service.Create(c.Request.Context(), req)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, budget)
+}
+
+// ListBudgets godoc
+// @Summary      Конверты пользователя
+// @Description  Возвращает конверты бюджета пользователя по категориям
+// @Tags         budgets
+// @Produce      json
+// @Param        user_id query string true "ID пользователя" Format(uuid)
+// @Success      200 {array} domain.Budget
+// @Failure      400 {object} domain.ErrorResponse
+// @Failure      404 {object} domain.ErrorResponse
+// @Failure      500 {object} domain.ErrorResponse
+// @Router       /budgets [get]
+func (h *BudgetHandler) ListBudgets(c *gin.Context) {
+	userID, ok := requiredUserID(c)
+	if !ok {
+		return
+	}
+
+	budgets, err := h.service.List(c.Request.Context(), userID)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, budgets)
+}
+
+// GetBudgetStatus godoc
+// @Summary      Состояние бюджета
+// @Description  Траты каждого конверта пользователя за месяц относительно лимита (с учетом скидок, без месяцев на паузе) и итоги по валютам. state: ok, warning (достигнут alert_percent) или exceeded (лимит превышен)
+// @Tags         budgets
+// @Produce      json
+// @Param        user_id query string true "ID пользователя" Format(uuid)
+// @Param        month query string false "Месяц, по умолчанию текущий" Format(MM-YYYY)
+// @Success      200 {object} domain.BudgetStatusResponse
+// @Failure      400 {object} domain.ErrorResponse
+// @Failure      404 {object} domain.ErrorResponse
+// @Failure      500 {object} domain.ErrorResponse
+// @Router       /budgets/status [get]
+func (h *BudgetHandler) GetBudgetStatus(c *gin.Context) {
+	userID, ok := requiredUserID(c)
+	if !ok {
+		return
+	}
+
+	var query domain.BudgetStatusQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		respondBadRequest(c, err)
+		return
+	}
+
+	status, err := h.service.Status(c.Request.Context(), userID, query)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, status)
+}
+
+// GetBudget godoc
+// @Summary      Получить конверт бюджета
+// @Tags         budgets
+// @Produce      json
+// @Param        id path string true "ID конверта" Format(uuid)
+// @Success      200 {object} domain.Budget
+// @Failure      400 {object} domain.ErrorResponse
+// @Failure      404 {object} domain.ErrorResponse
+// @Router       /budgets/{id} [get]
+func (h *BudgetHandler) GetBudget(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		respondErrorCode(c, http.StatusBadRequest, domain.CodeInvalidID, "invalid budget id")
+		return
+	}
+
+	budget, err := h.service.Get(c.Request.Context(), id)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, budget)
+}
+
+// UpdateBudget godoc
+// @Summary      Изменить конверт бюджета
+// @Description  Частичное обновление: категория, лимит и порог предупреждения; не переданные поля не меняются
+// @Tags         budgets
+// @Accept       json
+// @Produce      json
+// @Param        id path string true "ID конверта" Format(uuid)
+// @Param        budget body domain.UpdateBudgetRequest true "Изменения"
+// @Success      200 {object} domain.Budget
+// @Failure      400 {object} domain.ErrorResponse
+// @Failure      404 {object} domain.ErrorResponse
+// @Failure      409 {object} domain.ErrorResponse
+// @Failure      500 {object} domain.ErrorResponse
+// @Router       /budgets/{id} [patch]
+func (h *BudgetHandler) UpdateBudget(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		respondErrorCode(c, http.StatusBadRequest, domain.CodeInvalidID, "invalid budget id")
+		return
+	}
+
+	var req domain.UpdateBudgetRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBadRequest(c, err)
+		return
+	}
+
+	budget, err := h.service.Update(c.Request.Context(), id, req)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, budget)
+}
+
+// DeleteBudget godoc
+// @Summary      Удалить конверт бюджета
+// @Tags         budgets
+// @Produce      json
+// @Param        id path string true "ID конверта" Format(uuid)
+// @Success      200 {object} domain.SuccessResponse
+// @Failure      400 {object} domain.ErrorResponse
+// @Failure      404 {object} domain.ErrorResponse
+// @Router       /budgets/{id} [delete]
+func (h *BudgetHandler) DeleteBudget(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		respondErrorCode(c, http.StatusBadRequest, domain.CodeInvalidID, "invalid budget id")
+		return
+	}
+
+	if err := h.service.Delete(c.Request.Context(), id); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, domain.SuccessResponse{Message: "budget deleted"})
+}
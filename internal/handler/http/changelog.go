@@ -0,0 +1,43 @@
+package http
+
+import (
+	"net/http"
+
+	"aggregator_db/internal/changelog"
+	"aggregator_db/pkg/domain"
+	"github.com/gin-gonic/gin"
+)
+
+type ChangelogHandler struct {
+	changelog *changelog.Changelog
+}
+
+func NewChangelogHandler(changelog *changelog.Changelog) *ChangelogHandler {
+	return &ChangelogHandler{changelog: changelog}
+}
+
+// ListChangelog godoc
+// @Summary      Журнал изменений API
+// @Description  Версии API, новые сверху: затронутые ручки, признак несовместимых изменений (breaking) и даты удаления устаревших ручек и параметров (sunset). Клиент передает в since последнюю известную ему версию и получает только новые
+// @Tags         changelog
+// @Produce      json
+// @Param        since query string false "Последняя известная версия, MAJOR.MINOR.PATCH"
+// @Param        breaking query bool false "Только версии с несовместимыми изменениями"
+// @Param        endpoint query string false "Только изменения ручки, например GET /api/v1/subscriptions/{id}"
+// @Success      200 {object} domain.Changelog
+// @Failure      400 {object} domain.ErrorResponse
+// @Router       /changelog [get]
+func (h *ChangelogHandler) ListChangelog(c *gin.Context) {
+	var query domain.ChangelogQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		respondBadRequest(c, err)
+		return
+	}
+
+	result, err := h.changelog.List(query)
+	if err != nil {
+		respondBadRequest(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
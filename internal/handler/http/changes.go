@@ -0,0 +1,53 @@
+package http
+
+import (
+	"net/http"
+
+	"aggregator_db/internal/service"
+	"aggregator_db/pkg/domain"
+	"github.com/gin-gonic/gin"
+)
+
+type ChangesHandler struct {
+	service *service.ChangesService
+}
+
+func NewChangesHandler(service *service.ChangesService) *ChangesHandler {
+	return &ChangesHandler{service: service}
+}
+
+// ListSubscriptionChanges godoc
+// @Summary      Лента изменений подписок
+// @Description  Создания, изменения и удаления подписок после курсора since в порядке коммита. Если изменений нет, запрос ждет их до wait (не дольше CHANGES_MAX_WAIT) и по истечении отвечает пустым списком с новым курсором. Без since лента начинается с текущего момента: первый запрос возвращает только курсор. Курсор из ответа передается в since следующего запроса
+// @Tags         subscriptions
+// @Produce      json
+// @Param        user_id query string false "ID пользователя (устаревший вариант: userId); пустое значение отклоняется с 400" Format(uuid)
+// @Param        since query string false "Курсор предыдущего ответа"
+// @Param        wait query string false "Сколько ждать изменений, например 30s; без него ответ сразу"
+// @Param        limit query int false "Размер страницы" default(100) maximum(500)
+// @Success      200 {object} domain.SubscriptionChanges
+// @Failure      400 {object} domain.ErrorResponse
+// @Failure      500 {object} domain.ErrorResponse
+// @Router       /subscriptions/changes [get]
+func (h *ChangesHandler) ListSubscriptionChanges(c *gin.Context) {
+	var query domain.SubscriptionChangesQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		respondBadRequest(c, err)
+		return
+	}
+
+	userID, err := parseUserIDQuery(c)
+	if err != nil {
+		respondBadRequest(c, err)
+		return
+	}
+	query.UserID = userID
+
+	changes, err := h.service.Wait(c.Request.Context(), query)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, changes)
+}
@@ -0,0 +1,55 @@
+package http
+
+import (
+	"fmt"
+	"net/http"
+
+	"aggregator_db/internal/clients"
+	"github.com/gin-gonic/gin"
+)
+
+type ClientsHandler struct {
+	catalog *clients.Catalog
+}
+
+func NewClientsHandler(catalog *clients.Catalog) *ClientsHandler {
+	return &ClientsHandler{catalog: catalog}
+}
+
+// ListClientArtifacts godoc
+// @Summary      Файлы клиентов API
+// @Description  Спецификация OpenAPI этой сборки, конфигурации openapi-generator для TypeScript и Python, скрипт сборки и готовые пакеты, если образ собран с ними. Версия пакетов - версия API из журнала изменений
+// @Tags         clients
+// @Produce      json
+// @Success      200 {object} domain.ClientArtifacts
+// @Router       /clients [get]
+func (h *ClientsHandler) ListClientArtifacts(c *gin.Context) {
+	c.JSON(http.StatusOK, h.catalog.List())
+}
+
+// DownloadClientArtifact godoc
+// @Summary      Скачать файл клиента API
+// @Description  Файл из списка /clients. ETag - sha256 содержимого: с If-None-Match клиент получает 304, пока файл не изменился
+// @Tags         clients
+// @Produce      octet-stream
+// @Param        name path string true "Имя файла, например typescript.tgz"
+// @Success      200 {string} string "Содержимое файла"
+// @Success      304 "Файл не изменился"
+// @Failure      404 {object} domain.ErrorResponse
+// @Router       /clients/{name} [get]
+func (h *ClientsHandler) DownloadClientArtifact(c *gin.Context) {
+	artifact, err := h.catalog.Get(c.Param("name"))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	etag := `"` + artifact.SHA256 + `"`
+	c.Header("ETag", etag)
+	if c.GetHeader("If-None-Match") == etag {
+		c.Status(http.StatusNotModified)
+		return
+	}
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, artifact.Name))
+	c.Data(http.StatusOK, artifact.ContentType, artifact.Content)
+}
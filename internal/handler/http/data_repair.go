@@ -0,0 +1,98 @@
+package http
+
+import (
+	"net/http"
+
+	"aggregator_db/internal/service"
+	"aggregator_db/pkg/domain"
+	"github.com/gin-gonic/gin"
+)
+
+type DataRepairHandler struct {
+	service *service.DataRepairService
+}
+
+func NewDataRepairHandler(service *service.DataRepairService) *DataRepairHandler {
+	return &DataRepairHandler{service: service}
+}
+
+// ListDataIssues godoc
+// @Summary      Проверить целостность данных
+// @Description  Проверяет все подписки тенанта и ничего не меняет: end_before_start - end_date раньше start_date, invalid_start_date и invalid_end_date - месяц не в формате MM-YYYY, negative_price - отрицательная цена, orphaned_user - пользователь подписки не существует. fix - исправление из DATA_REPAIR_FIXES, которое применит POST /admin/data-issues/repair
+// @Tags         admin
+// @Produce      json
+// @Param        X-Admin-Token header string true "Токен администратора"
+// @Success      200 {object} domain.DataIssueReport
+// @Failure      401 {object} domain.ErrorResponse
+// @Failure      500 {object} domain.ErrorResponse
+// @Router       /admin/data-issues [get]
+func (h *DataRepairHandler) ListDataIssues(c *gin.Context) {
+	report, err := h.service.Scan(c.Request.Context())
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// RepairDataIssues godoc
+// @Summary      Исправить данные
+// @Description  Проверяет подписки пачками по batch_size (по умолчанию DATA_REPAIR_BATCH_SIZE) и применяет настроенные исправления; каждая пачка - одна транзакция, каждое исправление записывается в журнал. kinds ограничивает исправляемые виды нарушений. Тело необязательно
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        X-Admin-Token header string true "Токен администратора"
+// @Param        request body domain.DataRepairRequest false "Что исправлять"
+// @Success      200 {object} domain.DataIssueReport
+// @Failure      400 {object} domain.ErrorResponse
+// @Failure      401 {object} domain.ErrorResponse
+// @Failure      500 {object} domain.ErrorResponse
+// @Router       /admin/data-issues/repair [post]
+func (h *DataRepairHandler) RepairDataIssues(c *gin.Context) {
+	var req domain.DataRepairRequest
+
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			respondBadRequest(c, err)
+			return
+		}
+	}
+
+	report, err := h.service.Repair(c.Request.Context(), req)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// ListDataRepairs godoc
+// @Summary      Журнал исправлений данных
+// @Description  Примененные исправления, новые сверху: какое поле подписки изменено, с какого значения и на какое
+// @Tags         admin
+// @Produce      json
+// @Param        X-Admin-Token header string true "Токен администратора"
+// @Param        limit query int false "Размер страницы" default(100)
+// @Param        offset query int false "Смещение"
+// @Success      200 {array} domain.DataRepair
+// @Failure      400 {object} domain.ErrorResponse
+// @Failure      401 {object} domain.ErrorResponse
+// @Failure      500 {object} domain.ErrorResponse
+// @Router       /admin/data-repairs [get]
+func (h *DataRepairHandler) ListDataRepairs(c *gin.Context) {
+	var query domain.ListDataRepairsQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		respondBadRequest(c, err)
+		return
+	}
+
+	repairs, err := h.service.ListRepairs(c.Request.Context(), query)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, repairs)
+}
@@ -0,0 +1,48 @@
+package http
+
+import (
+	"net/http"
+	"net/http/pprof"
+
+	"aggregator_db/internal/clock"
+	"aggregator_db/internal/diagnostics"
+	"github.com/gin-gonic/gin"
+)
+
+type DebugHandler struct {
+	dumpDir string
+}
+
+func NewDebugHandler(dumpDir string) *DebugHandler {
+	return &DebugHandler{dumpDir: dumpDir}
+}
+
+// Pprof отдает профили net/http/pprof: /debug/pprof/ - список, /debug/pprof/profile -
+// CPU за seconds секунд, /debug/pprof/heap, /debug/pprof/goroutine и остальные по имени.
+func (h *DebugHandler) Pprof(c *gin.Context) {
+	switch name := c.Param("profile"); name {
+	case "", "/":
+		pprof.Index(c.Writer, c.Request)
+	case "/cmdline":
+		pprof.Cmdline(c.Writer, c.Request)
+	case "/profile":
+		pprof.Profile(c.Writer, c.Request)
+	case "/symbol":
+		pprof.Symbol(c.Writer, c.Request)
+	case "/trace":
+		pprof.Trace(c.Writer, c.Request)
+	default:
+		pprof.Handler(name[1:]).ServeHTTP(c.Writer, c.Request)
+	}
+}
+
+// Dump сохраняет стеки горутин и профиль кучи на диск реплики и возвращает пути к файлам.
+func (h *DebugHandler) Dump(c *gin.Context) {
+	dump, err := diagnostics.WriteRuntimeDump(h.dumpDir, clock.Now(c.Request.Context()))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, dump)
+}
@@ -0,0 +1,163 @@
+package http
+
+import (
+	"net/http"
+
+	"aggregator_db/internal/developer"
+	"aggregator_db/internal/service"
+	"aggregator_db/pkg/domain"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type DeveloperHandler struct {
+	service *service.DeveloperService
+}
+
+func NewDeveloperHandler(service *service.DeveloperService) *DeveloperHandler {
+	return &DeveloperHandler{service: service}
+}
+
+// RegisterApp godoc
+// @Summary      Зарегистрировать приложение
+// @Description  Регистрирует приложение стороннего разработчика и выдает ключ песочницы: запросы с ним работают с отдельной схемой, которая периодически сбрасывается. Ключ передается в заголовке X-API-Key и возвращается только в этом ответе
+// @Tags         developer
+// @Accept       json
+// @Produce      json
+// @Param        app body domain.RegisterDeveloperAppRequest true "Приложение"
+// @Success      201 {object} domain.DeveloperAppCredentials
+// @Failure      400 {object} domain.ErrorResponse
+// @Failure      500 {object} domain.ErrorResponse
+// @Router       /developer/apps [post]
+func (h *DeveloperHandler) RegisterApp(c *gin.Context) {
+	var req domain.RegisterDeveloperAppRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBadRequest(c, err)
+		return
+	}
+
+	credentials, err := h.service.Register(c.Request.Context(), req)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, credentials)
+}
+
+// GetApp godoc
+// @Summary      Текущее приложение
+// @Tags         developer
+// @Produce      json
+// @Param        X-API-Key header string true "Ключ приложения"
+// @Success      200 {object} domain.DeveloperApp
+// @Failure      401 {object} domain.ErrorResponse
+// @Failure      429 {object} domain.ErrorResponse
+// @Router       /developer/app [get]
+func (h *DeveloperHandler) GetApp(c *gin.Context) {
+	c.JSON(http.StatusOK, developer.FromContext(c.Request.Context()))
+}
+
+// GetAppUsage godoc
+// @Summary      Потребление приложения
+// @Description  Суточное потребление API приложением за период. Данные сбрасываются в хранилище периодически и могут отставать
+// @Tags         developer
+// @Produce      json
+// @Param        X-API-Key header string true "Ключ приложения"
+// @Param        from query string true "Начало периода (YYYY-MM-DD)"
+// @Param        to query string true "Конец периода включительно (YYYY-MM-DD)"
+// @Success      200 {object} domain.UsageResponse
+// @Failure      400 {object} domain.ErrorResponse
+// @Failure      401 {object} domain.ErrorResponse
+// @Failure      429 {object} domain.ErrorResponse
+// @Failure      500 {object} domain.ErrorResponse
+// @Router       /developer/app/usage [get]
+func (h *DeveloperHandler) GetAppUsage(c *gin.Context) {
+	var query domain.UsageQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		respondBadRequest(c, err)
+		return
+	}
+
+	usage, err := h.service.Usage(c.Request.Context(), developer.FromContext(c.Request.Context()), query.From, query.To)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, usage)
+}
+
+// GetAppRateLimit godoc
+// @Summary      Лимит запросов приложения
+// @Description  Лимит в минуту, остаток и время сброса текущего окна с учетом этого запроса. Те же значения приходят в заголовках X-RateLimit-* каждого ответа
+// @Tags         developer
+// @Produce      json
+// @Param        X-API-Key header string true "Ключ приложения"
+// @Success      200 {object} domain.RateLimitStatus
+// @Failure      401 {object} domain.ErrorResponse
+// @Failure      429 {object} domain.ErrorResponse
+// @Router       /developer/app/rate-limit [get]
+func (h *DeveloperHandler) GetAppRateLimit(c *gin.Context) {
+	c.JSON(http.StatusOK, h.service.RateLimit(developer.FromContext(c.Request.Context())))
+}
+
+// RotateAppSecret godoc
+// @Summary      Ротация ключа приложения
+// @Description  Выдает новый ключ; прежний перестает работать сразу. Новый ключ возвращается только в этом ответе
+// @Tags         developer
+// @Produce      json
+// @Param        X-API-Key header string true "Ключ приложения"
+// @Success      200 {object} domain.DeveloperAppCredentials
+// @Failure      401 {object} domain.ErrorResponse
+// @Failure      429 {object} domain.ErrorResponse
+// @Failure      500 {object} domain.ErrorResponse
+// @Router       /developer/app/rotate-secret [post]
+func (h *DeveloperHandler) RotateAppSecret(c *gin.Context) {
+	credentials, err := h.service.RotateSecret(c.Request.Context(), developer.FromContext(c.Request.Context()))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, credentials)
+}
+
+// UpdateDeveloperApp godoc
+// @Summary      Изменить приложение разработчика
+// @Description  Переводит приложение из песочницы в боевой режим (sandbox=false) и обратно, меняет лимит запросов в минуту
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        X-Admin-Token header string true "Токен администратора"
+// @Param        id path string true "ID приложения" Format(uuid)
+// @Param        app body domain.UpdateDeveloperAppRequest true "Изменения"
+// @Success      200 {object} domain.DeveloperApp
+// @Failure      400 {object} domain.ErrorResponse
+// @Failure      401 {object} domain.ErrorResponse
+// @Failure      403 {object} domain.ErrorResponse
+// @Failure      404 {object} domain.ErrorResponse
+// @Failure      500 {object} domain.ErrorResponse
+// @Router       /admin/developer-apps/{id} [patch]
+func (h *DeveloperHandler) UpdateDeveloperApp(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		respondErrorCode(c, http.StatusBadRequest, domain.CodeInvalidID, "invalid app id format")
+		return
+	}
+
+	var req domain.UpdateDeveloperAppRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBadRequest(c, err)
+		return
+	}
+
+	app, err := h.service.UpdateApp(c.Request.Context(), id, req)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, app)
+}
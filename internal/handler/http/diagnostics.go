@@ -0,0 +1,30 @@
+package http
+
+import (
+	"net/http"
+
+	"aggregator_db/internal/diagnostics"
+	"github.com/gin-gonic/gin"
+)
+
+type DiagnosticsHandler struct {
+	recorder *diagnostics.Recorder
+}
+
+func NewDiagnosticsHandler(recorder *diagnostics.Recorder) *DiagnosticsHandler {
+	return &DiagnosticsHandler{recorder: recorder}
+}
+
+// ListQueryPlans godoc
+// @Summary      Планы запросов к базе
+// @Description  Планы EXPLAIN (ANALYZE) запросов последних помеченных HTTP-запросов этой реплики, от новых к старым. Доступно при DB_EXPLAIN=header (запросы с X-Debug-Explain: true и токеном администратора) или all
+// @Tags         admin
+// @Produce      json
+// @Param        X-Admin-Token header string true "Токен администратора"
+// @Param        trace_id query string false "Только запросы этого трейса"
+// @Success      200 {array} domain.RequestDiagnostics
+// @Failure      401 {object} domain.ErrorResponse
+// @Router       /admin/diagnostics/query-plans [get]
+func (h *DiagnosticsHandler) ListQueryPlans(c *gin.Context) {
+	c.JSON(http.StatusOK, h.recorder.List(c.Query("trace_id")))
+}
@@ -0,0 +1,101 @@
+package http
+
+import (
+	"net/http"
+
+	"aggregator_db/pkg/domain"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// CreateDiscount godoc
+// @Summary      Добавить скидку к подписке
+// @Description  Скидка в процентах (kind=percent, percent от 1 до 100) или фиксированной суммой в валюте подписки (kind=fixed, amount) действует в месяцах с start_month по end_month включительно и учитывается в расчете стоимости. Процентные скидки складываются (не больше 100%), фиксированные вычитаются после них
+// @Tags         subscriptions
+// @Accept       json
+// @Produce      json
+// @Param        id path string true "ID подписки" Format(uuid)
+// @Param        discount body domain.CreateDiscountRequest true "Скидка"
+// @Success      201 {object} domain.Discount
+// @Failure      400 {object} domain.ErrorResponse
+// @Failure      404 {object} domain.ErrorResponse
+// @Failure      500 {object} domain.ErrorResponse
+// @Router       /subscriptions/{id}/discounts [post]
+func (h *SubscriptionHandler) CreateDiscount(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		respondErrorCode(c, http.StatusBadRequest, domain.CodeInvalidID, "invalid subscription id")
+		return
+	}
+
+	var req domain.CreateDiscountRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBadRequest(c, err)
+		return
+	}
+
+	discount, err := h.service.CreateDiscount(c.Request.Context(), id, req)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, discount)
+}
+
+// ListDiscounts godoc
+// @Summary      Скидки подписки
+// @Description  Возвращает скидки подписки в порядке начала действия
+// @Tags         subscriptions
+// @Produce      json
+// @Param        id path string true "ID подписки" Format(uuid)
+// @Success      200 {array} domain.Discount
+// @Failure      400 {object} domain.ErrorResponse
+// @Failure      404 {object} domain.ErrorResponse
+// @Router       /subscriptions/{id}/discounts [get]
+func (h *SubscriptionHandler) ListDiscounts(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		respondErrorCode(c, http.StatusBadRequest, domain.CodeInvalidID, "invalid subscription id")
+		return
+	}
+
+	discounts, err := h.service.ListDiscounts(c.Request.Context(), id)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, discounts)
+}
+
+// DeleteDiscount godoc
+// @Summary      Удалить скидку
+// @Description  Удаляет скидку подписки; суммы за прошедшие месяцы пересчитываются без нее
+// @Tags         subscriptions
+// @Produce      json
+// @Param        id path string true "ID подписки" Format(uuid)
+// @Param        discount_id path string true "ID скидки" Format(uuid)
+// @Success      200 {object} domain.SuccessResponse
+// @Failure      400 {object} domain.ErrorResponse
+// @Failure      404 {object} domain.ErrorResponse
+// @Router       /subscriptions/{id}/discounts/{discount_id} [delete]
+func (h *SubscriptionHandler) DeleteDiscount(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		respondErrorCode(c, http.StatusBadRequest, domain.CodeInvalidID, "invalid subscription id")
+		return
+	}
+	discountID, err := uuid.Parse(c.Param("discount_id"))
+	if err != nil {
+		respondErrorCode(c, http.StatusBadRequest, domain.CodeInvalidID, "invalid discount id")
+		return
+	}
+
+	if err := h.service.DeleteDiscount(c.Request.Context(), id, discountID); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, domain.SuccessResponse{Message: "discount deleted"})
+}
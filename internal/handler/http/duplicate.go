@@ -0,0 +1,68 @@
+package http
+
+import (
+	"net/http"
+
+	"aggregator_db/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+type DuplicateHandler struct {
+	service *service.DuplicateService
+}
+
+func NewDuplicateHandler(service *service.DuplicateService) *DuplicateHandler {
+	return &DuplicateHandler{service: service}
+}
+
+// ListDuplicates godoc
+// @Summary      Несколько планов одного сервиса
+// @Description  Группы действующих подписок пользователя на один сервис (например, две подписки Spotify), найденные фоновой задачей, с предложением оставить самый дорогой в пересчете на месяц план и отменить остальные. Подписки, переставшие действовать после проверки, не показываются
+// @Tags         subscriptions
+// @Produce      json
+// @Param        user_id query string true "ID пользователя" Format(uuid)
+// @Success      200 {object} domain.DuplicatesResponse
+// @Failure      400 {object} domain.ErrorResponse
+// @Failure      404 {object} domain.ErrorResponse
+// @Failure      500 {object} domain.ErrorResponse
+// @Router       /subscriptions/duplicates [get]
+func (h *DuplicateHandler) ListDuplicates(c *gin.Context) {
+	userID, ok := requiredUserID(c)
+	if !ok {
+		return
+	}
+
+	duplicates, err := h.service.List(c.Request.Context(), userID)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, duplicates)
+}
+
+// ListRecommendations godoc
+// @Summary      Рекомендации по экономии
+// @Description  Советы пользователю: consolidate_plans - оставить один из нескольких планов одного сервиса, с месячной экономией по валютам
+// @Tags         recommendations
+// @Produce      json
+// @Param        user_id query string true "ID пользователя" Format(uuid)
+// @Success      200 {object} domain.RecommendationsResponse
+// @Failure      400 {object} domain.ErrorResponse
+// @Failure      404 {object} domain.ErrorResponse
+// @Failure      500 {object} domain.ErrorResponse
+// @Router       /recommendations [get]
+func (h *DuplicateHandler) ListRecommendations(c *gin.Context) {
+	userID, ok := requiredUserID(c)
+	if !ok {
+		return
+	}
+
+	recommendations, err := h.service.Recommendations(c.Request.Context(), userID)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, recommendations)
+}
@@ -0,0 +1,178 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+	"sync"
+
+	"aggregator_db/internal/clients"
+	"aggregator_db/internal/exchange"
+	"aggregator_db/internal/repository/postgres"
+	"aggregator_db/internal/service"
+	"aggregator_db/internal/writequeue"
+	"aggregator_db/pkg/domain"
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+)
+
+// errorMappings - статус и код ответа для ошибок сервисного слоя. Проверяются
+// по порядку: первая подходящая запись определяет ответ.
+var errorMappings = []struct {
+	target error
+	status int
+	code   domain.ErrorCode
+}{
+	{service.ErrValidation, http.StatusBadRequest, domain.CodeValidationFailed},
+	{postgres.ErrAliasNotFound, http.StatusNotFound, domain.CodeServiceAliasNotFound},
+	{postgres.ErrTenantNotFound, http.StatusNotFound, domain.CodeTenantNotFound},
+	{postgres.ErrDeveloperAppNotFound, http.StatusNotFound, domain.CodeDeveloperAppNotFound},
+	{postgres.ErrExportNotFound, http.StatusNotFound, domain.CodeExportNotFound},
+	{writequeue.ErrEntryNotFound, http.StatusNotFound, domain.CodeQueuedWriteNotFound},
+	{postgres.ErrDiscountNotFound, http.StatusNotFound, domain.CodeDiscountNotFound},
+	{postgres.ErrUserNotFound, http.StatusNotFound, domain.CodeUserNotFound},
+	{postgres.ErrBudgetNotFound, http.StatusNotFound, domain.CodeBudgetNotFound},
+	{postgres.ErrAPIKeyNotFound, http.StatusNotFound, domain.CodeAPIKeyNotFound},
+	{postgres.ErrNudgeNotFound, http.StatusNotFound, domain.CodeNudgeNotFound},
+	{postgres.ErrAlertNotFound, http.StatusNotFound, domain.CodeAlertNotFound},
+	{clients.ErrArtifactNotFound, http.StatusNotFound, domain.CodeClientArtifactNotFound},
+	{postgres.ErrNotFound, http.StatusNotFound, domain.CodeSubscriptionNotFound},
+	{service.ErrQuotaExceeded, http.StatusForbidden, domain.CodeQuotaExceeded},
+	{postgres.ErrAlreadyExists, http.StatusConflict, domain.CodeSubscriptionAlreadyExists},
+	{postgres.ErrTenantAlreadyExists, http.StatusConflict, domain.CodeTenantAlreadyExists},
+	{postgres.ErrUserAlreadyExists, http.StatusConflict, domain.CodeUserAlreadyExists},
+	{postgres.ErrUserEmailTaken, http.StatusConflict, domain.CodeEmailTaken},
+	{postgres.ErrBudgetCategoryTaken, http.StatusConflict, domain.CodeBudgetCategoryTaken},
+	{postgres.ErrVersionConflict, http.StatusConflict, domain.CodeVersionConflict},
+	{exchange.ErrRateUnavailable, http.StatusServiceUnavailable, domain.CodeExchangeRateUnavailable},
+	{postgres.ErrUnavailable, http.StatusServiceUnavailable, domain.CodeDatabaseUnavailable},
+	{writequeue.ErrFull, http.StatusServiceUnavailable, domain.CodeWriteQueueFull},
+}
+
+// validationCodes уточняют VALIDATION_FAILED, когда известно, какое значение неверно.
+var validationCodes = []struct {
+	target error
+	code   domain.ErrorCode
+}{
+	{domain.ErrInvalidPeriod, domain.CodeInvalidPeriod},
+	{domain.ErrInvalidMoney, domain.CodeInvalidMoney},
+	{domain.ErrInvalidTag, domain.CodeInvalidTag},
+	{domain.ErrInvalidContinuation, domain.CodeInvalidContinuation},
+	{errInvalidUserID, domain.CodeInvalidID},
+}
+
+// respondError переводит ошибки сервисного слоя в HTTP-ответ.
+func respondError(c *gin.Context, err error) {
+	for _, mapping := range errorMappings {
+		if !errors.Is(err, mapping.target) {
+			continue
+		}
+		message := err.Error()
+		if mapping.code == domain.CodeSubscriptionNotFound {
+			message = "subscription not found"
+		}
+		code := mapping.code
+		if code == domain.CodeValidationFailed {
+			code = validationCode(err)
+		}
+		c.JSON(mapping.status, domain.ErrorResponse{Code: code, Error: message})
+		return
+	}
+
+	// Ошибка сохраняется в контексте gin, чтобы middleware трекера ошибок отправил ее как есть
+	_ = c.Error(err)
+	c.JSON(http.StatusInternalServerError, domain.ErrorResponse{Code: domain.CodeInternal, Error: err.Error()})
+}
+
+// respondBadRequest отвечает 400 на ошибку разбора запроса: тела, query или параметров.
+// Для ошибок валидации binding в details перечисляются поля.
+func respondBadRequest(c *gin.Context, err error) {
+	response := domain.ErrorResponse{Code: validationCode(err), Error: err.Error()}
+
+	var validationErrors validator.ValidationErrors
+	var typeError *json.UnmarshalTypeError
+	var syntaxError *json.SyntaxError
+	switch {
+	case errors.As(err, &validationErrors):
+		for _, fieldError := range validationErrors {
+			response.Details = append(response.Details, domain.ErrorDetail{
+				Field:   fieldError.Field(),
+				Rule:    fieldError.Tag(),
+				Message: ruleMessage(fieldError),
+			})
+		}
+	case errors.As(err, &typeError):
+		response.Details = []domain.ErrorDetail{{
+			Field:   typeError.Field,
+			Rule:    "type",
+			Message: fmt.Sprintf("%s must be %s", typeError.Field, typeError.Type),
+		}}
+	case errors.As(err, &syntaxError), errors.Is(err, io.EOF), errors.Is(err, io.ErrUnexpectedEOF):
+		response.Code = domain.CodeMalformedRequest
+	}
+
+	c.JSON(http.StatusBadRequest, response)
+}
+
+// respondErrorCode отвечает ошибкой с явно заданным кодом.
+func respondErrorCode(c *gin.Context, status int, code domain.ErrorCode, message string) {
+	c.JSON(status, domain.ErrorResponse{Code: code, Error: message})
+}
+
+func validationCode(err error) domain.ErrorCode {
+	for _, mapping := range validationCodes {
+		if errors.Is(err, mapping.target) {
+			return mapping.code
+		}
+	}
+	return domain.CodeValidationFailed
+}
+
+func ruleMessage(fieldError validator.FieldError) string {
+	field, param := fieldError.Field(), fieldError.Param()
+	switch fieldError.Tag() {
+	case "required":
+		return field + " is required"
+	case "oneof":
+		return fmt.Sprintf("%s must be one of %s", field, param)
+	case "min", "gte":
+		return fmt.Sprintf("%s must be at least %s", field, param)
+	case "max", "lte":
+		return fmt.Sprintf("%s must be at most %s", field, param)
+	case "gt":
+		return fmt.Sprintf("%s must be greater than %s", field, param)
+	case "email":
+		return field + " must be a valid email"
+	case "uuid":
+		return field + " must be a valid UUID"
+	default:
+		return fmt.Sprintf("%s failed the %s check", field, fieldError.Tag())
+	}
+}
+
+var fieldNamesOnce sync.Once
+
+// useRequestFieldNames называет поля в ошибках валидации так, как их видит клиент:
+// по тегу json или form, а не по имени поля Go.
+func useRequestFieldNames() {
+	fieldNamesOnce.Do(func() {
+		engine, ok := binding.Validator.Engine().(*validator.Validate)
+		if !ok {
+			return
+		}
+		engine.RegisterTagNameFunc(func(field reflect.StructField) string {
+			for _, tag := range []string{"json", "form"} {
+				name, _, _ := strings.Cut(field.Tag.Get(tag), ",")
+				if name != "" && name != "-" {
+					return name
+				}
+			}
+			return field.Name
+		})
+	})
+}
@@ -0,0 +1,28 @@
+package http
+
+import (
+	"net/http"
+
+	"aggregator_db/pkg/events"
+	"github.com/gin-gonic/gin"
+)
+
+type EventSchemaHandler struct {
+	registry *events.Registry
+}
+
+func NewEventSchemaHandler(registry *events.Registry) *EventSchemaHandler {
+	return &EventSchemaHandler{registry: registry}
+}
+
+// ListEventSchemas godoc
+// @Summary      Схемы событий
+// @Description  JSON Schema данных всех публикуемых событий со всеми версиями. Версия схемы приходит в поле schema_version каждого события; новые версии совместимы с прежними
+// @Tags         events
+// @Produce      json
+// @Param        event_type query string false "Тип события, например subscription.renewed"
+// @Success      200 {array} domain.EventSchema
+// @Router       /event-schemas [get]
+func (h *EventSchemaHandler) ListEventSchemas(c *gin.Context) {
+	c.JSON(http.StatusOK, h.registry.List(c.Query("event_type")))
+}
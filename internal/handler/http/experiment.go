@@ -0,0 +1,29 @@
+package http
+
+import (
+	"net/http"
+
+	"aggregator_db/internal/experiment"
+	"github.com/gin-gonic/gin"
+)
+
+type ExperimentHandler struct {
+	calculate *experiment.Calculate
+}
+
+func NewExperimentHandler(calculate *experiment.Calculate) *ExperimentHandler {
+	return &ExperimentHandler{calculate: calculate}
+}
+
+// GetCalculateExperiment godoc
+// @Summary      Сравнение реализаций расчета стоимости
+// @Description  Итоги эксперимента CALCULATE_EXPERIMENT на этой реплике с ее запуска: сколько расчетов отдала текущая (control) и новая (candidate) реализация, сколько повторено для сравнения, сколько совпало и разошлось, и последние расхождения с параметрами расчета. Расчеты, отданные из кэша, в эксперимент не попадают
+// @Tags         admin
+// @Produce      json
+// @Param        X-Admin-Token header string true "Токен администратора"
+// @Success      200 {object} domain.CalculateExperimentReport
+// @Failure      401 {object} domain.ErrorResponse
+// @Router       /admin/experiments/calculate [get]
+func (h *ExperimentHandler) GetCalculateExperiment(c *gin.Context) {
+	c.JSON(http.StatusOK, h.calculate.Report())
+}
@@ -0,0 +1,83 @@
+package http
+
+import (
+	"fmt"
+	"mime"
+	"net/http"
+	"path"
+
+	"aggregator_db/internal/clock"
+	"aggregator_db/internal/service"
+	"aggregator_db/pkg/domain"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type ExportHandler struct {
+	service *service.ExportService
+}
+
+func NewExportHandler(service *service.ExportService) *ExportHandler {
+	return &ExportHandler{service: service}
+}
+
+// GetExport godoc
+// @Summary      Статус выгрузки
+// @Description  Выгрузка, поставленная в очередь ручкой выгрузки с параметром email
+// @Tags         admin
+// @Produce      json
+// @Param        X-Admin-Token header string true "Токен администратора"
+// @Param        id path string true "ID выгрузки"
+// @Success      200 {object} domain.ExportJob
+// @Failure      400 {object} domain.ErrorResponse
+// @Failure      401 {object} domain.ErrorResponse
+// @Failure      403 {object} domain.ErrorResponse
+// @Failure      404 {object} domain.ErrorResponse
+// @Router       /admin/exports/{id} [get]
+func (h *ExportHandler) GetExport(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		respondErrorCode(c, http.StatusBadRequest, domain.CodeInvalidID, "invalid export id format")
+		return
+	}
+
+	job, err := h.service.Get(c.Request.Context(), id)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, job)
+}
+
+// DownloadExport godoc
+// @Summary      Скачать выгрузку
+// @Description  Ссылка из письма о готовой выгрузке. Действует EXPORT_LINK_TTL, доступ дает токен из ссылки
+// @Tags         exports
+// @Produce      octet-stream
+// @Param        id path string true "ID выгрузки"
+// @Param        token query string true "Токен из ссылки"
+// @Success      200 {string} string "Файл выгрузки"
+// @Failure      400 {object} domain.ErrorResponse
+// @Failure      404 {object} domain.ErrorResponse
+// @Router       /exports/{id}/download [get]
+func (h *ExportHandler) DownloadExport(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		respondErrorCode(c, http.StatusBadRequest, domain.CodeInvalidID, "invalid export id format")
+		return
+	}
+
+	job, err := h.service.Download(c.Request.Context(), id, c.Query("token"), clock.Now(c.Request.Context()))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	contentType := mime.TypeByExtension(path.Ext(job.FileName))
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, job.FileName))
+	c.Data(http.StatusOK, contentType, job.Content)
+}
@@ -0,0 +1,40 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"aggregator_db/internal/clock"
+	"aggregator_db/internal/repository/postgres"
+	"aggregator_db/pkg/domain"
+	"github.com/gin-gonic/gin"
+)
+
+// DataAsOfHeader - момент (RFC 3339), на который актуальны данные ответа без
+// поля data_as_of в теле.
+const DataAsOfHeader = "X-Data-As-Of"
+
+var errInvalidFreshness = errors.New("invalid freshness, expected strong or eventual")
+
+// readFreshness готовит чтения, которые могут уйти в реплику, по параметру
+// freshness: eventual (по умолчанию) разрешает реплику, strong читает основную базу.
+func readFreshness(c *gin.Context) (context.Context, func() time.Time, error) {
+	freshness := domain.Freshness(c.Query("freshness"))
+	if !freshness.Valid() {
+		return nil, nil, errInvalidFreshness
+	}
+	ctx, dataAsOf := freshRead(c, freshness)
+	return ctx, dataAsOf, nil
+}
+
+// freshRead помечает чтения запроса свежестью freshness. Возвращенная функция
+// после чтений дает момент, на который актуальны данные: начало запроса за
+// вычетом отставания реплики, если чтения шли в нее.
+func freshRead(c *gin.Context, freshness domain.Freshness) (context.Context, func() time.Time) {
+	start := clock.Now(c.Request.Context())
+	ctx, stamp := postgres.WithFreshness(c.Request.Context(), freshness)
+	return ctx, func() time.Time {
+		return start.Add(-stamp.Lag()).UTC()
+	}
+}
@@ -0,0 +1,100 @@
+package http
+
+import (
+	"bytes"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"aggregator_db/internal/config"
+	"aggregator_db/internal/exchange"
+	"aggregator_db/internal/repository/memory"
+	"aggregator_db/internal/service"
+	"aggregator_db/pkg/domain"
+	"aggregator_db/pkg/events"
+	"aggregator_db/pkg/mailer"
+	"github.com/gin-gonic/gin"
+)
+
+func newFuzzRouter() http.Handler {
+	gin.SetMode(gin.TestMode)
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	repo := memory.NewSubscriptionRepository()
+	publisher := events.NewLogPublisher(logger)
+	subscriptions := service.NewSubscriptionService(repo, memory.NewTransactor(), memory.NewServiceAliasRepository(), publisher, exchange.NewStaticProvider(domain.DefaultCurrency, nil), logger)
+	return SetupRouter(&config.Config{}, Services{
+		Subscriptions: subscriptions,
+		Notifications: service.NewNotificationService(repo, memory.NewNotificationSettingsRepository(), publisher, mailer.NewLogSender(logger), 20, logger),
+		Users:         service.NewUserService(memory.NewUserRepository(repo), nil, logger),
+		Budgets:       service.NewBudgetService(memory.NewBudgetRepository(), memory.NewUserRepository(repo), subscriptions, publisher, nil, logger),
+	}, logger)
+}
+
+func serve(router http.Handler, method, target string, body []byte) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, target, bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	return rec
+}
+
+func FuzzCreateSubscription(f *testing.F) {
+	f.Add([]byte(`{"service_name":"Yandex Plus","price":400,"user_id":"60601fee-2bf1-4721-ae6f-7636e79a0cba","start_date":"07-2025"}`))
+	f.Add([]byte(`{"service_name":"x","price":-1,"user_id":"bad","start_date":"99-9999","end_date":"01-2000"}`))
+	f.Add([]byte(`{"service_name":"x","price":1,"user_id":"60601fee-2bf1-4721-ae6f-7636e79a0cba","start_date":"07-2025","end_date":null}`))
+	f.Add([]byte(`[]`))
+	f.Add([]byte(`{"price":1e309}`))
+
+	router := newFuzzRouter()
+
+	f.Fuzz(func(t *testing.T, body []byte) {
+		rec := serve(router, http.MethodPost, "/api/v1/subscriptions", body)
+		if rec.Code >= http.StatusInternalServerError {
+			t.Fatalf("create returned %d for %q: %s", rec.Code, body, rec.Body.String())
+		}
+	})
+}
+
+func FuzzListSubscriptions(f *testing.F) {
+	f.Add("60601fee-2bf1-4721-ae6f-7636e79a0cba", "Yandex Plus", "10", "0")
+	f.Add("not-a-uuid", "'; DROP TABLE subscriptions; --", "-1", "abc")
+	f.Add("", "", "1000", "99999999999999999999")
+
+	router := newFuzzRouter()
+
+	f.Fuzz(func(t *testing.T, userID, serviceName, limit, offset string) {
+		params := url.Values{}
+		params.Set("user_id", userID)
+		params.Set("service_name", serviceName)
+		params.Set("limit", limit)
+		params.Set("offset", offset)
+
+		rec := serve(router, http.MethodGet, "/api/v1/subscriptions?"+params.Encode(), nil)
+		if rec.Code >= http.StatusInternalServerError {
+			t.Fatalf("list returned %d for %v: %s", rec.Code, params, rec.Body.String())
+		}
+	})
+}
+
+func FuzzCalculateTotal(f *testing.F) {
+	f.Add("01-2025", "12-2025", "60601fee-2bf1-4721-ae6f-7636e79a0cba")
+	f.Add("12-2025", "01-2025", "")
+	f.Add("2025-01", "13-2025", "{60601fee-2bf1-4721-ae6f-7636e79a0cba}")
+
+	router := newFuzzRouter()
+
+	f.Fuzz(func(t *testing.T, startPeriod, endPeriod, userID string) {
+		params := url.Values{}
+		params.Set("start_period", startPeriod)
+		params.Set("end_period", endPeriod)
+		params.Set("user_id", userID)
+
+		rec := serve(router, http.MethodGet, "/api/v1/subscriptions/calculate?"+params.Encode(), nil)
+		if rec.Code >= http.StatusInternalServerError {
+			t.Fatalf("calculate returned %d for %v: %s", rec.Code, params, rec.Body.String())
+		}
+	})
+}
@@ -0,0 +1,38 @@
+package http
+
+import (
+	"net/http"
+
+	"aggregator_db/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+type LimitsHandler struct {
+	service *service.LimitsService
+}
+
+func NewLimitsHandler(service *service.LimitsService) *LimitsHandler {
+	return &LimitsHandler{service: service}
+}
+
+// GetLimits godoc
+// @Summary      Лимиты вызывающего
+// @Description  Лимиты запросов, которые применяются к вызывающему (приложение по X-API-Key, ключ сервиса, тенант по X-Tenant-ID), с остатком и временем сброса текущего окна с учетом этого запроса, а также квоты тенанта и их использование. Самый строгий из лимитов приходит в заголовках X-RateLimit-* каждого ответа. Лимиты считаются на каждой реплике отдельно; без аутентификации список лимитов пуст
+// @Tags         limits
+// @Produce      json
+// @Param        X-API-Key header string false "Ключ приложения или внутреннего сервиса"
+// @Param        X-Tenant-ID header string false "Тенант"
+// @Success      200 {object} domain.CallerLimits
+// @Failure      401 {object} domain.ErrorResponse
+// @Failure      429 {object} domain.ErrorResponse
+// @Failure      500 {object} domain.ErrorResponse
+// @Router       /limits [get]
+func (h *LimitsHandler) GetLimits(c *gin.Context) {
+	limits, err := h.service.Get(c.Request.Context())
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, limits)
+}
@@ -0,0 +1,78 @@
+package http
+
+import (
+	"net/http"
+
+	"aggregator_db/internal/service"
+	"aggregator_db/pkg/domain"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type NotificationHandler struct {
+	service *service.NotificationService
+}
+
+func NewNotificationHandler(service *service.NotificationService) *NotificationHandler {
+	return &NotificationHandler{service: service}
+}
+
+// GetNotificationSettings godoc
+// @Summary      Настройки уведомлений пользователя
+// @Description  Возвращает настройки уведомлений; для пользователя без настроек уведомления выключены
+// @Tags         users
+// @Produce      json
+// @Param        id path string true "ID пользователя" Format(uuid)
+// @Success      200 {object} domain.NotificationSettings
+// @Failure      400 {object} domain.ErrorResponse
+// @Failure      500 {object} domain.ErrorResponse
+// @Router       /users/{id}/notification-settings [get]
+func (h *NotificationHandler) GetNotificationSettings(c *gin.Context) {
+	userID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		respondBadRequest(c, errInvalidUserID)
+		return
+	}
+
+	settings, err := h.service.GetSettings(c.Request.Context(), userID)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, settings)
+}
+
+// UpdateNotificationSettings godoc
+// @Summary      Изменить настройки уведомлений
+// @Description  Включает уведомления об изменении трат неделя к неделе и месяц к месяцу. Без threshold_percent используется порог по умолчанию. statement_channel выбирает канал ежемесячной выписки: email - письмом с CSV, webhook - событием statement.monthly, none (по умолчанию) - не отправлять. escalation - цепочка эскалации критических уведомлений (budget.exceeded): если уведомление не подтвердили за after_hours часов после предыдущего шага, оно уходит в channel пользователю user_id (по умолчанию владельцу)
+// @Tags         users
+// @Accept       json
+// @Produce      json
+// @Param        id path string true "ID пользователя" Format(uuid)
+// @Param        settings body domain.UpdateNotificationSettingsRequest true "Настройки"
+// @Success      200 {object} domain.NotificationSettings
+// @Failure      400 {object} domain.ErrorResponse
+// @Failure      500 {object} domain.ErrorResponse
+// @Router       /users/{id}/notification-settings [put]
+func (h *NotificationHandler) UpdateNotificationSettings(c *gin.Context) {
+	userID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		respondBadRequest(c, errInvalidUserID)
+		return
+	}
+
+	var req domain.UpdateNotificationSettingsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBadRequest(c, err)
+		return
+	}
+
+	settings, err := h.service.UpdateSettings(c.Request.Context(), userID, req)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, settings)
+}
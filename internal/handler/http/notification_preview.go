@@ -0,0 +1,47 @@
+package http
+
+import (
+	"net/http"
+
+	"aggregator_db/internal/service"
+	"aggregator_db/pkg/domain"
+	"github.com/gin-gonic/gin"
+)
+
+type NotificationPreviewHandler struct {
+	service *service.NotificationPreviewService
+}
+
+func NewNotificationPreviewHandler(service *service.NotificationPreviewService) *NotificationPreviewHandler {
+	return &NotificationPreviewHandler{service: service}
+}
+
+// PreviewNotification godoc
+// @Summary      Предпросмотр уведомления
+// @Description  Строит уведомление на реальных данных пользователя так же, как задачи планировщика, но ничего не отправляет. spend.weekly_change и spend.monthly_change сравнивают последнюю полную неделю или месяц с предыдущими, budget.warning и budget.exceeded проверяют конверты за текущий месяц. В ответе все кандидаты, send=false с причиной у тех, что не были бы отправлены
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        X-Admin-Token header string true "Токен администратора"
+// @Param        request body domain.NotificationPreviewRequest true "Тип уведомления и пользователь"
+// @Success      200 {object} domain.NotificationPreview
+// @Failure      400 {object} domain.ErrorResponse
+// @Failure      401 {object} domain.ErrorResponse
+// @Failure      404 {object} domain.ErrorResponse
+// @Failure      500 {object} domain.ErrorResponse
+// @Router       /notifications/preview [post]
+func (h *NotificationPreviewHandler) PreviewNotification(c *gin.Context) {
+	var req domain.NotificationPreviewRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBadRequest(c, err)
+		return
+	}
+
+	preview, err := h.service.Preview(c.Request.Context(), req)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, preview)
+}
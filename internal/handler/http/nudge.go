@@ -0,0 +1,75 @@
+package http
+
+import (
+	"net/http"
+
+	"aggregator_db/internal/service"
+	"aggregator_db/pkg/domain"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type NudgeHandler struct {
+	service *service.NudgeService
+}
+
+func NewNudgeHandler(service *service.NudgeService) *NudgeHandler {
+	return &NudgeHandler{service: service}
+}
+
+// ListNudges godoc
+// @Summary      Подсказки администраторам
+// @Description  Подсказки фоновой проверки правил: rising_churn - у тенанта растет число отмен, irregular_data - у пользователя подписки с подозрительными данными (нулевая цена, начало далеко в будущем). Новые сверху; скрытые только с include_dismissed=true
+// @Tags         admin
+// @Produce      json
+// @Param        X-Admin-Token header string true "Токен администратора"
+// @Param        kind query string false "Правило" Enums(rising_churn, irregular_data)
+// @Param        include_dismissed query bool false "Включить скрытые"
+// @Success      200 {array} domain.Nudge
+// @Failure      400 {object} domain.ErrorResponse
+// @Failure      401 {object} domain.ErrorResponse
+// @Failure      500 {object} domain.ErrorResponse
+// @Router       /admin/nudges [get]
+func (h *NudgeHandler) ListNudges(c *gin.Context) {
+	var query domain.ListNudgesQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		respondBadRequest(c, err)
+		return
+	}
+
+	nudges, err := h.service.List(c.Request.Context(), query)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, nudges)
+}
+
+// DismissNudge godoc
+// @Summary      Скрыть подсказку
+// @Description  Подсказка пропадает из списка; по тому же правилу и месяцу она больше не создается
+// @Tags         admin
+// @Produce      json
+// @Param        X-Admin-Token header string true "Токен администратора"
+// @Param        id path string true "ID подсказки" Format(uuid)
+// @Success      200 {object} domain.Nudge
+// @Failure      400 {object} domain.ErrorResponse
+// @Failure      401 {object} domain.ErrorResponse
+// @Failure      404 {object} domain.ErrorResponse
+// @Router       /admin/nudges/{id}/dismiss [post]
+func (h *NudgeHandler) DismissNudge(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		respondErrorCode(c, http.StatusBadRequest, domain.CodeInvalidID, "invalid nudge id")
+		return
+	}
+
+	nudge, err := h.service.Dismiss(c.Request.Context(), id)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, nudge)
+}
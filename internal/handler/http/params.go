@@ -0,0 +1,93 @@
+package http
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+var (
+	errInvalidUserID  = errors.New("invalid user_id format")
+	errInvalidUserIDs = errors.New("invalid user_ids format, expected comma-separated UUIDs")
+	errInvalidIfMatch = errors.New(`invalid If-Match, expected subscription ETag like "3"`)
+)
+
+// legacyUserIDParam - имя параметра, которое использовали старые клиенты.
+const legacyUserIDParam = "userId"
+
+// parseUserIDQuery разбирает фильтр user_id из query-строки.
+// Для совместимости со старыми клиентами принимает параметр userId.
+// Без параметра фильтра нет, а пустое значение отклоняется: иначе опечатка
+// в клиенте молча расширяла бы выборку до всех пользователей.
+func parseUserIDQuery(c *gin.Context) (*uuid.UUID, error) {
+	raw, ok := c.GetQuery("user_id")
+	if !ok {
+		raw, ok = c.GetQuery(legacyUserIDParam)
+	}
+	if !ok {
+		return nil, nil
+	}
+
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return nil, errInvalidUserID
+	}
+
+	id, err := uuid.Parse(raw)
+	if err != nil {
+		return nil, errInvalidUserID
+	}
+
+	return &id, nil
+}
+
+// parseUserIDsQuery разбирает список пользователей user_ids: через запятую
+// и/или повторением параметра.
+func parseUserIDsQuery(c *gin.Context) ([]uuid.UUID, error) {
+	var ids []uuid.UUID
+	for _, value := range c.QueryArray("user_ids") {
+		for _, raw := range strings.Split(value, ",") {
+			raw = strings.TrimSpace(raw)
+			if raw == "" {
+				continue
+			}
+			id, err := uuid.Parse(raw)
+			if err != nil {
+				return nil, errInvalidUserIDs
+			}
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
+
+// subscriptionETag - ETag подписки: ее версия в кавычках.
+func subscriptionETag(version int64) string {
+	return fmt.Sprintf(`"%d"`, version)
+}
+
+// expectedVersion возвращает версию, которую видел клиент: из If-Match или из
+// поля version тела. "*" и отсутствие обоих означают изменение без проверки.
+func expectedVersion(c *gin.Context, body *int64) (*int64, error) {
+	header := strings.TrimSpace(c.GetHeader("If-Match"))
+	if header == "" || header == "*" {
+		return body, nil
+	}
+
+	tag := strings.TrimPrefix(header, "W/")
+	if len(tag) < 2 || tag[0] != '"' || tag[len(tag)-1] != '"' {
+		return nil, errInvalidIfMatch
+	}
+	version, err := strconv.ParseInt(tag[1:len(tag)-1], 10, 64)
+	if err != nil {
+		return nil, errInvalidIfMatch
+	}
+	if body != nil && *body != version {
+		return nil, fmt.Errorf("If-Match %s does not match version %d in the body", header, *body)
+	}
+	return &version, nil
+}
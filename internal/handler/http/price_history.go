@@ -0,0 +1,36 @@
+package http
+
+import (
+	"net/http"
+
+	"aggregator_db/pkg/domain"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// GetPriceHistory godoc
+// @Summary      История цены подписки
+// @Description  Ступенчатый ряд для графика: каждая точка - цена с момента at до следующей точки, monthly_price приводит цены разных циклов оплаты к месяцу. annotations отмечают периоды скидок: с from до to (не включая), у бессрочной скидки to нет
+// @Tags         subscriptions
+// @Produce      json
+// @Param        id path string true "ID подписки" Format(uuid)
+// @Success      200 {object} domain.PriceHistoryResponse
+// @Failure      400 {object} domain.ErrorResponse
+// @Failure      404 {object} domain.ErrorResponse
+// @Failure      500 {object} domain.ErrorResponse
+// @Router       /subscriptions/{id}/price-history [get]
+func (h *SubscriptionHandler) GetPriceHistory(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		respondErrorCode(c, http.StatusBadRequest, domain.CodeInvalidID, "invalid subscription id")
+		return
+	}
+
+	history, err := h.service.PriceHistory(c.Request.Context(), id)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, history)
+}
@@ -0,0 +1,40 @@
+package http
+
+import (
+	"net/http"
+
+	"aggregator_db/internal/service"
+	"aggregator_db/pkg/domain"
+	"github.com/gin-gonic/gin"
+)
+
+// ProbeHandler отвечает на пробы Kubernetes. Без сервиса готовности реплика
+// считается готовой, как только живой процесс.
+type ProbeHandler struct {
+	readiness *service.ReadinessService
+}
+
+func NewProbeHandler(readiness *service.ReadinessService) *ProbeHandler {
+	return &ProbeHandler{readiness: readiness}
+}
+
+// Liveness - процесс жив и обрабатывает запросы; зависимости не проверяются,
+// чтобы отказ базы не приводил к перезапуску всех реплик.
+func (h *ProbeHandler) Liveness(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"status": domain.ProbeOK})
+}
+
+// Readiness - реплика может обслуживать запросы: 200 или 503 с состоянием каждой зависимости.
+func (h *ProbeHandler) Readiness(c *gin.Context) {
+	if h.readiness == nil {
+		c.JSON(http.StatusOK, domain.NewReadiness([]domain.DependencyStatus{}))
+		return
+	}
+
+	readiness := h.readiness.Ready(c.Request.Context())
+	status := http.StatusOK
+	if readiness.Status != domain.ProbeOK {
+		status = http.StatusServiceUnavailable
+	}
+	c.JSON(status, readiness)
+}
@@ -0,0 +1,46 @@
+package http
+
+import (
+	"net/http"
+
+	"aggregator_db/internal/service"
+	"aggregator_db/pkg/domain"
+	"github.com/gin-gonic/gin"
+)
+
+type ReplicationHandler struct {
+	service *service.ReplicationService
+}
+
+func NewReplicationHandler(service *service.ReplicationService) *ReplicationHandler {
+	return &ReplicationHandler{service: service}
+}
+
+// ApplyReplicatedSubscription godoc
+// @Summary      Применить изменение из другого региона
+// @Description  Записывает подписку целиком в том виде, в котором ее сохранил регион region. Если подписка уже есть, остающуюся версию выбирает политика REGION_CONFLICT_POLICY: last_writer_wins - более поздний updated_at, local_wins - локальная версия. Возвращает версию, которая осталась
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        X-Admin-Token header string true "Токен администратора"
+// @Param        subscription body domain.Subscription true "Подписка из другого региона"
+// @Success      200 {object} domain.Subscription
+// @Failure      400 {object} domain.ErrorResponse
+// @Failure      401 {object} domain.ErrorResponse
+// @Failure      500 {object} domain.ErrorResponse
+// @Router       /admin/replication/subscriptions [put]
+func (h *ReplicationHandler) ApplyReplicatedSubscription(c *gin.Context) {
+	var sub domain.Subscription
+	if err := c.ShouldBindJSON(&sub); err != nil {
+		respondBadRequest(c, err)
+		return
+	}
+
+	result, err := h.service.Apply(c.Request.Context(), &sub)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
@@ -1,39 +1,409 @@
 package http
 
 import (
+	"aggregator_db/internal/changelog"
+	"aggregator_db/internal/clients"
+	"aggregator_db/internal/config"
+	"aggregator_db/internal/diagnostics"
+	"aggregator_db/internal/experiment"
+	"aggregator_db/internal/metering"
+	"aggregator_db/internal/metricsui"
 	"aggregator_db/internal/middleware"
+	"aggregator_db/internal/ratelimit"
+	"aggregator_db/internal/retryafter"
 	"aggregator_db/internal/service"
+	"aggregator_db/internal/shadow"
+	"aggregator_db/pkg/domain"
+	"aggregator_db/pkg/errortracker"
+	"aggregator_db/pkg/events"
+	"aggregator_db/pkg/metrics"
 	"github.com/gin-gonic/gin"
 	swaggerFiles "github.com/swaggo/files"
 	ginSwagger "github.com/swaggo/gin-swagger"
 	"log/slog"
 )
 
-func SetupRouter(subscriptionService *service.SubscriptionService, logger *slog.Logger) *gin.Engine {
+// Services - сервисы, которые роутер раздает хендлерам.
+type Services struct {
+	Subscriptions *service.SubscriptionService
+	Notifications *service.NotificationService
+	Users         *service.UserService
+	// FieldVisibility - поля, скрытые от ролей, если тенант не задал своих правил (FIELD_VISIBILITY)
+	FieldVisibility domain.FieldVisibility
+	Budgets         *service.BudgetService
+	Duplicates      *service.DuplicateService
+	// Nudges включает ручки подсказок администраторам
+	Nudges *service.NudgeService
+	// DataRepair включает проверку и исправление целостности данных
+	DataRepair *service.DataRepairService
+	// Audit включает чтение журнала изменений
+	Audit *service.AuditService
+	// Changes включает ленту изменений подписок с долгим ожиданием
+	Changes *service.ChangesService
+	// Readiness проверяет зависимости для /readyz; без него реплика всегда готова
+	Readiness *service.ReadinessService
+	// SchemaMigration включает сверку и заполнение новых колонок переходов схемы
+	SchemaMigration *service.SchemaMigrationService
+	// NotificationPreview включает предпросмотр уведомлений для администраторов и поддержки
+	NotificationPreview *service.NotificationPreviewService
+	// Tenants включает изоляцию тенантов; без него X-Tenant-ID игнорируется
+	Tenants *service.TenantService
+	// Meter включает учет потребления API, Usage - ручки для его просмотра
+	Meter *metering.Meter
+	Usage *service.UsageService
+	// Exports включает доставку выгрузок на почту (параметр email у ручек выгрузки)
+	Exports *service.ExportService
+	// Statements включает историю ежемесячных выписок пользователя
+	Statements *service.StatementService
+	// Alerts включает критические уведомления с подтверждением и эскалацией
+	Alerts *service.AlertService
+	// Developer включает портал разработчиков и ключи X-API-Key с лимитом запросов
+	Developer *service.DeveloperService
+	Limiter   *ratelimit.Limiter
+	// Limits включает ручку лимитов вызывающего и лимиты ключей сервисов и тенантов
+	Limits *service.LimitsService
+	// APIKeys включает ключи внутренних сервисов (X-API-Key с префиксом sk_service_)
+	APIKeys *service.APIKeyService
+	// WriteQueue включает прием записей в локальную очередь, пока база недоступна
+	WriteQueue *service.WriteQueueService
+	// RetryAfter добавляет подсказку Retry-After к ответам 429 и 5xx
+	RetryAfter *retryafter.Policy
+	// EventSchemas - реестр схем публикуемых событий
+	EventSchemas *events.Registry
+	// Changelog - журнал изменений API
+	Changelog *changelog.Changelog
+	// Clients включает раздачу спецификации OpenAPI и клиентов API, собранных из нее
+	Clients *clients.Catalog
+	// Deprecations включает учет обращений к устаревшему из журнала и DEPRECATIONS
+	Deprecations []domain.Deprecation
+	// Diagnostics включает снятие планов запросов к базе (DB_EXPLAIN) и ручку для их просмотра
+	Diagnostics *diagnostics.Recorder
+	// Replication включает прием изменений подписок из других регионов (REGION)
+	Replication *service.ReplicationService
+	// SystemHealth включает сводную оценку состояния системы для дашбордов
+	SystemHealth *service.SystemHealthService
+	// SLO включает учет целей маршрутов (SLO_OBJECTIVES) и ручку их состояния
+	SLO *service.SLOService
+	// ErrorTracker включает отчеты о паниках и ответах 500 в трекер ошибок (SENTRY_DSN)
+	ErrorTracker *errortracker.Client
+	// Shadow включает повтор доли GET-запросов на теневое развертывание (SHADOW_URL)
+	Shadow *shadow.Shadower
+	// CalculateExperiment включает ручку сравнения реализаций расчета стоимости (CALCULATE_EXPERIMENT)
+	CalculateExperiment *experiment.Calculate
+}
+
+func SetupRouter(cfg *config.Config, services Services, logger *slog.Logger) *gin.Engine {
+	useRequestFieldNames()
 	router := gin.New()
-	router.Use(gin.Recovery())
+	if services.SLO != nil {
+		router.Use(middleware.SLO(services.SLO.Observe))
+	}
+	if services.ErrorTracker != nil {
+		router.Use(middleware.ErrorTrackingRecovery(services.ErrorTracker))
+		router.Use(middleware.ErrorTracking(services.ErrorTracker))
+	} else {
+		router.Use(gin.Recovery())
+	}
+	if services.RetryAfter != nil {
+		router.Use(middleware.RetryAfter(services.RetryAfter))
+	}
+	router.Use(middleware.Tracing())
+	if cfg.ServerTiming {
+		router.Use(middleware.ServerTiming())
+	}
+	if cfg.Compression.Enabled {
+		router.Use(middleware.Compress(cfg.Compression.MinSize, cfg.Compression.ContentTypes))
+	}
+	if services.Shadow != nil {
+		router.Use(middleware.Shadow(services.Shadow))
+	}
 	router.Use(middleware.Logger(logger))
+	// Выключенные ручки отвечают до аутентификации и остальных проверок
+	endpoints := middleware.NewEndpointSwitch(cfg.DisabledEndpoints)
+	router.Use(endpoints.Middleware())
+	if cfg.Clock.HeaderEnabled {
+		router.Use(middleware.TimeTravel(cfg.AdminToken))
+	}
+	if services.Diagnostics != nil {
+		router.Use(middleware.ExplainQueries(cfg.DBConfig.ExplainMode, cfg.AdminToken, services.Diagnostics))
+	}
+	if services.Tenants != nil {
+		router.Use(middleware.Tenant(services.Tenants.Get))
+	}
+	if services.APIKeys != nil {
+		router.Use(middleware.ServiceAPIKey(services.APIKeys.Authenticate))
+	}
+	if services.Developer != nil {
+		router.Use(middleware.DeveloperApp(services.Developer.Authenticate, services.Limiter))
+	}
+	if services.Limits != nil {
+		router.Use(middleware.RateLimit(services.Limiter, cfg.ServiceKeyRateLimitPerMinute))
+	}
+	router.Use(middleware.Audit(cfg.AdminToken))
+	if len(services.Deprecations) > 0 {
+		router.Use(middleware.Deprecation(services.Deprecations, "/api/v1/changelog", cfg.Deprecation.HeadersEnabled))
+	}
+	if services.Meter != nil {
+		router.Use(middleware.Metering(services.Meter))
+	}
 
-	router.GET("/health", func(c *gin.Context) {
-		c.JSON(200, gin.H{"status": "ok"})
-	})
+	probeHandler := NewProbeHandler(services.Readiness)
+	router.GET("/healthz", probeHandler.Liveness)
+	router.GET("/readyz", probeHandler.Readiness)
+
+	router.GET("/metrics", gin.WrapH(metrics.Handler()))
+	if cfg.Debug.MetricsUIEnabled {
+		router.GET("/admin/metrics-ui", gin.WrapH(metricsui.Handler()))
+	}
+
+	if cfg.Debug.PprofEnabled {
+		debugHandler := NewDebugHandler(cfg.Debug.DumpDir)
+		debug := router.Group("/debug", middleware.AdminAuth(cfg.AdminToken))
+		debug.GET("/pprof/*profile", debugHandler.Pprof)
+		debug.POST("/pprof/*profile", debugHandler.Pprof)
+		debug.POST("/dump", debugHandler.Dump)
+	}
 
 	// Swagger
 	router.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
 
 	v1 := router.Group("/api/v1")
 	{
-		subscriptionHandler := NewSubscriptionHandler(subscriptionService)
+		subscriptionHandler := NewSubscriptionHandler(services.Subscriptions, services.WriteQueue)
+		notificationHandler := NewNotificationHandler(services.Notifications)
+		userHandler := NewUserHandler(services.Users, services.Subscriptions)
+		budgetHandler := NewBudgetHandler(services.Budgets)
+		duplicateHandler := NewDuplicateHandler(services.Duplicates)
 
-		subscriptions := v1.Group("/subscriptions")
+		// Данные пользователей. С RBAC_ENABLED правила ниже ограничивают роль user
+		// ее собственными данными, support - чтением без скрытых полей, без него ничего не проверяют
+		owned := v1.Group("")
+		if cfg.RBACEnabled {
+			owned.Use(middleware.Principal(services.Users.Get), middleware.FieldVisibility(services.FieldVisibility))
+		}
+		scoped := middleware.ScopeUserID()
+		adminOnly := middleware.RequireAdmin()
+		ownSubscription := middleware.Owner("id", subscriptionOwner(services.Subscriptions))
+		ownBudget := middleware.Owner("id", budgetOwner(services.Budgets))
+		self := middleware.Owner("id", middleware.Self)
+
+		subscriptions := owned.Group("/subscriptions")
 		{
 			subscriptions.POST("", subscriptionHandler.CreateSubscription)
-			subscriptions.GET("", subscriptionHandler.ListSubscriptions)
-			subscriptions.GET("/calculate", subscriptionHandler.CalculateTotal)
-			subscriptions.GET("/:id", subscriptionHandler.GetSubscription)
-			subscriptions.PUT("/:id", subscriptionHandler.UpdateSubscription)
-			subscriptions.DELETE("/:id", subscriptionHandler.DeleteSubscription)
+			subscriptions.POST("/bulk", middleware.TenantFeature(domain.FeatureBulkOperations), subscriptionHandler.BulkCreateSubscriptions)
+			subscriptions.GET("", scoped, subscriptionHandler.ListSubscriptions)
+			subscriptions.DELETE("", adminOnly, middleware.TenantFeature(domain.FeatureBulkOperations), subscriptionHandler.DeleteSubscriptions)
+			subscriptions.GET("/calculate", scoped, subscriptionHandler.CalculateTotal)
+			subscriptions.GET("/calculate/breakdown", scoped, subscriptionHandler.CalculateBreakdown)
+			subscriptions.GET("/duplicates", scoped, duplicateHandler.ListDuplicates)
+			if services.Changes != nil {
+				subscriptions.GET("/changes", scoped, NewChangesHandler(services.Changes).ListSubscriptionChanges)
+			}
+			subscriptions.GET("/:id", ownSubscription, subscriptionHandler.GetSubscription)
+			subscriptions.PUT("/:id", ownSubscription, subscriptionHandler.ReplaceSubscription)
+			subscriptions.PATCH("/:id", ownSubscription, subscriptionHandler.UpdateSubscription)
+			subscriptions.DELETE("/:id", ownSubscription, subscriptionHandler.DeleteSubscription)
+			subscriptions.POST("/:id/status", ownSubscription, subscriptionHandler.ChangeSubscriptionStatus)
+			subscriptions.POST("/:id/cancel", ownSubscription, subscriptionHandler.CancelSubscription)
+			subscriptions.GET("/:id/status-history", ownSubscription, subscriptionHandler.GetStatusHistory)
+			subscriptions.POST("/:id/discounts", ownSubscription, subscriptionHandler.CreateDiscount)
+			subscriptions.GET("/:id/discounts", ownSubscription, subscriptionHandler.ListDiscounts)
+			subscriptions.DELETE("/:id/discounts/:discount_id", ownSubscription, subscriptionHandler.DeleteDiscount)
+			subscriptions.GET("/:id/price-history", ownSubscription, subscriptionHandler.GetPriceHistory)
+		}
+
+		users := owned.Group("/users")
+		{
+			users.POST("", adminOnly, userHandler.CreateUser)
+			users.GET("", adminOnly, userHandler.ListUsers)
+			users.GET("/:id", self, userHandler.GetUser)
+			users.PATCH("/:id", self, userHandler.UpdateUser)
+			users.DELETE("/:id", self, userHandler.DeleteUser)
+			users.GET("/:id/subscriptions", self, userHandler.ListUserSubscriptions)
+			users.GET("/:id/calendar", self, middleware.TenantFeature(domain.FeatureCalendar), subscriptionHandler.BillingCalendar)
+			users.GET("/:id/notification-settings", self, middleware.TenantFeature(domain.FeatureNotifications), notificationHandler.GetNotificationSettings)
+			users.PUT("/:id/notification-settings", self, middleware.TenantFeature(domain.FeatureNotifications), notificationHandler.UpdateNotificationSettings)
+			if services.Statements != nil {
+				users.GET("/:id/statements", self, middleware.TenantFeature(domain.FeatureNotifications), NewStatementHandler(services.Statements).ListStatements)
+			}
+			if services.Alerts != nil {
+				users.GET("/:id/alerts", self, middleware.TenantFeature(domain.FeatureNotifications), NewAlertHandler(services.Alerts).ListAlerts)
+			}
+		}
+
+		// Подтвердить уведомление может не только владелец: доступ проверяет хендлер
+		if services.Alerts != nil {
+			owned.POST("/alerts/:id/acknowledge", middleware.TenantFeature(domain.FeatureNotifications), NewAlertHandler(services.Alerts).AcknowledgeAlert)
+		}
+
+		budgets := owned.Group("/budgets")
+		{
+			budgets.POST("", budgetHandler.CreateBudget)
+			budgets.GET("", scoped, budgetHandler.ListBudgets)
+			budgets.GET("/status", scoped, budgetHandler.GetBudgetStatus)
+			budgets.GET("/:id", ownBudget, budgetHandler.GetBudget)
+			budgets.PATCH("/:id", ownBudget, budgetHandler.UpdateBudget)
+			budgets.DELETE("/:id", ownBudget, budgetHandler.DeleteBudget)
+		}
+
+		owned.GET("/analytics/yoy", scoped, subscriptionHandler.YearOverYear)
+		owned.GET("/recommendations", scoped, duplicateHandler.ListRecommendations)
+
+		if services.EventSchemas != nil {
+			v1.GET("/event-schemas", NewEventSchemaHandler(services.EventSchemas).ListEventSchemas)
+		}
+
+		if services.Changelog != nil {
+			v1.GET("/changelog", NewChangelogHandler(services.Changelog).ListChangelog)
 		}
+
+		if services.Clients != nil {
+			clientsHandler := NewClientsHandler(services.Clients)
+			v1.GET("/clients", clientsHandler.ListClientArtifacts)
+			v1.GET("/clients/:name", clientsHandler.DownloadClientArtifact)
+		}
+
+		if services.NotificationPreview != nil {
+			previewHandler := NewNotificationPreviewHandler(services.NotificationPreview)
+			v1.POST("/notifications/preview", middleware.AdminAuth(cfg.AdminToken), previewHandler.PreviewNotification)
+		}
+
+		var usageHandler *UsageHandler
+		if services.Usage != nil {
+			usageHandler = NewUsageHandler(services.Usage, services.Exports)
+			v1.GET("/usage", usageHandler.GetUsage)
+		}
+
+		var exportHandler *ExportHandler
+		if services.Exports != nil {
+			exportHandler = NewExportHandler(services.Exports)
+			// Ссылка из письма открывается без токена администратора, доступ дает токен в ссылке
+			v1.GET("/exports/:id/download", exportHandler.DownloadExport)
+		}
+
+		if services.Limits != nil {
+			v1.GET("/limits", NewLimitsHandler(services.Limits).GetLimits)
+		}
+
+		if services.Developer != nil {
+			developerHandler := NewDeveloperHandler(services.Developer)
+			v1.POST("/developer/apps", developerHandler.RegisterApp)
+
+			app := v1.Group("/developer/app")
+			app.Use(middleware.RequireDeveloperApp())
+			{
+				app.GET("", developerHandler.GetApp)
+				app.GET("/usage", developerHandler.GetAppUsage)
+				app.GET("/rate-limit", developerHandler.GetAppRateLimit)
+				app.POST("/rotate-secret", developerHandler.RotateAppSecret)
+			}
+		}
+
+		admin := v1.Group("/admin")
+		admin.Use(middleware.AdminAuth(cfg.AdminToken))
+		{
+			admin.POST("/subscriptions/backfill", subscriptionHandler.BackfillSubscription)
+			admin.GET("/service-aliases", subscriptionHandler.ListServiceAliases)
+			admin.PUT("/service-aliases", subscriptionHandler.UpsertServiceAlias)
+			admin.DELETE("/service-aliases/:alias", subscriptionHandler.DeleteServiceAlias)
+			admin.PUT("/users/:id/role", userHandler.SetUserRole)
+
+			if services.Tenants != nil {
+				tenantHandler := NewTenantHandler(services.Tenants)
+				admin.POST("/tenants", tenantHandler.CreateTenant)
+				admin.GET("/tenants", tenantHandler.ListTenants)
+				admin.GET("/tenants/:id", tenantHandler.GetTenant)
+				admin.DELETE("/tenants/:id", tenantHandler.DeleteTenant)
+				admin.POST("/tenants/:id/suspend", tenantHandler.SuspendTenant)
+				admin.POST("/tenants/:id/resume", tenantHandler.ResumeTenant)
+				admin.PUT("/tenants/:id/quotas", tenantHandler.UpdateTenantQuotas)
+				admin.PUT("/tenants/:id/money-format", tenantHandler.UpdateTenantMoneyFormat)
+				admin.PUT("/tenants/:id/open-ended", tenantHandler.UpdateTenantOpenEnded)
+				admin.PUT("/tenants/:id/field-visibility", tenantHandler.UpdateTenantFieldVisibility)
+				admin.PUT("/tenants/:id/custom-fields", tenantHandler.UpdateTenantCustomFields)
+				admin.PUT("/tenants/:id/pinned-rates", tenantHandler.UpdateTenantPinnedRates)
+				admin.PATCH("/tenants/:id/features", tenantHandler.UpdateTenantFeatures)
+				admin.GET("/tenants/:id/usage", tenantHandler.GetTenantUsage)
+				admin.POST("/tenants/:id/rotate-credentials", tenantHandler.RotateTenantCredentials)
+			}
+
+			if services.Diagnostics != nil {
+				admin.GET("/diagnostics/query-plans", NewDiagnosticsHandler(services.Diagnostics).ListQueryPlans)
+			}
+
+			if services.SystemHealth != nil {
+				admin.GET("/system-health", NewSystemHealthHandler(services.SystemHealth).GetSystemHealth)
+			}
+
+			if services.SLO != nil {
+				admin.GET("/slo", NewSLOHandler(services.SLO).GetSLOReport)
+			}
+
+			if services.CalculateExperiment != nil {
+				admin.GET("/experiments/calculate", NewExperimentHandler(services.CalculateExperiment).GetCalculateExperiment)
+			}
+
+			if services.Replication != nil {
+				admin.PUT("/replication/subscriptions", NewReplicationHandler(services.Replication).ApplyReplicatedSubscription)
+			}
+
+			if services.Nudges != nil {
+				nudgeHandler := NewNudgeHandler(services.Nudges)
+				admin.GET("/nudges", nudgeHandler.ListNudges)
+				admin.POST("/nudges/:id/dismiss", nudgeHandler.DismissNudge)
+			}
+
+			if services.DataRepair != nil {
+				dataRepairHandler := NewDataRepairHandler(services.DataRepair)
+				admin.GET("/data-issues", dataRepairHandler.ListDataIssues)
+				admin.POST("/data-issues/repair", dataRepairHandler.RepairDataIssues)
+				admin.GET("/data-repairs", dataRepairHandler.ListDataRepairs)
+			}
+
+			if services.Audit != nil {
+				admin.GET("/audit", NewAuditHandler(services.Audit).ListAudit)
+			}
+
+			if services.SchemaMigration != nil {
+				schemaMigrationHandler := NewSchemaMigrationHandler(services.SchemaMigration)
+				admin.GET("/schema-migration", schemaMigrationHandler.VerifySchemaMigration)
+				admin.POST("/schema-migration/backfill", schemaMigrationHandler.BackfillSchemaMigration)
+			}
+
+			if services.Developer != nil {
+				admin.PATCH("/developer-apps/:id", NewDeveloperHandler(services.Developer).UpdateDeveloperApp)
+			}
+
+			if services.APIKeys != nil {
+				apiKeyHandler := NewAPIKeyHandler(services.APIKeys)
+				admin.POST("/api-keys", apiKeyHandler.CreateAPIKey)
+				admin.GET("/api-keys", apiKeyHandler.ListAPIKeys)
+				admin.DELETE("/api-keys/:id", apiKeyHandler.RevokeAPIKey)
+			}
+
+			if usageHandler != nil {
+				admin.GET("/usage", usageHandler.ListUsage)
+				admin.GET("/usage/export", usageHandler.ExportUsage)
+			}
+
+			if exportHandler != nil {
+				admin.GET("/exports/:id", exportHandler.GetExport)
+			}
+
+			if services.WriteQueue != nil {
+				writeQueueHandler := NewWriteQueueHandler(services.WriteQueue)
+				admin.GET("/write-queue", writeQueueHandler.ListQueuedWrites)
+				admin.DELETE("/write-queue/:id", writeQueueHandler.DiscardQueuedWrite)
+			}
+		}
+	}
+
+	disabled, unmatched := endpoints.Apply(router.Routes())
+	if len(disabled) > 0 {
+		logger.Info("Endpoints disabled by configuration", "routes", disabled)
+	}
+	for _, rule := range unmatched {
+		logger.Warn("DISABLED_ENDPOINTS entry matches no route", "entry", rule)
 	}
 
 	return router
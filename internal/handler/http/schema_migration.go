@@ -0,0 +1,56 @@
+package http
+
+import (
+	"net/http"
+
+	"aggregator_db/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+type SchemaMigrationHandler struct {
+	service *service.SchemaMigrationService
+}
+
+func NewSchemaMigrationHandler(service *service.SchemaMigrationService) *SchemaMigrationHandler {
+	return &SchemaMigrationHandler{service: service}
+}
+
+// VerifySchemaMigration godoc
+// @Summary      Сверить колонки перехода схемы
+// @Description  Сравнивает новые колонки подписок тенанта (start_on, end_on, price_amount) со значениями, вычисленными из старых, для переходов в фазе dual_write или dual_read (MIGRATION_DATES, MIGRATION_MONEY) и ничего не меняет. actual = null - строка еще не заполнена. dates_ready и money_ready - расхождений нет, переход можно завершать
+// @Tags         admin
+// @Produce      json
+// @Param        X-Admin-Token header string true "Токен администратора"
+// @Success      200 {object} domain.SchemaMigrationReport
+// @Failure      401 {object} domain.ErrorResponse
+// @Failure      500 {object} domain.ErrorResponse
+// @Router       /admin/schema-migration [get]
+func (h *SchemaMigrationHandler) VerifySchemaMigration(c *gin.Context) {
+	report, err := h.service.Verify(c.Request.Context())
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// BackfillSchemaMigration godoc
+// @Summary      Заполнить колонки перехода схемы
+// @Description  Пачками по MIGRATION_BATCH_SIZE вычисляет заново новые колонки подписок, которые расходятся со старыми: так заполняются строки, записанные до включения dual_write. В ответе - расхождения до заполнения; готовность к переключению покажет повторная сверка
+// @Tags         admin
+// @Produce      json
+// @Param        X-Admin-Token header string true "Токен администратора"
+// @Success      200 {object} domain.SchemaMigrationReport
+// @Failure      401 {object} domain.ErrorResponse
+// @Failure      500 {object} domain.ErrorResponse
+// @Router       /admin/schema-migration/backfill [post]
+func (h *SchemaMigrationHandler) BackfillSchemaMigration(c *gin.Context) {
+	report, err := h.service.Backfill(c.Request.Context())
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
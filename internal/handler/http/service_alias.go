@@ -0,0 +1,81 @@
+package http
+
+import (
+	"net/http"
+
+	"aggregator_db/pkg/domain"
+	"github.com/gin-gonic/gin"
+)
+
+// ListServiceAliases godoc
+// @Summary      Список алиасов сервисов
+// @Description  Возвращает сопоставления вариантов написания сервисов с каноническими названиями
+// @Tags         admin
+// @Produce      json
+// @Param        X-Admin-Token header string true "Токен администратора"
+// @Success      200 {array} domain.ServiceAlias
+// @Failure      401 {object} domain.ErrorResponse
+// @Failure      403 {object} domain.ErrorResponse
+// @Failure      500 {object} domain.ErrorResponse
+// @Router       /admin/service-aliases [get]
+func (h *SubscriptionHandler) ListServiceAliases(c *gin.Context) {
+	aliases, err := h.service.ListServiceAliases(c.Request.Context())
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, aliases)
+}
+
+// UpsertServiceAlias godoc
+// @Summary      Создать или изменить алиас сервиса
+// @Description  Сводит вариант написания (например, "Яндекс Плюс") к каноническому названию ("Yandex Plus") для поиска и агрегации
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        X-Admin-Token header string true "Токен администратора"
+// @Param        alias body domain.UpsertServiceAliasRequest true "Алиас и каноническое название"
+// @Success      200 {object} domain.ServiceAlias
+// @Failure      400 {object} domain.ErrorResponse
+// @Failure      401 {object} domain.ErrorResponse
+// @Failure      403 {object} domain.ErrorResponse
+// @Failure      500 {object} domain.ErrorResponse
+// @Router       /admin/service-aliases [put]
+func (h *SubscriptionHandler) UpsertServiceAlias(c *gin.Context) {
+	var req domain.UpsertServiceAliasRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBadRequest(c, err)
+		return
+	}
+
+	alias, err := h.service.UpsertServiceAlias(c.Request.Context(), req)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, alias)
+}
+
+// DeleteServiceAlias godoc
+// @Summary      Удалить алиас сервиса
+// @Description  Удаляет сопоставление; название сравнивается по ключу: без учета регистра, с транслитерацией кириллицы
+// @Tags         admin
+// @Produce      json
+// @Param        X-Admin-Token header string true "Токен администратора"
+// @Param        alias path string true "Вариант написания сервиса"
+// @Success      200 {object} domain.SuccessResponse
+// @Failure      401 {object} domain.ErrorResponse
+// @Failure      403 {object} domain.ErrorResponse
+// @Failure      404 {object} domain.ErrorResponse
+// @Router       /admin/service-aliases/{alias} [delete]
+func (h *SubscriptionHandler) DeleteServiceAlias(c *gin.Context) {
+	if err := h.service.DeleteServiceAlias(c.Request.Context(), c.Param("alias")); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, domain.SuccessResponse{Message: "service alias deleted"})
+}
@@ -0,0 +1,29 @@
+package http
+
+import (
+	"net/http"
+
+	"aggregator_db/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+type SLOHandler struct {
+	service *service.SLOService
+}
+
+func NewSLOHandler(service *service.SLOService) *SLOHandler {
+	return &SLOHandler{service: service}
+}
+
+// GetSLOReport godoc
+// @Summary      Состояние целей маршрутов (SLO)
+// @Description  Для каждого маршрута из SLO_OBJECTIVES: цели доступности (доля ответов не 5xx) и задержки (доля ответов не дольше latency_threshold_ms), число запросов и плохих ответов за 5m, 30m, 1h и 6h и скорость сгорания бюджета ошибок (1 - бюджет расходуется ровно в срок). alerts - сработавшие правила: page (порог SLO_FAST_BURN_RATE в окнах 1h и 5m) и ticket (SLO_SLOW_BURN_RATE в окнах 6h и 30m). Запросы считаются на реплике, которая отвечает
+// @Tags         admin
+// @Produce      json
+// @Param        X-Admin-Token header string true "Токен администратора"
+// @Success      200 {object} domain.SLOReport
+// @Failure      401 {object} domain.ErrorResponse
+// @Router       /admin/slo [get]
+func (h *SLOHandler) GetSLOReport(c *gin.Context) {
+	c.JSON(http.StatusOK, h.service.Report(c.Request.Context()))
+}
@@ -0,0 +1,43 @@
+package http
+
+import (
+	"net/http"
+
+	"aggregator_db/internal/service"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type StatementHandler struct {
+	service *service.StatementService
+}
+
+func NewStatementHandler(service *service.StatementService) *StatementHandler {
+	return &StatementHandler{service: service}
+}
+
+// ListStatements godoc
+// @Summary      Ежемесячные выписки пользователя
+// @Description  Последние 24 выписки со статусом доставки, новые сверху. Выписка ставится в очередь в начале месяца за прошлый месяц, если в настройках уведомлений выбран statement_channel; failed - все попытки отправки исчерпаны, причина в error
+// @Tags         users
+// @Produce      json
+// @Param        id path string true "ID пользователя" Format(uuid)
+// @Success      200 {array} domain.ExportJob
+// @Failure      400 {object} domain.ErrorResponse
+// @Failure      500 {object} domain.ErrorResponse
+// @Router       /users/{id}/statements [get]
+func (h *StatementHandler) ListStatements(c *gin.Context) {
+	userID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		respondBadRequest(c, errInvalidUserID)
+		return
+	}
+
+	statements, err := h.service.List(c.Request.Context(), userID)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, statements)
+}
@@ -0,0 +1,108 @@
+package http
+
+import (
+	"net/http"
+
+	"aggregator_db/pkg/domain"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// ChangeSubscriptionStatus godoc
+// @Summary      Изменить статус подписки
+// @Description  Переводит подписку в новый статус. Допустимые переходы: active -> paused/cancelled/expired, paused -> active/cancelled/expired; cancelled и expired конечные
+// @Tags         subscriptions
+// @Accept       json
+// @Produce      json
+// @Param        id path string true "ID подписки" Format(uuid)
+// @Param        status body domain.ChangeStatusRequest true "Новый статус"
+// @Success      200 {object} domain.Subscription
+// @Failure      400 {object} domain.ErrorResponse
+// @Failure      404 {object} domain.ErrorResponse
+// @Failure      500 {object} domain.ErrorResponse
+// @Router       /subscriptions/{id}/status [post]
+func (h *SubscriptionHandler) ChangeSubscriptionStatus(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		respondErrorCode(c, http.StatusBadRequest, domain.CodeInvalidID, "invalid subscription id")
+		return
+	}
+
+	var req domain.ChangeStatusRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBadRequest(c, err)
+		return
+	}
+
+	subscription, err := h.service.ChangeStatus(c.Request.Context(), id, req)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, subscription)
+}
+
+// CancelSubscription godoc
+// @Summary      Отменить подписку
+// @Description  effective_month - последний оплачиваемый месяц (по умолчанию текущий), он записывается в end_date; статус cancelled действует со следующего месяца. Сохраняются cancelled_at и cancel_reason
+// @Tags         subscriptions
+// @Accept       json
+// @Produce      json
+// @Param        id path string true "ID подписки" Format(uuid)
+// @Param        cancellation body domain.CancelSubscriptionRequest false "Месяц и причина отмены"
+// @Success      200 {object} domain.Subscription
+// @Failure      400 {object} domain.ErrorResponse
+// @Failure      404 {object} domain.ErrorResponse
+// @Failure      409 {object} domain.ErrorResponse "Подписку изменили во время отмены (VERSION_CONFLICT)"
+// @Failure      500 {object} domain.ErrorResponse
+// @Router       /subscriptions/{id}/cancel [post]
+func (h *SubscriptionHandler) CancelSubscription(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		respondErrorCode(c, http.StatusBadRequest, domain.CodeInvalidID, "invalid subscription id")
+		return
+	}
+
+	var req domain.CancelSubscriptionRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			respondBadRequest(c, err)
+			return
+		}
+	}
+
+	subscription, err := h.service.Cancel(c.Request.Context(), id, req)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, subscription)
+}
+
+// GetStatusHistory godoc
+// @Summary      История статусов подписки
+// @Description  Возвращает изменения статуса в порядке вступления в силу
+// @Tags         subscriptions
+// @Produce      json
+// @Param        id path string true "ID подписки" Format(uuid)
+// @Success      200 {array} domain.StatusChange
+// @Failure      400 {object} domain.ErrorResponse
+// @Failure      404 {object} domain.ErrorResponse
+// @Router       /subscriptions/{id}/status-history [get]
+func (h *SubscriptionHandler) GetStatusHistory(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		respondErrorCode(c, http.StatusBadRequest, domain.CodeInvalidID, "invalid subscription id")
+		return
+	}
+
+	history, err := h.service.StatusHistory(c.Request.Context(), id)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, history)
+}
@@ -1,46 +1,155 @@
 package http
 
 import (
-	"errors"
+	"encoding/json"
 	"net/http"
+	"time"
 
-	"aggregator_db/internal/domain"
-	"aggregator_db/internal/repository/postgres"
 	"aggregator_db/internal/service"
+	"aggregator_db/pkg/domain"
 	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
 	"github.com/google/uuid"
 )
 
 type SubscriptionHandler struct {
 	service *service.SubscriptionService
+	// writes откладывает создание и изменение при недоступной базе; nil - режим выключен
+	writes *service.WriteQueueService
 }
 
-func NewSubscriptionHandler(service *service.SubscriptionService) *SubscriptionHandler {
-	return &SubscriptionHandler{service: service}
+func NewSubscriptionHandler(service *service.SubscriptionService, writes *service.WriteQueueService) *SubscriptionHandler {
+	return &SubscriptionHandler{service: service, writes: writes}
+}
+
+// respondWrite отвечает 202 с отложенной записью, если база была недоступна и запись попала в очередь.
+func respondWrite(c *gin.Context, status int, subscription *domain.Subscription, queued *domain.QueuedWrite, err error) {
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	if queued != nil {
+		c.JSON(http.StatusAccepted, queued)
+		return
+	}
+	c.Header("ETag", subscriptionETag(subscription.Version))
+	c.JSON(status, subscription)
 }
 
 // CreateSubscription godoc
 // @Summary      Создать новую подписку
-// @Description  Создает новую запись о подписке пользователя
+// @Description  Создает новую запись о подписке пользователя. ID можно передать в поле id, чтобы подписка, созданная на клиенте, сохранила его при синхронизации; занятый ID - 409
 // @Tags         subscriptions
 // @Accept       json
 // @Produce      json
 // @Param        subscription body domain.CreateSubscriptionRequest true "Данные подписки"
 // @Success      201 {object} domain.Subscription
+// @Success      202 {object} domain.QueuedWrite "База недоступна, создание отложено"
 // @Failure      400 {object} domain.ErrorResponse
+// @Failure      409 {object} domain.ErrorResponse "Подписка с таким id уже есть (SUBSCRIPTION_ALREADY_EXISTS)"
 // @Failure      500 {object} domain.ErrorResponse
+// @Failure      503 {object} domain.ErrorResponse
 // @Router       /subscriptions [post]
 func (h *SubscriptionHandler) CreateSubscription(c *gin.Context) {
 	var req domain.CreateSubscriptionRequest
 
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, domain.ErrorResponse{Error: err.Error()})
+		respondBadRequest(c, err)
+		return
+	}
+	if !authorizeUser(c, req.UserID) {
+		return
+	}
+
+	var subscription *domain.Subscription
+	var queued *domain.QueuedWrite
+	var err error
+	if h.writes != nil {
+		subscription, queued, err = h.writes.Create(c.Request.Context(), req)
+	} else {
+		subscription, err = h.service.Create(c.Request.Context(), req)
+	}
+	respondWrite(c, http.StatusCreated, subscription, queued, err)
+}
+
+// BulkCreateSubscriptions godoc
+// @Summary      Создать подписки пачкой
+// @Description  Создает до 1000 подписок в одной транзакции: либо все, либо ни одной. Результат возвращается по каждому элементу. Повтор id внутри пачки - ошибка элемента, id, занятый существующей подпиской, - 409 для всей пачки
+// @Tags         subscriptions
+// @Accept       json
+// @Produce      json
+// @Param        subscriptions body []domain.CreateSubscriptionRequest true "Массив подписок"
+// @Success      201 {object} domain.BulkCreateResponse
+// @Failure      400 {object} domain.BulkCreateResponse
+// @Failure      409 {object} domain.ErrorResponse "Подписка с id одного из элементов уже есть (SUBSCRIPTION_ALREADY_EXISTS)"
+// @Failure      500 {object} domain.ErrorResponse
+// @Router       /subscriptions/bulk [post]
+func (h *SubscriptionHandler) BulkCreateSubscriptions(c *gin.Context) {
+	var reqs []domain.CreateSubscriptionRequest
+
+	// Тело декодируем без валидации, чтобы вернуть ошибки по каждому элементу
+	if err := json.NewDecoder(c.Request.Body).Decode(&reqs); err != nil {
+		respondBadRequest(c, err)
 		return
 	}
 
-	subscription, err := h.service.Create(c.Request.Context(), req)
+	resp := domain.BulkCreateResponse{Items: make([]domain.BulkCreateItemResult, len(reqs))}
+	invalid := false
+	for i := range reqs {
+		resp.Items[i].Index = i
+		if err := binding.Validator.ValidateStruct(&reqs[i]); err != nil {
+			resp.Items[i].Error = err.Error()
+			invalid = true
+		}
+	}
+	if invalid {
+		c.JSON(http.StatusBadRequest, resp)
+		return
+	}
+	for _, req := range reqs {
+		if !authorizeUser(c, req.UserID) {
+			return
+		}
+	}
+
+	result, err := h.service.CreateBulk(c.Request.Context(), reqs)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, domain.ErrorResponse{Error: err.Error()})
+		if result != nil {
+			c.JSON(http.StatusBadRequest, result)
+			return
+		}
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, result)
+}
+
+// BackfillSubscription godoc
+// @Summary      Загрузить историческую подписку
+// @Description  Админский режим: создает подписку с заданными created_at/updated_at и помечает ее как загруженную задним числом
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        X-Admin-Token header string true "Токен администратора"
+// @Param        subscription body domain.BackfillSubscriptionRequest true "Исторические данные подписки"
+// @Success      201 {object} domain.Subscription
+// @Failure      400 {object} domain.ErrorResponse
+// @Failure      401 {object} domain.ErrorResponse
+// @Failure      403 {object} domain.ErrorResponse
+// @Failure      500 {object} domain.ErrorResponse
+// @Router       /admin/subscriptions/backfill [post]
+func (h *SubscriptionHandler) BackfillSubscription(c *gin.Context) {
+	var req domain.BackfillSubscriptionRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBadRequest(c, err)
+		return
+	}
+
+	subscription, err := h.service.Backfill(c.Request.Context(), req)
+	if err != nil {
+		respondError(c, err)
 		return
 	}
 
@@ -54,7 +163,10 @@ func (h *SubscriptionHandler) CreateSubscription(c *gin.Context) {
 // @Accept       json
 // @Produce      json
 // @Param        id path string true "ID подписки" Format(uuid)
+// @Param        freshness query string false "eventual - можно читать из реплики (по умолчанию), strong - только основная база" Enums(eventual, strong)
 // @Success      200 {object} domain.Subscription
+// @Header       200 {string} ETag "Версия подписки для If-Match"
+// @Header       200 {string} X-Data-As-Of "Момент, на который актуальны данные (RFC 3339)"
 // @Failure      400 {object} domain.ErrorResponse
 // @Failure      404 {object} domain.ErrorResponse
 // @Router       /subscriptions/{id} [get]
@@ -62,61 +174,115 @@ func (h *SubscriptionHandler) GetSubscription(c *gin.Context) {
 	idStr := c.Param("id")
 	id, err := uuid.Parse(idStr)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, domain.ErrorResponse{Error: "invalid subscription id"})
+		respondErrorCode(c, http.StatusBadRequest, domain.CodeInvalidID, "invalid subscription id")
 		return
 	}
 
-	subscription, err := h.service.GetByID(c.Request.Context(), id)
+	// Чтение без последующей записи может уйти в реплику (DB_REPLICA_DSN)
+	ctx, dataAsOf, err := readFreshness(c)
 	if err != nil {
-		if errors.Is(err, postgres.ErrNotFound) {
-			c.JSON(http.StatusNotFound, domain.ErrorResponse{Error: "subscription not found"})
-			return
-		}
-		c.JSON(http.StatusInternalServerError, domain.ErrorResponse{Error: err.Error()})
+		respondBadRequest(c, err)
+		return
+	}
+	subscription, err := h.service.GetByID(ctx, id)
+	if err != nil {
+		respondError(c, err)
 		return
 	}
 
+	c.Header("ETag", subscriptionETag(subscription.Version))
+	c.Header(DataAsOfHeader, dataAsOf().Format(time.RFC3339Nano))
 	c.JSON(http.StatusOK, subscription)
 }
 
+// ReplaceSubscription godoc
+// @Summary      Заменить подписку
+// @Description  Полностью заменяет данные подписки. Не переданный end_date делает подписку бессрочной
+// @Tags         subscriptions
+// @Accept       json
+// @Produce      json
+// @Param        id path string true "ID подписки" Format(uuid)
+// @Param        If-Match header string false "ETag подписки: изменение применится, только если ее не меняли после чтения"
+// @Param        subscription body domain.ReplaceSubscriptionRequest true "Новые данные подписки"
+// @Success      200 {object} domain.Subscription
+// @Success      202 {object} domain.QueuedWrite "База недоступна, изменение отложено"
+// @Failure      400 {object} domain.ErrorResponse
+// @Failure      404 {object} domain.ErrorResponse
+// @Failure      409 {object} domain.ErrorResponse "Подписку изменили после чтения (VERSION_CONFLICT)"
+// @Failure      500 {object} domain.ErrorResponse
+// @Failure      503 {object} domain.ErrorResponse
+// @Router       /subscriptions/{id} [put]
+func (h *SubscriptionHandler) ReplaceSubscription(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		respondErrorCode(c, http.StatusBadRequest, domain.CodeInvalidID, "invalid subscription id")
+		return
+	}
+
+	var req domain.ReplaceSubscriptionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBadRequest(c, err)
+		return
+	}
+	if req.Version, err = expectedVersion(c, req.Version); err != nil {
+		respondBadRequest(c, err)
+		return
+	}
+
+	var subscription *domain.Subscription
+	var queued *domain.QueuedWrite
+	if h.writes != nil {
+		subscription, queued, err = h.writes.Replace(c.Request.Context(), id, req)
+	} else {
+		subscription, err = h.service.Replace(c.Request.Context(), id, req)
+	}
+	respondWrite(c, http.StatusOK, subscription, queued, err)
+}
+
 // UpdateSubscription godoc
-// @Summary      Обновить подписку
-// @Description  Обновляет существующую подписку
+// @Summary      Частично обновить подписку
+// @Description  Обновляет только переданные поля. end_date: null снимает дату окончания
 // @Tags         subscriptions
 // @Accept       json
 // @Produce      json
 // @Param        id path string true "ID подписки" Format(uuid)
+// @Param        If-Match header string false "ETag подписки: изменение применится, только если ее не меняли после чтения"
 // @Param        subscription body domain.UpdateSubscriptionRequest true "Обновляемые данные"
 // @Success      200 {object} domain.Subscription
+// @Success      202 {object} domain.QueuedWrite "База недоступна, изменение отложено"
 // @Failure      400 {object} domain.ErrorResponse
 // @Failure      404 {object} domain.ErrorResponse
+// @Failure      409 {object} domain.ErrorResponse "Подписку изменили после чтения (VERSION_CONFLICT)"
 // @Failure      500 {object} domain.ErrorResponse
-// @Router       /subscriptions/{id} [put]
+// @Failure      503 {object} domain.ErrorResponse
+// @Router       /subscriptions/{id} [patch]
 func (h *SubscriptionHandler) UpdateSubscription(c *gin.Context) {
 	idStr := c.Param("id")
 	id, err := uuid.Parse(idStr)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, domain.ErrorResponse{Error: "invalid subscription id"})
+		respondErrorCode(c, http.StatusBadRequest, domain.CodeInvalidID, "invalid subscription id")
 		return
 	}
 
 	var req domain.UpdateSubscriptionRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, domain.ErrorResponse{Error: err.Error()})
+		respondBadRequest(c, err)
 		return
 	}
-
-	subscription, err := h.service.Update(c.Request.Context(), id, req)
-	if err != nil {
-		if errors.Is(err, postgres.ErrNotFound) {
-			c.JSON(http.StatusNotFound, domain.ErrorResponse{Error: "subscription not found"})
-			return
-		}
-		c.JSON(http.StatusInternalServerError, domain.ErrorResponse{Error: err.Error()})
+	if req.Version, err = expectedVersion(c, req.Version); err != nil {
+		respondBadRequest(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, subscription)
+	var subscription *domain.Subscription
+	var queued *domain.QueuedWrite
+	if h.writes != nil {
+		subscription, queued, err = h.writes.Update(c.Request.Context(), id, req)
+	} else {
+		subscription, err = h.service.Update(c.Request.Context(), id, req)
+	}
+	respondWrite(c, http.StatusOK, subscription, queued, err)
 }
 
 // DeleteSubscription godoc
@@ -134,79 +300,157 @@ func (h *SubscriptionHandler) DeleteSubscription(c *gin.Context) {
 	idStr := c.Param("id")
 	id, err := uuid.Parse(idStr)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, domain.ErrorResponse{Error: "invalid subscription id"})
+		respondErrorCode(c, http.StatusBadRequest, domain.CodeInvalidID, "invalid subscription id")
 		return
 	}
 
 	if err := h.service.Delete(c.Request.Context(), id); err != nil {
-		if errors.Is(err, postgres.ErrNotFound) {
-			c.JSON(http.StatusNotFound, domain.ErrorResponse{Error: "subscription not found"})
-			return
-		}
-		c.JSON(http.StatusInternalServerError, domain.ErrorResponse{Error: err.Error()})
+		respondError(c, err)
 		return
 	}
 
 	c.JSON(http.StatusOK, domain.SuccessResponse{Message: "subscription deleted"})
 }
 
+// DeleteSubscriptions godoc
+// @Summary      Удалить подписки по фильтру
+// @Description  Удаляет одной транзакцией все подписки, подходящие под фильтр (условия объединяются через AND). Нужен хотя бы один фильтр
+// @Tags         subscriptions
+// @Accept       json
+// @Produce      json
+// @Param        filter body domain.DeleteSubscriptionsFilter true "Фильтр удаления"
+// @Success      200 {object} domain.DeleteSubscriptionsResponse
+// @Failure      400 {object} domain.ErrorResponse
+// @Failure      500 {object} domain.ErrorResponse
+// @Router       /subscriptions [delete]
+func (h *SubscriptionHandler) DeleteSubscriptions(c *gin.Context) {
+	var filter domain.DeleteSubscriptionsFilter
+
+	if err := c.ShouldBindJSON(&filter); err != nil {
+		respondBadRequest(c, err)
+		return
+	}
+
+	resp, err := h.service.DeleteByFilter(c.Request.Context(), filter)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
 // ListSubscriptions godoc
 // @Summary      Получить список подписок
 // @Description  Возвращает список подписок с возможностью фильтрации
 // @Tags         subscriptions
 // @Accept       json
 // @Produce      json
-// @Param        user_id query string false "ID пользователя" Format(uuid)
-// @Param        service_name query string false "Название сервиса"
+// @Param        user_id query string false "ID пользователя (устаревший вариант: userId); пустое значение отклоняется с 400" Format(uuid)
+// @Param        service_name query string false "Название сервиса (с учетом транслитерации и алиасов)"
+// @Param        status query string false "Текущий статус подписки" Enums(active, paused, cancelled, expired)
+// @Param        tag query []string false "Метка; при нескольких tag подписка должна иметь их все" collectionFormat(multi)
+// @Param        q query string false "Поиск по названию и заметкам без учета регистра; каждое слово должно встретиться"
+// @Param        match query string false "Режим поиска q: contains - каждое слово в названии или заметках (по умолчанию), fuzzy - название похоже на q с учетом опечаток" Enums(contains, fuzzy)
+// @Param        min_price query string false "Цена за цикл оплаты не меньше, в единицах валюты currency (1000, 9.99)"
+// @Param        max_price query string false "Цена за цикл оплаты не больше, в единицах валюты currency"
+// @Param        currency query string false "Только подписки в этой валюте; с min_price или max_price по умолчанию RUB"
+// @Param        active_in query string false "Только подписки, действующие в этом месяце (MM-YYYY)" example(10-2025)
+// @Param        custom_field query []string false "Пользовательское поле тенанта name:value; при нескольких подписка должна совпасть по всем" collectionFormat(multi)
 // @Param        limit query int false "Лимит записей" default(100)
 // @Param        offset query int false "Смещение" default(0)
-// @Success      200 {array} domain.Subscription
+// @Param        snapshot query string false "Токен snapshot из ответа первой страницы: следующие страницы не видят подписки, созданные после нее"
+// @Param        cursor query string false "Токен next_cursor из ответа предыдущей страницы: страница начинается сразу после нее; вместе с offset не передается"
+// @Param        sort_by query string false "Поле сортировки: price - в минорных единицах без пересчета валют, start_date - по месяцу начала; курсор работает только с created_at по убыванию" Enums(price, start_date, service_name, created_at) default(created_at)
+// @Param        order query string false "Направление сортировки; по умолчанию desc для created_at и asc для остальных полей" Enums(asc, desc)
+// @Param        freshness query string false "eventual - можно читать из реплики (по умолчанию), strong - только основная база" Enums(eventual, strong)
+// @Success      200 {object} domain.ListSubscriptionsResponse
 // @Failure      400 {object} domain.ErrorResponse
+// @Failure      500 {object} domain.ErrorResponse
 // @Router       /subscriptions [get]
 func (h *SubscriptionHandler) ListSubscriptions(c *gin.Context) {
 	var query domain.ListSubscriptionsQuery
 
 	if err := c.ShouldBindQuery(&query); err != nil {
-		c.JSON(http.StatusBadRequest, domain.ErrorResponse{Error: err.Error()})
+		respondBadRequest(c, err)
 		return
 	}
 
-	subscriptions, err := h.service.List(c.Request.Context(), query)
+	userID, err := parseUserIDQuery(c)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, domain.ErrorResponse{Error: err.Error()})
+		respondBadRequest(c, err)
 		return
 	}
+	query.UserID = userID
 
-	c.JSON(http.StatusOK, subscriptions)
+	ctx, dataAsOf, err := readFreshness(c)
+	if err != nil {
+		respondBadRequest(c, err)
+		return
+	}
+	result, err := h.service.List(ctx, query)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	result.DataAsOf = dataAsOf()
+
+	c.JSON(http.StatusOK, result)
 }
 
 // CalculateTotal godoc
 // @Summary      Рассчитать суммарную стоимость
-// @Description  Рассчитывает суммарную стоимость подписок за период с фильтрацией
+// @Description  Рассчитывает суммарную стоимость подписок за период с фильтрацией. С user_ids возвращает общую сумму выбранных пользователей и подытог каждого (by_user), посчитанные одним запросом с группировкой по пользователю
 // @Tags         subscriptions
 // @Accept       json
 // @Produce      json
-// @Param        user_id query string false "ID пользователя" Format(uuid)
-// @Param        service_name query string false "Название сервиса"
+// @Param        user_id query string false "ID пользователя (устаревший вариант: userId); пустое значение отклоняется с 400" Format(uuid)
+// @Param        user_ids query []string false "Пользователи, до 100: общая сумма и подытог каждого в by_user; через запятую или повторением, несовместим с user_id" collectionFormat(csv)
+// @Param        service_name query string false "Название сервиса (с учетом транслитерации и алиасов)"
 // @Param        start_period query string true "Начало периода" Format(MM-YYYY)
 // @Param        end_period query string true "Конец периода" Format(MM-YYYY)
+// @Param        group_by query string false "Разбивка суммы: classification - по классам месяцев (new, renewal, upgraded, downgraded)" Enums(classification)
+// @Param        exclude_inactive query bool false "Не учитывать месяцы, когда подписка была на паузе или отменена"
+// @Param        currency query string false "Валюта суммы, подписки в других валютах не учитываются (по умолчанию RUB)"
+// @Param        target_currency query string false "Пересчитать подписки во всех валютах в эту валюту по текущему курсу"
+// @Param        tag query []string false "Учитывать только подписки со всеми указанными метками" collectionFormat(multi)
+// @Param        include_items query bool false "Добавить оплаченные месяцы подписок, из которых сложилась сумма (items), до 5000; стоимость позиций в валюте подписки"
+// @Param        freshness query string false "eventual - можно читать из реплики (по умолчанию), strong - только основная база" Enums(eventual, strong)
 // @Success      200 {object} domain.CalculateTotalResponse
 // @Failure      400 {object} domain.ErrorResponse
 // @Failure      500 {object} domain.ErrorResponse
+// @Failure      503 {object} domain.ErrorResponse
 // @Router       /subscriptions/calculate [get]
 func (h *SubscriptionHandler) CalculateTotal(c *gin.Context) {
 	var req domain.CalculateTotalRequest
 
 	if err := c.ShouldBindQuery(&req); err != nil {
-		c.JSON(http.StatusBadRequest, domain.ErrorResponse{Error: err.Error()})
+		respondBadRequest(c, err)
+		return
+	}
+
+	userID, err := parseUserIDQuery(c)
+	if err != nil {
+		respondBadRequest(c, err)
+		return
+	}
+	req.UserID = userID
+	if req.UserIDs, err = parseUserIDsQuery(c); err != nil {
+		respondBadRequest(c, err)
 		return
 	}
 
-	result, err := h.service.CalculateTotal(c.Request.Context(), req)
+	ctx, dataAsOf, err := readFreshness(c)
+	if err != nil {
+		respondBadRequest(c, err)
+		return
+	}
+	result, err := h.service.CalculateTotal(ctx, req)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, domain.ErrorResponse{Error: err.Error()})
+		respondError(c, err)
 		return
 	}
+	result.DataAsOf = dataAsOf()
 
 	c.JSON(http.StatusOK, result)
 }
@@ -0,0 +1,29 @@
+package http
+
+import (
+	"net/http"
+
+	"aggregator_db/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+type SystemHealthHandler struct {
+	service *service.SystemHealthService
+}
+
+func NewSystemHealthHandler(service *service.SystemHealthService) *SystemHealthHandler {
+	return &SystemHealthHandler{service: service}
+}
+
+// GetSystemHealth godoc
+// @Summary      Сводная оценка состояния системы
+// @Description  Для дашбордов и алертов: задержка ping базы (db_latency), очереди отложенных записей (write_queue) и выгрузок на почту (export_jobs), circuit breaker доставки событий (events_webhook), доля попаданий в кэш курсов (exchange_rates_cache_hit_rate) и доля ответов 5xx (http_error_rate) за последние минуты. У каждой составляющей оценка 0-100 и состояние ok, degraded или critical; score - среднее оценок, status - худшее состояние. Отвечает 200 при любом состоянии
+// @Tags         admin
+// @Produce      json
+// @Param        X-Admin-Token header string true "Токен администратора"
+// @Success      200 {object} domain.SystemHealth
+// @Failure      401 {object} domain.ErrorResponse
+// @Router       /admin/system-health [get]
+func (h *SystemHealthHandler) GetSystemHealth(c *gin.Context) {
+	c.JSON(http.StatusOK, h.service.Check(c.Request.Context()))
+}
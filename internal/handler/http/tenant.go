@@ -0,0 +1,445 @@
+package http
+
+import (
+	"net/http"
+
+	"aggregator_db/internal/service"
+	"aggregator_db/pkg/domain"
+	"github.com/gin-gonic/gin"
+)
+
+type TenantHandler struct {
+	service *service.TenantService
+}
+
+func NewTenantHandler(service *service.TenantService) *TenantHandler {
+	return &TenantHandler{service: service}
+}
+
+// CreateTenant godoc
+// @Summary      Подключить enterprise-тенанта
+// @Description  Создает схему tenant_<id> в общей базе (или использует отдельную базу из database_url), применяет к ней миграции и регистрирует тенанта. Дальше запросы с заголовками X-Tenant-ID и X-Tenant-Key работают с его данными. Ключ возвращается только в этом ответе
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        X-Admin-Token header string true "Токен администратора"
+// @Param        tenant body domain.CreateTenantRequest true "Тенант"
+// @Success      201 {object} domain.TenantCredentials
+// @Failure      400 {object} domain.ErrorResponse
+// @Failure      401 {object} domain.ErrorResponse
+// @Failure      403 {object} domain.ErrorResponse
+// @Failure      409 {object} domain.ErrorResponse
+// @Failure      500 {object} domain.ErrorResponse
+// @Router       /admin/tenants [post]
+func (h *TenantHandler) CreateTenant(c *gin.Context) {
+	var req domain.CreateTenantRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBadRequest(c, err)
+		return
+	}
+
+	tenant, err := h.service.Provision(c.Request.Context(), req)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, tenant)
+}
+
+// ListTenants godoc
+// @Summary      Список тенантов
+// @Tags         admin
+// @Produce      json
+// @Param        X-Admin-Token header string true "Токен администратора"
+// @Success      200 {array} domain.Tenant
+// @Failure      401 {object} domain.ErrorResponse
+// @Failure      403 {object} domain.ErrorResponse
+// @Failure      500 {object} domain.ErrorResponse
+// @Router       /admin/tenants [get]
+func (h *TenantHandler) ListTenants(c *gin.Context) {
+	tenants, err := h.service.List(c.Request.Context())
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, tenants)
+}
+
+// GetTenant godoc
+// @Summary      Получить тенанта
+// @Tags         admin
+// @Produce      json
+// @Param        X-Admin-Token header string true "Токен администратора"
+// @Param        id path string true "ID тенанта"
+// @Success      200 {object} domain.Tenant
+// @Failure      401 {object} domain.ErrorResponse
+// @Failure      403 {object} domain.ErrorResponse
+// @Failure      404 {object} domain.ErrorResponse
+// @Failure      500 {object} domain.ErrorResponse
+// @Router       /admin/tenants/{id} [get]
+func (h *TenantHandler) GetTenant(c *gin.Context) {
+	tenant, err := h.service.Get(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, tenant)
+}
+
+// DeleteTenant godoc
+// @Summary      Удалить тенанта
+// @Description  Снимает тенанта с регистрации и удаляет его схему вместе с данными. Отдельная база тенанта не удаляется
+// @Tags         admin
+// @Produce      json
+// @Param        X-Admin-Token header string true "Токен администратора"
+// @Param        id path string true "ID тенанта"
+// @Success      200 {object} domain.SuccessResponse
+// @Failure      401 {object} domain.ErrorResponse
+// @Failure      403 {object} domain.ErrorResponse
+// @Failure      404 {object} domain.ErrorResponse
+// @Failure      500 {object} domain.ErrorResponse
+// @Router       /admin/tenants/{id} [delete]
+func (h *TenantHandler) DeleteTenant(c *gin.Context) {
+	if err := h.service.Delete(c.Request.Context(), c.Param("id")); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, domain.SuccessResponse{Message: "tenant deleted"})
+}
+
+// SuspendTenant godoc
+// @Summary      Приостановить тенанта
+// @Description  Запросы тенанта получают 403, данные сохраняются
+// @Tags         admin
+// @Produce      json
+// @Param        X-Admin-Token header string true "Токен администратора"
+// @Param        id path string true "ID тенанта"
+// @Success      200 {object} domain.Tenant
+// @Failure      401 {object} domain.ErrorResponse
+// @Failure      403 {object} domain.ErrorResponse
+// @Failure      404 {object} domain.ErrorResponse
+// @Failure      500 {object} domain.ErrorResponse
+// @Router       /admin/tenants/{id}/suspend [post]
+func (h *TenantHandler) SuspendTenant(c *gin.Context) {
+	h.setStatus(c, domain.TenantStatusSuspended)
+}
+
+// ResumeTenant godoc
+// @Summary      Возобновить тенанта
+// @Tags         admin
+// @Produce      json
+// @Param        X-Admin-Token header string true "Токен администратора"
+// @Param        id path string true "ID тенанта"
+// @Success      200 {object} domain.Tenant
+// @Failure      401 {object} domain.ErrorResponse
+// @Failure      403 {object} domain.ErrorResponse
+// @Failure      404 {object} domain.ErrorResponse
+// @Failure      500 {object} domain.ErrorResponse
+// @Router       /admin/tenants/{id}/resume [post]
+func (h *TenantHandler) ResumeTenant(c *gin.Context) {
+	h.setStatus(c, domain.TenantStatusActive)
+}
+
+func (h *TenantHandler) setStatus(c *gin.Context, status domain.TenantStatus) {
+	tenant, err := h.service.SetStatus(c.Request.Context(), c.Param("id"), status)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, tenant)
+}
+
+// UpdateTenantQuotas godoc
+// @Summary      Задать квоты тенанта
+// @Description  Полностью заменяет квоты; отсутствующее поле снимает лимит. При превышении max_subscriptions создание подписок возвращает 403
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        X-Admin-Token header string true "Токен администратора"
+// @Param        id path string true "ID тенанта"
+// @Param        quotas body domain.TenantQuotas true "Квоты"
+// @Success      200 {object} domain.Tenant
+// @Failure      400 {object} domain.ErrorResponse
+// @Failure      401 {object} domain.ErrorResponse
+// @Failure      403 {object} domain.ErrorResponse
+// @Failure      404 {object} domain.ErrorResponse
+// @Failure      500 {object} domain.ErrorResponse
+// @Router       /admin/tenants/{id}/quotas [put]
+func (h *TenantHandler) UpdateTenantQuotas(c *gin.Context) {
+	var quotas domain.TenantQuotas
+
+	if err := c.ShouldBindJSON(&quotas); err != nil {
+		respondBadRequest(c, err)
+		return
+	}
+
+	tenant, err := h.service.SetQuotas(c.Request.Context(), c.Param("id"), quotas)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, tenant)
+}
+
+// UpdateTenantMoneyFormat godoc
+// @Summary      Задать правила округления и вывода сумм тенанта
+// @Description  Полностью заменяет правила для итогов расчета стоимости, помесячной разбивки, сравнения год к году и бюджетов: rounding (half_up или half_even), precision (знаков после запятой, не больше, чем у валюты) и units (major - в единицах валюты, minor - в минорных единицах). Пустой объект возвращает правила по умолчанию
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        X-Admin-Token header string true "Токен администратора"
+// @Param        id path string true "ID тенанта"
+// @Param        format body domain.MoneyFormat true "Правила"
+// @Success      200 {object} domain.Tenant
+// @Failure      400 {object} domain.ErrorResponse
+// @Failure      401 {object} domain.ErrorResponse
+// @Failure      403 {object} domain.ErrorResponse
+// @Failure      404 {object} domain.ErrorResponse
+// @Failure      500 {object} domain.ErrorResponse
+// @Router       /admin/tenants/{id}/money-format [put]
+func (h *TenantHandler) UpdateTenantMoneyFormat(c *gin.Context) {
+	var format domain.MoneyFormat
+
+	if err := c.ShouldBindJSON(&format); err != nil {
+		respondBadRequest(c, err)
+		return
+	}
+
+	tenant, err := h.service.SetMoneyFormat(c.Request.Context(), c.Param("id"), format)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, tenant)
+}
+
+// UpdateTenantOpenEnded godoc
+// @Summary      Задать расчет бессрочных подписок тенанта
+// @Description  Полностью заменяет настройку для подписок без end_date: total - до какого месяца они входят в расчет стоимости (period_end - до конца периода, по умолчанию; current_month - не дальше текущего месяца), forecast_months - сколько месяцев после текущего они учитываются в помесячной разбивке (без значения - бессрочно). Пустой объект возвращает поведение по умолчанию
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        X-Admin-Token header string true "Токен администратора"
+// @Param        id path string true "ID тенанта"
+// @Param        policy body domain.OpenEndedPolicy true "Настройка"
+// @Success      200 {object} domain.Tenant
+// @Failure      400 {object} domain.ErrorResponse
+// @Failure      401 {object} domain.ErrorResponse
+// @Failure      403 {object} domain.ErrorResponse
+// @Failure      404 {object} domain.ErrorResponse
+// @Failure      500 {object} domain.ErrorResponse
+// @Router       /admin/tenants/{id}/open-ended [put]
+func (h *TenantHandler) UpdateTenantOpenEnded(c *gin.Context) {
+	var policy domain.OpenEndedPolicy
+
+	if err := c.ShouldBindJSON(&policy); err != nil {
+		respondBadRequest(c, err)
+		return
+	}
+
+	tenant, err := h.service.SetOpenEndedPolicy(c.Request.Context(), c.Param("id"), policy)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, tenant)
+}
+
+// UpdateTenantFieldVisibility godoc
+// @Summary      Задать видимость полей для ролей тенанта
+// @Description  Полностью заменяет поля ответов, скрытые от ролей user и support: поле - JSON-ключ, который убирается на любой глубине ответа, money скрывает все суммы. Роль с пустым списком видит все поля. Пустой объект возвращает правила FIELD_VISIBILITY. Администратор видит все поля
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        X-Admin-Token header string true "Токен администратора"
+// @Param        id path string true "ID тенанта"
+// @Param        visibility body domain.FieldVisibility true "Скрытые поля по ролям" example({"support": ["money", "notes"]})
+// @Success      200 {object} domain.Tenant
+// @Failure      400 {object} domain.ErrorResponse
+// @Failure      401 {object} domain.ErrorResponse
+// @Failure      403 {object} domain.ErrorResponse
+// @Failure      404 {object} domain.ErrorResponse
+// @Failure      500 {object} domain.ErrorResponse
+// @Router       /admin/tenants/{id}/field-visibility [put]
+func (h *TenantHandler) UpdateTenantFieldVisibility(c *gin.Context) {
+	var visibility domain.FieldVisibility
+
+	if err := c.ShouldBindJSON(&visibility); err != nil {
+		respondBadRequest(c, err)
+		return
+	}
+
+	tenant, err := h.service.SetFieldVisibility(c.Request.Context(), c.Param("id"), visibility)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, tenant)
+}
+
+// UpdateTenantCustomFields godoc
+// @Summary      Задать пользовательские поля подписок тенанта
+// @Description  Полностью заменяет схему полей, которые подписки тенанта хранят в custom_fields: имя, тип (string, number, boolean), обязательность и правила проверки значения. Пустой список убирает поля. Сохраненные значения не пересчитываются: новая схема проверяет подписку при следующем изменении
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        X-Admin-Token header string true "Токен администратора"
+// @Param        id path string true "ID тенанта"
+// @Param        fields body []domain.CustomFieldDefinition true "Схема полей"
+// @Success      200 {object} domain.Tenant
+// @Failure      400 {object} domain.ErrorResponse
+// @Failure      401 {object} domain.ErrorResponse
+// @Failure      403 {object} domain.ErrorResponse
+// @Failure      404 {object} domain.ErrorResponse
+// @Failure      500 {object} domain.ErrorResponse
+// @Router       /admin/tenants/{id}/custom-fields [put]
+func (h *TenantHandler) UpdateTenantCustomFields(c *gin.Context) {
+	var schema domain.CustomFieldSchema
+
+	if err := c.ShouldBindJSON(&schema); err != nil {
+		respondBadRequest(c, err)
+		return
+	}
+
+	tenant, err := h.service.SetCustomFields(c.Request.Context(), c.Param("id"), schema)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, tenant)
+}
+
+// UpdateTenantPinnedRates godoc
+// @Summary      Закрепить курсы валют тенанта
+// @Description  Полностью заменяет курсы, закрепленные вручную: values - стоимость единицы валюты в base (по умолчанию RUB) положительной десятичной строкой. Закрепленный курс заменяет курс провайдера при пересчете в target_currency, так считаются подписки в криптовалютах и своих валютах из CUSTOM_CURRENCIES. Каждое изменение увеличивает version, она попадает в source пересчета. Пустой values снимает закрепление
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        X-Admin-Token header string true "Токен администратора"
+// @Param        id path string true "ID тенанта"
+// @Param        rates body domain.UpdatePinnedRatesRequest true "Курсы"
+// @Success      200 {object} domain.Tenant
+// @Failure      400 {object} domain.ErrorResponse
+// @Failure      401 {object} domain.ErrorResponse
+// @Failure      403 {object} domain.ErrorResponse
+// @Failure      404 {object} domain.ErrorResponse
+// @Failure      500 {object} domain.ErrorResponse
+// @Router       /admin/tenants/{id}/pinned-rates [put]
+func (h *TenantHandler) UpdateTenantPinnedRates(c *gin.Context) {
+	var req domain.UpdatePinnedRatesRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBadRequest(c, err)
+		return
+	}
+
+	tenant, err := h.service.SetPinnedRates(c.Request.Context(), c.Param("id"), req)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, tenant)
+}
+
+// UpdateTenantFeatures godoc
+// @Summary      Изменить флаги возможностей тенанта
+// @Description  Меняет переданные флаги (bulk_operations, calendar, notifications), остальные не трогает. Не заданный флаг считается включенным
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        X-Admin-Token header string true "Токен администратора"
+// @Param        id path string true "ID тенанта"
+// @Param        features body domain.UpdateTenantFeaturesRequest true "Флаги"
+// @Success      200 {object} domain.Tenant
+// @Failure      400 {object} domain.ErrorResponse
+// @Failure      401 {object} domain.ErrorResponse
+// @Failure      403 {object} domain.ErrorResponse
+// @Failure      404 {object} domain.ErrorResponse
+// @Failure      500 {object} domain.ErrorResponse
+// @Router       /admin/tenants/{id}/features [patch]
+func (h *TenantHandler) UpdateTenantFeatures(c *gin.Context) {
+	var req domain.UpdateTenantFeaturesRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBadRequest(c, err)
+		return
+	}
+
+	tenant, err := h.service.SetFeatures(c.Request.Context(), c.Param("id"), req.Features)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, tenant)
+}
+
+// GetTenantUsage godoc
+// @Summary      Потребление тенанта
+// @Description  Количество подписок тенанта и число его запросов к этому экземпляру сервиса с момента старта
+// @Tags         admin
+// @Produce      json
+// @Param        X-Admin-Token header string true "Токен администратора"
+// @Param        id path string true "ID тенанта"
+// @Success      200 {object} domain.TenantUsage
+// @Failure      401 {object} domain.ErrorResponse
+// @Failure      403 {object} domain.ErrorResponse
+// @Failure      404 {object} domain.ErrorResponse
+// @Failure      500 {object} domain.ErrorResponse
+// @Router       /admin/tenants/{id}/usage [get]
+func (h *TenantHandler) GetTenantUsage(c *gin.Context) {
+	usage, err := h.service.Usage(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, usage)
+}
+
+// RotateTenantCredentials godoc
+// @Summary      Ротация учетных данных тенанта
+// @Description  Выдает новый X-Tenant-Key, старый перестает действовать сразу. Для тенанта с отдельной базой можно заменить database_url; соединения со старыми учетными данными закрываются
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        X-Admin-Token header string true "Токен администратора"
+// @Param        id path string true "ID тенанта"
+// @Param        credentials body domain.RotateTenantCredentialsRequest false "Новые учетные данные базы"
+// @Success      200 {object} domain.TenantCredentials
+// @Failure      400 {object} domain.ErrorResponse
+// @Failure      401 {object} domain.ErrorResponse
+// @Failure      403 {object} domain.ErrorResponse
+// @Failure      404 {object} domain.ErrorResponse
+// @Failure      500 {object} domain.ErrorResponse
+// @Router       /admin/tenants/{id}/rotate-credentials [post]
+func (h *TenantHandler) RotateTenantCredentials(c *gin.Context) {
+	var req domain.RotateTenantCredentialsRequest
+
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			respondBadRequest(c, err)
+			return
+		}
+	}
+
+	credentials, err := h.service.RotateCredentials(c.Request.Context(), c.Param("id"), req)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, credentials)
+}
@@ -0,0 +1,137 @@
+package http
+
+import (
+	"fmt"
+	"net/http"
+
+	"aggregator_db/internal/metering"
+	"aggregator_db/internal/service"
+	"aggregator_db/pkg/domain"
+	"github.com/gin-gonic/gin"
+)
+
+type UsageHandler struct {
+	service *service.UsageService
+	// exports может быть nil: тогда доставка на почту недоступна
+	exports *service.ExportService
+}
+
+func NewUsageHandler(service *service.UsageService, exports *service.ExportService) *UsageHandler {
+	return &UsageHandler{service: service, exports: exports}
+}
+
+// GetUsage godoc
+// @Summary      Потребление API
+// @Description  Суточное потребление вызывающего (тенант из X-Tenant-ID или default): запросы, выгруженные строки, доставки вебхуков. Данные сбрасываются в хранилище периодически и могут отставать
+// @Tags         usage
+// @Produce      json
+// @Param        from query string true "Начало периода (YYYY-MM-DD)"
+// @Param        to query string true "Конец периода включительно (YYYY-MM-DD)"
+// @Success      200 {object} domain.UsageResponse
+// @Failure      400 {object} domain.ErrorResponse
+// @Failure      500 {object} domain.ErrorResponse
+// @Router       /usage [get]
+func (h *UsageHandler) GetUsage(c *gin.Context) {
+	var query domain.UsageQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		respondBadRequest(c, err)
+		return
+	}
+
+	usage, err := h.service.Usage(c.Request.Context(), metering.Consumer(c.Request.Context()), query.From, query.To)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, usage)
+}
+
+// ListUsage godoc
+// @Summary      Журнал потребления API
+// @Description  Суточные записи потребления всех потребителей или одного (consumer)
+// @Tags         admin
+// @Produce      json
+// @Param        X-Admin-Token header string true "Токен администратора"
+// @Param        consumer query string false "Потребитель: ID тенанта или default"
+// @Param        from query string true "Начало периода (YYYY-MM-DD)"
+// @Param        to query string true "Конец периода включительно (YYYY-MM-DD)"
+// @Success      200 {array} domain.UsageRecord
+// @Failure      400 {object} domain.ErrorResponse
+// @Failure      401 {object} domain.ErrorResponse
+// @Failure      403 {object} domain.ErrorResponse
+// @Failure      500 {object} domain.ErrorResponse
+// @Router       /admin/usage [get]
+func (h *UsageHandler) ListUsage(c *gin.Context) {
+	var query domain.UsageQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		respondBadRequest(c, err)
+		return
+	}
+
+	records, err := h.service.List(c.Request.Context(), query)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, records)
+}
+
+// ExportUsage godoc
+// @Summary      Выгрузка потребления для выставления счетов
+// @Description  CSV с итогами по потребителю и метрике за период: consumer,metric,quantity,from,to.
+// @Description  С параметром email файл формируется в фоне и отправляется на почту, ответ - 202 с выгрузкой
+// @Tags         admin
+// @Produce      text/csv
+// @Produce      json
+// @Param        X-Admin-Token header string true "Токен администратора"
+// @Param        consumer query string false "Потребитель: ID тенанта или default"
+// @Param        from query string true "Начало периода (YYYY-MM-DD)"
+// @Param        to query string true "Конец периода включительно (YYYY-MM-DD)"
+// @Param        email query string false "Отправить выгрузку на этот адрес"
+// @Param        delivery query string false "Способ доставки на почту: attachment (по умолчанию) или link" Enums(attachment, link)
+// @Success      200 {string} string "CSV"
+// @Success      202 {object} domain.ExportJob
+// @Failure      400 {object} domain.ErrorResponse
+// @Failure      401 {object} domain.ErrorResponse
+// @Failure      403 {object} domain.ErrorResponse
+// @Failure      500 {object} domain.ErrorResponse
+// @Router       /admin/usage/export [get]
+func (h *UsageHandler) ExportUsage(c *gin.Context) {
+	var query domain.UsageQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		respondBadRequest(c, err)
+		return
+	}
+	var delivery domain.ExportEmailQuery
+	if err := c.ShouldBindQuery(&delivery); err != nil {
+		respondBadRequest(c, err)
+		return
+	}
+
+	if delivery.Email != "" {
+		if h.exports == nil {
+			respondErrorCode(c, http.StatusBadRequest, domain.CodeFeatureUnavailable, "email delivery is not available")
+			return
+		}
+		job, err := h.exports.EnqueueUsage(c.Request.Context(), query, delivery)
+		if err != nil {
+			respondError(c, err)
+			return
+		}
+		c.JSON(http.StatusAccepted, job)
+		return
+	}
+
+	lines, err := h.service.InvoiceLines(c.Request.Context(), query)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, service.UsageExportFileName(query)))
+	c.Status(http.StatusOK)
+	_ = service.WriteInvoiceCSV(c.Writer, query, lines)
+}
@@ -0,0 +1,286 @@
+package http
+
+import (
+	"net/http"
+
+	"aggregator_db/internal/service"
+	"aggregator_db/pkg/domain"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// BillingCalendar godoc
+// @Summary      Календарь списаний пользователя
+// @Description  Возвращает ожидаемые списания по каждому дню месяца. День списания - день created_at подписки, для коротких месяцев переносится на последний день
+// @Tags         users
+// @Produce      json
+// @Param        id path string true "ID пользователя" Format(uuid)
+// @Param        month query string true "Месяц" Format(MM-YYYY)
+// @Success      200 {object} domain.BillingCalendarResponse
+// @Failure      400 {object} domain.ErrorResponse
+// @Failure      500 {object} domain.ErrorResponse
+// @Router       /users/{id}/calendar [get]
+func (h *SubscriptionHandler) BillingCalendar(c *gin.Context) {
+	userID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		respondBadRequest(c, errInvalidUserID)
+		return
+	}
+
+	month, ok := c.GetQuery("month")
+	if !ok {
+		respondErrorCode(c, http.StatusBadRequest, domain.CodeValidationFailed, "month is required")
+		return
+	}
+
+	calendar, err := h.service.BillingCalendar(c.Request.Context(), userID, month)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, calendar)
+}
+
+type UserHandler struct {
+	users         *service.UserService
+	subscriptions *service.SubscriptionService
+}
+
+func NewUserHandler(users *service.UserService, subscriptions *service.SubscriptionService) *UserHandler {
+	return &UserHandler{users: users, subscriptions: subscriptions}
+}
+
+// CreateUser godoc
+// @Summary      Создать пользователя
+// @Description  Заводит пользователя; id можно передать, чтобы сохранить идентификатор из внешней системы. Пользователи без подписок заводятся только так, остальные создаются автоматически вместе с первой подпиской
+// @Tags         users
+// @Accept       json
+// @Produce      json
+// @Param        user body domain.CreateUserRequest true "Пользователь"
+// @Success      201 {object} domain.User
+// @Failure      400 {object} domain.ErrorResponse
+// @Failure      409 {object} domain.ErrorResponse
+// @Failure      500 {object} domain.ErrorResponse
+// @Router       /users [post]
+func (h *UserHandler) CreateUser(c *gin.Context) {
+	var req domain.CreateUserRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBadRequest(c, err)
+		return
+	}
+
+	user, err := h.users.Create(c.Request.Context(), req)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, user)
+}
+
+// ListUsers godoc
+// @Summary      Список пользователей
+// @Description  Возвращает пользователей в порядке создания
+// @Tags         users
+// @Produce      json
+// @Param        limit query int false "Лимит записей" default(100)
+// @Param        offset query int false "Смещение" default(0)
+// @Success      200 {object} domain.ListUsersResponse
+// @Failure      400 {object} domain.ErrorResponse
+// @Failure      500 {object} domain.ErrorResponse
+// @Router       /users [get]
+func (h *UserHandler) ListUsers(c *gin.Context) {
+	var query domain.ListUsersQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		respondBadRequest(c, err)
+		return
+	}
+
+	result, err := h.users.List(c.Request.Context(), query)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// GetUser godoc
+// @Summary      Получить пользователя
+// @Tags         users
+// @Produce      json
+// @Param        id path string true "ID пользователя" Format(uuid)
+// @Success      200 {object} domain.User
+// @Failure      400 {object} domain.ErrorResponse
+// @Failure      404 {object} domain.ErrorResponse
+// @Router       /users/{id} [get]
+func (h *UserHandler) GetUser(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		respondBadRequest(c, errInvalidUserID)
+		return
+	}
+
+	user, err := h.users.Get(c.Request.Context(), id)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, user)
+}
+
+// UpdateUser godoc
+// @Summary      Изменить пользователя
+// @Description  Частичное обновление: отсутствующее поле не меняется, null или пустая строка очищает его
+// @Tags         users
+// @Accept       json
+// @Produce      json
+// @Param        id path string true "ID пользователя" Format(uuid)
+// @Param        user body domain.UpdateUserRequest true "Изменяемые поля"
+// @Success      200 {object} domain.User
+// @Failure      400 {object} domain.ErrorResponse
+// @Failure      404 {object} domain.ErrorResponse
+// @Failure      409 {object} domain.ErrorResponse
+// @Router       /users/{id} [patch]
+func (h *UserHandler) UpdateUser(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		respondBadRequest(c, errInvalidUserID)
+		return
+	}
+
+	var req domain.UpdateUserRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBadRequest(c, err)
+		return
+	}
+
+	user, err := h.users.Update(c.Request.Context(), id, req)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, user)
+}
+
+// SetUserRole godoc
+// @Summary      Назначить роль пользователю
+// @Description  Роль учитывается при RBAC_ENABLED: user видит и меняет только свои данные, admin - данные любого пользователя и сводные ручки
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        X-Admin-Token header string true "Токен администратора"
+// @Param        id path string true "ID пользователя" Format(uuid)
+// @Param        role body domain.SetUserRoleRequest true "Роль"
+// @Success      200 {object} domain.User
+// @Failure      400 {object} domain.ErrorResponse
+// @Failure      401 {object} domain.ErrorResponse
+// @Failure      403 {object} domain.ErrorResponse
+// @Failure      404 {object} domain.ErrorResponse
+// @Router       /admin/users/{id}/role [put]
+func (h *UserHandler) SetUserRole(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		respondBadRequest(c, errInvalidUserID)
+		return
+	}
+
+	var req domain.SetUserRoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBadRequest(c, err)
+		return
+	}
+
+	user, err := h.users.SetRole(c.Request.Context(), id, req.Role)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, user)
+}
+
+// DeleteUser godoc
+// @Summary      Удалить пользователя
+// @Description  Удаляет пользователя вместе со всеми его подписками, их историей статусов и скидками
+// @Tags         users
+// @Produce      json
+// @Param        id path string true "ID пользователя" Format(uuid)
+// @Success      200 {object} domain.SuccessResponse
+// @Failure      400 {object} domain.ErrorResponse
+// @Failure      404 {object} domain.ErrorResponse
+// @Router       /users/{id} [delete]
+func (h *UserHandler) DeleteUser(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		respondBadRequest(c, errInvalidUserID)
+		return
+	}
+
+	if err := h.users.Delete(c.Request.Context(), id); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, domain.SuccessResponse{Message: "user deleted"})
+}
+
+// ListUserSubscriptions godoc
+// @Summary      Подписки пользователя
+// @Description  То же, что GET /subscriptions?user_id=, но для несуществующего пользователя возвращает 404
+// @Tags         users
+// @Produce      json
+// @Param        id path string true "ID пользователя" Format(uuid)
+// @Param        service_name query string false "Название сервиса (с учетом транслитерации и алиасов)"
+// @Param        status query string false "Текущий статус подписки" Enums(active, paused, cancelled, expired)
+// @Param        tag query []string false "Метка; при нескольких tag подписка должна иметь их все" collectionFormat(multi)
+// @Param        q query string false "Поиск по названию и заметкам без учета регистра; каждое слово должно встретиться"
+// @Param        match query string false "Режим поиска q: contains - каждое слово в названии или заметках (по умолчанию), fuzzy - название похоже на q с учетом опечаток" Enums(contains, fuzzy)
+// @Param        min_price query string false "Цена за цикл оплаты не меньше, в единицах валюты currency (1000, 9.99)"
+// @Param        max_price query string false "Цена за цикл оплаты не больше, в единицах валюты currency"
+// @Param        currency query string false "Только подписки в этой валюте; с min_price или max_price по умолчанию RUB"
+// @Param        active_in query string false "Только подписки, действующие в этом месяце (MM-YYYY)" example(10-2025)
+// @Param        custom_field query []string false "Пользовательское поле тенанта name:value; при нескольких подписка должна совпасть по всем" collectionFormat(multi)
+// @Param        limit query int false "Лимит записей" default(100)
+// @Param        offset query int false "Смещение" default(0)
+// @Param        snapshot query string false "Токен snapshot из ответа первой страницы: следующие страницы не видят подписки, созданные после нее"
+// @Param        cursor query string false "Токен next_cursor из ответа предыдущей страницы: страница начинается сразу после нее; вместе с offset не передается"
+// @Param        sort_by query string false "Поле сортировки: price - в минорных единицах без пересчета валют, start_date - по месяцу начала; курсор работает только с created_at по убыванию" Enums(price, start_date, service_name, created_at) default(created_at)
+// @Param        order query string false "Направление сортировки; по умолчанию desc для created_at и asc для остальных полей" Enums(asc, desc)
+// @Success      200 {object} domain.ListSubscriptionsResponse
+// @Failure      400 {object} domain.ErrorResponse
+// @Failure      404 {object} domain.ErrorResponse
+// @Failure      500 {object} domain.ErrorResponse
+// @Router       /users/{id}/subscriptions [get]
+func (h *UserHandler) ListUserSubscriptions(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		respondBadRequest(c, errInvalidUserID)
+		return
+	}
+
+	var query domain.ListSubscriptionsQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		respondBadRequest(c, err)
+		return
+	}
+	query.UserID = &id
+
+	if _, err := h.users.Get(c.Request.Context(), id); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	ctx, dataAsOf := freshRead(c, domain.FreshnessStrong)
+	result, err := h.subscriptions.List(ctx, query)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	result.DataAsOf = dataAsOf()
+
+	c.JSON(http.StatusOK, result)
+}
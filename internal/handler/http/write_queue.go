@@ -0,0 +1,60 @@
+package http
+
+import (
+	"net/http"
+
+	"aggregator_db/internal/service"
+	"aggregator_db/pkg/domain"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type WriteQueueHandler struct {
+	service *service.WriteQueueService
+}
+
+func NewWriteQueueHandler(service *service.WriteQueueService) *WriteQueueHandler {
+	return &WriteQueueHandler{service: service}
+}
+
+// ListQueuedWrites godoc
+// @Summary      Отложенные записи
+// @Description  Записи, принятые этой репликой при недоступной базе: ожидающие повтора и конфликтные
+// @Tags         admin
+// @Produce      json
+// @Param        X-Admin-Token header string true "Токен администратора"
+// @Success      200 {array} domain.QueuedWrite
+// @Failure      401 {object} domain.ErrorResponse
+// @Failure      403 {object} domain.ErrorResponse
+// @Router       /admin/write-queue [get]
+func (h *WriteQueueHandler) ListQueuedWrites(c *gin.Context) {
+	c.JSON(http.StatusOK, h.service.List(c.Request.Context()))
+}
+
+// DiscardQueuedWrite godoc
+// @Summary      Отбросить отложенную запись
+// @Description  Удаляет запись из очереди без применения, например после разбора конфликта
+// @Tags         admin
+// @Produce      json
+// @Param        X-Admin-Token header string true "Токен администратора"
+// @Param        id path string true "ID отложенной записи" Format(uuid)
+// @Success      200 {object} domain.SuccessResponse
+// @Failure      400 {object} domain.ErrorResponse
+// @Failure      401 {object} domain.ErrorResponse
+// @Failure      403 {object} domain.ErrorResponse
+// @Failure      404 {object} domain.ErrorResponse
+// @Router       /admin/write-queue/{id} [delete]
+func (h *WriteQueueHandler) DiscardQueuedWrite(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		respondErrorCode(c, http.StatusBadRequest, domain.CodeInvalidID, "invalid queued write id")
+		return
+	}
+
+	if err := h.service.Discard(c.Request.Context(), id); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, domain.SuccessResponse{Message: "queued write discarded"})
+}
@@ -0,0 +1,133 @@
+// Package metering учитывает потребление API по потребителям (тенантам и
+// приложениям портала разработчиков) для выставления счетов.
+package metering
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"aggregator_db/internal/developer"
+	"aggregator_db/internal/repository/postgres"
+	"aggregator_db/internal/tenancy"
+	"aggregator_db/pkg/domain"
+)
+
+// Meter копит потребление в памяти и периодически сбрасывает его в UsageRepository,
+// чтобы не писать в базу на каждый запрос. Работает на каждой реплике.
+type Meter struct {
+	repo   postgres.UsageRepository
+	logger *slog.Logger
+	now    func() time.Time
+
+	mu      sync.Mutex
+	pending map[pendingKey]int64
+}
+
+type pendingKey struct {
+	consumer string
+	metric   domain.UsageMetric
+	day      string
+}
+
+func NewMeter(repo postgres.UsageRepository, logger *slog.Logger) *Meter {
+	return &Meter{
+		repo:    repo,
+		logger:  logger,
+		now:     time.Now,
+		pending: make(map[pendingKey]int64),
+	}
+}
+
+// Consumer возвращает потребителя запроса: приложение, тенанта или DefaultUsageConsumer.
+func Consumer(ctx context.Context) string {
+	if app := developer.FromContext(ctx); app != nil {
+		return app.UsageConsumer()
+	}
+	if tenant := tenancy.FromContext(ctx); tenant != nil {
+		return tenant.ID
+	}
+	return domain.DefaultUsageConsumer
+}
+
+// Record учитывает quantity единиц метрики для потребителя из ctx.
+func (m *Meter) Record(ctx context.Context, metric domain.UsageMetric, quantity int64) {
+	if m == nil || quantity <= 0 {
+		return
+	}
+
+	key := pendingKey{
+		consumer: Consumer(ctx),
+		metric:   metric,
+		day:      m.now().UTC().Format(domain.CalendarDateLayout),
+	}
+
+	m.mu.Lock()
+	m.pending[key] += quantity
+	m.mu.Unlock()
+}
+
+// Flush сохраняет накопленное. При ошибке записи значения возвращаются
+// в буфер и уйдут следующим сбросом.
+func (m *Meter) Flush(ctx context.Context) error {
+	m.mu.Lock()
+	pending := m.pending
+	m.pending = make(map[pendingKey]int64)
+	m.mu.Unlock()
+
+	if len(pending) == 0 {
+		return nil
+	}
+
+	records := make([]*domain.UsageRecord, 0, len(pending))
+	for key, quantity := range pending {
+		records = append(records, &domain.UsageRecord{Consumer: key.consumer, Metric: key.metric, Day: key.day, Quantity: quantity})
+	}
+
+	if err := m.repo.Add(ctx, records); err != nil {
+		m.mu.Lock()
+		for key, quantity := range pending {
+			m.pending[key] += quantity
+		}
+		m.mu.Unlock()
+		return err
+	}
+	return nil
+}
+
+// Run сбрасывает буфер каждые interval до отмены ctx, затем делает последний сброс.
+func (m *Meter) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			flushCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
+			defer cancel()
+			if err := m.Flush(flushCtx); err != nil {
+				m.logger.Error("failed to flush usage on shutdown", "error", err.Error())
+			}
+			return
+		case <-ticker.C:
+			if err := m.Flush(ctx); err != nil {
+				m.logger.Warn("failed to flush usage", "error", err.Error())
+			}
+		}
+	}
+}
+
+type contextKey struct{}
+
+// WithMeter кладет счетчик в контекст запроса, чтобы сервисы могли учитывать
+// потребление без явной зависимости от Meter.
+func WithMeter(ctx context.Context, m *Meter) context.Context {
+	return context.WithValue(ctx, contextKey{}, m)
+}
+
+// Record учитывает потребление счетчиком из ctx; без счетчика ничего не делает.
+func Record(ctx context.Context, metric domain.UsageMetric, quantity int64) {
+	m, _ := ctx.Value(contextKey{}).(*Meter)
+	m.Record(ctx, metric, quantity)
+}
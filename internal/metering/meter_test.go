@@ -0,0 +1,99 @@
+package metering
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"aggregator_db/internal/repository/memory"
+	"aggregator_db/internal/repository/postgres"
+	"aggregator_db/internal/tenancy"
+	"aggregator_db/pkg/domain"
+)
+
+// flakyUsageRepo отказывает в записи, пока fail выставлен.
+type flakyUsageRepo struct {
+	postgres.UsageRepository
+	fail bool
+}
+
+func (r *flakyUsageRepo) Add(ctx context.Context, records []*domain.UsageRecord) error {
+	if r.fail {
+		return errors.New("database is unavailable")
+	}
+	return r.UsageRepository.Add(ctx, records)
+}
+
+func TestMeterFlush(t *testing.T) {
+	ctx := context.Background()
+	repo := memory.NewUsageRepository()
+	meter := NewMeter(repo, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	meter.now = func() time.Time { return time.Date(2025, 10, 23, 12, 0, 0, 0, time.UTC) }
+
+	tenantCtx := tenancy.WithTenant(ctx, &domain.Tenant{ID: "acme"})
+	meter.Record(tenantCtx, domain.UsageAPIRequests, 1)
+	meter.Record(tenantCtx, domain.UsageAPIRequests, 1)
+	meter.Record(tenantCtx, domain.UsageExportedRows, 25)
+	meter.Record(ctx, domain.UsageAPIRequests, 1)
+	meter.Record(ctx, domain.UsageExportedRows, 0)
+
+	if err := meter.Flush(ctx); err != nil {
+		t.Fatal(err)
+	}
+	// Повторный сброс не должен удваивать записи
+	if err := meter.Flush(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	records, err := repo.List(ctx, domain.UsageQuery{From: "2025-10-23", To: "2025-10-23"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := make(map[string]int64)
+	for _, r := range records {
+		got[r.Consumer+"/"+string(r.Metric)] = r.Quantity
+	}
+	want := map[string]int64{
+		"acme/api_requests":    2,
+		"acme/exported_rows":   25,
+		"default/api_requests": 1,
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	for k, v := range want {
+		if got[k] != v {
+			t.Errorf("%s = %d, want %d", k, got[k], v)
+		}
+	}
+}
+
+func TestMeterKeepsUsageOnFailedFlush(t *testing.T) {
+	ctx := context.Background()
+	repo := &flakyUsageRepo{UsageRepository: memory.NewUsageRepository(), fail: true}
+	meter := NewMeter(repo, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	meter.now = func() time.Time { return time.Date(2025, 10, 23, 12, 0, 0, 0, time.UTC) }
+
+	meter.Record(ctx, domain.UsageWebhookDeliveries, 3)
+	if err := meter.Flush(ctx); err == nil {
+		t.Fatal("expected flush error")
+	}
+
+	meter.Record(ctx, domain.UsageWebhookDeliveries, 2)
+	repo.fail = false
+	if err := meter.Flush(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	records, err := repo.List(ctx, domain.UsageQuery{From: "2025-10-23", To: "2025-10-23"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 1 || records[0].Quantity != 5 {
+		t.Fatalf("got %+v, want one record with quantity 5", records)
+	}
+}
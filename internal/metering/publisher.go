@@ -0,0 +1,26 @@
+package metering
+
+import (
+	"context"
+
+	"aggregator_db/pkg/domain"
+	"aggregator_db/pkg/events"
+)
+
+type publisher struct {
+	next  events.Publisher
+	meter *Meter
+}
+
+// NewPublisher учитывает успешные доставки вебхуков потребителю из контекста события.
+func NewPublisher(next events.Publisher, meter *Meter) events.Publisher {
+	return &publisher{next: next, meter: meter}
+}
+
+func (p *publisher) Publish(ctx context.Context, event events.Event) error {
+	if err := p.next.Publish(ctx, event); err != nil {
+		return err
+	}
+	p.meter.Record(ctx, domain.UsageWebhookDeliveries, 1)
+	return nil
+}
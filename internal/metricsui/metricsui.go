@@ -0,0 +1,24 @@
+// Package metricsui - встроенная в бинарник страница с графиками ключевых метрик
+// для окружений без Grafana. Страница сама опрашивает /metrics и хранит историю
+// в браузере, поэтому сервис ничего не копит.
+package metricsui
+
+import (
+	_ "embed"
+	"net/http"
+)
+
+//go:embed index.html
+var page []byte
+
+// Handler отдает страницу дашборда. Данных в ней нет: все значения она берет
+// из /metrics, который открыт и без нее.
+func Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Header().Set("Cache-Control", "no-cache")
+		// Скрипт страницы встроен, внешние ресурсы ей не нужны
+		w.Header().Set("Content-Security-Policy", "default-src 'none'; script-src 'unsafe-inline'; style-src 'unsafe-inline'; connect-src 'self'")
+		_, _ = w.Write(page)
+	})
+}
@@ -0,0 +1,35 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"net/http"
+
+	"aggregator_db/pkg/domain"
+	"github.com/gin-gonic/gin"
+)
+
+const AdminTokenHeader = "X-Admin-Token"
+
+// AdminAuth пропускает только запросы с корректным X-Admin-Token.
+// Если токен не задан в конфигурации, админские ручки отключены.
+func AdminAuth(token string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if token == "" {
+			c.AbortWithStatusJSON(http.StatusForbidden, domain.ErrorResponse{Code: domain.CodeAdminAPIDisabled, Error: "admin api is disabled"})
+			return
+		}
+
+		provided := c.GetHeader(AdminTokenHeader)
+		if provided == "" {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, domain.ErrorResponse{Code: domain.CodeUnauthorized, Error: "admin token required"})
+			return
+		}
+
+		if subtle.ConstantTimeCompare([]byte(provided), []byte(token)) != 1 {
+			c.AbortWithStatusJSON(http.StatusForbidden, domain.ErrorResponse{Code: domain.CodeInvalidAdminToken, Error: "invalid admin token"})
+			return
+		}
+
+		c.Next()
+	}
+}
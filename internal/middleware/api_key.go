@@ -0,0 +1,48 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"aggregator_db/internal/apikey"
+	"aggregator_db/internal/repository/postgres"
+	"aggregator_db/pkg/domain"
+	"aggregator_db/pkg/tracing"
+	"github.com/gin-gonic/gin"
+)
+
+// APIKeyResolver ищет действующий ключ сервиса по значению из заголовка.
+type APIKeyResolver func(ctx context.Context, secret string) (*domain.APIKey, error)
+
+// ServiceAPIKey аутентифицирует внутренние сервисы по ключу X-API-Key с префиксом
+// sk_service_ и проверяет права ключа: с правами read разрешено только чтение.
+// Ключи приложений портала разработчиков пропускаются дальше без изменений.
+func ServiceAPIKey(resolve APIKeyResolver) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		secret := c.GetHeader(APIKeyHeader)
+		if !domain.IsServiceAPIKey(secret) {
+			c.Next()
+			return
+		}
+
+		key, err := resolve(c.Request.Context(), secret)
+		if errors.Is(err, postgres.ErrAPIKeyNotFound) {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, domain.ErrorResponse{Code: domain.CodeInvalidAPIKey, Error: "invalid api key"})
+			return
+		}
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, domain.ErrorResponse{Code: domain.CodeInternal, Error: err.Error()})
+			return
+		}
+		tracing.LabelsFromContext(c.Request.Context()).Set(tracing.LabelAPIKey, keyLabel(key.ID))
+
+		if !key.Scope.Allows(c.Request.Method) {
+			c.AbortWithStatusJSON(http.StatusForbidden, domain.ErrorResponse{Code: domain.CodeAPIKeyScopeDenied, Error: "api key scope " + string(key.Scope) + " does not allow " + c.Request.Method})
+			return
+		}
+
+		c.Request = c.Request.WithContext(apikey.WithKey(c.Request.Context(), key))
+		c.Next()
+	}
+}
@@ -0,0 +1,87 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"aggregator_db/internal/apikey"
+	"aggregator_db/internal/ratelimit"
+	"aggregator_db/internal/repository/postgres"
+	"aggregator_db/pkg/domain"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+func TestServiceAPIKey(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	keys := map[string]*domain.APIKey{
+		"sk_service_reader": {ID: uuid.New(), Name: "reports", Scope: domain.APIKeyScopeRead},
+		"sk_service_writer": {ID: uuid.New(), Name: "aggregator", Scope: domain.APIKeyScopeReadWrite},
+	}
+	resolve := func(_ context.Context, secret string) (*domain.APIKey, error) {
+		if key, ok := keys[secret]; ok {
+			return key, nil
+		}
+		return nil, postgres.ErrAPIKeyNotFound
+	}
+	apps := func(_ context.Context, apiKey string) (*domain.DeveloperApp, error) {
+		if apiKey == "sk_sandbox_test" {
+			return &domain.DeveloperApp{ID: uuid.New(), RateLimitPerMinute: 10}, nil
+		}
+		return nil, postgres.ErrDeveloperAppNotFound
+	}
+
+	router := gin.New()
+	router.Use(ServiceAPIKey(resolve), DeveloperApp(apps, ratelimit.NewLimiter(time.Hour)))
+	handler := func(c *gin.Context) {
+		name := ""
+		if key := apikey.FromContext(c.Request.Context()); key != nil {
+			name = key.Name
+		}
+		c.String(http.StatusOK, name)
+	}
+	router.GET("/subscriptions", handler)
+	router.POST("/subscriptions", handler)
+
+	tests := []struct {
+		name   string
+		method string
+		key    string
+		want   int
+		body   string
+	}{
+		{name: "no key", method: http.MethodGet, want: http.StatusOK},
+		{name: "read key reads", method: http.MethodGet, key: "sk_service_reader", want: http.StatusOK, body: "reports"},
+		{name: "read key cannot write", method: http.MethodPost, key: "sk_service_reader", want: http.StatusForbidden},
+		{name: "read-write key writes", method: http.MethodPost, key: "sk_service_writer", want: http.StatusOK, body: "aggregator"},
+		{name: "unknown or revoked key", method: http.MethodGet, key: "sk_service_other", want: http.StatusUnauthorized},
+		// Ключ приложения проверяет следующий middleware
+		{name: "developer app key", method: http.MethodPost, key: "sk_sandbox_test", want: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, "/subscriptions", nil)
+			if tt.key != "" {
+				req.Header.Set(APIKeyHeader, tt.key)
+			}
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+
+			if rec.Code != tt.want {
+				t.Errorf("status = %d, want %d", rec.Code, tt.want)
+			}
+			if rec.Code == http.StatusOK && rec.Body.String() != tt.body {
+				t.Errorf("key in context = %q, want %q", rec.Body.String(), tt.body)
+			}
+			// Ключи сервисов не попадают под лимит приложений
+			if limit := rec.Header().Get("X-RateLimit-Limit"); (limit != "") != (tt.key == "sk_sandbox_test") {
+				t.Errorf("X-RateLimit-Limit = %q", limit)
+			}
+		})
+	}
+}
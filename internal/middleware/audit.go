@@ -0,0 +1,52 @@
+package middleware
+
+import (
+	"crypto/subtle"
+
+	"aggregator_db/internal/apikey"
+	"aggregator_db/internal/audit"
+	"aggregator_db/internal/developer"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// Audit кладет в контекст исполнителя запроса и ID запроса для журнала изменений.
+// Исполнитель - ключ сервиса, приложение портала, пользователь из X-User-ID или
+// администратор по X-Admin-Token; ID запроса - X-Request-ID, который уже выдал
+// ErrorTracking, иначе заголовок клиента или новый ID. Регистрируется после
+// ServiceAPIKey и DeveloperApp.
+func Audit(adminToken string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		requestID := c.Writer.Header().Get(RequestIDHeader)
+		if requestID == "" {
+			requestID = c.GetHeader(RequestIDHeader)
+			if !validRequestID(requestID) {
+				requestID = uuid.NewString()
+			}
+			c.Header(RequestIDHeader, requestID)
+		}
+
+		request := audit.Request{Actor: auditActor(c, adminToken), RequestID: requestID}
+		c.Request = c.Request.WithContext(audit.WithRequest(c.Request.Context(), request))
+		c.Next()
+	}
+}
+
+func auditActor(c *gin.Context, adminToken string) string {
+	ctx := c.Request.Context()
+	if key := apikey.FromContext(ctx); key != nil {
+		return "api_key:" + key.Name
+	}
+	if app := developer.FromContext(ctx); app != nil {
+		return "app:" + app.ID.String()
+	}
+	if id, err := uuid.Parse(c.GetHeader(UserIDHeader)); err == nil {
+		return "user:" + id.String()
+	}
+	// Токен сверяется здесь же: без проверки заголовок мог бы прислать кто угодно
+	if provided := c.GetHeader(AdminTokenHeader); adminToken != "" && provided != "" &&
+		subtle.ConstantTimeCompare([]byte(provided), []byte(adminToken)) == 1 {
+		return "admin"
+	}
+	return "anonymous"
+}
@@ -0,0 +1,62 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"aggregator_db/internal/apikey"
+	"aggregator_db/internal/audit"
+	"aggregator_db/pkg/domain"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+func TestAudit(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		if c.GetHeader(APIKeyHeader) != "" {
+			c.Request = c.Request.WithContext(apikey.WithKey(c.Request.Context(), &domain.APIKey{Name: "reports"}))
+		}
+	}, Audit("secret"))
+	router.GET("/", func(c *gin.Context) {
+		request := audit.FromContext(c.Request.Context())
+		c.String(http.StatusOK, request.Actor+" "+request.RequestID)
+	})
+
+	user := uuid.New()
+	tests := []struct {
+		name      string
+		headers   map[string]string
+		actor     string
+		requestID string
+	}{
+		{name: "anonymous", actor: "anonymous"},
+		{name: "user", headers: map[string]string{UserIDHeader: user.String()}, actor: "user:" + user.String()},
+		{name: "invalid user", headers: map[string]string{UserIDHeader: "bad"}, actor: "anonymous"},
+		{name: "service key", headers: map[string]string{APIKeyHeader: "sk_service_x", UserIDHeader: user.String()}, actor: "api_key:reports"},
+		{name: "admin", headers: map[string]string{AdminTokenHeader: "secret"}, actor: "admin"},
+		{name: "wrong admin token", headers: map[string]string{AdminTokenHeader: "guess"}, actor: "anonymous"},
+		{name: "client request id", headers: map[string]string{RequestIDHeader: "req-42"}, actor: "anonymous", requestID: "req-42"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			for name, value := range tt.headers {
+				req.Header.Set(name, value)
+			}
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+
+			requestID := rec.Header().Get(RequestIDHeader)
+			if requestID == "" || (tt.requestID != "" && requestID != tt.requestID) {
+				t.Fatalf("request id = %q, want %q", requestID, tt.requestID)
+			}
+			if want := tt.actor + " " + requestID; rec.Body.String() != want {
+				t.Errorf("got %q, want %q", rec.Body.String(), want)
+			}
+		})
+	}
+}
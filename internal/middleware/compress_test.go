@@ -0,0 +1,78 @@
+package middleware
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestCompress(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	large := strings.Repeat("Yandex Plus ", 200)
+	router := gin.New()
+	router.Use(Compress(1024, []string{"application/json"}))
+	router.GET("/large", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"items": large})
+	})
+	router.GET("/small", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"status": "ok"})
+	})
+	router.GET("/csv", func(c *gin.Context) {
+		c.Data(http.StatusOK, "text/csv", []byte(large))
+	})
+
+	tests := []struct {
+		name           string
+		path           string
+		acceptEncoding string
+		wantGzip       bool
+	}{
+		{name: "large json", path: "/large", acceptEncoding: "gzip, deflate, br", wantGzip: true},
+		{name: "client without gzip", path: "/large", acceptEncoding: "br"},
+		{name: "gzip refused", path: "/large", acceptEncoding: "gzip;q=0"},
+		{name: "below min size", path: "/small", acceptEncoding: "gzip"},
+		{name: "other content type", path: "/csv", acceptEncoding: "gzip"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			req.Header.Set("Accept-Encoding", tt.acceptEncoding)
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+
+			if rec.Code != http.StatusOK {
+				t.Fatalf("status = %d", rec.Code)
+			}
+			gotGzip := rec.Header().Get("Content-Encoding") == "gzip"
+			if gotGzip != tt.wantGzip {
+				t.Fatalf("Content-Encoding = %q, want gzip %v", rec.Header().Get("Content-Encoding"), tt.wantGzip)
+			}
+
+			body := io.Reader(rec.Body)
+			if gotGzip {
+				if rec.Body.Len() >= len(large) {
+					t.Errorf("compressed body is %d bytes, not smaller than %d", rec.Body.Len(), len(large))
+				}
+				zr, err := gzip.NewReader(rec.Body)
+				if err != nil {
+					t.Fatal(err)
+				}
+				body = zr
+			}
+			data, err := io.ReadAll(body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.path != "/small" && !strings.Contains(string(data), large) {
+				t.Errorf("body was not preserved: %.80s", data)
+			}
+		})
+	}
+}
@@ -0,0 +1,142 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"aggregator_db/internal/audit"
+	"aggregator_db/pkg/domain"
+	"aggregator_db/pkg/metrics"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	DeprecationHeader = "Deprecation"
+	SunsetHeader      = "Sunset"
+
+	// maxDeprecatedFieldBody - сколько байт тела читается в поисках устаревших полей;
+	// поля в теле большего размера не ищутся
+	maxDeprecatedFieldBody = 1 << 20
+)
+
+var deprecatedRequests = metrics.NewCounterVec(
+	"deprecated_requests_total",
+	"Запросы к устаревшим ручкам, параметрам и полям по поверхности и потребителю: api_key:<имя>, app:<id>, user, admin или anonymous",
+	"surface", "consumer",
+)
+
+// Deprecation учитывает обращения к устаревшим поверхностям в метрике
+// deprecated_requests_total по потребителям: по ней видно, когда удаление никого
+// не затронет. С headers в ответ добавляются заголовки Deprecation (RFC 9745),
+// Sunset (RFC 8594) и Link на замену или журнал изменений changelogPath.
+// При повторе поверхности действует последнее описание. Регистрируется после Audit.
+func Deprecation(deprecations []domain.Deprecation, changelogPath string, headers bool) gin.HandlerFunc {
+	index := make(map[string][]domain.Deprecation)
+	for _, deprecation := range deprecations {
+		route := ginRoute(deprecation.Route)
+		surfaces := index[route]
+		for i := range surfaces {
+			if surfaces[i].Surface() == deprecation.Surface() {
+				surfaces = append(surfaces[:i], surfaces[i+1:]...)
+				break
+			}
+		}
+		index[route] = append(surfaces, deprecation)
+	}
+
+	return func(c *gin.Context) {
+		candidates := index[c.Request.Method+" "+c.FullPath()]
+		if len(candidates) == 0 {
+			c.Next()
+			return
+		}
+
+		query := c.Request.URL.Query()
+		var fields map[string]json.RawMessage
+		var matched []domain.Deprecation
+		for _, deprecation := range candidates {
+			switch {
+			case deprecation.Param != "":
+				if !query.Has(deprecation.Param) {
+					continue
+				}
+			case deprecation.Field != "":
+				if fields == nil {
+					fields = peekJSONFields(c.Request)
+				}
+				if _, ok := fields[deprecation.Field]; !ok {
+					continue
+				}
+			}
+			matched = append(matched, deprecation)
+		}
+		if len(matched) == 0 {
+			c.Next()
+			return
+		}
+
+		consumer := deprecationConsumer(audit.FromContext(c.Request.Context()).Actor)
+		nearest := matched[0]
+		for _, deprecation := range matched {
+			deprecatedRequests.Inc(deprecation.Surface(), consumer)
+			// Из нескольких устаревших поверхностей заголовки говорят о ближайшем удалении
+			if deprecation.Sunset.Before(nearest.Sunset) {
+				nearest = deprecation
+			}
+		}
+		if headers {
+			link := nearest.Link
+			if link == "" {
+				link = changelogPath + "?endpoint=" + url.QueryEscape(nearest.Route)
+			}
+			c.Header(DeprecationHeader, fmt.Sprintf("@%d", nearest.Since.Unix()))
+			c.Header(SunsetHeader, nearest.Sunset.UTC().Format(http.TimeFormat))
+			c.Header("Link", fmt.Sprintf(`<%s>; rel="deprecation"`, link))
+		}
+		c.Next()
+	}
+}
+
+// deprecationConsumer - потребитель для метки метрики: пользователи схлопываются
+// в "user", чтобы число рядов не росло с числом пользователей.
+func deprecationConsumer(actor string) string {
+	if strings.HasPrefix(actor, "user:") {
+		return "user"
+	}
+	return actor
+}
+
+// peekJSONFields читает поля верхнего уровня JSON-тела и возвращает тело запросу
+// нетронутым. Не JSON-объект или слишком большое тело дает пустой набор полей.
+func peekJSONFields(r *http.Request) map[string]json.RawMessage {
+	fields := make(map[string]json.RawMessage)
+	if r.Body == nil {
+		return fields
+	}
+	peeked, err := io.ReadAll(io.LimitReader(r.Body, maxDeprecatedFieldBody+1))
+	r.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(peeked), r.Body), r.Body}
+	if err != nil || len(peeked) > maxDeprecatedFieldBody {
+		return fields
+	}
+	_ = json.Unmarshal(peeked, &fields)
+	return fields
+}
+
+// ginRoute переводит "GET /api/v1/subscriptions/{id}" в маршрут gin "GET /api/v1/subscriptions/:id".
+func ginRoute(route string) string {
+	parts := strings.Split(route, "/")
+	for i, part := range parts {
+		if strings.HasPrefix(part, "{") && strings.HasSuffix(part, "}") {
+			parts[i] = ":" + strings.Trim(part, "{}")
+		}
+	}
+	return strings.Join(parts, "/")
+}
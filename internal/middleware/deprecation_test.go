@@ -0,0 +1,132 @@
+package middleware
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"aggregator_db/internal/audit"
+	"aggregator_db/pkg/domain"
+	"github.com/gin-gonic/gin"
+)
+
+func TestDeprecation(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	date := func(value string) time.Time {
+		parsed, _ := time.Parse(time.DateOnly, value)
+		return parsed
+	}
+	deprecations := []domain.Deprecation{
+		{Route: "GET /api/v1/subscriptions", Param: "userId", Since: date("2025-09-01"), Sunset: date("2027-03-01")},
+		{Route: "GET /api/v1/subscriptions/{id}/old", Since: date("2025-09-01"), Sunset: date("2026-01-01")},
+		// Настройка переопределяет дату из журнала
+		{Route: "GET /api/v1/subscriptions/{id}/old", Since: date("2025-09-01"), Sunset: date("2026-06-01")},
+		{
+			Route: "PUT /api/v1/subscriptions/{id}", Field: "price", Since: date("2026-01-12"), Sunset: date("2026-12-01"),
+			Link: "https://docs.example.com/price",
+		},
+	}
+
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		actor := c.GetHeader("X-Actor")
+		c.Request = c.Request.WithContext(audit.WithRequest(c.Request.Context(), audit.Request{Actor: actor}))
+	})
+	router.Use(Deprecation(deprecations, "/api/v1/changelog", true))
+	router.GET("/api/v1/subscriptions", func(c *gin.Context) { c.Status(http.StatusOK) })
+	router.GET("/api/v1/subscriptions/:id/old", func(c *gin.Context) { c.Status(http.StatusOK) })
+	router.PUT("/api/v1/subscriptions/:id", func(c *gin.Context) {
+		// Тело доходит до обработчика целиком
+		body, _ := io.ReadAll(c.Request.Body)
+		c.String(http.StatusOK, string(body))
+	})
+
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		body       string
+		actor      string
+		wantSunset string
+		wantLink   string
+		surface    string
+		consumer   string
+	}{
+		{name: "current param", method: http.MethodGet, path: "/api/v1/subscriptions?user_id=1"},
+		{
+			name: "deprecated param", method: http.MethodGet, path: "/api/v1/subscriptions?userId=1", actor: "api_key:reports",
+			wantSunset: "Mon, 01 Mar 2027 00:00:00 GMT",
+			wantLink:   `</api/v1/changelog?endpoint=GET+%2Fapi%2Fv1%2Fsubscriptions>; rel="deprecation"`,
+			surface:    "GET /api/v1/subscriptions?userId", consumer: "api_key:reports",
+		},
+		{
+			name: "deprecated endpoint", method: http.MethodGet, path: "/api/v1/subscriptions/42/old", actor: "user:9f1c4f0e-3b7a-4d2e-8a61-5c0d2b7e9a13",
+			wantSunset: "Mon, 01 Jun 2026 00:00:00 GMT",
+			wantLink:   `</api/v1/changelog?endpoint=GET+%2Fapi%2Fv1%2Fsubscriptions%2F%7Bid%7D%2Fold>; rel="deprecation"`,
+			surface:    "GET /api/v1/subscriptions/{id}/old", consumer: "user",
+		},
+		{
+			name: "deprecated field", method: http.MethodPut, path: "/api/v1/subscriptions/42", body: `{"price": 100, "service_name": "Netflix"}`,
+			actor: "anonymous", wantSunset: "Tue, 01 Dec 2026 00:00:00 GMT", wantLink: `<https://docs.example.com/price>; rel="deprecation"`,
+			surface: "PUT /api/v1/subscriptions/{id}#price", consumer: "anonymous",
+		},
+		{name: "current field", method: http.MethodPut, path: "/api/v1/subscriptions/42", body: `{"service_name": "Netflix"}`},
+		{name: "not an object", method: http.MethodPut, path: "/api/v1/subscriptions/42", body: `[{"price": 100}]`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var before float64
+			if tt.surface != "" {
+				before = deprecatedRequests.Value(tt.surface, tt.consumer)
+			}
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			req.Header.Set("X-Actor", tt.actor)
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+
+			if got := rec.Header().Get(SunsetHeader); got != tt.wantSunset {
+				t.Errorf("Sunset = %q, want %q", got, tt.wantSunset)
+			}
+			if got := rec.Header().Get("Link"); got != tt.wantLink {
+				t.Errorf("Link = %q, want %q", got, tt.wantLink)
+			}
+			if tt.body != "" && rec.Body.String() != tt.body {
+				t.Errorf("handler body = %q, want %q", rec.Body.String(), tt.body)
+			}
+			if tt.surface == "" {
+				return
+			}
+			if got := rec.Header().Get(DeprecationHeader); !strings.HasPrefix(got, "@") {
+				t.Errorf("Deprecation = %q, want @<unix time>", got)
+			}
+			if got := deprecatedRequests.Value(tt.surface, tt.consumer) - before; got != 1 {
+				t.Errorf("deprecated_requests_total{%s, %s} grew by %v, want 1", tt.surface, tt.consumer, got)
+			}
+		})
+	}
+}
+
+func TestDeprecationWithoutHeaders(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	since, _ := time.Parse(time.DateOnly, "2025-09-01")
+	router := gin.New()
+	router.Use(Deprecation([]domain.Deprecation{
+		{Route: "GET /api/v1/subscriptions", Param: "userId", Since: since, Sunset: since.AddDate(1, 0, 0)},
+	}, "/api/v1/changelog", false))
+	router.GET("/api/v1/subscriptions", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	before := deprecatedRequests.Value("GET /api/v1/subscriptions?userId", audit.SystemActor)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/subscriptions?userId=1", nil))
+	if rec.Header().Get(DeprecationHeader) != "" || rec.Header().Get(SunsetHeader) != "" {
+		t.Errorf("headers = %v, want none", rec.Header())
+	}
+	if got := deprecatedRequests.Value("GET /api/v1/subscriptions?userId", audit.SystemActor) - before; got != 1 {
+		t.Errorf("deprecated requests grew by %v, want 1", got)
+	}
+}
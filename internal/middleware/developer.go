@@ -0,0 +1,74 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"aggregator_db/internal/developer"
+	"aggregator_db/internal/ratelimit"
+	"aggregator_db/internal/repository/postgres"
+	"aggregator_db/internal/tenancy"
+	"aggregator_db/pkg/domain"
+	"aggregator_db/pkg/tracing"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	APIKeyHeader = "X-API-Key"
+	// SandboxHeader помечает ответы, выполненные в песочнице
+	SandboxHeader = "X-Sandbox"
+)
+
+// DeveloperAppResolver ищет приложение по ключу из заголовка.
+type DeveloperAppResolver func(ctx context.Context, apiKey string) (*domain.DeveloperApp, error)
+
+// DeveloperApp кладет в контекст приложение по ключу X-API-Key и применяет
+// его лимит запросов. Запросы без ключа и с ключами внутренних сервисов здесь не
+// ограничиваются: лимит ключей сервисов применяет RateLimit.
+// Запросы с ключом песочницы работают с тенантом песочницы вместо X-Tenant-ID.
+func DeveloperApp(resolve DeveloperAppResolver, limiter *ratelimit.Limiter) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		apiKey := c.GetHeader(APIKeyHeader)
+		if apiKey == "" || domain.IsServiceAPIKey(apiKey) {
+			c.Next()
+			return
+		}
+
+		app, err := resolve(c.Request.Context(), apiKey)
+		if errors.Is(err, postgres.ErrDeveloperAppNotFound) {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, domain.ErrorResponse{Code: domain.CodeInvalidAPIKey, Error: "invalid api key"})
+			return
+		}
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, domain.ErrorResponse{Code: domain.CodeInternal, Error: err.Error()})
+			return
+		}
+
+		labels := tracing.LabelsFromContext(c.Request.Context())
+		labels.Set(tracing.LabelAPIKey, keyLabel(app.ID))
+		if !allowRequest(c, limiter, ratelimit.AppKey(app), app.RateLimitPerMinute) {
+			return
+		}
+
+		ctx := developer.WithApp(c.Request.Context(), app)
+		if app.Sandbox {
+			ctx = tenancy.WithTenant(ctx, domain.SandboxTenant())
+			labels.Set(tracing.LabelTenant, domain.SandboxTenant().ID)
+			c.Header(SandboxHeader, "true")
+		}
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+	}
+}
+
+// RequireDeveloperApp пропускает только запросы с ключом приложения.
+func RequireDeveloperApp() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if developer.FromContext(c.Request.Context()) == nil {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, domain.ErrorResponse{Code: domain.CodeUnauthorized, Error: "missing " + APIKeyHeader + " header"})
+			return
+		}
+		c.Next()
+	}
+}
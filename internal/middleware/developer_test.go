@@ -0,0 +1,107 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"aggregator_db/internal/ratelimit"
+	"aggregator_db/internal/repository/postgres"
+	"aggregator_db/internal/tenancy"
+	"aggregator_db/pkg/domain"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+func TestDeveloperAppRateLimit(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	const key = "sk_sandbox_test"
+	app := &domain.DeveloperApp{ID: uuid.New(), RateLimitPerMinute: 2}
+	resolve := func(_ context.Context, apiKey string) (*domain.DeveloperApp, error) {
+		if apiKey != key {
+			return nil, postgres.ErrDeveloperAppNotFound
+		}
+		return app, nil
+	}
+
+	router := gin.New()
+	router.Use(DeveloperApp(resolve, ratelimit.NewLimiter(time.Hour)))
+	router.GET("/ok", func(c *gin.Context) { c.Status(http.StatusOK) })
+	router.GET("/app", RequireDeveloperApp(), func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	tests := []struct {
+		name      string
+		path      string
+		key       string
+		want      int
+		remaining string
+	}{
+		{name: "no key is not limited", path: "/ok", want: http.StatusOK},
+		{name: "no key on app route", path: "/app", want: http.StatusUnauthorized},
+		{name: "unknown key", path: "/ok", key: "sk_sandbox_other", want: http.StatusUnauthorized},
+		{name: "first request", path: "/app", key: key, want: http.StatusOK, remaining: "1"},
+		{name: "last request in window", path: "/ok", key: key, want: http.StatusOK, remaining: "0"},
+		{name: "over limit", path: "/ok", key: key, want: http.StatusTooManyRequests, remaining: "0"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			if tt.key != "" {
+				req.Header.Set(APIKeyHeader, tt.key)
+			}
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+
+			if rec.Code != tt.want {
+				t.Errorf("status = %d, want %d", rec.Code, tt.want)
+			}
+			if got := rec.Header().Get("X-RateLimit-Remaining"); got != tt.remaining {
+				t.Errorf("X-RateLimit-Remaining = %q, want %q", got, tt.remaining)
+			}
+			// Лимит окна в час: клиенту подсказывается дождаться нового окна
+			if retry := rec.Header().Get(RetryAfterHeader); (retry != "") != (tt.want == http.StatusTooManyRequests) {
+				t.Errorf("Retry-After = %q for status %d", retry, rec.Code)
+			}
+		})
+	}
+}
+
+func TestDeveloperAppSandbox(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	apps := map[string]*domain.DeveloperApp{
+		"sk_sandbox_test": {ID: uuid.New(), RateLimitPerMinute: 10, Sandbox: true},
+		"sk_live_test":    {ID: uuid.New(), RateLimitPerMinute: 10},
+	}
+	resolve := func(_ context.Context, apiKey string) (*domain.DeveloperApp, error) {
+		return apps[apiKey], nil
+	}
+
+	router := gin.New()
+	router.Use(DeveloperApp(resolve, ratelimit.NewLimiter(time.Minute)))
+	router.GET("/tenant", func(c *gin.Context) {
+		tenant := "public"
+		if t := tenancy.FromContext(c.Request.Context()); t != nil {
+			tenant = t.SchemaName
+		}
+		c.String(http.StatusOK, tenant)
+	})
+
+	for key, want := range map[string]string{"sk_sandbox_test": "sandbox", "sk_live_test": "public"} {
+		req := httptest.NewRequest(http.MethodGet, "/tenant", nil)
+		req.Header.Set(APIKeyHeader, key)
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+
+		if rec.Body.String() != want {
+			t.Errorf("%s: routed to %q, want %q", key, rec.Body.String(), want)
+		}
+		if got := rec.Header().Get(SandboxHeader) == "true"; got != apps[key].Sandbox {
+			t.Errorf("%s: %s header present = %v", key, SandboxHeader, got)
+		}
+	}
+}
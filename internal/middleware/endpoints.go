@@ -0,0 +1,111 @@
+package middleware
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+
+	"aggregator_db/pkg/domain"
+	"github.com/gin-gonic/gin"
+)
+
+// EndpointSwitch выключает ручки из конфигурации (DISABLED_ENDPOINTS). Правило -
+// необязательный метод и путь в виде "DELETE /api/v1/subscriptions/{id}"; путь
+// с "/*" в конце выключает все пути под префиксом. Маршруты остаются в роутере,
+// а Middleware отвечает на них до аутентификации: 405 с Allow, если у пути есть
+// включенные методы, иначе 404.
+type EndpointSwitch struct {
+	rules []endpointRule
+	// disabled - выключенные маршруты "METHOD /gin/path"
+	disabled map[string]bool
+	// allowed - включенные методы путей, у которых часть методов выключена
+	allowed map[string][]string
+}
+
+type endpointRule struct {
+	source string
+	method string
+	path   string
+	prefix bool
+}
+
+// NewEndpointSwitch разбирает правила; маршруты они затрагивают после Apply.
+func NewEndpointSwitch(rules []string) *EndpointSwitch {
+	s := &EndpointSwitch{disabled: make(map[string]bool), allowed: make(map[string][]string)}
+	for _, source := range rules {
+		rule := endpointRule{source: source, path: source}
+		if method, path, ok := strings.Cut(source, " "); ok {
+			rule.method, rule.path = method, path
+		}
+		rule.path = ginRoute(rule.path)
+		if path, ok := strings.CutSuffix(rule.path, "/*"); ok {
+			rule.path, rule.prefix = path+"/", true
+		}
+		s.rules = append(s.rules, rule)
+	}
+	return s
+}
+
+// Apply применяет правила к маршрутам роутера и возвращает выключенные маршруты
+// и правила, которые ни к чему не подошли (скорее всего, опечатки). Вызывается
+// после регистрации всех маршрутов и до приема запросов.
+func (s *EndpointSwitch) Apply(routes gin.RoutesInfo) (disabled, unmatched []string) {
+	matched := make([]bool, len(s.rules))
+	methods := make(map[string][]string)
+	for _, route := range routes {
+		methods[route.Path] = append(methods[route.Path], route.Method)
+		for i, rule := range s.rules {
+			if rule.matches(route.Method, route.Path) {
+				matched[i] = true
+				key := route.Method + " " + route.Path
+				if !s.disabled[key] {
+					s.disabled[key] = true
+					disabled = append(disabled, key)
+				}
+			}
+		}
+	}
+	for path, all := range methods {
+		enabled := slices.DeleteFunc(slices.Clone(all), func(method string) bool {
+			return s.disabled[method+" "+path]
+		})
+		if len(enabled) > 0 && len(enabled) < len(all) {
+			slices.Sort(enabled)
+			s.allowed[path] = enabled
+		}
+	}
+	for i, rule := range s.rules {
+		if !matched[i] {
+			unmatched = append(unmatched, rule.source)
+		}
+	}
+	return disabled, unmatched
+}
+
+func (r endpointRule) matches(method, path string) bool {
+	if r.method != "" && r.method != method {
+		return false
+	}
+	if r.prefix {
+		return strings.HasPrefix(path, r.path)
+	}
+	return path == r.path
+}
+
+// Middleware отвечает на выключенные ручки. Регистрируется раньше аутентификации
+// и остальных проверок запроса.
+func (s *EndpointSwitch) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		path := c.FullPath()
+		if !s.disabled[c.Request.Method+" "+path] {
+			c.Next()
+			return
+		}
+		if allowed := s.allowed[path]; len(allowed) > 0 {
+			c.Header("Allow", strings.Join(allowed, ", "))
+			c.AbortWithStatusJSON(http.StatusMethodNotAllowed, domain.ErrorResponse{Code: domain.CodeEndpointDisabled, Error: "method is disabled for this endpoint"})
+			return
+		}
+		c.AbortWithStatusJSON(http.StatusNotFound, domain.ErrorResponse{Code: domain.CodeEndpointDisabled, Error: "endpoint is disabled"})
+	}
+}
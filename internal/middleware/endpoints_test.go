@@ -0,0 +1,67 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestEndpointSwitch(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	endpoints := NewEndpointSwitch([]string{
+		"DELETE /items/{id}",
+		"PUT /items/{id}",
+		"/docs/*",
+		"/reports",
+		"POST /missing",
+	})
+	router := gin.New()
+	router.Use(endpoints.Middleware())
+	ok := func(c *gin.Context) { c.Status(http.StatusOK) }
+	router.GET("/items/:id", ok)
+	router.PUT("/items/:id", ok)
+	router.DELETE("/items/:id", ok)
+	router.GET("/docs/*any", ok)
+	router.GET("/reports", ok)
+	router.POST("/reports", ok)
+	router.GET("/reports/daily", ok)
+
+	disabled, unmatched := endpoints.Apply(router.Routes())
+	slices.Sort(disabled)
+	wantDisabled := []string{"DELETE /items/:id", "GET /docs/*any", "GET /reports", "POST /reports", "PUT /items/:id"}
+	if !slices.Equal(disabled, wantDisabled) {
+		t.Errorf("disabled = %v, want %v", disabled, wantDisabled)
+	}
+	if !slices.Equal(unmatched, []string{"POST /missing"}) {
+		t.Errorf("unmatched = %v", unmatched)
+	}
+
+	tests := []struct {
+		method string
+		path   string
+		want   int
+		allow  string
+	}{
+		{method: http.MethodGet, path: "/items/1", want: http.StatusOK},
+		{method: http.MethodDelete, path: "/items/1", want: http.StatusMethodNotAllowed, allow: "GET"},
+		{method: http.MethodGet, path: "/docs/index.html", want: http.StatusNotFound},
+		{method: http.MethodPost, path: "/reports", want: http.StatusNotFound},
+		{method: http.MethodGet, path: "/reports/daily", want: http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.path, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, httptest.NewRequest(tt.method, tt.path, nil))
+			if rec.Code != tt.want {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.want)
+			}
+			if got := rec.Header().Get("Allow"); got != tt.allow {
+				t.Errorf("Allow = %q, want %q", got, tt.allow)
+			}
+		})
+	}
+}
@@ -0,0 +1,68 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+
+	"aggregator_db/pkg/errortracker"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// RequestIDHeader - ID запроса: берется от клиента или шлюза, иначе создается.
+// Возвращается в ответе, чтобы по нему найти отчет в трекере ошибок.
+const RequestIDHeader = "X-Request-ID"
+
+// ErrorTracking открывает область запроса для отчетов об ошибках: ID запроса,
+// маршрут и пользователь из X-User-ID. Ответ 500, о котором за время запроса
+// не было отчета (например, ошибка без записи в лог), отправляется в трекер здесь.
+func ErrorTracking(tracker *errortracker.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		requestID := c.GetHeader(RequestIDHeader)
+		if !validRequestID(requestID) {
+			requestID = uuid.NewString()
+		}
+		c.Header(RequestIDHeader, requestID)
+
+		scope := errortracker.NewScope(requestID, c.Request.Method, c.FullPath(), c.Request.URL.Path)
+		if userID := c.GetHeader(UserIDHeader); userID != "" {
+			scope.SetUser(userID)
+		}
+		c.Request = c.Request.WithContext(errortracker.WithScope(c.Request.Context(), scope))
+
+		c.Next()
+
+		if c.Writer.Status() != http.StatusInternalServerError || scope.Reported() {
+			return
+		}
+		if last := c.Errors.Last(); last != nil {
+			tracker.CaptureError(c.Request.Context(), "http", last.Err, nil)
+		} else {
+			tracker.CaptureMessage(c.Request.Context(), "http",
+				fmt.Sprintf("%d %s %s", http.StatusInternalServerError, c.Request.Method, c.FullPath()), nil)
+		}
+	}
+}
+
+// ErrorTrackingRecovery заменяет gin.Recovery: паника отправляется в трекер со стеком,
+// клиент получает 500.
+func ErrorTrackingRecovery(tracker *errortracker.Client) gin.HandlerFunc {
+	return gin.CustomRecovery(func(c *gin.Context, recovered any) {
+		tracker.CapturePanic(c.Request.Context(), recovered)
+		c.AbortWithStatus(http.StatusInternalServerError)
+	})
+}
+
+// validRequestID пропускает только короткие ID из печатных символов без пробелов,
+// чтобы чужой заголовок не попал в ответ и отчеты как есть.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > 128 {
+		return false
+	}
+	for _, r := range id {
+		if r <= ' ' || r > '~' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,106 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"aggregator_db/pkg/errortracker"
+	"aggregator_db/pkg/httpclient"
+	"github.com/gin-gonic/gin"
+)
+
+func TestErrorTracking(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	var mu sync.Mutex
+	var reports []errortracker.Event
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var event errortracker.Event
+		_ = json.NewDecoder(r.Body).Decode(&event)
+		mu.Lock()
+		reports = append(reports, event)
+		mu.Unlock()
+	}))
+	defer server.Close()
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	dsn := strings.Replace(server.URL, "http://", "http://key@", 1) + "/1"
+	tracker, err := errortracker.New(errortracker.Config{DSN: dsn}, httpclient.New(httpclient.DefaultConfig("error_tracker"), logger), logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	appLogger := slog.New(errortracker.NewLogHandler(slog.NewTextHandler(io.Discard, nil), tracker))
+
+	router := gin.New()
+	router.Use(ErrorTrackingRecovery(tracker), ErrorTracking(tracker))
+	router.GET("/ok", func(c *gin.Context) { c.Status(http.StatusOK) })
+	router.GET("/panic", func(c *gin.Context) { panic("nil map") })
+	router.GET("/failed", func(c *gin.Context) {
+		_ = c.Error(errors.New("scan failed"))
+		c.Status(http.StatusInternalServerError)
+	})
+	router.GET("/logged", func(c *gin.Context) {
+		// Сервис уже записал ошибку в лог: второго отчета об ответе 500 быть не должно
+		appLogger.ErrorContext(c.Request.Context(), "failed to calculate total")
+		c.Status(http.StatusInternalServerError)
+	})
+
+	tests := []struct {
+		path      string
+		requestID string
+		status    int
+	}{
+		{path: "/ok", requestID: "gateway-1", status: http.StatusOK},
+		{path: "/panic", requestID: "bad id", status: http.StatusInternalServerError},
+		{path: "/failed", status: http.StatusInternalServerError},
+		{path: "/logged", status: http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+		req.Header.Set(RequestIDHeader, tt.requestID)
+		req.Header.Set(UserIDHeader, "user-1")
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		if rec.Code != tt.status {
+			t.Errorf("%s: status %d, want %d", tt.path, rec.Code, tt.status)
+		}
+		id := rec.Header().Get(RequestIDHeader)
+		if tt.requestID == "gateway-1" && id != tt.requestID || tt.requestID != "gateway-1" && len(id) != 36 {
+			t.Errorf("%s: %s = %q", tt.path, RequestIDHeader, id)
+		}
+	}
+	tracker.Close(context.Background())
+
+	if len(reports) != 3 {
+		t.Fatalf("reports = %+v", reports)
+	}
+	byRoute := make(map[string]errortracker.Event)
+	for _, report := range reports {
+		byRoute[report.Tags["route"]] = report
+		if report.User == nil || report.User.ID != "user-1" || len(report.Tags["request_id"]) != 36 {
+			t.Errorf("report without request data: %+v", report)
+		}
+	}
+	panicked := byRoute["GET /panic"]
+	if panicked.Level != "fatal" || panicked.Exception == nil || panicked.Exception.Values[0].Value != "nil map" {
+		t.Fatalf("panic report = %+v", panicked)
+	}
+	frames := panicked.Exception.Values[0].Stacktrace.Frames
+	if last := frames[len(frames)-1]; !strings.Contains(last.Function, "TestErrorTracking") {
+		t.Errorf("innermost frame = %+v", last)
+	}
+	if failed := byRoute["GET /failed"]; failed.Exception == nil || failed.Exception.Values[0].Value != "scan failed" {
+		t.Errorf("failed report = %+v", failed)
+	}
+	if logged := byRoute["GET /logged"]; logged.Message != "failed to calculate total" {
+		t.Errorf("logged report = %+v", logged)
+	}
+}
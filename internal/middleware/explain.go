@@ -0,0 +1,68 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"strconv"
+	"time"
+
+	"aggregator_db/internal/diagnostics"
+	"aggregator_db/internal/repository/postgres"
+	"aggregator_db/pkg/domain"
+	"aggregator_db/pkg/tracing"
+	"github.com/gin-gonic/gin"
+)
+
+// ExplainHeader помечает запрос для снятия планов в режиме ExplainOnHeader.
+const ExplainHeader = "X-Debug-Explain"
+
+// Режимы снятия планов запросов к базе (DB_EXPLAIN).
+const (
+	ExplainOff = "off"
+	// ExplainOnHeader - только запросы с X-Debug-Explain: true и корректным X-Admin-Token
+	ExplainOnHeader = "header"
+	// ExplainAll - все запросы; только для staging
+	ExplainAll = "all"
+)
+
+// ExplainQueries снимает EXPLAIN (ANALYZE) запросов к базе помеченных запросов и
+// сохраняет планы в recorder. Должен стоять после Tracing, чтобы записи были
+// привязаны к трейсу.
+func ExplainQueries(mode, adminToken string, recorder *diagnostics.Recorder) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !explainRequested(c, mode, adminToken) {
+			c.Next()
+			return
+		}
+
+		ctx, log := postgres.WithExplain(c.Request.Context())
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+
+		plans := log.Plans()
+		if len(plans) == 0 {
+			return
+		}
+		recorder.Add(domain.RequestDiagnostics{
+			TraceID: tracing.SpanContextFromContext(ctx).TraceID.String(),
+			Method:  c.Request.Method,
+			Path:    c.Request.URL.Path,
+			Status:  c.Writer.Status(),
+			At:      time.Now().UTC(),
+			Queries: plans,
+		})
+	}
+}
+
+func explainRequested(c *gin.Context, mode, adminToken string) bool {
+	switch mode {
+	case ExplainAll:
+		return true
+	case ExplainOnHeader:
+		flagged, _ := strconv.ParseBool(c.GetHeader(ExplainHeader))
+		provided := c.GetHeader(AdminTokenHeader)
+		// EXPLAIN ANALYZE выполняет запрос повторно, поэтому доступен только администратору
+		return flagged && adminToken != "" && subtle.ConstantTimeCompare([]byte(provided), []byte(adminToken)) == 1
+	default:
+		return false
+	}
+}
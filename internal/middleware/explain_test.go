@@ -0,0 +1,81 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"aggregator_db/internal/diagnostics"
+	"aggregator_db/internal/repository/postgres"
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// stubDB выполняет запросы без базы; транзакции для EXPLAIN недоступны.
+type stubDB struct{}
+
+func (stubDB) Exec(context.Context, string, ...any) (pgconn.CommandTag, error) {
+	return pgconn.CommandTag{}, nil
+}
+func (stubDB) Query(context.Context, string, ...any) (pgx.Rows, error) { return nil, nil }
+func (stubDB) QueryRow(context.Context, string, ...any) pgx.Row        { return nil }
+func (stubDB) Begin(context.Context) (pgx.Tx, error)                   { return nil, errors.New("no database") }
+
+func TestExplainQueries(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db := postgres.NewExplainDB(stubDB{}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	tests := []struct {
+		name    string
+		mode    string
+		headers map[string]string
+		want    int
+	}{
+		{name: "off", mode: ExplainOff, headers: map[string]string{ExplainHeader: "true", AdminTokenHeader: "secret"}, want: 0},
+		{name: "header without flag", mode: ExplainOnHeader, headers: map[string]string{AdminTokenHeader: "secret"}, want: 0},
+		{name: "header without admin token", mode: ExplainOnHeader, headers: map[string]string{ExplainHeader: "true"}, want: 0},
+		{name: "header with wrong token", mode: ExplainOnHeader, headers: map[string]string{ExplainHeader: "true", AdminTokenHeader: "wrong"}, want: 0},
+		{name: "header flagged", mode: ExplainOnHeader, headers: map[string]string{ExplainHeader: "true", AdminTokenHeader: "secret"}, want: 1},
+		{name: "all", mode: ExplainAll, want: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := diagnostics.NewRecorder(10)
+			router := gin.New()
+			router.Use(Tracing(), ExplainQueries(tt.mode, "secret", recorder))
+			router.GET("/subscriptions", func(c *gin.Context) {
+				// Служебные команды не снимаются
+				_, _ = db.Exec(c.Request.Context(), "SET LOCAL statement_timeout = 1000")
+				_, _ = db.Exec(c.Request.Context(), "SELECT 1")
+				c.Status(http.StatusOK)
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "/subscriptions", nil)
+			for key, value := range tt.headers {
+				req.Header.Set(key, value)
+			}
+			router.ServeHTTP(httptest.NewRecorder(), req)
+
+			entries := recorder.List("")
+			if len(entries) != tt.want {
+				t.Fatalf("got %d entries, want %d", len(entries), tt.want)
+			}
+			if tt.want == 0 {
+				return
+			}
+			entry := entries[0]
+			if len(entry.Queries) != 1 || entry.Queries[0].SQL != "SELECT 1" || entry.Queries[0].Error == "" || entry.TraceID == "" {
+				t.Errorf("entry = %+v", entry)
+			}
+			if got := recorder.List(entry.TraceID); len(got) != 1 {
+				t.Errorf("got %d entries for trace, want 1", len(got))
+			}
+		})
+	}
+}
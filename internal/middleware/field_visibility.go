@@ -0,0 +1,262 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"mime"
+	"net/http"
+	"slices"
+
+	"aggregator_db/internal/access"
+	"aggregator_db/internal/tenancy"
+	"aggregator_db/pkg/domain"
+	"github.com/gin-gonic/gin"
+)
+
+// FieldVisibility убирает из ответов поля, скрытые от роли принципала: правила
+// тенанта запроса, а без них defaults. Скрывается сериализованный ответ, поэтому
+// хендлеры о правилах не знают. JSON разбирается целиком, NDJSON - по строкам,
+// не прерывая поток; ответ другого типа не отдается вовсе (403), чтобы
+// скрытое поле не ушло в формате, который фильтр не понимает.
+func FieldVisibility(defaults domain.FieldVisibility) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		principal := access.FromContext(c.Request.Context())
+		if principal == nil {
+			c.Next()
+			return
+		}
+		visibility := defaults
+		if tenant := tenancy.FromContext(c.Request.Context()); tenant != nil && tenant.FieldVisibility != nil {
+			visibility = tenant.FieldVisibility
+		}
+		hidden := visibility.Hidden(principal.Role)
+		if len(hidden) == 0 {
+			c.Next()
+			return
+		}
+
+		w := &redactWriter{ResponseWriter: c.Writer, hidden: hidden}
+		c.Writer = w
+		defer w.finish()
+		c.Next()
+	}
+}
+
+type redactMode int
+
+const (
+	redactPending redactMode = iota
+	redactDocument
+	redactStream
+	redactDeny
+)
+
+// redactWriter копит тело ответа и отдает его без скрытых полей: JSON - после
+// завершения хендлера, NDJSON - по мере получения строк.
+type redactWriter struct {
+	gin.ResponseWriter
+	hidden []string
+
+	mode redactMode
+	buf  bytes.Buffer
+}
+
+func (w *redactWriter) Write(data []byte) (int, error) {
+	if w.mode == redactPending {
+		w.mode = w.decide()
+	}
+	switch w.mode {
+	case redactDocument:
+		w.buf.Write(data)
+	case redactStream:
+		w.buf.Write(data)
+		if err := w.writeLines(false); err != nil {
+			return 0, err
+		}
+	}
+	// Тело неподдерживаемого типа отбрасывается, ответ заменит finish
+	return len(data), nil
+}
+
+func (w *redactWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+// Flush пропускает вперед готовые строки NDJSON; JSON отдается только целиком.
+func (w *redactWriter) Flush() {
+	if w.mode == redactStream {
+		w.ResponseWriter.Flush()
+	}
+}
+
+func (w *redactWriter) decide() redactMode {
+	mediaType, _, _ := mime.ParseMediaType(w.Header().Get("Content-Type"))
+	switch mediaType {
+	case "application/json":
+		w.Header().Del("Content-Length")
+		return redactDocument
+	case domain.BreakdownStreamContentType:
+		w.Header().Del("Content-Length")
+		return redactStream
+	default:
+		return redactDeny
+	}
+}
+
+// writeLines отдает накопленные полные строки; final - и последнюю неполную.
+func (w *redactWriter) writeLines(final bool) error {
+	for {
+		line, rest, ok := bytes.Cut(w.buf.Bytes(), []byte("\n"))
+		if !ok && !final {
+			return nil
+		}
+		if len(line) > 0 {
+			redacted, err := redactFields(line, w.hidden)
+			if err != nil {
+				return err
+			}
+			if ok {
+				redacted = append(redacted, '\n')
+			}
+			if _, err := w.ResponseWriter.Write(redacted); err != nil {
+				return err
+			}
+		}
+		remaining := bytes.Clone(rest)
+		w.buf.Reset()
+		w.buf.Write(remaining)
+		if !ok || w.buf.Len() == 0 {
+			return nil
+		}
+	}
+}
+
+func (w *redactWriter) finish() {
+	switch w.mode {
+	case redactDocument:
+		redacted, err := redactFields(w.buf.Bytes(), w.hidden)
+		if err != nil {
+			w.deny()
+			return
+		}
+		_, _ = w.ResponseWriter.Write(redacted)
+	case redactStream:
+		_ = w.writeLines(true)
+	case redactDeny:
+		w.deny()
+	}
+}
+
+// deny заменяет ответ, который нельзя отфильтровать, на 403.
+func (w *redactWriter) deny() {
+	header := w.Header()
+	header.Del("Content-Disposition")
+	header.Del("Content-Length")
+	header.Set("Content-Type", "application/json; charset=utf-8")
+	w.ResponseWriter.WriteHeader(http.StatusForbidden)
+	body, _ := json.Marshal(errAccessDenied)
+	_, _ = w.ResponseWriter.Write(body)
+}
+
+// redactFields убирает из JSON-значения ключи hidden на любой глубине, сохраняя
+// порядок остальных ключей. domain.FieldMoney убирает суммы: объекты
+// {"amount", "currency"} и целые цены price, оставленные для совместимости.
+func redactFields(data []byte, hidden []string) ([]byte, error) {
+	var out bytes.Buffer
+	keep, err := redactValue(&out, bytes.TrimSpace(data), hidden)
+	if err != nil {
+		return nil, err
+	}
+	if !keep {
+		return []byte("null"), nil
+	}
+	return out.Bytes(), nil
+}
+
+// redactValue пишет value без скрытых полей; false - значение скрыто целиком.
+func redactValue(out *bytes.Buffer, value json.RawMessage, hidden []string) (bool, error) {
+	if len(value) == 0 || (value[0] != '{' && value[0] != '[') {
+		out.Write(value)
+		return true, nil
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(value))
+	if _, err := dec.Token(); err != nil {
+		return false, err
+	}
+	hideMoney := slices.Contains(hidden, domain.FieldMoney)
+
+	if value[0] == '[' {
+		out.WriteByte('[')
+		first := true
+		for dec.More() {
+			var item json.RawMessage
+			if err := dec.Decode(&item); err != nil {
+				return false, err
+			}
+			var buf bytes.Buffer
+			keep, err := redactValue(&buf, item, hidden)
+			if err != nil {
+				return false, err
+			}
+			if !keep {
+				continue
+			}
+			if !first {
+				out.WriteByte(',')
+			}
+			out.Write(buf.Bytes())
+			first = false
+		}
+		out.WriteByte(']')
+		return true, nil
+	}
+
+	var keys []string
+	var values []json.RawMessage
+	for dec.More() {
+		token, err := dec.Token()
+		if err != nil {
+			return false, err
+		}
+		var item json.RawMessage
+		if err := dec.Decode(&item); err != nil {
+			return false, err
+		}
+		keys = append(keys, token.(string))
+		values = append(values, item)
+	}
+	if hideMoney && len(keys) == 2 && slices.Contains(keys, "amount") && slices.Contains(keys, "currency") {
+		return false, nil
+	}
+
+	out.WriteByte('{')
+	first := true
+	for i, key := range keys {
+		if slices.Contains(hidden, key) || (hideMoney && key == "price" && isNumber(values[i])) {
+			continue
+		}
+		var buf bytes.Buffer
+		keep, err := redactValue(&buf, values[i], hidden)
+		if err != nil {
+			return false, err
+		}
+		if !keep {
+			continue
+		}
+		if !first {
+			out.WriteByte(',')
+		}
+		name, _ := json.Marshal(key)
+		out.Write(name)
+		out.WriteByte(':')
+		out.Write(buf.Bytes())
+		first = false
+	}
+	out.WriteByte('}')
+	return true, nil
+}
+
+func isNumber(value json.RawMessage) bool {
+	return len(value) > 0 && (value[0] == '-' || (value[0] >= '0' && value[0] <= '9'))
+}
@@ -0,0 +1,147 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"aggregator_db/internal/access"
+	"aggregator_db/internal/tenancy"
+	"aggregator_db/pkg/domain"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+func TestFieldVisibility(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	subscription := gin.H{
+		"id":           "8f1c",
+		"service_name": "Yandex Plus",
+		"price":        gin.H{"amount": "399.99", "currency": "RUB"},
+		"notes":        "семейная",
+		"discounts":    []gin.H{{"percent": 10, "price": gin.H{"amount": "40.00", "currency": "RUB"}}},
+	}
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		ctx := c.Request.Context()
+		if role := c.GetHeader("X-Role"); role != "" {
+			ctx = access.WithPrincipal(ctx, &access.Principal{UserID: uuid.New(), Role: domain.Role(role)})
+		}
+		if rules := c.GetHeader("X-Tenant-Rules"); rules != "" {
+			visibility, _ := domain.ParseFieldVisibility(rules)
+			ctx = tenancy.WithTenant(ctx, &domain.Tenant{ID: "acme", FieldVisibility: visibility})
+		}
+		c.Request = c.Request.WithContext(ctx)
+	}, FieldVisibility(domain.DefaultFieldVisibility()))
+	router.GET("/subscription", func(c *gin.Context) {
+		c.JSON(http.StatusOK, subscription)
+	})
+	router.GET("/totals", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{
+			"totals":  []gin.H{{"amount": "399.99", "currency": "RUB"}, {"amount": "9.99", "currency": "USD"}},
+			"renewal": gin.H{"service_name": "Netflix", "price": 400, "price_amount": gin.H{"amount": "399.99", "currency": "RUB"}},
+		})
+	})
+	// Выгрузка потоком, как помесячная разбивка с Accept: application/x-ndjson
+	router.GET("/stream", func(c *gin.Context) {
+		c.Header("Content-Type", domain.BreakdownStreamContentType)
+		c.Status(http.StatusOK)
+		enc := json.NewEncoder(c.Writer)
+		for _, month := range []string{"07-2025", "08-2025"} {
+			_ = enc.Encode(gin.H{"month": month, "total": gin.H{"amount": "399.99", "currency": "RUB"}})
+			c.Writer.Flush()
+		}
+		_ = enc.Encode(gin.H{"next_continuation": nil})
+	})
+	router.GET("/csv", func(c *gin.Context) {
+		c.Header("Content-Disposition", `attachment; filename="subscriptions.csv"`)
+		c.Data(http.StatusOK, "text/csv", []byte("service_name,price\nYandex Plus,399.99\n"))
+	})
+
+	tests := []struct {
+		name  string
+		path  string
+		role  string
+		rules string
+		want  int
+		body  string
+	}{
+		{
+			name: "user sees everything",
+			path: "/subscription",
+			role: "user",
+			want: http.StatusOK,
+			body: `{"discounts":[{"percent":10,"price":{"amount":"40.00","currency":"RUB"}}],"id":"8f1c","notes":"семейная",` +
+				`"price":{"amount":"399.99","currency":"RUB"},"service_name":"Yandex Plus"}`,
+		},
+		{
+			name: "support without money",
+			path: "/subscription",
+			role: "support",
+			want: http.StatusOK,
+			body: `{"discounts":[{"percent":10}],"id":"8f1c","notes":"семейная","service_name":"Yandex Plus"}`,
+		},
+		{
+			name: "support money lists and legacy prices",
+			path: "/totals",
+			role: "support",
+			want: http.StatusOK,
+			body: `{"renewal":{"service_name":"Netflix"},"totals":[]}`,
+		},
+		{
+			name:  "tenant rules replace defaults",
+			path:  "/subscription",
+			role:  "support",
+			rules: "support=notes,discounts",
+			want:  http.StatusOK,
+			body:  `{"id":"8f1c","price":{"amount":"399.99","currency":"RUB"},"service_name":"Yandex Plus"}`,
+		},
+		{
+			name:  "tenant rules for user",
+			path:  "/subscription",
+			role:  "user",
+			rules: "user=notes",
+			want:  http.StatusOK,
+			body: `{"discounts":[{"percent":10,"price":{"amount":"40.00","currency":"RUB"}}],"id":"8f1c",` +
+				`"price":{"amount":"399.99","currency":"RUB"},"service_name":"Yandex Plus"}`,
+		},
+		{
+			name: "support stream",
+			path: "/stream",
+			role: "support",
+			want: http.StatusOK,
+			body: "{\"month\":\"07-2025\"}\n{\"month\":\"08-2025\"}\n{\"next_continuation\":null}\n",
+		},
+		{name: "support other format", path: "/csv", role: "support", want: http.StatusForbidden},
+		{name: "user other format", path: "/csv", role: "user", want: http.StatusOK, body: "service_name,price\nYandex Plus,399.99\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			req.Header.Set("X-Role", tt.role)
+			if tt.rules != "" {
+				req.Header.Set("X-Tenant-Rules", tt.rules)
+			}
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+
+			if rec.Code != tt.want {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.want)
+			}
+			body := rec.Body.String()
+			if tt.want == http.StatusForbidden {
+				if strings.Contains(body, "399.99") || rec.Header().Get("Content-Disposition") != "" {
+					t.Errorf("denied response leaks the body: %q", body)
+				}
+				return
+			}
+			if body != tt.body {
+				t.Errorf("body = %s\nwant   %s", body, tt.body)
+			}
+		})
+	}
+}
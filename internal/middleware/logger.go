@@ -2,11 +2,35 @@ package middleware
 
 import (
 	"log/slog"
+	"strconv"
 	"time"
 
+	"aggregator_db/pkg/metrics"
+	"aggregator_db/pkg/tracing"
 	"github.com/gin-gonic/gin"
 )
 
+var httpRequests = metrics.NewCounterVec(
+	"http_requests_total",
+	"Количество обработанных HTTP-запросов",
+	"code",
+)
+
+var httpRequestDuration = metrics.NewHistogramVec(
+	"http_request_duration_seconds",
+	"Длительность HTTP-запросов по маршруту, коду ответа, тенанту и хэшу ключа API",
+	nil,
+	"method", "route", "code", "tenant", "api_key",
+)
+
+// RequestCounts - число обработанных запросов и ответов 5xx с момента старта.
+func RequestCounts() (errors, total float64) {
+	for class := 1; class <= 5; class++ {
+		total += httpRequests.Value(strconv.Itoa(class) + "xx")
+	}
+	return httpRequests.Value("5xx"), total
+}
+
 func Logger(logger *slog.Logger) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		start := time.Now()
@@ -17,8 +41,13 @@ func Logger(logger *slog.Logger) gin.HandlerFunc {
 
 		duration := time.Since(start)
 		statusCode := c.Writer.Status()
+		httpRequests.Inc(strconv.Itoa(statusCode/100) + "xx")
+		labels := tracing.LabelsFromContext(c.Request.Context())
+		httpRequestDuration.Observe(duration.Seconds(), method, labels.Get(tracing.LabelRoute), strconv.Itoa(statusCode),
+			labels.Get(tracing.LabelTenant), labels.Get(tracing.LabelAPIKey))
 
-		logger.Info("http request",
+		// Маршрут, тенант и ключ API добавляет обработчик лога по меткам запроса
+		logger.InfoContext(c.Request.Context(), "http request",
 			slog.String("method", method),
 			slog.String("path", path),
 			slog.Int("status", statusCode),
@@ -0,0 +1,18 @@
+package middleware
+
+import (
+	"aggregator_db/internal/metering"
+	"aggregator_db/pkg/domain"
+	"github.com/gin-gonic/gin"
+)
+
+// Metering учитывает запрос в потреблении API. Должен стоять после Tenant,
+// чтобы запрос был отнесен к тенанту.
+func Metering(meter *metering.Meter) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := metering.WithMeter(c.Request.Context(), meter)
+		c.Request = c.Request.WithContext(ctx)
+		meter.Record(ctx, domain.UsageAPIRequests, 1)
+		c.Next()
+	}
+}
@@ -0,0 +1,55 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"aggregator_db/internal/apikey"
+	"aggregator_db/internal/ratelimit"
+	"aggregator_db/internal/retryafter"
+	"aggregator_db/internal/tenancy"
+	"aggregator_db/pkg/domain"
+	"github.com/gin-gonic/gin"
+)
+
+const rateLimitStatusKey = "rate_limit_status"
+
+// RateLimit применяет лимиты запросов ключа внутреннего сервиса (serviceKeyLimit
+// в минуту, 0 - без лимита) и тенанта (квота requests_per_minute). Ставится после
+// Tenant, ServiceAPIKey и DeveloperApp; лимит приложения применяет сам DeveloperApp.
+func RateLimit(limiter *ratelimit.Limiter, serviceKeyLimit int) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+		if key := apikey.FromContext(ctx); key != nil && serviceKeyLimit > 0 {
+			if !allowRequest(c, limiter, ratelimit.ServiceKeyKey(key), serviceKeyLimit) {
+				return
+			}
+		}
+		if tenant := tenancy.FromContext(ctx); tenant != nil && tenant.Quotas.RequestsPerMinute != nil {
+			if !allowRequest(c, limiter, ratelimit.TenantKey(tenant), *tenant.Quotas.RequestsPerMinute) {
+				return
+			}
+		}
+		c.Next()
+	}
+}
+
+// allowRequest учитывает запрос в лимите key и при превышении отвечает 429.
+// Если к запросу применяется несколько лимитов, заголовки X-RateLimit-*
+// описывают тот, в котором осталось меньше запросов.
+func allowRequest(c *gin.Context, limiter *ratelimit.Limiter, key string, limit int) bool {
+	allowed, status := limiter.Allow(key, limit)
+	if prev, ok := c.Get(rateLimitStatusKey); !ok || status.Remaining < prev.(domain.RateLimitStatus).Remaining {
+		c.Set(rateLimitStatusKey, status)
+		c.Header("X-RateLimit-Limit", strconv.Itoa(status.Limit))
+		c.Header("X-RateLimit-Remaining", strconv.Itoa(status.Remaining))
+		c.Header("X-RateLimit-Reset", strconv.FormatInt(status.ResetAt.Unix(), 10))
+	}
+	if !allowed {
+		c.Header(RetryAfterHeader, strconv.Itoa(retryafter.Seconds(time.Until(status.ResetAt))))
+		c.AbortWithStatusJSON(http.StatusTooManyRequests, domain.ErrorResponse{Code: domain.CodeRateLimitExceeded, Error: "rate limit exceeded"})
+		return false
+	}
+	return true
+}
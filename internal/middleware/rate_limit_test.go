@@ -0,0 +1,68 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"aggregator_db/internal/apikey"
+	"aggregator_db/internal/ratelimit"
+	"aggregator_db/internal/tenancy"
+	"aggregator_db/pkg/domain"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+func TestRateLimitTightestLimitInHeaders(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tenantLimit := 2
+	tenant := &domain.Tenant{ID: "acme", Quotas: domain.TenantQuotas{RequestsPerMinute: &tenantLimit}}
+	key := &domain.APIKey{ID: uuid.New(), Scope: domain.APIKeyScopeRead}
+
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		ctx := tenancy.WithTenant(c.Request.Context(), tenant)
+		if c.GetHeader(APIKeyHeader) != "" {
+			ctx = apikey.WithKey(ctx, key)
+		}
+		c.Request = c.Request.WithContext(ctx)
+	})
+	router.Use(RateLimit(ratelimit.NewLimiter(time.Hour), 10))
+	router.GET("/ok", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	tests := []struct {
+		name      string
+		key       bool
+		want      int
+		limit     string
+		remaining string
+	}{
+		// Ключ сервиса: 9 из 10, тенант: 1 из 2 - в заголовках лимит тенанта
+		{name: "both limits", key: true, want: http.StatusOK, limit: "2", remaining: "1"},
+		{name: "tenant limit only", want: http.StatusOK, limit: "2", remaining: "0"},
+		{name: "tenant over limit", key: true, want: http.StatusTooManyRequests, limit: "2", remaining: "0"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/ok", nil)
+			if tt.key {
+				req.Header.Set(APIKeyHeader, "sk_service_test")
+			}
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+
+			if rec.Code != tt.want {
+				t.Errorf("status = %d, want %d", rec.Code, tt.want)
+			}
+			if got := rec.Header().Get("X-RateLimit-Limit"); got != tt.limit {
+				t.Errorf("X-RateLimit-Limit = %q, want %q", got, tt.limit)
+			}
+			if got := rec.Header().Get("X-RateLimit-Remaining"); got != tt.remaining {
+				t.Errorf("X-RateLimit-Remaining = %q, want %q", got, tt.remaining)
+			}
+		})
+	}
+}
@@ -0,0 +1,190 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+
+	"aggregator_db/internal/access"
+	"aggregator_db/internal/apikey"
+	"aggregator_db/internal/repository/postgres"
+	"aggregator_db/pkg/domain"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// UserIDHeader - пользователь запроса. Заголовок ставит шлюз после аутентификации,
+// сервис ему доверяет и берет роль пользователя из своей базы.
+const UserIDHeader = "X-User-ID"
+
+// UserResolver загружает пользователя запроса вместе с ролью.
+type UserResolver func(ctx context.Context, id uuid.UUID) (*domain.User, error)
+
+// OwnerResolver возвращает владельца ресурса с ID из пути запроса. Если ресурса
+// нет, ошибка должна оборачивать одну из ошибок ownerNotFound.
+type OwnerResolver func(ctx context.Context, id uuid.UUID) (uuid.UUID, error)
+
+var (
+	errAccessDenied = domain.ErrorResponse{Code: domain.CodeAccessDenied, Error: "access denied"}
+	errBlankUserID  = domain.ErrorResponse{Code: domain.CodeInvalidID, Error: "invalid user_id format"}
+)
+
+// ownerNotFound - ошибки OwnerResolver об отсутствии ресурса: на них Owner
+// пропускает запрос, и хендлер отвечает 404.
+var ownerNotFound = []error{postgres.ErrNotFound, postgres.ErrBudgetNotFound, postgres.ErrUserNotFound}
+
+// Principal определяет, от чьего имени выполняется запрос: по ключу внутреннего
+// сервиса (роль admin) или по X-User-ID. Без них запрос отклоняется с 401.
+func Principal(resolve UserResolver) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if key := apikey.FromContext(c.Request.Context()); key != nil {
+			c.Request = c.Request.WithContext(access.WithPrincipal(c.Request.Context(), &access.Principal{Role: domain.RoleAdmin}))
+			c.Next()
+			return
+		}
+
+		raw := c.GetHeader(UserIDHeader)
+		if raw == "" {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, domain.ErrorResponse{Code: domain.CodeUnauthorized, Error: UserIDHeader + " header is required"})
+			return
+		}
+		id, err := uuid.Parse(raw)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, domain.ErrorResponse{Code: domain.CodeUnauthorized, Error: "invalid " + UserIDHeader})
+			return
+		}
+
+		user, err := resolve(c.Request.Context(), id)
+		if errors.Is(err, postgres.ErrUserNotFound) {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, domain.ErrorResponse{Code: domain.CodeUnknownUser, Error: "unknown user"})
+			return
+		}
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, domain.ErrorResponse{Code: domain.CodeInternal, Error: err.Error()})
+			return
+		}
+
+		principal := &access.Principal{UserID: user.ID, Role: user.Role}
+		c.Request = c.Request.WithContext(access.WithPrincipal(c.Request.Context(), principal))
+		c.Next()
+	}
+}
+
+// RequireAdmin пропускает только администраторов. Как и остальные правила доступа,
+// без принципала в контексте (RBAC выключен) ничего не проверяет.
+func RequireAdmin() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if principal := access.FromContext(c.Request.Context()); principal != nil && !principal.IsAdmin() {
+			c.AbortWithStatusJSON(http.StatusForbidden, errAccessDenied)
+			return
+		}
+		c.Next()
+	}
+}
+
+// ScopeUserID ограничивает фильтр user_id пользователя его собственными данными:
+// без фильтра подставляет его ID, чужой ID (в том числе в списке user_ids)
+// отклоняет с 403, пустой - с 400. Администратор может передать любой user_id или не передавать
+// его для сводки по всем пользователям, поддержка - так же, но только на чтение.
+func ScopeUserID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		principal := access.FromContext(c.Request.Context())
+		if principal == nil || principal.IsAdmin() || readsAll(c, principal) {
+			c.Next()
+			return
+		}
+
+		query := c.Request.URL.Query()
+		listed := false
+		for _, value := range query["user_ids"] {
+			for _, raw := range strings.Split(value, ",") {
+				id, err := uuid.Parse(strings.TrimSpace(raw))
+				if err != nil {
+					// Некорректный ID отклонит хендлер с 400
+					continue
+				}
+				if id != principal.UserID {
+					c.AbortWithStatusJSON(http.StatusForbidden, errAccessDenied)
+					return
+				}
+				listed = true
+			}
+		}
+
+		// Параметр выбирается так же, как в хендлере: иначе фильтр, который
+		// хендлер не применит, прошел бы проверку
+		raw, ok := query["user_id"]
+		if !ok {
+			raw, ok = query["userId"]
+		}
+		if !ok {
+			if !listed {
+				query.Set("user_id", principal.UserID.String())
+				c.Request.URL.RawQuery = query.Encode()
+			}
+			c.Next()
+			return
+		}
+
+		value := strings.TrimSpace(raw[0])
+		if value == "" {
+			c.AbortWithStatusJSON(http.StatusBadRequest, errBlankUserID)
+			return
+		}
+		// Некорректный ID отклонит хендлер с 400
+		if id, err := uuid.Parse(value); err == nil && id != principal.UserID {
+			c.AbortWithStatusJSON(http.StatusForbidden, errAccessDenied)
+			return
+		}
+		c.Next()
+	}
+}
+
+// Owner пускает к ресурсу с ID из параметра пути param только его владельца и
+// администраторов, а поддержку - только на чтение. Если ресурса нет или ID
+// некорректный, ответ дает хендлер; если владельца не удалось определить по
+// другой причине, запрос отклоняется с 500.
+func Owner(param string, owner OwnerResolver) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		principal := access.FromContext(c.Request.Context())
+		if principal == nil || principal.IsAdmin() || readsAll(c, principal) {
+			c.Next()
+			return
+		}
+
+		id, err := uuid.Parse(c.Param(param))
+		if err != nil {
+			c.Next()
+			return
+		}
+		userID, err := owner(c.Request.Context(), id)
+		if err != nil {
+			for _, target := range ownerNotFound {
+				if errors.Is(err, target) {
+					c.Next()
+					return
+				}
+			}
+			c.AbortWithStatusJSON(http.StatusInternalServerError, domain.ErrorResponse{Code: domain.CodeInternal, Error: err.Error()})
+			return
+		}
+		if userID != principal.UserID {
+			c.AbortWithStatusJSON(http.StatusForbidden, errAccessDenied)
+			return
+		}
+		c.Next()
+	}
+}
+
+// readsAll сообщает, что запрос - чтение, а принципал может читать данные
+// любого пользователя (access.Principal.CanRead).
+func readsAll(c *gin.Context, principal *access.Principal) bool {
+	method := c.Request.Method
+	return (method == http.MethodGet || method == http.MethodHead) && principal.CanRead(uuid.Nil)
+}
+
+// Self - OwnerResolver для ручек пользователя: ресурс принадлежит самому себе.
+func Self(_ context.Context, id uuid.UUID) (uuid.UUID, error) {
+	return id, nil
+}
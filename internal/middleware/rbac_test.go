@@ -0,0 +1,144 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"aggregator_db/internal/apikey"
+	"aggregator_db/internal/repository/postgres"
+	"aggregator_db/pkg/domain"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+func TestRBAC(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	alice, bob, admin, support := uuid.New(), uuid.New(), uuid.New(), uuid.New()
+	users := map[uuid.UUID]domain.Role{alice: domain.RoleUser, bob: domain.RoleUser, admin: domain.RoleAdmin, support: domain.RoleSupport}
+	resolve := func(_ context.Context, id uuid.UUID) (*domain.User, error) {
+		role, ok := users[id]
+		if !ok {
+			return nil, postgres.ErrUserNotFound
+		}
+		return &domain.User{ID: id, Role: role}, nil
+	}
+	missing, broken := uuid.New(), uuid.New()
+	owner := func(_ context.Context, id uuid.UUID) (uuid.UUID, error) {
+		switch id {
+		case missing:
+			return uuid.Nil, postgres.ErrNotFound
+		case broken:
+			return uuid.Nil, errors.New("connection refused")
+		}
+		return id, nil
+	}
+
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		if c.GetHeader(APIKeyHeader) != "" {
+			key := &domain.APIKey{Name: "reports", Scope: domain.APIKeyScopeRead}
+			c.Request = c.Request.WithContext(apikey.WithKey(c.Request.Context(), key))
+		}
+	}, Principal(resolve))
+	// Ответ показывает фильтр user_id, который увидел хендлер
+	handler := func(c *gin.Context) {
+		c.String(http.StatusOK, c.Query("user_id"))
+	}
+	router.GET("/subscriptions", ScopeUserID(), handler)
+	router.GET("/subscriptions/:id", Owner("id", owner), handler)
+	router.PUT("/subscriptions/:id", Owner("id", owner), handler)
+	router.DELETE("/subscriptions", ScopeUserID(), handler)
+	router.GET("/users", RequireAdmin(), handler)
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		user   string
+		key    string
+		want   int
+		body   string
+		code   domain.ErrorCode
+	}{
+		{name: "no user", path: "/subscriptions", want: http.StatusUnauthorized},
+		{name: "invalid user", path: "/subscriptions", user: "bad", want: http.StatusUnauthorized},
+		{name: "unknown user", path: "/subscriptions", user: uuid.NewString(), want: http.StatusUnauthorized},
+		{name: "user filter defaults to self", path: "/subscriptions", user: alice.String(), want: http.StatusOK, body: alice.String()},
+		{name: "user own filter", path: "/subscriptions?user_id=" + alice.String(), user: alice.String(), want: http.StatusOK, body: alice.String()},
+		{name: "user foreign filter", path: "/subscriptions?user_id=" + bob.String(), user: alice.String(), want: http.StatusForbidden},
+		{name: "user own list", path: "/subscriptions?user_ids=" + alice.String(), user: alice.String(), want: http.StatusOK},
+		{name: "user foreign list", path: "/subscriptions?user_ids=" + alice.String() + "," + bob.String(), user: alice.String(), want: http.StatusForbidden},
+		{name: "user empty list defaults to self", path: "/subscriptions?user_ids=", user: alice.String(), want: http.StatusOK, body: alice.String()},
+		{name: "user legacy foreign filter", path: "/subscriptions?userId=" + bob.String(), user: alice.String(), want: http.StatusForbidden},
+		// Пустой фильтр хендлер не применил бы: он не должен открывать данные всех пользователей
+		{name: "user blank filter", path: "/subscriptions?user_id=", user: alice.String(), want: http.StatusBadRequest, code: domain.CodeInvalidID},
+		{name: "user whitespace filter", path: "/subscriptions?user_id=%20", user: alice.String(), want: http.StatusBadRequest, code: domain.CodeInvalidID},
+		{name: "user plus filter", path: "/subscriptions?user_id=+", user: alice.String(), want: http.StatusBadRequest, code: domain.CodeInvalidID},
+		{name: "user whitespace legacy filter", path: "/subscriptions?userId=%20", user: alice.String(), want: http.StatusBadRequest, code: domain.CodeInvalidID},
+		{name: "user blank filter with legacy", path: "/subscriptions?user_id=&userId=%20", user: alice.String(), want: http.StatusBadRequest, code: domain.CodeInvalidID},
+		{name: "user blank filter with legacy own", path: "/subscriptions?user_id=&userId=" + alice.String(), user: alice.String(), want: http.StatusBadRequest, code: domain.CodeInvalidID},
+		{name: "user padded own filter", path: "/subscriptions?user_id=%20" + alice.String(), user: alice.String(), want: http.StatusOK, body: " " + alice.String()},
+		{name: "user padded foreign filter", path: "/subscriptions?user_id=%20" + bob.String(), user: alice.String(), want: http.StatusForbidden},
+		{name: "user legacy own filter with foreign", path: "/subscriptions?user_id=" + alice.String() + "&userId=" + bob.String(), user: alice.String(), want: http.StatusOK, body: alice.String()},
+		{name: "user blank list with blank filter", path: "/subscriptions?user_ids=&user_id=%20", user: alice.String(), want: http.StatusBadRequest, code: domain.CodeInvalidID},
+		{name: "admin any filter", path: "/subscriptions?user_id=" + bob.String(), user: admin.String(), want: http.StatusOK, body: bob.String()},
+		{name: "admin aggregate", path: "/subscriptions", user: admin.String(), want: http.StatusOK},
+		{name: "user own resource", path: "/subscriptions/" + alice.String(), user: alice.String(), want: http.StatusOK},
+		{name: "user foreign resource", path: "/subscriptions/" + bob.String(), user: alice.String(), want: http.StatusForbidden},
+		// Отсутствующий ресурс отклонит хендлер с 404, а сбой поиска владельца - сам Owner с 500
+		{name: "user missing resource", path: "/subscriptions/" + missing.String(), user: alice.String(), want: http.StatusOK},
+		{name: "user resource owner error", path: "/subscriptions/" + broken.String(), user: alice.String(), want: http.StatusInternalServerError, code: domain.CodeInternal},
+		{name: "admin foreign resource", path: "/subscriptions/" + bob.String(), user: admin.String(), want: http.StatusOK},
+		{name: "user admin route", path: "/users", user: alice.String(), want: http.StatusForbidden},
+		{name: "admin admin route", path: "/users", user: admin.String(), want: http.StatusOK},
+		// Поддержка читает данные всех пользователей, но меняет только свои
+		{name: "support any filter", path: "/subscriptions?user_id=" + bob.String(), user: support.String(), want: http.StatusOK, body: bob.String()},
+		{name: "support aggregate", path: "/subscriptions", user: support.String(), want: http.StatusOK},
+		{name: "support foreign resource", path: "/subscriptions/" + bob.String(), user: support.String(), want: http.StatusOK},
+		{name: "support foreign write", method: http.MethodPut, path: "/subscriptions/" + bob.String(), user: support.String(), want: http.StatusForbidden},
+		{name: "support own write", method: http.MethodPut, path: "/subscriptions/" + support.String(), user: support.String(), want: http.StatusOK},
+		{name: "support foreign bulk write", method: http.MethodDelete, path: "/subscriptions?user_id=" + bob.String(), user: support.String(), want: http.StatusForbidden},
+		{name: "support admin route", path: "/users", user: support.String(), want: http.StatusForbidden},
+		// Ключи внутренних сервисов действуют как администратор
+		{name: "service key", path: "/users", key: "sk_service_reports", want: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			method := tt.method
+			if method == "" {
+				method = http.MethodGet
+			}
+			req := httptest.NewRequest(method, tt.path, nil)
+			if tt.user != "" {
+				req.Header.Set(UserIDHeader, tt.user)
+			}
+			if tt.key != "" {
+				req.Header.Set(APIKeyHeader, tt.key)
+			}
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+
+			if rec.Code != tt.want {
+				t.Errorf("status = %d, want %d", rec.Code, tt.want)
+			}
+			if rec.Code == http.StatusOK && rec.Body.String() != tt.body {
+				t.Errorf("user_id = %q, want %q", rec.Body.String(), tt.body)
+			}
+			if tt.code != "" {
+				var resp domain.ErrorResponse
+				if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+					t.Fatalf("decode error response: %v", err)
+				}
+				if resp.Code != tt.code {
+					t.Errorf("code = %q, want %q", resp.Code, tt.code)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,47 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+
+	"aggregator_db/internal/retryafter"
+	"aggregator_db/pkg/metrics"
+	"github.com/gin-gonic/gin"
+)
+
+const RetryAfterHeader = "Retry-After"
+
+var retryAfterHints = metrics.NewCounterVec(
+	"http_retry_after_hints_total",
+	"Ответы 429 и 5xx с подсказкой Retry-After по причине подсказки",
+	"reason",
+)
+
+// RetryAfter учитывает запрос в нагрузке и добавляет Retry-After к ответам 429 и 5xx,
+// если обработчик не выставил его сам (как лимит запросов приложения).
+// Должен стоять раньше остальных middleware, чтобы видеть их ответы.
+func RetryAfter(policy *retryafter.Policy) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		done := policy.Track()
+		defer done()
+
+		c.Writer = &retryAfterWriter{ResponseWriter: c.Writer, policy: policy}
+		c.Next()
+	}
+}
+
+// retryAfterWriter выставляет заголовок в момент выбора статуса, пока заголовки еще не отправлены.
+type retryAfterWriter struct {
+	gin.ResponseWriter
+	policy *retryafter.Policy
+}
+
+func (w *retryAfterWriter) WriteHeader(code int) {
+	if (code == http.StatusTooManyRequests || code >= http.StatusInternalServerError) &&
+		!w.Written() && w.Header().Get(RetryAfterHeader) == "" {
+		hint, reason := w.policy.Hint()
+		w.Header().Set(RetryAfterHeader, strconv.Itoa(retryafter.Seconds(hint)))
+		retryAfterHints.Inc(reason)
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
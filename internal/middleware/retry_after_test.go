@@ -0,0 +1,54 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"aggregator_db/internal/retryafter"
+	"aggregator_db/pkg/domain"
+	"github.com/gin-gonic/gin"
+)
+
+func TestRetryAfter(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	policy := retryafter.NewPolicy(time.Second, time.Minute, 100)
+	policy.Register("database", retryafter.SourceFunc(func() time.Duration { return 2500 * time.Millisecond }))
+
+	router := gin.New()
+	router.Use(RetryAfter(policy))
+	router.GET("/ok", func(c *gin.Context) { c.Status(http.StatusOK) })
+	router.GET("/bad", func(c *gin.Context) {
+		c.JSON(http.StatusBadRequest, domain.ErrorResponse{Error: "bad"})
+	})
+	router.GET("/unavailable", func(c *gin.Context) {
+		c.JSON(http.StatusServiceUnavailable, domain.ErrorResponse{Error: "database is unavailable"})
+	})
+	router.GET("/aborted", func(c *gin.Context) { c.AbortWithStatus(http.StatusBadGateway) })
+	router.GET("/own", func(c *gin.Context) {
+		c.Header(RetryAfterHeader, "42")
+		c.AbortWithStatusJSON(http.StatusTooManyRequests, domain.ErrorResponse{Error: "rate limit exceeded"})
+	})
+
+	tests := []struct {
+		path string
+		want string
+	}{
+		{path: "/ok"},
+		{path: "/bad"},
+		{path: "/unavailable", want: "3"},
+		{path: "/aborted", want: "3"},
+		{path: "/own", want: "42"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.path, nil))
+			if got := rec.Header().Get(RetryAfterHeader); got != tt.want {
+				t.Errorf("Retry-After = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
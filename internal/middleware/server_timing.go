@@ -0,0 +1,86 @@
+package middleware
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"aggregator_db/pkg/tracing"
+	"github.com/gin-gonic/gin"
+)
+
+const ServerTimingHeader = "Server-Timing"
+
+// serverTimingMetrics - метрики Server-Timing, собранные из спанов запроса по префиксу имени.
+var serverTimingMetrics = []struct {
+	name   string
+	desc   string
+	prefix string
+}{
+	{name: "db", desc: "database calls", prefix: "repository."},
+	{name: "ext", desc: "outgoing HTTP calls", prefix: "http.client "},
+}
+
+// ServerTiming добавляет заголовок Server-Timing: время в базе и внешних вызовах по спанам
+// запроса, время самого обработчика и сервисов (app) и общее время до ответа (total).
+func ServerTiming() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, timings := tracing.WithTimings(c.Request.Context())
+		c.Request = c.Request.WithContext(ctx)
+		c.Writer = &serverTimingWriter{ResponseWriter: c.Writer, start: time.Now(), timings: timings}
+		c.Next()
+	}
+}
+
+// serverTimingWriter считает тайминги в момент отправки заголовков: это время до ответа клиенту.
+type serverTimingWriter struct {
+	gin.ResponseWriter
+	start   time.Time
+	timings *tracing.Timings
+}
+
+func (w *serverTimingWriter) setHeader() {
+	if !w.Written() {
+		w.Header().Set(ServerTimingHeader, formatServerTiming(time.Since(w.start), w.timings))
+	}
+}
+
+func (w *serverTimingWriter) WriteHeader(code int) {
+	w.setHeader()
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *serverTimingWriter) Write(data []byte) (int, error) {
+	w.setHeader()
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *serverTimingWriter) WriteString(s string) (int, error) {
+	w.setHeader()
+	return w.ResponseWriter.WriteString(s)
+}
+
+func formatServerTiming(total time.Duration, timings *tracing.Timings) string {
+	var parts []string
+	app := total
+	for _, m := range serverTimingMetrics {
+		d, count := timings.Sum(func(name string) bool { return strings.HasPrefix(name, m.prefix) })
+		if count == 0 {
+			continue
+		}
+		app -= d
+		parts = append(parts, fmt.Sprintf("%s;dur=%s;desc=\"%d %s\"", m.name, formatMillis(d), count, m.desc))
+	}
+	// Параллельные вызовы могут в сумме превысить общее время
+	app = max(app, 0)
+	parts = append(parts,
+		fmt.Sprintf("app;dur=%s;desc=\"handler and services\"", formatMillis(app)),
+		fmt.Sprintf("total;dur=%s", formatMillis(total)),
+	)
+	return strings.Join(parts, ", ")
+}
+
+func formatMillis(d time.Duration) string {
+	return strconv.FormatFloat(float64(d.Microseconds())/1000, 'f', -1, 64)
+}
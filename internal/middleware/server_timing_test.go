@@ -0,0 +1,45 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"aggregator_db/pkg/tracing"
+	"github.com/gin-gonic/gin"
+)
+
+func TestServerTiming(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	router.Use(Tracing(), ServerTiming())
+	router.GET("/subscriptions", func(c *gin.Context) {
+		for _, name := range []string{"repository.List", "repository.Count", "http.client exchange_rates"} {
+			_, span := tracing.StartSpan(c.Request.Context(), name)
+			span.End()
+		}
+		c.JSON(http.StatusOK, gin.H{"items": []string{}})
+	})
+	router.GET("/plain", func(c *gin.Context) {
+		_, _ = c.Writer.WriteString("ok")
+	})
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/subscriptions", nil))
+	header := rec.Header().Get(ServerTimingHeader)
+	for _, want := range []string{`db;dur=`, `desc="2 database calls"`, `ext;dur=`, `desc="1 outgoing HTTP calls"`, `app;dur=`, `total;dur=`} {
+		if !strings.Contains(header, want) {
+			t.Errorf("Server-Timing %q does not contain %q", header, want)
+		}
+	}
+
+	// Без вызовов базы остаются только app и total, даже если обработчик пишет тело напрямую
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/plain", nil))
+	header = rec.Header().Get(ServerTimingHeader)
+	if !strings.HasPrefix(header, "app;dur=") || strings.Contains(header, "db;") {
+		t.Errorf("unexpected Server-Timing %q", header)
+	}
+}
@@ -0,0 +1,71 @@
+package middleware
+
+import (
+	"bytes"
+	"net/http"
+	"strings"
+
+	"aggregator_db/internal/shadow"
+	"github.com/gin-gonic/gin"
+)
+
+// maxShadowBody - ответы больше этого размера не сравниваются.
+const maxShadowBody = 1 << 20
+
+// Shadow повторяет выборку GET-запросов API на теневое развертывание после ответа
+// клиенту. Ручки администратора и уже повторенные запросы не повторяются. Ставится
+// после Compress, чтобы сравнивать несжатые тела.
+func Shadow(shadower *shadow.Shadower) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		path := c.Request.URL.Path
+		if c.Request.Method != http.MethodGet || c.GetHeader(shadow.Header) != "" ||
+			!strings.HasPrefix(path, "/api/") || strings.Contains(path, "/admin/") || !shadower.Sampled() {
+			c.Next()
+			return
+		}
+
+		req := shadow.Request{
+			Method: c.Request.Method,
+			Target: c.Request.URL.RequestURI(),
+			Header: c.Request.Header.Clone(),
+			Route:  c.FullPath(),
+		}
+		writer := &shadowWriter{ResponseWriter: c.Writer}
+		c.Writer = writer
+
+		c.Next()
+
+		if !writer.overflow {
+			shadower.Replay(req, shadow.Response{Status: writer.Status(), Body: writer.body.Bytes()})
+		}
+	}
+}
+
+// shadowWriter копирует тело ответа, пока оно не больше maxShadowBody.
+type shadowWriter struct {
+	gin.ResponseWriter
+	body     bytes.Buffer
+	overflow bool
+}
+
+func (w *shadowWriter) capture(data []byte) {
+	if w.overflow {
+		return
+	}
+	if w.body.Len()+len(data) > maxShadowBody {
+		w.overflow = true
+		w.body = bytes.Buffer{}
+		return
+	}
+	w.body.Write(data)
+}
+
+func (w *shadowWriter) Write(data []byte) (int, error) {
+	w.capture(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *shadowWriter) WriteString(s string) (int, error) {
+	w.capture([]byte(s))
+	return w.ResponseWriter.WriteString(s)
+}
@@ -0,0 +1,61 @@
+package middleware
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"aggregator_db/internal/shadow"
+	"aggregator_db/pkg/httpclient"
+	"github.com/gin-gonic/gin"
+)
+
+func TestShadow(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	replayed := make(chan string, 4)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		replayed <- r.Method + " " + r.URL.Path
+	}))
+	defer server.Close()
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	shadower := shadow.New(server.URL, shadow.Options{Percent: 100, Concurrency: 4, Timeout: time.Second},
+		httpclient.New(httpclient.DefaultConfig("shadow"), logger), logger)
+
+	router := gin.New()
+	router.Use(Shadow(shadower))
+	handler := func(c *gin.Context) { c.JSON(http.StatusOK, gin.H{"ok": true}) }
+	router.GET("/api/v1/subscriptions", handler)
+	router.POST("/api/v1/subscriptions", handler)
+	router.GET("/api/v1/admin/tenants", handler)
+
+	requests := []*http.Request{
+		httptest.NewRequest(http.MethodPost, "/api/v1/subscriptions", nil),
+		httptest.NewRequest(http.MethodGet, "/api/v1/admin/tenants", nil),
+		httptest.NewRequest(http.MethodGet, "/api/v1/subscriptions", nil),
+	}
+	replay := httptest.NewRequest(http.MethodGet, "/api/v1/subscriptions", nil)
+	replay.Header.Set(shadow.Header, "1")
+	requests = append(requests, replay)
+
+	for _, req := range requests {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		if rec.Code != http.StatusOK || rec.Body.String() != `{"ok":true}` {
+			t.Errorf("%s %s: %d %s", req.Method, req.URL.Path, rec.Code, rec.Body)
+		}
+	}
+
+	if got := <-replayed; got != "GET /api/v1/subscriptions" {
+		t.Errorf("replayed %s", got)
+	}
+	select {
+	case got := <-replayed:
+		t.Errorf("unexpected replay %s", got)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
@@ -0,0 +1,22 @@
+package middleware
+
+import (
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SLO передает observe ответы всех маршрутов. Маршрут - метод и шаблон пути gin,
+// поэтому /subscriptions/:id считается одним маршрутом для всех id. Middleware
+// ставится до gin.Recovery, чтобы паника учитывалась как 500.
+func SLO(observe func(route string, status int, duration time.Duration, now time.Time)) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+
+		c.Next()
+
+		if route := c.FullPath(); route != "" {
+			observe(c.Request.Method+" "+route, c.Writer.Status(), time.Since(start), time.Now())
+		}
+	}
+}
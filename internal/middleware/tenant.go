@@ -0,0 +1,74 @@
+package middleware
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"net/http"
+
+	"aggregator_db/internal/repository/postgres"
+	"aggregator_db/internal/tenancy"
+	"aggregator_db/pkg/domain"
+	"aggregator_db/pkg/tracing"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	TenantHeader    = "X-Tenant-ID"
+	TenantKeyHeader = "X-Tenant-Key"
+)
+
+// TenantResolver ищет тенанта по идентификатору из заголовка.
+type TenantResolver func(ctx context.Context, id string) (*domain.Tenant, error)
+
+// Tenant кладет в контекст запроса тенанта из X-Tenant-ID после проверки
+// ключа X-Tenant-Key. Запросы без заголовка обслуживаются в общей схеме.
+func Tenant(resolve TenantResolver) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(TenantHeader)
+		if id == "" {
+			c.Next()
+			return
+		}
+
+		tenant, err := resolve(c.Request.Context(), id)
+		if errors.Is(err, postgres.ErrTenantNotFound) {
+			c.AbortWithStatusJSON(http.StatusNotFound, domain.ErrorResponse{Code: domain.CodeTenantNotFound, Error: err.Error()})
+			return
+		}
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, domain.ErrorResponse{Code: domain.CodeInternal, Error: err.Error()})
+			return
+		}
+
+		if tenant.APIKeyHash != "" {
+			provided := domain.HashAPIKey(c.GetHeader(TenantKeyHeader))
+			if subtle.ConstantTimeCompare([]byte(provided), []byte(tenant.APIKeyHash)) != 1 {
+				c.AbortWithStatusJSON(http.StatusUnauthorized, domain.ErrorResponse{Code: domain.CodeInvalidTenantKey, Error: "invalid tenant key"})
+				return
+			}
+		}
+
+		if tenant.Status == domain.TenantStatusSuspended {
+			c.AbortWithStatusJSON(http.StatusForbidden, domain.ErrorResponse{Code: domain.CodeTenantSuspended, Error: "tenant is suspended"})
+			return
+		}
+
+		tenancy.RequestsTotal.Inc(tenant.ID)
+		tracing.LabelsFromContext(c.Request.Context()).Set(tracing.LabelTenant, tenant.ID)
+		c.Request = c.Request.WithContext(tenancy.WithTenant(c.Request.Context(), tenant))
+		c.Next()
+	}
+}
+
+// TenantFeature закрывает ручку для тенантов, у которых выключен флаг feature.
+func TenantFeature(feature string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		tenant := tenancy.FromContext(c.Request.Context())
+		if tenant != nil && !tenant.FeatureEnabled(feature) {
+			c.AbortWithStatusJSON(http.StatusForbidden, domain.ErrorResponse{Code: domain.CodeTenantFeatureOff, Error: "feature " + feature + " is disabled for tenant"})
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,71 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"aggregator_db/internal/repository/postgres"
+	"aggregator_db/pkg/domain"
+	"github.com/gin-gonic/gin"
+)
+
+func TestTenant(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	const key = "tk_test"
+	tenants := map[string]*domain.Tenant{
+		"acme":    {ID: "acme", Status: domain.TenantStatusActive, APIKeyHash: domain.HashAPIKey(key), Features: map[string]bool{domain.FeatureCalendar: false}},
+		"globex":  {ID: "globex", Status: domain.TenantStatusSuspended, APIKeyHash: domain.HashAPIKey(key)},
+		"initech": {ID: "initech", Status: domain.TenantStatusActive},
+	}
+	resolve := func(_ context.Context, id string) (*domain.Tenant, error) {
+		tenant, ok := tenants[id]
+		if !ok {
+			return nil, postgres.ErrTenantNotFound
+		}
+		return tenant, nil
+	}
+
+	router := gin.New()
+	router.Use(Tenant(resolve))
+	router.GET("/ok", func(c *gin.Context) { c.Status(http.StatusOK) })
+	router.GET("/calendar", TenantFeature(domain.FeatureCalendar), func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	tests := []struct {
+		name   string
+		path   string
+		tenant string
+		key    string
+		want   int
+	}{
+		{name: "no tenant", path: "/ok", want: http.StatusOK},
+		{name: "valid key", path: "/ok", tenant: "acme", key: key, want: http.StatusOK},
+		{name: "wrong key", path: "/ok", tenant: "acme", key: "tk_other", want: http.StatusUnauthorized},
+		{name: "unknown tenant", path: "/ok", tenant: "umbrella", want: http.StatusNotFound},
+		{name: "suspended", path: "/ok", tenant: "globex", key: key, want: http.StatusForbidden},
+		{name: "tenant without key", path: "/ok", tenant: "initech", want: http.StatusOK},
+		{name: "feature disabled", path: "/calendar", tenant: "acme", key: key, want: http.StatusForbidden},
+		{name: "feature enabled by default", path: "/calendar", tenant: "initech", want: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			if tt.tenant != "" {
+				req.Header.Set(TenantHeader, tt.tenant)
+			}
+			if tt.key != "" {
+				req.Header.Set(TenantKeyHeader, tt.key)
+			}
+
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+
+			if rec.Code != tt.want {
+				t.Fatalf("status = %d, want %d, body %s", rec.Code, tt.want, rec.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,40 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"net/http"
+
+	"aggregator_db/internal/clock"
+	"aggregator_db/pkg/domain"
+	"github.com/gin-gonic/gin"
+)
+
+// TimeTravelHeader задает время запроса: RFC 3339 замораживает часы, длительность
+// со знаком (+720h) сдвигает часы процесса.
+const TimeTravelHeader = "X-Debug-Time"
+
+// TimeTravel подменяет часы запроса по X-Debug-Time. Заголовок учитывается только
+// с корректным X-Admin-Token; включается CLOCK_HEADER_ENABLED и только для staging.
+func TimeTravel(adminToken string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		value := c.GetHeader(TimeTravelHeader)
+		if value == "" {
+			c.Next()
+			return
+		}
+
+		provided := c.GetHeader(AdminTokenHeader)
+		if adminToken == "" || subtle.ConstantTimeCompare([]byte(provided), []byte(adminToken)) != 1 {
+			c.AbortWithStatusJSON(http.StatusForbidden, domain.ErrorResponse{Code: domain.CodeTimeTravelDenied, Error: TimeTravelHeader + " requires a valid admin token"})
+			return
+		}
+
+		requestClock, err := clock.Parse(value, clock.Process())
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, domain.ErrorResponse{Code: domain.CodeValidationFailed, Error: err.Error()})
+			return
+		}
+		c.Request = c.Request.WithContext(clock.WithClock(c.Request.Context(), requestClock))
+		c.Next()
+	}
+}
@@ -0,0 +1,57 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"aggregator_db/internal/clock"
+	"github.com/gin-gonic/gin"
+)
+
+func TestTimeTravel(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	frozen := time.Date(2026, 1, 31, 23, 0, 0, 0, time.UTC)
+	clock.Set(clock.Frozen{At: frozen})
+	t.Cleanup(func() { clock.Set(clock.System{}) })
+
+	tests := []struct {
+		name    string
+		headers map[string]string
+		status  int
+		want    time.Time
+	}{
+		{name: "no header", status: http.StatusOK, want: frozen},
+		{name: "frozen", headers: map[string]string{TimeTravelHeader: "2027-03-01T00:00:00Z", AdminTokenHeader: "secret"}, status: http.StatusOK, want: time.Date(2027, 3, 1, 0, 0, 0, 0, time.UTC)},
+		{name: "shifted", headers: map[string]string{TimeTravelHeader: "+24h", AdminTokenHeader: "secret"}, status: http.StatusOK, want: frozen.Add(24 * time.Hour)},
+		{name: "without admin token", headers: map[string]string{TimeTravelHeader: "+24h"}, status: http.StatusForbidden},
+		{name: "invalid", headers: map[string]string{TimeTravelHeader: "tomorrow", AdminTokenHeader: "secret"}, status: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got time.Time
+			router := gin.New()
+			router.Use(TimeTravel("secret"))
+			router.GET("/now", func(c *gin.Context) {
+				got = clock.Now(c.Request.Context())
+				c.Status(http.StatusOK)
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "/now", nil)
+			for key, value := range tt.headers {
+				req.Header.Set(key, value)
+			}
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+
+			if rec.Code != tt.status {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.status)
+			}
+			if !got.Equal(tt.want) {
+				t.Errorf("now = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
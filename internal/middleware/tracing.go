@@ -0,0 +1,42 @@
+package middleware
+
+import (
+	"log/slog"
+
+	"aggregator_db/pkg/domain"
+	"aggregator_db/pkg/tracing"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// Tracing открывает серверный спан запроса, продолжая трейс из заголовка traceparent,
+// и метки запроса с шаблоном маршрута; тенанта и ключ API в них добавляют Tenant,
+// ServiceAPIKey и DeveloperApp.
+func Tracing() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+		if parent, ok := tracing.ParseTraceparent(c.GetHeader(tracing.TraceparentHeader)); ok {
+			ctx = tracing.ContextWithSpanContext(ctx, parent)
+		}
+		labels := &tracing.Labels{}
+		labels.Set(tracing.LabelRoute, c.FullPath())
+		ctx = tracing.ContextWithLabels(ctx, labels)
+
+		ctx, span := tracing.StartSpan(ctx, "http.server "+c.Request.Method+" "+c.FullPath(),
+			slog.String("http.method", c.Request.Method),
+			slog.String("http.path", c.Request.URL.Path),
+		)
+		defer span.End()
+
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+
+		span.SetAttributes(slog.Int("http.status_code", c.Writer.Status()))
+	}
+}
+
+// keyLabel - метка ключа API: начало SHA-256 его ID. Логи и метрики не раскрывают
+// ID ключей, но запросы одного ключа сопоставляются.
+func keyLabel(id uuid.UUID) string {
+	return domain.HashAPIKey(id.String())[:12]
+}
@@ -0,0 +1,80 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"aggregator_db/pkg/domain"
+	"aggregator_db/pkg/tracing"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+func TestRequestLabels(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	key := &domain.APIKey{ID: uuid.New(), Name: "reports", Scope: domain.APIKeyScopeRead}
+	resolveKey := func(context.Context, string) (*domain.APIKey, error) { return key, nil }
+	resolveTenant := func(_ context.Context, id string) (*domain.Tenant, error) {
+		return &domain.Tenant{ID: id, Status: domain.TenantStatusActive}, nil
+	}
+
+	var spans []*tracing.Span
+	tracing.SetExporter(func(span *tracing.Span) { spans = append(spans, span) })
+	defer tracing.SetExporter(nil)
+
+	var logs bytes.Buffer
+	logger := slog.New(tracing.NewLabelsHandler(slog.NewJSONHandler(&logs, nil)))
+
+	router := gin.New()
+	router.Use(Tracing(), Logger(logger), Tenant(resolveTenant), ServiceAPIKey(resolveKey))
+	router.GET("/subscriptions/:id", func(c *gin.Context) {
+		_, span := tracing.StartSpan(c.Request.Context(), "repository.GetByID")
+		span.End()
+		c.Status(http.StatusOK)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/subscriptions/"+uuid.NewString(), nil)
+	req.Header.Set(TenantHeader, "acme")
+	req.Header.Set(APIKeyHeader, "sk_service_reports")
+	router.ServeHTTP(httptest.NewRecorder(), req)
+
+	want := map[string]string{
+		tracing.LabelRoute:  "/subscriptions/:id",
+		tracing.LabelTenant: "acme",
+		tracing.LabelAPIKey: keyLabel(key.ID),
+	}
+
+	var line map[string]any
+	if err := json.Unmarshal(logs.Bytes(), &line); err != nil {
+		t.Fatalf("log line: %v: %s", err, logs.String())
+	}
+	for label, value := range want {
+		if line[label] != value {
+			t.Errorf("log %s = %v, want %q", label, line[label], value)
+		}
+	}
+
+	if len(spans) != 2 {
+		t.Fatalf("got %d spans, want repository and server spans", len(spans))
+	}
+	for _, span := range spans {
+		attrs := map[string]string{}
+		for _, attr := range span.Attributes() {
+			attrs[attr.Key] = attr.Value.String()
+		}
+		for label, value := range want {
+			if attrs[label] != value {
+				t.Errorf("span %s %s = %q, want %q", span.Name, label, attrs[label], value)
+			}
+		}
+	}
+	if len(want[tracing.LabelAPIKey]) != 12 || bytes.Contains(logs.Bytes(), []byte(key.ID.String())) {
+		t.Errorf("api key label must be a short hash, not the key ID")
+	}
+}
@@ -0,0 +1,304 @@
+// Package migrator применяет SQL-миграции из migrations/ к базе Postgres или
+// к схеме тенанта. Файлы и таблица schema_migrations - в формате golang-migrate,
+// но каждая миграция выполняется в одной транзакции с записью версии: неудачная
+// откатывается целиком и, в отличие от golang-migrate, не оставляет базу в
+// состоянии dirty, которое снимается только вручную (migrate force). Миграции
+// идут через pgx того же пула, что и запросы сервиса, поэтому search_path схемы
+// тенанта действует и на них.
+package migrator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"aggregator_db/migrations"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// lockKey - ключ advisory lock, под которым идут миграции: реплики с
+// MIGRATE_ON_START, стартовавшие одновременно, применяют их по очереди.
+const lockKey = 7243901566
+
+// Source возвращает файлы миграций: каталог dir, если он задан, иначе
+// встроенные в бинарник.
+func Source(dir string) fs.FS {
+	if dir == "" {
+		return migrations.FS
+	}
+	return os.DirFS(dir)
+}
+
+// Migrate применяет *.up.sql из files. Состояние хранится в
+// schema_migrations в формате golang-migrate, поэтому базу можно
+// мигрировать и его CLI.
+func Migrate(ctx context.Context, db *pgxpool.Pool, files fs.FS, logger *slog.Logger) error {
+	return withConn(ctx, db, func(conn session) error {
+		return up(ctx, conn, files, logger)
+	})
+}
+
+func up(ctx context.Context, conn session, files fs.FS, logger *slog.Logger) error {
+	list, err := listMigrations(files)
+	if err != nil {
+		return err
+	}
+
+	return withLock(ctx, conn, func() error {
+		current, err := currentVersion(ctx, conn)
+		if err != nil {
+			return err
+		}
+		for _, m := range list {
+			if m.version <= current {
+				continue
+			}
+			if err := apply(ctx, conn, files, m.name, m.version); err != nil {
+				return err
+			}
+			logger.Info("Applied migration", "version", m.version, "file", m.name)
+		}
+		return nil
+	})
+}
+
+// Down откатывает steps последних примененных миграций их *.down.sql.
+func Down(ctx context.Context, db *pgxpool.Pool, files fs.FS, steps int, logger *slog.Logger) error {
+	return withConn(ctx, db, func(conn session) error {
+		return down(ctx, conn, files, steps, logger)
+	})
+}
+
+func down(ctx context.Context, conn session, files fs.FS, steps int, logger *slog.Logger) error {
+	list, err := listMigrations(files)
+	if err != nil {
+		return err
+	}
+
+	return withLock(ctx, conn, func() error {
+		current, err := currentVersion(ctx, conn)
+		if err != nil {
+			return err
+		}
+		for ; steps > 0 && current > 0; steps-- {
+			i := sort.Search(len(list), func(i int) bool { return list[i].version >= current })
+			if i == len(list) || list[i].version != current {
+				return fmt.Errorf("migration %d is applied but not found in migrations", current)
+			}
+			// Версия 0 - ни одной примененной миграции, как у golang-migrate
+			var previous int64
+			if i > 0 {
+				previous = list[i-1].version
+			}
+			down := strings.TrimSuffix(list[i].name, ".up.sql") + ".down.sql"
+			if err := apply(ctx, conn, files, down, previous); err != nil {
+				return err
+			}
+			logger.Info("Reverted migration", "version", current, "file", down)
+			current = previous
+		}
+		return nil
+	})
+}
+
+// Status - примененная версия базы и миграции files.
+type Status struct {
+	Version    int64
+	Dirty      bool
+	Migrations []MigrationStatus
+}
+
+type MigrationStatus struct {
+	Version int64
+	Name    string
+	Applied bool
+}
+
+// Pending - число миграций, которые еще не применены.
+func (s *Status) Pending() int {
+	pending := 0
+	for _, m := range s.Migrations {
+		if !m.Applied {
+			pending++
+		}
+	}
+	return pending
+}
+
+func GetStatus(ctx context.Context, db *pgxpool.Pool, files fs.FS) (*Status, error) {
+	return getStatus(ctx, db, files)
+}
+
+func getStatus(ctx context.Context, db queryRower, files fs.FS) (*Status, error) {
+	list, err := listMigrations(files)
+	if err != nil {
+		return nil, err
+	}
+	status := &Status{Migrations: make([]MigrationStatus, len(list))}
+	if status.Version, status.Dirty, err = readVersion(ctx, db); err != nil {
+		return nil, err
+	}
+	for i, m := range list {
+		status.Migrations[i] = MigrationStatus{Version: m.version, Name: m.name, Applied: m.version <= status.Version}
+	}
+	return status, nil
+}
+
+// CheckApplied сообщает об ошибке, если база отстает от последней миграции files
+// или осталась в состоянии dirty после неудачной миграции.
+func CheckApplied(ctx context.Context, db *pgxpool.Pool, files fs.FS) error {
+	return checkApplied(ctx, db, files)
+}
+
+func checkApplied(ctx context.Context, db queryRower, files fs.FS) error {
+	status, err := getStatus(ctx, db, files)
+	if err != nil {
+		return err
+	}
+	if status.Dirty {
+		return fmt.Errorf("database is dirty at version %d", status.Version)
+	}
+	if len(status.Migrations) == 0 {
+		return nil
+	}
+	if latest := status.Migrations[len(status.Migrations)-1].Version; status.Version < latest {
+		return fmt.Errorf("database is at version %d, expected %d", status.Version, latest)
+	}
+	return nil
+}
+
+type migration struct {
+	version int64
+	name    string
+}
+
+// listMigrations возвращает *.up.sql из files по возрастанию версии.
+func listMigrations(files fs.FS) ([]migration, error) {
+	names, err := fs.Glob(files, "*.up.sql")
+	if err != nil {
+		return nil, err
+	}
+
+	list := make([]migration, 0, len(names))
+	for _, name := range names {
+		prefix, _, _ := strings.Cut(name, "_")
+		version, err := strconv.ParseInt(prefix, 10, 64)
+		if err != nil || version <= 0 {
+			return nil, fmt.Errorf("invalid migration name %s", name)
+		}
+		list = append(list, migration{version: version, name: name})
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].version < list[j].version })
+	for i := 1; i < len(list); i++ {
+		if list[i].version == list[i-1].version {
+			return nil, fmt.Errorf("duplicate migration version %d: %s and %s", list[i].version, list[i-1].name, list[i].name)
+		}
+	}
+	return list, nil
+}
+
+// session - соединение, на котором идут миграции: advisory lock держится
+// соединением, поэтому все запросы под ним идут через одно. В тестах подменяется.
+type session interface {
+	queryRower
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+	Begin(ctx context.Context) (pgx.Tx, error)
+}
+
+// withConn выполняет fn на одном соединении из пула.
+func withConn(ctx context.Context, db *pgxpool.Pool, fn func(conn session) error) error {
+	conn, err := db.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+	return fn(conn.Conn())
+}
+
+// withLock выполняет fn под advisory lock соединения conn.
+func withLock(ctx context.Context, conn session, fn func() error) error {
+	if _, err := conn.Exec(ctx, `SELECT pg_advisory_lock($1)`, lockKey); err != nil {
+		return fmt.Errorf("lock migrations: %w", err)
+	}
+	// Отмененный ctx не должен помешать снять блокировку
+	defer conn.Exec(context.WithoutCancel(ctx), `SELECT pg_advisory_unlock($1)`, lockKey)
+
+	return fn()
+}
+
+// currentVersion создает schema_migrations, если ее нет, и возвращает
+// примененную версию; dirty-база не мигрируется.
+func currentVersion(ctx context.Context, conn session) (int64, error) {
+	_, err := conn.Exec(ctx, `
+        CREATE TABLE IF NOT EXISTS schema_migrations (
+            version BIGINT NOT NULL PRIMARY KEY,
+            dirty BOOLEAN NOT NULL
+        )
+    `)
+	if err != nil {
+		return 0, fmt.Errorf("create schema_migrations: %w", err)
+	}
+	current, dirty, err := readVersion(ctx, conn)
+	if err != nil {
+		return 0, err
+	}
+	if dirty {
+		return 0, fmt.Errorf("database is dirty at version %d, fix it manually", current)
+	}
+	return current, nil
+}
+
+type queryRower interface {
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
+// readVersion возвращает 0, если миграций еще не было.
+func readVersion(ctx context.Context, db queryRower) (int64, bool, error) {
+	var version int64
+	var dirty bool
+	err := db.QueryRow(ctx, `SELECT version, dirty FROM schema_migrations LIMIT 1`).Scan(&version, &dirty)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, false, nil
+	}
+	// 42P01 - schema_migrations еще не создана
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "42P01" {
+		return 0, false, nil
+	}
+	return version, dirty, err
+}
+
+// apply выполняет файл name и записывает версию version одной транзакцией:
+// неудачная миграция не оставляет базу в состоянии dirty.
+func apply(ctx context.Context, conn session, files fs.FS, name string, version int64) error {
+	sql, err := fs.ReadFile(files, name)
+	if err != nil {
+		return err
+	}
+
+	err = pgx.BeginFunc(ctx, conn, func(tx pgx.Tx) error {
+		if _, err := tx.Exec(ctx, string(sql)); err != nil {
+			return err
+		}
+		if _, err := tx.Exec(ctx, `DELETE FROM schema_migrations`); err != nil {
+			return err
+		}
+		if version == 0 {
+			return nil
+		}
+		_, err := tx.Exec(ctx, `INSERT INTO schema_migrations (version, dirty) VALUES ($1, false)`, version)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("apply %s: %w", name, err)
+	}
+	return nil
+}
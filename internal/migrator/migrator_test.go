@@ -0,0 +1,283 @@
+package migrator
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"log/slog"
+	"strings"
+	"sync"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+func TestListMigrations(t *testing.T) {
+	files := fstest.MapFS{
+		"000010_tags.up.sql":       {},
+		"000010_tags.down.sql":     {},
+		"000002_users.up.sql":      {},
+		"000001_init.up.sql":       {},
+		"000001_init.down.sql":     {},
+		"README.md":                {},
+		"000002_users.down.sql":    {},
+		"000003_notes.up.sql.bak":  {},
+		"000004_budgets.down.sql":  {},
+		"000011_version.sql.draft": {},
+	}
+	list, err := listMigrations(files)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, m := range list {
+		names = append(names, m.name)
+	}
+	if got := strings.Join(names, ","); got != "000001_init.up.sql,000002_users.up.sql,000010_tags.up.sql" {
+		t.Errorf("migrations = %s", got)
+	}
+
+	for name, files := range map[string]fstest.MapFS{
+		"invalid name":      {"init.up.sql": {}},
+		"duplicate version": {"000001_init.up.sql": {}, "1_users.up.sql": {}},
+	} {
+		if _, err := listMigrations(files); err == nil {
+			t.Errorf("%s: no error", name)
+		}
+	}
+}
+
+// Каждую встроенную миграцию можно откатить: у нее есть *.down.sql
+func TestEmbeddedMigrations(t *testing.T) {
+	files := Source("")
+	list, err := listMigrations(files)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) == 0 {
+		t.Fatal("no embedded migrations")
+	}
+	for _, m := range list {
+		down := strings.TrimSuffix(m.name, ".up.sql") + ".down.sql"
+		if _, err := fs.Stat(files, down); err != nil {
+			t.Errorf("%s has no %s", m.name, down)
+		}
+	}
+}
+
+// fakeDB - база с таблицей schema_migrations и advisory lock миграций. Миграция
+// записывается в applied при коммите ее транзакции; миграция с текстом fail
+// завершается ошибкой.
+type fakeDB struct {
+	lock sync.Mutex
+
+	mu      sync.Mutex
+	created bool
+	rows    []fakeVersion
+	applied []string
+	fail    string
+}
+
+type fakeVersion struct {
+	version int64
+	dirty   bool
+}
+
+// fakeConn - соединение с fakeDB.
+type fakeConn struct {
+	db *fakeDB
+}
+
+func (c fakeConn) Exec(_ context.Context, sql string, _ ...any) (pgconn.CommandTag, error) {
+	switch {
+	case strings.Contains(sql, "pg_advisory_lock("):
+		c.db.lock.Lock()
+	case strings.Contains(sql, "pg_advisory_unlock("):
+		c.db.lock.Unlock()
+	case strings.Contains(sql, "CREATE TABLE IF NOT EXISTS schema_migrations"):
+		c.db.mu.Lock()
+		c.db.created = true
+		c.db.mu.Unlock()
+	default:
+		return pgconn.CommandTag{}, errors.New("unexpected statement outside transaction: " + sql)
+	}
+	return pgconn.CommandTag{}, nil
+}
+
+func (c fakeConn) QueryRow(context.Context, string, ...any) pgx.Row {
+	c.db.mu.Lock()
+	defer c.db.mu.Unlock()
+	switch {
+	case !c.db.created:
+		return fakeRow{err: &pgconn.PgError{Code: "42P01"}}
+	case len(c.db.rows) == 0:
+		return fakeRow{err: pgx.ErrNoRows}
+	}
+	return fakeRow{version: c.db.rows[0]}
+}
+
+func (c fakeConn) Begin(context.Context) (pgx.Tx, error) {
+	return &fakeTx{db: c.db}, nil
+}
+
+type fakeRow struct {
+	version fakeVersion
+	err     error
+}
+
+func (r fakeRow) Scan(dest ...any) error {
+	if r.err != nil {
+		return r.err
+	}
+	*dest[0].(*int64), *dest[1].(*bool) = r.version.version, r.version.dirty
+	return nil
+}
+
+// fakeTx копит изменения и применяет их к fakeDB при коммите.
+type fakeTx struct {
+	pgx.Tx
+	db      *fakeDB
+	pending []func()
+}
+
+func (tx *fakeTx) Exec(_ context.Context, sql string, args ...any) (pgconn.CommandTag, error) {
+	switch {
+	case strings.HasPrefix(sql, "DELETE FROM schema_migrations"):
+		tx.pending = append(tx.pending, func() { tx.db.rows = nil })
+	case strings.HasPrefix(sql, "INSERT INTO schema_migrations"):
+		version := args[0].(int64)
+		tx.pending = append(tx.pending, func() { tx.db.rows = append(tx.db.rows, fakeVersion{version: version}) })
+	case tx.db.fail != "" && strings.Contains(sql, tx.db.fail):
+		return pgconn.CommandTag{}, errors.New("syntax error")
+	default:
+		// Длинная миграция: без блокировки вторая реплика успела бы прочитать старую версию
+		time.Sleep(time.Millisecond)
+		tx.pending = append(tx.pending, func() { tx.db.applied = append(tx.db.applied, sql) })
+	}
+	return pgconn.CommandTag{}, nil
+}
+
+func (tx *fakeTx) Commit(context.Context) error {
+	tx.db.mu.Lock()
+	defer tx.db.mu.Unlock()
+	for _, change := range tx.pending {
+		change()
+	}
+	tx.pending = nil
+	return nil
+}
+
+func (tx *fakeTx) Rollback(context.Context) error {
+	tx.pending = nil
+	return nil
+}
+
+func testMigrations() fstest.MapFS {
+	return fstest.MapFS{
+		"000001_init.up.sql":    {Data: []byte("CREATE TABLE subscriptions")},
+		"000001_init.down.sql":  {Data: []byte("DROP TABLE subscriptions")},
+		"000002_users.up.sql":   {Data: []byte("CREATE TABLE users")},
+		"000002_users.down.sql": {Data: []byte("DROP TABLE users")},
+		"000003_tags.up.sql":    {Data: []byte("ALTER TABLE subscriptions ADD tags")},
+		"000003_tags.down.sql":  {Data: []byte("ALTER TABLE subscriptions DROP tags")},
+	}
+}
+
+func TestMigrateConcurrent(t *testing.T) {
+	ctx := context.Background()
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	db := &fakeDB{}
+	files := testMigrations()
+
+	// Реплики с MIGRATE_ON_START стартуют одновременно
+	var wg sync.WaitGroup
+	errs := make([]error, 4)
+	for i := range errs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = up(ctx, fakeConn{db: db}, files, logger)
+		}()
+	}
+	wg.Wait()
+	for i, err := range errs {
+		if err != nil {
+			t.Errorf("replica %d: %v", i, err)
+		}
+	}
+
+	want := "CREATE TABLE subscriptions,CREATE TABLE users,ALTER TABLE subscriptions ADD tags"
+	if got := strings.Join(db.applied, ","); got != want {
+		t.Errorf("applied = %s, want each migration once: %s", got, want)
+	}
+	if len(db.rows) != 1 || db.rows[0] != (fakeVersion{version: 3}) {
+		t.Errorf("schema_migrations = %+v, want version 3", db.rows)
+	}
+	if err := checkApplied(ctx, fakeConn{db: db}, files); err != nil {
+		t.Errorf("check applied: %v", err)
+	}
+
+	if err := down(ctx, fakeConn{db: db}, files, 2, logger); err != nil {
+		t.Fatal(err)
+	}
+	status, err := getStatus(ctx, fakeConn{db: db}, files)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.Version != 1 || status.Pending() != 2 {
+		t.Errorf("after down: version %d, pending %d, want 1 and 2", status.Version, status.Pending())
+	}
+}
+
+func TestMigrateDirty(t *testing.T) {
+	ctx := context.Background()
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	files := testMigrations()
+
+	// Неудачная миграция откатывается вместе с записью версии
+	db := &fakeDB{fail: "ADD tags"}
+	if err := up(ctx, fakeConn{db: db}, files, logger); err == nil || !strings.Contains(err.Error(), "000003_tags.up.sql") {
+		t.Fatalf("failed migration: err = %v", err)
+	}
+	if len(db.rows) != 1 || db.rows[0] != (fakeVersion{version: 2}) {
+		t.Errorf("after failure schema_migrations = %+v, want clean version 2", db.rows)
+	}
+	if err := checkApplied(ctx, fakeConn{db: db}, files); err == nil || !strings.Contains(err.Error(), "expected 3") {
+		t.Errorf("check applied after failure: err = %v", err)
+	}
+
+	// Исправленная миграция применяется следующим запуском
+	db.fail = ""
+	if err := up(ctx, fakeConn{db: db}, files, logger); err != nil {
+		t.Fatal(err)
+	}
+	if len(db.applied) != 3 || db.rows[0] != (fakeVersion{version: 3}) {
+		t.Errorf("after retry applied = %v, schema_migrations = %+v", db.applied, db.rows)
+	}
+
+	// dirty оставляет CLI golang-migrate: такую базу сервис не мигрирует и не считает готовой
+	db = &fakeDB{created: true, rows: []fakeVersion{{version: 2, dirty: true}}}
+	if err := up(ctx, fakeConn{db: db}, files, logger); err == nil || !strings.Contains(err.Error(), "dirty") {
+		t.Errorf("migrate dirty database: err = %v", err)
+	}
+	if err := down(ctx, fakeConn{db: db}, files, 1, logger); err == nil || !strings.Contains(err.Error(), "dirty") {
+		t.Errorf("revert dirty database: err = %v", err)
+	}
+	if len(db.applied) != 0 {
+		t.Errorf("dirty database: applied %v", db.applied)
+	}
+	if err := checkApplied(ctx, fakeConn{db: db}, files); err == nil || !strings.Contains(err.Error(), "dirty") {
+		t.Errorf("check dirty database: err = %v", err)
+	}
+	status, err := getStatus(ctx, fakeConn{db: db}, files)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !status.Dirty || status.Version != 2 {
+		t.Errorf("status = %+v, want dirty version 2", status)
+	}
+}
@@ -0,0 +1,87 @@
+// Package ratelimit ограничивает число запросов по ключу в фиксированном окне.
+// Счетчики живут в памяти реплики, поэтому при нескольких репликах фактический
+// лимит умножается на их число.
+package ratelimit
+
+import (
+	"sync"
+	"time"
+
+	"aggregator_db/pkg/domain"
+)
+
+type window struct {
+	start time.Time
+	count int
+}
+
+type Limiter struct {
+	mu      sync.Mutex
+	period  time.Duration
+	windows map[string]*window
+	now     func() time.Time
+}
+
+func NewLimiter(period time.Duration) *Limiter {
+	return &Limiter{
+		period:  period,
+		windows: make(map[string]*window),
+		now:     time.Now,
+	}
+}
+
+// current возвращает окно ключа, начиная новое, если прежнее истекло.
+// Вызывается под mu.
+func (l *Limiter) current(key string) *window {
+	now := l.now().UTC()
+	w, ok := l.windows[key]
+	if !ok || !now.Before(w.start.Add(l.period)) {
+		w = &window{start: now.Truncate(l.period)}
+		l.windows[key] = w
+	}
+	return w
+}
+
+func (l *Limiter) status(w *window, limit int) domain.RateLimitStatus {
+	remaining := limit - w.count
+	if remaining < 0 {
+		remaining = 0
+	}
+	return domain.RateLimitStatus{Limit: limit, Remaining: remaining, ResetAt: w.start.Add(l.period)}
+}
+
+// Allow учитывает запрос по ключу и сообщает, укладывается ли он в limit.
+// Отклоненные запросы тоже учитываются.
+func (l *Limiter) Allow(key string, limit int) (bool, domain.RateLimitStatus) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	w := l.current(key)
+	w.count++
+	return w.count <= limit, l.status(w, limit)
+}
+
+// Status возвращает состояние лимита без учета запроса.
+func (l *Limiter) Status(key string, limit int) domain.RateLimitStatus {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.status(l.current(key), limit)
+}
+
+// Ключи лимитов вызывающих разных видов не пересекаются.
+
+// AppKey - ключ лимита приложения портала разработчиков.
+func AppKey(app *domain.DeveloperApp) string {
+	return app.ID.String()
+}
+
+// ServiceKeyKey - ключ лимита ключа внутреннего сервиса.
+func ServiceKeyKey(key *domain.APIKey) string {
+	return "service_key:" + key.ID.String()
+}
+
+// TenantKey - ключ лимита тенанта.
+func TenantKey(tenant *domain.Tenant) string {
+	return "tenant:" + tenant.ID
+}
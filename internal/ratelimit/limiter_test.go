@@ -0,0 +1,38 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLimiterFixedWindow(t *testing.T) {
+	now := time.Date(2025, 10, 23, 15, 4, 30, 0, time.UTC)
+	l := NewLimiter(time.Minute)
+	l.now = func() time.Time { return now }
+
+	for i := 0; i < 3; i++ {
+		if ok, _ := l.Allow("app", 3); !ok {
+			t.Fatalf("request %d rejected within limit", i+1)
+		}
+	}
+	ok, status := l.Allow("app", 3)
+	if ok || status.Remaining != 0 {
+		t.Fatalf("4th request: allowed=%v remaining=%d, want rejected with 0", ok, status.Remaining)
+	}
+	if want := time.Date(2025, 10, 23, 15, 5, 0, 0, time.UTC); !status.ResetAt.Equal(want) {
+		t.Errorf("reset at %s, want %s", status.ResetAt, want)
+	}
+
+	// Другие ключи считаются отдельно
+	if ok, _ := l.Allow("other", 3); !ok {
+		t.Error("other key rejected")
+	}
+
+	now = now.Add(30 * time.Second)
+	if status := l.Status("app", 3); status.Remaining != 3 {
+		t.Errorf("remaining in new window = %d, want 3", status.Remaining)
+	}
+	if ok, _ := l.Allow("app", 3); !ok {
+		t.Error("request in new window rejected")
+	}
+}
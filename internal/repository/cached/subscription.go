@@ -0,0 +1,357 @@
+package cached
+
+import (
+	"context"
+	"encoding/json"
+	"maps"
+	"sync"
+	"time"
+
+	"aggregator_db/internal/repository/postgres"
+	"aggregator_db/internal/tenancy"
+	"aggregator_db/pkg/domain"
+	"aggregator_db/pkg/metrics"
+	"github.com/google/uuid"
+)
+
+var lookups = metrics.NewCounterVec(
+	"calculate_cache_total",
+	"Расчеты стоимости через кэш: hit, miss или bypass - чтение без допуска отставания",
+	"result",
+)
+
+// Options - настройки кэша расчетов стоимости.
+type Options struct {
+	// TTL ограничивает возраст записи: изменения с других реплик сервиса кэш не видит
+	TTL time.Duration
+	// MaxEntries - сколько расчетов хранится; при переполнении вытесняются случайные
+	MaxEntries int
+}
+
+// SubscriptionRepository кэширует CalculateTotal и CalculateTotalByUser по всем
+// параметрам расчета и тенанту. Изменение подписки сбрасывает расчеты ее
+// пользователя и расчеты без фильтра пользователей в тенанте - сразу и еще раз
+// после коммита единицы работы, чтобы расчет, прочитавший данные до коммита,
+// не остался в кэше. Из кэша отвечают только чтения, допускающие отставание
+// (postgres.PreferReplica); остальные идут в базу и обновляют запись. Изменения
+// в обход репозитория сбрасываются через postgres.SubscriptionCache.
+type SubscriptionRepository struct {
+	postgres.SubscriptionRepository
+	opts Options
+
+	mu      sync.Mutex
+	entries map[string]*entry
+	// index - ключи записей по тенанту и пользователю; записи без фильтра
+	// пользователей лежат под uuid.Nil
+	index map[owner]map[string]struct{}
+}
+
+type owner struct {
+	tenant string
+	user   uuid.UUID
+}
+
+type entry struct {
+	owners  []owner
+	totals  domain.Totals
+	byUser  map[uuid.UUID]domain.Totals
+	asOf    time.Time
+	expires time.Time
+}
+
+// NewSubscriptionRepository оборачивает next кэшем расчетов стоимости. Методы,
+// которых нет в этом файле, идут в next без изменений; новый метод, меняющий
+// подписки, должен сбрасывать кэш здесь же.
+func NewSubscriptionRepository(next postgres.SubscriptionRepository, opts Options) *SubscriptionRepository {
+	return &SubscriptionRepository{
+		SubscriptionRepository: next,
+		opts:                   opts,
+		entries:                make(map[string]*entry),
+		index:                  make(map[owner]map[string]struct{}),
+	}
+}
+
+func (r *SubscriptionRepository) CalculateTotal(ctx context.Context, req domain.CalculateTotalRequest) (domain.Totals, error) {
+	key := cacheKey(ctx, "total", req)
+	if cached := r.lookup(ctx, key); cached != nil {
+		return maps.Clone(cached.totals), nil
+	}
+	totals, err := r.SubscriptionRepository.CalculateTotal(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	r.store(ctx, key, req, &entry{totals: maps.Clone(totals)})
+	return totals, nil
+}
+
+func (r *SubscriptionRepository) CalculateTotalByUser(ctx context.Context, req domain.CalculateTotalRequest) (map[uuid.UUID]domain.Totals, error) {
+	key := cacheKey(ctx, "by_user", req)
+	if cached := r.lookup(ctx, key); cached != nil {
+		return cloneByUser(cached.byUser), nil
+	}
+	byUser, err := r.SubscriptionRepository.CalculateTotalByUser(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	r.store(ctx, key, req, &entry{byUser: cloneByUser(byUser)})
+	return byUser, nil
+}
+
+// lookup возвращает свежую запись key, если чтение ctx допускает отставание.
+// Возраст записи попадает в data_as_of ответа (postgres.ObserveLag).
+func (r *SubscriptionRepository) lookup(ctx context.Context, key string) *entry {
+	if !postgres.ToleratesLag(ctx) || postgres.InTx(ctx) {
+		lookups.Inc("bypass")
+		return nil
+	}
+	r.mu.Lock()
+	cached, ok := r.entries[key]
+	if ok && !time.Now().Before(cached.expires) {
+		r.removeLocked(key)
+		ok = false
+	}
+	r.mu.Unlock()
+	if !ok {
+		lookups.Inc("miss")
+		return nil
+	}
+	lookups.Inc("hit")
+	postgres.ObserveLag(ctx, time.Since(cached.asOf))
+	return cached
+}
+
+// store сохраняет расчет. Расчет внутри единицы работы может видеть ее
+// незакоммиченные изменения, поэтому не сохраняется.
+func (r *SubscriptionRepository) store(ctx context.Context, key string, req domain.CalculateTotalRequest, e *entry) {
+	if postgres.InTx(ctx) {
+		return
+	}
+	now := time.Now()
+	// Данные не новее, чем у чтения, которым они получены (например, из реплики)
+	e.asOf = now.Add(-postgres.ReadLag(ctx))
+	e.expires = now.Add(r.opts.TTL)
+
+	tenant := tenantOf(ctx)
+	switch {
+	case req.UserID != nil:
+		e.owners = []owner{{tenant, *req.UserID}}
+	case len(req.UserIDs) > 0:
+		for _, user := range req.UserIDs {
+			e.owners = append(e.owners, owner{tenant, user})
+		}
+	default:
+		e.owners = []owner{{tenant, uuid.Nil}}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.removeLocked(key)
+	for len(r.entries) >= r.opts.MaxEntries && len(r.entries) > 0 {
+		for victim := range r.entries {
+			r.removeLocked(victim)
+			break
+		}
+	}
+	r.entries[key] = e
+	for _, o := range e.owners {
+		if r.index[o] == nil {
+			r.index[o] = make(map[string]struct{})
+		}
+		r.index[o][key] = struct{}{}
+	}
+}
+
+func (r *SubscriptionRepository) removeLocked(key string) {
+	e, ok := r.entries[key]
+	if !ok {
+		return
+	}
+	delete(r.entries, key)
+	for _, o := range e.owners {
+		delete(r.index[o], key)
+		if len(r.index[o]) == 0 {
+			delete(r.index, o)
+		}
+	}
+}
+
+// invalidate сбрасывает расчеты пользователей users и расчеты без фильтра
+// пользователей в тенанте ctx сейчас и после коммита единицы работы ctx.
+func (r *SubscriptionRepository) invalidate(ctx context.Context, users ...uuid.UUID) {
+	tenant := tenantOf(ctx)
+	drop := func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		for _, user := range append(users, uuid.Nil) {
+			for key := range r.index[owner{tenant, user}] {
+				r.removeLocked(key)
+			}
+		}
+	}
+	drop()
+	postgres.AfterCommit(ctx, drop)
+}
+
+// invalidateTenant сбрасывает все расчеты тенанта ctx, когда затронутых
+// пользователей не узнать.
+func (r *SubscriptionRepository) invalidateTenant(ctx context.Context) {
+	tenant := tenantOf(ctx)
+	drop := func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		for o, keys := range r.index {
+			if o.tenant != tenant {
+				continue
+			}
+			for key := range keys {
+				r.removeLocked(key)
+			}
+		}
+	}
+	drop()
+	postgres.AfterCommit(ctx, drop)
+}
+
+func (r *SubscriptionRepository) InvalidateUsers(ctx context.Context, users ...uuid.UUID) {
+	r.invalidate(ctx, users...)
+}
+
+func (r *SubscriptionRepository) InvalidateTenant(ctx context.Context) {
+	r.invalidateTenant(ctx)
+}
+
+// invalidateSubscription сбрасывает расчеты владельца подписки id.
+func (r *SubscriptionRepository) invalidateSubscription(ctx context.Context, id uuid.UUID) {
+	sub, err := r.SubscriptionRepository.GetByID(ctx, id)
+	if err != nil {
+		r.invalidateTenant(ctx)
+		return
+	}
+	r.invalidate(ctx, sub.UserID)
+}
+
+func (r *SubscriptionRepository) Create(ctx context.Context, sub *domain.Subscription) error {
+	if err := r.SubscriptionRepository.Create(ctx, sub); err != nil {
+		return err
+	}
+	r.invalidate(ctx, sub.UserID)
+	return nil
+}
+
+func (r *SubscriptionRepository) CreateBatch(ctx context.Context, subs []*domain.Subscription) error {
+	if err := r.SubscriptionRepository.CreateBatch(ctx, subs); err != nil {
+		return err
+	}
+	users := make([]uuid.UUID, len(subs))
+	for i, sub := range subs {
+		users[i] = sub.UserID
+	}
+	r.invalidate(ctx, users...)
+	return nil
+}
+
+// Upsert может сменить владельца подписки, пришедшей из другого региона, поэтому
+// сбрасывает весь тенант.
+func (r *SubscriptionRepository) Upsert(ctx context.Context, sub *domain.Subscription) error {
+	if err := r.SubscriptionRepository.Upsert(ctx, sub); err != nil {
+		return err
+	}
+	r.invalidateTenant(ctx)
+	return nil
+}
+
+func (r *SubscriptionRepository) Update(ctx context.Context, sub *domain.Subscription) error {
+	if err := r.SubscriptionRepository.Update(ctx, sub); err != nil {
+		return err
+	}
+	r.invalidate(ctx, sub.UserID)
+	return nil
+}
+
+func (r *SubscriptionRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	// Владельца нужно узнать до удаления
+	sub, lookupErr := r.SubscriptionRepository.GetByID(ctx, id)
+	if err := r.SubscriptionRepository.Delete(ctx, id); err != nil {
+		return err
+	}
+	if lookupErr != nil {
+		r.invalidateTenant(ctx)
+		return nil
+	}
+	r.invalidate(ctx, sub.UserID)
+	return nil
+}
+
+func (r *SubscriptionRepository) DeleteByFilter(ctx context.Context, filter domain.DeleteSubscriptionsFilter) (int, error) {
+	deleted, err := r.SubscriptionRepository.DeleteByFilter(ctx, filter)
+	if err != nil || deleted == 0 {
+		return deleted, err
+	}
+	if filter.UserID != nil {
+		r.invalidate(ctx, *filter.UserID)
+	} else {
+		r.invalidateTenant(ctx)
+	}
+	return deleted, nil
+}
+
+func (r *SubscriptionRepository) ChangeStatus(ctx context.Context, change *domain.StatusChange) error {
+	if err := r.SubscriptionRepository.ChangeStatus(ctx, change); err != nil {
+		return err
+	}
+	r.invalidateSubscription(ctx, change.SubscriptionID)
+	return nil
+}
+
+func (r *SubscriptionRepository) Renew(ctx context.Context, id uuid.UUID, previousEnd, endDate string, renewedAt time.Time) error {
+	if err := r.SubscriptionRepository.Renew(ctx, id, previousEnd, endDate, renewedAt); err != nil {
+		return err
+	}
+	r.invalidateSubscription(ctx, id)
+	return nil
+}
+
+func (r *SubscriptionRepository) Cancel(ctx context.Context, sub *domain.Subscription, change *domain.StatusChange) error {
+	if err := r.SubscriptionRepository.Cancel(ctx, sub, change); err != nil {
+		return err
+	}
+	r.invalidate(ctx, sub.UserID)
+	return nil
+}
+
+func (r *SubscriptionRepository) CreateDiscount(ctx context.Context, discount *domain.Discount) error {
+	if err := r.SubscriptionRepository.CreateDiscount(ctx, discount); err != nil {
+		return err
+	}
+	r.invalidateSubscription(ctx, discount.SubscriptionID)
+	return nil
+}
+
+func (r *SubscriptionRepository) DeleteDiscount(ctx context.Context, subscriptionID, id uuid.UUID) error {
+	if err := r.SubscriptionRepository.DeleteDiscount(ctx, subscriptionID, id); err != nil {
+		return err
+	}
+	r.invalidateSubscription(ctx, subscriptionID)
+	return nil
+}
+
+// cacheKey - тенант, вид расчета и все его параметры.
+func cacheKey(ctx context.Context, kind string, req domain.CalculateTotalRequest) string {
+	params, _ := json.Marshal(req)
+	return tenantOf(ctx) + "\xff" + kind + "\xff" + string(params)
+}
+
+func tenantOf(ctx context.Context) string {
+	if tenant := tenancy.FromContext(ctx); tenant != nil {
+		return tenant.ID
+	}
+	return ""
+}
+
+func cloneByUser(byUser map[uuid.UUID]domain.Totals) map[uuid.UUID]domain.Totals {
+	clone := make(map[uuid.UUID]domain.Totals, len(byUser))
+	for user, totals := range byUser {
+		clone[user] = maps.Clone(totals)
+	}
+	return clone
+}
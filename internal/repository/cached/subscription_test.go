@@ -0,0 +1,210 @@
+package cached
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"aggregator_db/internal/repository/memory"
+	"aggregator_db/internal/repository/postgres"
+	"aggregator_db/internal/service"
+	"aggregator_db/internal/tenancy"
+	"aggregator_db/pkg/domain"
+	"github.com/google/uuid"
+)
+
+func TestCalculateTotalCache(t *testing.T) {
+	ctx := context.Background()
+	next := memory.NewSubscriptionRepository()
+	repo := NewSubscriptionRepository(next, Options{TTL: time.Minute, MaxEntries: 10})
+
+	alice, bob := uuid.New(), uuid.New()
+	subscribe := func(repo postgres.SubscriptionRepository, user uuid.UUID, price int64) {
+		t.Helper()
+		sub := &domain.Subscription{ID: uuid.New(), UserID: user, ServiceName: "Netflix", Price: domain.NewMoney(price, domain.DefaultCurrency), StartDate: "01-2025"}
+		if err := repo.Create(ctx, sub); err != nil {
+			t.Fatal(err)
+		}
+	}
+	total := func(ctx context.Context, user *uuid.UUID) int64 {
+		t.Helper()
+		totals, err := repo.CalculateTotal(ctx, domain.CalculateTotalRequest{UserID: user, StartPeriod: "01-2025", EndPeriod: "01-2025"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return totals.Get(domain.DefaultCurrency).Amount
+	}
+
+	subscribe(repo, alice, 100)
+	eventual, _ := postgres.WithFreshness(ctx, domain.FreshnessEventual)
+	if got := total(eventual, &alice); got != 100 {
+		t.Fatalf("alice total = %d, want 100", got)
+	}
+	if got := total(eventual, nil); got != 100 {
+		t.Fatalf("tenant total = %d, want 100", got)
+	}
+
+	// Запись мимо кэша не видна чтениям, допускающим отставание, но видна strong
+	subscribe(next, alice, 10)
+	if got := total(eventual, &alice); got != 100 {
+		t.Errorf("cached alice total = %d, want 100", got)
+	}
+	strong, _ := postgres.WithFreshness(ctx, domain.FreshnessStrong)
+	if got := total(strong, &alice); got != 110 {
+		t.Errorf("strong alice total = %d, want 110", got)
+	}
+
+	// Подписка Боба сбрасывает расчет тенанта, но не расчет Алисы
+	subscribe(next, alice, 1)
+	subscribe(repo, bob, 1000)
+	if got := total(eventual, &alice); got != 110 {
+		t.Errorf("alice total after bob's change = %d, want 110", got)
+	}
+	if got := total(eventual, nil); got != 1111 {
+		t.Errorf("tenant total after bob's change = %d, want 1111", got)
+	}
+
+	// Подписка Алисы сбрасывает ее расчет, а его возраст попадает в отставание ответа
+	subscribe(repo, alice, 1)
+	if got := total(eventual, &alice); got != 112 {
+		t.Errorf("alice total after her change = %d, want 112", got)
+	}
+	cachedCtx, stamp := postgres.WithFreshness(ctx, domain.FreshnessEventual)
+	time.Sleep(time.Millisecond)
+	total(cachedCtx, &alice)
+	if stamp.Lag() <= 0 {
+		t.Errorf("cache hit lag = %s, want positive", stamp.Lag())
+	}
+}
+
+func TestCacheInvalidatedByUserDelete(t *testing.T) {
+	ctx := context.Background()
+	next := memory.NewSubscriptionRepository()
+	repo := NewSubscriptionRepository(next, Options{TTL: time.Minute, MaxEntries: 10})
+	users := memory.NewUserRepository(next)
+	userService := service.NewUserService(users, repo, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	alice, bob := uuid.New(), uuid.New()
+	for user, price := range map[uuid.UUID]int64{alice: 100, bob: 10} {
+		if err := users.Create(ctx, &domain.User{ID: user, Role: domain.RoleUser}); err != nil {
+			t.Fatal(err)
+		}
+		sub := &domain.Subscription{ID: uuid.New(), UserID: user, ServiceName: "Netflix", Price: domain.NewMoney(price, domain.DefaultCurrency), StartDate: "01-2025"}
+		if err := repo.Create(ctx, sub); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	eventual, _ := postgres.WithFreshness(ctx, domain.FreshnessEventual)
+	req := domain.CalculateTotalRequest{StartPeriod: "01-2025", EndPeriod: "01-2025"}
+	total := func(user *uuid.UUID) int64 {
+		t.Helper()
+		req := req
+		req.UserID = user
+		totals, err := repo.CalculateTotal(eventual, req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return totals.Get(domain.DefaultCurrency).Amount
+	}
+	byUser := func() int {
+		t.Helper()
+		totals, err := repo.CalculateTotalByUser(eventual, domain.CalculateTotalRequest{UserIDs: []uuid.UUID{alice, bob}, StartPeriod: "01-2025", EndPeriod: "01-2025"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return len(totals)
+	}
+	if total(&alice) != 100 || total(nil) != 110 || byUser() != 2 {
+		t.Fatal("unexpected totals before delete")
+	}
+
+	// Подписки Алисы удаляются каскадом мимо репозитория подписок
+	if err := userService.Delete(ctx, alice); err != nil {
+		t.Fatal(err)
+	}
+	if got := total(&alice); got != 0 {
+		t.Errorf("alice total after delete = %d, want 0", got)
+	}
+	if got := total(nil); got != 10 {
+		t.Errorf("tenant total after delete = %d, want 10", got)
+	}
+	if got := byUser(); got != 1 {
+		t.Errorf("users in totals after delete = %d, want 1", got)
+	}
+	if got := total(&bob); got != 10 {
+		t.Errorf("bob total after delete = %d, want 10", got)
+	}
+}
+
+// dropProvisioner при удалении схемы удаляет подписки песочницы мимо кэша.
+type dropProvisioner struct {
+	postgres.TenantProvisioner
+	repo postgres.SubscriptionRepository
+	ids  []uuid.UUID
+}
+
+func (p *dropProvisioner) Deprovision(ctx context.Context, tenant *domain.Tenant) error {
+	ctx = tenancy.WithTenant(ctx, tenant)
+	for _, id := range p.ids {
+		if err := p.repo.Delete(ctx, id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func TestCacheInvalidatedBySandboxReset(t *testing.T) {
+	ctx := context.Background()
+	next := memory.NewSubscriptionRepository()
+	repo := NewSubscriptionRepository(next, Options{TTL: time.Minute, MaxEntries: 10})
+	provisioner := &dropProvisioner{TenantProvisioner: memory.NewTenantProvisioner(), repo: next}
+	sandbox := service.NewSandboxService(provisioner, repo, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	sandboxCtx := tenancy.WithTenant(ctx, domain.SandboxTenant())
+	user := uuid.New()
+	sub := &domain.Subscription{ID: uuid.New(), UserID: user, ServiceName: "Netflix", Price: domain.NewMoney(100, domain.DefaultCurrency), StartDate: "01-2025"}
+	if err := repo.Create(sandboxCtx, sub); err != nil {
+		t.Fatal(err)
+	}
+	provisioner.ids = append(provisioner.ids, sub.ID)
+
+	eventual, _ := postgres.WithFreshness(sandboxCtx, domain.FreshnessEventual)
+	req := domain.CalculateTotalRequest{StartPeriod: "01-2025", EndPeriod: "01-2025"}
+	total := func(user *uuid.UUID) int64 {
+		t.Helper()
+		req := req
+		req.UserID = user
+		totals, err := repo.CalculateTotal(eventual, req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return totals.Get(domain.DefaultCurrency).Amount
+	}
+	byUser := func() int {
+		t.Helper()
+		totals, err := repo.CalculateTotalByUser(eventual, domain.CalculateTotalRequest{UserIDs: []uuid.UUID{user}, StartPeriod: "01-2025", EndPeriod: "01-2025"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return len(totals)
+	}
+	if total(&user) != 100 || total(nil) != 100 || byUser() != 1 {
+		t.Fatal("unexpected totals before reset")
+	}
+
+	if err := sandbox.Reset(ctx, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	if got := total(&user); got != 0 {
+		t.Errorf("user total after reset = %d, want 0", got)
+	}
+	if got := total(nil); got != 0 {
+		t.Errorf("sandbox total after reset = %d, want 0", got)
+	}
+	if got := byUser(); got != 0 {
+		t.Errorf("users in totals after reset = %d, want 0", got)
+	}
+}
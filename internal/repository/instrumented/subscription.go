@@ -0,0 +1,338 @@
+package instrumented
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"aggregator_db/internal/repository/postgres"
+	"aggregator_db/internal/retryafter"
+	"aggregator_db/pkg/domain"
+	"aggregator_db/pkg/metrics"
+	"aggregator_db/pkg/tracing"
+	"github.com/google/uuid"
+)
+
+var (
+	queryDuration = metrics.NewHistogramVec(
+		"repository_query_duration_seconds",
+		"Длительность вызовов репозитория подписок; маршрут, тенант и хэш ключа API - из меток запроса",
+		nil,
+		"method", "status", "route", "tenant", "api_key",
+	)
+	queryRetries = metrics.NewCounterVec(
+		"repository_query_retries_total",
+		"Количество повторных попыток вызовов репозитория",
+		"method",
+	)
+	retryReasons = metrics.NewCounterVec(
+		"repository_retry_reasons_total",
+		"Повторы вызовов репозитория по виду временной ошибки: serialization, deadlock, connection",
+		"reason",
+	)
+	retriesExhausted = metrics.NewCounterVec(
+		"repository_retries_exhausted_total",
+		"Вызовы репозитория, не выполненные и после всех повторов",
+		"method",
+	)
+	slowQueries = metrics.NewCounterVec(
+		"repository_slow_queries_total",
+		"Количество медленных вызовов репозитория",
+		"method", "route", "tenant", "api_key",
+	)
+)
+
+type Options struct {
+	SlowQueryThreshold time.Duration
+	Retry              postgres.RetryPolicy
+	// Outage узнает о недоступности базы для подсказок Retry-After; может быть nil
+	Outage *retryafter.Outage
+}
+
+// subscriptionRepo оборачивает любой SubscriptionRepository метриками,
+// спанами, политикой повторов и логированием медленных вызовов.
+type subscriptionRepo struct {
+	next   postgres.SubscriptionRepository
+	logger *slog.Logger
+	opts   Options
+}
+
+func NewSubscriptionRepository(next postgres.SubscriptionRepository, logger *slog.Logger, opts Options) postgres.SubscriptionRepository {
+	return &subscriptionRepo{
+		next:   next,
+		logger: logger,
+		opts:   opts,
+	}
+}
+
+func (r *subscriptionRepo) Create(ctx context.Context, sub *domain.Subscription) error {
+	return r.observe(ctx, "Create", func(ctx context.Context) error {
+		return r.next.Create(ctx, sub)
+	})
+}
+
+func (r *subscriptionRepo) CreateBatch(ctx context.Context, subs []*domain.Subscription) error {
+	return r.observe(ctx, "CreateBatch", func(ctx context.Context) error {
+		return r.next.CreateBatch(ctx, subs)
+	})
+}
+
+func (r *subscriptionRepo) Upsert(ctx context.Context, sub *domain.Subscription) error {
+	return r.observe(ctx, "Upsert", func(ctx context.Context) error {
+		return r.next.Upsert(ctx, sub)
+	})
+}
+
+func (r *subscriptionRepo) GetByID(ctx context.Context, id uuid.UUID) (*domain.Subscription, error) {
+	var sub *domain.Subscription
+	err := r.observe(ctx, "GetByID", func(ctx context.Context) error {
+		var err error
+		sub, err = r.next.GetByID(ctx, id)
+		return err
+	})
+	return sub, err
+}
+
+func (r *subscriptionRepo) Update(ctx context.Context, sub *domain.Subscription) error {
+	return r.observe(ctx, "Update", func(ctx context.Context) error {
+		return r.next.Update(ctx, sub)
+	})
+}
+
+func (r *subscriptionRepo) Delete(ctx context.Context, id uuid.UUID) error {
+	return r.observe(ctx, "Delete", func(ctx context.Context) error {
+		return r.next.Delete(ctx, id)
+	})
+}
+
+func (r *subscriptionRepo) DeleteByFilter(ctx context.Context, filter domain.DeleteSubscriptionsFilter) (int, error) {
+	var deleted int
+	err := r.observe(ctx, "DeleteByFilter", func(ctx context.Context) error {
+		var err error
+		deleted, err = r.next.DeleteByFilter(ctx, filter)
+		return err
+	})
+	return deleted, err
+}
+
+func (r *subscriptionRepo) List(ctx context.Context, query domain.ListSubscriptionsQuery) ([]*domain.Subscription, error) {
+	var subs []*domain.Subscription
+	err := r.observe(ctx, "List", func(ctx context.Context) error {
+		var err error
+		subs, err = r.next.List(ctx, query)
+		return err
+	})
+	return subs, err
+}
+
+func (r *subscriptionRepo) Count(ctx context.Context, query domain.ListSubscriptionsQuery) (int, error) {
+	var total int
+	err := r.observe(ctx, "Count", func(ctx context.Context) error {
+		var err error
+		total, err = r.next.Count(ctx, query)
+		return err
+	})
+	return total, err
+}
+
+func (r *subscriptionRepo) CalculateTotal(ctx context.Context, req domain.CalculateTotalRequest) (domain.Totals, error) {
+	var total domain.Totals
+	err := r.observe(ctx, "CalculateTotal", func(ctx context.Context) error {
+		var err error
+		total, err = r.next.CalculateTotal(ctx, req)
+		return err
+	})
+	return total, err
+}
+
+func (r *subscriptionRepo) CalculateTotalByUser(ctx context.Context, req domain.CalculateTotalRequest) (map[uuid.UUID]domain.Totals, error) {
+	var totals map[uuid.UUID]domain.Totals
+	err := r.observe(ctx, "CalculateTotalByUser", func(ctx context.Context) error {
+		var err error
+		totals, err = r.next.CalculateTotalByUser(ctx, req)
+		return err
+	})
+	return totals, err
+}
+
+func (r *subscriptionRepo) ListRenewable(ctx context.Context, from, to string) ([]*domain.Subscription, error) {
+	var subs []*domain.Subscription
+	err := r.observe(ctx, "ListRenewable", func(ctx context.Context) error {
+		var err error
+		subs, err = r.next.ListRenewable(ctx, from, to)
+		return err
+	})
+	return subs, err
+}
+
+func (r *subscriptionRepo) Renew(ctx context.Context, id uuid.UUID, previousEnd, endDate string, renewedAt time.Time) error {
+	return r.observe(ctx, "Renew", func(ctx context.Context) error {
+		return r.next.Renew(ctx, id, previousEnd, endDate, renewedAt)
+	})
+}
+
+func (r *subscriptionRepo) Cancel(ctx context.Context, sub *domain.Subscription, change *domain.StatusChange) error {
+	return r.observe(ctx, "Cancel", func(ctx context.Context) error {
+		return r.next.Cancel(ctx, sub, change)
+	})
+}
+
+func (r *subscriptionRepo) ChangeStatus(ctx context.Context, change *domain.StatusChange) error {
+	return r.observe(ctx, "ChangeStatus", func(ctx context.Context) error {
+		return r.next.ChangeStatus(ctx, change)
+	})
+}
+
+func (r *subscriptionRepo) ListStatusChanges(ctx context.Context, subscriptionIDs []uuid.UUID) ([]*domain.StatusChange, error) {
+	var changes []*domain.StatusChange
+	err := r.observe(ctx, "ListStatusChanges", func(ctx context.Context) error {
+		var err error
+		changes, err = r.next.ListStatusChanges(ctx, subscriptionIDs)
+		return err
+	})
+	return changes, err
+}
+
+func (r *subscriptionRepo) ListHistory(ctx context.Context, req domain.CalculateTotalRequest) ([]*domain.Subscription, error) {
+	var subs []*domain.Subscription
+	err := r.observe(ctx, "ListHistory", func(ctx context.Context) error {
+		var err error
+		subs, err = r.next.ListHistory(ctx, req)
+		return err
+	})
+	return subs, err
+}
+
+func (r *subscriptionRepo) YearOverYear(ctx context.Context, req domain.YearOverYearRequest) ([]domain.YearOverYearUnits, error) {
+	var months []domain.YearOverYearUnits
+	err := r.observe(ctx, "YearOverYear", func(ctx context.Context) error {
+		var err error
+		months, err = r.next.YearOverYear(ctx, req)
+		return err
+	})
+	return months, err
+}
+
+func (r *subscriptionRepo) CreateDiscount(ctx context.Context, discount *domain.Discount) error {
+	return r.observe(ctx, "CreateDiscount", func(ctx context.Context) error {
+		return r.next.CreateDiscount(ctx, discount)
+	})
+}
+
+func (r *subscriptionRepo) ListDiscounts(ctx context.Context, subscriptionIDs []uuid.UUID) ([]*domain.Discount, error) {
+	var discounts []*domain.Discount
+	err := r.observe(ctx, "ListDiscounts", func(ctx context.Context) error {
+		var err error
+		discounts, err = r.next.ListDiscounts(ctx, subscriptionIDs)
+		return err
+	})
+	return discounts, err
+}
+
+func (r *subscriptionRepo) DeleteDiscount(ctx context.Context, subscriptionID, id uuid.UUID) error {
+	return r.observe(ctx, "DeleteDiscount", func(ctx context.Context) error {
+		return r.next.DeleteDiscount(ctx, subscriptionID, id)
+	})
+}
+
+func (r *subscriptionRepo) ListPriceHistory(ctx context.Context, subscriptionID uuid.UUID) ([]*domain.PriceChange, error) {
+	var changes []*domain.PriceChange
+	err := r.observe(ctx, "ListPriceHistory", func(ctx context.Context) error {
+		var err error
+		changes, err = r.next.ListPriceHistory(ctx, subscriptionID)
+		return err
+	})
+	return changes, err
+}
+
+func (r *subscriptionRepo) observe(ctx context.Context, method string, call func(ctx context.Context) error) error {
+	ctx, span := tracing.StartSpan(ctx, "repository."+method)
+	defer span.End()
+
+	start := time.Now()
+	err := r.withRetry(ctx, method, call)
+	duration := time.Since(start)
+	if err != nil && !errors.Is(err, postgres.ErrUnavailable) && postgres.IsUnavailable(err) {
+		err = fmt.Errorf("%w: %w", postgres.ErrUnavailable, err)
+	}
+	if r.opts.Outage != nil {
+		if errors.Is(err, postgres.ErrUnavailable) {
+			r.opts.Outage.Fail()
+		} else if err == nil {
+			r.opts.Outage.Recover()
+		}
+	}
+
+	status := "ok"
+	if err != nil && !errors.Is(err, postgres.ErrNotFound) && !errors.Is(err, postgres.ErrDiscountNotFound) {
+		status = "error"
+		span.RecordError(err)
+	}
+	labels := tracing.LabelsFromContext(ctx)
+	route, tenant, apiKey := labels.Get(tracing.LabelRoute), labels.Get(tracing.LabelTenant), labels.Get(tracing.LabelAPIKey)
+	queryDuration.Observe(duration.Seconds(), method, status, route, tenant, apiKey)
+
+	if r.opts.SlowQueryThreshold > 0 && duration >= r.opts.SlowQueryThreshold {
+		slowQueries.Inc(method, route, tenant, apiKey)
+		r.logger.WarnContext(ctx, "slow repository call",
+			slog.String("method", method),
+			slog.Duration("duration", duration),
+			slog.Duration("threshold", r.opts.SlowQueryThreshold),
+		)
+	}
+
+	return err
+}
+
+// readMethods только читают, поэтому повторяются и после обрыва соединения
+// посреди запроса.
+var readMethods = map[string]bool{
+	"GetByID":              true,
+	"List":                 true,
+	"Count":                true,
+	"CalculateTotal":       true,
+	"CalculateTotalByUser": true,
+	"ListRenewable":        true,
+	"ListStatusChanges":    true,
+	"ListHistory":          true,
+	"YearOverYear":         true,
+	"ListDiscounts":        true,
+	"ListPriceHistory":     true,
+}
+
+// withRetry повторяет вызов после временных ошибок. Внутри единицы работы вызов
+// не повторяется: ошибка уже откатила ее транзакцию, повторять нужно ее целиком.
+func (r *subscriptionRepo) withRetry(ctx context.Context, method string, call func(ctx context.Context) error) error {
+	if postgres.InTx(ctx) {
+		return call(ctx)
+	}
+
+	for attempt := 0; ; attempt++ {
+		err := call(ctx)
+		if err == nil || !postgres.IsRetryable(err, readMethods[method]) {
+			return err
+		}
+		if attempt >= r.opts.Retry.MaxRetries {
+			if attempt > 0 {
+				retriesExhausted.Inc(method)
+			}
+			return err
+		}
+
+		reason := postgres.RetryReason(err)
+		queryRetries.Inc(method)
+		retryReasons.Inc(reason)
+		r.logger.WarnContext(ctx, "retrying repository call",
+			slog.String("method", method),
+			slog.Int("attempt", attempt+1),
+			slog.String("reason", reason),
+			slog.String("error", err.Error()),
+		)
+
+		if !r.opts.Retry.Wait(ctx, attempt) {
+			return err
+		}
+	}
+}
@@ -0,0 +1,185 @@
+package instrumented
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"aggregator_db/internal/repository/postgres"
+	"aggregator_db/pkg/domain"
+	"aggregator_db/pkg/tracing"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// fakeRepo возвращает ошибки из errs по очереди, затем успех. Методы, которых
+// нет в этом файле, в тестах не вызываются.
+type fakeRepo struct {
+	postgres.SubscriptionRepository
+	errs  []error
+	delay time.Duration
+	calls int
+}
+
+func (r *fakeRepo) call() error {
+	r.calls++
+	time.Sleep(r.delay)
+	if len(r.errs) == 0 {
+		return nil
+	}
+	err := r.errs[0]
+	r.errs = r.errs[1:]
+	return err
+}
+
+func (r *fakeRepo) GetByID(context.Context, uuid.UUID) (*domain.Subscription, error) {
+	if err := r.call(); err != nil {
+		return nil, err
+	}
+	return &domain.Subscription{}, nil
+}
+
+func (r *fakeRepo) Create(context.Context, *domain.Subscription) error {
+	return r.call()
+}
+
+// fakeDB открывает транзакции, которые ничего не делают: этого достаточно,
+// чтобы контекст единицы работы считался транзакцией (postgres.InTx).
+type fakeDB struct {
+	postgres.DB
+}
+
+func (fakeDB) Begin(context.Context) (pgx.Tx, error) {
+	return fakeTx{}, nil
+}
+
+type fakeTx struct {
+	pgx.Tx
+}
+
+func (fakeTx) Commit(context.Context) error   { return nil }
+func (fakeTx) Rollback(context.Context) error { return nil }
+
+var (
+	errConnection    = &pgconn.PgError{Code: "08006"}
+	errSerialization = &pgconn.PgError{Code: "40001"}
+	errUnique        = &pgconn.PgError{Code: "23505"}
+)
+
+func newTestRepo(next postgres.SubscriptionRepository, logger *slog.Logger, opts Options) postgres.SubscriptionRepository {
+	if logger == nil {
+		logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+	}
+	return NewSubscriptionRepository(next, logger, opts)
+}
+
+func TestRetry(t *testing.T) {
+	policy := postgres.RetryPolicy{MaxRetries: 2}
+	tests := []struct {
+		name      string
+		method    string
+		errs      []error
+		wantCalls int
+		wantErr   error
+	}{
+		{name: "read after connection error", method: "GetByID", errs: []error{errConnection, errConnection}, wantCalls: 3},
+		{name: "write after connection error", method: "Create", errs: []error{errConnection}, wantCalls: 1, wantErr: postgres.ErrUnavailable},
+		{name: "write after serialization failure", method: "Create", errs: []error{errSerialization, errSerialization, errSerialization}, wantCalls: 3, wantErr: errSerialization},
+		{name: "read after unique violation", method: "GetByID", errs: []error{errUnique}, wantCalls: 1, wantErr: errUnique},
+		{name: "read after not found", method: "GetByID", errs: []error{postgres.ErrNotFound}, wantCalls: 1, wantErr: postgres.ErrNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			next := &fakeRepo{errs: tt.errs}
+			repo := newTestRepo(next, nil, Options{Retry: policy})
+			exhausted := retriesExhausted.Value(tt.method)
+
+			var err error
+			switch tt.method {
+			case "GetByID":
+				_, err = repo.GetByID(context.Background(), uuid.New())
+			case "Create":
+				err = repo.Create(context.Background(), &domain.Subscription{})
+			}
+			if tt.wantErr == nil && err != nil || tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Errorf("err = %v, want %v", err, tt.wantErr)
+			}
+			if next.calls != tt.wantCalls {
+				t.Errorf("calls = %d, want %d", next.calls, tt.wantCalls)
+			}
+			// Все повторы неудачны: вызов учитывается в repository_retries_exhausted_total
+			wantExhausted := 0.0
+			if tt.wantCalls > 1 && err != nil {
+				wantExhausted = 1
+			}
+			if got := retriesExhausted.Value(tt.method) - exhausted; got != wantExhausted {
+				t.Errorf("retries exhausted = %v, want %v", got, wantExhausted)
+			}
+		})
+	}
+}
+
+func TestNoRetryInTx(t *testing.T) {
+	next := &fakeRepo{errs: []error{errSerialization}}
+	repo := newTestRepo(next, nil, Options{Retry: postgres.RetryPolicy{MaxRetries: 2}})
+	unit := postgres.NewUnitOfWork(fakeDB{}, postgres.RetryPolicy{}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	// Повторять нужно единицу работы целиком: ошибка уже откатила ее транзакцию
+	err := unit.WithTx(context.Background(), func(ctx context.Context) error {
+		_, err := repo.GetByID(ctx, uuid.New())
+		return err
+	})
+	if !errors.Is(err, errSerialization) {
+		t.Errorf("err = %v, want serialization failure", err)
+	}
+	if next.calls != 1 {
+		t.Errorf("calls in unit of work = %d, want 1", next.calls)
+	}
+}
+
+func TestSlowCall(t *testing.T) {
+	var logs bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&logs, nil))
+	next := &fakeRepo{delay: 5 * time.Millisecond}
+	repo := newTestRepo(next, logger, Options{SlowQueryThreshold: time.Millisecond})
+
+	labels := &tracing.Labels{}
+	labels.Set(tracing.LabelRoute, "GET /test/slow")
+	labels.Set(tracing.LabelTenant, "acme")
+	ctx := tracing.ContextWithLabels(context.Background(), labels)
+	slow := slowQueries.Value("GetByID", "GET /test/slow", "acme", "")
+	observed, _ := queryDuration.Snapshot("GetByID", "ok", "GET /test/slow", "acme", "")
+	if _, err := repo.GetByID(ctx, uuid.New()); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := slowQueries.Value("GetByID", "GET /test/slow", "acme", "") - slow; got != 1 {
+		t.Errorf("slow queries = %v, want 1", got)
+	}
+	if count, _ := queryDuration.Snapshot("GetByID", "ok", "GET /test/slow", "acme", ""); count-observed != 1 {
+		t.Errorf("observed durations = %d, want 1", count-observed)
+	}
+	if !strings.Contains(logs.String(), "slow repository call") || !strings.Contains(logs.String(), "method=GetByID") {
+		t.Errorf("log = %q, want slow call warning", logs.String())
+	}
+
+	// Быстрый вызов не логируется
+	logs.Reset()
+	next.delay = 0
+	repo = newTestRepo(next, logger, Options{SlowQueryThreshold: time.Second})
+	if _, err := repo.GetByID(ctx, uuid.New()); err != nil {
+		t.Fatal(err)
+	}
+	if logs.Len() != 0 {
+		t.Errorf("fast call logged: %q", logs.String())
+	}
+	if got := slowQueries.Value("GetByID", "GET /test/slow", "acme", "") - slow; got != 1 {
+		t.Errorf("slow queries after fast call = %v, want 1", got)
+	}
+}
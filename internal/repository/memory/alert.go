@@ -0,0 +1,117 @@
+package memory
+
+import (
+	"context"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"aggregator_db/internal/repository/postgres"
+	"aggregator_db/pkg/domain"
+	"github.com/google/uuid"
+)
+
+type alertRepo struct {
+	mu     sync.RWMutex
+	alerts map[uuid.UUID]domain.Alert
+}
+
+func NewAlertRepository() postgres.AlertRepository {
+	return &alertRepo{alerts: make(map[uuid.UUID]domain.Alert)}
+}
+
+func (r *alertRepo) Create(_ context.Context, alert *domain.Alert) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.alerts[alert.ID]; ok {
+		return false, nil
+	}
+	stored := *alert
+	stored.Steps = slices.Clone(alert.Steps)
+	r.alerts[alert.ID] = stored
+	return true, nil
+}
+
+func (r *alertRepo) GetByID(_ context.Context, id uuid.UUID) (*domain.Alert, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	alert, ok := r.alerts[id]
+	if !ok {
+		return nil, postgres.ErrAlertNotFound
+	}
+	return &alert, nil
+}
+
+func (r *alertRepo) ListByUser(_ context.Context, userID uuid.UUID) ([]*domain.Alert, error) {
+	result := r.list(func(alert domain.Alert) bool { return alert.UserID == userID })
+	sort.Slice(result, func(i, j int) bool {
+		if !result[i].CreatedAt.Equal(result[j].CreatedAt) {
+			return result[i].CreatedAt.After(result[j].CreatedAt)
+		}
+		return result[i].ID.String() < result[j].ID.String()
+	})
+	return result, nil
+}
+
+func (r *alertRepo) ListDue(_ context.Context, now time.Time) ([]*domain.Alert, error) {
+	result := r.list(func(alert domain.Alert) bool {
+		return alert.Status == domain.AlertOpen && alert.NextEscalationAt != nil && !alert.NextEscalationAt.After(now)
+	})
+	sort.Slice(result, func(i, j int) bool {
+		if !result[i].NextEscalationAt.Equal(*result[j].NextEscalationAt) {
+			return result[i].NextEscalationAt.Before(*result[j].NextEscalationAt)
+		}
+		return result[i].ID.String() < result[j].ID.String()
+	})
+	return result, nil
+}
+
+func (r *alertRepo) list(match func(domain.Alert) bool) []*domain.Alert {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	result := make([]*domain.Alert, 0)
+	for _, alert := range r.alerts {
+		if match(alert) {
+			alert := alert
+			result = append(result, &alert)
+		}
+	}
+	return result
+}
+
+func (r *alertRepo) Advance(_ context.Context, alert *domain.Alert, from int) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, ok := r.alerts[alert.ID]
+	if !ok || stored.Status != domain.AlertOpen || stored.Step != from {
+		return false, nil
+	}
+	stored.Step = alert.Step
+	stored.EscalatedAt = alert.EscalatedAt
+	stored.NextEscalationAt = alert.NextEscalationAt
+	r.alerts[alert.ID] = stored
+	return true, nil
+}
+
+func (r *alertRepo) Acknowledge(_ context.Context, id uuid.UUID, by *uuid.UUID, at time.Time) (*domain.Alert, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	alert, ok := r.alerts[id]
+	if !ok {
+		return nil, postgres.ErrAlertNotFound
+	}
+	if alert.Status == domain.AlertOpen {
+		alert.Status = domain.AlertAcknowledged
+		alert.AcknowledgedAt = &at
+		alert.AcknowledgedBy = by
+		alert.NextEscalationAt = nil
+		r.alerts[id] = alert
+	}
+	return &alert, nil
+}
@@ -0,0 +1,73 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"aggregator_db/internal/repository/postgres"
+	"aggregator_db/pkg/domain"
+	"github.com/google/uuid"
+)
+
+type apiKeyRepo struct {
+	mu   sync.RWMutex
+	keys map[uuid.UUID]domain.APIKey
+}
+
+func NewAPIKeyRepository() postgres.APIKeyRepository {
+	return &apiKeyRepo{keys: make(map[uuid.UUID]domain.APIKey)}
+}
+
+func (r *apiKeyRepo) Create(_ context.Context, key *domain.APIKey) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.keys[key.ID] = *key
+	return nil
+}
+
+func (r *apiKeyRepo) GetBySecretHash(_ context.Context, hash string) (*domain.APIKey, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, key := range r.keys {
+		if key.SecretHash == hash && key.RevokedAt == nil {
+			return &key, nil
+		}
+	}
+	return nil, postgres.ErrAPIKeyNotFound
+}
+
+func (r *apiKeyRepo) List(_ context.Context) ([]*domain.APIKey, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	result := make([]*domain.APIKey, 0, len(r.keys))
+	for _, key := range r.keys {
+		key := key
+		result = append(result, &key)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		if !result[i].CreatedAt.Equal(result[j].CreatedAt) {
+			return result[i].CreatedAt.Before(result[j].CreatedAt)
+		}
+		return result[i].ID.String() < result[j].ID.String()
+	})
+	return result, nil
+}
+
+func (r *apiKeyRepo) Revoke(_ context.Context, id uuid.UUID, at time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key, ok := r.keys[id]
+	if !ok || key.RevokedAt != nil {
+		return postgres.ErrAPIKeyNotFound
+	}
+	key.RevokedAt = &at
+	r.keys[id] = key
+	return nil
+}
@@ -0,0 +1,106 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"aggregator_db/internal/repository/postgres"
+	"aggregator_db/pkg/domain"
+	"github.com/google/uuid"
+)
+
+type budgetRepo struct {
+	mu      sync.RWMutex
+	budgets map[uuid.UUID]domain.Budget
+}
+
+func NewBudgetRepository() postgres.BudgetRepository {
+	return &budgetRepo{budgets: make(map[uuid.UUID]domain.Budget)}
+}
+
+// categoryTaken повторяет уникальный индекс (user_id, category); вызывается под mu.
+func (r *budgetRepo) categoryTaken(budget *domain.Budget) bool {
+	for id, other := range r.budgets {
+		if id != budget.ID && other.UserID == budget.UserID && other.Category == budget.Category {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *budgetRepo) Create(_ context.Context, budget *domain.Budget) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.categoryTaken(budget) {
+		return postgres.ErrBudgetCategoryTaken
+	}
+	r.budgets[budget.ID] = *budget
+	return nil
+}
+
+func (r *budgetRepo) GetByID(_ context.Context, id uuid.UUID) (*domain.Budget, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	budget, ok := r.budgets[id]
+	if !ok {
+		return nil, postgres.ErrBudgetNotFound
+	}
+	return &budget, nil
+}
+
+func (r *budgetRepo) ListByUser(_ context.Context, userID uuid.UUID) ([]*domain.Budget, error) {
+	return r.list(func(budget *domain.Budget) bool { return budget.UserID == userID }), nil
+}
+
+func (r *budgetRepo) ListAll(_ context.Context) ([]*domain.Budget, error) {
+	return r.list(func(*domain.Budget) bool { return true }), nil
+}
+
+func (r *budgetRepo) list(match func(*domain.Budget) bool) []*domain.Budget {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	result := make([]*domain.Budget, 0)
+	for _, budget := range r.budgets {
+		budget := budget
+		if match(&budget) {
+			result = append(result, &budget)
+		}
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].UserID != result[j].UserID {
+			return result[i].UserID.String() < result[j].UserID.String()
+		}
+		return result[i].Category < result[j].Category
+	})
+	return result
+}
+
+func (r *budgetRepo) Update(_ context.Context, budget *domain.Budget) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.budgets[budget.ID]; !ok {
+		return postgres.ErrBudgetNotFound
+	}
+	if r.categoryTaken(budget) {
+		return postgres.ErrBudgetCategoryTaken
+	}
+	r.budgets[budget.ID] = *budget
+	return nil
+}
+
+func (r *budgetRepo) Delete(_ context.Context, id uuid.UUID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.budgets[id]; !ok {
+		return postgres.ErrBudgetNotFound
+	}
+	delete(r.budgets, id)
+	return nil
+}
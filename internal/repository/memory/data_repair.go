@@ -0,0 +1,138 @@
+package memory
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+
+	"aggregator_db/internal/repository/postgres"
+	"aggregator_db/pkg/domain"
+	"github.com/google/uuid"
+)
+
+// dataRepairRepo проверяет и исправляет подписки репозитория subs.
+type dataRepairRepo struct {
+	subs    *subscriptionRepo
+	repairs []domain.DataRepair
+}
+
+// NewDataRepairRepository принимает репозиторий подписок из этого пакета.
+func NewDataRepairRepository(subs postgres.SubscriptionRepository) postgres.DataRepairRepository {
+	return &dataRepairRepo{subs: subs.(*subscriptionRepo)}
+}
+
+func (r *dataRepairRepo) ScanRecords(_ context.Context, after uuid.UUID, limit int) ([]domain.DataRecord, error) {
+	r.subs.mu.RLock()
+	defer r.subs.mu.RUnlock()
+
+	records := make([]domain.DataRecord, 0)
+	for id, sub := range r.subs.subs {
+		if bytes.Compare(id[:], after[:]) <= 0 {
+			continue
+		}
+		_, userExists := r.subs.users[sub.UserID]
+		records = append(records, domain.DataRecord{
+			SubscriptionID: id,
+			UserID:         sub.UserID,
+			StartDate:      sub.StartDate,
+			EndDate:        sub.EndDate,
+			PriceMinor:     sub.Price.Amount,
+			UserExists:     userExists,
+		})
+	}
+	sort.Slice(records, func(i, j int) bool {
+		return bytes.Compare(records[i].SubscriptionID[:], records[j].SubscriptionID[:]) < 0
+	})
+	if len(records) > limit {
+		records = records[:limit]
+	}
+	return records, nil
+}
+
+func (r *dataRepairRepo) Apply(_ context.Context, repairs []*domain.DataRepair) ([]*domain.DataRepair, error) {
+	r.subs.mu.Lock()
+	defer r.subs.mu.Unlock()
+
+	// Как в транзакции postgres: сначала проверяются все исправления, затем применяются
+	next := make(map[uuid.UUID]domain.Subscription)
+	applied := make([]*domain.DataRepair, 0, len(repairs))
+	for _, repair := range repairs {
+		if repair.Fix == domain.FixProvisionUser {
+			applied = append(applied, repair)
+			continue
+		}
+		sub, ok := next[repair.SubscriptionID]
+		if !ok {
+			if sub, ok = r.subs.subs[repair.SubscriptionID]; !ok {
+				continue
+			}
+		}
+		switch repair.Field {
+		case "start_date":
+			if repair.Before == nil || sub.StartDate != *repair.Before || repair.After == nil {
+				continue
+			}
+			sub.StartDate = *repair.After
+		case "end_date":
+			if repair.Before == nil || sub.EndDate == nil || *sub.EndDate != *repair.Before {
+				continue
+			}
+			sub.EndDate = repair.After
+		case "price_minor":
+			if repair.Before == nil || strconv.FormatInt(sub.Price.Amount, 10) != *repair.Before || repair.After == nil {
+				continue
+			}
+			amount, err := strconv.ParseInt(*repair.After, 10, 64)
+			if err != nil {
+				return nil, err
+			}
+			sub.Price.Amount = amount
+		default:
+			return nil, fmt.Errorf("unsupported repair field %q", repair.Field)
+		}
+		sub.UpdatedAt = repair.AppliedAt
+		sub.Version++
+		next[repair.SubscriptionID] = sub
+		applied = append(applied, repair)
+	}
+
+	for id, sub := range next {
+		r.subs.subs[id] = sub
+	}
+	for _, repair := range applied {
+		if repair.Fix == domain.FixProvisionUser {
+			if _, ok := r.subs.users[repair.UserID]; !ok {
+				r.subs.users[repair.UserID] = domain.User{ID: repair.UserID, Role: domain.RoleUser, CreatedAt: repair.AppliedAt, UpdatedAt: repair.AppliedAt}
+			}
+		}
+		r.repairs = append(r.repairs, *repair)
+	}
+	return applied, nil
+}
+
+func (r *dataRepairRepo) ListRepairs(_ context.Context, query domain.ListDataRepairsQuery) ([]*domain.DataRepair, error) {
+	r.subs.mu.RLock()
+	defer r.subs.mu.RUnlock()
+
+	result := make([]*domain.DataRepair, 0, len(r.repairs))
+	for _, repair := range r.repairs {
+		repair := repair
+		result = append(result, &repair)
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		if !result[i].AppliedAt.Equal(result[j].AppliedAt) {
+			return result[i].AppliedAt.After(result[j].AppliedAt)
+		}
+		return result[i].ID.String() < result[j].ID.String()
+	})
+	if query.Offset >= len(result) {
+		return []*domain.DataRepair{}, nil
+	}
+	result = result[query.Offset:]
+	if len(result) > query.Limit {
+		result = result[:query.Limit]
+	}
+	return result, nil
+}
@@ -0,0 +1,61 @@
+package memory
+
+import (
+	"context"
+	"sync"
+
+	"aggregator_db/internal/repository/postgres"
+	"aggregator_db/pkg/domain"
+	"github.com/google/uuid"
+)
+
+type developerAppRepo struct {
+	mu   sync.RWMutex
+	apps map[uuid.UUID]domain.DeveloperApp
+}
+
+func NewDeveloperAppRepository() postgres.DeveloperAppRepository {
+	return &developerAppRepo{apps: make(map[uuid.UUID]domain.DeveloperApp)}
+}
+
+func (r *developerAppRepo) Create(_ context.Context, app *domain.DeveloperApp) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.apps[app.ID] = *app
+	return nil
+}
+
+func (r *developerAppRepo) Get(_ context.Context, id uuid.UUID) (*domain.DeveloperApp, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	app, ok := r.apps[id]
+	if !ok {
+		return nil, postgres.ErrDeveloperAppNotFound
+	}
+	return &app, nil
+}
+
+func (r *developerAppRepo) GetBySecretHash(_ context.Context, hash string) (*domain.DeveloperApp, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, app := range r.apps {
+		if app.SecretHash == hash {
+			return &app, nil
+		}
+	}
+	return nil, postgres.ErrDeveloperAppNotFound
+}
+
+func (r *developerAppRepo) Update(_ context.Context, app *domain.DeveloperApp) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.apps[app.ID]; !ok {
+		return postgres.ErrDeveloperAppNotFound
+	}
+	r.apps[app.ID] = *app
+	return nil
+}
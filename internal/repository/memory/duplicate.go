@@ -0,0 +1,64 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"aggregator_db/internal/repository/postgres"
+	"aggregator_db/pkg/domain"
+	"github.com/google/uuid"
+)
+
+type duplicateKey struct {
+	userID     uuid.UUID
+	serviceKey string
+}
+
+type duplicateRepo struct {
+	mu    sync.RWMutex
+	flags map[duplicateKey]domain.DuplicateFlag
+}
+
+func NewDuplicateRepository() postgres.DuplicateRepository {
+	return &duplicateRepo{flags: make(map[duplicateKey]domain.DuplicateFlag)}
+}
+
+func (r *duplicateRepo) Replace(_ context.Context, flags []*domain.DuplicateFlag, now time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	next := make(map[duplicateKey]domain.DuplicateFlag, len(flags))
+	for _, flag := range flags {
+		key := duplicateKey{userID: flag.UserID, serviceKey: flag.ServiceKey}
+		stored := *flag
+		stored.DetectedAt = now
+		if existing, ok := r.flags[key]; ok {
+			stored.DetectedAt = existing.DetectedAt
+		}
+		next[key] = stored
+	}
+	r.flags = next
+	return nil
+}
+
+func (r *duplicateRepo) ListByUser(_ context.Context, userID uuid.UUID) ([]*domain.DuplicateFlag, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	result := make([]*domain.DuplicateFlag, 0)
+	for key, flag := range r.flags {
+		flag := flag
+		if key.userID == userID {
+			result = append(result, &flag)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if !result[i].DetectedAt.Equal(result[j].DetectedAt) {
+			return result[i].DetectedAt.Before(result[j].DetectedAt)
+		}
+		return result[i].ServiceKey < result[j].ServiceKey
+	})
+	return result, nil
+}
@@ -0,0 +1,139 @@
+package memory
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"sync"
+	"time"
+
+	"aggregator_db/internal/repository/postgres"
+	"aggregator_db/pkg/domain"
+	"github.com/google/uuid"
+)
+
+type exportJobRepo struct {
+	mu   sync.Mutex
+	jobs map[uuid.UUID]domain.ExportJob
+}
+
+func NewExportJobRepository() postgres.ExportJobRepository {
+	return &exportJobRepo{jobs: make(map[uuid.UUID]domain.ExportJob)}
+}
+
+func (r *exportJobRepo) Create(_ context.Context, job *domain.ExportJob) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.jobs[job.ID] = *job
+	return nil
+}
+
+func (r *exportJobRepo) CreateOnce(_ context.Context, job *domain.ExportJob) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.jobs[job.ID]; ok {
+		return false, nil
+	}
+	r.jobs[job.ID] = *job
+	return true, nil
+}
+
+func (r *exportJobRepo) Get(_ context.Context, id uuid.UUID) (*domain.ExportJob, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	job, ok := r.jobs[id]
+	if !ok {
+		return nil, postgres.ErrExportNotFound
+	}
+	return &job, nil
+}
+
+func (r *exportJobRepo) Claim(_ context.Context, limit int, now, staleBefore time.Time) ([]*domain.ExportJob, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	claimable := make([]domain.ExportJob, 0)
+	for _, job := range r.jobs {
+		if job.Status == domain.ExportPending || (job.Status == domain.ExportProcessing && job.UpdatedAt.Before(staleBefore)) {
+			claimable = append(claimable, job)
+		}
+	}
+	sort.Slice(claimable, func(i, j int) bool { return claimable[i].CreatedAt.Before(claimable[j].CreatedAt) })
+	if len(claimable) > limit {
+		claimable = claimable[:limit]
+	}
+
+	jobs := make([]*domain.ExportJob, 0, len(claimable))
+	for _, job := range claimable {
+		job.Status = domain.ExportProcessing
+		job.Attempts++
+		job.UpdatedAt = now
+		r.jobs[job.ID] = job
+		jobs = append(jobs, &job)
+	}
+	return jobs, nil
+}
+
+func (r *exportJobRepo) Update(_ context.Context, job *domain.ExportJob) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.jobs[job.ID]; !ok {
+		return postgres.ErrExportNotFound
+	}
+	r.jobs[job.ID] = *job
+	return nil
+}
+
+func (r *exportJobRepo) PurgeExpired(_ context.Context, now time.Time) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	purged := 0
+	for id, job := range r.jobs {
+		if job.Content != nil && job.ExpiresAt != nil && job.ExpiresAt.Before(now) {
+			job.Content = nil
+			r.jobs[id] = job
+			purged++
+		}
+	}
+	return purged, nil
+}
+
+func (r *exportJobRepo) Backlog(_ context.Context) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	backlog := 0
+	for _, job := range r.jobs {
+		if job.Status == domain.ExportPending || job.Status == domain.ExportProcessing {
+			backlog++
+		}
+	}
+	return backlog, nil
+}
+
+func (r *exportJobRepo) ListStatements(_ context.Context, tenantID string, userID uuid.UUID, limit int) ([]*domain.ExportJob, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	jobs := make([]*domain.ExportJob, 0)
+	for _, job := range r.jobs {
+		var params domain.StatementParams
+		if job.Kind != domain.ExportStatement || json.Unmarshal(job.Params, &params) != nil {
+			continue
+		}
+		if params.TenantID == tenantID && params.UserID == userID {
+			job := job
+			jobs = append(jobs, &job)
+		}
+	}
+	sort.Slice(jobs, func(i, j int) bool { return jobs[i].CreatedAt.After(jobs[j].CreatedAt) })
+	if len(jobs) > limit {
+		jobs = jobs[:limit]
+	}
+	return jobs, nil
+}
@@ -0,0 +1,70 @@
+package memory
+
+import (
+	"context"
+	"slices"
+	"sort"
+	"sync"
+
+	"aggregator_db/internal/repository/postgres"
+	"aggregator_db/pkg/domain"
+	"github.com/google/uuid"
+)
+
+type notificationSettingsRepo struct {
+	mu       sync.RWMutex
+	settings map[uuid.UUID]domain.NotificationSettings
+}
+
+func NewNotificationSettingsRepository() postgres.NotificationSettingsRepository {
+	return &notificationSettingsRepo{settings: make(map[uuid.UUID]domain.NotificationSettings)}
+}
+
+func (r *notificationSettingsRepo) Get(_ context.Context, userID uuid.UUID) (*domain.NotificationSettings, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	settings, ok := r.settings[userID]
+	if !ok {
+		return &domain.NotificationSettings{UserID: userID, StatementChannel: domain.StatementNone}, nil
+	}
+	return &settings, nil
+}
+
+func (r *notificationSettingsRepo) Upsert(_ context.Context, settings *domain.NotificationSettings) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored := *settings
+	stored.Escalation = slices.Clone(settings.Escalation)
+	r.settings[settings.UserID] = stored
+	return nil
+}
+
+func (r *notificationSettingsRepo) ListSpendAlertsEnabled(_ context.Context) ([]*domain.NotificationSettings, error) {
+	return r.list(func(settings domain.NotificationSettings) bool { return settings.SpendAlerts }), nil
+}
+
+func (r *notificationSettingsRepo) ListStatementRecipients(_ context.Context) ([]*domain.NotificationSettings, error) {
+	return r.list(func(settings domain.NotificationSettings) bool {
+		return settings.StatementChannel != "" && settings.StatementChannel != domain.StatementNone
+	}), nil
+}
+
+func (r *notificationSettingsRepo) list(match func(domain.NotificationSettings) bool) []*domain.NotificationSettings {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	result := make([]*domain.NotificationSettings, 0)
+	for _, settings := range r.settings {
+		if match(settings) {
+			settings := settings
+			result = append(result, &settings)
+		}
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].UserID.String() < result[j].UserID.String()
+	})
+	return result
+}
@@ -0,0 +1,73 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"aggregator_db/internal/repository/postgres"
+	"aggregator_db/pkg/domain"
+	"github.com/google/uuid"
+)
+
+type nudgeRepo struct {
+	mu     sync.RWMutex
+	nudges map[uuid.UUID]domain.Nudge
+}
+
+func NewNudgeRepository() postgres.NudgeRepository {
+	return &nudgeRepo{nudges: make(map[uuid.UUID]domain.Nudge)}
+}
+
+func (r *nudgeRepo) Create(_ context.Context, nudge *domain.Nudge) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, existing := range r.nudges {
+		if existing.Key == nudge.Key {
+			return false, nil
+		}
+	}
+	r.nudges[nudge.ID] = *nudge
+	return true, nil
+}
+
+func (r *nudgeRepo) List(_ context.Context, query domain.ListNudgesQuery) ([]*domain.Nudge, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	result := make([]*domain.Nudge, 0)
+	for _, nudge := range r.nudges {
+		nudge := nudge
+		if query.Kind != "" && nudge.Kind != query.Kind {
+			continue
+		}
+		if !query.IncludeDismissed && nudge.DismissedAt != nil {
+			continue
+		}
+		result = append(result, &nudge)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if !result[i].CreatedAt.Equal(result[j].CreatedAt) {
+			return result[i].CreatedAt.After(result[j].CreatedAt)
+		}
+		return result[i].Key < result[j].Key
+	})
+	return result, nil
+}
+
+func (r *nudgeRepo) Dismiss(_ context.Context, id uuid.UUID, at time.Time) (*domain.Nudge, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	nudge, ok := r.nudges[id]
+	if !ok {
+		return nil, postgres.ErrNudgeNotFound
+	}
+	if nudge.DismissedAt == nil {
+		nudge.DismissedAt = &at
+		r.nudges[id] = nudge
+	}
+	return &nudge, nil
+}
@@ -0,0 +1,93 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"aggregator_db/internal/repository/postgres"
+	"aggregator_db/pkg/domain"
+)
+
+type serviceAliasRepo struct {
+	mu      sync.RWMutex
+	aliases map[string]domain.ServiceAlias
+}
+
+func NewServiceAliasRepository() postgres.ServiceAliasRepository {
+	return &serviceAliasRepo{aliases: make(map[string]domain.ServiceAlias)}
+}
+
+func (r *serviceAliasRepo) Upsert(_ context.Context, alias *domain.ServiceAlias) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for key, existing := range r.aliases {
+		if existing.CanonicalKey == alias.AliasKey {
+			existing.CanonicalKey = alias.CanonicalKey
+			existing.Canonical = alias.Canonical
+			r.aliases[key] = existing
+		}
+	}
+
+	if existing, ok := r.aliases[alias.AliasKey]; ok {
+		alias.CreatedAt = existing.CreatedAt
+	}
+	r.aliases[alias.AliasKey] = *alias
+	return nil
+}
+
+func (r *serviceAliasRepo) Get(_ context.Context, aliasKey string) (*domain.ServiceAlias, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	alias, ok := r.aliases[aliasKey]
+	if !ok {
+		return nil, postgres.ErrAliasNotFound
+	}
+	return &alias, nil
+}
+
+func (r *serviceAliasRepo) Delete(_ context.Context, aliasKey string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.aliases[aliasKey]; !ok {
+		return postgres.ErrAliasNotFound
+	}
+	delete(r.aliases, aliasKey)
+	return nil
+}
+
+func (r *serviceAliasRepo) List(_ context.Context) ([]*domain.ServiceAlias, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	aliases := make([]*domain.ServiceAlias, 0, len(r.aliases))
+	for _, alias := range r.aliases {
+		alias := alias
+		aliases = append(aliases, &alias)
+	}
+
+	sort.Slice(aliases, func(i, j int) bool {
+		if aliases[i].CanonicalKey != aliases[j].CanonicalKey {
+			return aliases[i].CanonicalKey < aliases[j].CanonicalKey
+		}
+		return aliases[i].AliasKey < aliases[j].AliasKey
+	})
+	return aliases, nil
+}
+
+func (r *serviceAliasRepo) AliasKeys(_ context.Context, canonicalKey string) ([]string, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	keys := make([]string, 0)
+	for key, alias := range r.aliases {
+		if alias.CanonicalKey == canonicalKey {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
@@ -0,0 +1,33 @@
+package memory
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"aggregator_db/internal/repository/postgres"
+)
+
+type statementClosingRepo struct {
+	mu     sync.RWMutex
+	closed map[time.Time]bool
+}
+
+func NewStatementClosingRepository() postgres.StatementClosingRepository {
+	return &statementClosingRepo{closed: make(map[time.Time]bool)}
+}
+
+func (r *statementClosingRepo) Closed(_ context.Context, month time.Time) (bool, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.closed[month.UTC()], nil
+}
+
+func (r *statementClosingRepo) Close(_ context.Context, month time.Time, _ int, _ time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.closed[month.UTC()] = true
+	return nil
+}
@@ -0,0 +1,772 @@
+package memory
+
+import (
+	"cmp"
+	"context"
+	"maps"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"aggregator_db/internal/repository/postgres"
+	"aggregator_db/pkg/domain"
+	"aggregator_db/pkg/region"
+	"github.com/google/uuid"
+)
+
+// subscriptionRepo - потокобезопасная реализация репозитория в памяти.
+// Повторяет семантику postgres-реализации и используется в тестах.
+type subscriptionRepo struct {
+	mu        sync.RWMutex
+	subs      map[uuid.UUID]domain.Subscription
+	changes   map[uuid.UUID][]*domain.StatusChange
+	discounts map[uuid.UUID][]*domain.Discount
+	prices    map[uuid.UUID][]*domain.PriceChange
+	// users - пользователи подписок, см. NewUserRepository
+	users map[uuid.UUID]domain.User
+}
+
+func NewSubscriptionRepository() postgres.SubscriptionRepository {
+	return &subscriptionRepo{
+		subs:      make(map[uuid.UUID]domain.Subscription),
+		changes:   make(map[uuid.UUID][]*domain.StatusChange),
+		discounts: make(map[uuid.UUID][]*domain.Discount),
+		prices:    make(map[uuid.UUID][]*domain.PriceChange),
+		users:     make(map[uuid.UUID]domain.User),
+	}
+}
+
+func (r *subscriptionRepo) Create(ctx context.Context, sub *domain.Subscription) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.subs[sub.ID]; ok {
+		return postgres.ErrAlreadyExists
+	}
+	if sub.Status == "" {
+		sub.Status = domain.StatusActive
+	}
+	sub.BillingCycle = sub.BillingCycle.OrDefault()
+	sub.Tags = cloneTags(sub.Tags)
+	sub.CustomFields = maps.Clone(sub.CustomFields)
+	sub.Region = region.FromContext(ctx)
+	sub.Version = 1
+	r.provisionUser(sub)
+	r.subs[sub.ID] = *sub
+	r.recordPrice(sub, sub.CreatedAt)
+	return nil
+}
+
+func (r *subscriptionRepo) CreateBatch(ctx context.Context, subs []*domain.Subscription) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	seen := make(map[uuid.UUID]bool, len(subs))
+	for _, sub := range subs {
+		if _, ok := r.subs[sub.ID]; ok || seen[sub.ID] {
+			return postgres.ErrAlreadyExists
+		}
+		seen[sub.ID] = true
+	}
+	for _, sub := range subs {
+		if sub.Status == "" {
+			sub.Status = domain.StatusActive
+		}
+		sub.BillingCycle = sub.BillingCycle.OrDefault()
+		sub.Tags = cloneTags(sub.Tags)
+		sub.CustomFields = maps.Clone(sub.CustomFields)
+		sub.Region = region.FromContext(ctx)
+		sub.Version = 1
+		r.provisionUser(sub)
+		r.subs[sub.ID] = *sub
+		r.recordPrice(sub, sub.CreatedAt)
+	}
+	return nil
+}
+
+// Upsert записывает подписку целиком, создавая ее при отсутствии.
+func (r *subscriptionRepo) Upsert(ctx context.Context, sub *domain.Subscription) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	sub.BillingCycle = sub.BillingCycle.OrDefault()
+	sub.Tags = cloneTags(sub.Tags)
+	sub.CustomFields = maps.Clone(sub.CustomFields)
+	sub.Region = region.FromContext(ctx)
+	sub.Version = r.subs[sub.ID].Version + 1
+	r.provisionUser(sub)
+	r.subs[sub.ID] = *sub
+	r.recordPrice(sub, sub.UpdatedAt)
+	return nil
+}
+
+// provisionUser заводит пользователя подписки, если его еще нет; вызывается под mu.
+func (r *subscriptionRepo) provisionUser(sub *domain.Subscription) {
+	if _, ok := r.users[sub.UserID]; !ok {
+		r.users[sub.UserID] = domain.User{ID: sub.UserID, Role: domain.RoleUser, CreatedAt: sub.CreatedAt, UpdatedAt: sub.CreatedAt}
+	}
+}
+
+// cloneTags копирует метки, чтобы вызывающий код не менял сохраненную подписку;
+// как и в postgres, отсутствие меток хранится пустым списком.
+func cloneTags(tags []string) []string {
+	if tags == nil {
+		return []string{}
+	}
+	return slices.Clone(tags)
+}
+
+func (r *subscriptionRepo) GetByID(_ context.Context, id uuid.UUID) (*domain.Subscription, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	sub, ok := r.subs[id]
+	if !ok {
+		return nil, postgres.ErrNotFound
+	}
+	return &sub, nil
+}
+
+func (r *subscriptionRepo) Update(ctx context.Context, sub *domain.Subscription) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	existing, ok := r.subs[sub.ID]
+	if !ok {
+		return postgres.ErrNotFound
+	}
+	if existing.Version != sub.Version {
+		return postgres.ErrVersionConflict
+	}
+
+	existing.ServiceName = sub.ServiceName
+	existing.Price = sub.Price
+	existing.StartDate = sub.StartDate
+	existing.EndDate = sub.EndDate
+	existing.AutoRenew = sub.AutoRenew
+	existing.BillingCycle = sub.BillingCycle.OrDefault()
+	existing.Tags = cloneTags(sub.Tags)
+	existing.Notes = sub.Notes
+	existing.CustomFields = maps.Clone(sub.CustomFields)
+	existing.UpdatedAt = sub.UpdatedAt
+	existing.Region = region.FromContext(ctx)
+	existing.Version++
+	sub.Region, sub.Version = existing.Region, existing.Version
+	r.subs[sub.ID] = existing
+	r.recordPrice(&existing, sub.UpdatedAt)
+	return nil
+}
+
+// recordPrice пишет цену подписки в историю, если она отличается от последней
+// записи; вызывается под mu.
+func (r *subscriptionRepo) recordPrice(sub *domain.Subscription, at time.Time) {
+	history := r.prices[sub.ID]
+	if n := len(history); n > 0 {
+		last := history[n-1]
+		if last.Price.Amount == sub.Price.Amount && last.Price.Currency == sub.Price.Currency && last.BillingCycle == sub.BillingCycle.OrDefault() {
+			return
+		}
+	}
+	r.prices[sub.ID] = append(history, &domain.PriceChange{
+		SubscriptionID: sub.ID,
+		Price:          sub.Price,
+		BillingCycle:   sub.BillingCycle.OrDefault(),
+		ChangedAt:      at,
+	})
+}
+
+func (r *subscriptionRepo) Delete(_ context.Context, id uuid.UUID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.subs[id]; !ok {
+		return postgres.ErrNotFound
+	}
+	delete(r.subs, id)
+	delete(r.changes, id)
+	delete(r.discounts, id)
+	delete(r.prices, id)
+	return nil
+}
+
+func (r *subscriptionRepo) DeleteByFilter(_ context.Context, filter domain.DeleteSubscriptionsFilter) (int, error) {
+	var endedBefore time.Time
+	if filter.EndedBefore != nil {
+		var err error
+		if endedBefore, err = domain.ParsePeriod(*filter.EndedBefore); err != nil {
+			return 0, err
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	deleted := 0
+	for id, sub := range r.subs {
+		if filter.UserID != nil && sub.UserID != *filter.UserID {
+			continue
+		}
+		if !matchesService(sub, filter.ServiceName, filter.ServiceKeys) {
+			continue
+		}
+		if filter.EndedBefore != nil {
+			if sub.EndDate == nil {
+				continue
+			}
+			end, err := domain.ParsePeriod(*sub.EndDate)
+			if err != nil || !end.Before(endedBefore) {
+				continue
+			}
+		}
+		delete(r.subs, id)
+		delete(r.changes, id)
+		delete(r.discounts, id)
+		delete(r.prices, id)
+		deleted++
+	}
+	return deleted, nil
+}
+
+func matchesListQuery(sub domain.Subscription, query domain.ListSubscriptionsQuery) bool {
+	if query.UserID != nil && sub.UserID != *query.UserID {
+		return false
+	}
+	if query.Status != nil && sub.Status != *query.Status {
+		return false
+	}
+	if !hasTags(sub, query.Tags) {
+		return false
+	}
+	if !matchesSearch(sub, query.Q, query.Match) {
+		return false
+	}
+	if query.Currency != "" && sub.Price.Currency != query.Currency {
+		return false
+	}
+	if query.MinPriceMinor != nil && sub.Price.Amount < *query.MinPriceMinor {
+		return false
+	}
+	if query.MaxPriceMinor != nil && sub.Price.Amount > *query.MaxPriceMinor {
+		return false
+	}
+	if query.ActiveMonth != nil && !activeIn(sub, *query.ActiveMonth) {
+		return false
+	}
+	if !hasCustomFields(sub, query.CustomFieldFilter) {
+		return false
+	}
+	if query.CreatedBefore != nil && sub.CreatedAt.After(*query.CreatedBefore) {
+		return false
+	}
+	return matchesService(sub, query.ServiceName, query.ServiceKeys)
+}
+
+func matchesService(sub domain.Subscription, name *string, keys []string) bool {
+	if len(keys) > 0 {
+		return slices.Contains(keys, domain.ServiceKey(sub.ServiceName))
+	}
+	return name == nil || sub.ServiceName == *name
+}
+
+// hasTags повторяет tags @> $n: у подписки есть все метки фильтра.
+func hasTags(sub domain.Subscription, tags []string) bool {
+	for _, tag := range tags {
+		if !slices.Contains(sub.Tags, tag) {
+			return false
+		}
+	}
+	return true
+}
+
+// activeIn повторяет условие по start_month и end_month: подписка действует в месяце month.
+func activeIn(sub domain.Subscription, month time.Time) bool {
+	start, err := domain.ParsePeriod(sub.StartDate)
+	if err != nil || start.After(month) {
+		return false
+	}
+	if sub.EndDate == nil {
+		return true
+	}
+	end, err := domain.ParsePeriod(*sub.EndDate)
+	return err == nil && !end.Before(month)
+}
+
+// hasCustomFields повторяет custom_fields @> $n: у подписки есть все значения фильтра.
+func hasCustomFields(sub domain.Subscription, filter domain.CustomFieldValues) bool {
+	for name, value := range filter {
+		if got, ok := sub.CustomFields[name]; !ok || got != value {
+			return false
+		}
+	}
+	return true
+}
+
+// matchesSearch повторяет ILIKE по названию и заметке: каждое слово q встречается без учета регистра.
+// С fuzzy повторяет оператор % из pg_trgm с порогом по умолчанию.
+func matchesSearch(sub domain.Subscription, q string, match domain.SearchMatch) bool {
+	if match == domain.SearchFuzzy {
+		q = strings.TrimSpace(q)
+		return q == "" || domain.TrigramSimilarity(sub.ServiceName, q) >= domain.DefaultSimilarityThreshold
+	}
+	text := sub.ServiceName
+	if sub.Notes != nil {
+		text += " " + *sub.Notes
+	}
+	text = strings.ToLower(text)
+	for _, word := range strings.Fields(q) {
+		if !strings.Contains(text, strings.ToLower(word)) {
+			return false
+		}
+	}
+	return true
+}
+
+func (r *subscriptionRepo) List(_ context.Context, query domain.ListSubscriptionsQuery) ([]*domain.Subscription, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	matched := make([]*domain.Subscription, 0)
+	for _, sub := range r.subs {
+		if !matchesListQuery(sub, query) {
+			continue
+		}
+		sub := sub
+		matched = append(matched, &sub)
+	}
+
+	field, order := query.Sort()
+	sort.Slice(matched, func(i, j int) bool {
+		c := compareListField(matched[i], matched[j], field)
+		if order == domain.SortDesc {
+			c = -c
+		}
+		if c == 0 {
+			return matched[i].ID.String() < matched[j].ID.String()
+		}
+		return c < 0
+	})
+	if query.After != nil {
+		after := *query.After
+		matched = slices.DeleteFunc(matched, func(sub *domain.Subscription) bool {
+			next := sub.CreatedAt.Before(after.CreatedAt) ||
+				sub.CreatedAt.Equal(after.CreatedAt) && sub.ID.String() > after.ID.String()
+			return !next
+		})
+	}
+
+	limit := query.Limit
+	if limit <= 0 {
+		limit = 100
+	}
+	if query.Offset >= len(matched) {
+		return make([]*domain.Subscription, 0), nil
+	}
+	matched = matched[query.Offset:]
+	if len(matched) > limit {
+		matched = matched[:limit]
+	}
+
+	return matched, nil
+}
+
+// compareListField сравнивает подписки по полю сортировки списка так же, как
+// колонки listSortColumns в Postgres.
+func compareListField(a, b *domain.Subscription, field domain.ListSortField) int {
+	switch field {
+	case domain.SortByPrice:
+		return cmp.Compare(a.Price.Amount, b.Price.Amount)
+	case domain.SortByStartDate:
+		startA, _ := domain.ParsePeriod(a.StartDate)
+		startB, _ := domain.ParsePeriod(b.StartDate)
+		return startA.Compare(startB)
+	case domain.SortByServiceName:
+		return cmp.Compare(a.ServiceName, b.ServiceName)
+	default:
+		return a.CreatedAt.Compare(b.CreatedAt)
+	}
+}
+
+func (r *subscriptionRepo) Count(_ context.Context, query domain.ListSubscriptionsQuery) (int, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	total := 0
+	for _, sub := range r.subs {
+		if matchesListQuery(sub, query) {
+			total++
+		}
+	}
+	return total, nil
+}
+
+// matchesTotalUser проверяет фильтры user_id и user_ids расчета стоимости.
+func matchesTotalUser(sub domain.Subscription, req domain.CalculateTotalRequest) bool {
+	if req.UserID != nil && sub.UserID != *req.UserID {
+		return false
+	}
+	return len(req.UserIDs) == 0 || slices.Contains(req.UserIDs, sub.UserID)
+}
+
+func (r *subscriptionRepo) CalculateTotal(_ context.Context, req domain.CalculateTotalRequest) (domain.Totals, error) {
+	totals, err := r.calculateTotals(req, false)
+	if err != nil {
+		return nil, err
+	}
+	if total, ok := totals[uuid.Nil]; ok {
+		return total, nil
+	}
+	return domain.Totals{}, nil
+}
+
+func (r *subscriptionRepo) CalculateTotalByUser(_ context.Context, req domain.CalculateTotalRequest) (map[uuid.UUID]domain.Totals, error) {
+	return r.calculateTotals(req, true)
+}
+
+// calculateTotals считает суммы по пользователям при byUser, иначе одну сумму под uuid.Nil.
+func (r *subscriptionRepo) calculateTotals(req domain.CalculateTotalRequest, byUser bool) (map[uuid.UUID]domain.Totals, error) {
+	periodStart, err := domain.ParsePeriod(req.StartPeriod)
+	if err != nil {
+		return nil, err
+	}
+	periodEnd, err := domain.ParsePeriod(req.EndPeriod)
+	if err != nil {
+		return nil, err
+	}
+	openEnd, err := domain.ParsePeriod(req.OpenEndedEnd())
+	if err != nil {
+		return nil, err
+	}
+	if openEnd.After(periodEnd) {
+		openEnd = periodEnd
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	units := make(map[uuid.UUID]map[domain.Currency]int64)
+	for _, sub := range r.subs {
+		if !matchesTotalUser(sub, req) {
+			continue
+		}
+		if !matchesService(sub, req.ServiceName, req.ServiceKeys) {
+			continue
+		}
+		if req.Currency != "" && sub.Price.Currency != req.Currency {
+			continue
+		}
+		if !hasTags(sub, req.Tags) {
+			continue
+		}
+
+		start, err := domain.ParsePeriod(sub.StartDate)
+		if err != nil {
+			return nil, err
+		}
+		end := openEnd
+		if sub.EndDate != nil {
+			end = periodEnd
+			subEnd, err := domain.ParsePeriod(*sub.EndDate)
+			if err != nil {
+				return nil, err
+			}
+			if subEnd.Before(end) {
+				end = subEnd
+			}
+		}
+		if start.Before(periodStart) {
+			start = periodStart
+		}
+		if end.Before(start) {
+			continue
+		}
+
+		user := uuid.Nil
+		if byUser {
+			user = sub.UserID
+		}
+		for month := start; !month.After(end); month = month.AddDate(0, 1, 0) {
+			if req.ExcludeInactive {
+				status, err := domain.StatusAt(r.changes[sub.ID], month)
+				if err != nil {
+					return nil, err
+				}
+				if !status.Billable() {
+					continue
+				}
+			}
+			charge, err := domain.DiscountedCharge(domain.ProratedMonthCharge(sub.Price.Amount, sub.BillingCycle, month), r.discounts[sub.ID], month)
+			if err != nil {
+				return nil, err
+			}
+			if units[user] == nil {
+				units[user] = make(map[domain.Currency]int64)
+			}
+			units[user][sub.Price.Currency] += charge
+		}
+	}
+
+	totals := make(map[uuid.UUID]domain.Totals, len(units))
+	for user, byCurrency := range units {
+		userTotals := make(domain.Totals, len(byCurrency))
+		for currency, u := range byCurrency {
+			userTotals[currency] = domain.RoundProratedWith(u, req.Rounding)
+		}
+		totals[user] = userTotals
+	}
+	return totals, nil
+}
+
+func (r *subscriptionRepo) ListHistory(_ context.Context, req domain.CalculateTotalRequest) ([]*domain.Subscription, error) {
+	periodEnd, err := domain.ParsePeriod(req.EndPeriod)
+	if err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	subs := make([]*domain.Subscription, 0)
+	for _, sub := range r.subs {
+		if !matchesTotalUser(sub, req) {
+			continue
+		}
+		if !matchesService(sub, req.ServiceName, req.ServiceKeys) {
+			continue
+		}
+		if !hasTags(sub, req.Tags) {
+			continue
+		}
+		start, err := domain.ParsePeriod(sub.StartDate)
+		if err != nil {
+			return nil, err
+		}
+		if start.After(periodEnd) {
+			continue
+		}
+		sub := sub
+		subs = append(subs, &sub)
+	}
+
+	// Порядок внутри группы задает domain.ClassifyBilledMonths, здесь важна только детерминированность
+	sort.Slice(subs, func(i, j int) bool {
+		if subs[i].CreatedAt.Equal(subs[j].CreatedAt) {
+			return subs[i].ID.String() < subs[j].ID.String()
+		}
+		return subs[i].CreatedAt.Before(subs[j].CreatedAt)
+	})
+
+	return subs, nil
+}
+
+func (r *subscriptionRepo) ChangeStatus(ctx context.Context, change *domain.StatusChange) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	sub, ok := r.subs[change.SubscriptionID]
+	if !ok {
+		return postgres.ErrNotFound
+	}
+
+	sub.Status = change.Status
+	sub.UpdatedAt = change.ChangedAt
+	sub.Region = region.FromContext(ctx)
+	sub.Version++
+	r.subs[sub.ID] = sub
+
+	stored := *change
+	r.changes[sub.ID] = append(r.changes[sub.ID], &stored)
+	return nil
+}
+
+func (r *subscriptionRepo) ListRenewable(_ context.Context, from, to string) ([]*domain.Subscription, error) {
+	fromMonth, err := domain.ParsePeriod(from)
+	if err != nil {
+		return nil, err
+	}
+	toMonth, err := domain.ParsePeriod(to)
+	if err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	result := make([]*domain.Subscription, 0)
+	for _, sub := range r.subs {
+		if !sub.AutoRenew || sub.Status != domain.StatusActive || sub.EndDate == nil {
+			continue
+		}
+		end, err := domain.ParsePeriod(*sub.EndDate)
+		if err != nil || end.Before(fromMonth) || end.After(toMonth) {
+			continue
+		}
+		sub := sub
+		result = append(result, &sub)
+	}
+
+	sort.Slice(result, func(i, j int) bool { return result[i].ID.String() < result[j].ID.String() })
+	return result, nil
+}
+
+func (r *subscriptionRepo) Renew(ctx context.Context, id uuid.UUID, previousEnd, endDate string, renewedAt time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	sub, ok := r.subs[id]
+	if !ok || !sub.AutoRenew || sub.EndDate == nil || *sub.EndDate != previousEnd {
+		return postgres.ErrNotFound
+	}
+
+	sub.EndDate = &endDate
+	sub.UpdatedAt = renewedAt
+	sub.Region = region.FromContext(ctx)
+	sub.Version++
+	r.subs[id] = sub
+	return nil
+}
+
+func (r *subscriptionRepo) Cancel(ctx context.Context, sub *domain.Subscription, change *domain.StatusChange) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	existing, ok := r.subs[sub.ID]
+	if !ok {
+		return postgres.ErrNotFound
+	}
+	if existing.Version != sub.Version {
+		return postgres.ErrVersionConflict
+	}
+	sub.Region = region.FromContext(ctx)
+	sub.Version++
+	r.subs[sub.ID] = *sub
+
+	stored := *change
+	r.changes[sub.ID] = append(r.changes[sub.ID], &stored)
+	return nil
+}
+
+func (r *subscriptionRepo) ListStatusChanges(_ context.Context, subscriptionIDs []uuid.UUID) ([]*domain.StatusChange, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	changes := make([]*domain.StatusChange, 0)
+	for _, id := range subscriptionIDs {
+		for _, change := range r.changes[id] {
+			change := *change
+			changes = append(changes, &change)
+		}
+	}
+	return changes, nil
+}
+
+func (r *subscriptionRepo) CreateDiscount(_ context.Context, discount *domain.Discount) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.subs[discount.SubscriptionID]; !ok {
+		return postgres.ErrNotFound
+	}
+	stored := *discount
+	r.discounts[discount.SubscriptionID] = append(r.discounts[discount.SubscriptionID], &stored)
+	return nil
+}
+
+func (r *subscriptionRepo) ListDiscounts(_ context.Context, subscriptionIDs []uuid.UUID) ([]*domain.Discount, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	discounts := make([]*domain.Discount, 0)
+	for _, id := range subscriptionIDs {
+		for _, discount := range r.discounts[id] {
+			discount := *discount
+			discounts = append(discounts, &discount)
+		}
+	}
+	return discounts, nil
+}
+
+func (r *subscriptionRepo) DeleteDiscount(_ context.Context, subscriptionID, id uuid.UUID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	discounts := r.discounts[subscriptionID]
+	for i, discount := range discounts {
+		if discount.ID == id {
+			r.discounts[subscriptionID] = slices.Delete(discounts, i, i+1)
+			return nil
+		}
+	}
+	return postgres.ErrDiscountNotFound
+}
+
+func (r *subscriptionRepo) YearOverYear(_ context.Context, req domain.YearOverYearRequest) ([]domain.YearOverYearUnits, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	result := make([]domain.YearOverYearUnits, 12)
+	for i := range result {
+		result[i].Month = i + 1
+	}
+
+	from := time.Date(req.Year-1, time.January, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(req.Year, time.December, 1, 0, 0, 0, 0, time.UTC)
+	for _, sub := range r.subs {
+		if req.UserID != nil && sub.UserID != *req.UserID {
+			continue
+		}
+		if !matchesService(sub, req.ServiceName, req.ServiceKeys) {
+			continue
+		}
+		if req.Currency != "" && sub.Price.Currency != req.Currency {
+			continue
+		}
+		if !hasTags(sub, req.Tags) {
+			continue
+		}
+
+		start, err := domain.ParsePeriod(sub.StartDate)
+		if err != nil {
+			return nil, err
+		}
+		end := to
+		if sub.EndDate != nil {
+			subEnd, err := domain.ParsePeriod(*sub.EndDate)
+			if err != nil {
+				return nil, err
+			}
+			if subEnd.Before(end) {
+				end = subEnd
+			}
+		}
+		if start.Before(from) {
+			start = from
+		}
+
+		for month := start; !month.After(end); month = month.AddDate(0, 1, 0) {
+			charge, err := domain.DiscountedCharge(domain.ProratedMonthCharge(sub.Price.Amount, sub.BillingCycle, month), r.discounts[sub.ID], month)
+			if err != nil {
+				return nil, err
+			}
+			if month.Year() == req.Year {
+				result[month.Month()-1].Current += charge
+			} else {
+				result[month.Month()-1].Previous += charge
+			}
+		}
+	}
+	return result, nil
+}
+
+func (r *subscriptionRepo) ListPriceHistory(_ context.Context, subscriptionID uuid.UUID) ([]*domain.PriceChange, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	changes := make([]*domain.PriceChange, 0, len(r.prices[subscriptionID]))
+	for _, change := range r.prices[subscriptionID] {
+		change := *change
+		changes = append(changes, &change)
+	}
+	return changes, nil
+}
@@ -0,0 +1,95 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"aggregator_db/internal/repository/postgres"
+	"aggregator_db/pkg/domain"
+)
+
+type tenantRepo struct {
+	mu      sync.RWMutex
+	tenants map[string]domain.Tenant
+}
+
+func NewTenantRepository() postgres.TenantRepository {
+	return &tenantRepo{tenants: make(map[string]domain.Tenant)}
+}
+
+func (r *tenantRepo) Create(_ context.Context, tenant *domain.Tenant) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.tenants[tenant.ID]; ok {
+		return postgres.ErrTenantAlreadyExists
+	}
+	r.tenants[tenant.ID] = *tenant
+	return nil
+}
+
+func (r *tenantRepo) Get(_ context.Context, id string) (*domain.Tenant, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	tenant, ok := r.tenants[id]
+	if !ok {
+		return nil, postgres.ErrTenantNotFound
+	}
+	return &tenant, nil
+}
+
+func (r *tenantRepo) List(_ context.Context) ([]*domain.Tenant, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	result := make([]*domain.Tenant, 0, len(r.tenants))
+	for _, tenant := range r.tenants {
+		tenant := tenant
+		result = append(result, &tenant)
+	}
+
+	sort.Slice(result, func(i, j int) bool { return result[i].ID < result[j].ID })
+	return result, nil
+}
+
+func (r *tenantRepo) Update(_ context.Context, tenant *domain.Tenant) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.tenants[tenant.ID]; !ok {
+		return postgres.ErrTenantNotFound
+	}
+	r.tenants[tenant.ID] = *tenant
+	return nil
+}
+
+func (r *tenantRepo) Delete(_ context.Context, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.tenants[id]; !ok {
+		return postgres.ErrTenantNotFound
+	}
+	delete(r.tenants, id)
+	return nil
+}
+
+type tenantProvisioner struct{}
+
+// NewTenantProvisioner возвращает провижинер без хранилища: in-memory
+// репозитории не разделяют данные тенантов.
+func NewTenantProvisioner() postgres.TenantProvisioner {
+	return tenantProvisioner{}
+}
+
+func (tenantProvisioner) Provision(context.Context, *domain.Tenant) error {
+	return nil
+}
+
+func (tenantProvisioner) Deprovision(context.Context, *domain.Tenant) error {
+	return nil
+}
+
+func (tenantProvisioner) Disconnect(string) {}
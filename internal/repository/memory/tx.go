@@ -0,0 +1,37 @@
+package memory
+
+import (
+	"context"
+	"sync"
+
+	"aggregator_db/internal/repository/postgres"
+)
+
+// Transactor - postgres.Transactor для репозиториев в памяти. Откатывать при
+// ошибке нечего, поэтому единицы работы только выполняются по очереди, а Lock
+// ничего не делает.
+type Transactor struct {
+	mu *sync.Mutex
+}
+
+func NewTransactor() Transactor {
+	return Transactor{mu: &sync.Mutex{}}
+}
+
+type unitKey struct{}
+
+func (t Transactor) WithTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	if ctx.Value(unitKey{}) != nil {
+		return fn(ctx)
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return fn(context.WithValue(ctx, unitKey{}, true))
+}
+
+func (t Transactor) Lock(ctx context.Context, _ string) error {
+	if ctx.Value(unitKey{}) == nil {
+		return postgres.ErrNoTx
+	}
+	return nil
+}
@@ -0,0 +1,64 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"aggregator_db/internal/repository/postgres"
+	"aggregator_db/pkg/domain"
+)
+
+type usageKey struct {
+	consumer string
+	metric   domain.UsageMetric
+	day      string
+}
+
+type usageRepo struct {
+	mu      sync.RWMutex
+	records map[usageKey]int64
+}
+
+func NewUsageRepository() postgres.UsageRepository {
+	return &usageRepo{records: make(map[usageKey]int64)}
+}
+
+func (r *usageRepo) Add(_ context.Context, records []*domain.UsageRecord) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, record := range records {
+		r.records[usageKey{record.Consumer, record.Metric, record.Day}] += record.Quantity
+	}
+	return nil
+}
+
+func (r *usageRepo) List(_ context.Context, query domain.UsageQuery) ([]*domain.UsageRecord, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	result := make([]*domain.UsageRecord, 0)
+	for key, quantity := range r.records {
+		// Дни в формате YYYY-MM-DD сравниваются как строки
+		if key.day < query.From || key.day > query.To {
+			continue
+		}
+		if query.Consumer != nil && key.consumer != *query.Consumer {
+			continue
+		}
+		result = append(result, &domain.UsageRecord{Consumer: key.consumer, Metric: key.metric, Day: key.day, Quantity: quantity})
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		a, b := result[i], result[j]
+		if a.Day != b.Day {
+			return a.Day < b.Day
+		}
+		if a.Consumer != b.Consumer {
+			return a.Consumer < b.Consumer
+		}
+		return a.Metric < b.Metric
+	})
+	return result, nil
+}
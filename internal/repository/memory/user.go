@@ -0,0 +1,132 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	"aggregator_db/internal/repository/postgres"
+	"aggregator_db/pkg/domain"
+	"github.com/google/uuid"
+)
+
+// userRepo хранит пользователей вместе с подписками репозитория subs, чтобы
+// повторить внешний ключ postgres: подписка заводит пользователя, а удаление
+// пользователя удаляет его подписки.
+type userRepo struct {
+	subs *subscriptionRepo
+}
+
+// NewUserRepository принимает репозиторий подписок из этого пакета.
+func NewUserRepository(subs postgres.SubscriptionRepository) postgres.UserRepository {
+	return &userRepo{subs: subs.(*subscriptionRepo)}
+}
+
+// emailTaken сообщает, занят ли email другим пользователем; вызывается под mu.
+func (r *userRepo) emailTaken(user *domain.User) bool {
+	if user.Email == nil {
+		return false
+	}
+	for id, other := range r.subs.users {
+		if id != user.ID && other.Email != nil && strings.EqualFold(*other.Email, *user.Email) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *userRepo) Create(_ context.Context, user *domain.User) error {
+	r.subs.mu.Lock()
+	defer r.subs.mu.Unlock()
+
+	if _, ok := r.subs.users[user.ID]; ok {
+		return postgres.ErrUserAlreadyExists
+	}
+	if r.emailTaken(user) {
+		return postgres.ErrUserEmailTaken
+	}
+	r.subs.users[user.ID] = *user
+	return nil
+}
+
+func (r *userRepo) GetByID(_ context.Context, id uuid.UUID) (*domain.User, error) {
+	r.subs.mu.RLock()
+	defer r.subs.mu.RUnlock()
+
+	user, ok := r.subs.users[id]
+	if !ok {
+		return nil, postgres.ErrUserNotFound
+	}
+	return &user, nil
+}
+
+func (r *userRepo) List(_ context.Context, query domain.ListUsersQuery) ([]*domain.User, error) {
+	r.subs.mu.RLock()
+	defer r.subs.mu.RUnlock()
+
+	users := make([]*domain.User, 0, len(r.subs.users))
+	for _, user := range r.subs.users {
+		user := user
+		users = append(users, &user)
+	}
+	sort.Slice(users, func(i, j int) bool {
+		if users[i].CreatedAt.Equal(users[j].CreatedAt) {
+			return users[i].ID.String() < users[j].ID.String()
+		}
+		return users[i].CreatedAt.Before(users[j].CreatedAt)
+	})
+
+	if query.Offset >= len(users) {
+		return []*domain.User{}, nil
+	}
+	users = users[query.Offset:]
+	if query.Limit > 0 && len(users) > query.Limit {
+		users = users[:query.Limit]
+	}
+	return users, nil
+}
+
+func (r *userRepo) Count(_ context.Context) (int, error) {
+	r.subs.mu.RLock()
+	defer r.subs.mu.RUnlock()
+
+	return len(r.subs.users), nil
+}
+
+func (r *userRepo) Update(_ context.Context, user *domain.User) error {
+	r.subs.mu.Lock()
+	defer r.subs.mu.Unlock()
+
+	existing, ok := r.subs.users[user.ID]
+	if !ok {
+		return postgres.ErrUserNotFound
+	}
+	if r.emailTaken(user) {
+		return postgres.ErrUserEmailTaken
+	}
+	existing.Email = user.Email
+	existing.Name = user.Name
+	existing.Role = user.Role
+	existing.UpdatedAt = user.UpdatedAt
+	r.subs.users[user.ID] = existing
+	return nil
+}
+
+func (r *userRepo) Delete(_ context.Context, id uuid.UUID) error {
+	r.subs.mu.Lock()
+	defer r.subs.mu.Unlock()
+
+	if _, ok := r.subs.users[id]; !ok {
+		return postgres.ErrUserNotFound
+	}
+	delete(r.subs.users, id)
+	for subID, sub := range r.subs.subs {
+		if sub.UserID == id {
+			delete(r.subs.subs, subID)
+			delete(r.subs.changes, subID)
+			delete(r.subs.discounts, subID)
+			delete(r.subs.prices, subID)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,319 @@
+package mysql
+
+import (
+	"context"
+	"time"
+
+	"aggregator_db/pkg/domain"
+	"github.com/google/uuid"
+)
+
+// buildTotalFilter собирает фильтры пользователя, сервиса, валюты и меток для
+// расчета стоимости; prefix - псевдоним таблицы подписок с точкой или пусто.
+func buildTotalFilter(req domain.CalculateTotalRequest, prefix string) (string, []any) {
+	where, args := buildUserServiceFilter(prefix, req.UserID, req.ServiceName, req.ServiceKeys)
+
+	if len(req.UserIDs) > 0 {
+		users, userArgs := inList(req.UserIDs)
+		where += " AND " + prefix + "user_id IN (" + users + ")"
+		args = append(args, userArgs...)
+	}
+
+	if req.Currency != "" {
+		where += " AND " + prefix + "currency = ?"
+		args = append(args, req.Currency)
+	}
+
+	tags, tagArgs := tagsFilter(prefix, req.Tags)
+	return where + tags, append(args, tagArgs...)
+}
+
+// totalPeriod - месяцы расчета: начало, конец и конец бессрочных подписок
+// (domain.CalculateTotalRequest.OpenEndedEnd), датами первого числа.
+type totalPeriod struct {
+	start, end, openEnd any
+}
+
+func parseTotalPeriod(req domain.CalculateTotalRequest) (totalPeriod, error) {
+	var period totalPeriod
+	var err error
+	if period.start, err = month(&req.StartPeriod); err != nil {
+		return period, err
+	}
+	if period.end, err = month(&req.EndPeriod); err != nil {
+		return period, err
+	}
+	openEnd := req.OpenEndedEnd()
+	period.openEnd, err = month(&openEnd)
+	return period, err
+}
+
+// monthUnits - стоимость месяца m.month подписки из CTE months в долях
+// 1/domain.ProrationDenominator, как domain.ProratedMonthCharge.
+const monthUnits = `
+            CASE m.billing_cycle
+                WHEN 'weekly' THEN m.price_minor * DATEDIFF(DATE_ADD(m.month, INTERVAL 1 MONTH), m.month) * 12
+                WHEN 'yearly' THEN m.price_minor * 7
+                ELSE m.price_minor * 84
+            END`
+
+// billableMonth отсекает месяцы CTE months, в которые по истории статусов
+// подписка была на паузе или отменена.
+const billableMonth = `
+            COALESCE((
+                SELECT c.status
+                FROM subscription_status_changes c
+                WHERE c.subscription_id = m.id AND c.effective_from <= m.month
+                ORDER BY c.effective_from DESC, c.changed_at DESC
+                LIMIT 1
+            ), 'active') NOT IN ('paused', 'cancelled')`
+
+// userUnits - стоимость в долях 1/domain.ProrationDenominator по пользователям
+// и валютам; без разбивки по пользователям вся сумма лежит под uuid.Nil.
+type userUnits map[uuid.UUID]map[domain.Currency]int64
+
+// groupUser - колонка пользователя в выборке стоимости: user_id при разбивке
+// по пользователям, иначе NULL, и все строки сводятся в одну группу.
+func groupUser(byUser bool) string {
+	if byUser {
+		return "user_id"
+	}
+	return "NULL"
+}
+
+// CalculateTotal считает стоимость в долях 1/domain.ProrationDenominator,
+// вычитает скидки и только затем округляет, как и репозиторий Postgres.
+func (r *subscriptionRepo) CalculateTotal(ctx context.Context, req domain.CalculateTotalRequest) (domain.Totals, error) {
+	totals, err := r.calculateTotals(ctx, req, false)
+	if err != nil {
+		return nil, err
+	}
+	if total, ok := totals[uuid.Nil]; ok {
+		return total, nil
+	}
+	return domain.Totals{}, nil
+}
+
+// CalculateTotalByUser считает то же, что CalculateTotal, теми же запросами с
+// GROUP BY user_id.
+func (r *subscriptionRepo) CalculateTotalByUser(ctx context.Context, req domain.CalculateTotalRequest) (map[uuid.UUID]domain.Totals, error) {
+	return r.calculateTotals(ctx, req, true)
+}
+
+func (r *subscriptionRepo) calculateTotals(ctx context.Context, req domain.CalculateTotalRequest, byUser bool) (map[uuid.UUID]domain.Totals, error) {
+	period, err := parseTotalPeriod(req)
+	if err != nil {
+		return nil, err
+	}
+
+	var units userUnits
+	if req.ExcludeInactive {
+		units, err = r.billableUnits(ctx, req, period, byUser)
+	} else {
+		units, err = r.periodUnits(ctx, req, period, byUser)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	discounts, err := r.discountUnits(ctx, req, period, byUser)
+	if err != nil {
+		return nil, err
+	}
+
+	totals := make(map[uuid.UUID]domain.Totals, len(units))
+	for user, byCurrency := range units {
+		userTotals := make(domain.Totals, len(byCurrency))
+		for currency, u := range byCurrency {
+			userTotals[currency] = domain.RoundProratedWith(u-discounts[user][currency], req.Rounding)
+		}
+		totals[user] = userTotals
+	}
+	return totals, nil
+}
+
+// periodUnits считает стоимость без разбивки на месяцы: число месяцев пересечения
+// подписки с периодом умножается на стоимость месяца.
+func (r *subscriptionRepo) periodUnits(ctx context.Context, req domain.CalculateTotalRequest, period totalPeriod, byUser bool) (userUnits, error) {
+	filter, filterArgs := buildTotalFilter(req, "")
+	sqlQuery := `
+        SELECT user_id, currency, CAST(SUM(
+            CASE billing_cycle
+                WHEN 'weekly' THEN price_minor * DATEDIFF(DATE_ADD(calc_end, INTERVAL 1 MONTH), calc_start) * 12
+                ELSE price_minor * (PERIOD_DIFF(EXTRACT(YEAR_MONTH FROM calc_end), EXTRACT(YEAR_MONTH FROM calc_start)) + 1)
+                    * CASE billing_cycle WHEN 'yearly' THEN 7 ELSE 84 END
+            END
+        ) AS SIGNED) AS total
+        FROM (
+            SELECT ` + groupUser(byUser) + ` AS user_id, price_minor, currency, billing_cycle,
+                GREATEST(start_month, CAST(? AS DATE)) AS calc_start,
+                LEAST(COALESCE(end_month, CAST(? AS DATE)), CAST(? AS DATE)) AS calc_end
+            FROM subscriptions
+            WHERE start_month <= ? AND (end_month IS NULL OR end_month >= ?)` + filter + `
+        ) period_calculations
+        WHERE calc_end >= calc_start
+        GROUP BY user_id, currency
+    `
+
+	args := append([]any{period.start, period.openEnd, period.end, period.end, period.start}, filterArgs...)
+	return r.queryUnits(ctx, sqlQuery, args...)
+}
+
+// monthsCTE раскладывает подписки под фильтр на месяцы периода; аргументы CTE -
+// monthsArgs. MySQL не умеет generate_series, поэтому месяцы периода строит
+// рекурсивный CTE.
+func monthsCTE(filter string) string {
+	return `
+        WITH RECURSIVE series (month) AS (
+            SELECT CAST(? AS DATE)
+            UNION ALL
+            SELECT DATE_ADD(month, INTERVAL 1 MONTH) FROM series WHERE month < CAST(? AS DATE)
+        ),
+        months AS (
+            SELECT s.id, s.user_id, s.price_minor, s.currency, s.billing_cycle, series.month
+            FROM subscriptions s
+            JOIN series ON series.month >= s.start_month AND series.month <= COALESCE(s.end_month, CAST(? AS DATE))
+            WHERE 1=1` + filter + `
+        )`
+}
+
+func monthsArgs(period totalPeriod, filterArgs []any) []any {
+	return append([]any{period.start, period.end, period.openEnd}, filterArgs...)
+}
+
+// billableUnits раскладывает подписки на месяцы и пропускает месяцы,
+// в которые по истории статусов подписка была на паузе или отменена.
+func (r *subscriptionRepo) billableUnits(ctx context.Context, req domain.CalculateTotalRequest, period totalPeriod, byUser bool) (userUnits, error) {
+	filter, filterArgs := buildTotalFilter(req, "s.")
+	sqlQuery := monthsCTE(filter) + `
+        SELECT ` + groupUser(byUser) + ` AS user_id, m.currency, CAST(SUM(` + monthUnits + `
+        ) AS SIGNED)
+        FROM months m
+        WHERE ` + billableMonth + `
+        GROUP BY 1, m.currency
+    `
+	return r.queryUnits(ctx, sqlQuery, monthsArgs(period, filterArgs)...)
+}
+
+// discountUnits считает, на сколько скидки уменьшают стоимость периода; формула
+// та же, что в domain.DiscountedCharge. В расчет попадают только подписки со скидками.
+func (r *subscriptionRepo) discountUnits(ctx context.Context, req domain.CalculateTotalRequest, period totalPeriod, byUser bool) (userUnits, error) {
+	filter, filterArgs := buildTotalFilter(req, "s.")
+	filter += `
+                AND EXISTS (SELECT 1 FROM subscription_discounts d WHERE d.subscription_id = s.id)`
+	status := ""
+	if req.ExcludeInactive {
+		status = " AND " + billableMonth
+	}
+
+	sqlQuery := monthsCTE(filter) + `,
+        discounted AS (
+            SELECT m.user_id, m.currency, ` + monthUnits + ` AS units,
+                LEAST(COALESCE(SUM(CASE WHEN d.kind = 'percent' THEN d.percent END), 0), 100) AS percent,
+                COALESCE(SUM(CASE WHEN d.kind = 'fixed' THEN d.amount_minor END), 0) AS fixed
+            FROM months m
+            JOIN subscription_discounts d ON d.subscription_id = m.id
+                AND d.start_month <= m.month
+                AND (d.end_month IS NULL OR d.end_month >= m.month)
+            WHERE 1=1` + status + `
+            GROUP BY m.id, m.user_id, m.month, m.currency, m.price_minor, m.billing_cycle
+        )
+        SELECT ` + groupUser(byUser) + ` AS user_id, currency, CAST(SUM(units - GREATEST(units * (100 - percent) DIV 100 - fixed * 84, 0)) AS SIGNED)
+        FROM discounted
+        GROUP BY 1, currency
+    `
+	return r.queryUnits(ctx, sqlQuery, monthsArgs(period, filterArgs)...)
+}
+
+// queryUnits читает строки (user_id, currency, units) в суммы по пользователям
+// и валютам; NULL в user_id - сумма без разбивки, она ложится под uuid.Nil.
+func (r *subscriptionRepo) queryUnits(ctx context.Context, sqlQuery string, args ...any) (userUnits, error) {
+	rows, err := r.db.conn(ctx).QueryContext(ctx, sqlQuery, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	units := make(userUnits)
+	for rows.Next() {
+		var user uuid.NullUUID
+		var currency domain.Currency
+		var total int64
+		if err := rows.Scan(&user, &currency, &total); err != nil {
+			return nil, err
+		}
+		if units[user.UUID] == nil {
+			units[user.UUID] = make(map[domain.Currency]int64)
+		}
+		units[user.UUID][currency] = total
+	}
+	return units, rows.Err()
+}
+
+// YearOverYear считает оба года одним запросом: подписки раскладываются на
+// месяцы двух лет, к каждому месяцу применяются действующие скидки (как в
+// domain.DiscountedCharge), затем месяцы сводятся по номеру.
+func (r *subscriptionRepo) YearOverYear(ctx context.Context, req domain.YearOverYearRequest) ([]domain.YearOverYearUnits, error) {
+	filter, filterArgs := buildTotalFilter(domain.CalculateTotalRequest{
+		UserID:      req.UserID,
+		ServiceName: req.ServiceName,
+		ServiceKeys: req.ServiceKeys,
+		Currency:    req.Currency,
+		Tags:        req.Tags,
+	}, "s.")
+	first := time.Date(req.Year-1, time.January, 1, 0, 0, 0, 0, time.UTC).Format(time.DateOnly)
+	last := time.Date(req.Year, time.December, 1, 0, 0, 0, 0, time.UTC).Format(time.DateOnly)
+
+	sqlQuery := `
+        WITH RECURSIVE months (month) AS (
+            SELECT CAST(? AS DATE)
+            UNION ALL
+            SELECT DATE_ADD(month, INTERVAL 1 MONTH) FROM months WHERE month < CAST(? AS DATE)
+        ),
+        charges AS (
+            SELECT month, GREATEST(units * (100 - LEAST(percent, 100)) DIV 100 - fixed * 84, 0) AS units
+            FROM (
+                SELECT m.month,
+                    CASE s.billing_cycle
+                        WHEN 'weekly' THEN s.price_minor * DATEDIFF(DATE_ADD(m.month, INTERVAL 1 MONTH), m.month) * 12
+                        WHEN 'yearly' THEN s.price_minor * 7
+                        ELSE s.price_minor * 84
+                    END AS units,
+                    COALESCE(SUM(CASE WHEN sd.kind = 'percent' THEN sd.percent END), 0) AS percent,
+                    COALESCE(SUM(CASE WHEN sd.kind = 'fixed' THEN sd.amount_minor END), 0) AS fixed
+                FROM months m
+                JOIN subscriptions s ON s.start_month <= m.month AND (s.end_month IS NULL OR s.end_month >= m.month)
+                LEFT JOIN subscription_discounts sd ON sd.subscription_id = s.id
+                    AND sd.start_month <= m.month
+                    AND (sd.end_month IS NULL OR sd.end_month >= m.month)
+                WHERE 1=1` + filter + `
+                GROUP BY m.month, s.id, s.price_minor, s.billing_cycle
+            ) month_charges
+        )
+        SELECT MONTH(m.month),
+            CAST(COALESCE(SUM(CASE WHEN YEAR(m.month) = ? THEN c.units END), 0) AS SIGNED),
+            CAST(COALESCE(SUM(CASE WHEN YEAR(m.month) = ? THEN c.units END), 0) AS SIGNED)
+        FROM months m
+        LEFT JOIN charges c ON c.month = m.month
+        GROUP BY MONTH(m.month)
+        ORDER BY MONTH(m.month)
+    `
+
+	args := append([]any{first, last}, filterArgs...)
+	args = append(args, req.Year, req.Year-1)
+	rows, err := r.db.conn(ctx).QueryContext(ctx, sqlQuery, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := make([]domain.YearOverYearUnits, 0, 12)
+	for rows.Next() {
+		var month domain.YearOverYearUnits
+		if err := rows.Scan(&month.Month, &month.Current, &month.Previous); err != nil {
+			return nil, err
+		}
+		result = append(result, month)
+	}
+	return result, rows.Err()
+}
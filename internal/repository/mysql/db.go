@@ -0,0 +1,70 @@
+// Package mysql - хранилище подписок в MySQL 8.0 для развертываний, где нет
+// Postgres для данных подписок (DB_DRIVER=mysql). Реализует тот же
+// store.SubscriptionRepository, что и internal/repository/postgres, включая
+// расчет стоимости в SQL; схема - в migrations этого пакета.
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// DB - то, что нужно репозиторию от соединения: *sql.DB, *sql.Conn или *sql.Tx.
+type DB interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// Open подключается к MySQL по dsn в формате go-sql-driver/mysql. Время
+// читается как time.Time в UTC, а миграции выполняются файлом целиком, поэтому
+// parseTime, loc и multiStatements задаются здесь независимо от dsn.
+func Open(ctx context.Context, dsn string) (*sql.DB, error) {
+	cfg, err := mysql.ParseDSN(dsn)
+	if err != nil {
+		return nil, fmt.Errorf("mysql dsn: %w", err)
+	}
+	cfg.ParseTime = true
+	cfg.Loc = time.UTC
+	cfg.MultiStatements = true
+
+	connector, err := mysql.NewConnector(cfg)
+	if err != nil {
+		return nil, err
+	}
+	db := sql.OpenDB(connector)
+	if err := db.PingContext(ctx); err != nil {
+		_ = db.Close()
+		return nil, err
+	}
+	return db, nil
+}
+
+// Коды ошибок сервера MySQL, которые репозиторий различает.
+const (
+	errDuplicateEntry  = 1062
+	errLockWaitTimeout = 1205
+	errDeadlock        = 1213
+)
+
+func errorNumber(err error) uint16 {
+	var myErr *mysql.MySQLError
+	if errors.As(err, &myErr) {
+		return myErr.Number
+	}
+	return 0
+}
+
+// IsRetryable сообщает, что транзакцию откатил сервер и ее можно выполнить заново.
+func IsRetryable(err error) bool {
+	switch errorNumber(err) {
+	case errDeadlock, errLockWaitTimeout:
+		return true
+	}
+	return false
+}
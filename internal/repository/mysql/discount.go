@@ -0,0 +1,121 @@
+package mysql
+
+import (
+	"context"
+
+	"aggregator_db/pkg/domain"
+	"aggregator_db/pkg/store"
+	"github.com/google/uuid"
+)
+
+func (r *subscriptionRepo) CreateDiscount(ctx context.Context, discount *domain.Discount) error {
+	var amount *int64
+	var currency *domain.Currency
+	if discount.Amount != nil {
+		amount, currency = &discount.Amount.Amount, &discount.Amount.Currency
+	}
+	start, err := month(&discount.StartMonth)
+	if err != nil {
+		return err
+	}
+	end, err := month(discount.EndMonth)
+	if err != nil {
+		return err
+	}
+
+	result, err := r.db.conn(ctx).ExecContext(ctx, `
+        INSERT INTO subscription_discounts (id, subscription_id, kind, percent, amount_minor, currency, start_month, end_month, code, created_at)
+        SELECT ?, id, ?, ?, ?, ?, ?, ?, ?, ?
+        FROM subscriptions
+        WHERE id = ?
+    `, discount.ID, discount.Kind, discount.Percent, amount, currency, start, end, discount.Code, discount.CreatedAt, discount.SubscriptionID)
+	return requireRows(result, err, store.ErrNotFound)
+}
+
+func (r *subscriptionRepo) ListDiscounts(ctx context.Context, subscriptionIDs []uuid.UUID) ([]*domain.Discount, error) {
+	if len(subscriptionIDs) == 0 {
+		return []*domain.Discount{}, nil
+	}
+	in, args := inList(subscriptionIDs)
+	rows, err := r.db.conn(ctx).QueryContext(ctx, `
+        SELECT id, subscription_id, kind, percent, amount_minor, currency,
+            DATE_FORMAT(start_month, '%m-%Y'), DATE_FORMAT(end_month, '%m-%Y'), code, created_at
+        FROM subscription_discounts
+        WHERE subscription_id IN (`+in+`)
+        ORDER BY subscription_id, start_month, created_at
+    `, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	discounts := make([]*domain.Discount, 0)
+	for rows.Next() {
+		var d domain.Discount
+		var amount *int64
+		var currency *domain.Currency
+		if err := rows.Scan(&d.ID, &d.SubscriptionID, &d.Kind, &d.Percent, &amount, &currency,
+			&d.StartMonth, &d.EndMonth, &d.Code, &d.CreatedAt); err != nil {
+			return nil, err
+		}
+		if amount != nil && currency != nil {
+			money := domain.NewMoney(*amount, *currency)
+			d.Amount = &money
+		}
+		discounts = append(discounts, &d)
+	}
+	return discounts, rows.Err()
+}
+
+func (r *subscriptionRepo) DeleteDiscount(ctx context.Context, subscriptionID, id uuid.UUID) error {
+	result, err := r.db.conn(ctx).ExecContext(ctx,
+		`DELETE FROM subscription_discounts WHERE id = ? AND subscription_id = ?`,
+		id, subscriptionID,
+	)
+	return requireRows(result, err, store.ErrDiscountNotFound)
+}
+
+// insertPrice пишет цену подписки в историю, если она отличается от последней записи.
+func insertPrice(ctx context.Context, tx DB, sub *domain.Subscription, changedAt any) error {
+	cycle := sub.BillingCycle.OrDefault()
+	_, err := tx.ExecContext(ctx, `
+        INSERT INTO subscription_price_history (subscription_id, price_minor, currency, billing_cycle, changed_at)
+        SELECT ?, ?, ?, ?, ?
+        FROM DUAL
+        WHERE NOT EXISTS (
+            SELECT 1 FROM (
+                SELECT price_minor, currency, billing_cycle
+                FROM subscription_price_history
+                WHERE subscription_id = ?
+                ORDER BY changed_at DESC, id DESC
+                LIMIT 1
+            ) last
+            WHERE last.price_minor = ? AND last.currency = ? AND last.billing_cycle = ?
+        )
+    `, sub.ID, sub.Price.Amount, sub.Price.Currency, cycle, changedAt,
+		sub.ID, sub.Price.Amount, sub.Price.Currency, cycle)
+	return err
+}
+
+func (r *subscriptionRepo) ListPriceHistory(ctx context.Context, subscriptionID uuid.UUID) ([]*domain.PriceChange, error) {
+	rows, err := r.db.conn(ctx).QueryContext(ctx, `
+        SELECT subscription_id, price_minor, currency, billing_cycle, changed_at
+        FROM subscription_price_history
+        WHERE subscription_id = ?
+        ORDER BY changed_at, id
+    `, subscriptionID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	changes := make([]*domain.PriceChange, 0)
+	for rows.Next() {
+		var change domain.PriceChange
+		if err := rows.Scan(&change.SubscriptionID, &change.Price.Amount, &change.Price.Currency, &change.BillingCycle, &change.ChangedAt); err != nil {
+			return nil, err
+		}
+		changes = append(changes, &change)
+	}
+	return changes, rows.Err()
+}
@@ -0,0 +1,115 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Миграции схемы MySQL в формате golang-migrate, как и migrations/ для Postgres.
+//
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+// Migrations возвращает встроенные миграции MySQL.
+func Migrations() fs.FS {
+	files, _ := fs.Sub(migrationFiles, "migrations")
+	return files
+}
+
+// migrationLock - имя блокировки GET_LOCK, под которой реплики применяют миграции по очереди.
+const migrationLock = "subscriptions:migrate"
+
+// Migrate применяет *.up.sql из files. DDL в MySQL не транзакционен, поэтому
+// версия помечается dirty до выполнения файла и очищается после: упавшую
+// миграцию нужно исправить вручную, как и с CLI golang-migrate.
+func Migrate(ctx context.Context, db *sql.DB, files fs.FS, logger *slog.Logger) error {
+	names, err := fs.Glob(files, "*.up.sql")
+	if err != nil {
+		return err
+	}
+	type migration struct {
+		version int64
+		name    string
+	}
+	list := make([]migration, 0, len(names))
+	for _, name := range names {
+		prefix, _, _ := strings.Cut(name, "_")
+		version, err := strconv.ParseInt(prefix, 10, 64)
+		if err != nil || version <= 0 {
+			return fmt.Errorf("invalid migration name %s", name)
+		}
+		list = append(list, migration{version: version, name: name})
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].version < list[j].version })
+
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, `DO GET_LOCK(?, -1)`, migrationLock); err != nil {
+		return fmt.Errorf("lock migrations: %w", err)
+	}
+	defer conn.ExecContext(context.WithoutCancel(ctx), `DO RELEASE_LOCK(?)`, migrationLock)
+
+	_, err = conn.ExecContext(ctx, `
+        CREATE TABLE IF NOT EXISTS schema_migrations (
+            version BIGINT NOT NULL PRIMARY KEY,
+            dirty BOOLEAN NOT NULL
+        )
+    `)
+	if err != nil {
+		return fmt.Errorf("create schema_migrations: %w", err)
+	}
+	var current int64
+	var dirty bool
+	err = conn.QueryRowContext(ctx, `SELECT version, dirty FROM schema_migrations LIMIT 1`).Scan(&current, &dirty)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+	if dirty {
+		return fmt.Errorf("database is dirty at version %d, fix it manually", current)
+	}
+
+	for _, m := range list {
+		if m.version <= current {
+			continue
+		}
+		if err := apply(ctx, conn, files, m.name, m.version); err != nil {
+			return err
+		}
+		logger.Info("Applied migration", "version", m.version, "file", m.name, "driver", "mysql")
+	}
+	return nil
+}
+
+func apply(ctx context.Context, conn *sql.Conn, files fs.FS, name string, version int64) error {
+	script, err := fs.ReadFile(files, name)
+	if err != nil {
+		return err
+	}
+	if err := setVersion(ctx, conn, version, true); err != nil {
+		return err
+	}
+	if _, err := conn.ExecContext(ctx, string(script)); err != nil {
+		return fmt.Errorf("apply %s: %w", name, err)
+	}
+	return setVersion(ctx, conn, version, false)
+}
+
+func setVersion(ctx context.Context, conn *sql.Conn, version int64, dirty bool) error {
+	if _, err := conn.ExecContext(ctx, `DELETE FROM schema_migrations`); err != nil {
+		return err
+	}
+	_, err := conn.ExecContext(ctx, `INSERT INTO schema_migrations (version, dirty) VALUES (?, ?)`, version, dirty)
+	return err
+}
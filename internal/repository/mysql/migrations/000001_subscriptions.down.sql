@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS subscription_price_history;
+DROP TABLE IF EXISTS subscription_discounts;
+DROP TABLE IF EXISTS subscription_status_changes;
+DROP TABLE IF EXISTS subscriptions;
@@ -0,0 +1,69 @@
+-- Схема подписок для MySQL 8.0: те же таблицы, что у Postgres после 000036, но
+-- месяцы подписок и скидок хранятся только датами (первое число месяца).
+CREATE TABLE IF NOT EXISTS subscriptions (
+    id CHAR(36) NOT NULL PRIMARY KEY,
+    service_name VARCHAR(255) NOT NULL,
+    service_key VARCHAR(255) NOT NULL,
+    price_minor BIGINT NOT NULL CHECK (price_minor >= 0),
+    currency CHAR(3) NOT NULL DEFAULT 'RUB',
+    billing_cycle VARCHAR(16) NOT NULL DEFAULT 'monthly' CHECK (billing_cycle IN ('weekly', 'monthly', 'yearly')),
+    user_id CHAR(36) NOT NULL,
+    start_month DATE NOT NULL,
+    end_month DATE NULL,
+    auto_renew BOOLEAN NOT NULL DEFAULT FALSE,
+    status VARCHAR(16) NOT NULL DEFAULT 'active' CHECK (status IN ('active', 'paused', 'cancelled', 'expired')),
+    cancelled_at DATETIME(6) NULL,
+    cancel_reason TEXT NULL,
+    is_backfilled BOOLEAN NOT NULL DEFAULT FALSE,
+    exclude_from_new_analytics BOOLEAN NOT NULL DEFAULT FALSE,
+    backfill_note TEXT NULL,
+    tags JSON NOT NULL,
+    notes TEXT NULL,
+    region VARCHAR(64) NOT NULL DEFAULT '',
+    version BIGINT NOT NULL DEFAULT 1,
+    created_at DATETIME(6) NOT NULL,
+    updated_at DATETIME(6) NOT NULL,
+    CHECK (end_month IS NULL OR end_month >= start_month),
+    INDEX idx_subscriptions_user_period (user_id, start_month, end_month),
+    INDEX idx_subscriptions_service_key (service_key),
+    INDEX idx_subscriptions_created (created_at, id),
+    INDEX idx_subscriptions_renewable (auto_renew, status, end_month)
+);
+
+CREATE TABLE IF NOT EXISTS subscription_status_changes (
+    id BIGINT NOT NULL AUTO_INCREMENT PRIMARY KEY,
+    subscription_id CHAR(36) NOT NULL,
+    status VARCHAR(16) NOT NULL CHECK (status IN ('active', 'paused', 'cancelled', 'expired')),
+    effective_from DATE NOT NULL,
+    changed_at DATETIME(6) NOT NULL,
+    INDEX idx_subscription_status_changes_subscription (subscription_id, effective_from),
+    CONSTRAINT fk_status_changes_subscription FOREIGN KEY (subscription_id) REFERENCES subscriptions (id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS subscription_discounts (
+    id CHAR(36) NOT NULL PRIMARY KEY,
+    subscription_id CHAR(36) NOT NULL,
+    kind VARCHAR(16) NOT NULL CHECK (kind IN ('percent', 'fixed')),
+    percent INT NULL CHECK (percent BETWEEN 1 AND 100),
+    amount_minor BIGINT NULL CHECK (amount_minor > 0),
+    currency CHAR(3) NULL,
+    start_month DATE NOT NULL,
+    end_month DATE NULL,
+    code VARCHAR(64) NULL,
+    created_at DATETIME(6) NOT NULL,
+    CHECK ((kind = 'percent' AND percent IS NOT NULL) OR (kind = 'fixed' AND amount_minor IS NOT NULL AND currency IS NOT NULL)),
+    CHECK (end_month IS NULL OR end_month >= start_month),
+    INDEX idx_subscription_discounts_subscription (subscription_id, start_month),
+    CONSTRAINT fk_discounts_subscription FOREIGN KEY (subscription_id) REFERENCES subscriptions (id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS subscription_price_history (
+    id BIGINT NOT NULL AUTO_INCREMENT PRIMARY KEY,
+    subscription_id CHAR(36) NOT NULL,
+    price_minor BIGINT NOT NULL,
+    currency CHAR(3) NOT NULL,
+    billing_cycle VARCHAR(16) NOT NULL,
+    changed_at DATETIME(6) NOT NULL,
+    INDEX idx_subscription_price_history_subscription (subscription_id, changed_at),
+    CONSTRAINT fk_price_history_subscription FOREIGN KEY (subscription_id) REFERENCES subscriptions (id) ON DELETE CASCADE
+);
@@ -0,0 +1,4 @@
+-- Значения пользовательских полей тенанта, как custom_fields в Postgres (000041).
+-- Индекса нет: фильтр JSON_CONTAINS проверяет подписки, уже отобранные остальными условиями.
+ALTER TABLE subscriptions
+    ADD COLUMN custom_fields JSON NULL;
@@ -0,0 +1,77 @@
+package mysql
+
+import (
+	"strings"
+	"testing"
+
+	"aggregator_db/pkg/domain"
+	"github.com/google/uuid"
+)
+
+func FuzzBuildListQuery(f *testing.F) {
+	f.Add("Yandex Plus", 10, 0, true, "work", "vpn paypal")
+	f.Add("'; DROP TABLE subscriptions; --", 100, 5, false, "", "%' OR 1=1 --")
+	f.Add("?", 0, -1, true, "?", "")
+
+	f.Fuzz(func(t *testing.T, serviceName string, limit, offset int, withUser bool, tag, search string) {
+		query := domain.ListSubscriptionsQuery{
+			ServiceName: &serviceName,
+			Limit:       limit,
+			Offset:      offset,
+			Q:           search,
+		}
+		if withUser {
+			id := uuid.New()
+			query.UserID = &id
+		}
+		if tag != "" {
+			query.Tags = []string{tag}
+		}
+
+		sql, args := buildListQuery(query)
+
+		// Текст запроса не должен зависеть от значений фильтров: ввод идет только в параметры
+		reference := "reference"
+		query.ServiceName = &reference
+		if tag != "" {
+			query.Tags = []string{reference}
+		}
+		query.Q = strings.Repeat(reference+" ", len(strings.Fields(search)))
+		if referenceSQL, _ := buildListQuery(query); referenceSQL != sql {
+			t.Fatalf("service_name leaked into SQL: %q", sql)
+		}
+
+		if placeholders := strings.Count(sql, "?"); placeholders != len(args) {
+			t.Fatalf("placeholders %d != args %d in %q", placeholders, len(args), sql)
+		}
+	})
+}
+
+func TestBuildTotalFilterPrefix(t *testing.T) {
+	userID := uuid.New()
+	service := "Netflix"
+	where, args := buildTotalFilter(domain.CalculateTotalRequest{
+		UserID:      &userID,
+		ServiceName: &service,
+		Currency:    "USD",
+		Tags:        []string{"work"},
+	}, "s.")
+
+	for _, column := range []string{"user_id", "service_name", "currency", "tags"} {
+		if !strings.Contains(where, "s."+column) {
+			t.Errorf("filter %q does not qualify %s", where, column)
+		}
+	}
+	if placeholders := strings.Count(where, "?"); placeholders != len(args) {
+		t.Fatalf("placeholders %d != args %d in %q", placeholders, len(args), where)
+	}
+	if args[0] != userID || args[len(args)-1] != `["work"]` {
+		t.Errorf("unexpected args order %v", args)
+	}
+
+	users := []uuid.UUID{uuid.New(), uuid.New()}
+	where, args = buildTotalFilter(domain.CalculateTotalRequest{UserIDs: users}, "s.")
+	if !strings.Contains(where, "s.user_id IN (?, ?)") || len(args) != len(users) {
+		t.Errorf("unexpected user_ids filter %q with args %v", where, args)
+	}
+}
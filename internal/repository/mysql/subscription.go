@@ -0,0 +1,618 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"aggregator_db/pkg/domain"
+	"aggregator_db/pkg/region"
+	"aggregator_db/pkg/store"
+	"github.com/google/uuid"
+)
+
+// Месяцы хранятся датой первого числа; в API они уходят строками MM-YYYY.
+const selectSubscriptionColumns = `id, service_name, price_minor, user_id,
+        DATE_FORMAT(start_month, '%m-%Y'), DATE_FORMAT(end_month, '%m-%Y'), created_at, updated_at,
+        is_backfilled, exclude_from_new_analytics, backfill_note, status, cancelled_at, cancel_reason, auto_renew,
+        billing_cycle, currency, tags, notes, region, version, custom_fields`
+
+type subscriptionRepo struct {
+	db *UnitOfWork
+}
+
+// NewSubscriptionRepository создает репозиторий поверх единиц работы db: его
+// вызовы с контекстом WithTx идут в транзакцию единицы.
+func NewSubscriptionRepository(db *UnitOfWork) store.SubscriptionRepository {
+	return &subscriptionRepo{db: db}
+}
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func scanSubscription(row scanner) (*domain.Subscription, error) {
+	var sub domain.Subscription
+	var tags, customFields []byte
+	err := row.Scan(
+		&sub.ID,
+		&sub.ServiceName,
+		&sub.Price.Amount,
+		&sub.UserID,
+		&sub.StartDate,
+		&sub.EndDate,
+		&sub.CreatedAt,
+		&sub.UpdatedAt,
+		&sub.Backfilled,
+		&sub.ExcludeFromNewAnalytics,
+		&sub.BackfillNote,
+		&sub.Status,
+		&sub.CancelledAt,
+		&sub.CancelReason,
+		&sub.AutoRenew,
+		&sub.BillingCycle,
+		&sub.Price.Currency,
+		&tags,
+		&sub.Notes,
+		&sub.Region,
+		&sub.Version,
+		&customFields,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(tags, &sub.Tags); err != nil {
+		return nil, fmt.Errorf("subscription %s: tags: %w", sub.ID, err)
+	}
+	// Подписки, созданные до 000002, хранят NULL
+	if customFields != nil {
+		if err := json.Unmarshal(customFields, &sub.CustomFields); err != nil {
+			return nil, fmt.Errorf("subscription %s: custom_fields: %w", sub.ID, err)
+		}
+	}
+	return &sub, nil
+}
+
+func collectSubscriptions(rows *sql.Rows, err error) ([]*domain.Subscription, error) {
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	subs := make([]*domain.Subscription, 0)
+	for rows.Next() {
+		sub, err := scanSubscription(rows)
+		if err != nil {
+			return nil, err
+		}
+		subs = append(subs, sub)
+	}
+	return subs, rows.Err()
+}
+
+// month переводит MM-YYYY в дату первого числа для колонки DATE; nil остается NULL.
+func month(period *string) (any, error) {
+	if period == nil {
+		return nil, nil
+	}
+	t, err := domain.ParsePeriod(*period)
+	if err != nil {
+		return nil, err
+	}
+	return t.Format(time.DateOnly), nil
+}
+
+func tagsJSON(tags []string) string {
+	if tags == nil {
+		tags = []string{}
+	}
+	data, _ := json.Marshal(tags)
+	return string(data)
+}
+
+func customFieldsJSON(values domain.CustomFieldValues) string {
+	if values == nil {
+		values = domain.CustomFieldValues{}
+	}
+	data, _ := json.Marshal(values)
+	return string(data)
+}
+
+const insertSubscriptionQuery = `
+        INSERT INTO subscriptions (id, service_name, price_minor, user_id, start_month, end_month, created_at, updated_at,
+            is_backfilled, exclude_from_new_analytics, backfill_note, status, cancelled_at, cancel_reason, auto_renew,
+            billing_cycle, currency, tags, notes, region, service_key, custom_fields, version)
+        VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, 1)
+    `
+
+func insertArgs(sub *domain.Subscription) ([]any, error) {
+	if sub.Status == "" {
+		sub.Status = domain.StatusActive
+	}
+	sub.BillingCycle = sub.BillingCycle.OrDefault()
+	if sub.Tags == nil {
+		sub.Tags = []string{}
+	}
+	start, err := month(&sub.StartDate)
+	if err != nil {
+		return nil, err
+	}
+	end, err := month(sub.EndDate)
+	if err != nil {
+		return nil, err
+	}
+
+	return []any{
+		sub.ID,
+		sub.ServiceName,
+		sub.Price.Amount,
+		sub.UserID,
+		start,
+		end,
+		sub.CreatedAt,
+		sub.UpdatedAt,
+		sub.Backfilled,
+		sub.ExcludeFromNewAnalytics,
+		sub.BackfillNote,
+		sub.Status,
+		sub.CancelledAt,
+		sub.CancelReason,
+		sub.AutoRenew,
+		sub.BillingCycle,
+		sub.Price.Currency,
+		tagsJSON(sub.Tags),
+		sub.Notes,
+		sub.Region,
+		domain.ServiceKey(sub.ServiceName),
+		customFieldsJSON(sub.CustomFields),
+	}, nil
+}
+
+// subscriptionConflict переводит занятый ID подписки в ErrAlreadyExists.
+func subscriptionConflict(err error) error {
+	if errorNumber(err) == errDuplicateEntry {
+		return store.ErrAlreadyExists
+	}
+	return err
+}
+
+func (r *subscriptionRepo) Create(ctx context.Context, sub *domain.Subscription) error {
+	return r.CreateBatch(ctx, []*domain.Subscription{sub})
+}
+
+// CreateBatch вставляет подписки в одной транзакции: либо все, либо ни одной.
+func (r *subscriptionRepo) CreateBatch(ctx context.Context, subs []*domain.Subscription) error {
+	return r.db.transact(ctx, func(tx DB) error {
+		for i, sub := range subs {
+			sub.Region, sub.Version = region.FromContext(ctx), 1
+			args, err := insertArgs(sub)
+			if err != nil {
+				return err
+			}
+			if _, err := tx.ExecContext(ctx, insertSubscriptionQuery, args...); err != nil {
+				err = subscriptionConflict(err)
+				if len(subs) > 1 {
+					err = fmt.Errorf("item %d: %w", i, err)
+				}
+				return err
+			}
+			if err := insertPrice(ctx, tx, sub, sub.CreatedAt); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Upsert записывает подписку целиком, включая статус и регион.
+func (r *subscriptionRepo) Upsert(ctx context.Context, sub *domain.Subscription) error {
+	sub.Region = region.FromContext(ctx)
+	args, err := insertArgs(sub)
+	if err != nil {
+		return err
+	}
+	query := insertSubscriptionQuery + `
+        ON DUPLICATE KEY UPDATE
+            service_name = VALUES(service_name), price_minor = VALUES(price_minor), user_id = VALUES(user_id),
+            start_month = VALUES(start_month), end_month = VALUES(end_month), created_at = VALUES(created_at),
+            updated_at = VALUES(updated_at), is_backfilled = VALUES(is_backfilled),
+            exclude_from_new_analytics = VALUES(exclude_from_new_analytics), backfill_note = VALUES(backfill_note),
+            status = VALUES(status), cancelled_at = VALUES(cancelled_at), cancel_reason = VALUES(cancel_reason),
+            auto_renew = VALUES(auto_renew), billing_cycle = VALUES(billing_cycle), currency = VALUES(currency),
+            tags = VALUES(tags), notes = VALUES(notes), region = VALUES(region), service_key = VALUES(service_key),
+            custom_fields = VALUES(custom_fields), version = version + 1
+    `
+	return r.db.transact(ctx, func(tx DB) error {
+		if _, err := tx.ExecContext(ctx, query, args...); err != nil {
+			return err
+		}
+		return insertPrice(ctx, tx, sub, sub.UpdatedAt)
+	})
+}
+
+func (r *subscriptionRepo) GetByID(ctx context.Context, id uuid.UUID) (*domain.Subscription, error) {
+	row := r.db.conn(ctx).QueryRowContext(ctx, `SELECT `+selectSubscriptionColumns+` FROM subscriptions WHERE id = ?`, id)
+	sub, err := scanSubscription(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, store.ErrNotFound
+	}
+	return sub, err
+}
+
+func (r *subscriptionRepo) Update(ctx context.Context, sub *domain.Subscription) error {
+	if sub.Tags == nil {
+		sub.Tags = []string{}
+	}
+	sub.Region = region.FromContext(ctx)
+	start, err := month(&sub.StartDate)
+	if err != nil {
+		return err
+	}
+	end, err := month(sub.EndDate)
+	if err != nil {
+		return err
+	}
+
+	return r.db.transact(ctx, func(tx DB) error {
+		result, err := tx.ExecContext(ctx, `
+            UPDATE subscriptions
+            SET service_name = ?, price_minor = ?, start_month = ?, end_month = ?, updated_at = ?, service_key = ?,
+                auto_renew = ?, billing_cycle = ?, currency = ?, tags = ?, notes = ?, region = ?, custom_fields = ?,
+                version = version + 1
+            WHERE id = ? AND version = ?
+        `, sub.ServiceName, sub.Price.Amount, start, end, sub.UpdatedAt, domain.ServiceKey(sub.ServiceName),
+			sub.AutoRenew, sub.BillingCycle.OrDefault(), sub.Price.Currency, tagsJSON(sub.Tags), sub.Notes, sub.Region,
+			customFieldsJSON(sub.CustomFields), sub.ID, sub.Version)
+		if err := versionChecked(ctx, tx, result, err, sub.ID); err != nil {
+			return err
+		}
+		sub.Version++
+		return insertPrice(ctx, tx, sub, sub.UpdatedAt)
+	})
+}
+
+// versionChecked объясняет, почему изменение с проверкой версии не затронуло
+// строку: подписки нет или ее версия уже другая. Версия растет с каждым
+// изменением, поэтому затронутая строка всегда считается измененной.
+func versionChecked(ctx context.Context, tx DB, result sql.Result, err error, id uuid.UUID) error {
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil || affected > 0 {
+		return err
+	}
+	var exists bool
+	if err := tx.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM subscriptions WHERE id = ?)`, id).Scan(&exists); err != nil {
+		return err
+	}
+	if !exists {
+		return store.ErrNotFound
+	}
+	return store.ErrVersionConflict
+}
+
+// requireRows возвращает missing, если запрос не затронул ни одной строки.
+func requireRows(result sql.Result, err error, missing error) error {
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return missing
+	}
+	return nil
+}
+
+func (r *subscriptionRepo) Delete(ctx context.Context, id uuid.UUID) error {
+	result, err := r.db.conn(ctx).ExecContext(ctx, `DELETE FROM subscriptions WHERE id = ?`, id)
+	return requireRows(result, err, store.ErrNotFound)
+}
+
+// DeleteByFilter удаляет все подписки под фильтр одним запросом; история
+// статусов, скидки и история цены удаляются каскадом.
+func (r *subscriptionRepo) DeleteByFilter(ctx context.Context, filter domain.DeleteSubscriptionsFilter) (int, error) {
+	where, args := buildUserServiceFilter("", filter.UserID, filter.ServiceName, filter.ServiceKeys)
+	if filter.EndedBefore != nil {
+		ended, err := month(filter.EndedBefore)
+		if err != nil {
+			return 0, err
+		}
+		where += " AND end_month IS NOT NULL AND end_month < ?"
+		args = append(args, ended)
+	}
+
+	result, err := r.db.conn(ctx).ExecContext(ctx, `DELETE FROM subscriptions WHERE 1=1`+where, args...)
+	if err != nil {
+		return 0, err
+	}
+	deleted, err := result.RowsAffected()
+	return int(deleted), err
+}
+
+// buildUserServiceFilter - общие для всех запросов фильтры пользователя и сервиса;
+// prefix - псевдоним таблицы подписок с точкой или пусто. Ключи сервиса
+// (синонимы названия) важнее точного названия.
+func buildUserServiceFilter(prefix string, userID *uuid.UUID, serviceName *string, serviceKeys []string) (string, []any) {
+	where := ""
+	args := []any{}
+
+	if userID != nil {
+		where += " AND " + prefix + "user_id = ?"
+		args = append(args, *userID)
+	}
+
+	if len(serviceKeys) > 0 {
+		where += " AND " + prefix + "service_key IN (?" + strings.Repeat(", ?", len(serviceKeys)-1) + ")"
+		for _, key := range serviceKeys {
+			args = append(args, key)
+		}
+	} else if serviceName != nil {
+		where += " AND " + prefix + "service_name = ?"
+		args = append(args, *serviceName)
+	}
+
+	return where, args
+}
+
+// tagsFilter оставляет подписки со всеми метками tags.
+func tagsFilter(prefix string, tags []string) (string, []any) {
+	if len(tags) == 0 {
+		return "", nil
+	}
+	return " AND JSON_CONTAINS(" + prefix + "tags, ?)", []any{tagsJSON(tags)}
+}
+
+const defaultListLimit = 100
+
+// buildListFilter собирает WHERE-часть для List и Count.
+func buildListFilter(query domain.ListSubscriptionsQuery) (string, []any) {
+	where, args := buildUserServiceFilter("", query.UserID, query.ServiceName, query.ServiceKeys)
+	where = " WHERE 1=1" + where
+
+	if query.Status != nil {
+		where += " AND status = ?"
+		args = append(args, *query.Status)
+	}
+
+	tags, tagArgs := tagsFilter("", query.Tags)
+	where += tags
+	args = append(args, tagArgs...)
+
+	if query.Currency != "" {
+		where += " AND currency = ?"
+		args = append(args, query.Currency)
+	}
+	if query.MinPriceMinor != nil {
+		where += " AND price_minor >= ?"
+		args = append(args, *query.MinPriceMinor)
+	}
+	if query.MaxPriceMinor != nil {
+		where += " AND price_minor <= ?"
+		args = append(args, *query.MaxPriceMinor)
+	}
+
+	if query.ActiveMonth != nil {
+		active := query.ActiveMonth.Format(time.DateOnly)
+		where += " AND start_month <= ? AND (end_month IS NULL OR end_month >= ?)"
+		args = append(args, active, active)
+	}
+	if len(query.CustomFieldFilter) > 0 {
+		where += " AND JSON_CONTAINS(custom_fields, ?)"
+		args = append(args, customFieldsJSON(query.CustomFieldFilter))
+	}
+
+	if query.CreatedBefore != nil {
+		where += " AND created_at <= ?"
+		args = append(args, *query.CreatedBefore)
+	}
+
+	// Сравнение идет в регистронезависимой сортировке колонок, как ILIKE в Postgres.
+	// Триграмм в MySQL нет, поэтому match=fuzzy ищет так же, как contains
+	for _, word := range strings.Fields(query.Q) {
+		where += " AND CONCAT(service_name, ' ', COALESCE(notes, '')) LIKE ?"
+		args = append(args, "%"+escapeLike(word)+"%")
+	}
+
+	return where, args
+}
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// escapeLike экранирует спецсимволы LIKE, чтобы они искались буквально.
+func escapeLike(s string) string {
+	return likeEscaper.Replace(s)
+}
+
+// listSortColumns - колонки, по которым разрешено сортировать список. Поле
+// сортировки попадает в текст запроса, поэтому берется только отсюда.
+var listSortColumns = map[domain.ListSortField]string{
+	domain.SortByCreatedAt:   "created_at",
+	domain.SortByPrice:       "price_minor",
+	domain.SortByStartDate:   "start_month",
+	domain.SortByServiceName: "service_name",
+}
+
+// listOrderBy возвращает колонку и направление сортировки списка; неизвестное
+// поле дает порядок по умолчанию.
+func listOrderBy(query domain.ListSubscriptionsQuery) string {
+	field, order := query.Sort()
+	column, ok := listSortColumns[field]
+	if !ok {
+		return "created_at DESC"
+	}
+	if order == domain.SortAsc {
+		return column + " ASC"
+	}
+	return column + " DESC"
+}
+
+func buildListQuery(query domain.ListSubscriptionsQuery) (string, []any) {
+	where, args := buildListFilter(query)
+	if query.After != nil {
+		where += " AND (created_at < ? OR (created_at = ? AND id > ?))"
+		args = append(args, query.After.CreatedAt, query.After.CreatedAt, query.After.ID)
+	}
+	// id различает подписки с равными значениями поля сортировки
+	sqlQuery := `SELECT ` + selectSubscriptionColumns + ` FROM subscriptions` + where + ` ORDER BY ` + listOrderBy(query) + `, id`
+
+	limit := query.Limit
+	if limit <= 0 {
+		limit = defaultListLimit
+	}
+	sqlQuery += " LIMIT ? OFFSET ?"
+	args = append(args, limit, max(query.Offset, 0))
+	return sqlQuery, args
+}
+
+func (r *subscriptionRepo) List(ctx context.Context, query domain.ListSubscriptionsQuery) ([]*domain.Subscription, error) {
+	sqlQuery, args := buildListQuery(query)
+	return collectSubscriptions(r.db.conn(ctx).QueryContext(ctx, sqlQuery, args...))
+}
+
+func (r *subscriptionRepo) Count(ctx context.Context, query domain.ListSubscriptionsQuery) (int, error) {
+	where, args := buildListFilter(query)
+	var total int
+	err := r.db.conn(ctx).QueryRowContext(ctx, `SELECT COUNT(*) FROM subscriptions`+where, args...).Scan(&total)
+	return total, err
+}
+
+const insertStatusChangeQuery = `
+        INSERT INTO subscription_status_changes (subscription_id, status, effective_from, changed_at)
+        VALUES (?, ?, ?, ?)
+    `
+
+func insertStatusChange(ctx context.Context, tx DB, change *domain.StatusChange) error {
+	effective, err := month(&change.EffectiveFrom)
+	if err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, insertStatusChangeQuery, change.SubscriptionID, change.Status, effective, change.ChangedAt)
+	return err
+}
+
+func (r *subscriptionRepo) ChangeStatus(ctx context.Context, change *domain.StatusChange) error {
+	return r.db.transact(ctx, func(tx DB) error {
+		result, err := tx.ExecContext(ctx,
+			`UPDATE subscriptions SET status = ?, updated_at = ?, region = ?, version = version + 1 WHERE id = ?`,
+			change.Status, change.ChangedAt, region.FromContext(ctx), change.SubscriptionID,
+		)
+		if err := requireRows(result, err, store.ErrNotFound); err != nil {
+			return err
+		}
+		return insertStatusChange(ctx, tx, change)
+	})
+}
+
+func (r *subscriptionRepo) ListStatusChanges(ctx context.Context, subscriptionIDs []uuid.UUID) ([]*domain.StatusChange, error) {
+	if len(subscriptionIDs) == 0 {
+		return []*domain.StatusChange{}, nil
+	}
+	in, args := inList(subscriptionIDs)
+	rows, err := r.db.conn(ctx).QueryContext(ctx, `
+        SELECT subscription_id, status, DATE_FORMAT(effective_from, '%m-%Y'), changed_at
+        FROM subscription_status_changes
+        WHERE subscription_id IN (`+in+`)
+        ORDER BY subscription_id, effective_from, changed_at
+    `, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	changes := make([]*domain.StatusChange, 0)
+	for rows.Next() {
+		var change domain.StatusChange
+		if err := rows.Scan(&change.SubscriptionID, &change.Status, &change.EffectiveFrom, &change.ChangedAt); err != nil {
+			return nil, err
+		}
+		changes = append(changes, &change)
+	}
+	return changes, rows.Err()
+}
+
+// inList - плейсхолдеры и аргументы для IN (...) по ids; ids не пуст.
+func inList(ids []uuid.UUID) (string, []any) {
+	args := make([]any, len(ids))
+	for i, id := range ids {
+		args[i] = id
+	}
+	return "?" + strings.Repeat(", ?", len(ids)-1), args
+}
+
+func (r *subscriptionRepo) ListRenewable(ctx context.Context, from, to string) ([]*domain.Subscription, error) {
+	fromMonth, err := month(&from)
+	if err != nil {
+		return nil, err
+	}
+	toMonth, err := month(&to)
+	if err != nil {
+		return nil, err
+	}
+	return collectSubscriptions(r.db.conn(ctx).QueryContext(ctx, `
+        SELECT `+selectSubscriptionColumns+`
+        FROM subscriptions
+        WHERE auto_renew AND status = 'active' AND end_month BETWEEN ? AND ?
+        ORDER BY id
+    `, fromMonth, toMonth))
+}
+
+func (r *subscriptionRepo) Renew(ctx context.Context, id uuid.UUID, previousEnd, endDate string, renewedAt time.Time) error {
+	previous, err := month(&previousEnd)
+	if err != nil {
+		return err
+	}
+	next, err := month(&endDate)
+	if err != nil {
+		return err
+	}
+	result, err := r.db.conn(ctx).ExecContext(ctx,
+		`UPDATE subscriptions SET end_month = ?, updated_at = ?, region = ?, version = version + 1
+         WHERE id = ? AND end_month = ? AND auto_renew`,
+		next, renewedAt, region.FromContext(ctx), id, previous,
+	)
+	return requireRows(result, err, store.ErrNotFound)
+}
+
+func (r *subscriptionRepo) Cancel(ctx context.Context, sub *domain.Subscription, change *domain.StatusChange) error {
+	sub.Region = region.FromContext(ctx)
+	end, err := month(sub.EndDate)
+	if err != nil {
+		return err
+	}
+	return r.db.transact(ctx, func(tx DB) error {
+		result, err := tx.ExecContext(ctx, `
+            UPDATE subscriptions
+            SET end_month = ?, status = ?, cancelled_at = ?, cancel_reason = ?, updated_at = ?, auto_renew = ?, region = ?,
+                version = version + 1
+            WHERE id = ? AND version = ?
+        `, end, sub.Status, sub.CancelledAt, sub.CancelReason, sub.UpdatedAt, sub.AutoRenew, sub.Region, sub.ID, sub.Version)
+		if err := versionChecked(ctx, tx, result, err, sub.ID); err != nil {
+			return err
+		}
+		sub.Version++
+		return insertStatusChange(ctx, tx, change)
+	})
+}
+
+func (r *subscriptionRepo) ListHistory(ctx context.Context, req domain.CalculateTotalRequest) ([]*domain.Subscription, error) {
+	end, err := month(&req.EndPeriod)
+	if err != nil {
+		return nil, err
+	}
+	filter, filterArgs := buildTotalFilter(req, "")
+	args := append([]any{end}, filterArgs...)
+	return collectSubscriptions(r.db.conn(ctx).QueryContext(ctx, `
+        SELECT `+selectSubscriptionColumns+`
+        FROM subscriptions
+        WHERE start_month <= ?`+filter+`
+        ORDER BY user_id, start_month, created_at
+    `, args...))
+}
@@ -0,0 +1,147 @@
+package mysql
+
+import (
+	"context"
+	"crypto/sha1"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+
+	"aggregator_db/internal/repository/postgres"
+	"aggregator_db/pkg/store"
+)
+
+// UnitOfWork - store.Transactor поверх MySQL. Репозитории пакета берут из него
+// соединение: транзакцию единицы работы из контекста или пул без нее.
+type UnitOfWork struct {
+	db     *sql.DB
+	retry  postgres.RetryPolicy
+	logger *slog.Logger
+}
+
+// NewUnitOfWork создает единицы работы поверх db. Единица, откаченная сервером
+// из-за взаимоблокировки или ожидания блокировки, выполняется заново по retry.
+func NewUnitOfWork(db *sql.DB, retry postgres.RetryPolicy, logger *slog.Logger) *UnitOfWork {
+	return &UnitOfWork{db: db, retry: retry, logger: logger}
+}
+
+type unitKey struct{}
+
+// unit - транзакция единицы работы на выделенном соединении: блокировки
+// GET_LOCK принадлежат соединению, и их нужно снять до его возврата в пул.
+type unit struct {
+	conn       *sql.Conn
+	tx         *sql.Tx
+	savepoints int
+	locked     bool
+}
+
+func unitFromContext(ctx context.Context) *unit {
+	u, _ := ctx.Value(unitKey{}).(*unit)
+	return u
+}
+
+func (w *UnitOfWork) WithTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	if unitFromContext(ctx) != nil {
+		return fn(ctx)
+	}
+
+	for attempt := 0; ; attempt++ {
+		err := w.run(ctx, fn)
+		if err == nil || attempt >= w.retry.MaxRetries || !IsRetryable(err) {
+			return err
+		}
+
+		w.logger.WarnContext(ctx, "retrying unit of work",
+			slog.Int("attempt", attempt+1),
+			slog.String("error", err.Error()),
+		)
+		if !w.retry.Wait(ctx, attempt) {
+			return err
+		}
+	}
+}
+
+func (w *UnitOfWork) run(ctx context.Context, fn func(ctx context.Context) error) error {
+	conn, err := w.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	u := &unit{conn: conn, tx: tx}
+	defer func() {
+		if u.locked {
+			// Отмененный ctx не должен помешать снять блокировки
+			_, _ = conn.ExecContext(context.WithoutCancel(ctx), `DO RELEASE_ALL_LOCKS()`)
+		}
+	}()
+	// После Commit откат ничего не делает, а при ошибке или панике fn откатывает все
+	defer tx.Rollback()
+
+	if err := fn(context.WithValue(ctx, unitKey{}, u)); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// Lock берет именованную блокировку MySQL до конца единицы работы. Имя
+// ограничено 64 символами, поэтому блокируется хэш key.
+func (w *UnitOfWork) Lock(ctx context.Context, key string) error {
+	u := unitFromContext(ctx)
+	if u == nil {
+		return store.ErrNoTx
+	}
+	sum := sha1.Sum([]byte(key))
+	var acquired sql.NullInt64
+	if err := u.tx.QueryRowContext(ctx, `SELECT GET_LOCK(?, -1)`, "subscriptions:"+hex.EncodeToString(sum[:])).Scan(&acquired); err != nil {
+		return err
+	}
+	if acquired.Int64 != 1 {
+		return fmt.Errorf("lock %q: not acquired", key)
+	}
+	u.locked = true
+	return nil
+}
+
+// conn возвращает транзакцию единицы работы ctx, а без нее - пул.
+func (w *UnitOfWork) conn(ctx context.Context) DB {
+	if u := unitFromContext(ctx); u != nil {
+		return u.tx
+	}
+	return w.db
+}
+
+// transact выполняет fn одной транзакцией, а внутри единицы работы - точкой
+// сохранения в ее транзакции: ошибка fn откатывает только сделанное fn.
+func (w *UnitOfWork) transact(ctx context.Context, fn func(tx DB) error) error {
+	u := unitFromContext(ctx)
+	if u == nil {
+		tx, err := w.db.BeginTx(ctx, nil)
+		if err != nil {
+			return err
+		}
+		defer tx.Rollback()
+		if err := fn(tx); err != nil {
+			return err
+		}
+		return tx.Commit()
+	}
+
+	u.savepoints++
+	savepoint := fmt.Sprintf("sp_%d", u.savepoints)
+	if _, err := u.tx.ExecContext(ctx, "SAVEPOINT "+savepoint); err != nil {
+		return err
+	}
+	if err := fn(u.tx); err != nil {
+		_, _ = u.tx.ExecContext(ctx, "ROLLBACK TO SAVEPOINT "+savepoint)
+		return err
+	}
+	_, err := u.tx.ExecContext(ctx, "RELEASE SAVEPOINT "+savepoint)
+	return err
+}
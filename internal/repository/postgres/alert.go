@@ -0,0 +1,115 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"aggregator_db/pkg/domain"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+var ErrAlertNotFound = errors.New("alert not found")
+
+// AlertRepository - критические уведомления, которые ждут подтверждения.
+type AlertRepository interface {
+	// Create сохраняет уведомление, если уведомления с таким ID еще нет; возвращает, создано ли оно.
+	Create(ctx context.Context, alert *domain.Alert) (bool, error)
+	GetByID(ctx context.Context, id uuid.UUID) (*domain.Alert, error)
+	ListByUser(ctx context.Context, userID uuid.UUID) ([]*domain.Alert, error)
+	// ListDue возвращает открытые уведомления, следующий шаг которых наступил к now.
+	ListDue(ctx context.Context, now time.Time) ([]*domain.Alert, error)
+	// Advance сохраняет Step, EscalatedAt и NextEscalationAt уведомления, если оно
+	// все еще открыто и выполненных шагов по-прежнему from; возвращает, сохранено ли.
+	Advance(ctx context.Context, alert *domain.Alert, from int) (bool, error)
+	// Acknowledge подтверждает уведомление; повторный вызов не меняет время и автора подтверждения.
+	Acknowledge(ctx context.Context, id uuid.UUID, by *uuid.UUID, at time.Time) (*domain.Alert, error)
+}
+
+type alertRepo struct {
+	db DB
+}
+
+func NewAlertRepository(db DB) AlertRepository {
+	return &alertRepo{db: db}
+}
+
+const alertColumns = `id, user_id, type, status, data, steps, step, next_escalation_at, created_at, escalated_at, acknowledged_at, acknowledged_by`
+
+func scanAlert(row pgx.Row) (*domain.Alert, error) {
+	var alert domain.Alert
+	err := row.Scan(&alert.ID, &alert.UserID, &alert.Type, &alert.Status, &alert.Data, &alert.Steps, &alert.Step,
+		&alert.NextEscalationAt, &alert.CreatedAt, &alert.EscalatedAt, &alert.AcknowledgedAt, &alert.AcknowledgedBy)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrAlertNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &alert, nil
+}
+
+func (r *alertRepo) Create(ctx context.Context, alert *domain.Alert) (bool, error) {
+	tag, err := r.db.Exec(ctx, `
+        INSERT INTO alerts (id, user_id, type, status, data, steps, step, next_escalation_at, created_at)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+        ON CONFLICT (id) DO NOTHING
+    `, alert.ID, alert.UserID, alert.Type, alert.Status, alert.Data, alert.Steps, alert.Step, alert.NextEscalationAt, alert.CreatedAt)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() == 1, nil
+}
+
+func (r *alertRepo) GetByID(ctx context.Context, id uuid.UUID) (*domain.Alert, error) {
+	return scanAlert(r.db.QueryRow(ctx, `SELECT `+alertColumns+` FROM alerts WHERE id = $1`, id))
+}
+
+func (r *alertRepo) ListByUser(ctx context.Context, userID uuid.UUID) ([]*domain.Alert, error) {
+	return r.list(ctx, `user_id = $1 ORDER BY created_at DESC, id`, userID)
+}
+
+func (r *alertRepo) ListDue(ctx context.Context, now time.Time) ([]*domain.Alert, error) {
+	return r.list(ctx, `status = 'open' AND next_escalation_at <= $1 ORDER BY next_escalation_at, id`, now)
+}
+
+func (r *alertRepo) list(ctx context.Context, where string, args ...interface{}) ([]*domain.Alert, error) {
+	rows, err := r.db.Query(ctx, `SELECT `+alertColumns+` FROM alerts WHERE `+where, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	alerts := make([]*domain.Alert, 0)
+	for rows.Next() {
+		alert, err := scanAlert(rows)
+		if err != nil {
+			return nil, err
+		}
+		alerts = append(alerts, alert)
+	}
+	return alerts, rows.Err()
+}
+
+func (r *alertRepo) Advance(ctx context.Context, alert *domain.Alert, from int) (bool, error) {
+	tag, err := r.db.Exec(ctx, `
+        UPDATE alerts
+        SET step = $2, escalated_at = $3, next_escalation_at = $4
+        WHERE id = $1 AND status = 'open' AND step = $5
+    `, alert.ID, alert.Step, alert.EscalatedAt, alert.NextEscalationAt, from)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() == 1, nil
+}
+
+func (r *alertRepo) Acknowledge(ctx context.Context, id uuid.UUID, by *uuid.UUID, at time.Time) (*domain.Alert, error) {
+	return scanAlert(r.db.QueryRow(ctx, `
+        UPDATE alerts
+        SET status = 'acknowledged', next_escalation_at = NULL,
+            acknowledged_by = CASE WHEN status = 'open' THEN $2 ELSE acknowledged_by END,
+            acknowledged_at = COALESCE(acknowledged_at, $3)
+        WHERE id = $1
+        RETURNING `+alertColumns, id, by, at))
+}
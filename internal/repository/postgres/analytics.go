@@ -0,0 +1,142 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"aggregator_db/pkg/domain"
+	"github.com/google/uuid"
+)
+
+// YearOverYear считает оба года одним запросом по колонкам start_month и end_month:
+// подписки раскладываются на месяцы двух лет, к каждому месяцу применяются
+// действующие скидки (как в domain.DiscountedCharge), затем месяцы сводятся по номеру.
+func (r *subscriptionRepo) YearOverYear(ctx context.Context, req domain.YearOverYearRequest) ([]domain.YearOverYearUnits, error) {
+	filter, filterArgs := buildTotalFilter(domain.CalculateTotalRequest{
+		UserID:      req.UserID,
+		ServiceName: req.ServiceName,
+		ServiceKeys: req.ServiceKeys,
+		Currency:    req.Currency,
+		Tags:        req.Tags,
+	}, 2)
+
+	// С итогами месяцы до cut неотмеченных пользователей берутся из них, а запрос
+	// по подпискам считает только остальное
+	var monthly []domain.YearOverYearUnits
+	if r.monthlyTotals && len(req.Tags) == 0 {
+		var users []uuid.UUID
+		if req.UserID != nil {
+			users = []uuid.UUID{*req.UserID}
+		}
+		through, stale, err := r.monthlyTotalsCoverage(ctx, users)
+		if err != nil {
+			return nil, err
+		}
+		if through != nil && through.Year() >= req.Year-1 {
+			cut := minTime(*through, time.Date(req.Year, time.December, 1, 0, 0, 0, 0, time.UTC))
+			if stale == nil {
+				stale = []uuid.UUID{}
+			}
+			if monthly, err = r.monthlyYearOverYear(ctx, req.Year, cut, stale, filter, filterArgs); err != nil {
+				return nil, err
+			}
+			filter += fmt.Sprintf(" AND (m.month > $%d OR s.user_id = ANY($%d))", len(filterArgs)+2, len(filterArgs)+3)
+			filterArgs = append(filterArgs, cut, stale)
+		}
+	}
+
+	sqlQuery := `
+        WITH months AS (
+            SELECT month::date AS month
+            FROM generate_series(make_date($1 - 1, 1, 1), make_date($1, 12, 1), interval '1 month') AS month
+        ),
+        charges AS (
+            SELECT m.month, GREATEST(
+                CASE s.billing_cycle
+                    WHEN 'weekly' THEN s.price_minor * ((m.month + interval '1 month')::date - m.month) * 12
+                    WHEN 'yearly' THEN s.price_minor * 7
+                    ELSE s.price_minor * 84
+                END * (100 - d.percent) / 100 - d.fixed * 84,
+                0
+            ) AS units
+            FROM months m
+            JOIN subscriptions s ON s.start_month <= m.month AND (s.end_month IS NULL OR s.end_month >= m.month)
+            CROSS JOIN LATERAL (
+                SELECT
+                    LEAST(COALESCE(SUM(sd.percent) FILTER (WHERE sd.kind = 'percent'), 0), 100) AS percent,
+                    COALESCE(SUM(sd.amount_minor) FILTER (WHERE sd.kind = 'fixed'), 0) AS fixed
+                FROM subscription_discounts sd
+                WHERE sd.subscription_id = s.id
+                    AND sd.start_month <= m.month
+                    AND (sd.end_month IS NULL OR sd.end_month >= m.month)
+            ) d
+            WHERE 1=1` + filter + `
+        )
+        SELECT EXTRACT(MONTH FROM m.month)::int,
+            COALESCE(SUM(c.units) FILTER (WHERE EXTRACT(YEAR FROM m.month) = $1), 0)::bigint,
+            COALESCE(SUM(c.units) FILTER (WHERE EXTRACT(YEAR FROM m.month) = $1 - 1), 0)::bigint
+        FROM months m
+        LEFT JOIN charges c ON c.month = m.month
+        GROUP BY 1
+        ORDER BY 1
+    `
+
+	args := append([]interface{}{req.Year}, filterArgs...)
+	rows, err := r.db.Query(ctx, sqlQuery, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := make([]domain.YearOverYearUnits, 0, 12)
+	for rows.Next() {
+		var month domain.YearOverYearUnits
+		if err := rows.Scan(&month.Month, &month.Current, &month.Previous); err != nil {
+			return nil, err
+		}
+		result = append(result, month)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	for i, month := range monthly {
+		result[i].Current += month.Current
+		result[i].Previous += month.Previous
+	}
+	return result, nil
+}
+
+// monthlyYearOverYear читает из итогов стоимость месяцев года year и предыдущего
+// до cut всех пользователей под filter, кроме stale, по номерам месяцев 1..12.
+func (r *subscriptionRepo) monthlyYearOverYear(ctx context.Context, year int, cut time.Time, stale []uuid.UUID, filter string, filterArgs []interface{}) ([]domain.YearOverYearUnits, error) {
+	// filter нумерует параметры с $2, итогам нужны еще $1 и два последних
+	sqlQuery := `
+        SELECT EXTRACT(MONTH FROM month)::int,
+            COALESCE(SUM(units - discount_units) FILTER (WHERE EXTRACT(YEAR FROM month) = $1), 0)::bigint,
+            COALESCE(SUM(units - discount_units) FILTER (WHERE EXTRACT(YEAR FROM month) = $1 - 1), 0)::bigint
+        FROM subscription_monthly_totals
+        WHERE month BETWEEN make_date($1 - 1, 1, 1) AND $` + fmt.Sprint(len(filterArgs)+2) + `
+            AND NOT (user_id = ANY($` + fmt.Sprint(len(filterArgs)+3) + `))` + filter + `
+        GROUP BY 1
+    `
+	args := append(append([]interface{}{year}, filterArgs...), cut, stale)
+	rows, err := r.db.Query(ctx, sqlQuery, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := make([]domain.YearOverYearUnits, 12)
+	for i := range result {
+		result[i].Month = i + 1
+	}
+	for rows.Next() {
+		var month domain.YearOverYearUnits
+		if err := rows.Scan(&month.Month, &month.Current, &month.Previous); err != nil {
+			return nil, err
+		}
+		result[month.Month-1] = month
+	}
+	return result, rows.Err()
+}
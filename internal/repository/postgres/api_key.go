@@ -0,0 +1,87 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"aggregator_db/pkg/domain"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+var ErrAPIKeyNotFound = errors.New("api key not found")
+
+// APIKeyRepository - ключи внутренних сервисов. Хранятся в public основной базы
+// и работают с базовым пулом, как приложения портала разработчиков.
+type APIKeyRepository interface {
+	Create(ctx context.Context, key *domain.APIKey) error
+	// GetBySecretHash ищет действующий (не отозванный) ключ по хэшу из X-API-Key
+	GetBySecretHash(ctx context.Context, hash string) (*domain.APIKey, error)
+	List(ctx context.Context) ([]*domain.APIKey, error)
+	// Revoke отзывает ключ; повторный отзыв возвращает ErrAPIKeyNotFound.
+	Revoke(ctx context.Context, id uuid.UUID, at time.Time) error
+}
+
+type apiKeyRepo struct {
+	db DB
+}
+
+func NewAPIKeyRepository(db DB) APIKeyRepository {
+	return &apiKeyRepo{db: db}
+}
+
+const apiKeyColumns = `id, name, scope, secret_hash, created_at, revoked_at`
+
+func scanAPIKey(row pgx.Row) (*domain.APIKey, error) {
+	var key domain.APIKey
+	if err := row.Scan(&key.ID, &key.Name, &key.Scope, &key.SecretHash, &key.CreatedAt, &key.RevokedAt); err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
+
+func (r *apiKeyRepo) Create(ctx context.Context, key *domain.APIKey) error {
+	_, err := r.db.Exec(ctx, `INSERT INTO public.api_keys (`+apiKeyColumns+`) VALUES ($1, $2, $3, $4, $5, $6)`,
+		key.ID, key.Name, key.Scope, key.SecretHash, key.CreatedAt, key.RevokedAt)
+	return err
+}
+
+func (r *apiKeyRepo) GetBySecretHash(ctx context.Context, hash string) (*domain.APIKey, error) {
+	row := r.db.QueryRow(ctx, `SELECT `+apiKeyColumns+` FROM public.api_keys WHERE secret_hash = $1 AND revoked_at IS NULL`, hash)
+
+	key, err := scanAPIKey(row)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrAPIKeyNotFound
+	}
+	return key, err
+}
+
+func (r *apiKeyRepo) List(ctx context.Context) ([]*domain.APIKey, error) {
+	rows, err := r.db.Query(ctx, `SELECT `+apiKeyColumns+` FROM public.api_keys ORDER BY created_at, id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	keys := make([]*domain.APIKey, 0)
+	for rows.Next() {
+		key, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	return keys, rows.Err()
+}
+
+func (r *apiKeyRepo) Revoke(ctx context.Context, id uuid.UUID, at time.Time) error {
+	result, err := r.db.Exec(ctx, `UPDATE public.api_keys SET revoked_at = $2 WHERE id = $1 AND revoked_at IS NULL`, id, at)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return ErrAPIKeyNotFound
+	}
+	return nil
+}
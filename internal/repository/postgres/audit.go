@@ -0,0 +1,260 @@
+package postgres
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+
+	"aggregator_db/internal/audit"
+	"aggregator_db/pkg/domain"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// AuditRepository читает журнал изменений, который репозитории пишут в транзакциях изменений.
+type AuditRepository interface {
+	List(ctx context.Context, query domain.ListAuditQuery) ([]*domain.AuditEntry, error)
+	// ListSubscriptionChanges возвращает изменения подписок после query.After в
+	// порядке коммита транзакций и курсор, с которого продолжать.
+	ListSubscriptionChanges(ctx context.Context, query domain.SubscriptionChangesQuery) ([]domain.SubscriptionChange, domain.ChangeCursor, error)
+}
+
+type auditRepo struct {
+	db DB
+}
+
+func NewAuditRepository(db DB) AuditRepository {
+	return &auditRepo{db: db}
+}
+
+func (r *auditRepo) List(ctx context.Context, query domain.ListAuditQuery) ([]*domain.AuditEntry, error) {
+	sqlQuery, args := buildAuditQuery(query)
+	rows, err := r.db.Query(ctx, sqlQuery, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := make([]*domain.AuditEntry, 0)
+	for rows.Next() {
+		var entry domain.AuditEntry
+		if err := rows.Scan(&entry.ID, &entry.Entity, &entry.EntityID, &entry.Action, &entry.Actor,
+			&entry.RequestID, &entry.Before, &entry.After, &entry.CreatedAt); err != nil {
+			return nil, err
+		}
+		entries = append(entries, &entry)
+	}
+	return entries, rows.Err()
+}
+
+// ListSubscriptionChanges читает только транзакции с txid меньше xmin снимка:
+// они все завершены, поэтому транзакция, которая закоммитится позже, не окажется
+// до уже выданного курсора. Долгая транзакция задерживает ленту до своего завершения.
+func (r *auditRepo) ListSubscriptionChanges(ctx context.Context, query domain.SubscriptionChangesQuery) ([]domain.SubscriptionChange, domain.ChangeCursor, error) {
+	var changes []domain.SubscriptionChange
+	var cursor domain.ChangeCursor
+	err := pgx.BeginFunc(ctx, r.db, func(tx pgx.Tx) error {
+		// Снимок один на транзакцию, поэтому xmin и выборка согласованы
+		if _, err := tx.Exec(ctx, `SET TRANSACTION ISOLATION LEVEL REPEATABLE READ, READ ONLY`); err != nil {
+			return err
+		}
+		var xmin uint64
+		if err := tx.QueryRow(ctx, `SELECT pg_snapshot_xmin(pg_current_snapshot())::text::bigint`).Scan(&xmin); err != nil {
+			return err
+		}
+		// Без курсора лента начинается с текущего момента
+		cursor = domain.ChangeCursor{TxID: xmin}
+		if query.After != nil {
+			cursor = *query.After
+		}
+
+		sqlQuery, args := buildSubscriptionChangesQuery(query, cursor, xmin)
+		rows, err := tx.Query(ctx, sqlQuery, args...)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		changes = make([]domain.SubscriptionChange, 0)
+		for rows.Next() {
+			var change domain.SubscriptionChange
+			if err := rows.Scan(&change.SubscriptionID, &change.UserID, &change.Action, &change.Version,
+				&change.ChangedAt, &cursor.TxID, &cursor.EntryID); err != nil {
+				return err
+			}
+			changes = append(changes, change)
+		}
+		if err := rows.Err(); err != nil {
+			return err
+		}
+		// Все транзакции до xmin прочитаны: курсор сдвигается к xmin, чтобы
+		// следующий запрос не перебирал их заново
+		if len(changes) == 0 && cursor.TxID < xmin {
+			cursor = domain.ChangeCursor{TxID: xmin}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, domain.ChangeCursor{}, err
+	}
+	return changes, cursor, nil
+}
+
+func buildSubscriptionChangesQuery(query domain.SubscriptionChangesQuery, after domain.ChangeCursor, xmin uint64) (string, []any) {
+	// xid8 приводится через text: у pgx нет кодека для него
+	sqlQuery := `
+        SELECT entity_id, (COALESCE(after, before)->>'user_id')::uuid, action,
+               (after->>'version')::bigint, created_at, txid::text::bigint, id
+        FROM audit_log
+        WHERE entity = $1
+          AND (txid, id) > ($2::text::xid8, $3)
+          AND txid < $4::text::xid8`
+	args := []any{domain.AuditSubscriptions, strconv.FormatUint(after.TxID, 10), after.EntryID, strconv.FormatUint(xmin, 10)}
+	if query.UserID != nil {
+		args = append(args, query.UserID.String())
+		sqlQuery += fmt.Sprintf(" AND COALESCE(after, before)->>'user_id' = $%d", len(args))
+	}
+	args = append(args, query.Limit)
+	sqlQuery += fmt.Sprintf(" ORDER BY txid, id LIMIT $%d", len(args))
+	return sqlQuery, args
+}
+
+func buildAuditQuery(query domain.ListAuditQuery) (string, []any) {
+	sqlQuery := `
+        SELECT id, entity, entity_id, action, actor, request_id, before, after, created_at
+        FROM audit_log
+        WHERE 1=1`
+	args := []any{}
+	add := func(condition string, value any) {
+		args = append(args, value)
+		sqlQuery += fmt.Sprintf(" AND "+condition, len(args))
+	}
+
+	if query.Entity != "" {
+		add("entity = $%d", query.Entity)
+	}
+	if query.EntityUUID != nil {
+		add("entity_id = $%d", *query.EntityUUID)
+	}
+	if query.Action != "" {
+		add("action = $%d", query.Action)
+	}
+	if query.Actor != "" {
+		add("actor = $%d", query.Actor)
+	}
+	if query.RequestID != "" {
+		add("request_id = $%d", query.RequestID)
+	}
+	if query.Since != nil {
+		add("created_at >= $%d", *query.Since)
+	}
+	if query.Until != nil {
+		add("created_at < $%d", *query.Until)
+	}
+
+	args = append(args, query.Limit, query.Offset)
+	sqlQuery += fmt.Sprintf(" ORDER BY created_at DESC, id LIMIT $%d OFFSET $%d", len(args)-1, len(args))
+	return sqlQuery, args
+}
+
+// auditRows - строки, изменения которых пишутся в журнал: ID по таблицам domain.Audit*.
+type auditRows map[string][]uuid.UUID
+
+// audited выполняет change в транзакции tx и пишет в журнал изменений строки rows
+// до и после изменения, с исполнителем из контекста. Строки блокируются до
+// изменения, поэтому снимок "до" не устаревает.
+func audited(ctx context.Context, tx pgx.Tx, rows auditRows, change func() error) error {
+	entities := make([]string, 0, len(rows))
+	for entity, ids := range rows {
+		if len(ids) > 0 {
+			entities = append(entities, entity)
+		}
+	}
+	sort.Strings(entities)
+
+	before := make(map[string]map[uuid.UUID]json.RawMessage, len(entities))
+	for _, entity := range entities {
+		snapshots, err := auditSnapshots(ctx, tx, entity, rows[entity], true)
+		if err != nil {
+			return err
+		}
+		before[entity] = snapshots
+	}
+	if err := change(); err != nil {
+		return err
+	}
+
+	var entries []*domain.AuditEntry
+	for _, entity := range entities {
+		after, err := auditSnapshots(ctx, tx, entity, rows[entity], false)
+		if err != nil {
+			return err
+		}
+		entries = append(entries, domain.NewAuditEntries(entity, rows[entity], before[entity], after)...)
+	}
+	return writeAudit(ctx, tx, entries)
+}
+
+// auditedTx выполняет change в новой транзакции с записью в журнал изменений (см. audited).
+func auditedTx(ctx context.Context, db DB, rows auditRows, change func(tx pgx.Tx) error) error {
+	return pgx.BeginFunc(ctx, db, func(tx pgx.Tx) error {
+		return audited(ctx, tx, rows, func() error { return change(tx) })
+	})
+}
+
+// auditSnapshots возвращает строки ids таблицы entity в JSON.
+func auditSnapshots(ctx context.Context, tx pgx.Tx, entity string, ids []uuid.UUID, lock bool) (map[uuid.UUID]json.RawMessage, error) {
+	// entity - одна из констант domain.Audit*, а не ввод пользователя
+	query := `SELECT t.id, to_jsonb(t) FROM ` + entity + ` t WHERE t.id = ANY($1)`
+	if lock {
+		query += ` FOR UPDATE`
+	}
+	rows, err := tx.Query(ctx, query, ids)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	snapshots := make(map[uuid.UUID]json.RawMessage, len(ids))
+	for rows.Next() {
+		var id uuid.UUID
+		var snapshot []byte
+		if err := rows.Scan(&id, &snapshot); err != nil {
+			return nil, err
+		}
+		snapshots[id] = snapshot
+	}
+	return snapshots, rows.Err()
+}
+
+// collectIDs возвращает ID строк, выбранных запросом query, например строк,
+// которые удалятся каскадом.
+func collectIDs(ctx context.Context, tx pgx.Tx, query string, args ...any) ([]uuid.UUID, error) {
+	rows, err := tx.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, pgx.RowTo[uuid.UUID])
+}
+
+func writeAudit(ctx context.Context, tx pgx.Tx, entries []*domain.AuditEntry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+	request := audit.FromContext(ctx)
+	var requestID *string
+	if request.RequestID != "" {
+		requestID = &request.RequestID
+	}
+
+	batch := &pgx.Batch{}
+	for _, entry := range entries {
+		batch.Queue(`
+            INSERT INTO audit_log (id, entity, entity_id, action, actor, request_id, before, after)
+            VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+        `, entry.ID, entry.Entity, entry.EntityID, entry.Action, request.Actor, requestID, entry.Before, entry.After)
+	}
+	return tx.SendBatch(ctx, batch).Close()
+}
@@ -0,0 +1,136 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+
+	"aggregator_db/pkg/domain"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+var (
+	ErrBudgetNotFound      = errors.New("budget not found")
+	ErrBudgetCategoryTaken = errors.New("user already has a budget for this category")
+)
+
+type BudgetRepository interface {
+	Create(ctx context.Context, budget *domain.Budget) error
+	GetByID(ctx context.Context, id uuid.UUID) (*domain.Budget, error)
+	// ListByUser возвращает конверты пользователя по категориям.
+	ListByUser(ctx context.Context, userID uuid.UUID) ([]*domain.Budget, error)
+	// ListAll нужен фоновой проверке лимитов: конверты всех пользователей по user_id.
+	ListAll(ctx context.Context) ([]*domain.Budget, error)
+	Update(ctx context.Context, budget *domain.Budget) error
+	Delete(ctx context.Context, id uuid.UUID) error
+}
+
+type budgetRepo struct {
+	db DB
+}
+
+func NewBudgetRepository(db DB) BudgetRepository {
+	return &budgetRepo{db: db}
+}
+
+const budgetColumns = `id, user_id, category, limit_minor, currency, alert_percent, created_at, updated_at`
+
+func scanBudget(row pgx.Row) (*domain.Budget, error) {
+	var budget domain.Budget
+	if err := row.Scan(&budget.ID, &budget.UserID, &budget.Category, &budget.Limit.Amount, &budget.Limit.Currency,
+		&budget.AlertPercent, &budget.CreatedAt, &budget.UpdatedAt); err != nil {
+		return nil, err
+	}
+	return &budget, nil
+}
+
+// budgetConflict переводит нарушения ограничений в ошибки репозитория.
+func budgetConflict(err error) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		switch pgErr.Code {
+		case "23505":
+			return ErrBudgetCategoryTaken
+		case "23503":
+			return ErrUserNotFound
+		}
+	}
+	return err
+}
+
+func (r *budgetRepo) Create(ctx context.Context, budget *domain.Budget) error {
+	err := auditedTx(ctx, r.db, auditRows{domain.AuditBudgets: {budget.ID}}, func(tx pgx.Tx) error {
+		_, err := tx.Exec(ctx, `INSERT INTO budgets (`+budgetColumns+`) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`,
+			budget.ID, budget.UserID, budget.Category, budget.Limit.Amount, budget.Limit.Currency,
+			budget.AlertPercent, budget.CreatedAt, budget.UpdatedAt)
+		return err
+	})
+	return budgetConflict(err)
+}
+
+func (r *budgetRepo) GetByID(ctx context.Context, id uuid.UUID) (*domain.Budget, error) {
+	row := r.db.QueryRow(ctx, `SELECT `+budgetColumns+` FROM budgets WHERE id = $1`, id)
+
+	budget, err := scanBudget(row)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrBudgetNotFound
+	}
+	return budget, err
+}
+
+func (r *budgetRepo) ListByUser(ctx context.Context, userID uuid.UUID) ([]*domain.Budget, error) {
+	return r.list(ctx, `SELECT `+budgetColumns+` FROM budgets WHERE user_id = $1 ORDER BY category`, userID)
+}
+
+func (r *budgetRepo) ListAll(ctx context.Context) ([]*domain.Budget, error) {
+	return r.list(ctx, `SELECT `+budgetColumns+` FROM budgets ORDER BY user_id, category`)
+}
+
+func (r *budgetRepo) list(ctx context.Context, query string, args ...any) ([]*domain.Budget, error) {
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	budgets := make([]*domain.Budget, 0)
+	for rows.Next() {
+		budget, err := scanBudget(rows)
+		if err != nil {
+			return nil, err
+		}
+		budgets = append(budgets, budget)
+	}
+	return budgets, rows.Err()
+}
+
+func (r *budgetRepo) Update(ctx context.Context, budget *domain.Budget) error {
+	err := auditedTx(ctx, r.db, auditRows{domain.AuditBudgets: {budget.ID}}, func(tx pgx.Tx) error {
+		result, err := tx.Exec(ctx, `
+            UPDATE budgets SET category = $2, limit_minor = $3, currency = $4, alert_percent = $5, updated_at = $6
+            WHERE id = $1
+        `, budget.ID, budget.Category, budget.Limit.Amount, budget.Limit.Currency, budget.AlertPercent, budget.UpdatedAt)
+		if err != nil {
+			return err
+		}
+		if result.RowsAffected() == 0 {
+			return ErrBudgetNotFound
+		}
+		return nil
+	})
+	return budgetConflict(err)
+}
+
+func (r *budgetRepo) Delete(ctx context.Context, id uuid.UUID) error {
+	return auditedTx(ctx, r.db, auditRows{domain.AuditBudgets: {id}}, func(tx pgx.Tx) error {
+		result, err := tx.Exec(ctx, `DELETE FROM budgets WHERE id = $1`, id)
+		if err != nil {
+			return err
+		}
+		if result.RowsAffected() == 0 {
+			return ErrBudgetNotFound
+		}
+		return nil
+	})
+}
@@ -0,0 +1,148 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+
+	"aggregator_db/pkg/domain"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// DataRepairRepository читает подписки для проверки целостности и применяет исправления.
+type DataRepairRepository interface {
+	// ScanRecords возвращает до limit подписок с ID больше after по возрастанию ID.
+	ScanRecords(ctx context.Context, after uuid.UUID, limit int) ([]domain.DataRecord, error)
+	// Apply применяет исправления одной транзакцией и пишет их в журнал. Исправление
+	// пропускается, если поле успело измениться после проверки; возвращаются примененные.
+	Apply(ctx context.Context, repairs []*domain.DataRepair) ([]*domain.DataRepair, error)
+	ListRepairs(ctx context.Context, query domain.ListDataRepairsQuery) ([]*domain.DataRepair, error)
+}
+
+type dataRepairRepo struct {
+	db DB
+	// migrations - фазы переходов схемы: исправленные даты и цена копируются в новые колонки
+	migrations domain.SchemaMigrations
+}
+
+func NewDataRepairRepository(db DB, migrations domain.SchemaMigrations) DataRepairRepository {
+	return &dataRepairRepo{db: db, migrations: migrations}
+}
+
+func (r *dataRepairRepo) ScanRecords(ctx context.Context, after uuid.UUID, limit int) ([]domain.DataRecord, error) {
+	rows, err := r.db.Query(ctx, `
+        SELECT s.id, s.user_id, s.start_date, s.end_date, s.price_minor, u.id IS NOT NULL
+        FROM subscriptions s
+        LEFT JOIN users u ON u.id = s.user_id
+        WHERE s.id > $1
+        ORDER BY s.id
+        LIMIT $2
+    `, after, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	records := make([]domain.DataRecord, 0, limit)
+	for rows.Next() {
+		var rec domain.DataRecord
+		if err := rows.Scan(&rec.SubscriptionID, &rec.UserID, &rec.StartDate, &rec.EndDate, &rec.PriceMinor, &rec.UserExists); err != nil {
+			return nil, err
+		}
+		records = append(records, rec)
+	}
+	return records, rows.Err()
+}
+
+// dataRepairUpdates - изменение поля подписки с проверкой, что поле все еще равно Before.
+var dataRepairUpdates = map[string]string{
+	"start_date":  `UPDATE subscriptions SET start_date = $2, updated_at = $4, version = version + 1 WHERE id = $1 AND start_date = $3`,
+	"end_date":    `UPDATE subscriptions SET end_date = $2, updated_at = $4, version = version + 1 WHERE id = $1 AND end_date = $3`,
+	"price_minor": `UPDATE subscriptions SET price_minor = $2::text::bigint, updated_at = $4, version = version + 1 WHERE id = $1 AND price_minor = $3::text::bigint`,
+}
+
+func (r *dataRepairRepo) Apply(ctx context.Context, repairs []*domain.DataRepair) ([]*domain.DataRepair, error) {
+	rows := auditRows{}
+	for _, repair := range repairs {
+		if repair.Fix == domain.FixProvisionUser {
+			rows[domain.AuditUsers] = append(rows[domain.AuditUsers], repair.UserID)
+		} else {
+			rows[domain.AuditSubscriptions] = append(rows[domain.AuditSubscriptions], repair.SubscriptionID)
+		}
+	}
+
+	applied := make([]*domain.DataRepair, 0, len(repairs))
+	err := auditedTx(ctx, r.db, rows, func(tx pgx.Tx) error {
+		applied = applied[:0]
+		for _, repair := range repairs {
+			ok, err := applyDataRepair(ctx, tx, repair)
+			if err != nil {
+				return err
+			}
+			if !ok {
+				continue
+			}
+
+			if _, err := tx.Exec(ctx, `
+                INSERT INTO data_repairs (id, subscription_id, user_id, kind, fix, field, before_value, after_value, applied_at)
+                VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+            `, repair.ID, repair.SubscriptionID, repair.UserID, repair.Kind, repair.Fix, repair.Field,
+				repair.Before, repair.After, repair.AppliedAt); err != nil {
+				return err
+			}
+			applied = append(applied, repair)
+		}
+
+		ids := make([]uuid.UUID, 0, len(applied))
+		for _, repair := range applied {
+			if repair.Fix != domain.FixProvisionUser {
+				ids = append(ids, repair.SubscriptionID)
+			}
+		}
+		return syncDualColumns(ctx, tx, r.migrations, ids)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return applied, nil
+}
+
+func applyDataRepair(ctx context.Context, tx pgx.Tx, repair *domain.DataRepair) (bool, error) {
+	if repair.Fix == domain.FixProvisionUser {
+		_, err := tx.Exec(ctx, provisionUserQuery, repair.UserID, repair.AppliedAt)
+		return err == nil, err
+	}
+	query, ok := dataRepairUpdates[repair.Field]
+	if !ok {
+		return false, fmt.Errorf("unsupported repair field %q", repair.Field)
+	}
+	tag, err := tx.Exec(ctx, query, repair.SubscriptionID, repair.After, repair.Before, repair.AppliedAt)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() == 1, nil
+}
+
+func (r *dataRepairRepo) ListRepairs(ctx context.Context, query domain.ListDataRepairsQuery) ([]*domain.DataRepair, error) {
+	rows, err := r.db.Query(ctx, `
+        SELECT id, subscription_id, user_id, kind, fix, field, before_value, after_value, applied_at
+        FROM data_repairs
+        ORDER BY applied_at DESC, id
+        LIMIT $1 OFFSET $2
+    `, query.Limit, query.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	repairs := make([]*domain.DataRepair, 0)
+	for rows.Next() {
+		var repair domain.DataRepair
+		if err := rows.Scan(&repair.ID, &repair.SubscriptionID, &repair.UserID, &repair.Kind, &repair.Fix,
+			&repair.Field, &repair.Before, &repair.After, &repair.AppliedAt); err != nil {
+			return nil, err
+		}
+		repairs = append(repairs, &repair)
+	}
+	return repairs, rows.Err()
+}
@@ -0,0 +1,85 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+
+	"aggregator_db/pkg/domain"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+var ErrDeveloperAppNotFound = errors.New("developer app not found")
+
+// DeveloperAppRepository - приложения портала разработчиков. Как и реестр тенантов,
+// хранится в public основной базы и работает с базовым пулом.
+type DeveloperAppRepository interface {
+	Create(ctx context.Context, app *domain.DeveloperApp) error
+	Get(ctx context.Context, id uuid.UUID) (*domain.DeveloperApp, error)
+	// GetBySecretHash ищет приложение по хэшу ключа из X-API-Key
+	GetBySecretHash(ctx context.Context, hash string) (*domain.DeveloperApp, error)
+	Update(ctx context.Context, app *domain.DeveloperApp) error
+}
+
+type developerAppRepo struct {
+	db DB
+}
+
+func NewDeveloperAppRepository(db DB) DeveloperAppRepository {
+	return &developerAppRepo{db: db}
+}
+
+const developerAppColumns = `id, name, contact_email, rate_limit_per_minute, sandbox, secret_hash, created_at, updated_at`
+
+func scanDeveloperApp(row pgx.Row) (*domain.DeveloperApp, error) {
+	var app domain.DeveloperApp
+	err := row.Scan(
+		&app.ID,
+		&app.Name,
+		&app.ContactEmail,
+		&app.RateLimitPerMinute,
+		&app.Sandbox,
+		&app.SecretHash,
+		&app.CreatedAt,
+		&app.UpdatedAt,
+	)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrDeveloperAppNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &app, nil
+}
+
+func (r *developerAppRepo) Create(ctx context.Context, app *domain.DeveloperApp) error {
+	_, err := r.db.Exec(ctx, `
+        INSERT INTO public.developer_apps (`+developerAppColumns+`)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+    `, app.ID, app.Name, app.ContactEmail, app.RateLimitPerMinute, app.Sandbox, app.SecretHash, app.CreatedAt, app.UpdatedAt)
+	return err
+}
+
+func (r *developerAppRepo) Get(ctx context.Context, id uuid.UUID) (*domain.DeveloperApp, error) {
+	return scanDeveloperApp(r.db.QueryRow(ctx, `SELECT `+developerAppColumns+` FROM public.developer_apps WHERE id = $1`, id))
+}
+
+func (r *developerAppRepo) GetBySecretHash(ctx context.Context, hash string) (*domain.DeveloperApp, error) {
+	return scanDeveloperApp(r.db.QueryRow(ctx, `SELECT `+developerAppColumns+` FROM public.developer_apps WHERE secret_hash = $1`, hash))
+}
+
+func (r *developerAppRepo) Update(ctx context.Context, app *domain.DeveloperApp) error {
+	result, err := r.db.Exec(ctx, `
+        UPDATE public.developer_apps
+        SET name = $2, contact_email = $3, rate_limit_per_minute = $4, sandbox = $5, secret_hash = $6, updated_at = $7
+        WHERE id = $1
+    `, app.ID, app.Name, app.ContactEmail, app.RateLimitPerMinute, app.Sandbox, app.SecretHash, app.UpdatedAt)
+	if err != nil {
+		return err
+	}
+
+	if result.RowsAffected() == 0 {
+		return ErrDeveloperAppNotFound
+	}
+	return nil
+}
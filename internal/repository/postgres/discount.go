@@ -0,0 +1,82 @@
+package postgres
+
+import (
+	"context"
+
+	"aggregator_db/pkg/domain"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+func (r *subscriptionRepo) CreateDiscount(ctx context.Context, discount *domain.Discount) error {
+	var amount *int64
+	var currency *domain.Currency
+	if discount.Amount != nil {
+		amount, currency = &discount.Amount.Amount, &discount.Amount.Currency
+	}
+
+	return auditedTx(ctx, r.db, auditRows{domain.AuditDiscounts: {discount.ID}}, func(tx pgx.Tx) error {
+		result, err := tx.Exec(ctx, `
+        INSERT INTO subscription_discounts (id, subscription_id, kind, percent, amount_minor, currency, start_month, end_month, code, created_at)
+        SELECT $1, id, $3, $4, $5, $6, TO_DATE($7, 'MM-YYYY'), TO_DATE($8, 'MM-YYYY'), $9, $10
+        FROM subscriptions
+        WHERE id = $2
+    `, discount.ID, discount.SubscriptionID, discount.Kind, discount.Percent, amount, currency,
+			discount.StartMonth, discount.EndMonth, discount.Code, discount.CreatedAt)
+		if err != nil {
+			return err
+		}
+		if result.RowsAffected() == 0 {
+			return ErrNotFound
+		}
+		return nil
+	})
+}
+
+func (r *subscriptionRepo) ListDiscounts(ctx context.Context, subscriptionIDs []uuid.UUID) ([]*domain.Discount, error) {
+	rows, err := r.db.Query(ctx, `
+        SELECT id, subscription_id, kind, percent, amount_minor, currency,
+            TO_CHAR(start_month, 'MM-YYYY'), TO_CHAR(end_month, 'MM-YYYY'), code, created_at
+        FROM subscription_discounts
+        WHERE subscription_id = ANY($1)
+        ORDER BY subscription_id, start_month, created_at
+    `, subscriptionIDs)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	discounts := make([]*domain.Discount, 0)
+	for rows.Next() {
+		var d domain.Discount
+		var amount *int64
+		var currency *domain.Currency
+		if err := rows.Scan(&d.ID, &d.SubscriptionID, &d.Kind, &d.Percent, &amount, &currency,
+			&d.StartMonth, &d.EndMonth, &d.Code, &d.CreatedAt); err != nil {
+			return nil, err
+		}
+		if amount != nil && currency != nil {
+			money := domain.NewMoney(*amount, *currency)
+			d.Amount = &money
+		}
+		discounts = append(discounts, &d)
+	}
+
+	return discounts, rows.Err()
+}
+
+func (r *subscriptionRepo) DeleteDiscount(ctx context.Context, subscriptionID, id uuid.UUID) error {
+	return auditedTx(ctx, r.db, auditRows{domain.AuditDiscounts: {id}}, func(tx pgx.Tx) error {
+		result, err := tx.Exec(ctx,
+			`DELETE FROM subscription_discounts WHERE id = $1 AND subscription_id = $2`,
+			id, subscriptionID,
+		)
+		if err != nil {
+			return err
+		}
+		if result.RowsAffected() == 0 {
+			return ErrDiscountNotFound
+		}
+		return nil
+	})
+}
@@ -0,0 +1,67 @@
+package postgres
+
+import (
+	"context"
+	"time"
+
+	"aggregator_db/pkg/domain"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+type DuplicateRepository interface {
+	// Replace сохраняет результат полного прогона: группы из flags добавляются или
+	// обновляются с сохранением detected_at, остальные удаляются.
+	Replace(ctx context.Context, flags []*domain.DuplicateFlag, now time.Time) error
+	ListByUser(ctx context.Context, userID uuid.UUID) ([]*domain.DuplicateFlag, error)
+}
+
+type duplicateRepo struct {
+	db DB
+}
+
+func NewDuplicateRepository(db DB) DuplicateRepository {
+	return &duplicateRepo{db: db}
+}
+
+func (r *duplicateRepo) Replace(ctx context.Context, flags []*domain.DuplicateFlag, now time.Time) error {
+	return pgx.BeginFunc(ctx, r.db, func(tx pgx.Tx) error {
+		for _, flag := range flags {
+			if _, err := tx.Exec(ctx, `
+                INSERT INTO subscription_duplicates (user_id, service_key, service_name, subscription_ids, detected_at, seen_at)
+                VALUES ($1, $2, $3, $4, $5, $5)
+                ON CONFLICT (user_id, service_key) DO UPDATE
+                SET service_name = EXCLUDED.service_name,
+                    subscription_ids = EXCLUDED.subscription_ids,
+                    seen_at = EXCLUDED.seen_at
+            `, flag.UserID, flag.ServiceKey, flag.ServiceName, flag.SubscriptionIDs, now); err != nil {
+				return err
+			}
+		}
+		_, err := tx.Exec(ctx, `DELETE FROM subscription_duplicates WHERE seen_at < $1`, now)
+		return err
+	})
+}
+
+func (r *duplicateRepo) ListByUser(ctx context.Context, userID uuid.UUID) ([]*domain.DuplicateFlag, error) {
+	rows, err := r.db.Query(ctx, `
+        SELECT user_id, service_key, service_name, subscription_ids, detected_at
+        FROM subscription_duplicates
+        WHERE user_id = $1
+        ORDER BY detected_at, service_key
+    `, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	flags := make([]*domain.DuplicateFlag, 0)
+	for rows.Next() {
+		var flag domain.DuplicateFlag
+		if err := rows.Scan(&flag.UserID, &flag.ServiceKey, &flag.ServiceName, &flag.SubscriptionIDs, &flag.DetectedAt); err != nil {
+			return nil, err
+		}
+		flags = append(flags, &flag)
+	}
+	return flags, rows.Err()
+}
@@ -0,0 +1,153 @@
+package postgres
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"aggregator_db/pkg/domain"
+	"aggregator_db/pkg/tracing"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// ExplainLog собирает планы запросов одного HTTP-запроса. Запросы снимаются,
+// только если контекст содержит ExplainLog (см. WithExplain).
+type ExplainLog struct {
+	mu    sync.Mutex
+	plans []domain.QueryPlan
+}
+
+type explainKey struct{}
+
+func WithExplain(ctx context.Context) (context.Context, *ExplainLog) {
+	log := &ExplainLog{}
+	return context.WithValue(ctx, explainKey{}, log), log
+}
+
+func explainFromContext(ctx context.Context) *ExplainLog {
+	log, _ := ctx.Value(explainKey{}).(*ExplainLog)
+	return log
+}
+
+func (l *ExplainLog) add(plan domain.QueryPlan) {
+	l.mu.Lock()
+	l.plans = append(l.plans, plan)
+	l.mu.Unlock()
+}
+
+func (l *ExplainLog) Plans() []domain.QueryPlan {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([]domain.QueryPlan(nil), l.plans...)
+}
+
+// explainDB перед каждым запросом с ExplainLog в контексте выполняет его под
+// EXPLAIN (ANALYZE) в отдельной транзакции или точке сохранения и откатывает ее,
+// поэтому запись не применяется дважды, но сам запрос фактически выполняется два раза.
+type explainDB struct {
+	next   DB
+	logger *slog.Logger
+}
+
+// NewExplainDB включает отладочный режим репозиториев поверх next. Предназначен
+// для staging: на запросах без ExplainLog накладных расходов нет.
+func NewExplainDB(next DB, logger *slog.Logger) DB {
+	return &explainDB{next: next, logger: logger}
+}
+
+func (d *explainDB) Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error) {
+	explain(ctx, d.next.Begin, d.logger, sql, args)
+	return d.next.Exec(ctx, sql, args...)
+}
+
+func (d *explainDB) Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error) {
+	explain(ctx, d.next.Begin, d.logger, sql, args)
+	return d.next.Query(ctx, sql, args...)
+}
+
+func (d *explainDB) QueryRow(ctx context.Context, sql string, args ...any) pgx.Row {
+	explain(ctx, d.next.Begin, d.logger, sql, args)
+	return d.next.QueryRow(ctx, sql, args...)
+}
+
+func (d *explainDB) Begin(ctx context.Context) (pgx.Tx, error) {
+	tx, err := d.next.Begin(ctx)
+	if err != nil || explainFromContext(ctx) == nil {
+		return tx, err
+	}
+	return &explainTx{Tx: tx, logger: d.logger}, nil
+}
+
+// explainTx снимает планы запросов внутри транзакции; EXPLAIN выполняется в точке сохранения.
+type explainTx struct {
+	pgx.Tx
+	logger *slog.Logger
+}
+
+func (t *explainTx) Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error) {
+	explain(ctx, t.Tx.Begin, t.logger, sql, args)
+	return t.Tx.Exec(ctx, sql, args...)
+}
+
+func (t *explainTx) Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error) {
+	explain(ctx, t.Tx.Begin, t.logger, sql, args)
+	return t.Tx.Query(ctx, sql, args...)
+}
+
+func (t *explainTx) QueryRow(ctx context.Context, sql string, args ...any) pgx.Row {
+	explain(ctx, t.Tx.Begin, t.logger, sql, args)
+	return t.Tx.QueryRow(ctx, sql, args...)
+}
+
+// explainable отбирает запросы, для которых EXPLAIN имеет смысл: служебные
+// команды (SET, LOCK, SAVEPOINT) пропускаются.
+func explainable(sql string) bool {
+	fields := strings.Fields(sql)
+	if len(fields) == 0 {
+		return false
+	}
+	switch strings.ToUpper(fields[0]) {
+	case "SELECT", "INSERT", "UPDATE", "DELETE", "WITH":
+		return true
+	}
+	return false
+}
+
+func explain(ctx context.Context, begin func(context.Context) (pgx.Tx, error), logger *slog.Logger, sql string, args []any) {
+	log := explainFromContext(ctx)
+	if log == nil || !explainable(sql) {
+		return
+	}
+
+	ctx, span := tracing.StartSpan(ctx, "db.explain", slog.String("db.statement", sql))
+	defer span.End()
+
+	start := time.Now()
+	plan := domain.QueryPlan{SQL: sql}
+	var raw []byte
+	tx, err := begin(ctx)
+	if err == nil {
+		err = tx.QueryRow(ctx, "EXPLAIN (ANALYZE, BUFFERS, FORMAT JSON) "+sql, args...).Scan(&raw)
+		// Откат отменяет изменения, сделанные EXPLAIN ANALYZE для INSERT/UPDATE/DELETE
+		_ = tx.Rollback(ctx)
+	}
+	plan.DurationMS = float64(time.Since(start).Microseconds()) / 1000
+	if err != nil {
+		plan.Error = err.Error()
+		span.RecordError(err)
+	} else {
+		plan.Plan = raw
+		span.SetAttributes(slog.String("db.plan", string(raw)))
+	}
+	log.add(plan)
+
+	logger.DebugContext(ctx, "query plan",
+		slog.String("sql", sql),
+		slog.Float64("duration_ms", plan.DurationMS),
+		slog.String("plan", string(plan.Plan)),
+		slog.String("error", plan.Error),
+	)
+}
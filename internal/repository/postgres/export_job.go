@@ -0,0 +1,191 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"aggregator_db/pkg/domain"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+var ErrExportNotFound = errors.New("export not found")
+
+// ExportJobRepository - очередь выгрузок с доставкой на почту. Хранится в public
+// основной базы и работает с базовым пулом.
+type ExportJobRepository interface {
+	Create(ctx context.Context, job *domain.ExportJob) error
+	// CreateOnce создает выгрузку, если выгрузки с ее ID еще нет, и сообщает, создана ли она.
+	CreateOnce(ctx context.Context, job *domain.ExportJob) (bool, error)
+	Get(ctx context.Context, id uuid.UUID) (*domain.ExportJob, error)
+	// Claim переводит в processing до limit ожидающих выгрузок, а также выгрузки,
+	// зависшие в processing с updated_at раньше staleBefore (упавшая реплика).
+	// Параллельные вызовы не получают одну и ту же выгрузку.
+	Claim(ctx context.Context, limit int, now, staleBefore time.Time) ([]*domain.ExportJob, error)
+	Update(ctx context.Context, job *domain.ExportJob) error
+	// PurgeExpired удаляет файлы выгрузок, срок ссылки на которые истек.
+	PurgeExpired(ctx context.Context, now time.Time) (int, error)
+	// Backlog - число выгрузок, еще не отправленных: pending и processing.
+	Backlog(ctx context.Context) (int, error)
+	// ListStatements возвращает до limit последних выписок пользователя тенанта tenantID, новые сверху.
+	ListStatements(ctx context.Context, tenantID string, userID uuid.UUID, limit int) ([]*domain.ExportJob, error)
+}
+
+type exportJobRepo struct {
+	db DB
+}
+
+func NewExportJobRepository(db DB) ExportJobRepository {
+	return &exportJobRepo{db: db}
+}
+
+const exportJobColumns = `id, kind, params, email, delivery, status, attempts, error, file_name, content,
+        download_token_hash, expires_at, created_at, updated_at, delivered_at`
+
+func scanExportJob(row pgx.Row) (*domain.ExportJob, error) {
+	var job domain.ExportJob
+	var tokenHash *string
+	err := row.Scan(
+		&job.ID,
+		&job.Kind,
+		&job.Params,
+		&job.Email,
+		&job.Delivery,
+		&job.Status,
+		&job.Attempts,
+		&job.Error,
+		&job.FileName,
+		&job.Content,
+		&tokenHash,
+		&job.ExpiresAt,
+		&job.CreatedAt,
+		&job.UpdatedAt,
+		&job.DeliveredAt,
+	)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrExportNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if tokenHash != nil {
+		job.DownloadTokenHash = *tokenHash
+	}
+	return &job, nil
+}
+
+func (r *exportJobRepo) Create(ctx context.Context, job *domain.ExportJob) error {
+	_, err := r.db.Exec(ctx, `
+        INSERT INTO public.export_jobs (id, kind, params, email, delivery, status, attempts, created_at, updated_at)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+    `, job.ID, job.Kind, job.Params, job.Email, job.Delivery, job.Status, job.Attempts, job.CreatedAt, job.UpdatedAt)
+	return err
+}
+
+func (r *exportJobRepo) CreateOnce(ctx context.Context, job *domain.ExportJob) (bool, error) {
+	tag, err := r.db.Exec(ctx, `
+        INSERT INTO public.export_jobs (id, kind, params, email, delivery, status, attempts, created_at, updated_at)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+        ON CONFLICT (id) DO NOTHING
+    `, job.ID, job.Kind, job.Params, job.Email, job.Delivery, job.Status, job.Attempts, job.CreatedAt, job.UpdatedAt)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() == 1, nil
+}
+
+func (r *exportJobRepo) Get(ctx context.Context, id uuid.UUID) (*domain.ExportJob, error) {
+	return scanExportJob(r.db.QueryRow(ctx, `SELECT `+exportJobColumns+` FROM public.export_jobs WHERE id = $1`, id))
+}
+
+func (r *exportJobRepo) Claim(ctx context.Context, limit int, now, staleBefore time.Time) ([]*domain.ExportJob, error) {
+	rows, err := r.db.Query(ctx, `
+        UPDATE public.export_jobs
+        SET status = 'processing', attempts = attempts + 1, updated_at = $1
+        WHERE id IN (
+            SELECT id FROM public.export_jobs
+            WHERE status = 'pending' OR (status = 'processing' AND updated_at < $2)
+            ORDER BY created_at
+            LIMIT $3
+            FOR UPDATE SKIP LOCKED
+        )
+        RETURNING `+exportJobColumns, now, staleBefore, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	jobs := make([]*domain.ExportJob, 0)
+	for rows.Next() {
+		job, err := scanExportJob(rows)
+		if err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, job)
+	}
+	return jobs, rows.Err()
+}
+
+func (r *exportJobRepo) Update(ctx context.Context, job *domain.ExportJob) error {
+	var tokenHash *string
+	if job.DownloadTokenHash != "" {
+		tokenHash = &job.DownloadTokenHash
+	}
+
+	result, err := r.db.Exec(ctx, `
+        UPDATE public.export_jobs
+        SET status = $2, attempts = $3, error = $4, file_name = $5, content = $6,
+            download_token_hash = $7, expires_at = $8, updated_at = $9, delivered_at = $10
+        WHERE id = $1
+    `, job.ID, job.Status, job.Attempts, job.Error, job.FileName, job.Content,
+		tokenHash, job.ExpiresAt, job.UpdatedAt, job.DeliveredAt)
+	if err != nil {
+		return err
+	}
+
+	if result.RowsAffected() == 0 {
+		return ErrExportNotFound
+	}
+	return nil
+}
+
+func (r *exportJobRepo) PurgeExpired(ctx context.Context, now time.Time) (int, error) {
+	result, err := r.db.Exec(ctx, `
+        UPDATE public.export_jobs SET content = NULL
+        WHERE content IS NOT NULL AND expires_at < $1
+    `, now)
+	if err != nil {
+		return 0, err
+	}
+	return int(result.RowsAffected()), nil
+}
+
+func (r *exportJobRepo) Backlog(ctx context.Context) (int, error) {
+	var backlog int
+	err := r.db.QueryRow(ctx, `SELECT COUNT(*) FROM public.export_jobs WHERE status IN ('pending', 'processing')`).Scan(&backlog)
+	return backlog, err
+}
+
+func (r *exportJobRepo) ListStatements(ctx context.Context, tenantID string, userID uuid.UUID, limit int) ([]*domain.ExportJob, error) {
+	rows, err := r.db.Query(ctx, `
+        SELECT `+exportJobColumns+` FROM public.export_jobs
+        WHERE kind = 'statement' AND params->>'user_id' = $1 AND COALESCE(params->>'tenant_id', '') = $2
+        ORDER BY created_at DESC
+        LIMIT $3
+    `, userID.String(), tenantID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	jobs := make([]*domain.ExportJob, 0)
+	for rows.Next() {
+		job, err := scanExportJob(rows)
+		if err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, job)
+	}
+	return jobs, rows.Err()
+}
@@ -0,0 +1,307 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"aggregator_db/pkg/domain"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// Помесячные итоги (миграция 000038): subscription_monthly_totals хранит стоимость
+// каждого пользователя, сервиса и валюты за месяцы до through включительно.
+// Триггеры отмечают пользователей, чьи подписки, скидки или статусы изменились,
+// в subscription_monthly_totals_stale, а MonthlyTotalsRepository.Refresh
+// пересчитывает отмеченных и добавляет закончившиеся месяцы. Расчет берет из итогов
+// месяцы до through неотмеченных пользователей, остальное считает по подпискам,
+// поэтому итоги никогда не отстают от подписок.
+
+// MonthlyTotalsRepository поддерживает subscription_monthly_totals.
+type MonthlyTotalsRepository interface {
+	// Refresh доводит итоги до месяца through (первое число; более ранний through
+	// ничего не откатывает) и пересчитывает до batchSize отмеченных пользователей.
+	// Реплики сервиса выполняют Refresh по очереди.
+	Refresh(ctx context.Context, through time.Time, batchSize int) (MonthlyTotalsRefresh, error)
+}
+
+// MonthlyTotalsRefresh - результат одного Refresh.
+type MonthlyTotalsRefresh struct {
+	// Through - последний посчитанный месяц
+	Through time.Time
+	// Built - итоги построены с нуля; иначе Months - сколько месяцев добавлено
+	Built  bool
+	Months int
+	// Users - сколько отмеченных пользователей пересчитано
+	Users int
+}
+
+type monthlyTotalsRepo struct {
+	db DB
+}
+
+func NewMonthlyTotalsRepository(db DB) MonthlyTotalsRepository {
+	return &monthlyTotalsRepo{db: db}
+}
+
+// buildMonthlyTotalsQuery добавляет итоги месяцев [$1, $2] подписок под filter;
+// $1 = NULL - с начала подписок. Стоимость месяца, скидки и статусы - те же
+// выражения, что в расчете стоимости.
+func buildMonthlyTotalsQuery(filter string) string {
+	return `
+        WITH months AS (
+            SELECT id, user_id, service_key, service_name, price_minor, currency, billing_cycle, month::date AS month
+            FROM subscriptions
+            CROSS JOIN LATERAL generate_series(
+                GREATEST(start_month, $1::date),
+                LEAST(COALESCE(end_month, $2::date), $2::date),
+                interval '1 month'
+            ) AS month
+            WHERE 1=1` + filter + `
+        ),
+        charges AS (
+            SELECT m.user_id, m.service_key, m.service_name, m.currency, m.month,
+                ` + monthUnits + ` AS units,
+                ` + billableMonth + ` AS billable,
+                LEAST(COALESCE(SUM(d.percent) FILTER (WHERE d.kind = 'percent'), 0), 100) AS percent,
+                COALESCE(SUM(d.amount_minor) FILTER (WHERE d.kind = 'fixed'), 0) AS fixed
+            FROM months m
+            LEFT JOIN subscription_discounts d ON d.subscription_id = m.id
+                AND d.start_month <= m.month
+                AND (d.end_month IS NULL OR d.end_month >= m.month)
+            GROUP BY m.id, m.user_id, m.service_key, m.service_name, m.month, m.currency, m.price_minor, m.billing_cycle
+        ),
+        discounted AS (
+            SELECT *, units - GREATEST(units * (100 - percent) / 100 - fixed * 84, 0) AS discount
+            FROM charges
+        )
+        INSERT INTO subscription_monthly_totals (user_id, service_key, service_name, currency, month,
+            units, billable_units, discount_units, billable_discount_units, billable_months)
+        SELECT user_id, service_key, service_name, currency, month,
+            SUM(units),
+            COALESCE(SUM(units) FILTER (WHERE billable), 0),
+            SUM(discount),
+            COALESCE(SUM(discount) FILTER (WHERE billable), 0),
+            COUNT(*) FILTER (WHERE billable)
+        FROM discounted
+        GROUP BY user_id, service_key, service_name, currency, month
+    `
+}
+
+func (r *monthlyTotalsRepo) Refresh(ctx context.Context, through time.Time, batchSize int) (MonthlyTotalsRefresh, error) {
+	var result MonthlyTotalsRefresh
+	err := pgx.BeginFunc(ctx, r.db, func(tx pgx.Tx) error {
+		// Блокировка состояния выстраивает пересчеты реплик в очередь
+		var current *time.Time
+		if err := tx.QueryRow(ctx, `SELECT through FROM subscription_monthly_totals_state FOR UPDATE`).Scan(&current); err != nil {
+			return err
+		}
+
+		switch {
+		case current == nil:
+			// Итоги строятся с нуля и учитывают все изменения, поэтому отметки снимаются.
+			// Снимаются только заблокированные здесь: отметку изменения, закоммиченного
+			// после построения, снимать нельзя
+			rows, err := tx.Query(ctx, `SELECT user_id FROM subscription_monthly_totals_stale FOR UPDATE`)
+			if err != nil {
+				return err
+			}
+			marked, err := pgx.CollectRows(rows, pgx.RowTo[uuid.UUID])
+			if err != nil {
+				return err
+			}
+			if _, err := tx.Exec(ctx, `DELETE FROM subscription_monthly_totals`); err != nil {
+				return err
+			}
+			if _, err := tx.Exec(ctx, buildMonthlyTotalsQuery(""), nil, through); err != nil {
+				return err
+			}
+			if _, err := tx.Exec(ctx, `DELETE FROM subscription_monthly_totals_stale WHERE user_id = ANY($1)`, marked); err != nil {
+				return err
+			}
+			result.Built = true
+		case current.Before(through):
+			// Новые месяцы добавляются всем; отмеченные пользователи потом пересчитываются целиком
+			if _, err := tx.Exec(ctx, buildMonthlyTotalsQuery(""), current.AddDate(0, 1, 0), through); err != nil {
+				return err
+			}
+			result.Months = domain.MonthsBetween(*current, through) - 1
+		default:
+			through = *current
+		}
+		if _, err := tx.Exec(ctx, `UPDATE subscription_monthly_totals_state SET through = $1`, through); err != nil {
+			return err
+		}
+		result.Through = through
+		if result.Built {
+			return nil
+		}
+
+		// Отметку, которую держит незакоммиченное изменение, пересчет пропускает до следующего раза
+		rows, err := tx.Query(ctx, `
+            SELECT user_id FROM subscription_monthly_totals_stale
+            ORDER BY marked_at
+            LIMIT $1
+            FOR UPDATE SKIP LOCKED
+        `, batchSize)
+		if err != nil {
+			return err
+		}
+		users, err := pgx.CollectRows(rows, pgx.RowTo[uuid.UUID])
+		if err != nil || len(users) == 0 {
+			return err
+		}
+		if _, err := tx.Exec(ctx, `DELETE FROM subscription_monthly_totals WHERE user_id = ANY($1)`, users); err != nil {
+			return err
+		}
+		if _, err := tx.Exec(ctx, buildMonthlyTotalsQuery(" AND user_id = ANY($3)"), nil, through, users); err != nil {
+			return err
+		}
+		if _, err := tx.Exec(ctx, `DELETE FROM subscription_monthly_totals_stale WHERE user_id = ANY($1)`, users); err != nil {
+			return err
+		}
+		result.Users = len(users)
+		return nil
+	})
+	return result, err
+}
+
+// monthlyTotalsServe сообщает, что расчет req можно брать из итогов: меток в них нет.
+func monthlyTotalsServe(req domain.CalculateTotalRequest) bool {
+	return len(req.Tags) == 0
+}
+
+// monthlyTotalsCoverage возвращает последний посчитанный месяц (nil - итогов нет)
+// и отмеченных пользователей из users; без users - всех отмеченных.
+func (r *subscriptionRepo) monthlyTotalsCoverage(ctx context.Context, users []uuid.UUID) (*time.Time, []uuid.UUID, error) {
+	var through *time.Time
+	var stale []uuid.UUID
+	err := r.db.QueryRow(ctx, `
+        SELECT through, ARRAY(
+            SELECT user_id FROM subscription_monthly_totals_stale
+            WHERE $1::uuid[] IS NULL OR user_id = ANY($1)
+        )
+        FROM subscription_monthly_totals_state
+    `, users).Scan(&through, &stale)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil, nil
+	}
+	return through, stale, err
+}
+
+// monthlyTotalsCut - последний месяц расчета req, который можно взять из итогов
+// по through: не позже конца периода и месяца, которым заканчиваются бессрочные
+// подписки (итоги считают их продолжающимися). ok = false - из итогов брать нечего.
+func monthlyTotalsCut(req domain.CalculateTotalRequest, through time.Time) (cut time.Time, ok bool, err error) {
+	start, err := domain.ParsePeriod(req.StartPeriod)
+	if err != nil {
+		return time.Time{}, false, err
+	}
+	end, err := domain.ParsePeriod(req.EndPeriod)
+	if err != nil {
+		return time.Time{}, false, err
+	}
+	openEnd, err := domain.ParsePeriod(req.OpenEndedEnd())
+	if err != nil {
+		return time.Time{}, false, err
+	}
+	cut = minTime(end, openEnd, through)
+	return cut, !cut.Before(start), nil
+}
+
+func minTime(first time.Time, rest ...time.Time) time.Time {
+	for _, t := range rest {
+		if t.Before(first) {
+			first = t
+		}
+	}
+	return first
+}
+
+// totalUnitsWithMonthly считает стоимость req за вычетом скидок: месяцы до cut
+// неотмеченных пользователей - по итогам, отмеченных пользователей и месяцы после
+// cut - по подпискам.
+func (r *subscriptionRepo) totalUnitsWithMonthly(ctx context.Context, req domain.CalculateTotalRequest, byUser bool) (userUnits, error) {
+	var users []uuid.UUID
+	if req.UserID != nil {
+		users = []uuid.UUID{*req.UserID}
+	} else if len(req.UserIDs) > 0 {
+		users = req.UserIDs
+	}
+	through, stale, err := r.monthlyTotalsCoverage(ctx, users)
+	if err != nil {
+		return nil, err
+	}
+	if through == nil {
+		return r.liveUnits(ctx, req, byUser)
+	}
+	cut, ok, err := monthlyTotalsCut(req, *through)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return r.liveUnits(ctx, req, byUser)
+	}
+
+	units, err := r.monthlyUnits(ctx, req, cut, stale, byUser)
+	if err != nil {
+		return nil, err
+	}
+	if len(stale) > 0 {
+		staleReq := req
+		staleReq.UserID, staleReq.UserIDs = nil, stale
+		staleReq.EndPeriod = domain.FormatPeriod(cut)
+		live, err := r.liveUnits(ctx, staleReq, byUser)
+		if err != nil {
+			return nil, err
+		}
+		units.add(live)
+	}
+	if end, _ := domain.ParsePeriod(req.EndPeriod); cut.Before(end) {
+		restReq := req
+		restReq.StartPeriod = domain.FormatPeriod(cut.AddDate(0, 1, 0))
+		live, err := r.liveUnits(ctx, restReq, byUser)
+		if err != nil {
+			return nil, err
+		}
+		units.add(live)
+	}
+	return units, nil
+}
+
+// monthlyUnits читает из итогов стоимость месяцев [req.StartPeriod, cut] за вычетом
+// скидок всех пользователей под фильтр req, кроме stale.
+func (r *subscriptionRepo) monthlyUnits(ctx context.Context, req domain.CalculateTotalRequest, cut time.Time, stale []uuid.UUID, byUser bool) (userUnits, error) {
+	filter, filterArgs := buildTotalFilter(req, 4)
+	units, discounts, having := "units", "discount_units", ""
+	if req.ExcludeInactive {
+		// Как в billableUnits: валюта без оплачиваемых месяцев в расчет не попадает
+		units, discounts, having = "billable_units", "billable_discount_units", `
+        HAVING SUM(billable_months) > 0`
+	}
+	sqlQuery := `
+        SELECT ` + groupUser(byUser) + ` AS user_id, currency, SUM(` + units + ` - ` + discounts + `)::bigint
+        FROM subscription_monthly_totals
+        WHERE month BETWEEN TO_DATE($1, 'MM-YYYY') AND $2
+            AND NOT (user_id = ANY($3))` + filter + `
+        GROUP BY 1, currency` + having
+
+	if stale == nil {
+		stale = []uuid.UUID{}
+	}
+	args := append([]interface{}{req.StartPeriod, cut, stale}, filterArgs...)
+	return r.queryUnits(ctx, sqlQuery, args...)
+}
+
+// add прибавляет к u суммы other.
+func (u userUnits) add(other userUnits) {
+	for user, byCurrency := range other {
+		if u[user] == nil {
+			u[user] = make(map[domain.Currency]int64, len(byCurrency))
+		}
+		for currency, units := range byCurrency {
+			u[user][currency] += units
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+
+	"aggregator_db/pkg/domain"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+type NotificationSettingsRepository interface {
+	// Get возвращает настройки пользователя; если их нет - настройки по умолчанию (уведомления выключены).
+	Get(ctx context.Context, userID uuid.UUID) (*domain.NotificationSettings, error)
+	Upsert(ctx context.Context, settings *domain.NotificationSettings) error
+	ListSpendAlertsEnabled(ctx context.Context) ([]*domain.NotificationSettings, error)
+	// ListStatementRecipients возвращает настройки пользователей, выбравших канал ежемесячной выписки.
+	ListStatementRecipients(ctx context.Context) ([]*domain.NotificationSettings, error)
+}
+
+type notificationSettingsRepo struct {
+	db DB
+}
+
+func NewNotificationSettingsRepository(db DB) NotificationSettingsRepository {
+	return &notificationSettingsRepo{db: db}
+}
+
+const notificationSettingsColumns = `user_id, spend_alerts, threshold_percent, statement_channel, escalation, updated_at`
+
+func scanNotificationSettings(row pgx.Row) (*domain.NotificationSettings, error) {
+	var settings domain.NotificationSettings
+	if err := row.Scan(&settings.UserID, &settings.SpendAlerts, &settings.ThresholdPercent, &settings.StatementChannel, &settings.Escalation, &settings.UpdatedAt); err != nil {
+		return nil, err
+	}
+	return &settings, nil
+}
+
+func (r *notificationSettingsRepo) Get(ctx context.Context, userID uuid.UUID) (*domain.NotificationSettings, error) {
+	row := r.db.QueryRow(ctx, `SELECT `+notificationSettingsColumns+` FROM user_notification_settings WHERE user_id = $1`, userID)
+
+	settings, err := scanNotificationSettings(row)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return &domain.NotificationSettings{UserID: userID, StatementChannel: domain.StatementNone}, nil
+	}
+	return settings, err
+}
+
+func (r *notificationSettingsRepo) Upsert(ctx context.Context, settings *domain.NotificationSettings) error {
+	escalation := settings.Escalation
+	if escalation == nil {
+		escalation = []domain.EscalationStep{}
+	}
+	_, err := r.db.Exec(ctx, `
+        INSERT INTO user_notification_settings (`+notificationSettingsColumns+`)
+        VALUES ($1, $2, $3, $4, $5, $6)
+        ON CONFLICT (user_id) DO UPDATE
+        SET spend_alerts = EXCLUDED.spend_alerts, threshold_percent = EXCLUDED.threshold_percent,
+            statement_channel = EXCLUDED.statement_channel, escalation = EXCLUDED.escalation,
+            updated_at = EXCLUDED.updated_at
+    `, settings.UserID, settings.SpendAlerts, settings.ThresholdPercent, settings.StatementChannel, escalation, settings.UpdatedAt)
+	return err
+}
+
+func (r *notificationSettingsRepo) ListSpendAlertsEnabled(ctx context.Context) ([]*domain.NotificationSettings, error) {
+	return r.list(ctx, `spend_alerts`)
+}
+
+func (r *notificationSettingsRepo) ListStatementRecipients(ctx context.Context) ([]*domain.NotificationSettings, error) {
+	return r.list(ctx, `statement_channel <> 'none'`)
+}
+
+func (r *notificationSettingsRepo) list(ctx context.Context, where string) ([]*domain.NotificationSettings, error) {
+	rows, err := r.db.Query(ctx, `SELECT `+notificationSettingsColumns+` FROM user_notification_settings WHERE `+where+` ORDER BY user_id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := make([]*domain.NotificationSettings, 0)
+	for rows.Next() {
+		settings, err := scanNotificationSettings(rows)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, settings)
+	}
+
+	return result, rows.Err()
+}
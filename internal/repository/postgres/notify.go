@@ -0,0 +1,40 @@
+package postgres
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// NotifyOnCommit вызывает notify после каждого успешного коммита транзакции,
+// начатой через db, а внутри единицы работы - после ее коммита. Так лента
+// изменений узнает о записи подписок сразу, а не при следующем опросе журнала.
+func NotifyOnCommit(db DB, notify func()) DB {
+	return &notifyingDB{DB: db, notify: notify}
+}
+
+type notifyingDB struct {
+	DB
+	notify func()
+}
+
+func (db *notifyingDB) Begin(ctx context.Context) (pgx.Tx, error) {
+	tx, err := db.DB.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &notifyingTx{Tx: tx, notify: db.notify}, nil
+}
+
+type notifyingTx struct {
+	pgx.Tx
+	notify func()
+}
+
+func (tx *notifyingTx) Commit(ctx context.Context) error {
+	if err := tx.Tx.Commit(ctx); err != nil {
+		return err
+	}
+	AfterCommit(ctx, tx.notify)
+	return nil
+}
@@ -0,0 +1,96 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"aggregator_db/pkg/domain"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+var ErrNudgeNotFound = errors.New("nudge not found")
+
+// NudgeRepository - подсказки администраторам. Хранятся в public основной базы
+// и работают с базовым пулом.
+type NudgeRepository interface {
+	// Create сохраняет подсказку, если подсказки с таким Key еще нет; возвращает, создана ли она.
+	Create(ctx context.Context, nudge *domain.Nudge) (bool, error)
+	List(ctx context.Context, query domain.ListNudgesQuery) ([]*domain.Nudge, error)
+	// Dismiss скрывает подсказку; повторный вызов не меняет время скрытия.
+	Dismiss(ctx context.Context, id uuid.UUID, at time.Time) (*domain.Nudge, error)
+}
+
+type nudgeRepo struct {
+	db DB
+}
+
+func NewNudgeRepository(db DB) NudgeRepository {
+	return &nudgeRepo{db: db}
+}
+
+const nudgeColumns = `id, key, kind, tenant_id, user_id, message, metrics, created_at, dismissed_at`
+
+func scanNudge(row pgx.Row) (*domain.Nudge, error) {
+	var nudge domain.Nudge
+	err := row.Scan(&nudge.ID, &nudge.Key, &nudge.Kind, &nudge.TenantID, &nudge.UserID, &nudge.Message,
+		&nudge.Metrics, &nudge.CreatedAt, &nudge.DismissedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrNudgeNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &nudge, nil
+}
+
+func (r *nudgeRepo) Create(ctx context.Context, nudge *domain.Nudge) (bool, error) {
+	tag, err := r.db.Exec(ctx, `
+        INSERT INTO public.admin_nudges (id, key, kind, tenant_id, user_id, message, metrics, created_at)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+        ON CONFLICT (key) DO NOTHING
+    `, nudge.ID, nudge.Key, nudge.Kind, nudge.TenantID, nudge.UserID, nudge.Message, nudge.Metrics, nudge.CreatedAt)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() == 1, nil
+}
+
+func (r *nudgeRepo) List(ctx context.Context, query domain.ListNudgesQuery) ([]*domain.Nudge, error) {
+	sqlQuery := `SELECT ` + nudgeColumns + ` FROM public.admin_nudges WHERE TRUE`
+	args := []interface{}{}
+	if query.Kind != "" {
+		args = append(args, query.Kind)
+		sqlQuery += fmt.Sprintf(" AND kind = $%d", len(args))
+	}
+	if !query.IncludeDismissed {
+		sqlQuery += " AND dismissed_at IS NULL"
+	}
+	sqlQuery += " ORDER BY created_at DESC, key"
+
+	rows, err := r.db.Query(ctx, sqlQuery, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	nudges := make([]*domain.Nudge, 0)
+	for rows.Next() {
+		nudge, err := scanNudge(rows)
+		if err != nil {
+			return nil, err
+		}
+		nudges = append(nudges, nudge)
+	}
+	return nudges, rows.Err()
+}
+
+func (r *nudgeRepo) Dismiss(ctx context.Context, id uuid.UUID, at time.Time) (*domain.Nudge, error) {
+	return scanNudge(r.db.QueryRow(ctx, `
+        UPDATE public.admin_nudges
+        SET dismissed_at = COALESCE(dismissed_at, $2)
+        WHERE id = $1
+        RETURNING `+nudgeColumns, id, at))
+}
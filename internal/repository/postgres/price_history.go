@@ -0,0 +1,51 @@
+package postgres
+
+import (
+	"context"
+
+	"aggregator_db/pkg/domain"
+	"github.com/google/uuid"
+)
+
+// insertPriceQuery пишет цену подписки в историю, если она отличается от последней записи.
+const insertPriceQuery = `
+        INSERT INTO subscription_price_history (subscription_id, price_minor, currency, billing_cycle, changed_at)
+        SELECT $1, $2, $3, $4, $5
+        WHERE NOT EXISTS (
+            SELECT 1 FROM (
+                SELECT price_minor, currency, billing_cycle
+                FROM subscription_price_history
+                WHERE subscription_id = $1
+                ORDER BY changed_at DESC, id DESC
+                LIMIT 1
+            ) last
+            WHERE last.price_minor = $2 AND last.currency = $3 AND last.billing_cycle = $4
+        )
+    `
+
+func insertPriceArgs(sub *domain.Subscription, changedAt interface{}) []interface{} {
+	return []interface{}{sub.ID, sub.Price.Amount, sub.Price.Currency, sub.BillingCycle.OrDefault(), changedAt}
+}
+
+func (r *subscriptionRepo) ListPriceHistory(ctx context.Context, subscriptionID uuid.UUID) ([]*domain.PriceChange, error) {
+	rows, err := r.db.Query(ctx, `
+        SELECT subscription_id, price_minor, currency, billing_cycle, changed_at
+        FROM subscription_price_history
+        WHERE subscription_id = $1
+        ORDER BY changed_at, id
+    `, subscriptionID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	changes := make([]*domain.PriceChange, 0)
+	for rows.Next() {
+		var change domain.PriceChange
+		if err := rows.Scan(&change.SubscriptionID, &change.Price.Amount, &change.Price.Currency, &change.BillingCycle, &change.ChangedAt); err != nil {
+			return nil, err
+		}
+		changes = append(changes, &change)
+	}
+	return changes, rows.Err()
+}
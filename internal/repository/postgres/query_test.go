@@ -0,0 +1,188 @@
+package postgres
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"aggregator_db/pkg/domain"
+	"github.com/google/uuid"
+)
+
+var placeholderRe = regexp.MustCompile(`\$(\d+)`)
+
+func FuzzBuildListQuery(f *testing.F) {
+	f.Add("Yandex Plus", 10, 0, true, "work", "vpn paypal")
+	f.Add("'; DROP TABLE subscriptions; --", 100, 5, false, "", "%' OR 1=1 --")
+	f.Add("$1", 0, -1, true, "$2", "")
+
+	f.Fuzz(func(t *testing.T, serviceName string, limit, offset int, withUser bool, tag, search string) {
+		query := domain.ListSubscriptionsQuery{
+			ServiceName: &serviceName,
+			Limit:       limit,
+			Offset:      offset,
+			Q:           search,
+		}
+		if withUser {
+			id := uuid.New()
+			query.UserID = &id
+		}
+		if tag != "" {
+			query.Tags = []string{tag}
+		}
+
+		sql, args := buildListQuery(query)
+
+		// Текст запроса не должен зависеть от значений фильтров: ввод идет только в параметры
+		reference := "reference"
+		query.ServiceName = &reference
+		if tag != "" {
+			query.Tags = []string{reference}
+		}
+		query.Q = strings.Repeat(reference+" ", len(strings.Fields(search)))
+		if referenceSQL, _ := buildListQuery(query); referenceSQL != sql {
+			t.Fatalf("service_name leaked into SQL: %q", sql)
+		}
+
+		placeholders := placeholderRe.FindAllStringSubmatch(sql, -1)
+		if len(placeholders) != len(args) {
+			t.Fatalf("placeholders %d != args %d in %q", len(placeholders), len(args), sql)
+		}
+		for i, p := range placeholders {
+			if p[1] != strconv.Itoa(i+1) {
+				t.Fatalf("placeholder #%d is $%s in %q", i+1, p[1], sql)
+			}
+		}
+	})
+}
+
+func TestEscapeLike(t *testing.T) {
+	cases := map[string]string{
+		"vpn":       "vpn",
+		"100%":      `100\%`,
+		"my_plan":   `my\_plan`,
+		`back\path`: `back\\path`,
+	}
+	for in, want := range cases {
+		if got := escapeLike(in); got != want {
+			t.Errorf("escapeLike(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestBuildListFilterFuzzy(t *testing.T) {
+	where, args := buildListFilter(domain.ListSubscriptionsQuery{Q: " yanex plus ", Match: domain.SearchFuzzy})
+	if !strings.Contains(where, "service_name % $1") || strings.Contains(where, "ILIKE") {
+		t.Errorf("unexpected fuzzy filter %q", where)
+	}
+	if len(args) != 1 || args[0] != "yanex plus" {
+		t.Errorf("fuzzy args = %v", args)
+	}
+}
+
+func TestListOrderBy(t *testing.T) {
+	cases := []struct {
+		query domain.ListSubscriptionsQuery
+		want  string
+	}{
+		{domain.ListSubscriptionsQuery{}, "created_at DESC"},
+		{domain.ListSubscriptionsQuery{SortBy: domain.SortByPrice}, "price_minor ASC"},
+		{domain.ListSubscriptionsQuery{SortBy: domain.SortByStartDate, Order: domain.SortDesc}, "start_month DESC"},
+		{domain.ListSubscriptionsQuery{Order: domain.SortAsc}, "created_at ASC"},
+		// Поле вне списка не попадает в запрос
+		{domain.ListSubscriptionsQuery{SortBy: "price_minor; DROP TABLE subscriptions", Order: domain.SortAsc}, "created_at DESC"},
+	}
+	for _, c := range cases {
+		if got := listOrderBy(c.query); got != c.want {
+			t.Errorf("listOrderBy(%q, %q) = %q, want %q", c.query.SortBy, c.query.Order, got, c.want)
+		}
+	}
+}
+
+func TestBuildAuditQuery(t *testing.T) {
+	id := uuid.New()
+	now := time.Now()
+	for _, query := range []domain.ListAuditQuery{
+		{Limit: 100},
+		{Entity: domain.AuditSubscriptions, EntityUUID: &id, Action: domain.AuditUpdate, Actor: "admin", RequestID: "req-1", Since: &now, Until: &now, Limit: 10, Offset: 20},
+	} {
+		sql, args := buildAuditQuery(query)
+		placeholders := placeholderRe.FindAllStringSubmatch(sql, -1)
+		if len(placeholders) != len(args) {
+			t.Fatalf("placeholders %d != args %d in %q", len(placeholders), len(args), sql)
+		}
+		for i, p := range placeholders {
+			if p[1] != strconv.Itoa(i+1) {
+				t.Fatalf("placeholder #%d is $%s in %q", i+1, p[1], sql)
+			}
+		}
+		if args[len(args)-2] != query.Limit || args[len(args)-1] != query.Offset {
+			t.Errorf("limit and offset args = %v", args[len(args)-2:])
+		}
+	}
+}
+
+func TestBuildSubscriptionChangesQuery(t *testing.T) {
+	userID := uuid.New()
+	after := domain.ChangeCursor{TxID: 812, EntryID: uuid.New()}
+	for _, query := range []domain.SubscriptionChangesQuery{
+		{Limit: 100},
+		{UserID: &userID, Limit: 10},
+	} {
+		sql, args := buildSubscriptionChangesQuery(query, after, 900)
+		placeholders := placeholderRe.FindAllStringSubmatch(sql, -1)
+		if len(placeholders) != len(args) {
+			t.Fatalf("placeholders %d != args %d in %q", len(placeholders), len(args), sql)
+		}
+		for i, p := range placeholders {
+			if p[1] != strconv.Itoa(i+1) {
+				t.Fatalf("placeholder #%d is $%s in %q", i+1, p[1], sql)
+			}
+		}
+		if args[1] != "812" || args[2] != after.EntryID || args[3] != "900" {
+			t.Errorf("cursor args = %v", args[1:4])
+		}
+		if args[len(args)-1] != query.Limit {
+			t.Errorf("limit arg = %v", args[len(args)-1])
+		}
+	}
+}
+
+func TestMonthlyTotalsCut(t *testing.T) {
+	through := time.Date(2025, time.September, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name    string
+		req     domain.CalculateTotalRequest
+		wantCut string
+		wantOK  bool
+	}{
+		{name: "past period", req: domain.CalculateTotalRequest{StartPeriod: "01-2025", EndPeriod: "06-2025"}, wantCut: "06-2025", wantOK: true},
+		{name: "up to current month", req: domain.CalculateTotalRequest{StartPeriod: "01-2025", EndPeriod: "10-2025"}, wantCut: "09-2025", wantOK: true},
+		{name: "open-ended capped", req: domain.CalculateTotalRequest{StartPeriod: "01-2025", EndPeriod: "12-2025", OpenEndedUntil: "04-2025"}, wantCut: "04-2025", wantOK: true},
+		{name: "after totals", req: domain.CalculateTotalRequest{StartPeriod: "10-2025", EndPeriod: "12-2025"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cut, ok, err := monthlyTotalsCut(tt.req, through)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if ok != tt.wantOK || (ok && domain.FormatPeriod(cut) != tt.wantCut) {
+				t.Errorf("cut = %s, %v; want %s, %v", domain.FormatPeriod(cut), ok, tt.wantCut, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestBuildListFilterActiveIn(t *testing.T) {
+	month := time.Date(2025, time.October, 1, 0, 0, 0, 0, time.UTC)
+	where, args := buildListFilter(domain.ListSubscriptionsQuery{ActiveMonth: &month})
+	if !strings.Contains(where, "start_month <= $1 AND (end_month IS NULL OR end_month >= $1)") {
+		t.Errorf("unexpected active_in filter %q", where)
+	}
+	if len(args) != 1 || args[0] != month {
+		t.Errorf("active_in args = %v", args)
+	}
+}
@@ -0,0 +1,225 @@
+package postgres
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"aggregator_db/pkg/domain"
+	"aggregator_db/pkg/metrics"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+var replicaReads = metrics.NewCounterVec(
+	"db_replica_reads_total",
+	"Чтения, помеченные для реплики: replica - выполнены на ней, fallback - ушли в основную базу",
+	"target",
+)
+
+type replicaKey struct{}
+
+// PreferReplica помечает чтения ctx как допускающие отставание реплики. Помечать
+// стоит только чтения, результат которых не используется для записи: реплика
+// может не видеть только что записанных строк.
+func PreferReplica(ctx context.Context) context.Context {
+	return context.WithValue(ctx, replicaKey{}, true)
+}
+
+func prefersReplica(ctx context.Context) bool {
+	prefer, _ := ctx.Value(replicaKey{}).(bool)
+	return prefer
+}
+
+// ToleratesLag сообщает, что чтения ctx помечены PreferReplica и допускают
+// отставание данных - от реплики или от кэша.
+func ToleratesLag(ctx context.Context) bool {
+	return prefersReplica(ctx)
+}
+
+type readStampKey struct{}
+
+// ReadStamp - на сколько данные чтений одного запроса отстают от его начала.
+// Отставание измеряется один раз, перед первым чтением из реплики.
+type ReadStamp struct {
+	mu       sync.Mutex
+	measured bool
+	lag      time.Duration
+}
+
+// Lag возвращает отставание прочитанных данных; без чтений из реплики - ноль.
+func (s *ReadStamp) Lag() time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.lag
+}
+
+// ObserveLag отмечает, что данные чтений ctx отстают от начала запроса как
+// минимум на lag: так кэши сообщают возраст ответа. Без WithFreshness ничего не делает.
+func ObserveLag(ctx context.Context, lag time.Duration) {
+	if stamp, _ := ctx.Value(readStampKey{}).(*ReadStamp); stamp != nil {
+		stamp.mu.Lock()
+		stamp.lag = max(stamp.lag, lag)
+		stamp.mu.Unlock()
+	}
+}
+
+// ReadLag возвращает отставание данных чтений ctx на текущий момент; без
+// WithFreshness - ноль.
+func ReadLag(ctx context.Context) time.Duration {
+	stamp, _ := ctx.Value(readStampKey{}).(*ReadStamp)
+	if stamp == nil {
+		return 0
+	}
+	return stamp.Lag()
+}
+
+// WithFreshness помечает чтения ctx требуемой свежестью: eventual (и пустая)
+// разрешает реплику, как PreferReplica, strong оставляет их в основной базе.
+// ReadStamp после чтений сообщает отставание данных от начала запроса.
+func WithFreshness(ctx context.Context, freshness domain.Freshness) (context.Context, *ReadStamp) {
+	stamp := &ReadStamp{}
+	ctx = context.WithValue(ctx, readStampKey{}, stamp)
+	if freshness != domain.FreshnessStrong {
+		ctx = PreferReplica(ctx)
+	}
+	return ctx, stamp
+}
+
+// replicaLagSQL - отставание реплики в секундах. Реплика, применившая весь
+// полученный WAL, не отстает, даже если основная база давно ничего не писала:
+// pg_last_xact_replay_timestamp показывает время последней транзакции, а не
+// свежесть данных. На основной базе функции восстановления возвращают NULL.
+const replicaLagSQL = `
+    SELECT CASE
+        WHEN NOT pg_is_in_recovery() OR pg_last_wal_receive_lsn() = pg_last_wal_replay_lsn() THEN 0
+        ELSE COALESCE(EXTRACT(EPOCH FROM now() - pg_last_xact_replay_timestamp()), 0)
+    END::float8
+`
+
+// ReplicaRouter отдает чтения, помеченные PreferReplica, реплике, а записи,
+// транзакции и остальные чтения - основной базе. Если реплика недоступна, чтение
+// повторяется в основной базе, и следующие retryInterval реплика не используется.
+type ReplicaRouter struct {
+	primary       DB
+	replica       DB
+	retryInterval time.Duration
+	logger        *slog.Logger
+
+	mu        sync.Mutex
+	downUntil time.Time
+}
+
+func NewReplicaRouter(primary, replica DB, retryInterval time.Duration, logger *slog.Logger) *ReplicaRouter {
+	return &ReplicaRouter{
+		primary:       primary,
+		replica:       replica,
+		retryInterval: retryInterval,
+		logger:        logger,
+	}
+}
+
+// useReplica сообщает, что чтение ctx можно отдать реплике прямо сейчас. Для
+// чтений с ReadStamp сначала измеряется отставание реплики; если она при этом
+// недоступна, чтение уходит в основную базу.
+func (r *ReplicaRouter) useReplica(ctx context.Context) bool {
+	if !prefersReplica(ctx) {
+		return false
+	}
+	r.mu.Lock()
+	up := time.Now().After(r.downUntil)
+	r.mu.Unlock()
+	if !up {
+		return false
+	}
+
+	stamp, _ := ctx.Value(readStampKey{}).(*ReadStamp)
+	if stamp == nil {
+		return true
+	}
+	stamp.mu.Lock()
+	defer stamp.mu.Unlock()
+	if stamp.measured {
+		return true
+	}
+	var seconds float64
+	if err := r.replica.QueryRow(ctx, replicaLagSQL).Scan(&seconds); err != nil {
+		if IsUnavailable(err) {
+			r.fallback(ctx, err)
+		}
+		return false
+	}
+	stamp.measured = true
+	stamp.lag = max(stamp.lag, time.Duration(seconds*float64(time.Second)))
+	return true
+}
+
+// fallback выводит реплику из работы на retryInterval после ошибки недоступности.
+func (r *ReplicaRouter) fallback(ctx context.Context, err error) {
+	replicaReads.Inc("fallback")
+	r.mu.Lock()
+	wasUp := time.Now().After(r.downUntil)
+	r.downUntil = time.Now().Add(r.retryInterval)
+	r.mu.Unlock()
+
+	if wasUp {
+		r.logger.WarnContext(ctx, "read replica unavailable, reading from primary",
+			slog.String("error", err.Error()),
+			slog.Duration("retry_in", r.retryInterval),
+		)
+	}
+}
+
+func (r *ReplicaRouter) Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error) {
+	return r.primary.Exec(ctx, sql, args...)
+}
+
+func (r *ReplicaRouter) Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error) {
+	if r.useReplica(ctx) {
+		rows, err := r.replica.Query(ctx, sql, args...)
+		if err == nil || !IsUnavailable(err) {
+			replicaReads.Inc("replica")
+			return rows, err
+		}
+		r.fallback(ctx, err)
+	}
+	return r.primary.Query(ctx, sql, args...)
+}
+
+func (r *ReplicaRouter) QueryRow(ctx context.Context, sql string, args ...any) pgx.Row {
+	if r.useReplica(ctx) {
+		return &replicaRow{
+			router: r,
+			ctx:    ctx,
+			row:    r.replica.QueryRow(ctx, sql, args...),
+			retry:  func() pgx.Row { return r.primary.QueryRow(ctx, sql, args...) },
+		}
+	}
+	return r.primary.QueryRow(ctx, sql, args...)
+}
+
+// Begin всегда открывает транзакцию в основной базе: транзакции пишут или читают
+// то, что собираются записать.
+func (r *ReplicaRouter) Begin(ctx context.Context) (pgx.Tx, error) {
+	return r.primary.Begin(ctx)
+}
+
+// replicaRow повторяет чтение строки в основной базе: ошибку QueryRow pgx
+// возвращает только из Scan.
+type replicaRow struct {
+	router *ReplicaRouter
+	ctx    context.Context
+	row    pgx.Row
+	retry  func() pgx.Row
+}
+
+func (r *replicaRow) Scan(dest ...any) error {
+	err := r.row.Scan(dest...)
+	if err == nil || !IsUnavailable(err) {
+		replicaReads.Inc("replica")
+		return err
+	}
+	r.router.fallback(r.ctx, err)
+	return r.retry().Scan(dest...)
+}
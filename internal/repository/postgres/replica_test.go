@@ -0,0 +1,176 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"aggregator_db/pkg/domain"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// readDB отвечает на чтения ошибкой err и считает их.
+type readDB struct {
+	DB
+	err   error
+	reads int
+}
+
+func (db *readDB) Query(context.Context, string, ...any) (pgx.Rows, error) {
+	db.reads++
+	return nil, db.err
+}
+
+func (db *readDB) QueryRow(context.Context, string, ...any) pgx.Row {
+	db.reads++
+	return errRow{err: db.err}
+}
+
+func TestReplicaRouter(t *testing.T) {
+	down := &pgconn.PgError{Code: "08006"}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	tests := []struct {
+		name        string
+		prefer      bool
+		replicaErr  error
+		wantReplica int
+		wantPrimary int
+		wantErr     error
+	}{
+		{name: "unmarked read goes to primary", wantPrimary: 1},
+		{name: "marked read goes to replica", prefer: true, wantReplica: 1},
+		{name: "query error is not retried", prefer: true, replicaErr: pgx.ErrNoRows, wantReplica: 1, wantErr: pgx.ErrNoRows},
+		{name: "unavailable replica falls back", prefer: true, replicaErr: down, wantReplica: 1, wantPrimary: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tt.prefer {
+				ctx = PreferReplica(ctx)
+			}
+
+			for _, read := range []struct {
+				name string
+				do   func(db DB) error
+			}{
+				{"Query", func(db DB) error {
+					_, err := db.Query(ctx, "SELECT 1")
+					return err
+				}},
+				{"QueryRow", func(db DB) error {
+					return db.QueryRow(ctx, "SELECT 1").Scan()
+				}},
+			} {
+				primary, replica := &readDB{}, &readDB{err: tt.replicaErr}
+				router := NewReplicaRouter(primary, replica, time.Minute, logger)
+
+				if err := read.do(router); !errors.Is(err, tt.wantErr) {
+					t.Errorf("%s error = %v, want %v", read.name, err, tt.wantErr)
+				}
+				if replica.reads != tt.wantReplica || primary.reads != tt.wantPrimary {
+					t.Errorf("%s reads: replica %d, primary %d, want %d and %d",
+						read.name, replica.reads, primary.reads, tt.wantReplica, tt.wantPrimary)
+				}
+			}
+		})
+	}
+}
+
+func TestReplicaRouterRetryInterval(t *testing.T) {
+	ctx := PreferReplica(context.Background())
+	primary, replica := &readDB{}, &readDB{err: &pgconn.PgError{Code: "08006"}}
+	router := NewReplicaRouter(primary, replica, time.Hour, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	for range 3 {
+		if _, err := router.Query(ctx, "SELECT 1"); err != nil {
+			t.Fatalf("Query() error = %v", err)
+		}
+	}
+	// После отказа реплика пропускается до конца интервала
+	if replica.reads != 1 || primary.reads != 3 {
+		t.Errorf("reads: replica %d, primary %d, want 1 and 3", replica.reads, primary.reads)
+	}
+
+	router.downUntil = time.Now().Add(-time.Second)
+	replica.err = nil
+	if _, err := router.Query(ctx, "SELECT 1"); err != nil {
+		t.Fatalf("Query() error = %v", err)
+	}
+	if replica.reads != 2 {
+		t.Errorf("replica reads after interval = %d, want 2", replica.reads)
+	}
+}
+
+// lagDB - реплика, отстающая на lag секунд.
+type lagDB struct {
+	readDB
+	lag float64
+}
+
+type lagRow struct{ lag float64 }
+
+func (r lagRow) Scan(dest ...any) error {
+	*dest[0].(*float64) = r.lag
+	return nil
+}
+
+func (db *lagDB) QueryRow(ctx context.Context, sql string, args ...any) pgx.Row {
+	if sql == replicaLagSQL {
+		return lagRow{lag: db.lag}
+	}
+	return db.readDB.QueryRow(ctx, sql, args...)
+}
+
+func TestWithFreshness(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	t.Run("strong reads primary", func(t *testing.T) {
+		primary, replica := &readDB{}, &lagDB{lag: 5}
+		router := NewReplicaRouter(primary, replica, time.Minute, logger)
+		ctx, stamp := WithFreshness(context.Background(), domain.FreshnessStrong)
+
+		if _, err := router.Query(ctx, "SELECT 1"); err != nil {
+			t.Fatal(err)
+		}
+		if primary.reads != 1 || replica.reads != 0 || stamp.Lag() != 0 {
+			t.Errorf("reads: primary %d, replica %d, lag %s", primary.reads, replica.reads, stamp.Lag())
+		}
+	})
+
+	t.Run("eventual measures replica lag once", func(t *testing.T) {
+		primary, replica := &readDB{}, &lagDB{lag: 2.5}
+		router := NewReplicaRouter(primary, replica, time.Minute, logger)
+		ctx, stamp := WithFreshness(context.Background(), domain.FreshnessEventual)
+
+		for range 2 {
+			if _, err := router.Query(ctx, "SELECT 1"); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if primary.reads != 0 || replica.reads != 2 {
+			t.Errorf("reads: primary %d, replica %d", primary.reads, replica.reads)
+		}
+		if stamp.Lag() != 2500*time.Millisecond {
+			t.Errorf("Lag() = %s, want 2.5s", stamp.Lag())
+		}
+	})
+
+	t.Run("unavailable replica falls back before read", func(t *testing.T) {
+		primary, replica := &readDB{}, &readDB{err: &pgconn.PgError{Code: "08006"}}
+		router := NewReplicaRouter(primary, replica, time.Minute, logger)
+		ctx, stamp := WithFreshness(context.Background(), "")
+
+		if _, err := router.Query(ctx, "SELECT 1"); err != nil {
+			t.Fatal(err)
+		}
+		// Чтение отставания - единственное обращение к реплике
+		if primary.reads != 1 || replica.reads != 1 || stamp.Lag() != 0 {
+			t.Errorf("reads: primary %d, replica %d, lag %s", primary.reads, replica.reads, stamp.Lag())
+		}
+	})
+}
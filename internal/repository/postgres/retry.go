@@ -0,0 +1,92 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// RetryPolicy - повторы обращений к базе после временных ошибок
+// (DB_MAX_RETRIES, DB_RETRY_BACKOFF, DB_RETRY_MAX_BACKOFF).
+type RetryPolicy struct {
+	// MaxRetries - число повторов после первой попытки; 0 - без повторов
+	MaxRetries int
+	// Backoff - пауза перед первым повтором; перед каждым следующим она удваивается
+	Backoff time.Duration
+	// MaxBackoff ограничивает паузу; 0 - без ограничения
+	MaxBackoff time.Duration
+}
+
+// Delay - пауза перед повтором attempt (с 0). Случайная половина паузы разводит
+// повторы реплик, упавших на одном сбое базы.
+func (p RetryPolicy) Delay(attempt int) time.Duration {
+	delay := p.Backoff
+	for range attempt {
+		if p.MaxBackoff > 0 && delay >= p.MaxBackoff {
+			break
+		}
+		delay *= 2
+	}
+	if p.MaxBackoff > 0 {
+		delay = min(delay, p.MaxBackoff)
+	}
+	if delay <= 0 {
+		return 0
+	}
+	half := delay / 2
+	return half + rand.N(delay-half+1)
+}
+
+// Wait ждет паузу перед повтором attempt; false - ctx завершился раньше.
+func (p RetryPolicy) Wait(ctx context.Context, attempt int) bool {
+	timer := time.NewTimer(p.Delay(attempt))
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
+
+// IsRetryable отбирает ошибки, после которых повтор не приведет к двойной записи:
+// запрос не был отправлен на сервер, либо транзакция была откачена сервером
+// (сбой сериализации, взаимоблокировка). Чтение (idempotent) повторяется и после
+// обрыва соединения посреди запроса.
+func IsRetryable(err error, idempotent bool) bool {
+	if err == nil {
+		return false
+	}
+	if pgconn.SafeToRetry(err) {
+		return true
+	}
+	switch RetryReason(err) {
+	case "serialization", "deadlock":
+		return true
+	case "connection":
+		return idempotent
+	}
+	return false
+}
+
+// RetryReason - вид временной ошибки для метрик повторов: serialization,
+// deadlock, connection или other.
+func RetryReason(err error) string {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		switch pgErr.Code {
+		case "40001":
+			return "serialization"
+		case "40P01":
+			return "deadlock"
+		}
+	}
+	if IsUnavailable(err) {
+		return "connection"
+	}
+	return "other"
+}
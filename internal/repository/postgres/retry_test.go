@@ -0,0 +1,63 @@
+package postgres
+
+import (
+	"fmt"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+func TestIsRetryable(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		write      bool
+		read       bool
+		wantReason string
+	}{
+		{name: "serialization failure", err: &pgconn.PgError{Code: "40001"}, write: true, read: true, wantReason: "serialization"},
+		{name: "deadlock", err: fmt.Errorf("update: %w", &pgconn.PgError{Code: "40P01"}), write: true, read: true, wantReason: "deadlock"},
+		{name: "connection reset", err: &pgconn.PgError{Code: "08006"}, read: true, wantReason: "connection"},
+		{name: "unexpected eof", err: io.ErrUnexpectedEOF, wantReason: "other"},
+		{name: "unique violation", err: &pgconn.PgError{Code: "23505"}, wantReason: "other"},
+		{name: "not found", err: ErrNotFound, wantReason: "other"},
+		{name: "nil", wantReason: "other"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsRetryable(tt.err, false); got != tt.write {
+				t.Errorf("IsRetryable(write) = %v, want %v", got, tt.write)
+			}
+			if got := IsRetryable(tt.err, true); got != tt.read {
+				t.Errorf("IsRetryable(read) = %v, want %v", got, tt.read)
+			}
+			if tt.err != nil {
+				if got := RetryReason(tt.err); got != tt.wantReason {
+					t.Errorf("RetryReason() = %q, want %q", got, tt.wantReason)
+				}
+			}
+		})
+	}
+
+	if !IsRetryable(fmt.Errorf("%w: reset", ErrUnavailable), true) {
+		t.Error("wrapped ErrUnavailable is not retried for reads")
+	}
+}
+
+func TestRetryPolicyDelay(t *testing.T) {
+	policy := RetryPolicy{Backoff: 100 * time.Millisecond, MaxBackoff: 300 * time.Millisecond}
+	for attempt, want := range []time.Duration{100, 200, 300, 300} {
+		want *= time.Millisecond
+		for range 20 {
+			if got := policy.Delay(attempt); got < want/2 || got > want {
+				t.Fatalf("Delay(%d) = %s, want within [%s, %s]", attempt, got, want/2, want)
+			}
+		}
+	}
+
+	if got := (RetryPolicy{}).Delay(3); got != 0 {
+		t.Errorf("Delay without backoff = %s, want 0", got)
+	}
+}
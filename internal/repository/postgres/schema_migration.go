@@ -0,0 +1,89 @@
+package postgres
+
+import (
+	"context"
+	"strings"
+
+	"aggregator_db/pkg/domain"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// SchemaMigrationRepository сверяет и заполняет новые колонки подписок переходов
+// blue/green (см. domain.SchemaMigrations).
+type SchemaMigrationRepository interface {
+	// ScanDualColumns возвращает до limit подписок с ID больше after по возрастанию ID.
+	ScanDualColumns(ctx context.Context, after uuid.UUID, limit int) ([]domain.DualColumnsRecord, error)
+	// Backfill заново вычисляет новые колонки подписок ids из старых.
+	Backfill(ctx context.Context, ids []uuid.UUID) error
+}
+
+type schemaMigrationRepo struct {
+	db         DB
+	migrations domain.SchemaMigrations
+}
+
+func NewSchemaMigrationRepository(db DB, migrations domain.SchemaMigrations) SchemaMigrationRepository {
+	return &schemaMigrationRepo{db: db, migrations: migrations}
+}
+
+func (r *schemaMigrationRepo) ScanDualColumns(ctx context.Context, after uuid.UUID, limit int) ([]domain.DualColumnsRecord, error) {
+	rows, err := r.db.Query(ctx, `
+        SELECT id, start_date, end_date, price_minor, currency, start_on, end_on, price_amount::text
+        FROM subscriptions
+        WHERE id > $1
+        ORDER BY id
+        LIMIT $2
+    `, after, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	records := make([]domain.DualColumnsRecord, 0, limit)
+	for rows.Next() {
+		var rec domain.DualColumnsRecord
+		if err := rows.Scan(&rec.SubscriptionID, &rec.StartDate, &rec.EndDate, &rec.PriceMinor, &rec.Currency,
+			&rec.StartOn, &rec.EndOn, &rec.PriceAmount); err != nil {
+			return nil, err
+		}
+		records = append(records, rec)
+	}
+	return records, rows.Err()
+}
+
+func (r *schemaMigrationRepo) Backfill(ctx context.Context, ids []uuid.UUID) error {
+	return syncDualColumns(ctx, r.db, r.migrations, ids)
+}
+
+type execer interface {
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+}
+
+// syncDualColumns вычисляет новые колонки подписок ids из старых для переходов,
+// которые их пишут. Вызывается в транзакции записи старых колонок, поэтому новые
+// не расходятся со старыми даже при параллельных изменениях.
+func syncDualColumns(ctx context.Context, db execer, migrations domain.SchemaMigrations, ids []uuid.UUID) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	var set []string
+	args := []any{ids}
+	if migrations.Dates.WritesNew() {
+		// start_month и end_month уже вычислены из строк MM-YYYY (см. миграцию 000020)
+		set = append(set, "start_on = start_month", "end_on = end_month")
+	}
+	if migrations.Money.WritesNew() {
+		// Число знаков валюты известно только приложению, поэтому передается параметром
+		codes, exponents := domain.CurrencyExponents()
+		set = append(set, `price_amount = price_minor::numeric / power(10::numeric, COALESCE(
+            (SELECT e.exponent FROM unnest($2::text[], $3::int[]) AS e(code, exponent) WHERE e.code = currency), 2))`)
+		args = append(args, codes, exponents)
+	}
+	if len(set) == 0 {
+		return nil
+	}
+
+	_, err := db.Exec(ctx, `UPDATE subscriptions SET `+strings.Join(set, ", ")+` WHERE id = ANY($1)`, args...)
+	return err
+}
@@ -0,0 +1,111 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+
+	"aggregator_db/pkg/domain"
+	"github.com/jackc/pgx/v5"
+)
+
+var ErrAliasNotFound = errors.New("service alias not found")
+
+type ServiceAliasRepository interface {
+	Upsert(ctx context.Context, alias *domain.ServiceAlias) error
+	Get(ctx context.Context, aliasKey string) (*domain.ServiceAlias, error)
+	Delete(ctx context.Context, aliasKey string) error
+	List(ctx context.Context) ([]*domain.ServiceAlias, error)
+	// AliasKeys возвращает ключи всех алиасов, сведенных к canonicalKey.
+	AliasKeys(ctx context.Context, canonicalKey string) ([]string, error)
+}
+
+type serviceAliasRepo struct {
+	db DB
+}
+
+func NewServiceAliasRepository(db DB) ServiceAliasRepository {
+	return &serviceAliasRepo{db: db}
+}
+
+const serviceAliasColumns = `alias, alias_key, canonical_name, canonical_key, created_at`
+
+func scanServiceAlias(row pgx.Row) (*domain.ServiceAlias, error) {
+	var alias domain.ServiceAlias
+	if err := row.Scan(&alias.Alias, &alias.AliasKey, &alias.Canonical, &alias.CanonicalKey, &alias.CreatedAt); err != nil {
+		return nil, err
+	}
+	return &alias, nil
+}
+
+// Upsert сохраняет алиас и перенаправляет на новое каноническое название
+// алиасы, которые указывали на сам alias_key, чтобы не образовывались цепочки.
+func (r *serviceAliasRepo) Upsert(ctx context.Context, alias *domain.ServiceAlias) error {
+	return pgx.BeginFunc(ctx, r.db, func(tx pgx.Tx) error {
+		_, err := tx.Exec(ctx, `
+            UPDATE service_aliases
+            SET canonical_key = $2, canonical_name = $3
+            WHERE canonical_key = $1
+        `, alias.AliasKey, alias.CanonicalKey, alias.Canonical)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.Exec(ctx, `
+            INSERT INTO service_aliases (`+serviceAliasColumns+`)
+            VALUES ($1, $2, $3, $4, $5)
+            ON CONFLICT (alias_key) DO UPDATE
+            SET alias = EXCLUDED.alias, canonical_name = EXCLUDED.canonical_name, canonical_key = EXCLUDED.canonical_key
+        `, alias.Alias, alias.AliasKey, alias.Canonical, alias.CanonicalKey, alias.CreatedAt)
+		return err
+	})
+}
+
+func (r *serviceAliasRepo) Get(ctx context.Context, aliasKey string) (*domain.ServiceAlias, error) {
+	row := r.db.QueryRow(ctx, `SELECT `+serviceAliasColumns+` FROM service_aliases WHERE alias_key = $1`, aliasKey)
+
+	alias, err := scanServiceAlias(row)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrAliasNotFound
+	}
+	return alias, err
+}
+
+func (r *serviceAliasRepo) Delete(ctx context.Context, aliasKey string) error {
+	result, err := r.db.Exec(ctx, `DELETE FROM service_aliases WHERE alias_key = $1`, aliasKey)
+	if err != nil {
+		return err
+	}
+
+	if result.RowsAffected() == 0 {
+		return ErrAliasNotFound
+	}
+
+	return nil
+}
+
+func (r *serviceAliasRepo) List(ctx context.Context) ([]*domain.ServiceAlias, error) {
+	rows, err := r.db.Query(ctx, `SELECT `+serviceAliasColumns+` FROM service_aliases ORDER BY canonical_key, alias_key`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	aliases := make([]*domain.ServiceAlias, 0)
+	for rows.Next() {
+		alias, err := scanServiceAlias(rows)
+		if err != nil {
+			return nil, err
+		}
+		aliases = append(aliases, alias)
+	}
+
+	return aliases, rows.Err()
+}
+
+func (r *serviceAliasRepo) AliasKeys(ctx context.Context, canonicalKey string) ([]string, error) {
+	rows, err := r.db.Query(ctx, `SELECT alias_key FROM service_aliases WHERE canonical_key = $1`, canonicalKey)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, pgx.RowTo[string])
+}
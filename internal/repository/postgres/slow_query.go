@@ -0,0 +1,188 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"reflect"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"aggregator_db/pkg/metrics"
+	"github.com/jackc/pgx/v5"
+)
+
+const (
+	// maxStatementLength и maxArgLength ограничивают запись о запросе в логе:
+	// CTE расчета суммы занимает несколько килобайт, а аргументы бывают массивами ID.
+	maxStatementLength = 2000
+	maxArgLength       = 64
+	maxArgItems        = 5
+)
+
+var slowStatements = metrics.NewCounterVec(
+	"db_slow_statements_total",
+	"Количество SQL-запросов дольше DB_SLOW_STATEMENT_THRESHOLD по виду запроса; BATCH - батч целиком",
+	"operation",
+)
+
+// SlowQueryTracer - трассировщик pgx, который пишет в лог запросы дольше порога
+// с текстом, аргументами и длительностью. В отличие от декоратора instrumented,
+// видит каждый SQL-запрос, а не вызов репозитория целиком.
+type SlowQueryTracer struct {
+	threshold time.Duration
+	logger    *slog.Logger
+}
+
+func NewSlowQueryTracer(threshold time.Duration, logger *slog.Logger) *SlowQueryTracer {
+	return &SlowQueryTracer{threshold: threshold, logger: logger}
+}
+
+type slowQueryKey struct{}
+
+type slowQueryStart struct {
+	at   time.Time
+	sql  string
+	args []any
+	// queries - число запросов батча
+	queries int
+}
+
+func (t *SlowQueryTracer) TraceQueryStart(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryStartData) context.Context {
+	return context.WithValue(ctx, slowQueryKey{}, slowQueryStart{at: time.Now(), sql: data.SQL, args: data.Args})
+}
+
+func (t *SlowQueryTracer) TraceQueryEnd(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryEndData) {
+	start, ok := ctx.Value(slowQueryKey{}).(slowQueryStart)
+	if !ok {
+		return
+	}
+	duration := time.Since(start.at)
+	if duration < t.threshold {
+		return
+	}
+
+	statement := NormalizeStatement(start.sql)
+	slowStatements.Inc(statementOperation(statement))
+	attrs := []any{
+		slog.String("statement", statement),
+		slog.Any("args", NormalizeArgs(start.args)),
+		slog.Duration("duration", duration),
+		slog.Duration("threshold", t.threshold),
+	}
+	if data.Err != nil {
+		attrs = append(attrs, slog.String("error", data.Err.Error()))
+	}
+	t.logger.WarnContext(ctx, "slow sql statement", attrs...)
+}
+
+// TraceBatchStart и TraceBatchEnd измеряют батч целиком: pgx не сообщает время
+// отдельных запросов батча, поэтому в лог попадает первый запрос и их число.
+func (t *SlowQueryTracer) TraceBatchStart(ctx context.Context, _ *pgx.Conn, data pgx.TraceBatchStartData) context.Context {
+	start := slowQueryStart{at: time.Now()}
+	if data.Batch != nil && len(data.Batch.QueuedQueries) > 0 {
+		first := data.Batch.QueuedQueries[0]
+		start.sql, start.args, start.queries = first.SQL, first.Arguments, len(data.Batch.QueuedQueries)
+	}
+	return context.WithValue(ctx, slowQueryKey{}, start)
+}
+
+func (t *SlowQueryTracer) TraceBatchQuery(context.Context, *pgx.Conn, pgx.TraceBatchQueryData) {}
+
+func (t *SlowQueryTracer) TraceBatchEnd(ctx context.Context, _ *pgx.Conn, data pgx.TraceBatchEndData) {
+	start, ok := ctx.Value(slowQueryKey{}).(slowQueryStart)
+	if !ok {
+		return
+	}
+	duration := time.Since(start.at)
+	if duration < t.threshold {
+		return
+	}
+
+	slowStatements.Inc("BATCH")
+	attrs := []any{
+		slog.String("statement", NormalizeStatement(start.sql)),
+		slog.Any("args", NormalizeArgs(start.args)),
+		slog.Int("queries", start.queries),
+		slog.Duration("duration", duration),
+		slog.Duration("threshold", t.threshold),
+	}
+	if data.Err != nil {
+		attrs = append(attrs, slog.String("error", data.Err.Error()))
+	}
+	t.logger.WarnContext(ctx, "slow sql batch", attrs...)
+}
+
+// NormalizeStatement схлопывает пробелы и переводы строк запроса и обрезает его.
+func NormalizeStatement(sql string) string {
+	return truncate(strings.Join(strings.Fields(sql), " "), maxStatementLength)
+}
+
+// NormalizeArgs выводит аргументы запроса коротко: длинные строки обрезаются,
+// от массивов остаются первые элементы и длина, от []byte - только размер.
+func NormalizeArgs(args []any) []string {
+	normalized := make([]string, len(args))
+	for i, arg := range args {
+		normalized[i] = normalizeArg(arg)
+	}
+	return normalized
+}
+
+func normalizeArg(arg any) string {
+	// Указатель разыменовывается раньше Stringer: String у nil-указателя паникует
+	if value := reflect.ValueOf(arg); value.Kind() == reflect.Pointer {
+		if value.IsNil() {
+			return "NULL"
+		}
+		return normalizeArg(value.Elem().Interface())
+	}
+
+	switch v := arg.(type) {
+	case nil:
+		return "NULL"
+	case []byte:
+		return fmt.Sprintf("<%d bytes>", len(v))
+	case string:
+		return fmt.Sprintf("%q", truncate(v, maxArgLength))
+	case time.Time:
+		return v.Format(time.RFC3339Nano)
+	case fmt.Stringer:
+		return truncate(v.String(), maxArgLength)
+	}
+
+	if value := reflect.ValueOf(arg); value.Kind() == reflect.Slice {
+		n := value.Len()
+		items := make([]string, 0, min(n, maxArgItems)+1)
+		for i := 0; i < n && i < maxArgItems; i++ {
+			items = append(items, normalizeArg(value.Index(i).Interface()))
+		}
+		if n > maxArgItems {
+			items = append(items, fmt.Sprintf("... %d items", n))
+		}
+		return "[" + strings.Join(items, ", ") + "]"
+	}
+	return truncate(fmt.Sprintf("%v", arg), maxArgLength)
+}
+
+func truncate(s string, limit int) string {
+	if len(s) <= limit {
+		return s
+	}
+	// Обрезка не должна разрывать многобайтный символ
+	cut := limit
+	for cut > 0 && !utf8.RuneStart(s[cut]) {
+		cut--
+	}
+	return s[:cut] + "…"
+}
+
+// statementOperation - первое слово запроса для метки метрики: SELECT, INSERT, WITH.
+func statementOperation(statement string) string {
+	operation, _, _ := strings.Cut(statement, " ")
+	switch operation = strings.ToUpper(operation); operation {
+	case "SELECT", "INSERT", "UPDATE", "DELETE", "WITH", "COPY":
+		return operation
+	}
+	return "OTHER"
+}
@@ -0,0 +1,74 @@
+package postgres
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestNormalizeStatement(t *testing.T) {
+	got := NormalizeStatement(`
+        SELECT id
+        FROM subscriptions
+        WHERE id = $1
+    `)
+	if got != "SELECT id FROM subscriptions WHERE id = $1" {
+		t.Errorf("statement = %q", got)
+	}
+	if got := NormalizeStatement(strings.Repeat("SELECT 1 ", 500)); len(got) > maxStatementLength+len("…") {
+		t.Errorf("statement is not truncated: %d bytes", len(got))
+	}
+}
+
+func TestNormalizeArgs(t *testing.T) {
+	id := uuid.MustParse("5e3f0c1a-1111-4a2b-8c3d-000000000001")
+	ids := make([]uuid.UUID, 7)
+	for i := range ids {
+		ids[i] = id
+	}
+	var missing *string
+	var missingID *uuid.UUID
+
+	got := NormalizeArgs([]any{
+		id,
+		"Yandex Plus",
+		strings.Repeat("ж", 40),
+		missing,
+		missingID,
+		&id,
+		[]byte("secret"),
+		time.Date(2025, time.July, 1, 0, 0, 0, 0, time.UTC),
+		ids,
+		39999,
+	})
+	want := []string{
+		id.String(),
+		`"Yandex Plus"`,
+		`"` + strings.Repeat("ж", 32) + `…"`,
+		"NULL",
+		"NULL",
+		id.String(),
+		"<6 bytes>",
+		"2025-07-01T00:00:00Z",
+		"[" + strings.Repeat(id.String()+", ", 5) + "... 7 items]",
+		"39999",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("args =\n%q\nwant\n%q", got, want)
+	}
+}
+
+func TestStatementOperation(t *testing.T) {
+	for statement, want := range map[string]string{
+		"WITH months AS (SELECT 1) SELECT * FROM months": "WITH",
+		"select id from subscriptions":                   "SELECT",
+		"SAVEPOINT explain":                              "OTHER",
+	} {
+		if got := statementOperation(statement); got != want {
+			t.Errorf("statementOperation(%q) = %q, want %q", statement, got, want)
+		}
+	}
+}
@@ -0,0 +1,38 @@
+package postgres
+
+import (
+	"context"
+	"time"
+)
+
+// StatementClosingRepository - закрытые месяцы ежемесячных выписок. Хранится в
+// базе тенанта: месяц закрывается в каждой базе отдельно.
+type StatementClosingRepository interface {
+	// Closed сообщает, поставлены ли уже выписки за month в очередь.
+	Closed(ctx context.Context, month time.Time) (bool, error)
+	// Close отмечает month закрытым; повторный вызов ничего не меняет.
+	Close(ctx context.Context, month time.Time, statements int, at time.Time) error
+}
+
+type statementClosingRepo struct {
+	db DB
+}
+
+func NewStatementClosingRepository(db DB) StatementClosingRepository {
+	return &statementClosingRepo{db: db}
+}
+
+func (r *statementClosingRepo) Closed(ctx context.Context, month time.Time) (bool, error) {
+	var closed bool
+	err := r.db.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM statement_closings WHERE month = $1)`, month).Scan(&closed)
+	return closed, err
+}
+
+func (r *statementClosingRepo) Close(ctx context.Context, month time.Time, statements int, at time.Time) error {
+	_, err := r.db.Exec(ctx, `
+        INSERT INTO statement_closings (month, statements, closed_at)
+        VALUES ($1, $2, $3)
+        ON CONFLICT (month) DO NOTHING
+    `, month, statements, at)
+	return err
+}
@@ -4,165 +4,574 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"strings"
+	"time"
 
-	"aggregator_db/internal/domain"
+	"aggregator_db/pkg/domain"
+	"aggregator_db/pkg/region"
+	"aggregator_db/pkg/store"
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
-	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/jackc/pgx/v5/pgconn"
 )
 
+// Ошибки и интерфейс хранилища подписок объявлены в pkg/store, чтобы их могли
+// использовать другие сервисы без pgx.
 var (
-	ErrNotFound      = errors.New("subscription not found")
-	ErrAlreadyExists = errors.New("subscription already exists")
+	ErrNotFound         = store.ErrNotFound
+	ErrAlreadyExists    = store.ErrAlreadyExists
+	ErrDiscountNotFound = store.ErrDiscountNotFound
+	ErrVersionConflict  = store.ErrVersionConflict
 )
 
-type SubscriptionRepository interface {
-	Create(ctx context.Context, sub *domain.Subscription) error
-	GetByID(ctx context.Context, id uuid.UUID) (*domain.Subscription, error)
-	Update(ctx context.Context, sub *domain.Subscription) error
-	Delete(ctx context.Context, id uuid.UUID) error
-	List(ctx context.Context, query domain.ListSubscriptionsQuery) ([]*domain.Subscription, error)
-	CalculateTotal(ctx context.Context, req domain.CalculateTotalRequest) (int, error)
+const subscriptionColumns = `id, service_name, price_minor, user_id, start_date, end_date, created_at, updated_at,
+        is_backfilled, exclude_from_new_analytics, backfill_note, status, cancelled_at, cancel_reason, auto_renew, billing_cycle, currency, tags, notes, region, custom_fields`
+
+type SubscriptionRepository = store.SubscriptionRepository
+
+// SubscriptionCache сбрасывает закэшированные расчеты по подпискам, которые
+// меняются в обход SubscriptionRepository: каскадом при удалении пользователя,
+// исправлением данных или заполнением колонок перехода схемы. Как и при записи
+// через репозиторий, расчеты сбрасываются сейчас и после коммита единицы работы ctx.
+type SubscriptionCache interface {
+	// InvalidateUsers сбрасывает расчеты пользователей users и расчеты без фильтра пользователей.
+	InvalidateUsers(ctx context.Context, users ...uuid.UUID)
+	// InvalidateTenant сбрасывает все расчеты тенанта ctx.
+	InvalidateTenant(ctx context.Context)
 }
 
+// selectSubscriptionColumns - колонки подписки и новые колонки переходов схемы,
+// из которых читается подписка в фазе dual_read.
+const selectSubscriptionColumns = subscriptionColumns + `, start_on, end_on, price_amount::text, version`
+
 type subscriptionRepo struct {
-	db *pgxpool.Pool
+	db DB
+	// migrations - фазы переходов схемы: в каких колонках писать и читать даты и цену
+	migrations domain.SchemaMigrations
+	// monthlyTotals - считать прошлые месяцы по subscription_monthly_totals (см. monthly_totals.go)
+	monthlyTotals bool
 }
 
-func NewSubscriptionRepository(db *pgxpool.Pool) SubscriptionRepository {
-	return &subscriptionRepo{db: db}
+func NewSubscriptionRepository(db DB, migrations domain.SchemaMigrations, monthlyTotals bool) SubscriptionRepository {
+	return &subscriptionRepo{db: db, migrations: migrations, monthlyTotals: monthlyTotals}
 }
 
-func (r *subscriptionRepo) Create(ctx context.Context, sub *domain.Subscription) error {
-	query := `
-        INSERT INTO subscriptions (id, service_name, price, user_id, start_date, end_date, created_at, updated_at)
-        VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+func (r *subscriptionRepo) scanSubscription(row pgx.Row) (*domain.Subscription, error) {
+	var sub domain.Subscription
+	var startOn, endOn *time.Time
+	var priceAmount *string
+	err := row.Scan(
+		&sub.ID,
+		&sub.ServiceName,
+		&sub.Price.Amount,
+		&sub.UserID,
+		&sub.StartDate,
+		&sub.EndDate,
+		&sub.CreatedAt,
+		&sub.UpdatedAt,
+		&sub.Backfilled,
+		&sub.ExcludeFromNewAnalytics,
+		&sub.BackfillNote,
+		&sub.Status,
+		&sub.CancelledAt,
+		&sub.CancelReason,
+		&sub.AutoRenew,
+		&sub.BillingCycle,
+		&sub.Price.Currency,
+		&sub.Tags,
+		&sub.Notes,
+		&sub.Region,
+		&sub.CustomFields,
+		&startOn,
+		&endOn,
+		&priceAmount,
+		&sub.Version,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	// Строки, которые еще не заполнены, читаются из старых колонок
+	if r.migrations.Dates.ReadsNew() {
+		if startOn != nil {
+			sub.StartDate = domain.FormatPeriod(*startOn)
+		}
+		if endOn != nil {
+			endDate := domain.FormatPeriod(*endOn)
+			sub.EndDate = &endDate
+		}
+	}
+	if r.migrations.Money.ReadsNew() && priceAmount != nil {
+		price, err := domain.MoneyFromDecimal(*priceAmount, sub.Price.Currency)
+		if err != nil {
+			return nil, fmt.Errorf("subscription %s: price_amount: %w", sub.ID, err)
+		}
+		sub.Price = price
+	}
+	return &sub, nil
+}
+
+const insertSubscriptionQuery = `
+        INSERT INTO subscriptions (` + subscriptionColumns + `, service_key)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21, $22)
     `
 
-	_, err := r.db.Exec(ctx, query,
+func insertArgs(sub *domain.Subscription) []interface{} {
+	if sub.Status == "" {
+		sub.Status = domain.StatusActive
+	}
+	sub.BillingCycle = sub.BillingCycle.OrDefault()
+	if sub.Tags == nil {
+		sub.Tags = []string{}
+	}
+	if sub.CustomFields == nil {
+		sub.CustomFields = domain.CustomFieldValues{}
+	}
+
+	return []interface{}{
 		sub.ID,
 		sub.ServiceName,
-		sub.Price,
+		sub.Price.Amount,
 		sub.UserID,
 		sub.StartDate,
 		sub.EndDate,
 		sub.CreatedAt,
 		sub.UpdatedAt,
-	)
+		sub.Backfilled,
+		sub.ExcludeFromNewAnalytics,
+		sub.BackfillNote,
+		sub.Status,
+		sub.CancelledAt,
+		sub.CancelReason,
+		sub.AutoRenew,
+		sub.BillingCycle,
+		sub.Price.Currency,
+		sub.Tags,
+		sub.Notes,
+		sub.Region,
+		sub.CustomFields,
+		domain.ServiceKey(sub.ServiceName),
+	}
+}
+
+// upsertSubscriptionQuery записывает подписку целиком, включая статус и регион.
+const upsertSubscriptionQuery = insertSubscriptionQuery + `
+        ON CONFLICT (id) DO UPDATE SET
+            service_name = EXCLUDED.service_name, price_minor = EXCLUDED.price_minor, user_id = EXCLUDED.user_id,
+            start_date = EXCLUDED.start_date, end_date = EXCLUDED.end_date, created_at = EXCLUDED.created_at,
+            updated_at = EXCLUDED.updated_at, is_backfilled = EXCLUDED.is_backfilled,
+            exclude_from_new_analytics = EXCLUDED.exclude_from_new_analytics, backfill_note = EXCLUDED.backfill_note,
+            status = EXCLUDED.status, cancelled_at = EXCLUDED.cancelled_at, cancel_reason = EXCLUDED.cancel_reason,
+            auto_renew = EXCLUDED.auto_renew, billing_cycle = EXCLUDED.billing_cycle, currency = EXCLUDED.currency,
+            tags = EXCLUDED.tags, notes = EXCLUDED.notes, region = EXCLUDED.region, service_key = EXCLUDED.service_key,
+            custom_fields = EXCLUDED.custom_fields, version = subscriptions.version + 1
+    `
+
+// Create заводит пользователя подписки, если его еще нет, и сохраняет подписку.
+func (r *subscriptionRepo) Create(ctx context.Context, sub *domain.Subscription) error {
+	sub.Region = region.FromContext(ctx)
+	sub.Version = 1
+	return auditedTx(ctx, r.db, auditRows{domain.AuditSubscriptions: {sub.ID}, domain.AuditUsers: {sub.UserID}}, func(tx pgx.Tx) error {
+		if _, err := tx.Exec(ctx, provisionUserQuery, sub.UserID, sub.CreatedAt); err != nil {
+			return err
+		}
+		if _, err := tx.Exec(ctx, insertSubscriptionQuery, insertArgs(sub)...); err != nil {
+			return subscriptionConflict(err)
+		}
+		if err := syncDualColumns(ctx, tx, r.migrations, []uuid.UUID{sub.ID}); err != nil {
+			return err
+		}
+		_, err := tx.Exec(ctx, insertPriceQuery, insertPriceArgs(sub, sub.CreatedAt)...)
+		return err
+	})
+}
 
+// subscriptionConflict переводит занятый ID подписки в ErrAlreadyExists: ID
+// может передать клиент (domain.CreateSubscriptionRequest.ID).
+func subscriptionConflict(err error) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23505" && pgErr.ConstraintName == "subscriptions_pkey" {
+		return ErrAlreadyExists
+	}
 	return err
 }
 
+// CreateBatch вставляет подписки одним батчем в транзакции: либо все, либо ни одной.
+func (r *subscriptionRepo) CreateBatch(ctx context.Context, subs []*domain.Subscription) error {
+	ids := make([]uuid.UUID, len(subs))
+	userIDs := make([]uuid.UUID, len(subs))
+	for i, sub := range subs {
+		sub.Region, sub.Version = region.FromContext(ctx), 1
+		ids[i], userIDs[i] = sub.ID, sub.UserID
+	}
+	return auditedTx(ctx, r.db, auditRows{domain.AuditSubscriptions: ids, domain.AuditUsers: userIDs}, func(tx pgx.Tx) error {
+		batch := &pgx.Batch{}
+		for _, sub := range subs {
+			batch.Queue(provisionUserQuery, sub.UserID, sub.CreatedAt)
+		}
+		for _, sub := range subs {
+			batch.Queue(insertSubscriptionQuery, insertArgs(sub)...)
+		}
+		for _, sub := range subs {
+			batch.Queue(insertPriceQuery, insertPriceArgs(sub, sub.CreatedAt)...)
+		}
+
+		results := tx.SendBatch(ctx, batch)
+		for range subs {
+			if _, err := results.Exec(); err != nil {
+				_ = results.Close()
+				return err
+			}
+		}
+		for i := range subs {
+			if _, err := results.Exec(); err != nil {
+				_ = results.Close()
+				return fmt.Errorf("item %d: %w", i, subscriptionConflict(err))
+			}
+		}
+		for range subs {
+			if _, err := results.Exec(); err != nil {
+				_ = results.Close()
+				return err
+			}
+		}
+		if err := results.Close(); err != nil {
+			return err
+		}
+		return syncDualColumns(ctx, tx, r.migrations, ids)
+	})
+}
+
+// Upsert записывает подписку целиком, создавая при отсутствии.
+func (r *subscriptionRepo) Upsert(ctx context.Context, sub *domain.Subscription) error {
+	sub.Region = region.FromContext(ctx)
+	return auditedTx(ctx, r.db, auditRows{domain.AuditSubscriptions: {sub.ID}, domain.AuditUsers: {sub.UserID}}, func(tx pgx.Tx) error {
+		if _, err := tx.Exec(ctx, provisionUserQuery, sub.UserID, sub.CreatedAt); err != nil {
+			return err
+		}
+		if _, err := tx.Exec(ctx, upsertSubscriptionQuery, insertArgs(sub)...); err != nil {
+			return err
+		}
+		if err := syncDualColumns(ctx, tx, r.migrations, []uuid.UUID{sub.ID}); err != nil {
+			return err
+		}
+		_, err := tx.Exec(ctx, insertPriceQuery, insertPriceArgs(sub, sub.UpdatedAt)...)
+		return err
+	})
+}
+
 func (r *subscriptionRepo) GetByID(ctx context.Context, id uuid.UUID) (*domain.Subscription, error) {
 	query := `
-        SELECT id, service_name, price, user_id, start_date, end_date, created_at, updated_at
+        SELECT ` + selectSubscriptionColumns + `
         FROM subscriptions
         WHERE id = $1
     `
 
-	var sub domain.Subscription
-	err := r.db.QueryRow(ctx, query, id).Scan(
-		&sub.ID,
-		&sub.ServiceName,
-		&sub.Price,
-		&sub.UserID,
-		&sub.StartDate,
-		&sub.EndDate,
-		&sub.CreatedAt,
-		&sub.UpdatedAt,
-	)
-
+	sub, err := r.scanSubscription(r.db.QueryRow(ctx, query, id))
 	if errors.Is(err, pgx.ErrNoRows) {
 		return nil, ErrNotFound
 	}
 
-	return &sub, err
+	return sub, err
 }
 
 func (r *subscriptionRepo) Update(ctx context.Context, sub *domain.Subscription) error {
 	query := `
         UPDATE subscriptions
-        SET service_name = $2, price = $3, start_date = $4, end_date = $5, updated_at = $6
-        WHERE id = $1
+        SET service_name = $2, price_minor = $3, start_date = $4, end_date = $5, updated_at = $6, service_key = $7, auto_renew = $8, billing_cycle = $9, currency = $10, tags = $11, notes = $12, region = $13,
+            custom_fields = $14, version = version + 1
+        WHERE id = $1 AND version = $15
+        RETURNING version
     `
+	if sub.Tags == nil {
+		sub.Tags = []string{}
+	}
+	if sub.CustomFields == nil {
+		sub.CustomFields = domain.CustomFieldValues{}
+	}
+	sub.Region = region.FromContext(ctx)
+
+	// Смена цены или цикла попадает в историю цен в той же транзакции
+	return auditedTx(ctx, r.db, auditRows{domain.AuditSubscriptions: {sub.ID}}, func(tx pgx.Tx) error {
+		err := tx.QueryRow(ctx, query,
+			sub.ID,
+			sub.ServiceName,
+			sub.Price.Amount,
+			sub.StartDate,
+			sub.EndDate,
+			sub.UpdatedAt,
+			domain.ServiceKey(sub.ServiceName),
+			sub.AutoRenew,
+			sub.BillingCycle.OrDefault(),
+			sub.Price.Currency,
+			sub.Tags,
+			sub.Notes,
+			sub.Region,
+			sub.CustomFields,
+			sub.Version,
+		).Scan(&sub.Version)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return versionMismatch(ctx, tx, sub.ID)
+		}
+		if err != nil {
+			return err
+		}
+		if err := syncDualColumns(ctx, tx, r.migrations, []uuid.UUID{sub.ID}); err != nil {
+			return err
+		}
 
-	result, err := r.db.Exec(ctx, query,
-		sub.ID,
-		sub.ServiceName,
-		sub.Price,
-		sub.StartDate,
-		sub.EndDate,
-		sub.UpdatedAt,
-	)
+		_, err = tx.Exec(ctx, insertPriceQuery, insertPriceArgs(sub, sub.UpdatedAt)...)
+		return err
+	})
+}
 
-	if err != nil {
+// versionMismatch объясняет, почему изменение с проверкой версии не затронуло строку:
+// подписки нет или ее версия уже другая.
+func versionMismatch(ctx context.Context, tx pgx.Tx, id uuid.UUID) error {
+	var exists bool
+	if err := tx.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM subscriptions WHERE id = $1)`, id).Scan(&exists); err != nil {
 		return err
 	}
-
-	if result.RowsAffected() == 0 {
+	if !exists {
 		return ErrNotFound
 	}
-
-	return nil
+	return ErrVersionConflict
 }
 
 func (r *subscriptionRepo) Delete(ctx context.Context, id uuid.UUID) error {
 	query := `DELETE FROM subscriptions WHERE id = $1`
 
-	result, err := r.db.Exec(ctx, query, id)
-	if err != nil {
-		return err
+	return auditedTx(ctx, r.db, auditRows{domain.AuditSubscriptions: {id}}, func(tx pgx.Tx) error {
+		result, err := tx.Exec(ctx, query, id)
+		if err != nil {
+			return err
+		}
+
+		if result.RowsAffected() == 0 {
+			return ErrNotFound
+		}
+
+		return nil
+	})
+}
+
+// DeleteByFilter удаляет все подписки, подходящие под фильтр. Подписки выбираются
+// с блокировкой и удаляются по ID, чтобы каждая попала в журнал изменений.
+func (r *subscriptionRepo) DeleteByFilter(ctx context.Context, filter domain.DeleteSubscriptionsFilter) (int, error) {
+	query := `SELECT id FROM subscriptions WHERE 1=1`
+	args := []interface{}{}
+	argIndex := 1
+
+	if filter.UserID != nil {
+		query += fmt.Sprintf(" AND user_id = $%d", argIndex)
+		args = append(args, *filter.UserID)
+		argIndex++
 	}
 
-	if result.RowsAffected() == 0 {
-		return ErrNotFound
+	if len(filter.ServiceKeys) > 0 {
+		query += fmt.Sprintf(" AND service_key = ANY($%d)", argIndex)
+		args = append(args, filter.ServiceKeys)
+		argIndex++
+	} else if filter.ServiceName != nil {
+		query += fmt.Sprintf(" AND service_name = $%d", argIndex)
+		args = append(args, *filter.ServiceName)
+		argIndex++
+	}
+
+	if filter.EndedBefore != nil {
+		query += fmt.Sprintf(" AND end_date IS NOT NULL AND TO_DATE(end_date, 'MM-YYYY') < TO_DATE($%d, 'MM-YYYY')", argIndex)
+		args = append(args, *filter.EndedBefore)
+	}
+
+	deleted := 0
+	err := pgx.BeginFunc(ctx, r.db, func(tx pgx.Tx) error {
+		ids, err := collectIDs(ctx, tx, query+` FOR UPDATE`, args...)
+		if err != nil {
+			return err
+		}
+
+		return audited(ctx, tx, auditRows{domain.AuditSubscriptions: ids}, func() error {
+			result, err := tx.Exec(ctx, `DELETE FROM subscriptions WHERE id = ANY($1)`, ids)
+			if err != nil {
+				return err
+			}
+			deleted = int(result.RowsAffected())
+			return nil
+		})
+	})
+	if err != nil {
+		return 0, err
 	}
 
-	return nil
+	return deleted, nil
 }
 
-func (r *subscriptionRepo) List(ctx context.Context, query domain.ListSubscriptionsQuery) ([]*domain.Subscription, error) {
-	sqlQuery := `
-        SELECT id, service_name, price, user_id, start_date, end_date, created_at, updated_at
-        FROM subscriptions
-        WHERE 1=1
-    `
+const defaultListLimit = 100
+
+// buildListFilter собирает WHERE-часть для List и Count с нумерацией параметров с 1.
+func buildListFilter(query domain.ListSubscriptionsQuery) (string, []interface{}) {
+	where := " WHERE 1=1"
 	args := []interface{}{}
 	argIndex := 1
 
 	if query.UserID != nil {
-		userUUID, err := uuid.Parse(*query.UserID)
-		if err != nil {
-			return nil, fmt.Errorf("invalid user_id format: %w", err)
-		}
-		sqlQuery += fmt.Sprintf(" AND user_id = $%d", argIndex)
-		args = append(args, userUUID)
+		where += fmt.Sprintf(" AND user_id = $%d", argIndex)
+		args = append(args, *query.UserID)
 		argIndex++
 	}
 
-	if query.ServiceName != nil {
-		sqlQuery += fmt.Sprintf(" AND service_name = $%d", argIndex)
+	if len(query.ServiceKeys) > 0 {
+		where += fmt.Sprintf(" AND service_key = ANY($%d)", argIndex)
+		args = append(args, query.ServiceKeys)
+		argIndex++
+	} else if query.ServiceName != nil {
+		where += fmt.Sprintf(" AND service_name = $%d", argIndex)
 		args = append(args, *query.ServiceName)
 		argIndex++
 	}
 
-	sqlQuery += " ORDER BY created_at DESC"
+	if query.Status != nil {
+		where += fmt.Sprintf(" AND status = $%d", argIndex)
+		args = append(args, *query.Status)
+		argIndex++
+	}
 
-	if query.Limit > 0 {
-		sqlQuery += fmt.Sprintf(" LIMIT $%d", argIndex)
-		args = append(args, query.Limit)
+	if len(query.Tags) > 0 {
+		where += fmt.Sprintf(" AND tags @> $%d", argIndex)
+		args = append(args, query.Tags)
 		argIndex++
-	} else {
-		sqlQuery += fmt.Sprintf(" LIMIT $%d", argIndex)
-		args = append(args, 100)
+	}
+
+	if query.Currency != "" {
+		where += fmt.Sprintf(" AND currency = $%d", argIndex)
+		args = append(args, query.Currency)
+		argIndex++
+	}
+
+	if query.MinPriceMinor != nil {
+		where += fmt.Sprintf(" AND price_minor >= $%d", argIndex)
+		args = append(args, *query.MinPriceMinor)
+		argIndex++
+	}
+
+	if query.MaxPriceMinor != nil {
+		where += fmt.Sprintf(" AND price_minor <= $%d", argIndex)
+		args = append(args, *query.MaxPriceMinor)
+		argIndex++
+	}
+
+	// Условие по start_month и end_month идет по idx_subscriptions_months
+	if query.ActiveMonth != nil {
+		where += fmt.Sprintf(" AND start_month <= $%d AND (end_month IS NULL OR end_month >= $%d)", argIndex, argIndex)
+		args = append(args, *query.ActiveMonth)
 		argIndex++
 	}
 
+	// @> идет по idx_subscriptions_custom_fields; значения уже приведены к типам полей
+	if len(query.CustomFieldFilter) > 0 {
+		where += fmt.Sprintf(" AND custom_fields @> $%d", argIndex)
+		args = append(args, query.CustomFieldFilter)
+		argIndex++
+	}
+
+	if query.CreatedBefore != nil {
+		where += fmt.Sprintf(" AND created_at <= $%d", argIndex)
+		args = append(args, *query.CreatedBefore)
+		argIndex++
+	}
+
+	// Оператор % идет по idx_subscriptions_service_name_trgm; порог похожести -
+	// параметр соединения pg_trgm.similarity_threshold (DB_SEARCH_SIMILARITY_THRESHOLD)
+	if query.Match == domain.SearchFuzzy {
+		if q := strings.TrimSpace(query.Q); q != "" {
+			where += fmt.Sprintf(" AND service_name %% $%d", argIndex)
+			args = append(args, q)
+			argIndex++
+		}
+		return where, args
+	}
+
+	// Выражение совпадает с idx_subscriptions_search, поэтому ILIKE идет по триграммному индексу
+	for _, word := range strings.Fields(query.Q) {
+		where += fmt.Sprintf(" AND (service_name || ' ' || COALESCE(notes, '')) ILIKE $%d", argIndex)
+		args = append(args, "%"+escapeLike(word)+"%")
+		argIndex++
+	}
+
+	return where, args
+}
+
+// SimilarityThresholdParam - параметр соединения с порогом похожести оператора %
+// из pg_trgm, по которому работает поиск match=fuzzy.
+const SimilarityThresholdParam = "pg_trgm.similarity_threshold"
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// escapeLike экранирует спецсимволы LIKE, чтобы они искались буквально.
+func escapeLike(s string) string {
+	return likeEscaper.Replace(s)
+}
+
+// listSortColumns - колонки, по которым разрешено сортировать список. Поле
+// сортировки попадает в текст запроса, поэтому берется только отсюда.
+var listSortColumns = map[domain.ListSortField]string{
+	domain.SortByCreatedAt:   "created_at",
+	domain.SortByPrice:       "price_minor",
+	domain.SortByStartDate:   "start_month",
+	domain.SortByServiceName: "service_name",
+}
+
+// listOrderBy возвращает колонку и направление сортировки списка; неизвестное
+// поле дает порядок по умолчанию.
+func listOrderBy(query domain.ListSubscriptionsQuery) string {
+	field, order := query.Sort()
+	column, ok := listSortColumns[field]
+	if !ok {
+		return "created_at DESC"
+	}
+	if order == domain.SortAsc {
+		return column + " ASC"
+	}
+	return column + " DESC"
+}
+
+func buildListQuery(query domain.ListSubscriptionsQuery) (string, []interface{}) {
+	where, args := buildListFilter(query)
+	sqlQuery := `
+        SELECT ` + selectSubscriptionColumns + `
+        FROM subscriptions` + where
+	argIndex := len(args) + 1
+
+	// Курсор продолжает порядок ORDER BY: раньше по времени или в то же время с большим id
+	if query.After != nil {
+		sqlQuery += fmt.Sprintf(" AND (created_at < $%d OR (created_at = $%d AND id > $%d))", argIndex, argIndex, argIndex+1)
+		args = append(args, query.After.CreatedAt, query.After.ID)
+		argIndex += 2
+	}
+
+	// id различает подписки с равными значениями поля сортировки: без него их
+	// порядок между страницами не определен
+	sqlQuery += " ORDER BY " + listOrderBy(query) + ", id"
+
+	limit := query.Limit
+	if limit <= 0 {
+		limit = defaultListLimit
+	}
+	sqlQuery += fmt.Sprintf(" LIMIT $%d", argIndex)
+	args = append(args, limit)
+	argIndex++
+
 	if query.Offset > 0 {
 		sqlQuery += fmt.Sprintf(" OFFSET $%d", argIndex)
 		args = append(args, query.Offset)
 	}
 
+	return sqlQuery, args
+}
+
+func (r *subscriptionRepo) List(ctx context.Context, query domain.ListSubscriptionsQuery) ([]*domain.Subscription, error) {
+	sqlQuery, args := buildListQuery(query)
+
 	rows, err := r.db.Query(ctx, sqlQuery, args...)
 	if err != nil {
 		return nil, err
@@ -171,77 +580,436 @@ func (r *subscriptionRepo) List(ctx context.Context, query domain.ListSubscripti
 
 	subscriptions := make([]*domain.Subscription, 0)
 	for rows.Next() {
-		var sub domain.Subscription
-		err := rows.Scan(
-			&sub.ID,
-			&sub.ServiceName,
-			&sub.Price,
-			&sub.UserID,
-			&sub.StartDate,
-			&sub.EndDate,
-			&sub.CreatedAt,
-			&sub.UpdatedAt,
-		)
+		sub, err := r.scanSubscription(rows)
 		if err != nil {
 			return nil, err
 		}
-		subscriptions = append(subscriptions, &sub)
+		subscriptions = append(subscriptions, sub)
 	}
 
 	return subscriptions, rows.Err()
 }
 
-func (r *subscriptionRepo) CalculateTotal(ctx context.Context, req domain.CalculateTotalRequest) (int, error) {
+func (r *subscriptionRepo) Count(ctx context.Context, query domain.ListSubscriptionsQuery) (int, error) {
+	where, args := buildListFilter(query)
+
+	var total int
+	err := r.db.QueryRow(ctx, `SELECT COUNT(*) FROM subscriptions`+where, args...).Scan(&total)
+	return total, err
+}
+
+// buildTotalFilter собирает фильтры пользователя и сервиса для расчета стоимости;
+// параметры нумеруются с argIndex.
+func buildTotalFilter(req domain.CalculateTotalRequest, argIndex int) (string, []interface{}) {
+	where := ""
+	args := []interface{}{}
+
+	if req.UserID != nil {
+		where += fmt.Sprintf(" AND user_id = $%d", argIndex)
+		args = append(args, *req.UserID)
+		argIndex++
+	}
+
+	if len(req.UserIDs) > 0 {
+		where += fmt.Sprintf(" AND user_id = ANY($%d)", argIndex)
+		args = append(args, req.UserIDs)
+		argIndex++
+	}
+
+	if len(req.ServiceKeys) > 0 {
+		where += fmt.Sprintf(" AND service_key = ANY($%d)", argIndex)
+		args = append(args, req.ServiceKeys)
+		argIndex++
+	} else if req.ServiceName != nil {
+		where += fmt.Sprintf(" AND service_name = $%d", argIndex)
+		args = append(args, *req.ServiceName)
+		argIndex++
+	}
+
+	if req.Currency != "" {
+		where += fmt.Sprintf(" AND currency = $%d", argIndex)
+		args = append(args, req.Currency)
+		argIndex++
+	}
+
+	if len(req.Tags) > 0 {
+		where += fmt.Sprintf(" AND tags @> $%d", argIndex)
+		args = append(args, req.Tags)
+	}
+
+	return where, args
+}
+
+// monthUnits - стоимость месяца m.month подписки из CTE months в долях
+// 1/domain.ProrationDenominator, как domain.ProratedMonthCharge.
+const monthUnits = `
+            CASE m.billing_cycle
+                WHEN 'weekly' THEN m.price_minor * ((m.month + interval '1 month')::date - m.month) * 12
+                WHEN 'yearly' THEN m.price_minor * 7
+                ELSE m.price_minor * 84
+            END`
+
+// billableMonth отсекает месяцы CTE months, в которые по истории статусов
+// подписка была на паузе или отменена.
+const billableMonth = `
+            COALESCE((
+                SELECT c.status
+                FROM subscription_status_changes c
+                WHERE c.subscription_id = m.id AND c.effective_from <= m.month
+                ORDER BY c.effective_from DESC, c.changed_at DESC
+                LIMIT 1
+            ), 'active') NOT IN ('paused', 'cancelled')`
+
+// userUnits - стоимость в долях 1/domain.ProrationDenominator по пользователям
+// и валютам; без разбивки по пользователям вся сумма лежит под uuid.Nil.
+type userUnits map[uuid.UUID]map[domain.Currency]int64
+
+// groupUser - колонка пользователя в выборке стоимости: user_id при разбивке
+// по пользователям, иначе NULL, и все строки сводятся в одну группу.
+func groupUser(byUser bool) string {
+	if byUser {
+		return "user_id"
+	}
+	return "NULL::uuid"
+}
+
+// CalculateTotal считает стоимость в долях 1/domain.ProrationDenominator,
+// вычитает скидки и только затем округляет, как и расчет по классам в сервисе.
+func (r *subscriptionRepo) CalculateTotal(ctx context.Context, req domain.CalculateTotalRequest) (domain.Totals, error) {
+	totals, err := r.calculateTotals(ctx, req, false)
+	if err != nil {
+		return nil, err
+	}
+	if total, ok := totals[uuid.Nil]; ok {
+		return total, nil
+	}
+	return domain.Totals{}, nil
+}
+
+// CalculateTotalByUser считает то же, что CalculateTotal, теми же запросами с
+// GROUP BY user_id.
+func (r *subscriptionRepo) CalculateTotalByUser(ctx context.Context, req domain.CalculateTotalRequest) (map[uuid.UUID]domain.Totals, error) {
+	return r.calculateTotals(ctx, req, true)
+}
+
+func (r *subscriptionRepo) calculateTotals(ctx context.Context, req domain.CalculateTotalRequest, byUser bool) (map[uuid.UUID]domain.Totals, error) {
+	var units userUnits
+	var err error
+	if r.monthlyTotals && monthlyTotalsServe(req) {
+		units, err = r.totalUnitsWithMonthly(ctx, req, byUser)
+	} else {
+		units, err = r.liveUnits(ctx, req, byUser)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	totals := make(map[uuid.UUID]domain.Totals, len(units))
+	for user, byCurrency := range units {
+		userTotals := make(domain.Totals, len(byCurrency))
+		for currency, u := range byCurrency {
+			userTotals[currency] = domain.RoundProratedWith(u, req.Rounding)
+		}
+		totals[user] = userTotals
+	}
+	return totals, nil
+}
+
+// liveUnits считает стоимость периода за вычетом скидок по подпискам.
+func (r *subscriptionRepo) liveUnits(ctx context.Context, req domain.CalculateTotalRequest, byUser bool) (userUnits, error) {
+	var units userUnits
+	var err error
+	if req.ExcludeInactive {
+		units, err = r.billableUnits(ctx, req, byUser)
+	} else {
+		units, err = r.periodUnits(ctx, req, byUser)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	discounts, err := r.discountUnits(ctx, req, byUser)
+	if err != nil {
+		return nil, err
+	}
+	for user, byCurrency := range units {
+		for currency := range byCurrency {
+			byCurrency[currency] -= discounts[user][currency]
+		}
+	}
+	return units, nil
+}
+
+func (r *subscriptionRepo) periodUnits(ctx context.Context, req domain.CalculateTotalRequest, byUser bool) (userUnits, error) {
+	filter, filterArgs := buildTotalFilter(req, 4)
 	sqlQuery := `
         WITH period_calculations AS (
             SELECT 
-                price,
+                ` + groupUser(byUser) + ` AS user_id,
+                price_minor,
+                currency,
+                billing_cycle,
                 GREATEST(
                     TO_DATE(start_date, 'MM-YYYY'),
                     TO_DATE($1, 'MM-YYYY')
                 ) as calc_start,
                 LEAST(
-                    COALESCE(TO_DATE(end_date, 'MM-YYYY'), TO_DATE($2, 'MM-YYYY')),
+                    COALESCE(TO_DATE(end_date, 'MM-YYYY'), TO_DATE($3, 'MM-YYYY')),
                     TO_DATE($2, 'MM-YYYY')
                 ) as calc_end
             FROM subscriptions
             WHERE 
                 TO_DATE(start_date, 'MM-YYYY') <= TO_DATE($2, 'MM-YYYY')
                 AND (end_date IS NULL OR TO_DATE(end_date, 'MM-YYYY') >= TO_DATE($1, 'MM-YYYY'))
+    ` + filter + `
+        )
+        SELECT user_id, currency, SUM(
+            CASE billing_cycle
+                WHEN 'weekly' THEN price_minor * ((calc_end + interval '1 month')::date - calc_start) * 12
+                ELSE price_minor * (
+                    (EXTRACT(YEAR FROM calc_end)::int - EXTRACT(YEAR FROM calc_start)::int) * 12 +
+                    (EXTRACT(MONTH FROM calc_end)::int - EXTRACT(MONTH FROM calc_start)::int) + 1
+                ) * CASE billing_cycle WHEN 'yearly' THEN 7 ELSE 84 END
+            END
+        )::bigint as total
+        FROM period_calculations
+        WHERE calc_end >= calc_start
+        GROUP BY user_id, currency
     `
 
-	args := []interface{}{req.StartPeriod, req.EndPeriod}
-	argIndex := 3
+	args := append([]interface{}{req.StartPeriod, req.EndPeriod, req.OpenEndedEnd()}, filterArgs...)
+	return r.queryUnits(ctx, sqlQuery, args...)
+}
 
-	if req.UserID != nil {
-		userUUID, err := uuid.Parse(*req.UserID)
+// monthsCTE раскладывает подписки под фильтр на месяцы периода [$1, $2];
+// бессрочные подписки заканчиваются месяцем $3 (domain.CalculateTotalRequest.OpenEndedEnd).
+func monthsCTE(filter string) string {
+	return `
+        WITH months AS (
+            SELECT id, user_id, price_minor, currency, billing_cycle, month::date AS month
+            FROM subscriptions
+            CROSS JOIN LATERAL generate_series(
+                GREATEST(TO_DATE(start_date, 'MM-YYYY'), TO_DATE($1, 'MM-YYYY')),
+                LEAST(COALESCE(TO_DATE(end_date, 'MM-YYYY'), TO_DATE($3, 'MM-YYYY')), TO_DATE($2, 'MM-YYYY')),
+                interval '1 month'
+            ) AS month
+            WHERE 1=1` + filter + `
+        )`
+}
+
+// billableUnits раскладывает подписки на месяцы и пропускает месяцы,
+// в которые по истории статусов подписка была на паузе или отменена.
+func (r *subscriptionRepo) billableUnits(ctx context.Context, req domain.CalculateTotalRequest, byUser bool) (userUnits, error) {
+	filter, filterArgs := buildTotalFilter(req, 4)
+	sqlQuery := monthsCTE(filter) + `
+        SELECT ` + groupUser(byUser) + ` AS user_id, m.currency, SUM(` + monthUnits + `
+        )::bigint
+        FROM months m
+        WHERE ` + billableMonth + `
+        GROUP BY 1, m.currency
+    `
+
+	args := append([]interface{}{req.StartPeriod, req.EndPeriod, req.OpenEndedEnd()}, filterArgs...)
+	return r.queryUnits(ctx, sqlQuery, args...)
+}
+
+// discountUnits считает, на сколько скидки уменьшают стоимость периода; формула
+// та же, что в domain.DiscountedCharge. В расчет попадают только подписки со скидками.
+func (r *subscriptionRepo) discountUnits(ctx context.Context, req domain.CalculateTotalRequest, byUser bool) (userUnits, error) {
+	filter, filterArgs := buildTotalFilter(req, 4)
+	filter += `
+                AND EXISTS (SELECT 1 FROM subscription_discounts d WHERE d.subscription_id = subscriptions.id)`
+	status := ""
+	if req.ExcludeInactive {
+		status = " AND " + billableMonth
+	}
+
+	sqlQuery := monthsCTE(filter) + `,
+        discounted AS (
+            SELECT m.user_id, m.currency, ` + monthUnits + ` AS units,
+                LEAST(COALESCE(SUM(d.percent) FILTER (WHERE d.kind = 'percent'), 0), 100) AS percent,
+                COALESCE(SUM(d.amount_minor) FILTER (WHERE d.kind = 'fixed'), 0) AS fixed
+            FROM months m
+            JOIN subscription_discounts d ON d.subscription_id = m.id
+                AND d.start_month <= m.month
+                AND (d.end_month IS NULL OR d.end_month >= m.month)
+            WHERE 1=1` + status + `
+            GROUP BY m.id, m.user_id, m.month, m.currency, m.price_minor, m.billing_cycle
+        )
+        SELECT ` + groupUser(byUser) + ` AS user_id, currency, SUM(units - GREATEST(units * (100 - percent) / 100 - fixed * 84, 0))::bigint
+        FROM discounted
+        GROUP BY 1, currency
+    `
+
+	args := append([]interface{}{req.StartPeriod, req.EndPeriod, req.OpenEndedEnd()}, filterArgs...)
+	return r.queryUnits(ctx, sqlQuery, args...)
+}
+
+// queryUnits читает строки (user_id, currency, units) в суммы по пользователям
+// и валютам; NULL в user_id - сумма без разбивки, она ложится под uuid.Nil.
+func (r *subscriptionRepo) queryUnits(ctx context.Context, sqlQuery string, args ...interface{}) (userUnits, error) {
+	rows, err := r.db.Query(ctx, sqlQuery, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	units := make(userUnits)
+	for rows.Next() {
+		var user uuid.NullUUID
+		var currency domain.Currency
+		var total int64
+		if err := rows.Scan(&user, &currency, &total); err != nil {
+			return nil, err
+		}
+		if units[user.UUID] == nil {
+			units[user.UUID] = make(map[domain.Currency]int64)
+		}
+		units[user.UUID][currency] = total
+	}
+	return units, rows.Err()
+}
+
+func (r *subscriptionRepo) ChangeStatus(ctx context.Context, change *domain.StatusChange) error {
+	return auditedTx(ctx, r.db, auditRows{domain.AuditSubscriptions: {change.SubscriptionID}}, func(tx pgx.Tx) error {
+		result, err := tx.Exec(ctx,
+			`UPDATE subscriptions SET status = $2, updated_at = $3, region = $4, version = version + 1 WHERE id = $1`,
+			change.SubscriptionID, change.Status, change.ChangedAt, region.FromContext(ctx),
+		)
 		if err != nil {
-			return 0, fmt.Errorf("invalid user_id format: %w", err)
+			return err
 		}
-		sqlQuery += fmt.Sprintf(" AND user_id = $%d", argIndex)
-		args = append(args, userUUID)
-		argIndex++
+		if result.RowsAffected() == 0 {
+			return ErrNotFound
+		}
+
+		_, err = tx.Exec(ctx, `
+            INSERT INTO subscription_status_changes (subscription_id, status, effective_from, changed_at)
+            VALUES ($1, $2, TO_DATE($3, 'MM-YYYY'), $4)
+        `, change.SubscriptionID, change.Status, change.EffectiveFrom, change.ChangedAt)
+		return err
+	})
+}
+
+func (r *subscriptionRepo) ListRenewable(ctx context.Context, from, to string) ([]*domain.Subscription, error) {
+	rows, err := r.db.Query(ctx, `
+        SELECT `+selectSubscriptionColumns+`
+        FROM subscriptions
+        WHERE auto_renew AND status = 'active' AND end_date IS NOT NULL
+            AND TO_DATE(end_date, 'MM-YYYY') BETWEEN TO_DATE($1, 'MM-YYYY') AND TO_DATE($2, 'MM-YYYY')
+        ORDER BY id
+    `, from, to)
+	if err != nil {
+		return nil, err
 	}
+	defer rows.Close()
 
-	if req.ServiceName != nil {
-		sqlQuery += fmt.Sprintf(" AND service_name = $%d", argIndex)
-		args = append(args, *req.ServiceName)
-		argIndex++
+	subs := make([]*domain.Subscription, 0)
+	for rows.Next() {
+		sub, err := r.scanSubscription(rows)
+		if err != nil {
+			return nil, err
+		}
+		subs = append(subs, sub)
 	}
+	return subs, rows.Err()
+}
 
-	sqlQuery += `
-        )
-        SELECT COALESCE(SUM(
-            price * (
-                (EXTRACT(YEAR FROM calc_end)::int - EXTRACT(YEAR FROM calc_start)::int) * 12 +
-                (EXTRACT(MONTH FROM calc_end)::int - EXTRACT(MONTH FROM calc_start)::int) + 1
-            )
-        ), 0)::int as total
-        FROM period_calculations
-        WHERE calc_end >= calc_start
+func (r *subscriptionRepo) Renew(ctx context.Context, id uuid.UUID, previousEnd, endDate string, renewedAt time.Time) error {
+	return auditedTx(ctx, r.db, auditRows{domain.AuditSubscriptions: {id}}, func(tx pgx.Tx) error {
+		result, err := tx.Exec(ctx,
+			`UPDATE subscriptions SET end_date = $3, updated_at = $4, region = $5, version = version + 1
+             WHERE id = $1 AND end_date = $2 AND auto_renew`,
+			id, previousEnd, endDate, renewedAt, region.FromContext(ctx),
+		)
+		if err != nil {
+			return err
+		}
+		if result.RowsAffected() == 0 {
+			return ErrNotFound
+		}
+		return syncDualColumns(ctx, tx, r.migrations, []uuid.UUID{id})
+	})
+}
+
+func (r *subscriptionRepo) Cancel(ctx context.Context, sub *domain.Subscription, change *domain.StatusChange) error {
+	sub.Region = region.FromContext(ctx)
+	return auditedTx(ctx, r.db, auditRows{domain.AuditSubscriptions: {sub.ID}}, func(tx pgx.Tx) error {
+		err := tx.QueryRow(ctx, `
+            UPDATE subscriptions
+            SET end_date = $2, status = $3, cancelled_at = $4, cancel_reason = $5, updated_at = $6, auto_renew = $7, region = $8,
+                version = version + 1
+            WHERE id = $1 AND version = $9
+            RETURNING version
+        `, sub.ID, sub.EndDate, sub.Status, sub.CancelledAt, sub.CancelReason, sub.UpdatedAt, sub.AutoRenew, sub.Region, sub.Version).Scan(&sub.Version)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return versionMismatch(ctx, tx, sub.ID)
+		}
+		if err != nil {
+			return err
+		}
+		if err := syncDualColumns(ctx, tx, r.migrations, []uuid.UUID{sub.ID}); err != nil {
+			return err
+		}
+
+		_, err = tx.Exec(ctx, `
+            INSERT INTO subscription_status_changes (subscription_id, status, effective_from, changed_at)
+            VALUES ($1, $2, TO_DATE($3, 'MM-YYYY'), $4)
+        `, change.SubscriptionID, change.Status, change.EffectiveFrom, change.ChangedAt)
+		return err
+	})
+}
+
+func (r *subscriptionRepo) ListStatusChanges(ctx context.Context, subscriptionIDs []uuid.UUID) ([]*domain.StatusChange, error) {
+	rows, err := r.db.Query(ctx, `
+        SELECT subscription_id, status, TO_CHAR(effective_from, 'MM-YYYY'), changed_at
+        FROM subscription_status_changes
+        WHERE subscription_id = ANY($1)
+        ORDER BY subscription_id, effective_from, changed_at
+    `, subscriptionIDs)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	changes := make([]*domain.StatusChange, 0)
+	for rows.Next() {
+		var change domain.StatusChange
+		if err := rows.Scan(&change.SubscriptionID, &change.Status, &change.EffectiveFrom, &change.ChangedAt); err != nil {
+			return nil, err
+		}
+		changes = append(changes, &change)
+	}
+
+	return changes, rows.Err()
+}
+
+func (r *subscriptionRepo) ListHistory(ctx context.Context, req domain.CalculateTotalRequest) ([]*domain.Subscription, error) {
+	sqlQuery := `
+        SELECT ` + selectSubscriptionColumns + `
+        FROM subscriptions
+        WHERE TO_DATE(start_date, 'MM-YYYY') <= TO_DATE($1, 'MM-YYYY')
     `
+	filter, filterArgs := buildTotalFilter(req, 2)
+	sqlQuery += filter
+	args := append([]interface{}{req.EndPeriod}, filterArgs...)
 
-	var total int
-	err := r.db.QueryRow(ctx, sqlQuery, args...).Scan(&total)
-	return total, err
+	sqlQuery += " ORDER BY user_id, TO_DATE(start_date, 'MM-YYYY'), created_at"
+
+	rows, err := r.db.Query(ctx, sqlQuery, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	subscriptions := make([]*domain.Subscription, 0)
+	for rows.Next() {
+		sub, err := r.scanSubscription(rows)
+		if err != nil {
+			return nil, err
+		}
+		subscriptions = append(subscriptions, sub)
+	}
+
+	return subscriptions, rows.Err()
 }
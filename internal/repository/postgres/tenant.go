@@ -0,0 +1,209 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+
+	"aggregator_db/internal/migrator"
+	"aggregator_db/pkg/domain"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+var (
+	ErrTenantNotFound      = errors.New("tenant not found")
+	ErrTenantAlreadyExists = errors.New("tenant already exists")
+)
+
+// TenantRepository - реестр тенантов. Он всегда хранится в public основной базы,
+// поэтому репозиторий работает с базовым пулом, а не с TenantRouter.
+type TenantRepository interface {
+	Create(ctx context.Context, tenant *domain.Tenant) error
+	Get(ctx context.Context, id string) (*domain.Tenant, error)
+	List(ctx context.Context) ([]*domain.Tenant, error)
+	Update(ctx context.Context, tenant *domain.Tenant) error
+	Delete(ctx context.Context, id string) error
+}
+
+type tenantRepo struct {
+	db DB
+}
+
+func NewTenantRepository(db DB) TenantRepository {
+	return &tenantRepo{db: db}
+}
+
+const tenantColumns = `id, isolation, schema_name, status, max_subscriptions, requests_per_minute, features, money_format, open_ended, pinned_rates,
+        field_visibility, custom_fields, COALESCE(database_url, ''), COALESCE(api_key_hash, ''), created_at, updated_at`
+
+func scanTenant(row pgx.Row) (*domain.Tenant, error) {
+	var tenant domain.Tenant
+	err := row.Scan(
+		&tenant.ID,
+		&tenant.Isolation,
+		&tenant.SchemaName,
+		&tenant.Status,
+		&tenant.Quotas.MaxSubscriptions,
+		&tenant.Quotas.RequestsPerMinute,
+		&tenant.Features,
+		&tenant.MoneyFormat,
+		&tenant.OpenEnded,
+		&tenant.PinnedRates,
+		&tenant.FieldVisibility,
+		&tenant.CustomFields,
+		&tenant.DatabaseURL,
+		&tenant.APIKeyHash,
+		&tenant.CreatedAt,
+		&tenant.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &tenant, nil
+}
+
+func nullIfEmpty(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
+
+func tenantFeatures(tenant *domain.Tenant) map[string]bool {
+	if tenant.Features == nil {
+		return map[string]bool{}
+	}
+	return tenant.Features
+}
+
+func (r *tenantRepo) Create(ctx context.Context, tenant *domain.Tenant) error {
+	_, err := r.db.Exec(ctx, `
+        INSERT INTO public.tenants (id, isolation, schema_name, status, max_subscriptions, requests_per_minute, features, money_format, open_ended,
+            pinned_rates, field_visibility, custom_fields, database_url, api_key_hash, created_at, updated_at)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16)
+    `, tenant.ID, tenant.Isolation, tenant.SchemaName, tenant.Status, tenant.Quotas.MaxSubscriptions, tenant.Quotas.RequestsPerMinute, tenantFeatures(tenant), tenant.MoneyFormat,
+		tenant.OpenEnded, tenant.PinnedRates, tenant.FieldVisibility, tenant.CustomFields, nullIfEmpty(tenant.DatabaseURL), nullIfEmpty(tenant.APIKeyHash), tenant.CreatedAt, tenant.UpdatedAt)
+
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+		return ErrTenantAlreadyExists
+	}
+	return err
+}
+
+func (r *tenantRepo) Get(ctx context.Context, id string) (*domain.Tenant, error) {
+	row := r.db.QueryRow(ctx, `SELECT `+tenantColumns+` FROM public.tenants WHERE id = $1`, id)
+
+	tenant, err := scanTenant(row)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrTenantNotFound
+	}
+	return tenant, err
+}
+
+func (r *tenantRepo) List(ctx context.Context) ([]*domain.Tenant, error) {
+	rows, err := r.db.Query(ctx, `SELECT `+tenantColumns+` FROM public.tenants ORDER BY id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tenants := make([]*domain.Tenant, 0)
+	for rows.Next() {
+		tenant, err := scanTenant(rows)
+		if err != nil {
+			return nil, err
+		}
+		tenants = append(tenants, tenant)
+	}
+	return tenants, rows.Err()
+}
+
+func (r *tenantRepo) Update(ctx context.Context, tenant *domain.Tenant) error {
+	result, err := r.db.Exec(ctx, `
+        UPDATE public.tenants
+        SET status = $2, max_subscriptions = $3, requests_per_minute = $4, features = $5, money_format = $6, open_ended = $7,
+            pinned_rates = $8, field_visibility = $9, custom_fields = $10, database_url = $11, api_key_hash = $12, updated_at = $13
+        WHERE id = $1
+    `, tenant.ID, tenant.Status, tenant.Quotas.MaxSubscriptions, tenant.Quotas.RequestsPerMinute, tenantFeatures(tenant), tenant.MoneyFormat, tenant.OpenEnded,
+		tenant.PinnedRates, tenant.FieldVisibility, tenant.CustomFields, nullIfEmpty(tenant.DatabaseURL), nullIfEmpty(tenant.APIKeyHash), tenant.UpdatedAt)
+	if err != nil {
+		return err
+	}
+
+	if result.RowsAffected() == 0 {
+		return ErrTenantNotFound
+	}
+	return nil
+}
+
+func (r *tenantRepo) Delete(ctx context.Context, id string) error {
+	result, err := r.db.Exec(ctx, `DELETE FROM public.tenants WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+
+	if result.RowsAffected() == 0 {
+		return ErrTenantNotFound
+	}
+	return nil
+}
+
+// TenantProvisioner готовит хранилище нового тенанта: создает схему
+// (для изоляции через схему) и применяет к нему все миграции.
+type TenantProvisioner interface {
+	Provision(ctx context.Context, tenant *domain.Tenant) error
+	// Deprovision удаляет схему тенанта. Отдельная база тенанта не удаляется.
+	Deprovision(ctx context.Context, tenant *domain.Tenant) error
+	// Disconnect закрывает соединения тенанта после смены учетных данных или приостановки.
+	Disconnect(tenantID string)
+}
+
+type tenantProvisioner struct {
+	router     *TenantRouter
+	migrations fs.FS
+	logger     *slog.Logger
+}
+
+func NewTenantProvisioner(router *TenantRouter, migrations fs.FS, logger *slog.Logger) TenantProvisioner {
+	return &tenantProvisioner{router: router, migrations: migrations, logger: logger}
+}
+
+// Provision идемпотентен: схема создается через IF NOT EXISTS, а мигратор
+// продолжает с последней примененной версии, так что неудачную попытку можно повторить.
+func (p *tenantProvisioner) Provision(ctx context.Context, tenant *domain.Tenant) error {
+	if tenant.Isolation == domain.TenantIsolationSchema {
+		_, err := p.router.base.Exec(ctx, `CREATE SCHEMA IF NOT EXISTS `+pgx.Identifier{tenant.SchemaName}.Sanitize())
+		if err != nil {
+			return fmt.Errorf("create schema: %w", err)
+		}
+	}
+
+	pool, err := p.router.PoolFor(ctx, tenant)
+	if err != nil {
+		return err
+	}
+
+	if err := migrator.Migrate(ctx, pool, p.migrations, p.logger.With("tenant", tenant.ID)); err != nil {
+		p.router.Evict(tenant.ID)
+		return fmt.Errorf("migrate tenant %s: %w", tenant.ID, err)
+	}
+	return nil
+}
+
+func (p *tenantProvisioner) Deprovision(ctx context.Context, tenant *domain.Tenant) error {
+	p.router.Evict(tenant.ID)
+
+	if tenant.Isolation != domain.TenantIsolationSchema {
+		return nil
+	}
+	_, err := p.router.base.Exec(ctx, `DROP SCHEMA IF EXISTS `+pgx.Identifier{tenant.SchemaName}.Sanitize()+` CASCADE`)
+	return err
+}
+
+func (p *tenantProvisioner) Disconnect(tenantID string) {
+	p.router.Evict(tenantID)
+}
@@ -0,0 +1,151 @@
+package postgres
+
+import (
+	"context"
+	"sync"
+
+	"aggregator_db/internal/tenancy"
+	"aggregator_db/pkg/domain"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// TenantRouter направляет запросы репозиториев в пул тенанта из контекста.
+// Запросы без тенанта идут в базовый пул. Тенанту со схемой выдается пул
+// к основной базе с search_path на его схему, тенанту с отдельной базой - пул
+// к ней. Пулы создаются лениво и живут до Evict или Close.
+type TenantRouter struct {
+	base     *pgxpool.Pool
+	maxConns int32
+
+	mu    sync.Mutex
+	pools map[string]*pgxpool.Pool
+}
+
+func NewTenantRouter(base *pgxpool.Pool, maxConns int) *TenantRouter {
+	return &TenantRouter{
+		base:     base,
+		maxConns: int32(maxConns),
+		pools:    make(map[string]*pgxpool.Pool),
+	}
+}
+
+// PoolFor возвращает пул тенанта; для nil - базовый пул.
+func (r *TenantRouter) PoolFor(_ context.Context, tenant *domain.Tenant) (*pgxpool.Pool, error) {
+	if tenant == nil {
+		return r.base, nil
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if pool, ok := r.pools[tenant.ID]; ok {
+		return pool, nil
+	}
+
+	var cfg *pgxpool.Config
+	if tenant.Isolation == domain.TenantIsolationDatabase {
+		parsed, err := pgxpool.ParseConfig(tenant.DatabaseURL)
+		if err != nil {
+			return nil, err
+		}
+		// Своя база тенанта пишет медленные запросы в лог, обновляет соединения и ищет так же, как основная
+		base := r.base.Config()
+		parsed.ConnConfig.Tracer = base.ConnConfig.Tracer
+		parsed.MaxConnLifetime = base.MaxConnLifetime
+		parsed.MaxConnIdleTime = base.MaxConnIdleTime
+		parsed.HealthCheckPeriod = base.HealthCheckPeriod
+		if threshold, ok := base.ConnConfig.RuntimeParams[SimilarityThresholdParam]; ok {
+			parsed.ConnConfig.RuntimeParams[SimilarityThresholdParam] = threshold
+		}
+		if parsed.ConnConfig.ConnectTimeout == 0 {
+			parsed.ConnConfig.ConnectTimeout = base.ConnConfig.ConnectTimeout
+		}
+		cfg = parsed
+	} else {
+		cfg = r.base.Config()
+		// public остается в пути ради функций расширений; все таблицы есть в схеме тенанта
+		cfg.ConnConfig.RuntimeParams["search_path"] = pgx.Identifier{tenant.SchemaName}.Sanitize() + ", public"
+	}
+	if r.maxConns > 0 {
+		cfg.MaxConns = r.maxConns
+	}
+	// Пулы тенантов не держат соединений впрок: DB_MIN_CONNS относится только к базовому пулу
+	cfg.MinConns = 0
+
+	// NewWithConfig не открывает соединений, поэтому держать mu здесь дешево
+	pool, err := pgxpool.NewWithConfig(context.Background(), cfg)
+	if err != nil {
+		return nil, err
+	}
+	r.pools[tenant.ID] = pool
+	return pool, nil
+}
+
+// Evict закрывает пул тенанта; следующий запрос создаст его заново.
+func (r *TenantRouter) Evict(tenantID string) {
+	r.mu.Lock()
+	pool, ok := r.pools[tenantID]
+	delete(r.pools, tenantID)
+	r.mu.Unlock()
+
+	if ok {
+		pool.Close()
+	}
+}
+
+// Close закрывает пулы тенантов. Базовый пул закрывает его владелец.
+func (r *TenantRouter) Close() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for id, pool := range r.pools {
+		pool.Close()
+		delete(r.pools, id)
+	}
+}
+
+func (r *TenantRouter) pool(ctx context.Context) (*pgxpool.Pool, error) {
+	return r.PoolFor(ctx, tenancy.FromContext(ctx))
+}
+
+func (r *TenantRouter) Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error) {
+	pool, err := r.pool(ctx)
+	if err != nil {
+		return pgconn.CommandTag{}, err
+	}
+	return pool.Exec(ctx, sql, args...)
+}
+
+func (r *TenantRouter) Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error) {
+	pool, err := r.pool(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return pool.Query(ctx, sql, args...)
+}
+
+func (r *TenantRouter) QueryRow(ctx context.Context, sql string, args ...any) pgx.Row {
+	pool, err := r.pool(ctx)
+	if err != nil {
+		return errRow{err: err}
+	}
+	return pool.QueryRow(ctx, sql, args...)
+}
+
+func (r *TenantRouter) Begin(ctx context.Context) (pgx.Tx, error) {
+	pool, err := r.pool(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return pool.Begin(ctx)
+}
+
+type errRow struct {
+	err error
+}
+
+func (r errRow) Scan(...any) error {
+	return r.err
+}
@@ -0,0 +1,147 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"aggregator_db/pkg/metrics"
+	"aggregator_db/pkg/store"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// Transactor и ErrNoTx объявлены в pkg/store.
+type Transactor = store.Transactor
+
+var ErrNoTx = store.ErrNoTx
+
+var unitRetries = metrics.NewCounterVec(
+	"db_unit_retries_total",
+	"Повторы единиц работы после временных ошибок базы по виду ошибки",
+	"reason",
+)
+
+// UnitOfWork - DB, который отдает запросы транзакции единицы работы из контекста,
+// а без нее - базе db. Репозитории, созданные поверх него, участвуют в WithTx
+// без изменений.
+type UnitOfWork struct {
+	db     DB
+	retry  RetryPolicy
+	logger *slog.Logger
+}
+
+// NewUnitOfWork создает единицы работы поверх db. Единица, откаченная сервером
+// (сбой сериализации, взаимоблокировка) или не начавшаяся из-за соединения,
+// выполняется заново по retry.
+func NewUnitOfWork(db DB, retry RetryPolicy, logger *slog.Logger) *UnitOfWork {
+	return &UnitOfWork{db: db, retry: retry, logger: logger}
+}
+
+type unitKey struct{}
+
+// unit - транзакция единицы работы и то, что нужно сделать после ее коммита.
+type unit struct {
+	tx          pgx.Tx
+	afterCommit []func()
+}
+
+func unitFromContext(ctx context.Context) *unit {
+	u, _ := ctx.Value(unitKey{}).(*unit)
+	return u
+}
+
+// InTx сообщает, что ctx принадлежит единице работы. Повторять отдельный запрос
+// в ней бессмысленно: после ошибки транзакция уже откачена целиком.
+func InTx(ctx context.Context) bool {
+	return unitFromContext(ctx) != nil
+}
+
+// AfterCommit вызывает fn после коммита единицы работы ctx, а вне ее - сразу.
+// При откате fn не вызывается.
+func AfterCommit(ctx context.Context, fn func()) {
+	if u := unitFromContext(ctx); u != nil {
+		u.afterCommit = append(u.afterCommit, fn)
+		return
+	}
+	fn()
+}
+
+func (w *UnitOfWork) WithTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	if InTx(ctx) {
+		return fn(ctx)
+	}
+
+	for attempt := 0; ; attempt++ {
+		u := &unit{}
+		err := pgx.BeginFunc(ctx, w.db, func(tx pgx.Tx) error {
+			u.tx = tx
+			return fn(context.WithValue(ctx, unitKey{}, u))
+		})
+		if err == nil {
+			for _, fn := range u.afterCommit {
+				fn()
+			}
+			return nil
+		}
+		// Обрыв соединения посреди единицы не повторяется: коммит мог пройти
+		if attempt >= w.retry.MaxRetries || !IsRetryable(err, false) {
+			return unavailable(err)
+		}
+
+		reason := RetryReason(err)
+		unitRetries.Inc(reason)
+		w.logger.WarnContext(ctx, "retrying unit of work",
+			slog.Int("attempt", attempt+1),
+			slog.String("reason", reason),
+			slog.String("error", err.Error()),
+		)
+		if !w.retry.Wait(ctx, attempt) {
+			return unavailable(err)
+		}
+	}
+}
+
+// unavailable оборачивает ошибку недоступности базы в ErrUnavailable, как это
+// делает instrumented-репозиторий: по ней, например, откладываются записи, если
+// не удалось даже начать транзакцию.
+func unavailable(err error) error {
+	if !errors.Is(err, ErrUnavailable) && IsUnavailable(err) {
+		return fmt.Errorf("%w: %w", ErrUnavailable, err)
+	}
+	return err
+}
+
+func (w *UnitOfWork) Lock(ctx context.Context, key string) error {
+	u := unitFromContext(ctx)
+	if u == nil {
+		return ErrNoTx
+	}
+	_, err := u.tx.Exec(ctx, `SELECT pg_advisory_xact_lock(hashtextextended($1, 0))`, key)
+	return err
+}
+
+func (w *UnitOfWork) conn(ctx context.Context) DB {
+	if u := unitFromContext(ctx); u != nil {
+		return u.tx
+	}
+	return w.db
+}
+
+func (w *UnitOfWork) Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error) {
+	return w.conn(ctx).Exec(ctx, sql, args...)
+}
+
+func (w *UnitOfWork) Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error) {
+	return w.conn(ctx).Query(ctx, sql, args...)
+}
+
+func (w *UnitOfWork) QueryRow(ctx context.Context, sql string, args ...any) pgx.Row {
+	return w.conn(ctx).QueryRow(ctx, sql, args...)
+}
+
+// Begin внутри единицы работы открывает точку сохранения в ее транзакции.
+func (w *UnitOfWork) Begin(ctx context.Context) (pgx.Tx, error) {
+	return w.conn(ctx).Begin(ctx)
+}
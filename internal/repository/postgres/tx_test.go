@@ -0,0 +1,156 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"slices"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// recordingDB записывает, куда пришли запросы: в базу, в транзакцию или в точку сохранения.
+type recordingDB struct {
+	DB
+	log []string
+}
+
+func (db *recordingDB) Exec(_ context.Context, sql string, _ ...any) (pgconn.CommandTag, error) {
+	db.log = append(db.log, "db: "+sql)
+	return pgconn.CommandTag{}, nil
+}
+
+func (db *recordingDB) Begin(context.Context) (pgx.Tx, error) {
+	db.log = append(db.log, "begin")
+	return &recordingTx{db: db, name: "tx"}, nil
+}
+
+type recordingTx struct {
+	pgx.Tx
+	db   *recordingDB
+	name string
+	done bool
+}
+
+func (tx *recordingTx) Exec(_ context.Context, sql string, _ ...any) (pgconn.CommandTag, error) {
+	tx.db.log = append(tx.db.log, tx.name+": "+sql)
+	return pgconn.CommandTag{}, nil
+}
+
+func (tx *recordingTx) Begin(context.Context) (pgx.Tx, error) {
+	tx.db.log = append(tx.db.log, "savepoint")
+	return &recordingTx{db: tx.db, name: "savepoint"}, nil
+}
+
+func (tx *recordingTx) Commit(context.Context) error {
+	tx.done = true
+	tx.db.log = append(tx.db.log, "commit "+tx.name)
+	return nil
+}
+
+// Rollback после Commit ничего не делает, как и в pgx: BeginFunc вызывает его всегда.
+func (tx *recordingTx) Rollback(context.Context) error {
+	if tx.done {
+		return nil
+	}
+	tx.done = true
+	tx.db.log = append(tx.db.log, "rollback "+tx.name)
+	return nil
+}
+
+func TestUnitOfWork(t *testing.T) {
+	ctx := context.Background()
+	db := &recordingDB{}
+	uow := NewUnitOfWork(db, RetryPolicy{MaxRetries: 2}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	notified := 0
+	repoDB := NotifyOnCommit(uow, func() { notified++ })
+
+	if err := uow.Lock(ctx, "key"); !errors.Is(err, ErrNoTx) {
+		t.Errorf("Lock outside a unit = %v, want ErrNoTx", err)
+	}
+
+	err := uow.WithTx(ctx, func(ctx context.Context) error {
+		if _, err := uow.Exec(ctx, "INSERT 1"); err != nil {
+			return err
+		}
+		// Транзакция репозитория становится точкой сохранения, уведомление ждет коммита
+		if err := pgx.BeginFunc(ctx, repoDB, func(tx pgx.Tx) error {
+			_, err := tx.Exec(ctx, "INSERT 2")
+			return err
+		}); err != nil {
+			return err
+		}
+		if notified != 0 {
+			t.Error("notified before the unit committed")
+		}
+		// Вложенная единица работы присоединяется к внешней
+		return uow.WithTx(ctx, func(ctx context.Context) error {
+			_, err := uow.Exec(ctx, "INSERT 3")
+			return err
+		})
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"begin", "tx: INSERT 1", "savepoint", "savepoint: INSERT 2", "commit savepoint", "tx: INSERT 3", "commit tx"}
+	if !slices.Equal(db.log, want) {
+		t.Errorf("log = %q\nwant  %q", db.log, want)
+	}
+	if notified != 1 {
+		t.Errorf("notified %d times after commit, want 1", notified)
+	}
+
+	db.log, notified = nil, 0
+	failed := errors.New("failed")
+	err = uow.WithTx(ctx, func(ctx context.Context) error {
+		if err := pgx.BeginFunc(ctx, repoDB, func(tx pgx.Tx) error { return nil }); err != nil {
+			return err
+		}
+		return failed
+	})
+	if !errors.Is(err, failed) {
+		t.Fatalf("err = %v, want %v", err, failed)
+	}
+	if want := []string{"begin", "savepoint", "commit savepoint", "rollback tx"}; !slices.Equal(db.log, want) {
+		t.Errorf("log = %q\nwant  %q", db.log, want)
+	}
+	if notified != 0 {
+		t.Errorf("notified %d times after rollback", notified)
+	}
+
+	// Единица, откаченная сервером из-за сбоя сериализации, выполняется заново
+	db.log = nil
+	attempts := 0
+	err = uow.WithTx(ctx, func(ctx context.Context) error {
+		attempts++
+		if err := pgx.BeginFunc(ctx, repoDB, func(tx pgx.Tx) error { return nil }); err != nil {
+			return err
+		}
+		if attempts == 1 {
+			return &pgconn.PgError{Code: "40001"}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = []string{"begin", "savepoint", "commit savepoint", "rollback tx", "begin", "savepoint", "commit savepoint", "commit tx"}
+	if !slices.Equal(db.log, want) {
+		t.Errorf("log = %q\nwant  %q", db.log, want)
+	}
+	if notified != 1 {
+		t.Errorf("notified %d times after a retried unit, want 1", notified)
+	}
+
+	// Вне единицы работы запросы идут в базу
+	db.log = nil
+	if _, err := uow.Exec(ctx, "INSERT 4"); err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"db: INSERT 4"}; !slices.Equal(db.log, want) {
+		t.Errorf("log = %q, want %q", db.log, want)
+	}
+}
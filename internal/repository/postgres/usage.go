@@ -0,0 +1,69 @@
+package postgres
+
+import (
+	"context"
+
+	"aggregator_db/pkg/domain"
+	"github.com/jackc/pgx/v5"
+)
+
+// UsageRepository - журнал потребления API. Как и реестр тенантов, хранится
+// в public основной базы и работает с базовым пулом.
+type UsageRepository interface {
+	// Add прибавляет количество к суточным записям.
+	Add(ctx context.Context, records []*domain.UsageRecord) error
+	List(ctx context.Context, query domain.UsageQuery) ([]*domain.UsageRecord, error)
+}
+
+type usageRepo struct {
+	db DB
+}
+
+func NewUsageRepository(db DB) UsageRepository {
+	return &usageRepo{db: db}
+}
+
+func (r *usageRepo) Add(ctx context.Context, records []*domain.UsageRecord) error {
+	batch := &pgx.Batch{}
+	for _, record := range records {
+		batch.Queue(`
+            INSERT INTO public.usage_records (consumer, metric, day, quantity)
+            VALUES ($1, $2, $3::date, $4)
+            ON CONFLICT (consumer, metric, day) DO UPDATE
+            SET quantity = usage_records.quantity + EXCLUDED.quantity
+        `, record.Consumer, record.Metric, record.Day, record.Quantity)
+	}
+
+	return pgx.BeginFunc(ctx, r.db, func(tx pgx.Tx) error {
+		return tx.SendBatch(ctx, batch).Close()
+	})
+}
+
+func (r *usageRepo) List(ctx context.Context, query domain.UsageQuery) ([]*domain.UsageRecord, error) {
+	sql := `
+        SELECT consumer, metric, TO_CHAR(day, 'YYYY-MM-DD'), quantity
+        FROM public.usage_records
+        WHERE day BETWEEN $1::date AND $2::date`
+	args := []interface{}{query.From, query.To}
+	if query.Consumer != nil {
+		sql += ` AND consumer = $3`
+		args = append(args, *query.Consumer)
+	}
+	sql += ` ORDER BY day, consumer, metric`
+
+	rows, err := r.db.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	records := make([]*domain.UsageRecord, 0)
+	for rows.Next() {
+		var record domain.UsageRecord
+		if err := rows.Scan(&record.Consumer, &record.Metric, &record.Day, &record.Quantity); err != nil {
+			return nil, err
+		}
+		records = append(records, &record)
+	}
+	return records, rows.Err()
+}
@@ -0,0 +1,144 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+
+	"aggregator_db/pkg/domain"
+	"aggregator_db/pkg/store"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// Ошибки и интерфейс хранилища пользователей объявлены в pkg/store.
+var (
+	ErrUserNotFound      = store.ErrUserNotFound
+	ErrUserAlreadyExists = store.ErrUserAlreadyExists
+	ErrUserEmailTaken    = store.ErrUserEmailTaken
+)
+
+type UserRepository = store.UserRepository
+
+type userRepo struct {
+	db DB
+}
+
+func NewUserRepository(db DB) UserRepository {
+	return &userRepo{db: db}
+}
+
+const userColumns = `id, email, name, role, created_at, updated_at`
+
+// provisionUserQuery заводит пользователя подписки, если его еще нет.
+const provisionUserQuery = `
+        INSERT INTO users (id, created_at, updated_at) VALUES ($1, $2, $2)
+        ON CONFLICT (id) DO NOTHING
+    `
+
+func scanUser(row pgx.Row) (*domain.User, error) {
+	var user domain.User
+	if err := row.Scan(&user.ID, &user.Email, &user.Name, &user.Role, &user.CreatedAt, &user.UpdatedAt); err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// userConflict переводит нарушение уникальности в ошибку репозитория.
+func userConflict(err error) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+		if pgErr.ConstraintName == "idx_users_email" {
+			return ErrUserEmailTaken
+		}
+		return ErrUserAlreadyExists
+	}
+	return err
+}
+
+func (r *userRepo) Create(ctx context.Context, user *domain.User) error {
+	err := auditedTx(ctx, r.db, auditRows{domain.AuditUsers: {user.ID}}, func(tx pgx.Tx) error {
+		_, err := tx.Exec(ctx, `INSERT INTO users (`+userColumns+`) VALUES ($1, $2, $3, $4, $5, $6)`,
+			user.ID, user.Email, user.Name, user.Role, user.CreatedAt, user.UpdatedAt)
+		return err
+	})
+	return userConflict(err)
+}
+
+func (r *userRepo) GetByID(ctx context.Context, id uuid.UUID) (*domain.User, error) {
+	row := r.db.QueryRow(ctx, `SELECT `+userColumns+` FROM users WHERE id = $1`, id)
+
+	user, err := scanUser(row)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrUserNotFound
+	}
+	return user, err
+}
+
+func (r *userRepo) List(ctx context.Context, query domain.ListUsersQuery) ([]*domain.User, error) {
+	rows, err := r.db.Query(ctx, `SELECT `+userColumns+` FROM users ORDER BY created_at, id LIMIT $1 OFFSET $2`,
+		query.Limit, query.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	users := make([]*domain.User, 0)
+	for rows.Next() {
+		user, err := scanUser(rows)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, user)
+	}
+	return users, rows.Err()
+}
+
+func (r *userRepo) Count(ctx context.Context) (int, error) {
+	var count int
+	err := r.db.QueryRow(ctx, `SELECT COUNT(*) FROM users`).Scan(&count)
+	return count, err
+}
+
+func (r *userRepo) Update(ctx context.Context, user *domain.User) error {
+	err := auditedTx(ctx, r.db, auditRows{domain.AuditUsers: {user.ID}}, func(tx pgx.Tx) error {
+		result, err := tx.Exec(ctx, `UPDATE users SET email = $2, name = $3, role = $4, updated_at = $5 WHERE id = $1`,
+			user.ID, user.Email, user.Name, user.Role, user.UpdatedAt)
+		if err != nil {
+			return err
+		}
+		if result.RowsAffected() == 0 {
+			return ErrUserNotFound
+		}
+		return nil
+	})
+	return userConflict(err)
+}
+
+func (r *userRepo) Delete(ctx context.Context, id uuid.UUID) error {
+	return pgx.BeginFunc(ctx, r.db, func(tx pgx.Tx) error {
+		// Подписки и бюджеты удаляются каскадом и тоже попадают в журнал
+		rows := auditRows{domain.AuditUsers: {id}}
+		for entity, query := range map[string]string{
+			domain.AuditSubscriptions: `SELECT id FROM subscriptions WHERE user_id = $1`,
+			domain.AuditBudgets:       `SELECT id FROM budgets WHERE user_id = $1`,
+		} {
+			ids, err := collectIDs(ctx, tx, query, id)
+			if err != nil {
+				return err
+			}
+			rows[entity] = ids
+		}
+
+		return audited(ctx, tx, rows, func() error {
+			result, err := tx.Exec(ctx, `DELETE FROM users WHERE id = $1`, id)
+			if err != nil {
+				return err
+			}
+			if result.RowsAffected() == 0 {
+				return ErrUserNotFound
+			}
+			return nil
+		})
+	})
+}
@@ -0,0 +1,54 @@
+package retryafter
+
+import (
+	"sync"
+	"time"
+)
+
+// Outage отслеживает недоступность зависимости по результатам обращений к ней.
+// После каждой неудачи подряд ожидаемое время восстановления удваивается от base до max;
+// первый успешный вызов сбрасывает счетчик.
+type Outage struct {
+	mu       sync.Mutex
+	base     time.Duration
+	max      time.Duration
+	failures int
+	failedAt time.Time
+	now      func() time.Time
+}
+
+func NewOutage(base, maxDelay time.Duration) *Outage {
+	return &Outage{base: base, max: maxDelay, now: time.Now}
+}
+
+func (o *Outage) Fail() {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	o.failures++
+	o.failedAt = o.now()
+}
+
+func (o *Outage) Recover() {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	o.failures = 0
+}
+
+// RetryAfter возвращает, сколько осталось до ожидаемого восстановления с момента последней неудачи.
+func (o *Outage) RetryAfter() time.Duration {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if o.failures == 0 {
+		return 0
+	}
+	delay := o.base
+	for i := 1; i < o.failures && delay < o.max; i++ {
+		delay *= 2
+	}
+	delay = min(delay, o.max)
+
+	return max(delay-o.now().Sub(o.failedAt), 0)
+}
@@ -0,0 +1,99 @@
+// Package retryafter подсказывает клиентам, через сколько повторить запрос,
+// получивший 429 или 5xx. Подсказка берется из состояния зависимостей (база,
+// circuit breaker исходящих клиентов), а если они в порядке - из текущей нагрузки.
+package retryafter
+
+import (
+	"math"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Source сообщает, через сколько зависимость ожидает восстановиться; 0 - зависимость в порядке.
+type Source interface {
+	RetryAfter() time.Duration
+}
+
+type SourceFunc func() time.Duration
+
+func (f SourceFunc) RetryAfter() time.Duration { return f() }
+
+// ReasonLoad - подсказка посчитана по нагрузке, а не по состоянию зависимости.
+const ReasonLoad = "load"
+
+type namedSource struct {
+	name   string
+	source Source
+}
+
+type Policy struct {
+	min         time.Duration
+	max         time.Duration
+	maxInFlight int64
+	inFlight    atomic.Int64
+
+	mu      sync.RWMutex
+	sources []namedSource
+}
+
+// NewPolicy - подсказка не короче minDelay и не длиннее maxDelay. При maxInFlight
+// одновременных запросах нагрузочная подсказка достигает maxDelay.
+func NewPolicy(minDelay, maxDelay time.Duration, maxInFlight int) *Policy {
+	if maxDelay < minDelay {
+		maxDelay = minDelay
+	}
+	return &Policy{min: minDelay, max: maxDelay, maxInFlight: int64(maxInFlight)}
+}
+
+// Register добавляет зависимость; name попадает в метрики как причина подсказки.
+func (p *Policy) Register(name string, source Source) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.sources = append(p.sources, namedSource{name: name, source: source})
+}
+
+// Track учитывает запрос в нагрузке; возвращенная функция вызывается по его завершении.
+func (p *Policy) Track() func() {
+	p.inFlight.Add(1)
+	return func() { p.inFlight.Add(-1) }
+}
+
+// Hint возвращает подсказку и ее причину: самую долгую задержку среди
+// деградировавших зависимостей, а если таких нет - задержку по нагрузке.
+func (p *Policy) Hint() (time.Duration, string) {
+	var hint time.Duration
+	reason := ""
+
+	p.mu.RLock()
+	for _, s := range p.sources {
+		if d := s.source.RetryAfter(); d > hint {
+			hint, reason = d, s.name
+		}
+	}
+	p.mu.RUnlock()
+
+	if reason == "" {
+		hint, reason = p.loadHint(), ReasonLoad
+	}
+	return p.clamp(hint), reason
+}
+
+// loadHint растет линейно от min без нагрузки до max при maxInFlight запросах.
+func (p *Policy) loadHint() time.Duration {
+	if p.maxInFlight <= 0 {
+		return p.min
+	}
+	load := math.Min(float64(p.inFlight.Load())/float64(p.maxInFlight), 1)
+	return p.min + time.Duration(load*float64(p.max-p.min))
+}
+
+func (p *Policy) clamp(d time.Duration) time.Duration {
+	return min(max(d, p.min), p.max)
+}
+
+// Seconds округляет подсказку вверх до целых секунд для заголовка Retry-After.
+func Seconds(d time.Duration) int {
+	return max(int(math.Ceil(d.Seconds())), 1)
+}
@@ -0,0 +1,87 @@
+package retryafter
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPolicyHint(t *testing.T) {
+	p := NewPolicy(time.Second, 30*time.Second, 10)
+
+	if hint, reason := p.Hint(); hint != time.Second || reason != ReasonLoad {
+		t.Errorf("idle: got %s %q, want 1s by load", hint, reason)
+	}
+
+	// Половина допустимой нагрузки - половина пути от min до max
+	var done []func()
+	for i := 0; i < 5; i++ {
+		done = append(done, p.Track())
+	}
+	if hint, _ := p.Hint(); hint != 15500*time.Millisecond {
+		t.Errorf("half load: got %s, want 15.5s", hint)
+	}
+	for i := 0; i < 20; i++ {
+		done = append(done, p.Track())
+	}
+	if hint, _ := p.Hint(); hint != 30*time.Second {
+		t.Errorf("overload: got %s, want max", hint)
+	}
+	for _, d := range done {
+		d()
+	}
+
+	// Деградировавшая зависимость важнее нагрузки; берется самая долгая задержка
+	p.Register("healthy", SourceFunc(func() time.Duration { return 0 }))
+	p.Register("database", SourceFunc(func() time.Duration { return 4 * time.Second }))
+	p.Register("exchange_rates", SourceFunc(func() time.Duration { return 7 * time.Second }))
+	if hint, reason := p.Hint(); hint != 7*time.Second || reason != "exchange_rates" {
+		t.Errorf("degraded: got %s %q, want 7s from exchange_rates", hint, reason)
+	}
+
+	p.Register("broken", SourceFunc(func() time.Duration { return time.Hour }))
+	if hint, _ := p.Hint(); hint != 30*time.Second {
+		t.Errorf("hint must be clamped to max, got %s", hint)
+	}
+}
+
+func TestOutageBackoff(t *testing.T) {
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	o := NewOutage(time.Second, 10*time.Second)
+	o.now = func() time.Time { return now }
+
+	if d := o.RetryAfter(); d != 0 {
+		t.Fatalf("healthy dependency: got %s, want 0", d)
+	}
+
+	for _, want := range []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 8 * time.Second, 10 * time.Second} {
+		o.Fail()
+		if d := o.RetryAfter(); d != want {
+			t.Errorf("after %d failures: got %s, want %s", o.failures, d, want)
+		}
+	}
+
+	// Подсказка отсчитывается от последней неудачи
+	now = now.Add(3 * time.Second)
+	if d := o.RetryAfter(); d != 7*time.Second {
+		t.Errorf("got %s, want 7s", d)
+	}
+	now = now.Add(time.Minute)
+	if d := o.RetryAfter(); d != 0 {
+		t.Errorf("expired outage: got %s, want 0", d)
+	}
+
+	o.Fail()
+	o.Recover()
+	if d := o.RetryAfter(); d != 0 {
+		t.Errorf("recovered: got %s, want 0", d)
+	}
+}
+
+func TestSeconds(t *testing.T) {
+	cases := map[time.Duration]int{0: 1, 300 * time.Millisecond: 1, time.Second: 1, 1500 * time.Millisecond: 2, time.Minute: 60}
+	for d, want := range cases {
+		if got := Seconds(d); got != want {
+			t.Errorf("Seconds(%s) = %d, want %d", d, got, want)
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package scheduler
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"aggregator_db/internal/clock"
+	"aggregator_db/pkg/metrics"
+)
+
+var (
+	jobRuns = metrics.NewCounterVec(
+		"scheduler_job_runs_total",
+		"Количество запусков фоновых задач",
+		"job", "status",
+	)
+	jobDuration = metrics.NewHistogramVec(
+		"scheduler_job_duration_seconds",
+		"Длительность фоновых задач",
+		nil,
+		"job",
+	)
+)
+
+// Job запускается сразу при старте планировщика и затем каждые Interval.
+type Job struct {
+	Name     string
+	Interval time.Duration
+	Run      func(ctx context.Context, now time.Time) error
+}
+
+// Scheduler - простой планировщик периодических задач внутри процесса.
+// При нескольких репликах задачи выполняются на каждой, поэтому включается явно.
+type Scheduler struct {
+	jobs   []Job
+	logger *slog.Logger
+}
+
+func New(logger *slog.Logger) *Scheduler {
+	return &Scheduler{logger: logger}
+}
+
+func (s *Scheduler) Add(job Job) {
+	s.jobs = append(s.jobs, job)
+}
+
+// Run блокируется до отмены ctx и дожидается завершения текущих запусков.
+func (s *Scheduler) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+	for _, job := range s.jobs {
+		wg.Add(1)
+		go func(job Job) {
+			defer wg.Done()
+			s.loop(ctx, job)
+		}(job)
+	}
+	wg.Wait()
+}
+
+func (s *Scheduler) loop(ctx context.Context, job Job) {
+	ticker := time.NewTicker(job.Interval)
+	defer ticker.Stop()
+
+	for {
+		s.runOnce(ctx, job)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *Scheduler) runOnce(ctx context.Context, job Job) {
+	start := time.Now()
+	err := job.Run(ctx, clock.Now(ctx))
+	jobDuration.Observe(time.Since(start).Seconds(), job.Name)
+
+	if err != nil {
+		jobRuns.Inc(job.Name, "error")
+		s.logger.ErrorContext(ctx, "scheduled job failed",
+			slog.String("job", job.Name),
+			slog.String("error", err.Error()),
+		)
+		return
+	}
+	jobRuns.Inc(job.Name, "ok")
+}
@@ -0,0 +1,180 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"time"
+
+	"aggregator_db/internal/clock"
+	"aggregator_db/internal/repository/postgres"
+	"aggregator_db/pkg/domain"
+	"aggregator_db/pkg/events"
+	"github.com/google/uuid"
+)
+
+// AlertService ведет критические уведомления: ждет их подтверждения и, если
+// подтверждения нет, отправляет по цепочке эскалации из настроек владельца.
+type AlertService struct {
+	repo          postgres.AlertRepository
+	settings      postgres.NotificationSettingsRepository
+	users         postgres.UserRepository
+	notifications *NotificationService
+	logger        *slog.Logger
+}
+
+func NewAlertService(repo postgres.AlertRepository, settings postgres.NotificationSettingsRepository, users postgres.UserRepository, notifications *NotificationService, logger *slog.Logger) *AlertService {
+	return &AlertService{
+		repo:          repo,
+		settings:      settings,
+		users:         users,
+		notifications: notifications,
+		logger:        logger,
+	}
+}
+
+// Raise заводит уведомление по опубликованному событию event, если у владельца
+// userID настроена цепочка эскалации. ID уведомления - ID события, поэтому
+// повторная публикация того же события не создает второе уведомление.
+func (s *AlertService) Raise(ctx context.Context, userID uuid.UUID, event events.Event) error {
+	settings, err := s.settings.Get(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if len(settings.Escalation) == 0 {
+		return nil
+	}
+
+	data, err := json.Marshal(event.Data)
+	if err != nil {
+		return err
+	}
+	alert := &domain.Alert{
+		ID:        event.ID,
+		UserID:    userID,
+		Type:      event.Type,
+		Status:    domain.AlertOpen,
+		Data:      data,
+		Steps:     settings.Escalation,
+		CreatedAt: event.OccurredAt,
+	}
+	alert.NextEscalationAt = alert.NextEscalation(event.OccurredAt)
+
+	created, err := s.repo.Create(ctx, alert)
+	if err != nil {
+		return err
+	}
+	if created {
+		s.logger.InfoContext(ctx, "alert raised",
+			slog.String("id", alert.ID.String()),
+			slog.String("type", alert.Type),
+			slog.String("user_id", userID.String()),
+		)
+	}
+	return nil
+}
+
+// Escalate - задача планировщика. Отправляет открытые уведомления, у которых
+// наступил следующий шаг, и планирует шаг после него. Неудачная доставка
+// повторяется при следующем запуске; шаг, который отправить некому (получатель
+// удален или не указал почту), пропускается.
+func (s *AlertService) Escalate(ctx context.Context, now time.Time) error {
+	alerts, err := s.repo.ListDue(ctx, now)
+	if err != nil {
+		return err
+	}
+
+	escalated := 0
+	for _, alert := range alerts {
+		if err := s.deliver(ctx, alert); err != nil {
+			s.logger.WarnContext(ctx, "failed to escalate alert",
+				slog.String("id", alert.ID.String()),
+				slog.Int("step", alert.Step+1),
+				slog.String("error", err.Error()),
+			)
+			continue
+		}
+
+		from := alert.Step
+		alert.Step++
+		alert.EscalatedAt = &now
+		alert.NextEscalationAt = alert.NextEscalation(now)
+		// Уведомление могли подтвердить, пока шаг отправлялся: тогда Advance ничего не меняет
+		if _, err := s.repo.Advance(ctx, alert, from); err != nil {
+			return err
+		}
+		escalated++
+	}
+
+	s.logger.InfoContext(ctx, "alert escalation finished",
+		slog.Int("due", len(alerts)),
+		slog.Int("escalated", escalated),
+	)
+	return nil
+}
+
+// deliver отправляет текущий шаг уведомления его получателю.
+func (s *AlertService) deliver(ctx context.Context, alert *domain.Alert) error {
+	step := alert.Steps[alert.Step]
+	recipient := alert.Recipient(step)
+
+	if step.Channel == domain.AlertWebhook {
+		return s.notifications.PublishAlertEscalation(ctx, domain.AlertEscalation{
+			AlertID:     alert.ID,
+			UserID:      alert.UserID,
+			RecipientID: recipient,
+			Type:        alert.Type,
+			Step:        alert.Step + 1,
+			CreatedAt:   alert.CreatedAt,
+			Data:        alert.Data,
+		})
+	}
+
+	user, err := s.users.GetByID(ctx, recipient)
+	if errors.Is(err, postgres.ErrUserNotFound) {
+		s.logger.WarnContext(ctx, "alert escalation skipped: recipient not found",
+			slog.String("id", alert.ID.String()),
+			slog.String("recipient_id", recipient.String()),
+		)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if user.Email == nil || *user.Email == "" {
+		s.logger.WarnContext(ctx, "alert escalation skipped: recipient has no email",
+			slog.String("id", alert.ID.String()),
+			slog.String("recipient_id", recipient.String()),
+		)
+		return nil
+	}
+	return s.notifications.SendAlertEscalation(ctx, *user.Email, alert)
+}
+
+func (s *AlertService) Get(ctx context.Context, id uuid.UUID) (*domain.Alert, error) {
+	return s.repo.GetByID(ctx, id)
+}
+
+// List возвращает уведомления пользователя, новые сверху.
+func (s *AlertService) List(ctx context.Context, userID uuid.UUID) ([]*domain.Alert, error) {
+	if _, err := s.users.GetByID(ctx, userID); err != nil {
+		return nil, err
+	}
+	return s.repo.ListByUser(ctx, userID)
+}
+
+// Acknowledge подтверждает уведомление и останавливает его эскалацию. by -
+// пользователь запроса; nil, если доступ не проверяется.
+func (s *AlertService) Acknowledge(ctx context.Context, id uuid.UUID, by *uuid.UUID) (*domain.Alert, error) {
+	alert, err := s.repo.Acknowledge(ctx, id, by, clock.Now(ctx))
+	if err != nil {
+		return nil, err
+	}
+
+	s.logger.InfoContext(ctx, "alert acknowledged",
+		slog.String("id", id.String()),
+		slog.Int("step", alert.Step),
+	)
+	return alert, nil
+}
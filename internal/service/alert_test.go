@@ -0,0 +1,160 @@
+package service
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"aggregator_db/internal/exchange"
+	"aggregator_db/internal/repository/memory"
+	"aggregator_db/pkg/domain"
+	"github.com/google/uuid"
+)
+
+func TestAlertEscalation(t *testing.T) {
+	ctx := context.Background()
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	subs := memory.NewSubscriptionRepository()
+	users := memory.NewUserRepository(subs)
+	settings := memory.NewNotificationSettingsRepository()
+	mail := &recordingMailer{}
+	publisher := &recordingPublisher{}
+
+	aliceEmail := "alice@example.com"
+	alice := &domain.User{ID: uuid.New(), Email: &aliceEmail}
+	bob := &domain.User{ID: uuid.New()}
+	for _, user := range []*domain.User{alice, bob} {
+		if err := users.Create(ctx, user); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := subs.Create(ctx, &domain.Subscription{ID: uuid.New(), UserID: alice.ID, ServiceName: "Netflix", Tags: []string{"streaming"},
+		Price: domain.NewMoney(59900, domain.DefaultCurrency), StartDate: "06-2025"}); err != nil {
+		t.Fatal(err)
+	}
+	// Сначала письмо самой Алисе, через два часа - событие для Боба
+	if err := settings.Upsert(ctx, &domain.NotificationSettings{UserID: alice.ID, StatementChannel: domain.StatementNone,
+		Escalation: []domain.EscalationStep{
+			{AfterHours: 1, Channel: domain.AlertEmail},
+			{AfterHours: 2, Channel: domain.AlertWebhook, UserID: &bob.ID},
+		}}); err != nil {
+		t.Fatal(err)
+	}
+
+	subscriptions := NewSubscriptionService(subs, memory.NewTransactor(), memory.NewServiceAliasRepository(), &recordingPublisher{}, exchange.NewStaticProvider(domain.DefaultCurrency, nil), logger)
+	notifications := NewNotificationService(subs, settings, publisher, mail, 20, logger)
+	alerts := NewAlertService(memory.NewAlertRepository(), settings, users, notifications, logger)
+	budgets := NewBudgetService(memory.NewBudgetRepository(), users, subscriptions, publisher, alerts, logger)
+	if _, err := budgets.Create(ctx, domain.CreateBudgetRequest{UserID: alice.ID, Category: "streaming", Limit: domain.NewMoney(10000, domain.DefaultCurrency)}); err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Date(2025, 10, 23, 12, 0, 0, 0, time.UTC)
+	// Повторная проверка публикует то же событие и не заводит второе уведомление
+	for _, at := range []time.Time{now, now.Add(30 * time.Minute)} {
+		if err := budgets.CheckBudgets(ctx, at); err != nil {
+			t.Fatal(err)
+		}
+	}
+	list, err := alerts.List(ctx, alice.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 1 || list[0].Type != "budget.exceeded" || list[0].ID != publisher.events[0].ID {
+		t.Fatalf("expected one budget.exceeded alert, got %+v", list)
+	}
+	alert := list[0]
+
+	escalate := func(after time.Duration) {
+		t.Helper()
+		if err := alerts.Escalate(ctx, now.Add(after)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	escalate(50 * time.Minute)
+	if len(mail.sent) != 0 {
+		t.Fatalf("escalated before after_hours: %+v", mail.sent)
+	}
+	escalate(time.Hour)
+	if len(mail.sent) != 1 || mail.sent[0].To != aliceEmail || !strings.Contains(mail.sent[0].Body, alert.ID.String()) {
+		t.Fatalf("expected escalation mail to alice, got %+v", mail.sent)
+	}
+	// Второй шаг считается от первого, а не от уведомления
+	published := len(publisher.events)
+	escalate(2 * time.Hour)
+	if len(publisher.events) != published {
+		t.Fatalf("second step escalated early: %+v", publisher.events[published:])
+	}
+	escalate(3 * time.Hour)
+	if len(publisher.events) != published+1 || publisher.events[published].Type != "alert.escalated" {
+		t.Fatalf("expected alert.escalated, got %+v", publisher.events[published:])
+	}
+	if escalation := publisher.events[published].Data.(domain.AlertEscalation); escalation.RecipientID != bob.ID || escalation.Step != 2 {
+		t.Errorf("unexpected escalation %+v", escalation)
+	}
+
+	got, err := alerts.Get(ctx, alert.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Step != 2 || got.NextEscalationAt != nil || got.Status != domain.AlertOpen {
+		t.Errorf("expected exhausted open alert, got %+v", got)
+	}
+	if !got.Notifies(bob.ID) || got.Notifies(uuid.New()) {
+		t.Errorf("unexpected alert recipients %+v", got.Steps)
+	}
+
+	acked, err := alerts.Acknowledge(ctx, alert.ID, &bob.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if acked.Status != domain.AlertAcknowledged || acked.AcknowledgedBy == nil || *acked.AcknowledgedBy != bob.ID {
+		t.Errorf("unexpected acknowledged alert %+v", acked)
+	}
+	// Повторное подтверждение не меняет автора
+	if again, err := alerts.Acknowledge(ctx, alert.ID, &alice.ID); err != nil || *again.AcknowledgedBy != bob.ID {
+		t.Errorf("repeated acknowledge: %+v, %v", again, err)
+	}
+}
+
+func TestAcknowledgeStopsEscalation(t *testing.T) {
+	ctx := context.Background()
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	subs := memory.NewSubscriptionRepository()
+	users := memory.NewUserRepository(subs)
+	settings := memory.NewNotificationSettingsRepository()
+	publisher := &recordingPublisher{}
+
+	owner := &domain.User{ID: uuid.New()}
+	if err := users.Create(ctx, owner); err != nil {
+		t.Fatal(err)
+	}
+	if err := settings.Upsert(ctx, &domain.NotificationSettings{UserID: owner.ID,
+		Escalation: []domain.EscalationStep{{AfterHours: 1, Channel: domain.AlertWebhook}}}); err != nil {
+		t.Fatal(err)
+	}
+	alerts := NewAlertService(memory.NewAlertRepository(), settings, users,
+		NewNotificationService(subs, settings, publisher, &recordingMailer{}, 20, logger), logger)
+
+	now := time.Date(2025, 10, 23, 12, 0, 0, 0, time.UTC)
+	event := budgetAlertEvent(domain.BudgetEnvelope{
+		Budget:      &domain.Budget{ID: uuid.New(), UserID: owner.ID, Category: "streaming"},
+		BudgetUsage: domain.BudgetUsage{State: domain.BudgetExceeded},
+	}, "10-2025", now)
+	if err := alerts.Raise(ctx, owner.ID, event); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := alerts.Acknowledge(ctx, event.ID, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := alerts.Escalate(ctx, now.Add(2*time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if len(publisher.events) != 0 {
+		t.Errorf("acknowledged alert escalated: %+v", publisher.events)
+	}
+}
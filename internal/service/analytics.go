@@ -0,0 +1,58 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"aggregator_db/pkg/domain"
+)
+
+// YearOverYear сравнивает траты по месяцам года req.Year с тем же месяцем предыдущего года.
+// Месяцы и итог округляются из долей независимо по правилам тенанта, как в CalculateTotal.
+func (s *SubscriptionService) YearOverYear(ctx context.Context, req domain.YearOverYearRequest) (*domain.YearOverYearResponse, error) {
+	if req.Currency == "" {
+		req.Currency = domain.DefaultCurrency
+	}
+	if !req.Currency.Valid() {
+		return nil, fmt.Errorf("%w: unsupported currency %q", ErrValidation, req.Currency)
+	}
+	keys, err := s.serviceKeys(ctx, req.ServiceName)
+	if err != nil {
+		return nil, err
+	}
+	req.ServiceKeys = keys
+	if req.Tags, err = normalizeTags(req.Tags); err != nil {
+		return nil, err
+	}
+
+	units, err := s.repo.YearOverYear(ctx, req)
+	if err != nil {
+		s.logger.ErrorContext(ctx, "failed to compare years",
+			slog.Int("year", req.Year),
+			slog.String("error", err.Error()),
+		)
+		return nil, err
+	}
+
+	format := moneyFormat(ctx)
+	money := func(units int64) domain.Money {
+		return format.Apply(domain.NewMoney(domain.RoundProratedWith(units, format.Rounding), req.Currency))
+	}
+	resp := &domain.YearOverYearResponse{
+		Year:         req.Year,
+		PreviousYear: req.Year - 1,
+		Months:       make([]domain.YearOverYearMonth, 0, len(units)),
+	}
+	var current, previous int64
+	for _, month := range units {
+		resp.Months = append(resp.Months, domain.YearOverYearMonth{
+			Month:           month.Month,
+			SpendComparison: domain.CompareSpend(money(month.Current), money(month.Previous)),
+		})
+		current += month.Current
+		previous += month.Previous
+	}
+	resp.Total = domain.CompareSpend(money(current), money(previous))
+	return resp, nil
+}
@@ -0,0 +1,70 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+
+	"aggregator_db/internal/clock"
+	"aggregator_db/internal/repository/postgres"
+	"aggregator_db/pkg/domain"
+	"github.com/google/uuid"
+)
+
+// APIKeyService выпускает и проверяет ключи внутренних сервисов.
+type APIKeyService struct {
+	repo   postgres.APIKeyRepository
+	logger *slog.Logger
+}
+
+func NewAPIKeyService(repo postgres.APIKeyRepository, logger *slog.Logger) *APIKeyService {
+	return &APIKeyService{repo: repo, logger: logger}
+}
+
+// Create выпускает ключ; сам ключ возвращается только здесь.
+func (s *APIKeyService) Create(ctx context.Context, req domain.CreateAPIKeyRequest) (*domain.APIKeyCredentials, error) {
+	secret, err := domain.NewServiceAPIKey()
+	if err != nil {
+		return nil, err
+	}
+
+	key := &domain.APIKey{
+		ID:         uuid.New(),
+		Name:       req.Name,
+		Scope:      req.Scope,
+		SecretHash: domain.HashAPIKey(secret),
+		CreatedAt:  clock.Now(ctx),
+	}
+	if err := s.repo.Create(ctx, key); err != nil {
+		s.logger.ErrorContext(ctx, "failed to create api key",
+			slog.String("error", err.Error()),
+		)
+		return nil, err
+	}
+
+	s.logger.InfoContext(ctx, "api key created",
+		slog.String("key_id", key.ID.String()),
+		slog.String("name", key.Name),
+		slog.String("scope", string(key.Scope)),
+	)
+
+	return &domain.APIKeyCredentials{Key: key, APIKey: secret}, nil
+}
+
+// Authenticate ищет действующий ключ по значению из X-API-Key.
+func (s *APIKeyService) Authenticate(ctx context.Context, secret string) (*domain.APIKey, error) {
+	return s.repo.GetBySecretHash(ctx, domain.HashAPIKey(secret))
+}
+
+func (s *APIKeyService) List(ctx context.Context) ([]*domain.APIKey, error) {
+	return s.repo.List(ctx)
+}
+
+// Revoke отзывает ключ; запросы с ним сразу получают 401.
+func (s *APIKeyService) Revoke(ctx context.Context, id uuid.UUID) error {
+	if err := s.repo.Revoke(ctx, id, clock.Now(ctx)); err != nil {
+		return err
+	}
+
+	s.logger.InfoContext(ctx, "api key revoked", slog.String("key_id", id.String()))
+	return nil
+}
@@ -0,0 +1,53 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"aggregator_db/internal/repository/postgres"
+	"aggregator_db/pkg/domain"
+	"github.com/google/uuid"
+)
+
+// AuditService читает журнал изменений данных.
+type AuditService struct {
+	repo postgres.AuditRepository
+}
+
+func NewAuditService(repo postgres.AuditRepository) *AuditService {
+	return &AuditService{repo: repo}
+}
+
+// List возвращает записи журнала под фильтры, новые сверху.
+func (s *AuditService) List(ctx context.Context, query domain.ListAuditQuery) ([]*domain.AuditEntry, error) {
+	if query.EntityID != "" {
+		id, err := uuid.Parse(query.EntityID)
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid entity_id", ErrValidation)
+		}
+		query.EntityUUID = &id
+	}
+	var err error
+	if query.Since, err = parseAuditTime("from", query.From); err != nil {
+		return nil, err
+	}
+	if query.Until, err = parseAuditTime("to", query.To); err != nil {
+		return nil, err
+	}
+	if query.Since != nil && query.Until != nil && !query.Until.After(*query.Since) {
+		return nil, fmt.Errorf("%w: to must be after from", ErrValidation)
+	}
+	return s.repo.List(ctx, query)
+}
+
+func parseAuditTime(name, value string) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+	parsed, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s: expected RFC 3339 time", ErrValidation, name)
+	}
+	return &parsed, nil
+}
@@ -0,0 +1,134 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"aggregator_db/internal/repository/postgres"
+	"aggregator_db/internal/tenancy"
+	"aggregator_db/pkg/domain"
+	"aggregator_db/pkg/events"
+	"aggregator_db/pkg/region"
+	"github.com/google/uuid"
+)
+
+// BIExportService отправляет во внешние BI-системы ежедневные сводки по основной
+// базе и каждому тенанту, чтобы дашборды не опрашивали API аналитики.
+type BIExportService struct {
+	subs postgres.SubscriptionRepository
+	// tenants - реестр тенантов; без него сводка строится только по основной базе
+	tenants postgres.TenantRepository
+	// endpoints - по публикатору на адрес; недоступный адрес не мешает остальным
+	endpoints []events.Publisher
+	logger    *slog.Logger
+}
+
+func NewBIExportService(subs postgres.SubscriptionRepository, tenants postgres.TenantRepository, endpoints []events.Publisher, logger *slog.Logger) *BIExportService {
+	return &BIExportService{
+		subs:      subs,
+		tenants:   tenants,
+		endpoints: endpoints,
+		logger:    logger,
+	}
+}
+
+// Push - задача планировщика. Строит сводку bi.daily_totals за текущий день (UTC)
+// для основной базы и активных тенантов и отправляет ее на все адреса. У всех адресов
+// одно событие на тенанта и запуск, а сводку за день получатель определяет по tenant_id
+// и date: повторный запуск в тот же день присылает обновленные итоги.
+func (s *BIExportService) Push(ctx context.Context, now time.Time) error {
+	now = now.UTC()
+
+	scopes := []*domain.Tenant{nil}
+	if s.tenants != nil {
+		tenants, err := s.tenants.List(ctx)
+		if err != nil {
+			return err
+		}
+		for _, tenant := range tenants {
+			if tenant.Status == domain.TenantStatusActive {
+				scopes = append(scopes, tenant)
+			}
+		}
+	}
+
+	sent, failed := 0, 0
+	for _, tenant := range scopes {
+		scopeCtx, scopeName := ctx, domain.DefaultTenantScope
+		if tenant != nil {
+			scopeCtx, scopeName = tenancy.WithTenant(ctx, tenant), tenant.ID
+		}
+
+		totals, err := s.dailyTotals(scopeCtx, scopeName, now)
+		if err != nil {
+			// Недоступная база одного тенанта не должна останавливать выгрузку остальных
+			s.logger.WarnContext(ctx, "failed to build bi daily totals",
+				slog.String("tenant_id", scopeName),
+				slog.String("error", err.Error()),
+			)
+			failed++
+			continue
+		}
+
+		event := events.Event{
+			ID:         uuid.NewSHA1(uuid.NameSpaceURL, []byte("bi.daily_totals:"+scopeName+":"+now.Format(time.RFC3339Nano))),
+			Type:       "bi.daily_totals",
+			OccurredAt: now,
+			Region:     region.Current(),
+			Data:       totals,
+		}
+		for i, endpoint := range s.endpoints {
+			if err := endpoint.Publish(ctx, event); err != nil {
+				s.logger.WarnContext(ctx, "failed to push bi daily totals",
+					slog.String("tenant_id", scopeName),
+					slog.Int("endpoint", i),
+					slog.String("error", err.Error()),
+				)
+				failed++
+				continue
+			}
+			sent++
+		}
+	}
+
+	s.logger.InfoContext(ctx, "bi export finished",
+		slog.Int("scopes", len(scopes)),
+		slog.Int("sent", sent),
+		slog.Int("failed", failed),
+	)
+	return nil
+}
+
+func (s *BIExportService) dailyTotals(ctx context.Context, tenantID string, now time.Time) (*domain.DailyTotals, error) {
+	totals := &domain.DailyTotals{
+		TenantID: tenantID,
+		Date:     now.Format(time.DateOnly),
+		ByStatus: make(map[domain.SubscriptionStatus]int),
+		Month:    domain.FormatPeriod(now),
+	}
+
+	for _, status := range []domain.SubscriptionStatus{domain.StatusActive, domain.StatusPaused, domain.StatusCancelled, domain.StatusExpired} {
+		count, err := s.subs.Count(ctx, domain.ListSubscriptionsQuery{Status: &status})
+		if err != nil {
+			return nil, err
+		}
+		totals.ByStatus[status] = count
+		totals.Subscriptions += count
+	}
+
+	// Траты месяца считаются как в расчете стоимости по всем валютам сразу,
+	// без месяцев на паузе и после отмены
+	spend, err := s.subs.CalculateTotal(ctx, domain.CalculateTotalRequest{
+		StartPeriod:     totals.Month,
+		EndPeriod:       totals.Month,
+		ExcludeInactive: true,
+		Rounding:        moneyFormat(ctx).Rounding,
+		OpenEndedUntil:  openEndedPolicy(ctx).TotalUntil(now),
+	})
+	if err != nil {
+		return nil, err
+	}
+	totals.MonthlySpend = domain.NewMonthlySpend(spend)
+	return totals, nil
+}
@@ -0,0 +1,89 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"aggregator_db/internal/repository/memory"
+	"aggregator_db/pkg/domain"
+	"aggregator_db/pkg/events"
+	"github.com/google/uuid"
+)
+
+type failingPublisher struct{}
+
+func (failingPublisher) Publish(context.Context, events.Event) error {
+	return errors.New("endpoint unavailable")
+}
+
+func TestBIExportPush(t *testing.T) {
+	ctx := context.Background()
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	subs := memory.NewSubscriptionRepository()
+	now := time.Date(2025, 7, 14, 6, 0, 0, 0, time.UTC)
+
+	cancelled := &domain.Subscription{ID: uuid.New(), UserID: uuid.New(), ServiceName: "Yandex Plus", Price: domain.NewMoney(40000, domain.DefaultCurrency), StartDate: "01-2025"}
+	for _, sub := range []*domain.Subscription{
+		{ID: uuid.New(), UserID: uuid.New(), ServiceName: "Netflix", Price: domain.NewMoney(79900, domain.DefaultCurrency), StartDate: "01-2025"},
+		{ID: uuid.New(), UserID: uuid.New(), ServiceName: "Spotify", Price: domain.NewMoney(999, "USD"), StartDate: "03-2025"},
+		cancelled,
+	} {
+		if err := subs.Create(ctx, sub); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := subs.ChangeStatus(ctx, &domain.StatusChange{SubscriptionID: cancelled.ID, Status: domain.StatusCancelled, EffectiveFrom: "06-2025", ChangedAt: now}); err != nil {
+		t.Fatal(err)
+	}
+
+	tenants := memory.NewTenantRepository()
+	for _, tenant := range []*domain.Tenant{
+		{ID: "acme", Isolation: domain.TenantIsolationSchema, SchemaName: "tenant_acme", Status: domain.TenantStatusActive},
+		{ID: "globex", Isolation: domain.TenantIsolationSchema, SchemaName: "tenant_globex", Status: domain.TenantStatusSuspended},
+	} {
+		if err := tenants.Create(ctx, tenant); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Недоступный адрес не мешает доставке на остальные
+	publisher := &recordingPublisher{}
+	svc := NewBIExportService(subs, tenants, []events.Publisher{failingPublisher{}, publisher}, logger)
+	if err := svc.Push(ctx, now); err != nil {
+		t.Fatal(err)
+	}
+	if len(publisher.events) != 2 {
+		t.Fatalf("got %d events, want default and acme", len(publisher.events))
+	}
+
+	first := publisher.events[0]
+	totals, ok := first.Data.(*domain.DailyTotals)
+	if !ok || first.Type != "bi.daily_totals" {
+		t.Fatalf("event = %+v", first)
+	}
+	if totals.TenantID != domain.DefaultTenantScope || totals.Date != "2025-07-14" || totals.Month != "07-2025" {
+		t.Errorf("totals = %+v", totals)
+	}
+	if totals.Subscriptions != 3 || totals.ByStatus[domain.StatusActive] != 2 || totals.ByStatus[domain.StatusCancelled] != 1 {
+		t.Errorf("counts = %d %v", totals.Subscriptions, totals.ByStatus)
+	}
+	// Отмененная с июня подписка в траты июля не входит
+	if totals.MonthlySpend[domain.DefaultCurrency] != "799.00" || totals.MonthlySpend["USD"] != "9.99" {
+		t.Errorf("monthly spend = %v", totals.MonthlySpend)
+	}
+	if tenant := publisher.events[1].Data.(*domain.DailyTotals).TenantID; tenant != "acme" {
+		t.Errorf("second scope = %s, want acme", tenant)
+	}
+
+	// Следующий запуск в тот же день присылает сводку за тот же день новым событием
+	if err := svc.Push(ctx, now.Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if next := publisher.events[2]; next.ID == first.ID || next.Data.(*domain.DailyTotals).Date != totals.Date {
+		t.Errorf("next run in the same day: %+v", next)
+	}
+}
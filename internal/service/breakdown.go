@@ -0,0 +1,139 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"aggregator_db/internal/clock"
+	"aggregator_db/pkg/domain"
+)
+
+// breakdownChunkMonths - по сколько месяцев разбивка считается за один проход:
+// история подписок загружается на каждый кусок, а не на весь период сразу.
+const breakdownChunkMonths = 12
+
+// CalculateBreakdown возвращает одну страницу помесячной разбивки стоимости.
+func (s *SubscriptionService) CalculateBreakdown(ctx context.Context, req domain.CalculateBreakdownRequest) (*domain.CalculateBreakdownResponse, error) {
+	resp := &domain.CalculateBreakdownResponse{Months: make([]domain.MonthBreakdown, 0, req.Limit)}
+	next, err := s.StreamBreakdown(ctx, req, func(month domain.MonthBreakdown) error {
+		resp.Months = append(resp.Months, month)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	resp.NextContinuation = next
+	return resp, nil
+}
+
+// StreamBreakdown считает до req.Limit месяцев разбивки, начиная с начала периода
+// или с месяца из токена продолжения, и передает их emit по одному по мере расчета.
+// Возвращает токен следующей страницы (пустой, если период закончился). При ошибке
+// токен указывает на первый месяц, не переданный в emit, чтобы клиент мог продолжить.
+func (s *SubscriptionService) StreamBreakdown(ctx context.Context, req domain.CalculateBreakdownRequest, emit func(domain.MonthBreakdown) error) (string, error) {
+	start, end, err := s.prepareTotalRequest(ctx, &req.CalculateTotalRequest)
+	if err != nil {
+		return "", err
+	}
+	if req.Limit <= 0 {
+		req.Limit = 12
+	}
+
+	// Горизонт не входит в отпечаток: токен продолжения переживает смену месяца
+	req.OpenEndedUntil = openEndedPolicy(ctx).ForecastUntil(clock.Now(ctx))
+	fingerprint := req.Fingerprint()
+	from := start
+	if req.Continuation != "" {
+		if from, err = domain.DecodeContinuation(req.Continuation, fingerprint); err != nil {
+			return "", fmt.Errorf("%w: %w", ErrValidation, err)
+		}
+		if from.Before(start) || from.After(end) {
+			return "", fmt.Errorf("%w: %w", ErrValidation, domain.ErrInvalidContinuation)
+		}
+	}
+	to := from.AddDate(0, req.Limit-1, 0)
+	if to.After(end) {
+		to = end
+	}
+
+	settle := func(totals domain.Totals) (domain.Money, error) {
+		return totals.Get(req.Currency), nil
+	}
+	if req.TargetCurrency != "" {
+		rates, err := s.rates.Rates(ctx)
+		if err != nil {
+			s.logger.ErrorContext(ctx, "failed to load exchange rates",
+				slog.String("error", err.Error()),
+			)
+			return "", err
+		}
+		settle = func(totals domain.Totals) (domain.Money, error) {
+			return rates.ConvertTotals(totals, req.TargetCurrency)
+		}
+	}
+	format := moneyFormat(ctx)
+	settle = formatSettle(format, settle)
+
+	items := 0
+	for chunkStart := from; !chunkStart.After(to); chunkStart = chunkStart.AddDate(0, breakdownChunkMonths, 0) {
+		chunkEnd := chunkStart.AddDate(0, breakdownChunkMonths-1, 0)
+		if chunkEnd.After(to) {
+			chunkEnd = to
+		}
+
+		months, err := s.billedMonths(ctx, req.CalculateTotalRequest, chunkStart, chunkEnd)
+		if err != nil {
+			return domain.EncodeContinuation(chunkStart, fingerprint), err
+		}
+		byMonth := make(map[string][]domain.BilledMonth)
+		for _, month := range months {
+			byMonth[month.Month] = append(byMonth[month.Month], month)
+		}
+
+		for month := chunkStart; !month.After(chunkEnd); month = month.AddDate(0, 1, 0) {
+			billed := byMonth[domain.FormatPeriod(month)]
+			bucket, err := monthBreakdown(month, billed, req.Rounding, settle)
+			if err == nil && req.IncludeItems {
+				// Лимит позиций - на всю страницу: уменьшив limit, клиент продолжит по токену
+				if items += len(billed); items > domain.MaxTotalItems {
+					err = fmt.Errorf("%w: include_items: billed months of the page exceed the limit of %d, lower limit",
+						ErrValidation, domain.MaxTotalItems)
+				} else {
+					bucket.Items = domain.TotalItems(billed, format)
+				}
+			}
+			if err == nil {
+				err = emit(bucket)
+			}
+			if err != nil {
+				return domain.EncodeContinuation(month, fingerprint), err
+			}
+		}
+	}
+
+	if to.Before(end) {
+		return domain.EncodeContinuation(to.AddDate(0, 1, 0), fingerprint), nil
+	}
+	return "", nil
+}
+
+func monthBreakdown(month time.Time, months []domain.BilledMonth, mode domain.RoundingMode, settle func(domain.Totals) (domain.Money, error)) (domain.MonthBreakdown, error) {
+	byClass, total := classTotals(months, mode)
+
+	bucket := domain.MonthBreakdown{
+		Month:            domain.FormatPeriod(month),
+		ByClassification: make(map[domain.BillingClass]domain.Money, len(byClass)),
+	}
+	var err error
+	if bucket.TotalCost, err = settle(total); err != nil {
+		return domain.MonthBreakdown{}, err
+	}
+	for class, classTotals := range byClass {
+		if bucket.ByClassification[class], err = settle(classTotals); err != nil {
+			return domain.MonthBreakdown{}, err
+		}
+	}
+	return bucket, nil
+}
@@ -0,0 +1,314 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"aggregator_db/internal/clock"
+	"aggregator_db/internal/repository/postgres"
+	"aggregator_db/pkg/domain"
+	"aggregator_db/pkg/events"
+	"aggregator_db/pkg/region"
+	"github.com/google/uuid"
+)
+
+type BudgetService struct {
+	repo          postgres.BudgetRepository
+	users         postgres.UserRepository
+	subscriptions *SubscriptionService
+	publisher     events.Publisher
+	// alerts заводит уведомления с эскалацией для budget.exceeded; nil - без эскалации
+	alerts *AlertService
+	logger *slog.Logger
+}
+
+func NewBudgetService(repo postgres.BudgetRepository, users postgres.UserRepository, subscriptions *SubscriptionService, publisher events.Publisher, alerts *AlertService, logger *slog.Logger) *BudgetService {
+	return &BudgetService{
+		repo:          repo,
+		users:         users,
+		subscriptions: subscriptions,
+		publisher:     publisher,
+		alerts:        alerts,
+		logger:        logger,
+	}
+}
+
+// normalizeCategory приводит категорию к виду метки подписки.
+func normalizeCategory(category string) (string, error) {
+	tags, err := domain.NormalizeTags([]string{category})
+	if err != nil {
+		return "", fmt.Errorf("%w: category: %w", ErrValidation, err)
+	}
+	return tags[0], nil
+}
+
+func validateBudgetLimit(limit domain.Money) error {
+	if !limit.Currency.Valid() {
+		return fmt.Errorf("%w: unsupported limit currency %q", ErrValidation, limit.Currency)
+	}
+	if limit.Amount <= 0 {
+		return fmt.Errorf("%w: limit must be positive", ErrValidation)
+	}
+	return nil
+}
+
+func (s *BudgetService) Create(ctx context.Context, req domain.CreateBudgetRequest) (*domain.Budget, error) {
+	category, err := normalizeCategory(req.Category)
+	if err != nil {
+		return nil, err
+	}
+	if err := validateBudgetLimit(req.Limit); err != nil {
+		return nil, err
+	}
+	if _, err := s.users.GetByID(ctx, req.UserID); err != nil {
+		return nil, err
+	}
+
+	now := clock.Now(ctx)
+	budget := &domain.Budget{
+		ID:           uuid.New(),
+		UserID:       req.UserID,
+		Category:     category,
+		Limit:        req.Limit,
+		AlertPercent: domain.DefaultBudgetAlertPercent,
+		CreatedAt:    now,
+		UpdatedAt:    now,
+	}
+	if req.AlertPercent != nil {
+		budget.AlertPercent = *req.AlertPercent
+	}
+
+	if err := s.repo.Create(ctx, budget); err != nil {
+		s.logger.ErrorContext(ctx, "failed to create budget",
+			slog.String("user_id", budget.UserID.String()),
+			slog.String("error", err.Error()),
+		)
+		return nil, err
+	}
+
+	s.logger.InfoContext(ctx, "budget created",
+		slog.String("id", budget.ID.String()),
+		slog.String("category", budget.Category),
+	)
+	return budget, nil
+}
+
+func (s *BudgetService) Get(ctx context.Context, id uuid.UUID) (*domain.Budget, error) {
+	return s.repo.GetByID(ctx, id)
+}
+
+func (s *BudgetService) List(ctx context.Context, userID uuid.UUID) ([]*domain.Budget, error) {
+	if _, err := s.users.GetByID(ctx, userID); err != nil {
+		return nil, err
+	}
+	return s.repo.ListByUser(ctx, userID)
+}
+
+func (s *BudgetService) Update(ctx context.Context, id uuid.UUID, req domain.UpdateBudgetRequest) (*domain.Budget, error) {
+	budget, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Category != nil {
+		if budget.Category, err = normalizeCategory(*req.Category); err != nil {
+			return nil, err
+		}
+	}
+	if req.Limit != nil {
+		if err := validateBudgetLimit(*req.Limit); err != nil {
+			return nil, err
+		}
+		budget.Limit = *req.Limit
+	}
+	if req.AlertPercent != nil {
+		budget.AlertPercent = *req.AlertPercent
+	}
+	budget.UpdatedAt = clock.Now(ctx)
+
+	if err := s.repo.Update(ctx, budget); err != nil {
+		s.logger.ErrorContext(ctx, "failed to update budget",
+			slog.String("id", id.String()),
+			slog.String("error", err.Error()),
+		)
+		return nil, err
+	}
+	return budget, nil
+}
+
+func (s *BudgetService) Delete(ctx context.Context, id uuid.UUID) error {
+	if err := s.repo.Delete(ctx, id); err != nil {
+		return err
+	}
+
+	s.logger.InfoContext(ctx, "budget deleted", slog.String("id", id.String()))
+	return nil
+}
+
+// Status считает траты каждого конверта пользователя за месяц month (по умолчанию текущий).
+func (s *BudgetService) Status(ctx context.Context, userID uuid.UUID, query domain.BudgetStatusQuery) (*domain.BudgetStatusResponse, error) {
+	month := domain.FormatPeriod(clock.Now(ctx))
+	if query.Month != "" {
+		parsed, err := domain.ParsePeriod(query.Month)
+		if err != nil {
+			return nil, fmt.Errorf("%w: month: %w", ErrValidation, err)
+		}
+		month = domain.FormatPeriod(parsed)
+	}
+
+	budgets, err := s.List(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	envelopes, err := s.envelopes(ctx, budgets, month)
+	if err != nil {
+		return nil, err
+	}
+
+	return &domain.BudgetStatusResponse{
+		UserID:    userID,
+		Month:     month,
+		Envelopes: envelopes,
+		Totals:    domain.SummarizeBudgets(envelopes),
+	}, nil
+}
+
+// envelopes считает траты конвертов за месяц так же, как расчет стоимости: с учетом
+// скидок и без месяцев на паузе и после отмены.
+func (s *BudgetService) envelopes(ctx context.Context, budgets []*domain.Budget, month string) ([]domain.BudgetEnvelope, error) {
+	format := moneyFormat(ctx)
+	envelopes := make([]domain.BudgetEnvelope, 0, len(budgets))
+	for _, budget := range budgets {
+		total, err := s.subscriptions.CalculateTotal(ctx, domain.CalculateTotalRequest{
+			UserID:          &budget.UserID,
+			StartPeriod:     month,
+			EndPeriod:       month,
+			Currency:        budget.Limit.Currency,
+			Tags:            []string{budget.Category},
+			ExcludeInactive: true,
+		})
+		if err != nil {
+			return nil, err
+		}
+		envelopes = append(envelopes, domain.BudgetEnvelope{
+			Budget:      budget,
+			BudgetUsage: domain.NewBudgetUsage(format.Apply(budget.Limit), total.TotalCost, budget.AlertPercent),
+		})
+	}
+	return envelopes, nil
+}
+
+// CheckBudgets - задача планировщика. Считает траты всех конвертов за текущий месяц
+// и публикует budget.warning или budget.exceeded для конвертов не в состоянии ok.
+// ID события зависит от конверта, месяца и состояния, поэтому повторные запуски
+// дают дубли с тем же Idempotency-Key, а переход warning -> exceeded - новое событие.
+// budget.exceeded ждет подтверждения, если у владельца настроена эскалация.
+func (s *BudgetService) CheckBudgets(ctx context.Context, now time.Time) error {
+	month := domain.FormatPeriod(now.UTC())
+
+	budgets, err := s.repo.ListAll(ctx)
+	if err != nil {
+		return err
+	}
+
+	published := 0
+	for _, budget := range budgets {
+		envelopes, err := s.envelopes(ctx, []*domain.Budget{budget}, month)
+		if err != nil {
+			// Ошибка по одному конверту не должна останавливать проверку остальных
+			s.logger.WarnContext(ctx, "failed to check budget",
+				slog.String("id", budget.ID.String()),
+				slog.String("error", err.Error()),
+			)
+			continue
+		}
+		envelope := envelopes[0]
+		if envelope.State == domain.BudgetOK {
+			continue
+		}
+
+		event := budgetAlertEvent(envelope, month, now)
+		if err := s.publisher.Publish(ctx, event); err != nil {
+			s.logger.WarnContext(ctx, "failed to publish budget alert",
+				slog.String("id", budget.ID.String()),
+				slog.String("error", err.Error()),
+			)
+			continue
+		}
+		published++
+
+		if s.alerts != nil && domain.IsCriticalAlert(event.Type) {
+			if err := s.alerts.Raise(ctx, budget.UserID, event); err != nil {
+				s.logger.WarnContext(ctx, "failed to raise budget alert",
+					slog.String("id", budget.ID.String()),
+					slog.String("error", err.Error()),
+				)
+			}
+		}
+	}
+
+	s.logger.InfoContext(ctx, "budget check finished",
+		slog.Int("budgets", len(budgets)),
+		slog.Int("published", published),
+	)
+	return nil
+}
+
+// PreviewAlerts повторяет CheckBudgets для конвертов одного пользователя, ничего
+// не публикуя. Отправленными считаются только события в состоянии state.
+func (s *BudgetService) PreviewAlerts(ctx context.Context, userID uuid.UUID, state domain.BudgetState, now time.Time) (*domain.NotificationPreview, error) {
+	month := domain.FormatPeriod(now.UTC())
+
+	budgets, err := s.List(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	envelopes, err := s.envelopes(ctx, budgets, month)
+	if err != nil {
+		return nil, err
+	}
+
+	preview := &domain.NotificationPreview{
+		Type:          "budget." + string(state),
+		UserID:        userID,
+		At:            now.UTC(),
+		Notifications: make([]domain.PreviewedNotification, 0, len(envelopes)),
+		Reasons:       make([]string, 0),
+	}
+	if len(envelopes) == 0 {
+		preview.Reasons = append(preview.Reasons, "user has no budgets")
+	}
+	for _, envelope := range envelopes {
+		notification := domain.PreviewedNotification{Event: budgetAlertEvent(envelope, month, now), Send: envelope.State == state}
+		if !notification.Send {
+			notification.Reason = fmt.Sprintf("budget %q is %s in %s, not %s",
+				envelope.Budget.Category, envelope.State, month, state)
+		}
+		preview.Notifications = append(preview.Notifications, notification)
+	}
+	preview.Summarize()
+	return preview, nil
+}
+
+func budgetAlertEvent(envelope domain.BudgetEnvelope, month string, now time.Time) events.Event {
+	key := fmt.Sprintf("budget:%s:%s:%s", envelope.Budget.ID, month, envelope.State)
+	return events.Event{
+		ID:         uuid.NewSHA1(uuid.NameSpaceURL, []byte(key)),
+		Type:       "budget." + string(envelope.State),
+		OccurredAt: now,
+		Region:     region.Current(),
+		Data: domain.BudgetAlert{
+			BudgetID:           envelope.Budget.ID,
+			UserID:             envelope.Budget.UserID,
+			Category:           envelope.Budget.Category,
+			Month:              month,
+			Limit:              envelope.Limit,
+			Spent:              envelope.Spent,
+			UtilizationPercent: envelope.UtilizationPercent,
+			AlertPercent:       envelope.Budget.AlertPercent,
+			State:              envelope.State,
+		},
+	}
+}
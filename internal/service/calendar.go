@@ -0,0 +1,49 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"aggregator_db/pkg/domain"
+	"github.com/google/uuid"
+)
+
+// BillingCalendar возвращает ожидаемые списания пользователя по дням месяца.
+func (s *SubscriptionService) BillingCalendar(ctx context.Context, userID uuid.UUID, month string) (*domain.BillingCalendarResponse, error) {
+	monthStart, err := domain.ParsePeriod(month)
+	if err != nil {
+		return nil, fmt.Errorf("%w: month: %w", ErrValidation, err)
+	}
+
+	subs, err := s.repo.ListHistory(ctx, domain.CalculateTotalRequest{
+		UserID:    &userID,
+		EndPeriod: month,
+	})
+	if err != nil {
+		s.logger.ErrorContext(ctx, "failed to load subscriptions for calendar",
+			slog.String("user_id", userID.String()),
+			slog.String("error", err.Error()),
+		)
+		return nil, err
+	}
+
+	// Подписки на паузе или отмененные к этому месяцу списаний не дают
+	bySubscription, err := statusChangesBySubscription(ctx, s.repo, subs)
+	if err != nil {
+		return nil, err
+	}
+
+	billable := make([]*domain.Subscription, 0, len(subs))
+	for _, sub := range subs {
+		status, err := domain.StatusAt(bySubscription[sub.ID], monthStart)
+		if err != nil {
+			return nil, err
+		}
+		if status.Billable() {
+			billable = append(billable, sub)
+		}
+	}
+
+	return domain.BuildBillingCalendar(userID, billable, monthStart)
+}
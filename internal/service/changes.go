@@ -0,0 +1,73 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"aggregator_db/internal/changefeed"
+	"aggregator_db/internal/repository/postgres"
+	"aggregator_db/pkg/domain"
+)
+
+// ChangesService отдает ленту изменений подписок с долгим ожиданием: запрос
+// без изменений ждет их до wait вместо частого опроса списка клиентом.
+type ChangesService struct {
+	repo         postgres.AuditRepository
+	bus          *changefeed.Bus
+	maxWait      time.Duration
+	pollInterval time.Duration
+}
+
+func NewChangesService(repo postgres.AuditRepository, bus *changefeed.Bus, maxWait, pollInterval time.Duration) *ChangesService {
+	return &ChangesService{repo: repo, bus: bus, maxWait: maxWait, pollInterval: pollInterval}
+}
+
+// Wait возвращает изменения после курсора since, а если их нет - ждет до wait,
+// пока они появятся. По истечении wait ответ пустой, но с новым курсором.
+func (s *ChangesService) Wait(ctx context.Context, query domain.SubscriptionChangesQuery) (*domain.SubscriptionChanges, error) {
+	if query.Since != "" {
+		cursor, err := domain.DecodeChangeCursor(query.Since)
+		if err != nil {
+			return nil, err
+		}
+		query.After = &cursor
+	}
+	if query.Wait != "" {
+		wait, err := time.ParseDuration(query.Wait)
+		if err != nil || wait < 0 {
+			return nil, fmt.Errorf("%w: wait: expected a non-negative duration like 30s", ErrValidation)
+		}
+		query.WaitFor = min(wait, s.maxWait)
+	}
+
+	timeout := time.NewTimer(query.WaitFor)
+	defer timeout.Stop()
+	poll := time.NewTicker(s.pollInterval)
+	defer poll.Stop()
+	for {
+		// Канал берется до чтения журнала: коммит между чтением и ожиданием его закроет
+		changed := s.bus.Changed()
+		changes, cursor, err := s.repo.ListSubscriptionChanges(ctx, query)
+		if err != nil {
+			return nil, err
+		}
+		query.After = &cursor
+		if len(changes) > 0 || query.WaitFor == 0 {
+			return &domain.SubscriptionChanges{
+				Changes: changes,
+				Cursor:  cursor.Encode(),
+				HasMore: len(changes) == query.Limit,
+			}, nil
+		}
+
+		select {
+		case <-changed:
+		case <-poll.C:
+		case <-timeout.C:
+			return &domain.SubscriptionChanges{Changes: changes, Cursor: cursor.Encode()}, nil
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
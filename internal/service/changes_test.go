@@ -0,0 +1,111 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"aggregator_db/internal/changefeed"
+	"aggregator_db/pkg/domain"
+	"github.com/google/uuid"
+)
+
+// changeLog - журнал изменений подписок в памяти: изменение с номером n получает
+// курсор {TxID: n}.
+type changeLog struct {
+	mu      sync.Mutex
+	changes []domain.SubscriptionChange
+}
+
+func (l *changeLog) add(change domain.SubscriptionChange) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.changes = append(l.changes, change)
+}
+
+func (l *changeLog) List(context.Context, domain.ListAuditQuery) ([]*domain.AuditEntry, error) {
+	return nil, nil
+}
+
+func (l *changeLog) ListSubscriptionChanges(_ context.Context, query domain.SubscriptionChangesQuery) ([]domain.SubscriptionChange, domain.ChangeCursor, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	cursor := domain.ChangeCursor{TxID: uint64(len(l.changes))}
+	if query.After == nil {
+		return []domain.SubscriptionChange{}, cursor, nil
+	}
+	changes := make([]domain.SubscriptionChange, 0)
+	for i := query.After.TxID; i < uint64(len(l.changes)) && len(changes) < query.Limit; i++ {
+		changes = append(changes, l.changes[i])
+		cursor.TxID = i + 1
+	}
+	return changes, cursor, nil
+}
+
+func TestChangesServiceWait(t *testing.T) {
+	ctx := context.Background()
+	log := &changeLog{}
+	bus := changefeed.NewBus()
+	// Опрос журнала реже ожидания: изменение должно прийти по сигналу шины
+	svc := NewChangesService(log, bus, time.Minute, time.Hour)
+
+	page, err := svc.Wait(ctx, domain.SubscriptionChangesQuery{Limit: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Changes) != 0 {
+		t.Fatalf("changes without since = %+v, want none", page.Changes)
+	}
+
+	done := make(chan *domain.SubscriptionChanges)
+	go func() {
+		page, err := svc.Wait(ctx, domain.SubscriptionChangesQuery{Since: page.Cursor, Wait: "30s", Limit: 10})
+		if err != nil {
+			t.Error(err)
+		}
+		done <- page
+	}()
+	time.Sleep(20 * time.Millisecond)
+	change := domain.SubscriptionChange{SubscriptionID: uuid.New(), UserID: uuid.New(), Action: domain.AuditCreate}
+	log.add(change)
+	bus.Notify()
+
+	select {
+	case page = <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("wait did not return after notify")
+	}
+	if len(page.Changes) != 1 || page.Changes[0].SubscriptionID != change.SubscriptionID {
+		t.Fatalf("changes = %+v, want the created subscription", page.Changes)
+	}
+
+	// Без изменений запрос возвращается по истечении wait с тем же курсором
+	start := time.Now()
+	empty, err := svc.Wait(ctx, domain.SubscriptionChangesQuery{Since: page.Cursor, Wait: "50ms", Limit: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(empty.Changes) != 0 || empty.Cursor != page.Cursor {
+		t.Errorf("timeout page = %+v, want empty with cursor %s", empty, page.Cursor)
+	}
+	if elapsed := time.Since(start); elapsed < 50*time.Millisecond {
+		t.Errorf("returned after %s, want at least wait", elapsed)
+	}
+}
+
+func TestChangesServiceValidation(t *testing.T) {
+	svc := NewChangesService(&changeLog{}, changefeed.NewBus(), time.Minute, time.Second)
+	for _, query := range []domain.SubscriptionChangesQuery{
+		{Wait: "soon", Limit: 10},
+		{Wait: "-1s", Limit: 10},
+	} {
+		if _, err := svc.Wait(context.Background(), query); !errors.Is(err, ErrValidation) {
+			t.Errorf("Wait(%q) error = %v, want validation", query.Wait, err)
+		}
+	}
+	if _, err := svc.Wait(context.Background(), domain.SubscriptionChangesQuery{Since: "not-a-cursor", Limit: 10}); !errors.Is(err, domain.ErrInvalidContinuation) {
+		t.Errorf("invalid since error = %v", err)
+	}
+}
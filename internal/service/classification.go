@@ -0,0 +1,205 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"aggregator_db/pkg/domain"
+	"aggregator_db/pkg/events"
+)
+
+// totalByClassification раскладывает сумму периода по классам оплаченных месяцев и валютам.
+func (s *SubscriptionService) totalByClassification(ctx context.Context, req domain.CalculateTotalRequest, start, end time.Time) (map[domain.BillingClass]domain.Totals, error) {
+	months, err := s.billedMonths(ctx, req, start, end)
+	if err != nil {
+		return nil, err
+	}
+	totals, _ := classTotals(months, req.Rounding)
+	return totals, nil
+}
+
+// billedMonths возвращает классифицированные оплаченные месяцы периода под фильтр req
+// со стоимостью Charge за вычетом скидок.
+func (s *SubscriptionService) billedMonths(ctx context.Context, req domain.CalculateTotalRequest, start, end time.Time) ([]domain.BilledMonth, error) {
+	req.EndPeriod = domain.FormatPeriod(end)
+	history, err := s.repo.ListHistory(ctx, req)
+	if err != nil {
+		s.logger.ErrorContext(ctx, "failed to load subscription history",
+			slog.String("error", err.Error()),
+		)
+		return nil, err
+	}
+
+	history = domain.CapOpenEnded(history, req.OpenEndedUntil)
+
+	aliases, err := s.aliasMap(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	months, err := domain.ClassifyBilledMonths(history, start, end, aliases)
+	if err != nil {
+		return nil, err
+	}
+	if req.ExcludeInactive {
+		if months, err = s.billableMonths(ctx, history, months); err != nil {
+			return nil, err
+		}
+	}
+
+	discounts, err := discountsBySubscription(ctx, s.repo, history)
+	if err != nil {
+		return nil, err
+	}
+
+	billed := months[:0]
+	for _, month := range months {
+		if req.Currency != "" && month.PriceAmount.Currency != req.Currency {
+			continue
+		}
+		m, err := domain.ParsePeriod(month.Month)
+		if err != nil {
+			return nil, err
+		}
+		if month.Charge, err = domain.DiscountedCharge(month.Charge, discounts[month.SubscriptionID], m); err != nil {
+			return nil, err
+		}
+		billed = append(billed, month)
+	}
+	return billed, nil
+}
+
+// totalItems - позиции include_items из оплаченных месяцев. Больше domain.MaxTotalItems
+// позиций не отдаются: такой расчет нужно сузить по периоду или фильтрам.
+func totalItems(months []domain.BilledMonth, format domain.MoneyFormat) ([]domain.TotalItem, error) {
+	if len(months) > domain.MaxTotalItems {
+		return nil, fmt.Errorf("%w: include_items: %d billed months exceed the limit of %d, narrow the period or filters",
+			ErrValidation, len(months), domain.MaxTotalItems)
+	}
+	return domain.TotalItems(months, format), nil
+}
+
+// classTotals складывает стоимость месяцев по классам и валютам и округляет один раз
+// по правилу mode; второй результат - итог по всем классам.
+func classTotals(months []domain.BilledMonth, mode domain.RoundingMode) (map[domain.BillingClass]domain.Totals, domain.Totals) {
+	units := make(map[domain.BillingClass]map[domain.Currency]int64, len(domain.BillingClasses))
+	for _, class := range domain.BillingClasses {
+		units[class] = make(map[domain.Currency]int64)
+	}
+	totalUnits := make(map[domain.Currency]int64)
+	for _, month := range months {
+		units[month.Class][month.PriceAmount.Currency] += month.Charge
+		totalUnits[month.PriceAmount.Currency] += month.Charge
+	}
+
+	totals := make(map[domain.BillingClass]domain.Totals, len(domain.BillingClasses))
+	for class, byCurrency := range units {
+		totals[class] = make(domain.Totals, len(byCurrency))
+		for currency, u := range byCurrency {
+			totals[class][currency] = domain.RoundProratedWith(u, mode)
+		}
+	}
+	total := make(domain.Totals, len(totalUnits))
+	for currency, u := range totalUnits {
+		total[currency] = domain.RoundProratedWith(u, mode)
+	}
+	return totals, total
+}
+
+// billableMonths отбрасывает месяцы, в которые подписка была на паузе или отменена.
+func (s *SubscriptionService) billableMonths(ctx context.Context, history []*domain.Subscription, months []domain.BilledMonth) ([]domain.BilledMonth, error) {
+	bySubscription, err := statusChangesBySubscription(ctx, s.repo, history)
+	if err != nil {
+		return nil, err
+	}
+
+	billable := months[:0]
+	for _, month := range months {
+		m, err := domain.ParsePeriod(month.Month)
+		if err != nil {
+			return nil, err
+		}
+		status, err := domain.StatusAt(bySubscription[month.SubscriptionID], m)
+		if err != nil {
+			return nil, err
+		}
+		if status.Billable() {
+			billable = append(billable, month)
+		}
+	}
+	return billable, nil
+}
+
+// publishClassification асинхронно публикует событие subscription.<class>
+// для первого месяца каждой созданной подписки. Ошибки только логируются:
+// доставка событий не должна влиять на ответ API.
+func (s *SubscriptionService) publishClassification(ctx context.Context, subs ...*domain.Subscription) {
+	ctx = context.WithoutCancel(ctx)
+
+	go func() {
+		for _, sub := range subs {
+			month, err := s.classifyStart(ctx, sub)
+			if err != nil {
+				s.logger.WarnContext(ctx, "failed to classify subscription",
+					slog.String("id", sub.ID.String()),
+					slog.String("error", err.Error()),
+				)
+				continue
+			}
+
+			event := events.New("subscription."+string(month.Class), month)
+			if err := s.publisher.Publish(ctx, event); err != nil {
+				s.logger.WarnContext(ctx, "failed to publish event",
+					slog.String("type", event.Type),
+					slog.String("id", sub.ID.String()),
+					slog.String("error", err.Error()),
+				)
+			}
+		}
+	}()
+}
+
+func (s *SubscriptionService) classifyStart(ctx context.Context, sub *domain.Subscription) (domain.BilledMonth, error) {
+	serviceName := sub.ServiceName
+	keys, err := s.serviceKeys(ctx, &serviceName)
+	if err != nil {
+		return domain.BilledMonth{}, err
+	}
+
+	history, err := s.repo.ListHistory(ctx, domain.CalculateTotalRequest{
+		UserID:      &sub.UserID,
+		ServiceKeys: keys,
+		EndPeriod:   sub.StartDate,
+	})
+	if err != nil {
+		return domain.BilledMonth{}, err
+	}
+
+	aliases, err := s.aliasMap(ctx)
+	if err != nil {
+		return domain.BilledMonth{}, err
+	}
+
+	class, err := domain.ClassifyStart(history, sub, aliases)
+	if err != nil {
+		return domain.BilledMonth{}, err
+	}
+
+	start, err := domain.ParsePeriod(sub.StartDate)
+	if err != nil {
+		return domain.BilledMonth{}, err
+	}
+
+	return domain.BilledMonth{
+		SubscriptionID: sub.ID,
+		UserID:         sub.UserID,
+		ServiceName:    sub.ServiceName,
+		Month:          sub.StartDate,
+		Price:          sub.Price.Major(),
+		PriceAmount:    sub.Price,
+		Class:          class,
+		Charge:         domain.ProratedMonthCharge(sub.Price.Amount, sub.BillingCycle, start),
+	}, nil
+}
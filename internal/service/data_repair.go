@@ -0,0 +1,128 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"aggregator_db/internal/clock"
+	"aggregator_db/internal/repository/postgres"
+	"aggregator_db/pkg/domain"
+	"github.com/google/uuid"
+)
+
+// DataRepairService ищет подписки с нарушенной целостностью (окончание раньше
+// начала, неразбираемые месяцы, отрицательная цена, несуществующий пользователь)
+// и применяет к ним настроенные исправления.
+type DataRepairService struct {
+	repo postgres.DataRepairRepository
+	// cache - кэш расчетов по подпискам; nil, если кэш выключен
+	cache postgres.SubscriptionCache
+	fixes domain.DataFixes
+	// batchSize - сколько подписок читается и исправляется за одну транзакцию
+	batchSize int
+	logger    *slog.Logger
+}
+
+func NewDataRepairService(repo postgres.DataRepairRepository, cache postgres.SubscriptionCache, fixes domain.DataFixes, batchSize int, logger *slog.Logger) *DataRepairService {
+	return &DataRepairService{
+		repo:      repo,
+		cache:     cache,
+		fixes:     fixes,
+		batchSize: batchSize,
+		logger:    logger,
+	}
+}
+
+// Scan проверяет все подписки и ничего не меняет. Fix в отчете показывает, что
+// сделает Repair.
+func (s *DataRepairService) Scan(ctx context.Context) (*domain.DataIssueReport, error) {
+	return s.run(ctx, nil, s.batchSize, false)
+}
+
+// Repair проверяет подписки пачками и в каждой пачке одной транзакцией применяет
+// настроенные исправления вместе с записями в журнал.
+func (s *DataRepairService) Repair(ctx context.Context, req domain.DataRepairRequest) (*domain.DataIssueReport, error) {
+	var kinds map[domain.DataIssueKind]bool
+	if len(req.Kinds) > 0 {
+		kinds = make(map[domain.DataIssueKind]bool, len(req.Kinds))
+		for _, kind := range req.Kinds {
+			if !kind.Valid() {
+				return nil, fmt.Errorf("%w: unknown data issue %q", ErrValidation, kind)
+			}
+			kinds[kind] = true
+		}
+	}
+	batchSize := s.batchSize
+	if req.BatchSize > 0 {
+		batchSize = req.BatchSize
+	}
+	return s.run(ctx, kinds, batchSize, true)
+}
+
+func (s *DataRepairService) run(ctx context.Context, kinds map[domain.DataIssueKind]bool, batchSize int, repair bool) (*domain.DataIssueReport, error) {
+	report := &domain.DataIssueReport{
+		ByKind: make(map[domain.DataIssueKind]int),
+		Issues: make([]domain.DataIssue, 0),
+	}
+
+	after := uuid.Nil
+	for {
+		records, err := s.repo.ScanRecords(ctx, after, batchSize)
+		if err != nil {
+			return nil, err
+		}
+		if len(records) == 0 {
+			break
+		}
+		after = records[len(records)-1].SubscriptionID
+		report.Scanned += len(records)
+
+		var pending []*domain.DataRepair
+		for _, rec := range records {
+			for _, issue := range domain.CheckDataRecord(rec, s.fixes) {
+				report.ByKind[issue.Kind]++
+				report.Issues = append(report.Issues, issue)
+				if fix := issue.Repair(); repair && fix != nil && (kinds == nil || kinds[issue.Kind]) {
+					pending = append(pending, fix)
+				}
+			}
+		}
+
+		if len(pending) > 0 {
+			now := clock.Now(ctx)
+			for _, fix := range pending {
+				fix.ID, fix.AppliedAt = uuid.New(), now
+			}
+			applied, err := s.repo.Apply(ctx, pending)
+			if err != nil {
+				return nil, err
+			}
+			if s.cache != nil && len(applied) > 0 {
+				users := make([]uuid.UUID, len(applied))
+				for i, fix := range applied {
+					users[i] = fix.UserID
+				}
+				s.cache.InvalidateUsers(ctx, users...)
+			}
+			report.Repaired = append(report.Repaired, applied...)
+		}
+
+		if len(records) < batchSize {
+			break
+		}
+	}
+
+	if repair {
+		s.logger.InfoContext(ctx, "data repair finished",
+			slog.Int("scanned", report.Scanned),
+			slog.Int("issues", len(report.Issues)),
+			slog.Int("repaired", len(report.Repaired)),
+		)
+	}
+	return report, nil
+}
+
+func (s *DataRepairService) ListRepairs(ctx context.Context, query domain.ListDataRepairsQuery) ([]*domain.DataRepair, error) {
+	return s.repo.ListRepairs(ctx, query)
+}
@@ -0,0 +1,113 @@
+package service
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+
+	"aggregator_db/internal/repository/memory"
+	"aggregator_db/pkg/domain"
+	"github.com/google/uuid"
+)
+
+// fakeCache запоминает сброшенные расчеты.
+type fakeCache struct {
+	users   map[uuid.UUID]bool
+	tenants int
+}
+
+func (c *fakeCache) InvalidateUsers(_ context.Context, users ...uuid.UUID) {
+	if c.users == nil {
+		c.users = make(map[uuid.UUID]bool)
+	}
+	for _, user := range users {
+		c.users[user] = true
+	}
+}
+
+func (c *fakeCache) InvalidateTenant(context.Context) {
+	c.tenants++
+}
+
+func TestDataRepairService(t *testing.T) {
+	ctx := context.Background()
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	repo := memory.NewSubscriptionRepository()
+
+	early := "01-2025"
+	subs := []*domain.Subscription{
+		{ID: uuid.New(), UserID: uuid.New(), ServiceName: "Netflix", Price: domain.NewMoney(79900, domain.DefaultCurrency), StartDate: "07-2025", EndDate: &early},
+		{ID: uuid.New(), UserID: uuid.New(), ServiceName: "Spotify", Price: domain.NewMoney(-29900, domain.DefaultCurrency), StartDate: "7/2025"},
+		{ID: uuid.New(), UserID: uuid.New(), ServiceName: "Yandex Plus", Price: domain.NewMoney(29900, domain.DefaultCurrency), StartDate: "03-2025"},
+	}
+	for _, sub := range subs {
+		if err := repo.Create(ctx, sub); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	fixes := domain.DataFixes{
+		domain.IssueEndBeforeStart:   domain.FixEndAtStart,
+		domain.IssueInvalidStartDate: domain.FixNormalizeDate,
+		domain.IssueNegativePrice:    domain.FixAbsPrice,
+	}
+	cache := &fakeCache{}
+	svc := NewDataRepairService(memory.NewDataRepairRepository(repo), cache, fixes, 2, logger)
+
+	report, err := svc.Scan(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Scanned != 3 || len(report.Issues) != 3 || len(report.Repaired) != 0 {
+		t.Fatalf("scan report = %+v", report)
+	}
+
+	// Исправляются только выбранные виды нарушений
+	report, err = svc.Repair(ctx, domain.DataRepairRequest{Kinds: []domain.DataIssueKind{domain.IssueEndBeforeStart, domain.IssueNegativePrice}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Repaired) != 2 {
+		t.Fatalf("repaired %d, want 2", len(report.Repaired))
+	}
+	if len(cache.users) != 2 || !cache.users[subs[0].UserID] || !cache.users[subs[1].UserID] {
+		t.Errorf("invalidated users = %v, want owners of repaired subscriptions", cache.users)
+	}
+	fixed, _ := repo.GetByID(ctx, subs[0].ID)
+	if fixed.EndDate == nil || *fixed.EndDate != "07-2025" {
+		t.Errorf("end_date = %v, want 07-2025", fixed.EndDate)
+	}
+	fixed, _ = repo.GetByID(ctx, subs[1].ID)
+	if fixed.Price.Amount != 29900 || fixed.StartDate != "7/2025" {
+		t.Errorf("price = %d, start_date = %s", fixed.Price.Amount, fixed.StartDate)
+	}
+
+	report, err = svc.Repair(ctx, domain.DataRepairRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Repaired) != 1 || report.Repaired[0].Kind != domain.IssueInvalidStartDate {
+		t.Fatalf("repaired = %+v", report.Repaired)
+	}
+
+	report, err = svc.Scan(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Issues) != 0 {
+		t.Errorf("issues after repair = %+v", report.Issues)
+	}
+
+	audit, err := svc.ListRepairs(ctx, domain.ListDataRepairsQuery{Limit: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(audit) != 3 {
+		t.Errorf("audit has %d entries, want 3", len(audit))
+	}
+
+	if _, err := svc.Repair(ctx, domain.DataRepairRequest{Kinds: []domain.DataIssueKind{"unknown"}}); err == nil {
+		t.Error("unknown kind accepted")
+	}
+}
@@ -0,0 +1,123 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+
+	"aggregator_db/internal/clock"
+	"aggregator_db/internal/ratelimit"
+	"aggregator_db/internal/repository/postgres"
+	"aggregator_db/pkg/domain"
+	"github.com/google/uuid"
+)
+
+// DeveloperService обслуживает портал разработчиков: регистрацию приложений,
+// их ключи, потребление и лимиты запросов.
+type DeveloperService struct {
+	repo      postgres.DeveloperAppRepository
+	usage     *UsageService
+	limiter   *ratelimit.Limiter
+	rateLimit int
+	logger    *slog.Logger
+}
+
+// NewDeveloperService - rateLimit задает лимит запросов в минуту для новых приложений.
+func NewDeveloperService(repo postgres.DeveloperAppRepository, usage *UsageService, limiter *ratelimit.Limiter, rateLimit int, logger *slog.Logger) *DeveloperService {
+	return &DeveloperService{repo: repo, usage: usage, limiter: limiter, rateLimit: rateLimit, logger: logger}
+}
+
+// Register регистрирует приложение и выдает ему ключ песочницы.
+func (s *DeveloperService) Register(ctx context.Context, req domain.RegisterDeveloperAppRequest) (*domain.DeveloperAppCredentials, error) {
+	apiKey, err := domain.NewSandboxAPIKey()
+	if err != nil {
+		return nil, err
+	}
+
+	now := clock.Now(ctx)
+	app := &domain.DeveloperApp{
+		ID:                 uuid.New(),
+		Name:               req.Name,
+		ContactEmail:       req.ContactEmail,
+		RateLimitPerMinute: s.rateLimit,
+		Sandbox:            true,
+		SecretHash:         domain.HashAPIKey(apiKey),
+		CreatedAt:          now,
+		UpdatedAt:          now,
+	}
+	if err := s.repo.Create(ctx, app); err != nil {
+		s.logger.ErrorContext(ctx, "failed to register developer app",
+			slog.String("error", err.Error()),
+		)
+		return nil, err
+	}
+
+	s.logger.InfoContext(ctx, "developer app registered",
+		slog.String("app_id", app.ID.String()),
+		slog.String("name", app.Name),
+	)
+
+	return &domain.DeveloperAppCredentials{App: app, APIKey: apiKey}, nil
+}
+
+// Authenticate ищет приложение по ключу из X-API-Key.
+func (s *DeveloperService) Authenticate(ctx context.Context, apiKey string) (*domain.DeveloperApp, error) {
+	return s.repo.GetBySecretHash(ctx, domain.HashAPIKey(apiKey))
+}
+
+func (s *DeveloperService) Usage(ctx context.Context, app *domain.DeveloperApp, from, to string) (*domain.UsageResponse, error) {
+	return s.usage.Usage(ctx, app.UsageConsumer(), from, to)
+}
+
+// RateLimit возвращает состояние лимита приложения в текущем окне на этой реплике.
+func (s *DeveloperService) RateLimit(app *domain.DeveloperApp) domain.RateLimitStatus {
+	return s.limiter.Status(ratelimit.AppKey(app), app.RateLimitPerMinute)
+}
+
+// RotateSecret выдает приложению новый ключ; прежний перестает работать сразу.
+func (s *DeveloperService) RotateSecret(ctx context.Context, app *domain.DeveloperApp) (*domain.DeveloperAppCredentials, error) {
+	apiKey, err := domain.NewSandboxAPIKey()
+	if err != nil {
+		return nil, err
+	}
+
+	rotated := *app
+	rotated.SecretHash = domain.HashAPIKey(apiKey)
+	rotated.UpdatedAt = clock.Now(ctx)
+	if err := s.repo.Update(ctx, &rotated); err != nil {
+		return nil, err
+	}
+
+	s.logger.InfoContext(ctx, "developer app secret rotated",
+		slog.String("app_id", app.ID.String()),
+	)
+
+	return &domain.DeveloperAppCredentials{App: &rotated, APIKey: apiKey}, nil
+}
+
+// UpdateApp меняет режим песочницы и лимит приложения по решению администратора.
+func (s *DeveloperService) UpdateApp(ctx context.Context, id uuid.UUID, req domain.UpdateDeveloperAppRequest) (*domain.DeveloperApp, error) {
+	app, err := s.repo.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Sandbox != nil {
+		app.Sandbox = *req.Sandbox
+	}
+	if req.RateLimitPerMinute != nil {
+		app.RateLimitPerMinute = *req.RateLimitPerMinute
+	}
+	app.UpdatedAt = clock.Now(ctx)
+
+	if err := s.repo.Update(ctx, app); err != nil {
+		return nil, err
+	}
+
+	s.logger.InfoContext(ctx, "developer app updated",
+		slog.String("app_id", app.ID.String()),
+		slog.Bool("sandbox", app.Sandbox),
+		slog.Int("rate_limit_per_minute", app.RateLimitPerMinute),
+	)
+
+	return app, nil
+}
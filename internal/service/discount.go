@@ -0,0 +1,133 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"aggregator_db/internal/clock"
+	"aggregator_db/internal/repository/postgres"
+	"aggregator_db/pkg/domain"
+	"github.com/google/uuid"
+)
+
+// CreateDiscount добавляет скидку на подписку. Фиксированная скидка задается
+// в валюте подписки; окно скидки может начинаться и в прошлом - тогда она
+// пересчитывает суммы за уже прошедшие месяцы.
+func (s *SubscriptionService) CreateDiscount(ctx context.Context, subscriptionID uuid.UUID, req domain.CreateDiscountRequest) (*domain.Discount, error) {
+	sub, err := s.repo.GetByID(ctx, subscriptionID)
+	if err != nil {
+		return nil, err
+	}
+
+	discount := &domain.Discount{
+		ID:             uuid.New(),
+		SubscriptionID: subscriptionID,
+		Kind:           req.Kind,
+		StartMonth:     req.StartMonth,
+		EndMonth:       req.EndMonth,
+		CreatedAt:      clock.Now(ctx),
+	}
+
+	switch req.Kind {
+	case domain.DiscountPercent:
+		if req.Amount != nil {
+			return nil, fmt.Errorf("%w: amount is only allowed for fixed discounts", ErrValidation)
+		}
+		if req.Percent == nil || *req.Percent < 1 || *req.Percent > 100 {
+			return nil, fmt.Errorf("%w: percent must be between 1 and 100", ErrValidation)
+		}
+		discount.Percent = req.Percent
+	case domain.DiscountFixed:
+		if req.Percent != nil {
+			return nil, fmt.Errorf("%w: percent is only allowed for percent discounts", ErrValidation)
+		}
+		if req.Amount == nil || req.Amount.Amount <= 0 {
+			return nil, fmt.Errorf("%w: amount must be positive", ErrValidation)
+		}
+		if req.Amount.Currency != sub.Price.Currency {
+			return nil, fmt.Errorf("%w: amount currency must match subscription currency %s", ErrValidation, sub.Price.Currency)
+		}
+		discount.Amount = req.Amount
+	default:
+		return nil, fmt.Errorf("%w: unknown discount kind %q", ErrValidation, req.Kind)
+	}
+
+	start, err := domain.ParsePeriod(req.StartMonth)
+	if err != nil {
+		return nil, fmt.Errorf("%w: start_month: %w", ErrValidation, err)
+	}
+	if req.EndMonth != nil {
+		end, err := domain.ParsePeriod(*req.EndMonth)
+		if err != nil {
+			return nil, fmt.Errorf("%w: end_month: %w", ErrValidation, err)
+		}
+		if end.Before(start) {
+			return nil, fmt.Errorf("%w: end_month must not be before start_month", ErrValidation)
+		}
+	}
+
+	if req.Code != nil {
+		code := strings.TrimSpace(*req.Code)
+		if code != "" {
+			discount.Code = &code
+		}
+	}
+
+	if err := s.repo.CreateDiscount(ctx, discount); err != nil {
+		s.logger.ErrorContext(ctx, "failed to create discount",
+			slog.String("subscription_id", subscriptionID.String()),
+			slog.String("error", err.Error()),
+		)
+		return nil, err
+	}
+
+	s.logger.InfoContext(ctx, "discount created",
+		slog.String("id", discount.ID.String()),
+		slog.String("subscription_id", subscriptionID.String()),
+		slog.String("kind", string(discount.Kind)),
+	)
+	return discount, nil
+}
+
+func (s *SubscriptionService) ListDiscounts(ctx context.Context, subscriptionID uuid.UUID) ([]*domain.Discount, error) {
+	if _, err := s.repo.GetByID(ctx, subscriptionID); err != nil {
+		return nil, err
+	}
+	return s.repo.ListDiscounts(ctx, []uuid.UUID{subscriptionID})
+}
+
+func (s *SubscriptionService) DeleteDiscount(ctx context.Context, subscriptionID, id uuid.UUID) error {
+	if _, err := s.repo.GetByID(ctx, subscriptionID); err != nil {
+		return err
+	}
+	if err := s.repo.DeleteDiscount(ctx, subscriptionID, id); err != nil {
+		return err
+	}
+
+	s.logger.InfoContext(ctx, "discount deleted",
+		slog.String("id", id.String()),
+		slog.String("subscription_id", subscriptionID.String()),
+	)
+	return nil
+}
+
+// discountsBySubscription загружает скидки подписок, сгруппированные по ID.
+func discountsBySubscription(ctx context.Context, repo postgres.SubscriptionRepository, subs []*domain.Subscription) (map[uuid.UUID][]*domain.Discount, error) {
+	ids := make([]uuid.UUID, len(subs))
+	for i, sub := range subs {
+		ids[i] = sub.ID
+	}
+
+	discounts, err := repo.ListDiscounts(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	bySubscription := make(map[uuid.UUID][]*domain.Discount)
+	for _, discount := range discounts {
+		bySubscription[discount.SubscriptionID] = append(bySubscription[discount.SubscriptionID], discount)
+	}
+	return bySubscription, nil
+}
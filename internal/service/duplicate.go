@@ -0,0 +1,132 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"aggregator_db/internal/clock"
+	"aggregator_db/internal/repository/postgres"
+	"aggregator_db/pkg/domain"
+	"github.com/google/uuid"
+)
+
+// DuplicateService ищет у пользователей несколько планов одного сервиса
+// (например, две подписки Spotify) и предлагает оставить один.
+type DuplicateService struct {
+	repo   postgres.DuplicateRepository
+	subs   postgres.SubscriptionRepository
+	users  postgres.UserRepository
+	logger *slog.Logger
+}
+
+func NewDuplicateService(repo postgres.DuplicateRepository, subs postgres.SubscriptionRepository, users postgres.UserRepository, logger *slog.Logger) *DuplicateService {
+	return &DuplicateService{
+		repo:   repo,
+		subs:   subs,
+		users:  users,
+		logger: logger,
+	}
+}
+
+// activeDuplicates находит группы среди подписок, действующих в месяце month.
+func (s *DuplicateService) activeDuplicates(ctx context.Context, userID *uuid.UUID, month time.Time) ([]*domain.Subscription, []*domain.DuplicateFlag, error) {
+	subs, err := s.subs.ListHistory(ctx, domain.CalculateTotalRequest{
+		UserID:    userID,
+		EndPeriod: domain.FormatPeriod(month),
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	changes, err := statusChangesBySubscription(ctx, s.subs, subs)
+	if err != nil {
+		return nil, nil, err
+	}
+	flags, err := domain.DetectDuplicates(subs, changes, month)
+	if err != nil {
+		return nil, nil, err
+	}
+	return subs, flags, nil
+}
+
+// Detect - задача планировщика. Находит группы по всем пользователям за текущий месяц
+// и заменяет ими сохраненные; исчезнувшие группы (план отменили) удаляются.
+func (s *DuplicateService) Detect(ctx context.Context, now time.Time) error {
+	now = now.UTC()
+	month := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+
+	_, flags, err := s.activeDuplicates(ctx, nil, month)
+	if err != nil {
+		return err
+	}
+	if err := s.repo.Replace(ctx, flags, now); err != nil {
+		return err
+	}
+
+	s.logger.InfoContext(ctx, "duplicate scan finished", slog.Int("groups", len(flags)))
+	return nil
+}
+
+// List возвращает найденные задачей группы пользователя с предложением, какой план
+// оставить. Подписки, которые с момента проверки перестали действовать, не показываются,
+// а группа, где их осталось меньше двух, пропускается.
+func (s *DuplicateService) List(ctx context.Context, userID uuid.UUID) (*domain.DuplicatesResponse, error) {
+	if _, err := s.users.GetByID(ctx, userID); err != nil {
+		return nil, err
+	}
+	flags, err := s.repo.ListByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := clock.Now(ctx)
+	subs, current, err := s.activeDuplicates(ctx, &userID, time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[uuid.UUID]*domain.Subscription, len(subs))
+	for _, sub := range subs {
+		byID[sub.ID] = sub
+	}
+	active := make(map[uuid.UUID]bool)
+	for _, flag := range current {
+		for _, id := range flag.SubscriptionIDs {
+			active[id] = true
+		}
+	}
+
+	resp := &domain.DuplicatesResponse{UserID: userID, Duplicates: make([]domain.DuplicatePlans, 0, len(flags))}
+	for _, flag := range flags {
+		plans := make([]*domain.Subscription, 0, len(flag.SubscriptionIDs))
+		for _, id := range flag.SubscriptionIDs {
+			if active[id] {
+				plans = append(plans, byID[id])
+			}
+		}
+		if len(plans) < 2 {
+			continue
+		}
+		resp.Duplicates = append(resp.Duplicates, domain.DuplicatePlans{
+			ServiceKey:    flag.ServiceKey,
+			ServiceName:   flag.ServiceName,
+			DetectedAt:    flag.DetectedAt,
+			Subscriptions: plans,
+			Suggestion:    domain.SuggestConsolidation(plans),
+		})
+	}
+	return resp, nil
+}
+
+// Recommendations - советы пользователю по экономии; пока это объединение планов одного сервиса.
+func (s *DuplicateService) Recommendations(ctx context.Context, userID uuid.UUID) (*domain.RecommendationsResponse, error) {
+	duplicates, err := s.List(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &domain.RecommendationsResponse{UserID: userID, Recommendations: make([]domain.Recommendation, 0, len(duplicates.Duplicates))}
+	for _, plans := range duplicates.Duplicates {
+		resp.Recommendations = append(resp.Recommendations, domain.NewConsolidateRecommendation(plans))
+	}
+	return resp, nil
+}
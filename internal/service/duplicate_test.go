@@ -0,0 +1,61 @@
+package service
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"aggregator_db/internal/repository/memory"
+	"aggregator_db/pkg/domain"
+	"github.com/google/uuid"
+)
+
+func TestDuplicateService(t *testing.T) {
+	ctx := context.Background()
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	repo := memory.NewSubscriptionRepository()
+
+	userID := uuid.New()
+	sub := func(name string, amount int64) *domain.Subscription {
+		return &domain.Subscription{ID: uuid.New(), UserID: userID, ServiceName: name, Price: domain.NewMoney(amount, domain.DefaultCurrency), StartDate: "01-2025"}
+	}
+	individual, family := sub("Spotify", 29900), sub("Spotify Family", 49900)
+	duo := sub("spotify", 39900)
+	for _, s := range []*domain.Subscription{individual, family, duo, sub("Netflix", 79900)} {
+		if err := repo.Create(ctx, s); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	svc := NewDuplicateService(memory.NewDuplicateRepository(), repo, memory.NewUserRepository(repo), logger)
+	if err := svc.Detect(ctx, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+
+	// "Spotify Family" - другой ключ сервиса, в группу попадают только два Spotify
+	recs, err := svc.Recommendations(ctx, userID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(recs.Recommendations) != 1 {
+		t.Fatalf("got %d recommendations, want 1", len(recs.Recommendations))
+	}
+	rec := recs.Recommendations[0]
+	if rec.Type != domain.RecommendationConsolidatePlans || rec.Suggestion.Keep != duo.ID || rec.Suggestion.MonthlySavings.Get(domain.DefaultCurrency).Amount != 29900 {
+		t.Errorf("recommendation = %+v", rec)
+	}
+
+	// Подписку удалили после проверки: группа из одного плана не показывается до следующего запуска
+	if err := repo.Delete(ctx, individual.ID); err != nil {
+		t.Fatal(err)
+	}
+	duplicates, err := svc.List(ctx, userID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(duplicates.Duplicates) != 0 {
+		t.Errorf("got %d groups after delete, want 0", len(duplicates.Duplicates))
+	}
+}
@@ -0,0 +1,239 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"aggregator_db/internal/clock"
+	"aggregator_db/internal/repository/postgres"
+	"aggregator_db/pkg/domain"
+	"github.com/google/uuid"
+)
+
+const (
+	// exportBatchSize - сколько выгрузок обрабатывает один запуск задачи
+	exportBatchSize = 10
+	// maxExportAttempts - после стольких неудачных попыток выгрузка помечается failed
+	maxExportAttempts = 3
+	// exportStaleAfter - выгрузка в processing дольше этого срока считается брошенной
+	exportStaleAfter = 15 * time.Minute
+)
+
+// ExportOptions - настройки доставки выгрузок.
+type ExportOptions struct {
+	// PublicURL - адрес сервиса для ссылок на скачивание
+	PublicURL string
+	LinkTTL   time.Duration
+	// MaxAttachmentBytes - файлы больше отправляются ссылкой
+	MaxAttachmentBytes int
+}
+
+// ExportService ставит выгрузки в очередь и доставляет их на почту задачей планировщика.
+type ExportService struct {
+	repo          postgres.ExportJobRepository
+	usage         *UsageService
+	notifications *NotificationService
+	// statements доставляет ежемесячные выписки; без него выписки в очереди не отправляются
+	statements *StatementService
+	opts       ExportOptions
+	logger     *slog.Logger
+}
+
+func NewExportService(repo postgres.ExportJobRepository, usage *UsageService, notifications *NotificationService, statements *StatementService, opts ExportOptions, logger *slog.Logger) *ExportService {
+	return &ExportService{
+		repo:          repo,
+		usage:         usage,
+		notifications: notifications,
+		statements:    statements,
+		opts:          opts,
+		logger:        logger,
+	}
+}
+
+// EnqueueUsage ставит в очередь CSV-выгрузку потребления. Период проверяется сразу,
+// чтобы ошибка в запросе не обнаружилась только в фоновой задаче.
+func (s *ExportService) EnqueueUsage(ctx context.Context, query domain.UsageQuery, delivery domain.ExportEmailQuery) (*domain.ExportJob, error) {
+	if err := validateUsageRange(query.From, query.To); err != nil {
+		return nil, err
+	}
+	return s.enqueue(ctx, domain.ExportUsage, query, delivery)
+}
+
+func (s *ExportService) enqueue(ctx context.Context, kind domain.ExportKind, params any, delivery domain.ExportEmailQuery) (*domain.ExportJob, error) {
+	if delivery.Email == "" {
+		return nil, fmt.Errorf("%w: email is required", ErrValidation)
+	}
+	if delivery.Delivery == "" {
+		delivery.Delivery = domain.DeliveryAttachment
+	}
+
+	raw, err := json.Marshal(params)
+	if err != nil {
+		return nil, err
+	}
+
+	now := clock.Now(ctx)
+	job := &domain.ExportJob{
+		ID:        uuid.New(),
+		Kind:      kind,
+		Params:    raw,
+		Email:     delivery.Email,
+		Delivery:  delivery.Delivery,
+		Status:    domain.ExportPending,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if err := s.repo.Create(ctx, job); err != nil {
+		s.logger.ErrorContext(ctx, "failed to enqueue export",
+			slog.String("kind", string(kind)),
+			slog.String("error", err.Error()),
+		)
+		return nil, err
+	}
+
+	s.logger.InfoContext(ctx, "export enqueued",
+		slog.String("export_id", job.ID.String()),
+		slog.String("kind", string(kind)),
+		slog.String("delivery", string(job.Delivery)),
+	)
+	return job, nil
+}
+
+// Backlog - число выгрузок, которые еще не отправлены.
+func (s *ExportService) Backlog(ctx context.Context) (int, error) {
+	return s.repo.Backlog(ctx)
+}
+
+func (s *ExportService) Get(ctx context.Context, id uuid.UUID) (*domain.ExportJob, error) {
+	return s.repo.Get(ctx, id)
+}
+
+// Download возвращает выгрузку с файлом по ссылке из письма. Неверный токен,
+// истекшая ссылка и выгрузка без файла неотличимы: все дают ErrExportNotFound.
+func (s *ExportService) Download(ctx context.Context, id uuid.UUID, token string, now time.Time) (*domain.ExportJob, error) {
+	job, err := s.repo.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if job.DownloadTokenHash == "" || job.Content == nil || job.ExpiresAt == nil || !now.Before(*job.ExpiresAt) ||
+		subtle.ConstantTimeCompare([]byte(domain.HashAPIKey(token)), []byte(job.DownloadTokenHash)) != 1 {
+		return nil, postgres.ErrExportNotFound
+	}
+	return job, nil
+}
+
+// Deliver - задача планировщика: формирует ожидающие выгрузки, отправляет их
+// через NotificationService и удаляет файлы с истекшими ссылками.
+func (s *ExportService) Deliver(ctx context.Context, now time.Time) error {
+	now = now.UTC()
+	if purged, err := s.repo.PurgeExpired(ctx, now); err != nil {
+		s.logger.WarnContext(ctx, "failed to purge expired exports", slog.String("error", err.Error()))
+	} else if purged > 0 {
+		s.logger.InfoContext(ctx, "expired exports purged", slog.Int("purged", purged))
+	}
+
+	jobs, err := s.repo.Claim(ctx, exportBatchSize, now, now.Add(-exportStaleAfter))
+	if err != nil {
+		return err
+	}
+
+	for _, job := range jobs {
+		if err := s.deliver(ctx, job, now); err != nil {
+			message := err.Error()
+			job.Error = &message
+			job.Status = domain.ExportPending
+			if job.Attempts >= maxExportAttempts {
+				job.Status = domain.ExportFailed
+			}
+			s.logger.WarnContext(ctx, "failed to deliver export",
+				slog.String("export_id", job.ID.String()),
+				slog.Int("attempt", job.Attempts),
+				slog.String("error", message),
+			)
+		}
+
+		job.UpdatedAt = clock.Now(ctx)
+		if err := s.repo.Update(ctx, job); err != nil {
+			s.logger.ErrorContext(ctx, "failed to save export",
+				slog.String("export_id", job.ID.String()),
+				slog.String("error", err.Error()),
+			)
+		}
+	}
+	return nil
+}
+
+func (s *ExportService) deliver(ctx context.Context, job *domain.ExportJob, now time.Time) error {
+	if job.Kind == domain.ExportStatement {
+		if s.statements == nil {
+			return errors.New("monthly statements are not configured")
+		}
+		if err := s.statements.Deliver(ctx, job); err != nil {
+			return err
+		}
+		markDelivered(ctx, job)
+		return nil
+	}
+
+	fileName, content, err := s.generate(ctx, job)
+	if err != nil {
+		return err
+	}
+	job.FileName = fileName
+
+	// Большие файлы почтовые серверы не принимают, их отправляем ссылкой
+	downloadURL := ""
+	if job.Delivery == domain.DeliveryLink || len(content) > s.opts.MaxAttachmentBytes {
+		token, err := domain.NewDownloadToken()
+		if err != nil {
+			return err
+		}
+		expiresAt := now.Add(s.opts.LinkTTL)
+		job.Content = content
+		job.DownloadTokenHash = domain.HashAPIKey(token)
+		job.ExpiresAt = &expiresAt
+		downloadURL = fmt.Sprintf("%s/api/v1/exports/%s/download?token=%s", strings.TrimRight(s.opts.PublicURL, "/"), job.ID, token)
+	}
+
+	if err := s.notifications.SendExport(ctx, job, content, downloadURL); err != nil {
+		return err
+	}
+	markDelivered(ctx, job)
+	return nil
+}
+
+func markDelivered(ctx context.Context, job *domain.ExportJob) {
+	deliveredAt := clock.Now(ctx)
+	job.Status = domain.ExportDelivered
+	job.Error = nil
+	job.DeliveredAt = &deliveredAt
+}
+
+// generate формирует файл выгрузки по ее виду и параметрам.
+func (s *ExportService) generate(ctx context.Context, job *domain.ExportJob) (string, []byte, error) {
+	switch job.Kind {
+	case domain.ExportUsage:
+		var query domain.UsageQuery
+		if err := json.Unmarshal(job.Params, &query); err != nil {
+			return "", nil, fmt.Errorf("decode export params: %w", err)
+		}
+		lines, err := s.usage.InvoiceLines(ctx, query)
+		if err != nil {
+			return "", nil, err
+		}
+		var buf bytes.Buffer
+		if err := WriteInvoiceCSV(&buf, query, lines); err != nil {
+			return "", nil, err
+		}
+		return UsageExportFileName(query), buf.Bytes(), nil
+	default:
+		return "", nil, fmt.Errorf("unknown export kind %q", job.Kind)
+	}
+}
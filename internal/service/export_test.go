@@ -0,0 +1,159 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"aggregator_db/internal/repository/memory"
+	"aggregator_db/internal/repository/postgres"
+	"aggregator_db/pkg/domain"
+	"aggregator_db/pkg/mailer"
+)
+
+type recordingMailer struct {
+	mu   sync.Mutex
+	sent []mailer.Message
+	err  error
+}
+
+func (m *recordingMailer) Send(_ context.Context, msg mailer.Message) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.err != nil {
+		return m.err
+	}
+	m.sent = append(m.sent, msg)
+	return nil
+}
+
+func newTestExportService(t *testing.T, mail mailer.Sender, maxAttachment int) *ExportService {
+	t.Helper()
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	usageRepo := memory.NewUsageRepository()
+	if err := usageRepo.Add(context.Background(), []*domain.UsageRecord{
+		{Consumer: "acme", Metric: domain.UsageAPIRequests, Day: "2025-10-01", Quantity: 120},
+		{Consumer: "acme", Metric: domain.UsageAPIRequests, Day: "2025-10-02", Quantity: 30},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	notifications := NewNotificationService(memory.NewSubscriptionRepository(), memory.NewNotificationSettingsRepository(),
+		&recordingPublisher{}, mail, 20, logger)
+	return NewExportService(memory.NewExportJobRepository(), NewUsageService(usageRepo), notifications, nil,
+		ExportOptions{PublicURL: "https://api.example.com/", LinkTTL: time.Hour, MaxAttachmentBytes: maxAttachment}, logger)
+}
+
+func TestExportDelivery(t *testing.T) {
+	ctx := context.Background()
+	query := domain.UsageQuery{From: "2025-10-01", To: "2025-10-31"}
+	now := time.Date(2025, 11, 1, 9, 0, 0, 0, time.UTC)
+
+	t.Run("attachment", func(t *testing.T) {
+		mail := &recordingMailer{}
+		svc := newTestExportService(t, mail, 1<<20)
+
+		job, err := svc.EnqueueUsage(ctx, query, domain.ExportEmailQuery{Email: "billing@example.com"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := svc.Deliver(ctx, now); err != nil {
+			t.Fatal(err)
+		}
+
+		if len(mail.sent) != 1 || len(mail.sent[0].Attachments) != 1 {
+			t.Fatalf("expected one mail with an attachment, got %+v", mail.sent)
+		}
+		attachment := mail.sent[0].Attachments[0]
+		if attachment.Name != "usage_2025-10-01_2025-10-31.csv" || !strings.Contains(string(attachment.Data), "acme,api_requests,150") {
+			t.Errorf("unexpected attachment %s: %s", attachment.Name, attachment.Data)
+		}
+
+		stored, _ := svc.Get(ctx, job.ID)
+		if stored.Status != domain.ExportDelivered || stored.Content != nil {
+			t.Errorf("status %s, content kept: %v", stored.Status, stored.Content != nil)
+		}
+
+		// Повторный запуск не отправляет выгрузку еще раз
+		if err := svc.Deliver(ctx, now); err != nil || len(mail.sent) != 1 {
+			t.Errorf("export delivered twice: %d mails, %v", len(mail.sent), err)
+		}
+	})
+
+	t.Run("large file falls back to link", func(t *testing.T) {
+		mail := &recordingMailer{}
+		svc := newTestExportService(t, mail, 10)
+
+		job, err := svc.EnqueueUsage(ctx, query, domain.ExportEmailQuery{Email: "billing@example.com", Delivery: domain.DeliveryAttachment})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := svc.Deliver(ctx, now); err != nil {
+			t.Fatal(err)
+		}
+		if len(mail.sent) != 1 || len(mail.sent[0].Attachments) != 0 {
+			t.Fatalf("expected a mail without attachments, got %+v", mail.sent)
+		}
+
+		link := mail.sent[0].Body[strings.Index(mail.sent[0].Body, "https://"):]
+		parsed, err := url.Parse(strings.TrimSpace(link))
+		if err != nil || parsed.Path != "/api/v1/exports/"+job.ID.String()+"/download" {
+			t.Fatalf("unexpected link %q: %v", link, err)
+		}
+		token := parsed.Query().Get("token")
+
+		downloaded, err := svc.Download(ctx, job.ID, token, now)
+		if err != nil || !strings.Contains(string(downloaded.Content), "acme,api_requests,150") {
+			t.Errorf("download failed: %v", err)
+		}
+		if _, err := svc.Download(ctx, job.ID, "wrong", now); !errors.Is(err, postgres.ErrExportNotFound) {
+			t.Errorf("wrong token: got %v", err)
+		}
+		if _, err := svc.Download(ctx, job.ID, token, now.Add(2*time.Hour)); !errors.Is(err, postgres.ErrExportNotFound) {
+			t.Errorf("expired link: got %v", err)
+		}
+
+		// После истечения ссылки файл удаляется
+		if err := svc.Deliver(ctx, now.Add(2*time.Hour)); err != nil {
+			t.Fatal(err)
+		}
+		if stored, _ := svc.Get(ctx, job.ID); stored.Content != nil {
+			t.Error("expired export content was not purged")
+		}
+	})
+
+	t.Run("retries then fails", func(t *testing.T) {
+		mail := &recordingMailer{err: errors.New("smtp unavailable")}
+		svc := newTestExportService(t, mail, 1<<20)
+
+		job, err := svc.EnqueueUsage(ctx, query, domain.ExportEmailQuery{Email: "billing@example.com"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i := 0; i < maxExportAttempts; i++ {
+			if err := svc.Deliver(ctx, now); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		stored, _ := svc.Get(ctx, job.ID)
+		if stored.Status != domain.ExportFailed || stored.Attempts != maxExportAttempts || stored.Error == nil {
+			t.Errorf("got status %s after %d attempts, error %v", stored.Status, stored.Attempts, stored.Error)
+		}
+	})
+
+	t.Run("invalid period is rejected at once", func(t *testing.T) {
+		svc := newTestExportService(t, &recordingMailer{}, 1<<20)
+		_, err := svc.EnqueueUsage(ctx, domain.UsageQuery{From: "2025-10-31", To: "2025-10-01"}, domain.ExportEmailQuery{Email: "billing@example.com"})
+		if !errors.Is(err, ErrValidation) {
+			t.Errorf("got %v, want validation error", err)
+		}
+	})
+}
@@ -0,0 +1,65 @@
+package service
+
+import (
+	"context"
+
+	"aggregator_db/internal/apikey"
+	"aggregator_db/internal/developer"
+	"aggregator_db/internal/ratelimit"
+	"aggregator_db/internal/tenancy"
+	"aggregator_db/pkg/domain"
+)
+
+// LimitsService показывает вызывающему его лимиты запросов и квоты тенанта,
+// чтобы клиенты сами снижали темп до отказов 429.
+type LimitsService struct {
+	limiter         *ratelimit.Limiter
+	tenants         *TenantService
+	serviceKeyLimit int
+}
+
+// NewLimitsService создает сервис лимитов; tenants может быть nil, если тенанты выключены.
+func NewLimitsService(limiter *ratelimit.Limiter, tenants *TenantService, serviceKeyLimit int) *LimitsService {
+	return &LimitsService{limiter: limiter, tenants: tenants, serviceKeyLimit: serviceKeyLimit}
+}
+
+// Get возвращает лимиты, которые middleware применяют к запросу ctx; остатки
+// уже учитывают сам запрос.
+func (s *LimitsService) Get(ctx context.Context) (*domain.CallerLimits, error) {
+	limits := &domain.CallerLimits{RateLimits: []domain.CallerRateLimit{}}
+
+	if app := developer.FromContext(ctx); app != nil {
+		limits.RateLimits = append(limits.RateLimits, domain.CallerRateLimit{
+			Scope:           domain.RateLimitScopeDeveloperApp,
+			ID:              app.ID.String(),
+			RateLimitStatus: s.limiter.Status(ratelimit.AppKey(app), app.RateLimitPerMinute),
+		})
+	}
+	if key := apikey.FromContext(ctx); key != nil && s.serviceKeyLimit > 0 {
+		limits.RateLimits = append(limits.RateLimits, domain.CallerRateLimit{
+			Scope:           domain.RateLimitScopeServiceKey,
+			ID:              key.ID.String(),
+			RateLimitStatus: s.limiter.Status(ratelimit.ServiceKeyKey(key), s.serviceKeyLimit),
+		})
+	}
+
+	tenant := tenancy.FromContext(ctx)
+	if tenant == nil {
+		return limits, nil
+	}
+	if tenant.Quotas.RequestsPerMinute != nil {
+		limits.RateLimits = append(limits.RateLimits, domain.CallerRateLimit{
+			Scope:           domain.RateLimitScopeTenant,
+			ID:              tenant.ID,
+			RateLimitStatus: s.limiter.Status(ratelimit.TenantKey(tenant), *tenant.Quotas.RequestsPerMinute),
+		})
+	}
+	if s.tenants != nil {
+		usage, err := s.tenants.TenantUsage(ctx, tenant)
+		if err != nil {
+			return nil, err
+		}
+		limits.Tenant = usage
+	}
+	return limits, nil
+}
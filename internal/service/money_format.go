@@ -0,0 +1,44 @@
+package service
+
+import (
+	"context"
+
+	"aggregator_db/internal/tenancy"
+	"aggregator_db/pkg/domain"
+)
+
+// moneyFormat возвращает правила округления и вывода сумм тенанта запроса.
+func moneyFormat(ctx context.Context) domain.MoneyFormat {
+	if tenant := tenancy.FromContext(ctx); tenant != nil && tenant.MoneyFormat != nil {
+		return *tenant.MoneyFormat
+	}
+	return domain.DefaultMoneyFormat
+}
+
+// openEndedPolicy - настройка бессрочных подписок тенанта из контекста.
+func openEndedPolicy(ctx context.Context) domain.OpenEndedPolicy {
+	if tenant := tenancy.FromContext(ctx); tenant != nil && tenant.OpenEnded != nil {
+		return *tenant.OpenEnded
+	}
+	return domain.OpenEndedPolicy{}
+}
+
+// formatSettle оборачивает перевод итогов в сумму ответа форматом тенанта.
+func formatSettle(format domain.MoneyFormat, settle func(domain.Totals) (domain.Money, error)) func(domain.Totals) (domain.Money, error) {
+	return func(totals domain.Totals) (domain.Money, error) {
+		money, err := settle(totals)
+		if err != nil {
+			return domain.Money{}, err
+		}
+		return format.Apply(money), nil
+	}
+}
+
+// customFieldSchema - пользовательские поля подписок тенанта из контекста;
+// без тенанта полей нет.
+func customFieldSchema(ctx context.Context) domain.CustomFieldSchema {
+	if tenant := tenancy.FromContext(ctx); tenant != nil {
+		return tenant.CustomFields
+	}
+	return nil
+}
@@ -0,0 +1,93 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"aggregator_db/internal/repository/postgres"
+	"aggregator_db/internal/tenancy"
+	"aggregator_db/pkg/domain"
+)
+
+// MonthlyTotalsService поддерживает помесячные итоги подписок (см.
+// postgres.MonthlyTotalsRepository) в основной базе и у каждого активного тенанта.
+type MonthlyTotalsService struct {
+	repo postgres.MonthlyTotalsRepository
+	// tenants - реестр тенантов; без него итоги поддерживаются только в основной базе
+	tenants   postgres.TenantRepository
+	batchSize int
+	logger    *slog.Logger
+}
+
+func NewMonthlyTotalsService(repo postgres.MonthlyTotalsRepository, tenants postgres.TenantRepository, batchSize int, logger *slog.Logger) *MonthlyTotalsService {
+	return &MonthlyTotalsService{
+		repo:      repo,
+		tenants:   tenants,
+		batchSize: batchSize,
+		logger:    logger,
+	}
+}
+
+// Refresh - задача планировщика. Доводит итоги до прошлого месяца: текущий месяц
+// еще меняется и всегда считается по подпискам. Затем пересчитывает пользователей
+// с изменившимися подписками пачками, пока отмеченные не кончатся.
+func (s *MonthlyTotalsService) Refresh(ctx context.Context, now time.Time) error {
+	now = now.UTC()
+	through := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC).AddDate(0, -1, 0)
+
+	scopes := []*domain.Tenant{nil}
+	if s.tenants != nil {
+		tenants, err := s.tenants.List(ctx)
+		if err != nil {
+			return err
+		}
+		for _, tenant := range tenants {
+			if tenant.Status == domain.TenantStatusActive {
+				scopes = append(scopes, tenant)
+			}
+		}
+	}
+
+	users, failed := 0, 0
+	for _, tenant := range scopes {
+		scopeCtx, scopeName := ctx, domain.DefaultTenantScope
+		if tenant != nil {
+			scopeCtx, scopeName = tenancy.WithTenant(ctx, tenant), tenant.ID
+		}
+
+		for ctx.Err() == nil {
+			result, err := s.repo.Refresh(scopeCtx, through, s.batchSize)
+			if err != nil {
+				// Недоступная база одного тенанта не должна останавливать остальных
+				s.logger.WarnContext(ctx, "failed to refresh monthly totals",
+					slog.String("tenant_id", scopeName),
+					slog.String("error", err.Error()),
+				)
+				failed++
+				break
+			}
+			if result.Built || result.Months > 0 {
+				s.logger.InfoContext(ctx, "monthly totals extended",
+					slog.String("tenant_id", scopeName),
+					slog.String("through", domain.FormatPeriod(result.Through)),
+					slog.Bool("built", result.Built),
+				)
+			}
+			users += result.Users
+			if !result.Built && result.Users < s.batchSize {
+				break
+			}
+		}
+	}
+
+	if users == 0 && failed == 0 {
+		return nil
+	}
+	s.logger.InfoContext(ctx, "monthly totals refresh finished",
+		slog.Int("scopes", len(scopes)),
+		slog.Int("users", users),
+		slog.Int("failed", failed),
+	)
+	return nil
+}
@@ -0,0 +1,440 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"math"
+	"mime"
+	"path"
+	"strings"
+	"time"
+
+	"aggregator_db/internal/clock"
+	"aggregator_db/internal/repository/postgres"
+	"aggregator_db/pkg/domain"
+	"aggregator_db/pkg/events"
+	"aggregator_db/pkg/mailer"
+	"aggregator_db/pkg/region"
+	"github.com/google/uuid"
+)
+
+type NotificationService struct {
+	subs             postgres.SubscriptionRepository
+	settings         postgres.NotificationSettingsRepository
+	publisher        events.Publisher
+	mailer           mailer.Sender
+	defaultThreshold int
+	logger           *slog.Logger
+}
+
+func NewNotificationService(subs postgres.SubscriptionRepository, settings postgres.NotificationSettingsRepository, publisher events.Publisher, mail mailer.Sender, defaultThreshold int, logger *slog.Logger) *NotificationService {
+	return &NotificationService{
+		subs:             subs,
+		settings:         settings,
+		publisher:        publisher,
+		mailer:           mail,
+		defaultThreshold: defaultThreshold,
+		logger:           logger,
+	}
+}
+
+// SendExport отправляет готовую выгрузку на почту из job: файлом во вложении
+// или, если задан downloadURL, ссылкой на скачивание.
+func (s *NotificationService) SendExport(ctx context.Context, job *domain.ExportJob, content []byte, downloadURL string) error {
+	msg := mailer.Message{
+		To:      job.Email,
+		Subject: fmt.Sprintf("Выгрузка %s готова", job.FileName),
+	}
+	if downloadURL != "" {
+		msg.Body = fmt.Sprintf("Выгрузка %s готова. Скачать файл можно по ссылке до %s (UTC):\n\n%s\n",
+			job.FileName, job.ExpiresAt.UTC().Format("2006-01-02 15:04"), downloadURL)
+	} else {
+		msg.Body = fmt.Sprintf("Выгрузка %s во вложении.\n", job.FileName)
+		msg.Attachments = []mailer.Attachment{{Name: job.FileName, ContentType: mime.TypeByExtension(path.Ext(job.FileName)), Data: content}}
+	}
+
+	if err := s.mailer.Send(ctx, msg); err != nil {
+		return err
+	}
+
+	s.logger.InfoContext(ctx, "export sent",
+		slog.String("export_id", job.ID.String()),
+		slog.String("delivery", string(job.Delivery)),
+	)
+	return nil
+}
+
+// SendStatement отправляет ежемесячную выписку на почту из job: итоги по валютам
+// в тексте письма, строки по сервисам - CSV-файлом во вложении.
+func (s *NotificationService) SendStatement(ctx context.Context, job *domain.ExportJob, statement *domain.Statement, content []byte) error {
+	var body strings.Builder
+	fmt.Fprintf(&body, "Выписка по подпискам за %s.\n\n", statement.Month)
+	if len(statement.Totals) == 0 {
+		fmt.Fprintf(&body, "Списаний в этом месяце не было.\n")
+	}
+	for _, total := range statement.Totals {
+		fmt.Fprintf(&body, "Итого: %s (в %s: %s", total.Current, statement.PreviousMonth, total.Previous)
+		if total.ChangePercent != nil {
+			fmt.Fprintf(&body, ", %+.2f%%", *total.ChangePercent)
+		}
+		body.WriteString(")\n")
+	}
+	fmt.Fprintf(&body, "\nСписания по сервисам - в файле %s.\n", job.FileName)
+
+	msg := mailer.Message{
+		To:          job.Email,
+		Subject:     fmt.Sprintf("Выписка по подпискам за %s", statement.Month),
+		Body:        body.String(),
+		Attachments: []mailer.Attachment{{Name: job.FileName, ContentType: mime.TypeByExtension(path.Ext(job.FileName)), Data: content}},
+	}
+	if err := s.mailer.Send(ctx, msg); err != nil {
+		return err
+	}
+
+	s.logger.InfoContext(ctx, "statement sent",
+		slog.String("export_id", job.ID.String()),
+		slog.String("user_id", statement.UserID.String()),
+		slog.String("month", statement.Month),
+	)
+	return nil
+}
+
+// PublishStatement публикует выписку событием statement.monthly. ID события -
+// ID выгрузки выписки: повторная попытка доставки дает тот же Idempotency-Key.
+func (s *NotificationService) PublishStatement(ctx context.Context, id uuid.UUID, statement *domain.Statement) error {
+	event := events.Event{
+		ID:         id,
+		Type:       "statement.monthly",
+		OccurredAt: clock.Now(ctx),
+		Region:     region.Current(),
+		Data:       statement,
+	}
+	if err := s.publisher.Publish(ctx, event); err != nil {
+		return err
+	}
+
+	s.logger.InfoContext(ctx, "statement published",
+		slog.String("export_id", id.String()),
+		slog.String("user_id", statement.UserID.String()),
+		slog.String("month", statement.Month),
+	)
+	return nil
+}
+
+// SendAlertEscalation отправляет на адрес email неподтвержденное критическое
+// уведомление с данными исходного события.
+func (s *NotificationService) SendAlertEscalation(ctx context.Context, email string, alert *domain.Alert) error {
+	var body strings.Builder
+	fmt.Fprintf(&body, "Уведомление %s от %s (UTC) не подтверждено.\n\n", alert.Type, alert.CreatedAt.UTC().Format("2006-01-02 15:04"))
+	var data bytes.Buffer
+	if err := json.Indent(&data, alert.Data, "", "  "); err == nil {
+		fmt.Fprintf(&body, "%s\n\n", data.String())
+	}
+	fmt.Fprintf(&body, "Подтвердить: POST /api/v1/alerts/%s/acknowledge\n", alert.ID)
+
+	msg := mailer.Message{
+		To:      email,
+		Subject: fmt.Sprintf("Не подтверждено: %s", alert.Type),
+		Body:    body.String(),
+	}
+	if err := s.mailer.Send(ctx, msg); err != nil {
+		return err
+	}
+
+	s.logger.InfoContext(ctx, "alert escalation sent",
+		slog.String("alert_id", alert.ID.String()),
+		slog.Int("step", alert.Step+1),
+	)
+	return nil
+}
+
+// PublishAlertEscalation публикует событие alert.escalated. ID события зависит
+// от уведомления и шага: повторная попытка дает тот же Idempotency-Key.
+func (s *NotificationService) PublishAlertEscalation(ctx context.Context, escalation domain.AlertEscalation) error {
+	key := fmt.Sprintf("alert:%s:%d", escalation.AlertID, escalation.Step)
+	event := events.Event{
+		ID:         uuid.NewSHA1(uuid.NameSpaceURL, []byte(key)),
+		Type:       "alert.escalated",
+		OccurredAt: clock.Now(ctx),
+		Region:     region.Current(),
+		Data:       escalation,
+	}
+	if err := s.publisher.Publish(ctx, event); err != nil {
+		return err
+	}
+
+	s.logger.InfoContext(ctx, "alert escalation published",
+		slog.String("alert_id", escalation.AlertID.String()),
+		slog.Int("step", escalation.Step),
+	)
+	return nil
+}
+
+func (s *NotificationService) GetSettings(ctx context.Context, userID uuid.UUID) (*domain.NotificationSettings, error) {
+	return s.settings.Get(ctx, userID)
+}
+
+func (s *NotificationService) UpdateSettings(ctx context.Context, userID uuid.UUID, req domain.UpdateNotificationSettingsRequest) (*domain.NotificationSettings, error) {
+	if err := validateEscalation(req.Escalation); err != nil {
+		return nil, err
+	}
+
+	now := clock.Now(ctx)
+	settings := &domain.NotificationSettings{
+		UserID:           userID,
+		SpendAlerts:      req.SpendAlerts,
+		ThresholdPercent: req.ThresholdPercent,
+		StatementChannel: req.StatementChannel,
+		Escalation:       req.Escalation,
+		UpdatedAt:        &now,
+	}
+	if settings.StatementChannel == "" {
+		settings.StatementChannel = domain.StatementNone
+	}
+
+	if err := s.settings.Upsert(ctx, settings); err != nil {
+		s.logger.ErrorContext(ctx, "failed to save notification settings",
+			slog.String("user_id", userID.String()),
+			slog.String("error", err.Error()),
+		)
+		return nil, err
+	}
+
+	s.logger.InfoContext(ctx, "notification settings updated",
+		slog.String("user_id", userID.String()),
+		slog.Bool("spend_alerts", settings.SpendAlerts),
+		slog.String("statement_channel", string(settings.StatementChannel)),
+		slog.Int("escalation_steps", len(settings.Escalation)),
+	)
+
+	return settings, nil
+}
+
+// validateEscalation повторяет проверки привязки запроса для вызовов не из HTTP:
+// шаг без задержки эскалировал бы уведомление сразу на весь список получателей.
+func validateEscalation(steps []domain.EscalationStep) error {
+	if len(steps) > domain.MaxEscalationSteps {
+		return fmt.Errorf("%w: escalation: at most %d steps", ErrValidation, domain.MaxEscalationSteps)
+	}
+	for i, step := range steps {
+		if step.AfterHours < 1 {
+			return fmt.Errorf("%w: escalation[%d]: after_hours must be positive", ErrValidation, i)
+		}
+		if step.Channel != domain.AlertEmail && step.Channel != domain.AlertWebhook {
+			return fmt.Errorf("%w: escalation[%d]: channel must be email or webhook", ErrValidation, i)
+		}
+	}
+	return nil
+}
+
+// CompareSpend - задача планировщика. По понедельникам сравнивает траты за две последние
+// полные недели, первого числа - за два последних полных месяца, и публикует событие
+// spend.weekly_change / spend.monthly_change, если изменение превысило порог пользователя.
+// Обрабатываются только пользователи, включившие spend_alerts.
+func (s *NotificationService) CompareSpend(ctx context.Context, now time.Time) error {
+	now = now.UTC()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+
+	windows := make([]spendWindow, 0, 2)
+	if today.Weekday() == time.Monday {
+		windows = append(windows, lastSpendWindow(domain.SpendWeek, today))
+	}
+	if today.Day() == 1 {
+		windows = append(windows, lastSpendWindow(domain.SpendMonth, today))
+	}
+	if len(windows) == 0 {
+		return nil
+	}
+
+	users, err := s.settings.ListSpendAlertsEnabled(ctx)
+	if err != nil {
+		return err
+	}
+
+	published := 0
+	for _, settings := range users {
+		threshold := s.threshold(settings)
+
+		for _, w := range windows {
+			changes, err := s.spendChanges(ctx, settings.UserID, w.period, w.previous, w.start, w.end)
+			if err != nil {
+				// Ошибка по одному пользователю не должна останавливать рассылку остальным
+				s.logger.WarnContext(ctx, "failed to compare spend",
+					slog.String("user_id", settings.UserID.String()),
+					slog.String("period", string(w.period)),
+					slog.String("error", err.Error()),
+				)
+				continue
+			}
+			for _, change := range changes {
+				if math.Abs(change.ChangePercent) < float64(threshold) {
+					continue
+				}
+				change.ThresholdPercent = threshold
+
+				if err := s.publisher.Publish(ctx, spendChangeEvent(change, now)); err != nil {
+					s.logger.WarnContext(ctx, "failed to publish spend change",
+						slog.String("user_id", settings.UserID.String()),
+						slog.String("error", err.Error()),
+					)
+					continue
+				}
+				published++
+			}
+		}
+	}
+
+	s.logger.InfoContext(ctx, "spend comparison finished",
+		slog.Int("users", len(users)),
+		slog.Int("published", published),
+	)
+	return nil
+}
+
+// PreviewSpend повторяет CompareSpend для одного пользователя за последнюю полную
+// неделю или месяц до now, ничего не публикуя. Изменения ниже порога и пользователи
+// с выключенными spend_alerts тоже попадают в ответ - с причиной, по которой
+// уведомление не ушло бы.
+func (s *NotificationService) PreviewSpend(ctx context.Context, userID uuid.UUID, period domain.SpendPeriod, now time.Time) (*domain.NotificationPreview, error) {
+	now = now.UTC()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	w := lastSpendWindow(period, today)
+
+	settings, err := s.settings.Get(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	preview := &domain.NotificationPreview{
+		Type:          "spend." + periodAdjective(period) + "_change",
+		UserID:        userID,
+		At:            now,
+		Notifications: make([]domain.PreviewedNotification, 0),
+		Reasons:       make([]string, 0),
+	}
+	if !settings.SpendAlerts {
+		preview.Reasons = append(preview.Reasons, "spend alerts are disabled in notification settings")
+	}
+
+	changes, err := s.spendChanges(ctx, userID, w.period, w.previous, w.start, w.end)
+	if err != nil {
+		return nil, err
+	}
+	if len(changes) == 0 {
+		preview.Reasons = append(preview.Reasons, fmt.Sprintf("no spending in the previous %s starting %s to compare with",
+			period, w.previous.Format(domain.CalendarDateLayout)))
+	}
+
+	threshold := s.threshold(settings)
+	for _, change := range changes {
+		change.ThresholdPercent = threshold
+		notification := domain.PreviewedNotification{Event: spendChangeEvent(change, now), Send: settings.SpendAlerts}
+		if math.Abs(change.ChangePercent) < float64(threshold) {
+			notification.Send = false
+			notification.Reason = fmt.Sprintf("%s spend changed by %.2f%%, below the %d%% threshold",
+				change.Currency, change.ChangePercent, threshold)
+		}
+		preview.Notifications = append(preview.Notifications, notification)
+	}
+	preview.Summarize()
+	return preview, nil
+}
+
+// threshold - порог изменения трат пользователя в процентах.
+func (s *NotificationService) threshold(settings *domain.NotificationSettings) int {
+	if settings.ThresholdPercent != nil {
+		return *settings.ThresholdPercent
+	}
+	return s.defaultThreshold
+}
+
+// spendWindow - сравниваемые периоды: [previous, start) и [start, end).
+type spendWindow struct {
+	period               domain.SpendPeriod
+	previous, start, end time.Time
+}
+
+// lastSpendWindow - последние две полные недели (с понедельника) или два полных
+// месяца, закончившиеся не позже today.
+func lastSpendWindow(period domain.SpendPeriod, today time.Time) spendWindow {
+	if period == domain.SpendWeek {
+		end := today.AddDate(0, 0, -int(today.Weekday()+6)%7)
+		return spendWindow{period, end.AddDate(0, 0, -14), end.AddDate(0, 0, -7), end}
+	}
+	end := time.Date(today.Year(), today.Month(), 1, 0, 0, 0, 0, time.UTC)
+	return spendWindow{period, end.AddDate(0, -2, 0), end.AddDate(0, -1, 0), end}
+}
+
+// spendChanges сравнивает траты за [previous, start) и [start, end) отдельно по каждой валюте.
+// Валюты, в которых в предыдущем периоде трат не было, пропускаются: сравнивать не с чем.
+func (s *NotificationService) spendChanges(ctx context.Context, userID uuid.UUID, period domain.SpendPeriod, previous, start, end time.Time) ([]*domain.SpendChange, error) {
+	subs, err := s.subs.ListHistory(ctx, domain.CalculateTotalRequest{
+		UserID:    &userID,
+		EndPeriod: domain.FormatPeriod(end.AddDate(0, 0, -1)),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	changes, err := statusChangesBySubscription(ctx, s.subs, subs)
+	if err != nil {
+		return nil, err
+	}
+
+	before, err := domain.ChargesBetween(subs, changes, previous, start)
+	if err != nil {
+		return nil, err
+	}
+	current, err := domain.ChargesBetween(subs, changes, start, end)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*domain.SpendChange, 0, len(before))
+	for _, prev := range before.List() {
+		if prev.Amount == 0 {
+			continue
+		}
+		cur := current.Get(prev.Currency)
+		percent := float64(cur.Amount-prev.Amount) / float64(prev.Amount) * 100
+		result = append(result, &domain.SpendChange{
+			UserID:         userID,
+			Period:         period,
+			PreviousStart:  previous.Format(domain.CalendarDateLayout),
+			CurrentStart:   start.Format(domain.CalendarDateLayout),
+			Previous:       prev.Major(),
+			Current:        cur.Major(),
+			Currency:       prev.Currency,
+			PreviousAmount: prev,
+			CurrentAmount:  cur,
+			ChangePercent:  math.Round(percent*100) / 100,
+		})
+	}
+	return result, nil
+}
+
+// spendChangeEvent строит событие с детерминированным ID: повторный запуск задачи
+// за тот же период дает тот же Idempotency-Key, и потребитель может отбросить дубль.
+func spendChangeEvent(change *domain.SpendChange, now time.Time) events.Event {
+	key := fmt.Sprintf("spend:%s:%s:%s", change.UserID, change.Period, change.CurrentStart)
+	// Ключ событий в валюте по умолчанию не меняется, чтобы не сломать дедупликацию у потребителей
+	if change.Currency != domain.DefaultCurrency {
+		key += ":" + string(change.Currency)
+	}
+	return events.Event{
+		ID:         uuid.NewSHA1(uuid.NameSpaceURL, []byte(key)),
+		Type:       "spend." + periodAdjective(change.Period) + "_change",
+		OccurredAt: now,
+		Region:     region.Current(),
+		Data:       change,
+	}
+}
+
+func periodAdjective(period domain.SpendPeriod) string {
+	if period == domain.SpendWeek {
+		return "weekly"
+	}
+	return "monthly"
+}
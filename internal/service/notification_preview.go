@@ -0,0 +1,43 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"aggregator_db/internal/clock"
+	"aggregator_db/internal/repository/postgres"
+	"aggregator_db/pkg/domain"
+)
+
+// NotificationPreviewService показывает, какие уведомления получил бы пользователь
+// при следующем запуске задач планировщика, ничего не отправляя.
+type NotificationPreviewService struct {
+	users         postgres.UserRepository
+	notifications *NotificationService
+	budgets       *BudgetService
+}
+
+func NewNotificationPreviewService(users postgres.UserRepository, notifications *NotificationService, budgets *BudgetService) *NotificationPreviewService {
+	return &NotificationPreviewService{users: users, notifications: notifications, budgets: budgets}
+}
+
+// Preview строит уведомление req.Type для пользователя на текущих данных. Изменения
+// трат сравниваются за последнюю полную неделю или месяц, конверты - за текущий месяц.
+func (s *NotificationPreviewService) Preview(ctx context.Context, req domain.NotificationPreviewRequest) (*domain.NotificationPreview, error) {
+	if _, err := s.users.GetByID(ctx, req.UserID); err != nil {
+		return nil, err
+	}
+	now := clock.Now(ctx)
+
+	switch req.Type {
+	case "spend.weekly_change":
+		return s.notifications.PreviewSpend(ctx, req.UserID, domain.SpendWeek, now)
+	case "spend.monthly_change":
+		return s.notifications.PreviewSpend(ctx, req.UserID, domain.SpendMonth, now)
+	case "budget.warning", "budget.exceeded":
+		return s.budgets.PreviewAlerts(ctx, req.UserID, domain.BudgetState(strings.TrimPrefix(req.Type, "budget.")), now)
+	default:
+		return nil, fmt.Errorf("%w: type: unsupported notification type %q", ErrValidation, req.Type)
+	}
+}
@@ -0,0 +1,166 @@
+package service
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"sync"
+	"testing"
+	"time"
+
+	"aggregator_db/internal/repository/memory"
+	"aggregator_db/pkg/domain"
+	"aggregator_db/pkg/events"
+	"aggregator_db/pkg/mailer"
+	"github.com/google/uuid"
+)
+
+type recordingPublisher struct {
+	mu     sync.Mutex
+	events []events.Event
+}
+
+func (p *recordingPublisher) Publish(_ context.Context, event events.Event) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.events = append(p.events, event)
+	return nil
+}
+
+func TestCompareSpend(t *testing.T) {
+	ctx := context.Background()
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	subs := memory.NewSubscriptionRepository()
+	settings := memory.NewNotificationSettingsRepository()
+
+	optedIn, silent := uuid.New(), uuid.New()
+	for _, sub := range []*domain.Subscription{
+		{ID: uuid.New(), UserID: optedIn, ServiceName: "Yandex Plus", Price: domain.NewMoney(40000, domain.DefaultCurrency), StartDate: "01-2025", CreatedAt: time.Date(2025, 1, 15, 9, 0, 0, 0, time.UTC)},
+		{ID: uuid.New(), UserID: optedIn, ServiceName: "Netflix", Price: domain.NewMoney(90000, domain.DefaultCurrency), StartDate: "07-2025", CreatedAt: time.Date(2025, 7, 10, 9, 0, 0, 0, time.UTC)},
+		{ID: uuid.New(), UserID: silent, ServiceName: "Netflix", Price: domain.NewMoney(90000, domain.DefaultCurrency), StartDate: "07-2025", CreatedAt: time.Date(2025, 7, 10, 9, 0, 0, 0, time.UTC)},
+	} {
+		if err := subs.Create(ctx, sub); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := settings.Upsert(ctx, &domain.NotificationSettings{UserID: optedIn, SpendAlerts: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		name    string
+		now     time.Time
+		typ     string
+		percent float64
+	}{
+		// Июнь 400 -> июль 1300
+		{name: "monthly", now: time.Date(2025, 8, 1, 3, 0, 0, 0, time.UTC), typ: "spend.monthly_change", percent: 225},
+		// Неделя 07.07-13.07: Netflix 900, неделя 14.07-20.07: Yandex Plus 400
+		{name: "weekly", now: time.Date(2025, 7, 21, 3, 0, 0, 0, time.UTC), typ: "spend.weekly_change", percent: -55.56},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			publisher := &recordingPublisher{}
+			svc := NewNotificationService(subs, settings, publisher, mailer.NewLogSender(logger), 20, logger)
+
+			if err := svc.CompareSpend(ctx, tc.now); err != nil {
+				t.Fatal(err)
+			}
+			if len(publisher.events) != 1 {
+				t.Fatalf("got %d events, want 1: %+v", len(publisher.events), publisher.events)
+			}
+
+			event := publisher.events[0]
+			change := event.Data.(*domain.SpendChange)
+			if event.Type != tc.typ || change.UserID != optedIn || change.ChangePercent != tc.percent {
+				t.Errorf("got %s for %s with %.2f%%, want %s for %s with %.2f%%",
+					event.Type, change.UserID, change.ChangePercent, tc.typ, optedIn, tc.percent)
+			}
+
+			// Повторный запуск за тот же период дает тот же ID события
+			if err := svc.CompareSpend(ctx, tc.now); err != nil {
+				t.Fatal(err)
+			}
+			if publisher.events[1].ID != event.ID {
+				t.Errorf("event id changed between runs: %s != %s", publisher.events[1].ID, event.ID)
+			}
+		})
+	}
+
+	t.Run("no comparison due", func(t *testing.T) {
+		publisher := &recordingPublisher{}
+		svc := NewNotificationService(subs, settings, publisher, mailer.NewLogSender(logger), 20, logger)
+		if err := svc.CompareSpend(ctx, time.Date(2025, 7, 23, 3, 0, 0, 0, time.UTC)); err != nil {
+			t.Fatal(err)
+		}
+		if len(publisher.events) != 0 {
+			t.Errorf("expected no events on a regular day, got %d", len(publisher.events))
+		}
+	})
+}
+
+func TestPreviewSpend(t *testing.T) {
+	ctx := context.Background()
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	subs := memory.NewSubscriptionRepository()
+	settings := memory.NewNotificationSettingsRepository()
+
+	optedIn, silent := uuid.New(), uuid.New()
+	for _, sub := range []*domain.Subscription{
+		{ID: uuid.New(), UserID: optedIn, ServiceName: "Yandex Plus", Price: domain.NewMoney(40000, domain.DefaultCurrency), StartDate: "01-2025", CreatedAt: time.Date(2025, 1, 15, 9, 0, 0, 0, time.UTC)},
+		{ID: uuid.New(), UserID: optedIn, ServiceName: "Netflix", Price: domain.NewMoney(90000, domain.DefaultCurrency), StartDate: "07-2025", CreatedAt: time.Date(2025, 7, 10, 9, 0, 0, 0, time.UTC)},
+		{ID: uuid.New(), UserID: silent, ServiceName: "Netflix", Price: domain.NewMoney(90000, domain.DefaultCurrency), StartDate: "07-2025", CreatedAt: time.Date(2025, 7, 10, 9, 0, 0, 0, time.UTC)},
+	} {
+		if err := subs.Create(ctx, sub); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := settings.Upsert(ctx, &domain.NotificationSettings{UserID: optedIn, SpendAlerts: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	publisher := &recordingPublisher{}
+	svc := NewNotificationService(subs, settings, publisher, mailer.NewLogSender(logger), 20, logger)
+
+	t.Run("matches scheduled run", func(t *testing.T) {
+		// Среда после понедельника 21.07 сравнивает те же недели, что и задача
+		preview, err := svc.PreviewSpend(ctx, optedIn, domain.SpendWeek, time.Date(2025, 7, 23, 12, 0, 0, 0, time.UTC))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := svc.CompareSpend(ctx, time.Date(2025, 7, 21, 3, 0, 0, 0, time.UTC)); err != nil {
+			t.Fatal(err)
+		}
+		if !preview.WouldSend || len(preview.Notifications) != 1 || len(publisher.events) != 1 {
+			t.Fatalf("got preview %+v and %d published events", preview, len(publisher.events))
+		}
+		if event := preview.Notifications[0].Event.(events.Event); event.ID != publisher.events[0].ID {
+			t.Errorf("preview event id %s differs from published %s", event.ID, publisher.events[0].ID)
+		}
+	})
+
+	t.Run("alerts disabled", func(t *testing.T) {
+		preview, err := svc.PreviewSpend(ctx, silent, domain.SpendWeek, time.Date(2025, 7, 23, 12, 0, 0, 0, time.UTC))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if preview.WouldSend || len(preview.Notifications) != 1 || preview.Notifications[0].Send {
+			t.Errorf("expected a candidate that is not sent, got %+v", preview)
+		}
+		if len(preview.Reasons) != 1 {
+			t.Errorf("expected a reason for disabled alerts, got %v", preview.Reasons)
+		}
+	})
+
+	t.Run("nothing to compare", func(t *testing.T) {
+		// В июне у пользователя трат не было
+		preview, err := svc.PreviewSpend(ctx, silent, domain.SpendMonth, time.Date(2025, 8, 15, 12, 0, 0, 0, time.UTC))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if preview.WouldSend || len(preview.Notifications) != 0 || len(preview.Reasons) != 2 {
+			t.Errorf("expected no candidates with two reasons, got %+v", preview)
+		}
+	})
+}
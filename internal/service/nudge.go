@@ -0,0 +1,144 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"aggregator_db/internal/clock"
+	"aggregator_db/internal/repository/postgres"
+	"aggregator_db/internal/tenancy"
+	"aggregator_db/pkg/domain"
+	"github.com/google/uuid"
+)
+
+// NudgeService проверяет правила по данным основной базы и каждого тенанта
+// и выдает администраторам подсказки.
+type NudgeService struct {
+	repo  postgres.NudgeRepository
+	subs  postgres.SubscriptionRepository
+	rules domain.NudgeRules
+	// tenants - реестр тенантов; без него проверяется только основная база
+	tenants postgres.TenantRepository
+	logger  *slog.Logger
+}
+
+func NewNudgeService(repo postgres.NudgeRepository, subs postgres.SubscriptionRepository, tenants postgres.TenantRepository, rules domain.NudgeRules, logger *slog.Logger) *NudgeService {
+	return &NudgeService{
+		repo:    repo,
+		subs:    subs,
+		rules:   rules,
+		tenants: tenants,
+		logger:  logger,
+	}
+}
+
+// Evaluate - задача планировщика. Проверяет включенные правила за текущий месяц в основной
+// базе и в базах активных тенантов. Подсказка с уже выданным ключом не создается повторно.
+func (s *NudgeService) Evaluate(ctx context.Context, now time.Time) error {
+	now = now.UTC()
+	month := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+
+	scopes := []*domain.Tenant{nil}
+	if s.tenants != nil {
+		tenants, err := s.tenants.List(ctx)
+		if err != nil {
+			return err
+		}
+		for _, tenant := range tenants {
+			if tenant.Status == domain.TenantStatusActive {
+				scopes = append(scopes, tenant)
+			}
+		}
+	}
+
+	created := 0
+	for _, tenant := range scopes {
+		var tenantID *string
+		scopeCtx, scopeName := ctx, "default"
+		if tenant != nil {
+			tenantID = &tenant.ID
+			scopeCtx, scopeName = tenancy.WithTenant(ctx, tenant), tenant.ID
+		}
+
+		nudges, err := s.evaluateScope(scopeCtx, tenantID, month)
+		if err != nil {
+			// Недоступная база одного тенанта не должна останавливать проверку остальных
+			s.logger.WarnContext(ctx, "failed to evaluate nudge rules",
+				slog.String("tenant_id", scopeName),
+				slog.String("error", err.Error()),
+			)
+			continue
+		}
+
+		for _, nudge := range nudges {
+			nudge.ID = uuid.New()
+			nudge.CreatedAt = now
+			ok, err := s.repo.Create(ctx, nudge)
+			if err != nil {
+				return err
+			}
+			if ok {
+				created++
+			}
+		}
+	}
+
+	s.logger.InfoContext(ctx, "nudge evaluation finished",
+		slog.Int("scopes", len(scopes)),
+		slog.Int("created", created),
+	)
+	return nil
+}
+
+func (s *NudgeService) evaluateScope(ctx context.Context, tenantID *string, month time.Time) ([]*domain.Nudge, error) {
+	// Все подписки, включая начинающиеся в далеком будущем: их ищет правило irregular_data
+	subs, err := s.subs.ListHistory(ctx, domain.CalculateTotalRequest{
+		EndPeriod: domain.FormatPeriod(month.AddDate(100, 0, 0)),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	nudges := make([]*domain.Nudge, 0)
+	if s.rules.Has(domain.NudgeRisingChurn) {
+		ids := make([]uuid.UUID, len(subs))
+		for i, sub := range subs {
+			ids[i] = sub.ID
+		}
+		changes, err := s.subs.ListStatusChanges(ctx, ids)
+		if err != nil {
+			return nil, err
+		}
+
+		current, previous := domain.FormatPeriod(month), domain.FormatPeriod(month.AddDate(0, -1, 0))
+		churn := s.rules.ChurnNudge(tenantID, current, previous,
+			domain.CountCancellations(changes, current), domain.CountCancellations(changes, previous))
+		if churn != nil {
+			nudges = append(nudges, churn)
+		}
+	}
+	if s.rules.Has(domain.NudgeIrregularData) {
+		irregular, err := s.rules.IrregularDataNudges(tenantID, subs, month)
+		if err != nil {
+			return nil, err
+		}
+		nudges = append(nudges, irregular...)
+	}
+	return nudges, nil
+}
+
+func (s *NudgeService) List(ctx context.Context, query domain.ListNudgesQuery) ([]*domain.Nudge, error) {
+	return s.repo.List(ctx, query)
+}
+
+// Dismiss скрывает подсказку из списка; по тому же ключу она больше не появится.
+func (s *NudgeService) Dismiss(ctx context.Context, id uuid.UUID) (*domain.Nudge, error) {
+	nudge, err := s.repo.Dismiss(ctx, id, clock.Now(ctx))
+	if err != nil {
+		return nil, err
+	}
+
+	s.logger.InfoContext(ctx, "nudge dismissed", slog.String("id", id.String()))
+	return nudge, nil
+}
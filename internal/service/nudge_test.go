@@ -0,0 +1,77 @@
+package service
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"aggregator_db/internal/repository/memory"
+	"aggregator_db/pkg/domain"
+	"github.com/google/uuid"
+)
+
+func TestNudgeService(t *testing.T) {
+	ctx := context.Background()
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	repo := memory.NewSubscriptionRepository()
+	now := time.Date(2025, 10, 15, 3, 0, 0, 0, time.UTC)
+
+	userID := uuid.New()
+	for i := 0; i < 3; i++ {
+		sub := &domain.Subscription{ID: uuid.New(), UserID: uuid.New(), ServiceName: "Netflix", Price: domain.NewMoney(79900, domain.DefaultCurrency), StartDate: "01-2025"}
+		if err := repo.Create(ctx, sub); err != nil {
+			t.Fatal(err)
+		}
+		if err := repo.ChangeStatus(ctx, &domain.StatusChange{SubscriptionID: sub.ID, Status: domain.StatusCancelled, EffectiveFrom: "10-2025", ChangedAt: now}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := repo.Create(ctx, &domain.Subscription{ID: uuid.New(), UserID: userID, ServiceName: "Trial", Price: domain.NewMoney(0, domain.DefaultCurrency), StartDate: "09-2025"}); err != nil {
+		t.Fatal(err)
+	}
+
+	nudges := memory.NewNudgeRepository()
+	rules := domain.NudgeRules{Enabled: domain.NudgeKinds, ChurnMinCancellations: 3, ChurnRisePercent: 50, FutureStartMonths: 12}
+	svc := NewNudgeService(nudges, repo, nil, rules, logger)
+
+	// Повторный запуск в том же месяце не дублирует подсказки
+	for i := 0; i < 2; i++ {
+		if err := svc.Evaluate(ctx, now); err != nil {
+			t.Fatal(err)
+		}
+	}
+	list, err := svc.List(ctx, domain.ListNudgesQuery{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 2 {
+		t.Fatalf("got %d nudges, want 2", len(list))
+	}
+	kinds := map[domain.NudgeKind]*domain.Nudge{}
+	for _, nudge := range list {
+		kinds[nudge.Kind] = nudge
+	}
+	if churn := kinds[domain.NudgeRisingChurn]; churn == nil || churn.Key != "rising_churn:default:10-2025" {
+		t.Errorf("churn nudge = %+v", churn)
+	}
+	irregular := kinds[domain.NudgeIrregularData]
+	if irregular == nil || *irregular.UserID != userID {
+		t.Fatalf("irregular data nudge = %+v", irregular)
+	}
+
+	// Скрытая подсказка не возвращается и не создается заново
+	if _, err := svc.Dismiss(ctx, irregular.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := svc.Evaluate(ctx, now); err != nil {
+		t.Fatal(err)
+	}
+	if list, _ := svc.List(ctx, domain.ListNudgesQuery{}); len(list) != 1 {
+		t.Errorf("got %d active nudges after dismiss, want 1", len(list))
+	}
+	if list, _ := svc.List(ctx, domain.ListNudgesQuery{IncludeDismissed: true}); len(list) != 2 {
+		t.Errorf("got %d nudges with dismissed, want 2", len(list))
+	}
+}
@@ -0,0 +1,73 @@
+package service
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"aggregator_db/internal/clock"
+	"aggregator_db/internal/exchange"
+	"aggregator_db/internal/repository/memory"
+	"aggregator_db/internal/tenancy"
+	"aggregator_db/pkg/domain"
+	"github.com/google/uuid"
+)
+
+func TestOpenEndedPolicy(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	ctx := clock.WithClock(context.Background(), clock.Frozen{At: time.Date(2025, 10, 15, 12, 0, 0, 0, time.UTC)})
+	repo := memory.NewSubscriptionRepository()
+	svc := NewSubscriptionService(repo, memory.NewTransactor(), memory.NewServiceAliasRepository(), &recordingPublisher{}, exchange.NewStaticProvider(domain.DefaultCurrency, nil), logger)
+
+	end := "11-2025"
+	for _, sub := range []*domain.Subscription{
+		{ID: uuid.New(), UserID: uuid.New(), ServiceName: "Netflix", Price: domain.NewMoney(10000, domain.DefaultCurrency), StartDate: "09-2025"},
+		{ID: uuid.New(), UserID: uuid.New(), ServiceName: "Spotify", Price: domain.NewMoney(1000, domain.DefaultCurrency), StartDate: "09-2025", EndDate: &end},
+	} {
+		if err := repo.Create(ctx, sub); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	months := 1
+	tests := []struct {
+		name      string
+		policy    *domain.OpenEndedPolicy
+		total     int64
+		breakdown []int64
+	}{
+		// 09-2025 - 12-2025: бессрочная подписка все 4 месяца, вторая - 3 месяца
+		{name: "default", total: 43000, breakdown: []int64{11000, 11000, 11000, 10000}},
+		{name: "current month", policy: &domain.OpenEndedPolicy{Total: domain.OpenEndedToCurrentMonth}, total: 23000, breakdown: []int64{11000, 11000, 11000, 10000}},
+		{name: "forecast horizon", policy: &domain.OpenEndedPolicy{ForecastMonths: &months}, total: 43000, breakdown: []int64{11000, 11000, 11000, 0}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tenantCtx := tenancy.WithTenant(ctx, &domain.Tenant{ID: "acme", OpenEnded: tt.policy})
+			req := domain.CalculateTotalRequest{StartPeriod: "09-2025", EndPeriod: "12-2025"}
+
+			resp, err := svc.CalculateTotal(tenantCtx, req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resp.TotalCost.Amount != tt.total {
+				t.Errorf("total = %d, want %d", resp.TotalCost.Amount, tt.total)
+			}
+
+			breakdown, err := svc.CalculateBreakdown(tenantCtx, domain.CalculateBreakdownRequest{CalculateTotalRequest: req, Limit: 12})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(breakdown.Months) != len(tt.breakdown) {
+				t.Fatalf("got %d months, want %d", len(breakdown.Months), len(tt.breakdown))
+			}
+			for i, month := range breakdown.Months {
+				if month.TotalCost.Amount != tt.breakdown[i] {
+					t.Errorf("%s = %d, want %d", month.Month, month.TotalCost.Amount, tt.breakdown[i])
+				}
+			}
+		})
+	}
+}
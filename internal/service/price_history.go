@@ -0,0 +1,30 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+
+	"aggregator_db/pkg/domain"
+	"github.com/google/uuid"
+)
+
+// PriceHistory возвращает цену подписки во времени для графика с отметками скидок.
+func (s *SubscriptionService) PriceHistory(ctx context.Context, id uuid.UUID) (*domain.PriceHistoryResponse, error) {
+	if _, err := s.repo.GetByID(ctx, id); err != nil {
+		return nil, err
+	}
+
+	changes, err := s.repo.ListPriceHistory(ctx, id)
+	if err != nil {
+		s.logger.ErrorContext(ctx, "failed to load price history",
+			slog.String("id", id.String()),
+			slog.String("error", err.Error()),
+		)
+		return nil, err
+	}
+	discounts, err := s.repo.ListDiscounts(ctx, []uuid.UUID{id})
+	if err != nil {
+		return nil, err
+	}
+	return domain.NewPriceHistory(id, changes, discounts)
+}
@@ -0,0 +1,35 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"aggregator_db/internal/tenancy"
+	"aggregator_db/pkg/domain"
+)
+
+var ErrQuotaExceeded = errors.New("quota exceeded")
+
+// checkSubscriptionQuota проверяет, что тенант запроса может создать еще adding подписок.
+// Вызывается в единице работы вместе со вставкой: блокировка квоты тенанта держится
+// до коммита, поэтому параллельные запросы не превысят лимит.
+func (s *SubscriptionService) checkSubscriptionQuota(ctx context.Context, adding int) error {
+	tenant := tenancy.FromContext(ctx)
+	if tenant == nil || tenant.Quotas.MaxSubscriptions == nil {
+		return nil
+	}
+	if err := s.tx.Lock(ctx, "subscription_quota:"+tenant.ID); err != nil {
+		return err
+	}
+
+	count, err := s.repo.Count(ctx, domain.ListSubscriptionsQuery{})
+	if err != nil {
+		return err
+	}
+
+	if limit := *tenant.Quotas.MaxSubscriptions; count+adding > limit {
+		return fmt.Errorf("%w: tenant %s is limited to %d subscriptions", ErrQuotaExceeded, tenant.ID, limit)
+	}
+	return nil
+}
@@ -0,0 +1,54 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"aggregator_db/pkg/domain"
+)
+
+// ReadinessCheck - проверка зависимости для /readyz. Required - без нее реплика
+// не может обслуживать запросы.
+type ReadinessCheck struct {
+	Name     string
+	Required bool
+	Check    func(ctx context.Context) error
+}
+
+// ReadinessService проверяет зависимости реплики, чтобы балансировщик
+// (readinessProbe Kubernetes) не направлял трафик на реплику с отказавшей базой.
+type ReadinessService struct {
+	checks  []ReadinessCheck
+	timeout time.Duration
+}
+
+// NewReadinessService создает сервис; timeout ограничивает каждую проверку, чтобы
+// зависшее соединение отвечало отказом, а не таймаутом пробы.
+func NewReadinessService(timeout time.Duration, checks ...ReadinessCheck) *ReadinessService {
+	return &ReadinessService{checks: checks, timeout: timeout}
+}
+
+// Ready выполняет проверки параллельно; порядок зависимостей совпадает с порядком проверок.
+func (s *ReadinessService) Ready(ctx context.Context) *domain.Readiness {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	dependencies := make([]domain.DependencyStatus, len(s.checks))
+	var wg sync.WaitGroup
+	for i, check := range s.checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			dependency := domain.DependencyStatus{Name: check.Name, Status: domain.ProbeOK, Required: check.Required}
+			if err := check.Check(ctx); err != nil {
+				dependency.Status = domain.ProbeFail
+				dependency.Error = err.Error()
+			}
+			dependencies[i] = dependency
+		}()
+	}
+	wg.Wait()
+
+	return domain.NewReadiness(dependencies)
+}
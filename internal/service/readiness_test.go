@@ -0,0 +1,38 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"aggregator_db/pkg/domain"
+)
+
+func TestReadinessService(t *testing.T) {
+	hang := func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+	ok := func(context.Context) error { return nil }
+
+	svc := NewReadinessService(20*time.Millisecond,
+		ReadinessCheck{Name: "database", Required: true, Check: hang},
+		ReadinessCheck{Name: "exchange_rates", Check: ok},
+	)
+	readiness := svc.Ready(context.Background())
+	if readiness.Status != domain.ProbeFail {
+		t.Errorf("status = %s, want fail", readiness.Status)
+	}
+	if db := readiness.Dependencies[0]; db.Status != domain.ProbeFail || db.Error != context.DeadlineExceeded.Error() {
+		t.Errorf("database = %+v", db)
+	}
+
+	// Отказ необязательной зависимости не снимает реплику с трафика
+	svc = NewReadinessService(time.Second,
+		ReadinessCheck{Name: "database", Required: true, Check: ok},
+		ReadinessCheck{Name: "exchange_rates", Check: hang},
+	)
+	if readiness := svc.Ready(context.Background()); readiness.Status != domain.ProbeOK {
+		t.Errorf("status = %s, want ok", readiness.Status)
+	}
+}
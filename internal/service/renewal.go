@@ -0,0 +1,90 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"aggregator_db/internal/repository/postgres"
+	"aggregator_db/pkg/domain"
+	"aggregator_db/pkg/events"
+	"aggregator_db/pkg/region"
+	"github.com/google/uuid"
+)
+
+// RenewSubscriptions - задача планировщика. В последний день месяца продлевает на месяц
+// активные подписки с auto_renew, у которых end_date - текущий месяц, и публикует
+// subscription.renewed. Подписки, пропущенные в прошлом месяце (сервис не работал
+// в последний день), догоняются; более старые не трогаются.
+func (s *SubscriptionService) RenewSubscriptions(ctx context.Context, now time.Time) error {
+	now = now.UTC()
+	month := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+
+	subs, err := s.repo.ListRenewable(ctx, domain.FormatPeriod(month.AddDate(0, -1, 0)), domain.FormatPeriod(month))
+	if err != nil {
+		return err
+	}
+
+	renewed := 0
+	for _, sub := range subs {
+		endDates, err := domain.RenewalEndDates(*sub.EndDate, now)
+		if err != nil {
+			return err
+		}
+
+		previous := *sub.EndDate
+		for _, endDate := range endDates {
+			err := s.repo.Renew(ctx, sub.ID, previous, endDate, now)
+			if errors.Is(err, postgres.ErrNotFound) {
+				// Подписку изменили или продлил другой экземпляр - пропускаем
+				break
+			}
+			if err != nil {
+				s.logger.WarnContext(ctx, "failed to renew subscription",
+					slog.String("id", sub.ID.String()),
+					slog.String("error", err.Error()),
+				)
+				break
+			}
+
+			renewal := &domain.SubscriptionRenewal{
+				SubscriptionID:  sub.ID,
+				UserID:          sub.UserID,
+				ServiceName:     sub.ServiceName,
+				Price:           sub.Price.Major(),
+				PriceAmount:     sub.Price,
+				PreviousEndDate: previous,
+				EndDate:         endDate,
+			}
+			if err := s.publisher.Publish(ctx, renewalEvent(renewal, now)); err != nil {
+				s.logger.WarnContext(ctx, "failed to publish renewal",
+					slog.String("id", sub.ID.String()),
+					slog.String("error", err.Error()),
+				)
+			}
+
+			previous = endDate
+			renewed++
+		}
+	}
+
+	s.logger.InfoContext(ctx, "subscription renewal finished",
+		slog.Int("candidates", len(subs)),
+		slog.Int("renewed", renewed),
+	)
+	return nil
+}
+
+// renewalEvent строит событие с ID, детерминированным по подписке и новому end_date.
+func renewalEvent(renewal *domain.SubscriptionRenewal, now time.Time) events.Event {
+	key := fmt.Sprintf("renewal:%s:%s", renewal.SubscriptionID, renewal.EndDate)
+	return events.Event{
+		ID:         uuid.NewSHA1(uuid.NameSpaceURL, []byte(key)),
+		Type:       "subscription.renewed",
+		OccurredAt: now,
+		Region:     region.Current(),
+		Data:       renewal,
+	}
+}
@@ -0,0 +1,75 @@
+package service
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"aggregator_db/internal/exchange"
+	"aggregator_db/internal/repository/memory"
+	"aggregator_db/pkg/domain"
+	"github.com/google/uuid"
+)
+
+func TestRenewSubscriptions(t *testing.T) {
+	ctx := context.Background()
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	repo := memory.NewSubscriptionRepository()
+
+	period := func(s string) *string { return &s }
+	current := &domain.Subscription{ID: uuid.New(), ServiceName: "Netflix", Price: domain.NewMoney(90000, domain.DefaultCurrency), StartDate: "01-2025", EndDate: period("09-2025"), AutoRenew: true}
+	missed := &domain.Subscription{ID: uuid.New(), ServiceName: "Spotify", Price: domain.NewMoney(30000, domain.DefaultCurrency), StartDate: "01-2025", EndDate: period("08-2025"), AutoRenew: true}
+	stale := &domain.Subscription{ID: uuid.New(), ServiceName: "Ivi", Price: domain.NewMoney(29900, domain.DefaultCurrency), StartDate: "01-2024", EndDate: period("12-2024"), AutoRenew: true}
+	manual := &domain.Subscription{ID: uuid.New(), ServiceName: "Yandex Plus", Price: domain.NewMoney(40000, domain.DefaultCurrency), StartDate: "01-2025", EndDate: period("09-2025")}
+	for _, sub := range []*domain.Subscription{current, missed, stale, manual} {
+		if err := repo.Create(ctx, sub); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	publisher := &recordingPublisher{}
+	svc := NewSubscriptionService(repo, memory.NewTransactor(), memory.NewServiceAliasRepository(), publisher, exchange.NewStaticProvider(domain.DefaultCurrency, nil), logger)
+
+	// Не последний день месяца: продлевается только пропущенная в августе подписка
+	if err := svc.RenewSubscriptions(ctx, time.Date(2025, 9, 15, 3, 0, 0, 0, time.UTC)); err != nil {
+		t.Fatal(err)
+	}
+	if len(publisher.events) != 1 {
+		t.Fatalf("got %d events mid-month, want 1", len(publisher.events))
+	}
+
+	// Последний день сентября: обе подписки уходят на октябрь
+	if err := svc.RenewSubscriptions(ctx, time.Date(2025, 9, 30, 3, 0, 0, 0, time.UTC)); err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[uuid.UUID]string{current.ID: "10-2025", missed.ID: "10-2025", stale.ID: "12-2024", manual.ID: "09-2025"}
+	for id, end := range want {
+		sub, err := repo.GetByID(ctx, id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if *sub.EndDate != end {
+			t.Errorf("%s: end_date = %s, want %s", sub.ServiceName, *sub.EndDate, end)
+		}
+	}
+
+	if len(publisher.events) != 3 {
+		t.Fatalf("got %d events, want 3", len(publisher.events))
+	}
+	for _, event := range publisher.events {
+		if event.Type != "subscription.renewed" {
+			t.Errorf("unexpected event type %s", event.Type)
+		}
+	}
+
+	// Повторный запуск в тот же день ничего не продлевает
+	if err := svc.RenewSubscriptions(ctx, time.Date(2025, 9, 30, 9, 0, 0, 0, time.UTC)); err != nil {
+		t.Fatal(err)
+	}
+	if len(publisher.events) != 3 {
+		t.Errorf("rerun published %d extra events", len(publisher.events)-3)
+	}
+}
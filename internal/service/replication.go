@@ -0,0 +1,90 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"aggregator_db/internal/repository/postgres"
+	"aggregator_db/pkg/domain"
+	"aggregator_db/pkg/region"
+	"github.com/google/uuid"
+)
+
+// ReplicationService применяет изменения подписок, пришедшие из других регионов
+// при двунаправленной репликации. Если подписка уже есть в этом регионе, какую
+// версию оставить, решает хук resolve (REGION_CONFLICT_POLICY).
+type ReplicationService struct {
+	repo    postgres.SubscriptionRepository
+	resolve region.Resolver
+	logger  *slog.Logger
+}
+
+func NewReplicationService(repo postgres.SubscriptionRepository, resolve region.Resolver, logger *slog.Logger) *ReplicationService {
+	return &ReplicationService{
+		repo:    repo,
+		resolve: resolve,
+		logger:  logger,
+	}
+}
+
+// Apply записывает версию подписки из региона incoming.Region и возвращает
+// версию, которая осталась после разрешения конфликта.
+func (s *ReplicationService) Apply(ctx context.Context, incoming *domain.Subscription) (*domain.Subscription, error) {
+	if err := validateReplicated(incoming); err != nil {
+		return nil, err
+	}
+
+	local, err := s.repo.GetByID(ctx, incoming.ID)
+	switch {
+	case errors.Is(err, postgres.ErrNotFound):
+	case err != nil:
+		return nil, err
+	default:
+		if s.resolve(local, incoming) != incoming {
+			s.logger.InfoContext(ctx, "replicated change rejected",
+				slog.String("id", incoming.ID.String()),
+				slog.String("origin_region", incoming.Region),
+				slog.String("local_region", local.Region),
+			)
+			return local, nil
+		}
+	}
+
+	if err := s.repo.Upsert(region.WithOrigin(ctx, incoming.Region), incoming); err != nil {
+		s.logger.ErrorContext(ctx, "failed to apply replicated change",
+			slog.String("id", incoming.ID.String()),
+			slog.String("origin_region", incoming.Region),
+			slog.String("error", err.Error()),
+		)
+		return nil, err
+	}
+
+	s.logger.InfoContext(ctx, "replicated change applied",
+		slog.String("id", incoming.ID.String()),
+		slog.String("origin_region", incoming.Region),
+	)
+	return incoming, nil
+}
+
+func validateReplicated(sub *domain.Subscription) error {
+	if sub.ID == uuid.Nil || sub.UserID == uuid.Nil {
+		return fmt.Errorf("%w: id and user_id are required", ErrValidation)
+	}
+	if sub.Region == "" {
+		return fmt.Errorf("%w: region is required", ErrValidation)
+	}
+	if sub.Region == region.Current() {
+		return fmt.Errorf("%w: change originates from this region", ErrValidation)
+	}
+	switch sub.Status {
+	case domain.StatusActive, domain.StatusPaused, domain.StatusCancelled, domain.StatusExpired:
+	default:
+		return fmt.Errorf("%w: unknown status %q", ErrValidation, sub.Status)
+	}
+	if err := validatePrice(sub.Price); err != nil {
+		return err
+	}
+	return validateDates(sub.StartDate, sub.EndDate)
+}
@@ -0,0 +1,71 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"aggregator_db/internal/repository/memory"
+	"aggregator_db/pkg/domain"
+	"aggregator_db/pkg/region"
+	"github.com/google/uuid"
+)
+
+func TestReplicationServiceApply(t *testing.T) {
+	region.Set("eu")
+	t.Cleanup(func() { region.Set("") })
+
+	ctx := context.Background()
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	repo := memory.NewSubscriptionRepository()
+	created := time.Date(2025, 10, 1, 12, 0, 0, 0, time.UTC)
+
+	local := &domain.Subscription{ID: uuid.New(), UserID: uuid.New(), ServiceName: "Netflix", Price: domain.NewMoney(79900, domain.DefaultCurrency),
+		StartDate: "10-2025", CreatedAt: created, UpdatedAt: created.Add(time.Hour)}
+	if err := repo.Create(ctx, local); err != nil {
+		t.Fatal(err)
+	}
+	if local.Region != "eu" {
+		t.Fatalf("local region = %q, want eu", local.Region)
+	}
+
+	svc := NewReplicationService(repo, region.LastWriterWins, logger)
+	incoming := func(updatedAt time.Time, amount int64) *domain.Subscription {
+		sub := *local
+		sub.Price = domain.NewMoney(amount, domain.DefaultCurrency)
+		sub.UpdatedAt = updatedAt
+		sub.Region = "us"
+		return &sub
+	}
+
+	// Более старая версия из другого региона не перетирает локальную
+	got, err := svc.Apply(ctx, incoming(created, 99900))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Region != "eu" || got.Price.Amount != 79900 {
+		t.Errorf("stale change applied: %+v", got)
+	}
+
+	got, err = svc.Apply(ctx, incoming(created.Add(2*time.Hour), 99900))
+	if err != nil {
+		t.Fatal(err)
+	}
+	stored, err := repo.GetByID(ctx, local.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Region != "us" || stored.Region != "us" || stored.Price.Amount != 99900 {
+		t.Errorf("newer change not applied: got %+v, stored %+v", got, stored)
+	}
+
+	// Изменение с регионом этого развертывания - эхо собственной записи
+	echo := incoming(created.Add(3*time.Hour), 99900)
+	echo.Region = "eu"
+	if _, err := svc.Apply(ctx, echo); !errors.Is(err, ErrValidation) {
+		t.Errorf("echo: err = %v, want ErrValidation", err)
+	}
+}
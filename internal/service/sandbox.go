@@ -0,0 +1,50 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"aggregator_db/internal/repository/postgres"
+	"aggregator_db/internal/tenancy"
+	"aggregator_db/pkg/domain"
+)
+
+// SandboxService готовит и периодически сбрасывает схему песочницы,
+// с которой работают ключи приложений в режиме sandbox.
+type SandboxService struct {
+	provisioner postgres.TenantProvisioner
+	cache       postgres.SubscriptionCache
+	logger      *slog.Logger
+}
+
+// NewSandboxService - cache сбрасывает расчеты песочницы после сброса; nil, если кэша нет.
+func NewSandboxService(provisioner postgres.TenantProvisioner, cache postgres.SubscriptionCache, logger *slog.Logger) *SandboxService {
+	return &SandboxService{provisioner: provisioner, cache: cache, logger: logger}
+}
+
+// Prepare создает схему песочницы и применяет к ней миграции, если это еще не сделано.
+func (s *SandboxService) Prepare(ctx context.Context) error {
+	return s.provisioner.Provision(ctx, domain.SandboxTenant())
+}
+
+// Reset пересоздает схему песочницы: все данные интеграторов удаляются,
+// данные из миграций (алиасы сервисов) появляются заново. Запросы в песочницу
+// во время сброса могут завершиться ошибкой.
+func (s *SandboxService) Reset(ctx context.Context, _ time.Time) error {
+	sandbox := domain.SandboxTenant()
+	if err := s.provisioner.Deprovision(ctx, sandbox); err != nil {
+		return err
+	}
+	err := s.provisioner.Provision(ctx, sandbox)
+	// Расчеты по удаленным данным неверны, даже если схема не создалась заново
+	if s.cache != nil {
+		s.cache.InvalidateTenant(tenancy.WithTenant(ctx, sandbox))
+	}
+	if err != nil {
+		return err
+	}
+
+	s.logger.InfoContext(ctx, "sandbox reset", slog.String("schema", sandbox.SchemaName))
+	return nil
+}
@@ -0,0 +1,138 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"aggregator_db/internal/repository/postgres"
+	"aggregator_db/pkg/domain"
+	"aggregator_db/pkg/metrics"
+	"github.com/google/uuid"
+)
+
+// maxMigrationMismatches - сколько расхождений попадает в отчет сверки.
+const maxMigrationMismatches = 100
+
+var migrationMismatches = metrics.NewGaugeVec(
+	"schema_migration_mismatches",
+	"Расхождения новых колонок подписок со старыми на момент последней сверки",
+	"column",
+)
+
+// SchemaMigrationService сверяет новые колонки подписок со старыми перед
+// переключением перехода схемы и заполняет строки, записанные до dual_write.
+type SchemaMigrationService struct {
+	repo postgres.SchemaMigrationRepository
+	// cache - кэш расчетов по подпискам; nil, если кэш выключен
+	cache      postgres.SubscriptionCache
+	migrations domain.SchemaMigrations
+	batchSize  int
+	logger     *slog.Logger
+}
+
+func NewSchemaMigrationService(repo postgres.SchemaMigrationRepository, cache postgres.SubscriptionCache, migrations domain.SchemaMigrations, batchSize int, logger *slog.Logger) *SchemaMigrationService {
+	return &SchemaMigrationService{
+		repo:       repo,
+		cache:      cache,
+		migrations: migrations,
+		batchSize:  batchSize,
+		logger:     logger,
+	}
+}
+
+// Verify сверяет все подписки и ничего не меняет.
+func (s *SchemaMigrationService) Verify(ctx context.Context) (*domain.SchemaMigrationReport, error) {
+	return s.run(ctx, false)
+}
+
+// Backfill заполняет новые колонки подписок, где они расходятся со старыми, пачками:
+// так заполняются строки, записанные до включения dual_write. В отчете -
+// расхождения, найденные до заполнения, поэтому готовность к переключению
+// показывает следующая сверка.
+func (s *SchemaMigrationService) Backfill(ctx context.Context) (*domain.SchemaMigrationReport, error) {
+	return s.run(ctx, true)
+}
+
+// VerifyJob - задача планировщика: сверка с метрикой schema_migration_mismatches
+// и предупреждением в логе при расхождениях.
+func (s *SchemaMigrationService) VerifyJob(ctx context.Context, _ time.Time) error {
+	report, err := s.Verify(ctx)
+	if err != nil {
+		return err
+	}
+	for _, column := range []string{"start_on", "end_on", "price_amount"} {
+		migrationMismatches.Set(float64(report.ByColumn[column]), column)
+	}
+
+	if len(report.Mismatches) > 0 {
+		s.logger.WarnContext(ctx, "schema migration columns differ",
+			slog.Int("scanned", report.Scanned),
+			slog.Any("by_column", report.ByColumn),
+		)
+		return nil
+	}
+	s.logger.InfoContext(ctx, "schema migration columns match",
+		slog.Int("scanned", report.Scanned),
+		slog.Bool("dates_ready", report.DatesReady),
+		slog.Bool("money_ready", report.MoneyReady),
+	)
+	return nil
+}
+
+func (s *SchemaMigrationService) run(ctx context.Context, backfill bool) (*domain.SchemaMigrationReport, error) {
+	report := &domain.SchemaMigrationReport{
+		Phases:     s.migrations,
+		ByColumn:   make(map[string]int),
+		Mismatches: make([]domain.DualColumnMismatch, 0),
+	}
+
+	after := uuid.Nil
+	for {
+		records, err := s.repo.ScanDualColumns(ctx, after, s.batchSize)
+		if err != nil {
+			return nil, err
+		}
+		if len(records) == 0 {
+			break
+		}
+		after = records[len(records)-1].SubscriptionID
+		report.Scanned += len(records)
+
+		var stale []uuid.UUID
+		for _, rec := range records {
+			mismatches := domain.CheckDualColumns(rec, s.migrations)
+			if len(mismatches) == 0 {
+				continue
+			}
+			stale = append(stale, rec.SubscriptionID)
+			for _, mismatch := range mismatches {
+				report.ByColumn[mismatch.Column]++
+				if len(report.Mismatches) < maxMigrationMismatches {
+					report.Mismatches = append(report.Mismatches, mismatch)
+				}
+			}
+		}
+
+		if backfill && len(stale) > 0 {
+			if err := s.repo.Backfill(ctx, stale); err != nil {
+				return nil, err
+			}
+			// Сверка не знает владельцев подписок, а расчеты могут читать новые колонки
+			if s.cache != nil {
+				s.cache.InvalidateTenant(ctx)
+			}
+			report.Backfilled += len(stale)
+		}
+	}
+
+	report.DatesReady = s.migrations.Dates.WritesNew() && report.ByColumn["start_on"] == 0 && report.ByColumn["end_on"] == 0
+	report.MoneyReady = s.migrations.Money.WritesNew() && report.ByColumn["price_amount"] == 0
+	if backfill {
+		s.logger.InfoContext(ctx, "schema migration backfill finished",
+			slog.Int("scanned", report.Scanned),
+			slog.Int("backfilled", report.Backfilled),
+		)
+	}
+	return report, nil
+}
@@ -0,0 +1,106 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"log/slog"
+	"sort"
+	"testing"
+
+	"aggregator_db/pkg/domain"
+	"github.com/google/uuid"
+)
+
+// dualColumnsRepo хранит записи сверки в памяти; Backfill вычисляет новые колонки
+// из старых, как это делает SQL репозитория.
+type dualColumnsRepo struct {
+	records []domain.DualColumnsRecord
+}
+
+func (r *dualColumnsRepo) ScanDualColumns(_ context.Context, after uuid.UUID, limit int) ([]domain.DualColumnsRecord, error) {
+	var page []domain.DualColumnsRecord
+	for _, rec := range r.records {
+		if bytes.Compare(rec.SubscriptionID[:], after[:]) > 0 && len(page) < limit {
+			page = append(page, rec)
+		}
+	}
+	return page, nil
+}
+
+func (r *dualColumnsRepo) Backfill(_ context.Context, ids []uuid.UUID) error {
+	for _, id := range ids {
+		for i := range r.records {
+			rec := &r.records[i]
+			if rec.SubscriptionID != id {
+				continue
+			}
+			start, _ := domain.ParsePeriod(rec.StartDate)
+			rec.StartOn = &start
+			if rec.EndDate != nil {
+				end, _ := domain.ParsePeriod(*rec.EndDate)
+				rec.EndOn = &end
+			}
+			amount := domain.NewMoney(rec.PriceMinor, rec.Currency).FormatAmount()
+			rec.PriceAmount = &amount
+		}
+	}
+	return nil
+}
+
+func TestSchemaMigrationService(t *testing.T) {
+	ctx := context.Background()
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	start, _ := domain.ParsePeriod("03-2025")
+	amount := "299.00000000"
+	repo := &dualColumnsRepo{}
+	for i := 0; i < 5; i++ {
+		rec := domain.DualColumnsRecord{SubscriptionID: uuid.New(), StartDate: "03-2025", PriceMinor: 29900, Currency: domain.DefaultCurrency}
+		// Две подписки записаны до включения dual_write
+		if i >= 2 {
+			rec.StartOn, rec.PriceAmount = &start, &amount
+		}
+		repo.records = append(repo.records, rec)
+	}
+	sort.Slice(repo.records, func(i, j int) bool {
+		return bytes.Compare(repo.records[i].SubscriptionID[:], repo.records[j].SubscriptionID[:]) < 0
+	})
+
+	migrations := domain.SchemaMigrations{Dates: domain.MigrationDualWrite, Money: domain.MigrationDualWrite}
+	cache := &fakeCache{}
+	svc := NewSchemaMigrationService(repo, cache, migrations, 2, logger)
+
+	report, err := svc.Verify(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Scanned != 5 || report.ByColumn["start_on"] != 2 || report.ByColumn["price_amount"] != 2 || report.ByColumn["end_on"] != 0 {
+		t.Errorf("report = %+v", report)
+	}
+	if report.DatesReady || report.MoneyReady || len(report.Mismatches) != 4 {
+		t.Errorf("report = %+v", report)
+	}
+	if cache.tenants != 0 {
+		t.Errorf("verify invalidated the cache %d times", cache.tenants)
+	}
+
+	report, err = svc.Backfill(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Backfilled != 2 {
+		t.Errorf("backfilled = %d, want 2", report.Backfilled)
+	}
+	if cache.tenants == 0 {
+		t.Error("backfill did not invalidate the cache")
+	}
+
+	report, err = svc.Verify(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !report.DatesReady || !report.MoneyReady || len(report.Mismatches) != 0 {
+		t.Errorf("after backfill report = %+v", report)
+	}
+}
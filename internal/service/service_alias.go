@@ -0,0 +1,139 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"aggregator_db/internal/clock"
+	"aggregator_db/internal/repository/postgres"
+	"aggregator_db/pkg/domain"
+)
+
+// resolveServiceKey возвращает канонический ключ названия сервиса с учетом алиасов.
+func (s *SubscriptionService) resolveServiceKey(ctx context.Context, name string) (string, error) {
+	key := domain.ServiceKey(name)
+
+	alias, err := s.aliases.Get(ctx, key)
+	if errors.Is(err, postgres.ErrAliasNotFound) {
+		return key, nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return alias.CanonicalKey, nil
+}
+
+// serviceKeys возвращает ключи всех написаний сервиса, которые сводятся к name:
+// сам канонический ключ и ключи его алиасов.
+func (s *SubscriptionService) serviceKeys(ctx context.Context, name *string) ([]string, error) {
+	if name == nil {
+		return nil, nil
+	}
+
+	canonical, err := s.resolveServiceKey(ctx, *name)
+	if err != nil {
+		return nil, err
+	}
+	if canonical == "" {
+		return nil, fmt.Errorf("%w: service_name must contain letters or digits", ErrValidation)
+	}
+
+	aliasKeys, err := s.aliases.AliasKeys(ctx, canonical)
+	if err != nil {
+		return nil, err
+	}
+
+	return append([]string{canonical}, aliasKeys...), nil
+}
+
+// aliasMap возвращает отображение ключей алиасов в канонические ключи.
+func (s *SubscriptionService) aliasMap(ctx context.Context) (map[string]string, error) {
+	aliases, err := s.aliases.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make(map[string]string, len(aliases))
+	for _, alias := range aliases {
+		keys[alias.AliasKey] = alias.CanonicalKey
+	}
+	return keys, nil
+}
+
+func (s *SubscriptionService) ListServiceAliases(ctx context.Context) ([]*domain.ServiceAlias, error) {
+	aliases, err := s.aliases.List(ctx)
+	if err != nil {
+		s.logger.ErrorContext(ctx, "failed to list service aliases",
+			slog.String("error", err.Error()),
+		)
+		return nil, err
+	}
+	return aliases, nil
+}
+
+// UpsertServiceAlias сводит написание alias к каноническому названию canonical.
+// Если canonical сам является алиасом, используется его каноническое название.
+func (s *SubscriptionService) UpsertServiceAlias(ctx context.Context, req domain.UpsertServiceAliasRequest) (*domain.ServiceAlias, error) {
+	aliasKey := domain.ServiceKey(req.Alias)
+	if aliasKey == "" {
+		return nil, fmt.Errorf("%w: alias must contain letters or digits", ErrValidation)
+	}
+
+	canonical := req.Canonical
+	canonicalKey := domain.ServiceKey(canonical)
+	existing, err := s.aliases.Get(ctx, canonicalKey)
+	switch {
+	case err == nil:
+		canonical, canonicalKey = existing.Canonical, existing.CanonicalKey
+	case !errors.Is(err, postgres.ErrAliasNotFound):
+		return nil, err
+	}
+
+	if canonicalKey == "" {
+		return nil, fmt.Errorf("%w: canonical must contain letters or digits", ErrValidation)
+	}
+	if canonicalKey == aliasKey {
+		return nil, fmt.Errorf("%w: alias and canonical resolve to the same service", ErrValidation)
+	}
+
+	alias := &domain.ServiceAlias{
+		Alias:        req.Alias,
+		AliasKey:     aliasKey,
+		Canonical:    canonical,
+		CanonicalKey: canonicalKey,
+		CreatedAt:    clock.Now(ctx),
+	}
+
+	if err := s.aliases.Upsert(ctx, alias); err != nil {
+		s.logger.ErrorContext(ctx, "failed to save service alias",
+			slog.String("alias", req.Alias),
+			slog.String("error", err.Error()),
+		)
+		return nil, err
+	}
+
+	s.logger.InfoContext(ctx, "service alias saved",
+		slog.String("alias", alias.Alias),
+		slog.String("canonical", alias.Canonical),
+	)
+
+	return s.aliases.Get(ctx, aliasKey)
+}
+
+func (s *SubscriptionService) DeleteServiceAlias(ctx context.Context, alias string) error {
+	if err := s.aliases.Delete(ctx, domain.ServiceKey(alias)); err != nil {
+		s.logger.ErrorContext(ctx, "failed to delete service alias",
+			slog.String("alias", alias),
+			slog.String("error", err.Error()),
+		)
+		return err
+	}
+
+	s.logger.InfoContext(ctx, "service alias deleted",
+		slog.String("alias", alias),
+	)
+
+	return nil
+}
@@ -0,0 +1,85 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"aggregator_db/internal/clock"
+	"aggregator_db/internal/slo"
+	"aggregator_db/pkg/domain"
+	"aggregator_db/pkg/events"
+	"aggregator_db/pkg/region"
+	"github.com/google/uuid"
+)
+
+// SLOService отдает состояние целей маршрутов и публикует slo.burn_rate, когда
+// бюджет ошибок сгорает быстрее порога.
+type SLOService struct {
+	tracker   *slo.Tracker
+	publisher events.Publisher
+	logger    *slog.Logger
+}
+
+func NewSLOService(tracker *slo.Tracker, publisher events.Publisher, logger *slog.Logger) *SLOService {
+	return &SLOService{tracker: tracker, publisher: publisher, logger: logger}
+}
+
+// Observe учитывает ответ маршрута route ("METHOD /path").
+func (s *SLOService) Observe(route string, status int, duration time.Duration, now time.Time) {
+	s.tracker.Observe(route, status, duration, now)
+}
+
+func (s *SLOService) Report(ctx context.Context) *domain.SLOReport {
+	now := clock.Now(ctx)
+	return &domain.SLOReport{CheckedAt: now.UTC(), Routes: s.tracker.Status(now)}
+}
+
+// Run проверяет скорость сгорания каждые interval. Проверка идет на каждой реплике:
+// запросы считаются локально, а не в общей базе.
+func (s *SLOService) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			if err := s.CheckBurnRates(ctx, now); err != nil {
+				s.logger.Warn("failed to publish slo alerts", "error", err.Error())
+			}
+		}
+	}
+}
+
+// CheckBurnRates публикует оповещения, которые начали срабатывать с прошлой проверки.
+func (s *SLOService) CheckBurnRates(ctx context.Context, now time.Time) error {
+	for _, alert := range s.tracker.Evaluate(now) {
+		s.logger.WarnContext(ctx, "slo error budget burning",
+			slog.String("route", alert.Route),
+			slog.String("objective", string(alert.Objective)),
+			slog.String("severity", string(alert.Severity)),
+			slog.Float64("burn_rate", alert.LongBurnRate),
+		)
+		if err := s.publisher.Publish(ctx, sloBurnEvent(alert, now)); err != nil {
+			return fmt.Errorf("publish slo alert for %s: %w", alert.Route, err)
+		}
+	}
+	return nil
+}
+
+// sloBurnEvent строит событие с ID из маршрута, цели, правила и минуты: реплики
+// региона, заметившие сгорание в одну минуту, публикуют одно событие.
+func sloBurnEvent(alert domain.SLOBurnAlert, now time.Time) events.Event {
+	minute := now.UTC().Truncate(time.Minute)
+	key := fmt.Sprintf("slo:%s:%s:%s:%s:%s", region.Current(), alert.Route, alert.Objective, alert.Severity, minute.Format(time.RFC3339))
+	return events.Event{
+		ID:         uuid.NewSHA1(uuid.NameSpaceURL, []byte(key)),
+		Type:       "slo.burn_rate",
+		OccurredAt: now,
+		Region:     region.Current(),
+		Data:       alert,
+	}
+}
@@ -0,0 +1,263 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"time"
+
+	"aggregator_db/internal/repository/postgres"
+	"aggregator_db/internal/tenancy"
+	"aggregator_db/pkg/domain"
+	"github.com/google/uuid"
+)
+
+// statementHistory - сколько последних выписок пользователя показывает List.
+const statementHistory = 24
+
+// StatementService закрывает месяцы ежемесячных выписок и доставляет их. Выписка
+// - выгрузка вида statement в очереди ExportService: очередь повторяет неудачные
+// попытки и хранит статус доставки, который пользователь видит в List.
+type StatementService struct {
+	subs     postgres.SubscriptionRepository
+	settings postgres.NotificationSettingsRepository
+	users    postgres.UserRepository
+	closings postgres.StatementClosingRepository
+	exports  postgres.ExportJobRepository
+	// tenants - реестр тенантов; без него выписки закрываются только в основной базе
+	tenants       postgres.TenantRepository
+	notifications *NotificationService
+	logger        *slog.Logger
+}
+
+func NewStatementService(subs postgres.SubscriptionRepository, settings postgres.NotificationSettingsRepository, users postgres.UserRepository,
+	closings postgres.StatementClosingRepository, exports postgres.ExportJobRepository, tenants postgres.TenantRepository,
+	notifications *NotificationService, logger *slog.Logger) *StatementService {
+	return &StatementService{
+		subs:          subs,
+		settings:      settings,
+		users:         users,
+		closings:      closings,
+		exports:       exports,
+		tenants:       tenants,
+		notifications: notifications,
+		logger:        logger,
+	}
+}
+
+// Close - задача планировщика. Закрывает прошлый месяц в основной базе и у каждого
+// активного тенанта: ставит в очередь выписку каждому пользователю, выбравшему
+// канал, и отмечает месяц закрытым. ID выписки выводится из тенанта, пользователя
+// и месяца, поэтому повтор после сбоя не ставит ее второй раз.
+func (s *StatementService) Close(ctx context.Context, now time.Time) error {
+	now = now.UTC()
+	month := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC).AddDate(0, -1, 0)
+
+	scopes := []*domain.Tenant{nil}
+	if s.tenants != nil {
+		tenants, err := s.tenants.List(ctx)
+		if err != nil {
+			return err
+		}
+		for _, tenant := range tenants {
+			if tenant.Status == domain.TenantStatusActive {
+				scopes = append(scopes, tenant)
+			}
+		}
+	}
+
+	closed, enqueued, failed := 0, 0, 0
+	for _, tenant := range scopes {
+		scopeCtx, scopeName, tenantID := ctx, domain.DefaultTenantScope, ""
+		if tenant != nil {
+			scopeCtx, scopeName, tenantID = tenancy.WithTenant(ctx, tenant), tenant.ID, tenant.ID
+		}
+
+		n, err := s.closeScope(scopeCtx, tenantID, month, now)
+		if err != nil {
+			// Недоступная база одного тенанта не должна останавливать остальных
+			s.logger.WarnContext(ctx, "failed to close monthly statements",
+				slog.String("tenant_id", scopeName),
+				slog.String("month", domain.FormatPeriod(month)),
+				slog.String("error", err.Error()),
+			)
+			failed++
+			continue
+		}
+		if n >= 0 {
+			closed++
+			enqueued += n
+		}
+	}
+
+	if closed == 0 && failed == 0 {
+		return nil
+	}
+	s.logger.InfoContext(ctx, "monthly statements closed",
+		slog.String("month", domain.FormatPeriod(month)),
+		slog.Int("scopes", closed),
+		slog.Int("enqueued", enqueued),
+		slog.Int("failed", failed),
+	)
+	return nil
+}
+
+// closeScope ставит выписки одной базы в очередь и возвращает их число или -1,
+// если месяц уже закрыт. Месяц отмечается закрытым, только если в очередь
+// попали все выписки: иначе следующий запуск поставит недостающие.
+func (s *StatementService) closeScope(ctx context.Context, tenantID string, month, now time.Time) (int, error) {
+	closed, err := s.closings.Closed(ctx, month)
+	if err != nil || closed {
+		return -1, err
+	}
+
+	recipients, err := s.settings.ListStatementRecipients(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	enqueued := 0
+	for _, settings := range recipients {
+		job, err := s.statementJob(ctx, tenantID, settings, month, now)
+		if err != nil {
+			return 0, err
+		}
+		if job == nil {
+			continue
+		}
+		created, err := s.exports.CreateOnce(ctx, job)
+		if err != nil {
+			return 0, err
+		}
+		if created {
+			enqueued++
+		}
+	}
+
+	if err := s.closings.Close(ctx, month, enqueued, now); err != nil {
+		return 0, err
+	}
+	return enqueued, nil
+}
+
+// statementJob строит выгрузку выписки для пользователя. Пользователь с каналом
+// email, но без почты, пропускается: отправить выписку некуда.
+func (s *StatementService) statementJob(ctx context.Context, tenantID string, settings *domain.NotificationSettings, month, now time.Time) (*domain.ExportJob, error) {
+	email, delivery := "", domain.DeliveryWebhook
+	if settings.StatementChannel == domain.StatementEmail {
+		user, err := s.users.GetByID(ctx, settings.UserID)
+		if errors.Is(err, postgres.ErrUserNotFound) {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if user.Email == nil || *user.Email == "" {
+			s.logger.WarnContext(ctx, "statement skipped: user has no email",
+				slog.String("user_id", settings.UserID.String()),
+			)
+			return nil, nil
+		}
+		email, delivery = *user.Email, domain.DeliveryAttachment
+	}
+
+	params := domain.StatementParams{TenantID: tenantID, UserID: settings.UserID, Month: domain.FormatPeriod(month)}
+	raw, err := json.Marshal(params)
+	if err != nil {
+		return nil, err
+	}
+	key := fmt.Sprintf("statement:%s:%s:%s", tenantID, settings.UserID, params.Month)
+	return &domain.ExportJob{
+		ID:        uuid.NewSHA1(uuid.NameSpaceURL, []byte(key)),
+		Kind:      domain.ExportStatement,
+		Params:    raw,
+		Email:     email,
+		Delivery:  delivery,
+		Status:    domain.ExportPending,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}, nil
+}
+
+// Deliver формирует выписку из job и отправляет ее письмом с CSV или событием
+// statement.monthly. Статус доставки сохраняет ExportService.
+func (s *StatementService) Deliver(ctx context.Context, job *domain.ExportJob) error {
+	var params domain.StatementParams
+	if err := json.Unmarshal(job.Params, &params); err != nil {
+		return fmt.Errorf("decode statement params: %w", err)
+	}
+	month, err := domain.ParsePeriod(params.Month)
+	if err != nil {
+		return fmt.Errorf("decode statement params: %w", err)
+	}
+	if params.TenantID != "" {
+		if s.tenants == nil {
+			return fmt.Errorf("statement of tenant %q: tenants are not configured", params.TenantID)
+		}
+		tenant, err := s.tenants.Get(ctx, params.TenantID)
+		if err != nil {
+			return err
+		}
+		ctx = tenancy.WithTenant(ctx, tenant)
+	}
+
+	statement, err := s.Build(ctx, params.UserID, month)
+	if err != nil {
+		return err
+	}
+
+	if job.Delivery == domain.DeliveryWebhook {
+		return s.notifications.PublishStatement(ctx, job.ID, statement)
+	}
+	var buf bytes.Buffer
+	if err := WriteStatementCSV(&buf, statement); err != nil {
+		return err
+	}
+	job.FileName = domain.StatementFileName(month)
+	return s.notifications.SendStatement(ctx, job, statement, buf.Bytes())
+}
+
+// Build считает выписку пользователя за month.
+func (s *StatementService) Build(ctx context.Context, userID uuid.UUID, month time.Time) (*domain.Statement, error) {
+	subs, err := s.subs.ListHistory(ctx, domain.CalculateTotalRequest{
+		UserID:    &userID,
+		EndPeriod: domain.FormatPeriod(month),
+	})
+	if err != nil {
+		return nil, err
+	}
+	changes, err := statusChangesBySubscription(ctx, s.subs, subs)
+	if err != nil {
+		return nil, err
+	}
+	return domain.BuildStatement(userID, month, subs, changes)
+}
+
+// List возвращает последние выписки пользователя со статусом доставки, новые сверху.
+func (s *StatementService) List(ctx context.Context, userID uuid.UUID) ([]*domain.ExportJob, error) {
+	tenantID := ""
+	if tenant := tenancy.FromContext(ctx); tenant != nil {
+		tenantID = tenant.ID
+	}
+	return s.exports.ListStatements(ctx, tenantID, userID, statementHistory)
+}
+
+// WriteStatementCSV пишет строки выписки: суммы за прошлый месяц и месяц выписки
+// по сервисам и валютам.
+func WriteStatementCSV(w io.Writer, statement *domain.Statement) error {
+	cw := csv.NewWriter(w)
+	_ = cw.Write([]string{"service_name", "currency", statement.PreviousMonth, statement.Month, "change"})
+	for _, line := range statement.Lines {
+		_ = cw.Write([]string{line.ServiceName, string(line.Currency), line.Previous.FormatAmount(), line.Current.FormatAmount(), string(line.Change)})
+	}
+	for _, total := range statement.Totals {
+		_ = cw.Write([]string{"total", string(total.Currency), total.Previous.FormatAmount(), total.Current.FormatAmount(), ""})
+	}
+	cw.Flush()
+	return cw.Error()
+}
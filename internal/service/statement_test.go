@@ -0,0 +1,98 @@
+package service
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"aggregator_db/internal/repository/memory"
+	"aggregator_db/pkg/domain"
+	"github.com/google/uuid"
+)
+
+func TestMonthlyStatements(t *testing.T) {
+	ctx := context.Background()
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	subs := memory.NewSubscriptionRepository()
+	users := memory.NewUserRepository(subs)
+	settings := memory.NewNotificationSettingsRepository()
+	jobs := memory.NewExportJobRepository()
+	mail := &recordingMailer{}
+	publisher := &recordingPublisher{}
+
+	email := "alice@example.com"
+	alice := &domain.User{ID: uuid.New(), Email: &email}
+	bob := &domain.User{ID: uuid.New()}
+	carol := &domain.User{ID: uuid.New()}
+	for _, user := range []*domain.User{alice, bob, carol} {
+		if err := users.Create(ctx, user); err != nil {
+			t.Fatal(err)
+		}
+		if err := subs.Create(ctx, &domain.Subscription{ID: uuid.New(), UserID: user.ID, ServiceName: "Netflix",
+			Price: domain.NewMoney(59900, domain.DefaultCurrency), StartDate: "06-2025"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := subs.Create(ctx, &domain.Subscription{ID: uuid.New(), UserID: alice.ID, ServiceName: "Spotify",
+		Price: domain.NewMoney(29900, domain.DefaultCurrency), StartDate: "07-2025"}); err != nil {
+		t.Fatal(err)
+	}
+	// Боб выбрал почту, но не указал ее; Кэрол получает выписку событием
+	for user, channel := range map[uuid.UUID]domain.StatementChannel{alice.ID: domain.StatementEmail, bob.ID: domain.StatementEmail, carol.ID: domain.StatementWebhook} {
+		if err := settings.Upsert(ctx, &domain.NotificationSettings{UserID: user, StatementChannel: channel}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	notifications := NewNotificationService(subs, settings, publisher, mail, 20, logger)
+	statements := NewStatementService(subs, settings, users, memory.NewStatementClosingRepository(), jobs, nil, notifications, logger)
+	exports := NewExportService(jobs, nil, notifications, statements, ExportOptions{MaxAttachmentBytes: 1 << 20}, logger)
+
+	now := time.Date(2025, 8, 1, 3, 0, 0, 0, time.UTC)
+	if err := statements.Close(ctx, now); err != nil {
+		t.Fatal(err)
+	}
+	// Месяц уже закрыт: повторный запуск ничего не ставит в очередь
+	if err := statements.Close(ctx, now.Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if err := exports.Deliver(ctx, now); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(mail.sent) != 1 || mail.sent[0].To != email || len(mail.sent[0].Attachments) != 1 {
+		t.Fatalf("expected one statement mail to alice, got %+v", mail.sent)
+	}
+	if !strings.Contains(mail.sent[0].Body, "Итого: 898.00 RUB (в 06-2025: 599.00 RUB, +49.92%)") {
+		t.Errorf("unexpected mail body:\n%s", mail.sent[0].Body)
+	}
+	attachment := mail.sent[0].Attachments[0]
+	wantCSV := "service_name,currency,06-2025,07-2025,change\n" +
+		"Netflix,RUB,599.00,599.00,unchanged\n" +
+		"Spotify,RUB,0.00,299.00,new\n" +
+		"total,RUB,599.00,898.00,\n"
+	if attachment.Name != "statement_2025-07.csv" || string(attachment.Data) != wantCSV {
+		t.Errorf("unexpected attachment %s:\n%s", attachment.Name, attachment.Data)
+	}
+
+	if len(publisher.events) != 1 || publisher.events[0].Type != "statement.monthly" {
+		t.Fatalf("expected one statement event for carol, got %+v", publisher.events)
+	}
+	if statement := publisher.events[0].Data.(*domain.Statement); statement.UserID != carol.ID || statement.Month != "07-2025" {
+		t.Errorf("unexpected statement event %+v", statement)
+	}
+
+	history, err := statements.List(ctx, alice.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(history) != 1 || history[0].Status != domain.ExportDelivered || history[0].FileName != "statement_2025-07.csv" {
+		t.Errorf("unexpected alice statements %+v", history)
+	}
+	if history, _ := statements.List(ctx, bob.ID); len(history) != 0 {
+		t.Errorf("bob without email got statements %+v", history)
+	}
+}
@@ -0,0 +1,241 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"aggregator_db/internal/clock"
+	"aggregator_db/internal/repository/postgres"
+	"aggregator_db/pkg/domain"
+	"github.com/google/uuid"
+)
+
+// ChangeStatus переводит подписку в новый статус, проверяя допустимость перехода.
+// Изменение действует с месяца effective_from и не может переписывать историю:
+// месяц не раньше начала подписки и последнего изменения статуса, и не позже текущего месяца.
+// История читается и дополняется одной транзакцией.
+func (s *SubscriptionService) ChangeStatus(ctx context.Context, id uuid.UUID, req domain.ChangeStatusRequest) (*domain.Subscription, error) {
+	var sub *domain.Subscription
+	err := s.tx.WithTx(ctx, func(ctx context.Context) error {
+		// Изменения статуса одной подписки выполняются по очереди
+		if err := s.tx.Lock(ctx, "subscription_status:"+id.String()); err != nil {
+			return err
+		}
+		var err error
+		sub, err = s.changeStatus(ctx, id, req)
+		return err
+	})
+	return sub, err
+}
+
+func (s *SubscriptionService) changeStatus(ctx context.Context, id uuid.UUID, req domain.ChangeStatusRequest) (*domain.Subscription, error) {
+	sub, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if sub.Status == req.Status {
+		return nil, fmt.Errorf("%w: subscription is already %s", ErrValidation, req.Status)
+	}
+	if !sub.Status.CanTransitionTo(req.Status) {
+		return nil, fmt.Errorf("%w: cannot change status from %s to %s", ErrValidation, sub.Status, req.Status)
+	}
+
+	now := clock.Now(ctx)
+	start, err := domain.ParsePeriod(sub.StartDate)
+	if err != nil {
+		return nil, err
+	}
+	latest := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	if latest.Before(start) {
+		latest = start
+	}
+
+	effective := latest
+	if req.EffectiveFrom != nil {
+		if effective, err = domain.ParsePeriod(*req.EffectiveFrom); err != nil {
+			return nil, fmt.Errorf("%w: effective_from: %w", ErrValidation, err)
+		}
+	}
+	if effective.Before(start) {
+		return nil, fmt.Errorf("%w: effective_from must not be before start_date", ErrValidation)
+	}
+	if effective.After(latest) {
+		return nil, fmt.Errorf("%w: effective_from must not be in the future", ErrValidation)
+	}
+
+	history, err := s.repo.ListStatusChanges(ctx, []uuid.UUID{id})
+	if err != nil {
+		return nil, err
+	}
+	for _, change := range history {
+		from, err := domain.ParsePeriod(change.EffectiveFrom)
+		if err != nil {
+			return nil, err
+		}
+		if effective.Before(from) {
+			return nil, fmt.Errorf("%w: effective_from must not be before the last status change (%s)", ErrValidation, change.EffectiveFrom)
+		}
+	}
+
+	change := &domain.StatusChange{
+		SubscriptionID: id,
+		Status:         req.Status,
+		EffectiveFrom:  domain.FormatPeriod(effective),
+		ChangedAt:      now,
+	}
+	if err := s.repo.ChangeStatus(ctx, change); err != nil {
+		s.logger.ErrorContext(ctx, "failed to change subscription status",
+			slog.String("id", id.String()),
+			slog.String("error", err.Error()),
+		)
+		return nil, err
+	}
+
+	s.logger.InfoContext(ctx, "subscription status changed",
+		slog.String("id", id.String()),
+		slog.String("from", string(sub.Status)),
+		slog.String("to", string(req.Status)),
+		slog.String("effective_from", change.EffectiveFrom),
+	)
+
+	sub.Status = req.Status
+	sub.UpdatedAt = now
+	sub.Version++
+	return sub, nil
+}
+
+// Cancel отменяет подписку: месяц effective_month становится последним оплачиваемым
+// (end_date), статус cancelled действует со следующего месяца. Месяц не может быть
+// раньше начала подписки и последнего изменения статуса, позже текущего месяца
+// и позже уже заданного end_date. История читается и дополняется одной транзакцией.
+func (s *SubscriptionService) Cancel(ctx context.Context, id uuid.UUID, req domain.CancelSubscriptionRequest) (*domain.Subscription, error) {
+	var sub *domain.Subscription
+	err := s.tx.WithTx(ctx, func(ctx context.Context) error {
+		// Изменения статуса одной подписки выполняются по очереди
+		if err := s.tx.Lock(ctx, "subscription_status:"+id.String()); err != nil {
+			return err
+		}
+		var err error
+		sub, err = s.cancel(ctx, id, req)
+		return err
+	})
+	return sub, err
+}
+
+func (s *SubscriptionService) cancel(ctx context.Context, id uuid.UUID, req domain.CancelSubscriptionRequest) (*domain.Subscription, error) {
+	sub, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if !sub.Status.CanTransitionTo(domain.StatusCancelled) {
+		return nil, fmt.Errorf("%w: cannot cancel subscription in status %s", ErrValidation, sub.Status)
+	}
+
+	now := clock.Now(ctx)
+	start, err := domain.ParsePeriod(sub.StartDate)
+	if err != nil {
+		return nil, err
+	}
+	latest := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	if latest.Before(start) {
+		latest = start
+	}
+	if sub.EndDate != nil {
+		end, err := domain.ParsePeriod(*sub.EndDate)
+		if err != nil {
+			return nil, err
+		}
+		if end.Before(latest) {
+			latest = end
+		}
+	}
+
+	lastMonth := latest
+	if req.EffectiveMonth != nil {
+		if lastMonth, err = domain.ParsePeriod(*req.EffectiveMonth); err != nil {
+			return nil, fmt.Errorf("%w: effective_month: %w", ErrValidation, err)
+		}
+	}
+	if lastMonth.Before(start) {
+		return nil, fmt.Errorf("%w: effective_month must not be before start_date", ErrValidation)
+	}
+	if lastMonth.After(latest) {
+		return nil, fmt.Errorf("%w: effective_month must not be in the future or after end_date", ErrValidation)
+	}
+
+	effective := lastMonth.AddDate(0, 1, 0)
+	history, err := s.repo.ListStatusChanges(ctx, []uuid.UUID{id})
+	if err != nil {
+		return nil, err
+	}
+	for _, change := range history {
+		from, err := domain.ParsePeriod(change.EffectiveFrom)
+		if err != nil {
+			return nil, err
+		}
+		if effective.Before(from) {
+			return nil, fmt.Errorf("%w: effective_month must not be before the last status change (%s)", ErrValidation, change.EffectiveFrom)
+		}
+	}
+
+	previous := sub.Status
+	endDate := domain.FormatPeriod(lastMonth)
+	sub.EndDate = &endDate
+	sub.Status = domain.StatusCancelled
+	sub.AutoRenew = false
+	sub.CancelledAt = &now
+	sub.CancelReason = req.Reason
+	sub.UpdatedAt = now
+
+	change := &domain.StatusChange{
+		SubscriptionID: id,
+		Status:         domain.StatusCancelled,
+		EffectiveFrom:  domain.FormatPeriod(effective),
+		ChangedAt:      now,
+	}
+	if err := s.repo.Cancel(ctx, sub, change); err != nil {
+		s.logger.ErrorContext(ctx, "failed to cancel subscription",
+			slog.String("id", id.String()),
+			slog.String("error", err.Error()),
+		)
+		return nil, err
+	}
+
+	s.logger.InfoContext(ctx, "subscription cancelled",
+		slog.String("id", id.String()),
+		slog.String("from", string(previous)),
+		slog.String("end_date", endDate),
+	)
+
+	return sub, nil
+}
+
+func (s *SubscriptionService) StatusHistory(ctx context.Context, id uuid.UUID) ([]*domain.StatusChange, error) {
+	if _, err := s.repo.GetByID(ctx, id); err != nil {
+		return nil, err
+	}
+	return s.repo.ListStatusChanges(ctx, []uuid.UUID{id})
+}
+
+// statusChangesBySubscription загружает историю статусов подписок, сгруппированную по ID.
+func statusChangesBySubscription(ctx context.Context, repo postgres.SubscriptionRepository, subs []*domain.Subscription) (map[uuid.UUID][]*domain.StatusChange, error) {
+	ids := make([]uuid.UUID, len(subs))
+	for i, sub := range subs {
+		ids[i] = sub.ID
+	}
+
+	changes, err := repo.ListStatusChanges(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	bySubscription := make(map[uuid.UUID][]*domain.StatusChange)
+	for _, change := range changes {
+		bySubscription[change.SubscriptionID] = append(bySubscription[change.SubscriptionID], change)
+	}
+	return bySubscription, nil
+}
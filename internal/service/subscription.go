@@ -2,44 +2,195 @@ package service
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"log/slog"
+	"slices"
+	"strings"
 	"time"
+	"unicode/utf8"
 
-	"aggregator_db/internal/domain"
+	"aggregator_db/internal/clock"
+	"aggregator_db/internal/exchange"
+	"aggregator_db/internal/metering"
 	"aggregator_db/internal/repository/postgres"
+	"aggregator_db/pkg/domain"
+	"aggregator_db/pkg/events"
 	"github.com/google/uuid"
 )
 
+var ErrValidation = errors.New("validation error")
+
 type SubscriptionService struct {
-	repo   postgres.SubscriptionRepository
-	logger *slog.Logger
+	repo postgres.SubscriptionRepository
+	// tx объединяет проверку квоты с записью и чтение истории статусов с ее изменением
+	tx        postgres.Transactor
+	aliases   postgres.ServiceAliasRepository
+	publisher events.Publisher
+	rates     exchange.Provider
+	logger    *slog.Logger
 }
 
-func NewSubscriptionService(repo postgres.SubscriptionRepository, logger *slog.Logger) *SubscriptionService {
+func NewSubscriptionService(repo postgres.SubscriptionRepository, tx postgres.Transactor, aliases postgres.ServiceAliasRepository, publisher events.Publisher, rates exchange.Provider, logger *slog.Logger) *SubscriptionService {
 	return &SubscriptionService{
-		repo:   repo,
-		logger: logger,
+		repo:      repo,
+		tx:        tx,
+		aliases:   aliases,
+		publisher: publisher,
+		rates:     rates,
+		logger:    logger,
+	}
+}
+
+// validatePrice проверяет, что цена передана, валюта поддерживается, а сумма не отрицательна.
+func validatePrice(price domain.Money) error {
+	if price.IsZero() {
+		return fmt.Errorf("%w: price is required", ErrValidation)
+	}
+	if !price.Currency.Valid() {
+		return fmt.Errorf("%w: price: unsupported currency %q", ErrValidation, price.Currency)
+	}
+	if price.Amount < 0 {
+		return fmt.Errorf("%w: price must not be negative", ErrValidation)
+	}
+	return nil
+}
+
+// normalizeTags приводит метки к каноническому виду; ошибка оборачивается в ErrValidation.
+func normalizeTags(tags []string) ([]string, error) {
+	normalized, err := domain.NormalizeTags(tags)
+	if err != nil {
+		return nil, fmt.Errorf("%w: tags: %w", ErrValidation, err)
+	}
+	return normalized, nil
+}
+
+const (
+	maxNotesLength       = 1000
+	maxSearchQueryLength = 200
+)
+
+// normalizeNotes обрезает пробелы по краям; пустая заметка не хранится.
+func normalizeNotes(notes *string) (*string, error) {
+	if notes == nil {
+		return nil, nil
+	}
+	trimmed := strings.TrimSpace(*notes)
+	if trimmed == "" {
+		return nil, nil
+	}
+	if utf8.RuneCountInString(trimmed) > maxNotesLength {
+		return nil, fmt.Errorf("%w: notes must be at most %d characters", ErrValidation, maxNotesLength)
 	}
+	return &trimmed, nil
+}
+
+// normalizeCustomFields проверяет пользовательские поля по схеме тенанта; пустые
+// значения не хранятся.
+func normalizeCustomFields(ctx context.Context, values domain.CustomFieldValues) (domain.CustomFieldValues, error) {
+	normalized, err := customFieldSchema(ctx).Normalize(values)
+	if err != nil {
+		return nil, fmt.Errorf("%w: custom_fields: %w", ErrValidation, err)
+	}
+	if len(normalized) == 0 {
+		return nil, nil
+	}
+	return normalized, nil
+}
+
+func validateDates(startDate string, endDate *string) error {
+	start, err := domain.ParsePeriod(startDate)
+	if err != nil {
+		return fmt.Errorf("%w: start_date: %w", ErrValidation, err)
+	}
+
+	if endDate != nil {
+		end, err := domain.ParsePeriod(*endDate)
+		if err != nil {
+			return fmt.Errorf("%w: end_date: %w", ErrValidation, err)
+		}
+		if end.Before(start) {
+			return fmt.Errorf("%w: end_date must not be before start_date", ErrValidation)
+		}
+	}
+
+	return nil
 }
 
 func (s *SubscriptionService) Create(ctx context.Context, req domain.CreateSubscriptionRequest) (*domain.Subscription, error) {
-	sub := &domain.Subscription{
-		ID:          uuid.New(),
-		ServiceName: req.ServiceName,
-		Price:       req.Price,
-		UserID:      req.UserID,
-		StartDate:   req.StartDate,
-		EndDate:     req.EndDate,
-		CreatedAt:   time.Now().UTC(),
-		UpdatedAt:   time.Now().UTC(),
-	}
-
-	if err := s.repo.Create(ctx, sub); err != nil {
-		s.logger.ErrorContext(ctx, "failed to create subscription",
-			slog.String("user_id", req.UserID.String()),
-			slog.String("service", req.ServiceName),
-			slog.String("error", err.Error()),
-		)
+	if err := validateCreate(req); err != nil {
+		return nil, err
+	}
+	return s.create(ctx, newSubscription(req, clock.Now(ctx)))
+}
+
+func validateCreate(req domain.CreateSubscriptionRequest) error {
+	if req.ID != nil && *req.ID == uuid.Nil {
+		return fmt.Errorf("%w: id must not be the nil UUID", ErrValidation)
+	}
+	if err := validatePrice(req.Price); err != nil {
+		return err
+	}
+	if _, err := normalizeTags(req.Tags); err != nil {
+		return err
+	}
+	if _, err := normalizeNotes(req.Notes); err != nil {
+		return err
+	}
+	return validateDates(req.StartDate, req.EndDate)
+}
+
+// newSubscription собирает подписку из запроса. Id, если его не передал клиент,
+// назначается до сохранения, чтобы его можно было вернуть, даже если запись отложена.
+func newSubscription(req domain.CreateSubscriptionRequest, now time.Time) *domain.Subscription {
+	// Метки и заметка уже проверены в validateCreate
+	tags, _ := domain.NormalizeTags(req.Tags)
+	notes, _ := normalizeNotes(req.Notes)
+	id := uuid.New()
+	if req.ID != nil {
+		id = *req.ID
+	}
+	return &domain.Subscription{
+		ID:           id,
+		ServiceName:  req.ServiceName,
+		Price:        req.Price,
+		UserID:       req.UserID,
+		StartDate:    req.StartDate,
+		EndDate:      req.EndDate,
+		AutoRenew:    req.AutoRenew,
+		BillingCycle: req.BillingCycle.OrDefault(),
+		Tags:         tags,
+		Notes:        notes,
+		CustomFields: req.CustomFields,
+		CreatedAt:    now,
+		UpdatedAt:    now,
+	}
+}
+
+// create проверяет квоту тенанта и сохраняет собранную подписку одной транзакцией.
+func (s *SubscriptionService) create(ctx context.Context, sub *domain.Subscription) (*domain.Subscription, error) {
+	// Поля проверяются здесь, а не в validateCreate: схема зависит от тенанта
+	customFields, err := normalizeCustomFields(ctx, sub.CustomFields)
+	if err != nil {
+		return nil, err
+	}
+	sub.CustomFields = customFields
+
+	err = s.tx.WithTx(ctx, func(ctx context.Context) error {
+		if err := s.checkSubscriptionQuota(ctx, 1); err != nil {
+			return err
+		}
+		if err := s.repo.Create(ctx, sub); err != nil {
+			s.logger.ErrorContext(ctx, "failed to create subscription",
+				slog.String("user_id", sub.UserID.String()),
+				slog.String("service", sub.ServiceName),
+				slog.String("error", err.Error()),
+			)
+			return err
+		}
+		return nil
+	})
+	if err != nil {
 		return nil, err
 	}
 
@@ -49,9 +200,210 @@ func (s *SubscriptionService) Create(ctx context.Context, req domain.CreateSubsc
 		slog.String("service", sub.ServiceName),
 	)
 
+	s.publishClassification(ctx, sub)
+
+	return sub, nil
+}
+
+// MaxBulkCreateItems ограничивает размер одного запроса массового создания.
+const MaxBulkCreateItems = 1000
+
+// CreateBulk создает подписки одной транзакцией. Если хотя бы один элемент
+// не прошел валидацию, ничего не сохраняется, а ошибки возвращаются по каждому элементу.
+func (s *SubscriptionService) CreateBulk(ctx context.Context, reqs []domain.CreateSubscriptionRequest) (*domain.BulkCreateResponse, error) {
+	if len(reqs) == 0 {
+		return nil, fmt.Errorf("%w: at least one subscription is required", ErrValidation)
+	}
+	if len(reqs) > MaxBulkCreateItems {
+		return nil, fmt.Errorf("%w: at most %d subscriptions per request", ErrValidation, MaxBulkCreateItems)
+	}
+
+	resp := &domain.BulkCreateResponse{Items: make([]domain.BulkCreateItemResult, len(reqs))}
+	invalid := 0
+	ids := make(map[uuid.UUID]int)
+	for i, req := range reqs {
+		resp.Items[i].Index = i
+		if err := validateCreate(req); err != nil {
+			resp.Items[i].Error = err.Error()
+			invalid++
+			continue
+		}
+		if _, err := normalizeCustomFields(ctx, req.CustomFields); err != nil {
+			resp.Items[i].Error = err.Error()
+			invalid++
+			continue
+		}
+		if req.ID == nil {
+			continue
+		}
+		if first, ok := ids[*req.ID]; ok {
+			resp.Items[i].Error = fmt.Sprintf("%s: id duplicates item %d", ErrValidation, first)
+			invalid++
+			continue
+		}
+		ids[*req.ID] = i
+	}
+	if invalid > 0 {
+		return resp, fmt.Errorf("%w: %d of %d items are invalid", ErrValidation, invalid, len(reqs))
+	}
+	now := clock.Now(ctx)
+	subs := make([]*domain.Subscription, len(reqs))
+	for i, req := range reqs {
+		subs[i] = newSubscription(req, now)
+		// Поля уже проверены выше
+		subs[i].CustomFields, _ = normalizeCustomFields(ctx, req.CustomFields)
+	}
+
+	err := s.tx.WithTx(ctx, func(ctx context.Context) error {
+		if err := s.checkSubscriptionQuota(ctx, len(reqs)); err != nil {
+			return err
+		}
+		if err := s.repo.CreateBatch(ctx, subs); err != nil {
+			s.logger.ErrorContext(ctx, "failed to create subscriptions in bulk",
+				slog.Int("count", len(subs)),
+				slog.String("error", err.Error()),
+			)
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for i, sub := range subs {
+		resp.Items[i].Subscription = sub
+	}
+	resp.Created = len(subs)
+
+	s.logger.InfoContext(ctx, "subscriptions created in bulk",
+		slog.Int("count", len(subs)),
+	)
+
+	s.publishClassification(ctx, subs...)
+
+	return resp, nil
+}
+
+// Backfill создает подписку из исторических данных: даты создания/обновления
+// берутся из запроса, запись помечается как загруженная задним числом. Отмененная
+// подписка получает cancelled_at = updated_at.
+func (s *SubscriptionService) Backfill(ctx context.Context, req domain.BackfillSubscriptionRequest) (*domain.Subscription, error) {
+	if err := validatePrice(req.Price); err != nil {
+		return nil, err
+	}
+	if err := validateDates(req.StartDate, req.EndDate); err != nil {
+		return nil, err
+	}
+	tags, err := normalizeTags(req.Tags)
+	if err != nil {
+		return nil, err
+	}
+	now := clock.Now(ctx)
+
+	status := domain.StatusActive
+	if req.Status != nil {
+		status = *req.Status
+	}
+	if err := validateBackfillStatus(status, req.EndDate, now); err != nil {
+		return nil, err
+	}
+
+	createdAt := req.CreatedAt.UTC()
+	if createdAt.After(now) {
+		return nil, fmt.Errorf("%w: created_at must not be in the future", ErrValidation)
+	}
+
+	updatedAt := createdAt
+	if req.UpdatedAt != nil {
+		updatedAt = req.UpdatedAt.UTC()
+	}
+	if updatedAt.Before(createdAt) {
+		return nil, fmt.Errorf("%w: updated_at must not be before created_at", ErrValidation)
+	}
+	if updatedAt.After(now) {
+		return nil, fmt.Errorf("%w: updated_at must not be in the future", ErrValidation)
+	}
+
+	// Исторические записи по умолчанию не учитываются как "новые подписки"
+	exclude := true
+	if req.ExcludeFromNewAnalytics != nil {
+		exclude = *req.ExcludeFromNewAnalytics
+	}
+
+	sub := &domain.Subscription{
+		ID:                      uuid.New(),
+		ServiceName:             req.ServiceName,
+		Price:                   req.Price,
+		BillingCycle:            req.BillingCycle.OrDefault(),
+		UserID:                  req.UserID,
+		StartDate:               req.StartDate,
+		EndDate:                 req.EndDate,
+		CreatedAt:               createdAt,
+		UpdatedAt:               updatedAt,
+		Status:                  status,
+		Backfilled:              true,
+		ExcludeFromNewAnalytics: exclude,
+		BackfillNote:            req.Note,
+		Tags:                    tags,
+	}
+	if status == domain.StatusCancelled {
+		sub.CancelledAt = &updatedAt
+	}
+
+	err = s.tx.WithTx(ctx, func(ctx context.Context) error {
+		if err := s.checkSubscriptionQuota(ctx, 1); err != nil {
+			return err
+		}
+		if err := s.repo.Create(ctx, sub); err != nil {
+			s.logger.ErrorContext(ctx, "failed to backfill subscription",
+				slog.String("user_id", req.UserID.String()),
+				slog.String("service", req.ServiceName),
+				slog.String("error", err.Error()),
+			)
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.logger.InfoContext(ctx, "subscription backfilled",
+		slog.String("id", sub.ID.String()),
+		slog.String("user_id", sub.UserID.String()),
+		slog.String("service", sub.ServiceName),
+		slog.Time("created_at", sub.CreatedAt),
+	)
+
 	return sub, nil
 }
 
+// validateBackfillStatus допускает конечный статус только у подписки, которая
+// закончилась до текущего месяца now: paused и конечный статус текущей подписки
+// меняются через ChangeStatus и Cancel с записью в историю статусов.
+func validateBackfillStatus(status domain.SubscriptionStatus, endDate *string, now time.Time) error {
+	switch status {
+	case domain.StatusActive:
+		return nil
+	case domain.StatusCancelled, domain.StatusExpired:
+	default:
+		return fmt.Errorf("%w: status must be one of active, cancelled, expired", ErrValidation)
+	}
+
+	if endDate == nil {
+		return fmt.Errorf("%w: status %s requires end_date", ErrValidation, status)
+	}
+	end, err := domain.ParsePeriod(*endDate)
+	if err != nil {
+		return fmt.Errorf("%w: end_date: %w", ErrValidation, err)
+	}
+	if !end.Before(time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)) {
+		return fmt.Errorf("%w: status %s requires end_date before the current month", ErrValidation, status)
+	}
+	return nil
+}
+
 func (s *SubscriptionService) GetByID(ctx context.Context, id uuid.UUID) (*domain.Subscription, error) {
 	sub, err := s.repo.GetByID(ctx, id)
 	if err != nil {
@@ -65,30 +417,144 @@ func (s *SubscriptionService) GetByID(ctx context.Context, id uuid.UUID) (*domai
 	return sub, nil
 }
 
+// Replace полностью заменяет изменяемые поля подписки.
+func (s *SubscriptionService) Replace(ctx context.Context, id uuid.UUID, req domain.ReplaceSubscriptionRequest) (*domain.Subscription, error) {
+	sub, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkVersion(sub, req.Version); err != nil {
+		return nil, err
+	}
+
+	sub.ServiceName = req.ServiceName
+	sub.Price = req.Price
+	sub.StartDate = req.StartDate
+	sub.EndDate = req.EndDate
+	sub.AutoRenew = req.AutoRenew
+	sub.BillingCycle = req.BillingCycle.OrDefault()
+	sub.Tags = req.Tags
+	sub.Notes = req.Notes
+	sub.CustomFields = req.CustomFields
+
+	return s.save(ctx, sub)
+}
+
+// Update применяет частичное обновление: меняются только переданные поля.
 func (s *SubscriptionService) Update(ctx context.Context, id uuid.UUID, req domain.UpdateSubscriptionRequest) (*domain.Subscription, error) {
+	if err := validateUpdate(req); err != nil {
+		return nil, err
+	}
+
 	sub, err := s.repo.GetByID(ctx, id)
 	if err != nil {
 		return nil, err
 	}
+	if err := checkVersion(sub, req.Version); err != nil {
+		return nil, err
+	}
 
-	if req.ServiceName != nil {
-		sub.ServiceName = *req.ServiceName
+	if req.ServiceName.Set {
+		sub.ServiceName = req.ServiceName.Value
+	}
+	if req.Price.Set {
+		sub.Price = req.Price.Value
+	}
+	if req.StartDate.Set {
+		sub.StartDate = req.StartDate.Value
 	}
-	if req.Price != nil {
-		sub.Price = *req.Price
+	if req.EndDate.Set {
+		if req.EndDate.Null {
+			sub.EndDate = nil
+		} else {
+			endDate := req.EndDate.Value
+			sub.EndDate = &endDate
+		}
 	}
-	if req.StartDate != nil {
-		sub.StartDate = *req.StartDate
+	if req.AutoRenew.Set {
+		sub.AutoRenew = req.AutoRenew.Value
 	}
-	if req.EndDate != nil {
-		sub.EndDate = req.EndDate
+	if req.BillingCycle.Set {
+		sub.BillingCycle = req.BillingCycle.Value
+	}
+	if req.Tags.Set {
+		sub.Tags = req.Tags.Value
+	}
+	if req.Notes.Set {
+		if req.Notes.Null {
+			sub.Notes = nil
+		} else {
+			notes := req.Notes.Value
+			sub.Notes = &notes
+		}
+	}
+	if req.CustomFields.Set {
+		// Переданный объект заменяет поля целиком, null очищает их
+		sub.CustomFields = req.CustomFields.Value
 	}
 
-	sub.UpdatedAt = time.Now().UTC()
+	return s.save(ctx, sub)
+}
+
+// checkVersion сверяет версию, которую видел клиент, с текущей. Без версии клиента
+// изменение все равно не затрет чужое, сделанное после чтения в сервисе: репозиторий
+// записывает подписку только с прочитанной версией.
+func checkVersion(sub *domain.Subscription, expected *int64) error {
+	if expected != nil && *expected != sub.Version {
+		return fmt.Errorf("%w: subscription is at version %d, not %d", postgres.ErrVersionConflict, sub.Version, *expected)
+	}
+	return nil
+}
+
+// validateUpdate проверяет поля частичного обновления, не обращаясь к базе.
+func validateUpdate(req domain.UpdateSubscriptionRequest) error {
+	if req.ServiceName.Null || req.Price.Null || req.StartDate.Null || req.AutoRenew.Null || req.BillingCycle.Null {
+		return fmt.Errorf("%w: only end_date, tags, notes and custom_fields can be cleared with null", ErrValidation)
+	}
+	if req.ServiceName.Set && req.ServiceName.Value == "" {
+		return fmt.Errorf("%w: service_name must not be empty", ErrValidation)
+	}
+	if req.Price.Set {
+		if err := validatePrice(req.Price.Value); err != nil {
+			return err
+		}
+	}
+	if req.BillingCycle.Set && !req.BillingCycle.Value.Valid() {
+		return fmt.Errorf("%w: billing_cycle must be one of weekly, monthly, yearly", ErrValidation)
+	}
+	if _, err := normalizeTags(req.Tags.Value); err != nil {
+		return err
+	}
+	if _, err := normalizeNotes(&req.Notes.Value); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (s *SubscriptionService) save(ctx context.Context, sub *domain.Subscription) (*domain.Subscription, error) {
+	if err := validatePrice(sub.Price); err != nil {
+		return nil, err
+	}
+	if err := validateDates(sub.StartDate, sub.EndDate); err != nil {
+		return nil, err
+	}
+	tags, err := normalizeTags(sub.Tags)
+	if err != nil {
+		return nil, err
+	}
+	sub.Tags = tags
+	if sub.Notes, err = normalizeNotes(sub.Notes); err != nil {
+		return nil, err
+	}
+	if sub.CustomFields, err = normalizeCustomFields(ctx, sub.CustomFields); err != nil {
+		return nil, err
+	}
+
+	sub.UpdatedAt = clock.Now(ctx)
 
 	if err := s.repo.Update(ctx, sub); err != nil {
 		s.logger.ErrorContext(ctx, "failed to update subscription",
-			slog.String("id", id.String()),
+			slog.String("id", sub.ID.String()),
 			slog.String("error", err.Error()),
 		)
 		return nil, err
@@ -117,7 +583,139 @@ func (s *SubscriptionService) Delete(ctx context.Context, id uuid.UUID) error {
 	return nil
 }
 
-func (s *SubscriptionService) List(ctx context.Context, query domain.ListSubscriptionsQuery) ([]*domain.Subscription, error) {
+// DeleteByFilter удаляет все подписки под фильтр одной транзакцией.
+// Пустой фильтр запрещен, чтобы случайно не удалить все данные.
+func (s *SubscriptionService) DeleteByFilter(ctx context.Context, filter domain.DeleteSubscriptionsFilter) (*domain.DeleteSubscriptionsResponse, error) {
+	if filter.UserID == nil && filter.ServiceName == nil && filter.EndedBefore == nil {
+		return nil, fmt.Errorf("%w: at least one of user_id, service_name, ended_before is required", ErrValidation)
+	}
+	if filter.EndedBefore != nil {
+		if _, err := domain.ParsePeriod(*filter.EndedBefore); err != nil {
+			return nil, fmt.Errorf("%w: ended_before: %w", ErrValidation, err)
+		}
+	}
+
+	keys, err := s.serviceKeys(ctx, filter.ServiceName)
+	if err != nil {
+		return nil, err
+	}
+	filter.ServiceKeys = keys
+
+	deleted, err := s.repo.DeleteByFilter(ctx, filter)
+	if err != nil {
+		s.logger.ErrorContext(ctx, "failed to delete subscriptions by filter",
+			slog.String("error", err.Error()),
+		)
+		return nil, err
+	}
+
+	attrs := []any{slog.Int("deleted", deleted)}
+	if filter.UserID != nil {
+		attrs = append(attrs, slog.String("user_id", filter.UserID.String()))
+	}
+	s.logger.InfoContext(ctx, "subscriptions deleted by filter", attrs...)
+
+	return &domain.DeleteSubscriptionsResponse{Deleted: deleted}, nil
+}
+
+// preparePriceRange проверяет валюту и границы цены списка и переводит границы в
+// минорные единицы. Цены в разных валютах несравнимы, поэтому границы всегда
+// относятся к одной валюте, по умолчанию RUB.
+func preparePriceRange(query *domain.ListSubscriptionsQuery) error {
+	if query.Currency == "" && (query.MinPrice != "" || query.MaxPrice != "") {
+		query.Currency = domain.DefaultCurrency
+	}
+	if query.Currency != "" && !query.Currency.Valid() {
+		return fmt.Errorf("%w: unsupported currency %q", ErrValidation, query.Currency)
+	}
+
+	bound := func(name, value string) (*int64, error) {
+		if value == "" {
+			return nil, nil
+		}
+		price, err := domain.ParseMoney(value, query.Currency)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s: %w", ErrValidation, name, err)
+		}
+		if price.Amount < 0 {
+			return nil, fmt.Errorf("%w: %s must not be negative", ErrValidation, name)
+		}
+		return &price.Amount, nil
+	}
+	var err error
+	if query.MinPriceMinor, err = bound("min_price", query.MinPrice); err != nil {
+		return err
+	}
+	if query.MaxPriceMinor, err = bound("max_price", query.MaxPrice); err != nil {
+		return err
+	}
+	if query.MinPriceMinor != nil && query.MaxPriceMinor != nil && *query.MinPriceMinor > *query.MaxPriceMinor {
+		return fmt.Errorf("%w: min_price must not be greater than max_price", ErrValidation)
+	}
+	return nil
+}
+
+func (s *SubscriptionService) List(ctx context.Context, query domain.ListSubscriptionsQuery) (*domain.ListSubscriptionsResponse, error) {
+	keys, err := s.serviceKeys(ctx, query.ServiceName)
+	if err != nil {
+		return nil, err
+	}
+	query.ServiceKeys = keys
+	if query.Tags, err = normalizeTags(query.Tags); err != nil {
+		return nil, err
+	}
+	query.Q = strings.TrimSpace(query.Q)
+	if utf8.RuneCountInString(query.Q) > maxSearchQueryLength {
+		return nil, fmt.Errorf("%w: q must be at most %d characters", ErrValidation, maxSearchQueryLength)
+	}
+	// Снимок отсекает подписки, созданные после первой страницы: они сдвигали бы
+	// смещение, и страницы повторяли бы подписки
+	snapshot := clock.Now(ctx)
+	if query.Snapshot != "" {
+		if snapshot, err = domain.DecodeListSnapshot(query.Snapshot); err != nil {
+			return nil, fmt.Errorf("%w: snapshot: %w", ErrValidation, err)
+		}
+	}
+	query.CreatedBefore = &snapshot
+	if query.SortBy != "" && !slices.Contains(domain.ListSortFields, query.SortBy) {
+		return nil, fmt.Errorf("%w: sort_by must be one of %v", ErrValidation, domain.ListSortFields)
+	}
+	if query.Order != "" && query.Order != domain.SortAsc && query.Order != domain.SortDesc {
+		return nil, fmt.Errorf("%w: order must be asc or desc", ErrValidation)
+	}
+	if query.Match != "" && query.Match != domain.SearchContains && query.Match != domain.SearchFuzzy {
+		return nil, fmt.Errorf("%w: match must be contains or fuzzy", ErrValidation)
+	}
+	if err := preparePriceRange(&query); err != nil {
+		return nil, err
+	}
+	if query.ActiveIn != "" {
+		month, err := domain.ParsePeriod(query.ActiveIn)
+		if err != nil {
+			return nil, fmt.Errorf("%w: active_in: %w", ErrValidation, err)
+		}
+		query.ActiveMonth = &month
+	}
+	if query.CustomFieldFilter, err = customFieldSchema(ctx).ParseFilters(query.CustomField); err != nil {
+		return nil, fmt.Errorf("%w: custom_field: %w", ErrValidation, err)
+	}
+	limit := query.Limit
+	if query.Cursor != "" {
+		if query.Offset > 0 {
+			return nil, fmt.Errorf("%w: cursor and offset are mutually exclusive", ErrValidation)
+		}
+		if !query.DefaultSort() {
+			return nil, fmt.Errorf("%w: cursor supports only the default order, use offset with sort_by", ErrValidation)
+		}
+		after, err := domain.DecodeListCursor(query.Cursor)
+		if err != nil {
+			return nil, fmt.Errorf("%w: cursor: %w", ErrValidation, err)
+		}
+		query.After = &after
+		// Смещение страницы с курсором неизвестно, поэтому продолжение видно по лишней подписке
+		query.Limit++
+	}
+
 	subscriptions, err := s.repo.List(ctx, query)
 	if err != nil {
 		s.logger.ErrorContext(ctx, "failed to list subscriptions",
@@ -125,12 +723,111 @@ func (s *SubscriptionService) List(ctx context.Context, query domain.ListSubscri
 		)
 		return nil, err
 	}
+	hasMore := query.After != nil && len(subscriptions) > limit
+	if hasMore {
+		subscriptions = subscriptions[:limit]
+	}
+
+	total, err := s.repo.Count(ctx, query)
+	if err != nil {
+		s.logger.ErrorContext(ctx, "failed to count subscriptions",
+			slog.String("error", err.Error()),
+		)
+		return nil, err
+	}
+
+	metering.Record(ctx, domain.UsageExportedRows, int64(len(subscriptions)))
+
+	if query.Cursor == "" {
+		hasMore = query.Offset+len(subscriptions) < total
+	}
+	resp := &domain.ListSubscriptionsResponse{
+		Items:      subscriptions,
+		TotalCount: total,
+		Limit:      limit,
+		Offset:     query.Offset,
+		HasMore:    hasMore,
+		Snapshot:   domain.EncodeListSnapshot(snapshot),
+	}
+	// Курсор хранит позицию только в порядке по умолчанию
+	if hasMore && len(subscriptions) > 0 && query.DefaultSort() {
+		last := subscriptions[len(subscriptions)-1]
+		resp.NextCursor = domain.ListCursor{CreatedAt: last.CreatedAt, ID: last.ID}.Encode()
+	}
+	return resp, nil
+}
+
+// uniqueIDs убирает повторы, сохраняя порядок первого появления.
+func uniqueIDs(ids []uuid.UUID) []uuid.UUID {
+	if len(ids) == 0 {
+		return ids
+	}
+	seen := make(map[uuid.UUID]bool, len(ids))
+	unique := ids[:0:0]
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			unique = append(unique, id)
+		}
+	}
+	return unique
+}
+
+// prepareTotalRequest проверяет период и валюты расчета и дополняет фильтры:
+// валюту по умолчанию, ключи сервиса с учетом алиасов и нормализованные метки.
+func (s *SubscriptionService) prepareTotalRequest(ctx context.Context, req *domain.CalculateTotalRequest) (time.Time, time.Time, error) {
+	start, err := domain.ParsePeriod(req.StartPeriod)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("%w: start_period: %w", ErrValidation, err)
+	}
+	end, err := domain.ParsePeriod(req.EndPeriod)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("%w: end_period: %w", ErrValidation, err)
+	}
+	if end.Before(start) {
+		return time.Time{}, time.Time{}, fmt.Errorf("%w: end_period must not be before start_period", ErrValidation)
+	}
+	if req.UserID != nil && len(req.UserIDs) > 0 {
+		return time.Time{}, time.Time{}, fmt.Errorf("%w: user_id and user_ids are mutually exclusive", ErrValidation)
+	}
+	if len(req.UserIDs) > domain.MaxCalculateUsers {
+		return time.Time{}, time.Time{}, fmt.Errorf("%w: user_ids must not contain more than %d users", ErrValidation, domain.MaxCalculateUsers)
+	}
+	req.UserIDs = uniqueIDs(req.UserIDs)
+	switch {
+	case req.TargetCurrency != "" && req.Currency != "":
+		return time.Time{}, time.Time{}, fmt.Errorf("%w: currency and target_currency are mutually exclusive", ErrValidation)
+	case req.TargetCurrency != "":
+		if !req.TargetCurrency.Valid() {
+			return time.Time{}, time.Time{}, fmt.Errorf("%w: unsupported target_currency %q", ErrValidation, req.TargetCurrency)
+		}
+	case req.Currency == "":
+		req.Currency = domain.DefaultCurrency
+	}
+	if req.Currency != "" && !req.Currency.Valid() {
+		return time.Time{}, time.Time{}, fmt.Errorf("%w: unsupported currency %q", ErrValidation, req.Currency)
+	}
 
-	return subscriptions, nil
+	keys, err := s.serviceKeys(ctx, req.ServiceName)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	req.ServiceKeys = keys
+	if req.Tags, err = normalizeTags(req.Tags); err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	req.Rounding = moneyFormat(ctx).Rounding
+	return start, end, nil
 }
 
 func (s *SubscriptionService) CalculateTotal(ctx context.Context, req domain.CalculateTotalRequest) (*domain.CalculateTotalResponse, error) {
-	total, err := s.repo.CalculateTotal(ctx, req)
+	start, end, err := s.prepareTotalRequest(ctx, &req)
+	if err != nil {
+		return nil, err
+	}
+	req.OpenEndedUntil = openEndedPolicy(ctx).TotalUntil(clock.Now(ctx))
+
+	totals, err := s.repo.CalculateTotal(ctx, req)
 	if err != nil {
 		s.logger.ErrorContext(ctx, "failed to calculate total",
 			slog.String("error", err.Error()),
@@ -138,9 +835,106 @@ func (s *SubscriptionService) CalculateTotal(ctx context.Context, req domain.Cal
 		return nil, err
 	}
 
+	// Без target_currency сумма считается только в валюте currency
+	settle := func(totals domain.Totals) (domain.Money, error) {
+		return totals.Get(req.Currency), nil
+	}
+	resp := &domain.CalculateTotalResponse{}
+	if req.TargetCurrency != "" {
+		rates, err := s.rates.Rates(ctx)
+		if err != nil {
+			s.logger.ErrorContext(ctx, "failed to load exchange rates",
+				slog.String("error", err.Error()),
+			)
+			return nil, err
+		}
+		if resp.Conversion, err = newConversion(rates, totals, req.TargetCurrency); err != nil {
+			return nil, err
+		}
+		settle = func(totals domain.Totals) (domain.Money, error) {
+			return rates.ConvertTotals(totals, req.TargetCurrency)
+		}
+	}
+	settle = formatSettle(moneyFormat(ctx), settle)
+
+	if resp.TotalCost, err = settle(totals); err != nil {
+		return nil, err
+	}
+
 	s.logger.InfoContext(ctx, "total calculated",
-		slog.Int("total", total),
+		slog.Int64("total", resp.TotalCost.Amount),
+		slog.String("currency", string(resp.TotalCost.Currency)),
+		slog.Int("users", len(req.UserIDs)),
 	)
 
-	return &domain.CalculateTotalResponse{TotalCost: total}, nil
+	if len(req.UserIDs) > 0 {
+		if resp.ByUser, err = s.totalByUser(ctx, req, settle); err != nil {
+			return nil, err
+		}
+	}
+
+	if req.GroupBy == "classification" {
+		byClass, err := s.totalByClassification(ctx, req, start, end)
+		if err != nil {
+			return nil, err
+		}
+		resp.ByClassification = make(map[domain.BillingClass]domain.Money, len(byClass))
+		for class, classTotals := range byClass {
+			if resp.ByClassification[class], err = settle(classTotals); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if req.IncludeItems {
+		months, err := s.billedMonths(ctx, req, start, end)
+		if err != nil {
+			return nil, err
+		}
+		if resp.Items, err = totalItems(months, moneyFormat(ctx)); err != nil {
+			return nil, err
+		}
+	}
+
+	return resp, nil
+}
+
+// totalByUser считает подытоги пользователей из req.UserIDs одним запросом с
+// разбивкой по пользователям. Подытоги округляются каждый отдельно, поэтому их
+// сумма может отличаться от общей суммы на доли копейки каждого пользователя.
+func (s *SubscriptionService) totalByUser(ctx context.Context, req domain.CalculateTotalRequest, settle func(domain.Totals) (domain.Money, error)) ([]domain.UserTotal, error) {
+	byUser, err := s.repo.CalculateTotalByUser(ctx, req)
+	if err != nil {
+		s.logger.ErrorContext(ctx, "failed to calculate totals by user",
+			slog.String("error", err.Error()),
+		)
+		return nil, err
+	}
+
+	result := make([]domain.UserTotal, len(req.UserIDs))
+	for i, userID := range req.UserIDs {
+		result[i].UserID = userID
+		if result[i].TotalCost, err = settle(byUser[userID]); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+// newConversion описывает пересчет: исходные суммы и курс каждой валюты к целевой.
+func newConversion(rates *exchange.Rates, totals domain.Totals, target domain.Currency) (*domain.CurrencyConversion, error) {
+	conversion := &domain.CurrencyConversion{
+		Source:    rates.Source,
+		RatesDate: rates.Date,
+		Totals:    totals,
+		Rates:     make(map[domain.Currency]string, len(totals)),
+	}
+	for currency := range totals {
+		rate, err := rates.Rate(currency, target)
+		if err != nil {
+			return nil, err
+		}
+		conversion.Rates[currency] = exchange.FormatRate(rate)
+	}
+	return conversion, nil
 }
@@ -0,0 +1,460 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"aggregator_db/internal/clock"
+	"aggregator_db/internal/exchange"
+	"aggregator_db/internal/repository/memory"
+	"aggregator_db/internal/repository/postgres"
+	"aggregator_db/internal/tenancy"
+	"aggregator_db/pkg/domain"
+	"github.com/google/uuid"
+)
+
+func TestUpdateVersionConflict(t *testing.T) {
+	ctx := context.Background()
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	repo := memory.NewSubscriptionRepository()
+	sub := &domain.Subscription{ID: uuid.New(), ServiceName: "Netflix", Price: domain.NewMoney(90000, domain.DefaultCurrency), StartDate: "01-2025"}
+	if err := repo.Create(ctx, sub); err != nil {
+		t.Fatal(err)
+	}
+	svc := NewSubscriptionService(repo, memory.NewTransactor(), memory.NewServiceAliasRepository(), &recordingPublisher{}, exchange.NewStaticProvider(domain.DefaultCurrency, nil), logger)
+
+	version := func(v int64) *int64 { return &v }
+	updated, err := svc.Update(ctx, sub.ID, domain.UpdateSubscriptionRequest{
+		AutoRenew: domain.Optional[bool]{Set: true, Value: true},
+		Version:   version(1),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if updated.Version != 2 {
+		t.Errorf("version after update = %d, want 2", updated.Version)
+	}
+
+	// Второй клиент прочитал подписку до первого изменения
+	_, err = svc.Replace(ctx, sub.ID, domain.ReplaceSubscriptionRequest{
+		ServiceName: "Netflix Premium", Price: domain.NewMoney(120000, domain.DefaultCurrency), StartDate: "01-2025",
+		Version: version(1),
+	})
+	if !errors.Is(err, postgres.ErrVersionConflict) {
+		t.Fatalf("stale replace: err = %v, want ErrVersionConflict", err)
+	}
+
+	// Без версии изменение применяется к текущей
+	if _, err := svc.Update(ctx, sub.ID, domain.UpdateSubscriptionRequest{AutoRenew: domain.Optional[bool]{Set: true}}); err != nil {
+		t.Fatal(err)
+	}
+	current, err := repo.GetByID(ctx, sub.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if current.ServiceName != "Netflix" || current.AutoRenew || current.Version != 3 {
+		t.Errorf("subscription = %s auto_renew=%v version=%d, want Netflix false 3", current.ServiceName, current.AutoRenew, current.Version)
+	}
+
+	// Запись с версией, прочитанной до чужого изменения, отклоняет репозиторий
+	stale := *current
+	stale.Version = 2
+	if err := repo.Update(ctx, &stale); !errors.Is(err, postgres.ErrVersionConflict) {
+		t.Errorf("stale repository update: err = %v, want ErrVersionConflict", err)
+	}
+}
+
+func TestListSnapshot(t *testing.T) {
+	ctx := context.Background()
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	repo := memory.NewSubscriptionRepository()
+	userID := uuid.New()
+	createdAt := time.Date(2025, 1, 15, 12, 0, 0, 0, time.UTC)
+	for i := 0; i < 3; i++ {
+		sub := &domain.Subscription{ID: uuid.New(), UserID: userID, ServiceName: "Netflix", Price: domain.NewMoney(90000, domain.DefaultCurrency),
+			StartDate: "01-2025", CreatedAt: createdAt.Add(time.Duration(i) * time.Hour)}
+		if err := repo.Create(ctx, sub); err != nil {
+			t.Fatal(err)
+		}
+	}
+	svc := NewSubscriptionService(repo, memory.NewTransactor(), memory.NewServiceAliasRepository(), &recordingPublisher{}, exchange.NewStaticProvider(domain.DefaultCurrency, nil), logger)
+
+	first, err := svc.List(ctx, domain.ListSubscriptionsQuery{UserID: &userID, Limit: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Подписка, созданная между страницами, встает первой и сдвигает смещение
+	if _, err := svc.Create(ctx, domain.CreateSubscriptionRequest{UserID: userID, ServiceName: "Spotify",
+		Price: domain.NewMoney(30000, domain.DefaultCurrency), StartDate: "01-2025"}); err != nil {
+		t.Fatal(err)
+	}
+
+	second, err := svc.List(ctx, domain.ListSubscriptionsQuery{UserID: &userID, Limit: 2, Offset: 2, Snapshot: first.Snapshot})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if second.TotalCount != 3 || len(second.Items) != 1 || second.HasMore {
+		t.Fatalf("second page = %d items of %d, has_more=%v; want the last of 3", len(second.Items), second.TotalCount, second.HasMore)
+	}
+	for _, seen := range first.Items {
+		if seen.ID == second.Items[0].ID {
+			t.Errorf("subscription %s is on both pages", seen.ID)
+		}
+	}
+	if second.Snapshot != first.Snapshot {
+		t.Errorf("snapshot = %s, want %s from the first page", second.Snapshot, first.Snapshot)
+	}
+
+	if _, err := svc.List(ctx, domain.ListSubscriptionsQuery{Limit: 2, Snapshot: "yesterday"}); !errors.Is(err, domain.ErrInvalidContinuation) {
+		t.Errorf("invalid snapshot error = %v", err)
+	}
+}
+
+func TestListCursor(t *testing.T) {
+	ctx := context.Background()
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	repo := memory.NewSubscriptionRepository()
+	userID := uuid.New()
+	createdAt := time.Date(2025, 1, 15, 12, 0, 0, 0, time.UTC)
+	// Две пары подписок созданы одновременно: курсор различает их по id
+	for i := 0; i < 5; i++ {
+		sub := &domain.Subscription{ID: uuid.New(), UserID: userID, ServiceName: "Netflix", Price: domain.NewMoney(90000, domain.DefaultCurrency),
+			StartDate: "01-2025", CreatedAt: createdAt.Add(time.Duration(i/2) * time.Hour)}
+		if err := repo.Create(ctx, sub); err != nil {
+			t.Fatal(err)
+		}
+	}
+	svc := NewSubscriptionService(repo, memory.NewTransactor(), memory.NewServiceAliasRepository(), &recordingPublisher{}, exchange.NewStaticProvider(domain.DefaultCurrency, nil), logger)
+
+	all, err := svc.List(ctx, domain.ListSubscriptionsQuery{UserID: &userID, Limit: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if all.NextCursor != "" {
+		t.Errorf("next_cursor on the only page = %q, want empty", all.NextCursor)
+	}
+
+	page, err := svc.List(ctx, domain.ListSubscriptionsQuery{UserID: &userID, Limit: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	seen := page.Items
+	for page.HasMore {
+		// Подписка, созданная между страницами, встает первой и не сдвигает следующие
+		if _, err := svc.Create(ctx, domain.CreateSubscriptionRequest{UserID: userID, ServiceName: "Spotify",
+			Price: domain.NewMoney(30000, domain.DefaultCurrency), StartDate: "01-2025"}); err != nil {
+			t.Fatal(err)
+		}
+		if page, err = svc.List(ctx, domain.ListSubscriptionsQuery{UserID: &userID, Limit: 2, Cursor: page.NextCursor}); err != nil {
+			t.Fatal(err)
+		}
+		seen = append(seen, page.Items...)
+	}
+	if len(seen) != len(all.Items) {
+		t.Fatalf("cursor pages returned %d subscriptions, want %d", len(seen), len(all.Items))
+	}
+	for i := range seen {
+		if seen[i].ID != all.Items[i].ID {
+			t.Errorf("item %d = %s, want %s", i, seen[i].ID, all.Items[i].ID)
+		}
+	}
+
+	if _, err := svc.List(ctx, domain.ListSubscriptionsQuery{Limit: 2, Cursor: "yesterday"}); !errors.Is(err, domain.ErrInvalidContinuation) {
+		t.Errorf("invalid cursor error = %v", err)
+	}
+	if _, err := svc.List(ctx, domain.ListSubscriptionsQuery{Limit: 2, Offset: 2, Cursor: all.Snapshot}); !errors.Is(err, ErrValidation) {
+		t.Errorf("cursor with offset error = %v", err)
+	}
+}
+
+func TestListSort(t *testing.T) {
+	ctx := context.Background()
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	repo := memory.NewSubscriptionRepository()
+	userID := uuid.New()
+	createdAt := time.Date(2025, 1, 15, 12, 0, 0, 0, time.UTC)
+	for i, sub := range []struct {
+		service string
+		price   int64
+		start   string
+	}{
+		{"Spotify", 29900, "03-2024"},
+		{"Netflix", 59900, "11-2024"},
+		{"Apple Music", 16900, "02-2025"},
+		{"YouTube Premium", 19900, "06-2023"},
+	} {
+		if err := repo.Create(ctx, &domain.Subscription{ID: uuid.New(), UserID: userID, ServiceName: sub.service,
+			Price: domain.NewMoney(sub.price, domain.DefaultCurrency), StartDate: sub.start, CreatedAt: createdAt.Add(time.Duration(i) * time.Hour)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	svc := NewSubscriptionService(repo, memory.NewTransactor(), memory.NewServiceAliasRepository(), &recordingPublisher{}, exchange.NewStaticProvider(domain.DefaultCurrency, nil), logger)
+
+	services := func(query domain.ListSubscriptionsQuery) []string {
+		t.Helper()
+		query.UserID, query.Limit = &userID, 10
+		resp, err := svc.List(ctx, query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		names := make([]string, 0, len(resp.Items))
+		for _, sub := range resp.Items {
+			names = append(names, sub.ServiceName)
+		}
+		return names
+	}
+	cases := []struct {
+		query domain.ListSubscriptionsQuery
+		want  []string
+	}{
+		{domain.ListSubscriptionsQuery{}, []string{"YouTube Premium", "Apple Music", "Netflix", "Spotify"}},
+		{domain.ListSubscriptionsQuery{SortBy: domain.SortByCreatedAt, Order: domain.SortAsc}, []string{"Spotify", "Netflix", "Apple Music", "YouTube Premium"}},
+		{domain.ListSubscriptionsQuery{SortBy: domain.SortByServiceName}, []string{"Apple Music", "Netflix", "Spotify", "YouTube Premium"}},
+		{domain.ListSubscriptionsQuery{SortBy: domain.SortByStartDate}, []string{"YouTube Premium", "Spotify", "Netflix", "Apple Music"}},
+		{domain.ListSubscriptionsQuery{SortBy: domain.SortByPrice, Order: domain.SortDesc}, []string{"Netflix", "Spotify", "YouTube Premium", "Apple Music"}},
+	}
+	for _, c := range cases {
+		if got := services(c.query); !slices.Equal(got, c.want) {
+			t.Errorf("sort_by=%s order=%s: %v, want %v", c.query.SortBy, c.query.Order, got, c.want)
+		}
+	}
+
+	// Страницы со смещением продолжают сортировку, курсор - только порядок по умолчанию
+	page, err := svc.List(ctx, domain.ListSubscriptionsQuery{UserID: &userID, Limit: 2, Offset: 2, SortBy: domain.SortByServiceName})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Items) != 2 || page.Items[0].ServiceName != "Spotify" || page.HasMore || page.NextCursor != "" {
+		t.Errorf("second page by service_name = %+v", page)
+	}
+	first, err := svc.List(ctx, domain.ListSubscriptionsQuery{UserID: &userID, Limit: 2, SortBy: domain.SortByPrice})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !first.HasMore || first.NextCursor != "" {
+		t.Errorf("first page by price: has_more %v, next_cursor %q", first.HasMore, first.NextCursor)
+	}
+	cursor := domain.ListCursor{CreatedAt: createdAt, ID: uuid.New()}.Encode()
+	if _, err := svc.List(ctx, domain.ListSubscriptionsQuery{Limit: 2, Cursor: cursor, SortBy: domain.SortByPrice}); !errors.Is(err, ErrValidation) {
+		t.Errorf("cursor with sort_by error = %v", err)
+	}
+	if _, err := svc.List(ctx, domain.ListSubscriptionsQuery{Limit: 2, SortBy: "price_minor"}); !errors.Is(err, ErrValidation) {
+		t.Errorf("unknown sort_by error = %v", err)
+	}
+}
+
+func TestSubscriptionQuotaConcurrent(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	repo := memory.NewSubscriptionRepository()
+	svc := NewSubscriptionService(repo, memory.NewTransactor(), memory.NewServiceAliasRepository(), &recordingPublisher{}, exchange.NewStaticProvider(domain.DefaultCurrency, nil), logger)
+
+	limit := 5
+	ctx := tenancy.WithTenant(context.Background(), &domain.Tenant{ID: "acme", Quotas: domain.TenantQuotas{MaxSubscriptions: &limit}})
+	req := domain.CreateSubscriptionRequest{
+		ServiceName: "Netflix",
+		Price:       domain.NewMoney(90000, domain.DefaultCurrency),
+		UserID:      uuid.New(),
+		StartDate:   "01-2025",
+	}
+
+	// Проверка квоты и вставка идут одной единицей работы, поэтому параллельные
+	// запросы не превышают лимит
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	created, rejected := 0, 0
+	for range 20 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := svc.Create(ctx, req)
+			mu.Lock()
+			defer mu.Unlock()
+			switch {
+			case err == nil:
+				created++
+			case errors.Is(err, ErrQuotaExceeded):
+				rejected++
+			default:
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if created != limit || rejected != 20-limit {
+		t.Errorf("created %d, rejected %d; want %d and %d", created, rejected, limit, 20-limit)
+	}
+	if count, err := repo.Count(context.Background(), domain.ListSubscriptionsQuery{}); err != nil || count != limit {
+		t.Errorf("stored %d subscriptions (%v), want %d", count, err, limit)
+	}
+}
+
+func TestCreateWithClientID(t *testing.T) {
+	ctx := context.Background()
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	repo := memory.NewSubscriptionRepository()
+	svc := NewSubscriptionService(repo, memory.NewTransactor(), memory.NewServiceAliasRepository(), &recordingPublisher{}, exchange.NewStaticProvider(domain.DefaultCurrency, nil), logger)
+
+	id := uuid.New()
+	req := domain.CreateSubscriptionRequest{
+		ID:          &id,
+		ServiceName: "Netflix",
+		Price:       domain.NewMoney(90000, domain.DefaultCurrency),
+		UserID:      uuid.New(),
+		StartDate:   "01-2025",
+	}
+	sub, err := svc.Create(ctx, req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sub.ID != id {
+		t.Errorf("id = %s, want client id %s", sub.ID, id)
+	}
+	if _, err := svc.Create(ctx, req); !errors.Is(err, postgres.ErrAlreadyExists) {
+		t.Errorf("second create err = %v, want ErrAlreadyExists", err)
+	}
+
+	nilID := uuid.Nil
+	req.ID = &nilID
+	if _, err := svc.Create(ctx, req); !errors.Is(err, ErrValidation) {
+		t.Errorf("nil id err = %v, want ErrValidation", err)
+	}
+
+	// Повтор ID внутри пачки отклоняется до записи с ошибкой у повторного элемента
+	other := uuid.New()
+	first, second := req, req
+	first.ID, second.ID = &other, &other
+	resp, err := svc.CreateBulk(ctx, []domain.CreateSubscriptionRequest{first, second})
+	if !errors.Is(err, ErrValidation) {
+		t.Fatalf("bulk err = %v, want ErrValidation", err)
+	}
+	if resp.Items[0].Error != "" || resp.Items[1].Error != "validation error: id duplicates item 0" {
+		t.Errorf("bulk items = %+v", resp.Items)
+	}
+	if _, err := repo.GetByID(ctx, other); !errors.Is(err, postgres.ErrNotFound) {
+		t.Errorf("bulk with duplicate ids stored a subscription: %v", err)
+	}
+}
+
+func TestCustomFields(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	repo := memory.NewSubscriptionRepository()
+	svc := NewSubscriptionService(repo, memory.NewTransactor(), memory.NewServiceAliasRepository(), &recordingPublisher{}, exchange.NewStaticProvider(domain.DefaultCurrency, nil), logger)
+
+	ctx := tenancy.WithTenant(context.Background(), &domain.Tenant{ID: "acme", CustomFields: domain.CustomFieldSchema{
+		{Name: "po_number", Type: domain.CustomFieldString, Required: true},
+		{Name: "seats", Type: domain.CustomFieldNumber},
+	}})
+	req := domain.CreateSubscriptionRequest{
+		ServiceName:  "Slack",
+		Price:        domain.NewMoney(90000, domain.DefaultCurrency),
+		UserID:       uuid.New(),
+		StartDate:    "01-2025",
+		CustomFields: domain.CustomFieldValues{"po_number": "PO-1", "seats": 25.0},
+	}
+	slack, err := svc.Create(ctx, req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.ServiceName, req.CustomFields = "Zoom", domain.CustomFieldValues{"po_number": "PO-2"}
+	if _, err := svc.Create(ctx, req); err != nil {
+		t.Fatal(err)
+	}
+
+	// Без обязательного поля подписка не создается; без тенанта полей нет вовсе
+	req.CustomFields = domain.CustomFieldValues{"seats": 3.0}
+	if _, err := svc.Create(ctx, req); !errors.Is(err, ErrValidation) {
+		t.Errorf("create without required field: err = %v, want ErrValidation", err)
+	}
+	if _, err := svc.Create(context.Background(), req); !errors.Is(err, ErrValidation) {
+		t.Errorf("create without tenant: err = %v, want ErrValidation", err)
+	}
+	if _, err := svc.Update(ctx, slack.ID, domain.UpdateSubscriptionRequest{CustomFields: domain.Optional[domain.CustomFieldValues]{Set: true, Null: true}}); !errors.Is(err, ErrValidation) {
+		t.Errorf("clearing required field: err = %v, want ErrValidation", err)
+	}
+
+	resp, err := svc.List(ctx, domain.ListSubscriptionsQuery{Limit: 10, CustomField: []string{"seats:25", "po_number:PO-1"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Items) != 1 || resp.Items[0].ID != slack.ID {
+		t.Errorf("filtered list = %+v", resp.Items)
+	}
+	if _, err := svc.List(ctx, domain.ListSubscriptionsQuery{Limit: 10, CustomField: []string{"seats:many"}}); !errors.Is(err, ErrValidation) {
+		t.Errorf("invalid filter: err = %v, want ErrValidation", err)
+	}
+}
+
+func TestBackfillStatus(t *testing.T) {
+	ctx := clock.WithClock(context.Background(), clock.Frozen{At: time.Date(2025, 10, 15, 12, 0, 0, 0, time.UTC)})
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	repo := memory.NewSubscriptionRepository()
+	svc := NewSubscriptionService(repo, memory.NewTransactor(), memory.NewServiceAliasRepository(), &recordingPublisher{}, exchange.NewStaticProvider(domain.DefaultCurrency, nil), logger)
+
+	status := func(s domain.SubscriptionStatus) *domain.SubscriptionStatus { return &s }
+	period := func(p string) *string { return &p }
+	updatedAt := time.Date(2022, 12, 31, 0, 0, 0, 0, time.UTC)
+	backfill := func(s *domain.SubscriptionStatus, endDate *string) (*domain.Subscription, error) {
+		return svc.Backfill(ctx, domain.BackfillSubscriptionRequest{
+			ServiceName: "Netflix",
+			Price:       domain.NewMoney(90000, domain.DefaultCurrency),
+			UserID:      uuid.New(),
+			StartDate:   "07-2021",
+			EndDate:     endDate,
+			CreatedAt:   time.Date(2021, 7, 1, 0, 0, 0, 0, time.UTC),
+			UpdatedAt:   &updatedAt,
+			Status:      s,
+		})
+	}
+
+	sub, err := backfill(nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sub.Status != domain.StatusActive || sub.CancelledAt != nil {
+		t.Errorf("default status = %s, cancelled_at = %v", sub.Status, sub.CancelledAt)
+	}
+
+	sub, err = backfill(status(domain.StatusCancelled), period("12-2022"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	stored, err := repo.GetByID(ctx, sub.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.Status != domain.StatusCancelled || stored.CancelledAt == nil || !stored.CancelledAt.Equal(updatedAt) {
+		t.Errorf("cancelled backfill: status = %s, cancelled_at = %v", stored.Status, stored.CancelledAt)
+	}
+
+	sub, err = backfill(status(domain.StatusExpired), period("09-2025"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sub.Status != domain.StatusExpired || sub.CancelledAt != nil {
+		t.Errorf("expired backfill: status = %s, cancelled_at = %v", sub.Status, sub.CancelledAt)
+	}
+
+	invalid := []struct {
+		name    string
+		status  domain.SubscriptionStatus
+		endDate *string
+	}{
+		{name: "cancelled without end_date", status: domain.StatusCancelled},
+		{name: "expired in current month", status: domain.StatusExpired, endDate: period("10-2025")},
+		{name: "cancelled in the future", status: domain.StatusCancelled, endDate: period("01-2026")},
+		{name: "paused", status: domain.StatusPaused, endDate: period("12-2022")},
+		{name: "unknown", status: "archived", endDate: period("12-2022")},
+	}
+	for _, tt := range invalid {
+		if _, err := backfill(status(tt.status), tt.endDate); !errors.Is(err, ErrValidation) {
+			t.Errorf("%s: err = %v, want ErrValidation", tt.name, err)
+		}
+	}
+}
@@ -0,0 +1,131 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"aggregator_db/internal/clock"
+	"aggregator_db/pkg/domain"
+)
+
+// healthCheckTimeout ограничивает одну проверку, чтобы зависшая база не задерживала
+// ответ дашборду.
+const healthCheckTimeout = 2 * time.Second
+
+// HealthCheck возвращает одну составляющую оценки состояния системы.
+type HealthCheck func(ctx context.Context) domain.HealthComponent
+
+// SystemHealthService сводит задержку базы, очереди, кэш и ошибки в одну оценку.
+type SystemHealthService struct {
+	checks []HealthCheck
+}
+
+func NewSystemHealthService(checks ...HealthCheck) *SystemHealthService {
+	return &SystemHealthService{checks: checks}
+}
+
+// Check выполняет проверки параллельно; порядок составляющих совпадает с порядком проверок.
+func (s *SystemHealthService) Check(ctx context.Context) *domain.SystemHealth {
+	ctx, cancel := context.WithTimeout(ctx, healthCheckTimeout)
+	defer cancel()
+
+	components := make([]domain.HealthComponent, len(s.checks))
+	var wg sync.WaitGroup
+	for i, check := range s.checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			components[i] = check(ctx)
+		}()
+	}
+	wg.Wait()
+
+	return domain.NewSystemHealth(components, clock.Now(ctx))
+}
+
+// failedComponent - составляющая, значение которой не удалось получить.
+func failedComponent(name, unit string, err error) domain.HealthComponent {
+	return domain.HealthComponent{Name: name, Status: domain.HealthCritical, Unit: unit, Detail: err.Error()}
+}
+
+// DatabaseLatencyCheck измеряет время ping базы в миллисекундах.
+func DatabaseLatencyCheck(ping func(ctx context.Context) error, thresholds domain.HealthThresholds) HealthCheck {
+	return func(ctx context.Context) domain.HealthComponent {
+		start := time.Now()
+		if err := ping(ctx); err != nil {
+			return failedComponent("db_latency", "ms", err)
+		}
+		latency := float64(time.Since(start).Microseconds()) / 1000
+		return thresholds.Component("db_latency", latency, "ms")
+	}
+}
+
+// BacklogCheck оценивает длину очереди name, которую возвращает size.
+func BacklogCheck(name string, size func(ctx context.Context) (int, error), thresholds domain.HealthThresholds) HealthCheck {
+	return func(ctx context.Context) domain.HealthComponent {
+		backlog, err := size(ctx)
+		if err != nil {
+			return failedComponent(name, "items", err)
+		}
+		return thresholds.Component(name, float64(backlog), "items")
+	}
+}
+
+// CircuitCheck проверяет circuit breaker исходящего клиента name: открытый breaker
+// означает, что доставка не работает. retryAfter - время до пробного запроса.
+func CircuitCheck(name string, retryAfter func() time.Duration) HealthCheck {
+	return func(context.Context) domain.HealthComponent {
+		wait := retryAfter()
+		if wait <= 0 {
+			return domain.HealthComponent{Name: name, Status: domain.HealthOK, Score: 100, Unit: "s"}
+		}
+		return domain.HealthComponent{Name: name, Status: domain.HealthCritical, Value: math.Ceil(wait.Seconds()), Unit: "s",
+			Detail: fmt.Sprintf("circuit breaker is open, next attempt in %s", wait.Round(time.Second))}
+	}
+}
+
+// RatioCheck оценивает долю в процентах по счетчикам с момента старта, которые
+// возвращает counts. Доля считается за последние от window до 2*window, а не за все
+// время работы, иначе всплеск ошибок после недели без них не был бы виден.
+func RatioCheck(name string, counts func() (part, total float64), window time.Duration, thresholds domain.HealthThresholds) HealthCheck {
+	started := ratioSample{at: time.Now()}
+	w := &ratioWindow{window: window, prev: started, cur: started}
+	return func(ctx context.Context) domain.HealthComponent {
+		part, total, since := w.observe(clock.Now(ctx), counts)
+		if total == 0 {
+			return domain.HealthComponent{Name: name, Status: domain.HealthOK, Score: 100, Unit: "%",
+				Detail: fmt.Sprintf("no data since %s", since.Format(time.RFC3339))}
+		}
+		component := thresholds.Component(name, math.Round(part/total*10000)/100, "%")
+		component.Detail = fmt.Sprintf("%.0f of %.0f since %s", part, total, since.Format(time.RFC3339))
+		return component
+	}
+}
+
+type ratioSample struct {
+	at          time.Time
+	part, total float64
+}
+
+// ratioWindow хранит значения счетчиков на начало двух окон: доля считается от
+// prev, а когда cur становится старше window, он занимает место prev.
+type ratioWindow struct {
+	window time.Duration
+
+	mu        sync.Mutex
+	prev, cur ratioSample
+}
+
+func (w *ratioWindow) observe(now time.Time, counts func() (float64, float64)) (part, total float64, since time.Time) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	currentPart, currentTotal := counts()
+	if now.Sub(w.cur.at) >= w.window {
+		w.prev, w.cur = w.cur, ratioSample{at: now, part: currentPart, total: currentTotal}
+	}
+	return currentPart - w.prev.part, currentTotal - w.prev.total, w.prev.at
+}
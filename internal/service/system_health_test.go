@@ -0,0 +1,90 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"aggregator_db/internal/clock"
+	"aggregator_db/pkg/domain"
+)
+
+func TestHealthThresholds(t *testing.T) {
+	latency := domain.HealthThresholds{Warn: 50, Critical: 500}
+	hitRate := domain.HealthThresholds{Warn: 80, Critical: 20}
+
+	cases := []struct {
+		thresholds domain.HealthThresholds
+		value      float64
+		score      int
+		status     domain.HealthStatus
+	}{
+		{latency, 10, 100, domain.HealthOK},
+		{latency, 275, 50, domain.HealthDegraded},
+		{latency, 900, 0, domain.HealthCritical},
+		// Для доли попаданий плохо низкое значение
+		{hitRate, 95, 100, domain.HealthOK},
+		{hitRate, 50, 50, domain.HealthDegraded},
+		{hitRate, 5, 0, domain.HealthCritical},
+	}
+	for _, tc := range cases {
+		got := tc.thresholds.Component("test", tc.value, "")
+		if got.Score != tc.score || got.Status != tc.status {
+			t.Errorf("%+v: value %v scored %d %s, want %d %s", tc.thresholds, tc.value, got.Score, got.Status, tc.score, tc.status)
+		}
+	}
+}
+
+func TestSystemHealth(t *testing.T) {
+	backlog := 110
+	svc := NewSystemHealthService(
+		DatabaseLatencyCheck(func(context.Context) error { return nil }, domain.HealthThresholds{Warn: 50, Critical: 500}),
+		BacklogCheck("export_jobs", func(context.Context) (int, error) { return backlog, nil }, domain.HealthThresholds{Warn: 20, Critical: 200}),
+		CircuitCheck("events_webhook", func() time.Duration { return 0 }),
+	)
+
+	health := svc.Check(context.Background())
+	if health.Status != domain.HealthDegraded || health.Score != 83 || len(health.Components) != 3 {
+		t.Fatalf("got %s with score %d and %d components, want degraded 83 with 3", health.Status, health.Score, len(health.Components))
+	}
+	if name := health.Components[1].Name; name != "export_jobs" {
+		t.Errorf("components reordered: got %q second", name)
+	}
+
+	failing := NewSystemHealthService(
+		DatabaseLatencyCheck(func(context.Context) error { return errors.New("connection refused") }, domain.HealthThresholds{Warn: 50, Critical: 500}),
+		CircuitCheck("events_webhook", func() time.Duration { return 30 * time.Second }),
+	)
+	health = failing.Check(context.Background())
+	if health.Status != domain.HealthCritical || health.Score != 0 {
+		t.Errorf("got %s with score %d, want critical 0", health.Status, health.Score)
+	}
+}
+
+func TestRatioCheck(t *testing.T) {
+	now := time.Now()
+	var errs, total float64
+	check := RatioCheck("http_error_rate", func() (float64, float64) { return errs, total },
+		5*time.Minute, domain.HealthThresholds{Warn: 1, Critical: 10})
+	at := func(offset time.Duration) domain.HealthComponent {
+		return check(clock.WithClock(context.Background(), clock.Frozen{At: now.Add(offset)}))
+	}
+
+	if got := at(0); got.Status != domain.HealthOK || got.Value != 0 {
+		t.Errorf("without requests got %+v, want ok", got)
+	}
+
+	errs, total = 50, 100
+	if got := at(time.Minute); got.Value != 50 || got.Status != domain.HealthCritical {
+		t.Errorf("got %+v, want 50%% critical", got)
+	}
+
+	// Через два окна без ошибок старый всплеск больше не учитывается
+	total = 200
+	at(6 * time.Minute)
+	total = 300
+	if got := at(12 * time.Minute); got.Value != 0 || got.Status != domain.HealthOK {
+		t.Errorf("got %+v after the spike aged out, want 0%% ok", got)
+	}
+}
@@ -0,0 +1,339 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"maps"
+	"net/url"
+	"slices"
+
+	"aggregator_db/internal/clock"
+	"aggregator_db/internal/repository/postgres"
+	"aggregator_db/internal/tenancy"
+	"aggregator_db/pkg/domain"
+)
+
+type TenantService struct {
+	repo        postgres.TenantRepository
+	provisioner postgres.TenantProvisioner
+	subs        postgres.SubscriptionRepository
+	logger      *slog.Logger
+}
+
+func NewTenantService(repo postgres.TenantRepository, provisioner postgres.TenantProvisioner, subs postgres.SubscriptionRepository, logger *slog.Logger) *TenantService {
+	return &TenantService{repo: repo, provisioner: provisioner, subs: subs, logger: logger}
+}
+
+// Provision создает и мигрирует хранилище тенанта и только после этого
+// регистрирует его, чтобы запросы не попали в недомигрированную схему.
+func (s *TenantService) Provision(ctx context.Context, req domain.CreateTenantRequest) (*domain.TenantCredentials, error) {
+	if !domain.ValidTenantID(req.ID) {
+		return nil, fmt.Errorf("%w: id must match ^[a-z][a-z0-9_]{2,30}$", ErrValidation)
+	}
+	if req.ID == domain.SandboxTenantID {
+		return nil, fmt.Errorf("%w: id %s is reserved", ErrValidation, req.ID)
+	}
+
+	if _, err := s.repo.Get(ctx, req.ID); err == nil {
+		return nil, postgres.ErrTenantAlreadyExists
+	}
+
+	apiKey, err := domain.NewTenantAPIKey()
+	if err != nil {
+		return nil, err
+	}
+
+	now := clock.Now(ctx)
+	tenant := &domain.Tenant{
+		ID:         req.ID,
+		Isolation:  domain.TenantIsolationSchema,
+		SchemaName: domain.TenantSchemaName(req.ID),
+		Status:     domain.TenantStatusActive,
+		Features:   map[string]bool{},
+		APIKeyHash: domain.HashAPIKey(apiKey),
+		CreatedAt:  now,
+		UpdatedAt:  now,
+	}
+	if req.DatabaseURL != nil {
+		if err := validateDatabaseURL(*req.DatabaseURL); err != nil {
+			return nil, err
+		}
+		tenant.Isolation = domain.TenantIsolationDatabase
+		tenant.SchemaName = "public"
+		tenant.DatabaseURL = *req.DatabaseURL
+	}
+
+	if err := s.provisioner.Provision(ctx, tenant); err != nil {
+		s.logger.ErrorContext(ctx, "failed to provision tenant",
+			slog.String("tenant_id", tenant.ID),
+			slog.String("error", err.Error()),
+		)
+		return nil, err
+	}
+
+	if err := s.repo.Create(ctx, tenant); err != nil {
+		return nil, err
+	}
+
+	s.logger.InfoContext(ctx, "tenant provisioned",
+		slog.String("tenant_id", tenant.ID),
+		slog.String("isolation", string(tenant.Isolation)),
+		slog.String("schema", tenant.SchemaName),
+	)
+
+	return &domain.TenantCredentials{Tenant: tenant, APIKey: apiKey}, nil
+}
+
+func validateDatabaseURL(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil || raw == "" || (u.Scheme != "postgres" && u.Scheme != "postgresql") {
+		return fmt.Errorf("%w: database_url must be a postgres:// URL", ErrValidation)
+	}
+	return nil
+}
+
+func (s *TenantService) Get(ctx context.Context, id string) (*domain.Tenant, error) {
+	return s.repo.Get(ctx, id)
+}
+
+func (s *TenantService) List(ctx context.Context) ([]*domain.Tenant, error) {
+	return s.repo.List(ctx)
+}
+
+// SetStatus приостанавливает или возобновляет тенанта. Приостановленный
+// тенант получает 403 на все запросы, его соединения закрываются.
+func (s *TenantService) SetStatus(ctx context.Context, id string, status domain.TenantStatus) (*domain.Tenant, error) {
+	return s.update(ctx, id, func(tenant *domain.Tenant) error {
+		tenant.Status = status
+		return nil
+	})
+}
+
+func (s *TenantService) SetQuotas(ctx context.Context, id string, quotas domain.TenantQuotas) (*domain.Tenant, error) {
+	return s.update(ctx, id, func(tenant *domain.Tenant) error {
+		tenant.Quotas = quotas
+		return nil
+	})
+}
+
+// SetMoneyFormat задает правила округления и вывода итоговых сумм тенанта;
+// пустой формат возвращает правила по умолчанию.
+func (s *TenantService) SetMoneyFormat(ctx context.Context, id string, format domain.MoneyFormat) (*domain.Tenant, error) {
+	if err := format.Validate(); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrValidation, err)
+	}
+
+	return s.update(ctx, id, func(tenant *domain.Tenant) error {
+		if format == (domain.MoneyFormat{}) {
+			tenant.MoneyFormat = nil
+		} else {
+			tenant.MoneyFormat = &format
+		}
+		return nil
+	})
+}
+
+// SetOpenEndedPolicy задает, как считаются бессрочные подписки тенанта;
+// пустая настройка возвращает поведение по умолчанию.
+func (s *TenantService) SetOpenEndedPolicy(ctx context.Context, id string, policy domain.OpenEndedPolicy) (*domain.Tenant, error) {
+	if err := policy.Validate(); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrValidation, err)
+	}
+
+	return s.update(ctx, id, func(tenant *domain.Tenant) error {
+		if policy.Total == "" && policy.ForecastMonths == nil {
+			tenant.OpenEnded = nil
+		} else {
+			tenant.OpenEnded = &policy
+		}
+		return nil
+	})
+}
+
+// SetFieldVisibility задает поля, скрытые от ролей тенанта; пустые правила
+// возвращают правила FIELD_VISIBILITY. Роль с пустым списком видит все поля.
+func (s *TenantService) SetFieldVisibility(ctx context.Context, id string, visibility domain.FieldVisibility) (*domain.Tenant, error) {
+	if err := visibility.Validate(); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrValidation, err)
+	}
+
+	return s.update(ctx, id, func(tenant *domain.Tenant) error {
+		if len(visibility) == 0 {
+			tenant.FieldVisibility = nil
+		} else {
+			tenant.FieldVisibility = visibility
+		}
+		return nil
+	})
+}
+
+// SetCustomFields заменяет схему пользовательских полей подписок тенанта. Уже
+// сохраненные значения не пересчитываются: новая схема проверяет подписку при
+// следующем изменении.
+func (s *TenantService) SetCustomFields(ctx context.Context, id string, schema domain.CustomFieldSchema) (*domain.Tenant, error) {
+	if err := schema.Validate(); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrValidation, err)
+	}
+
+	return s.update(ctx, id, func(tenant *domain.Tenant) error {
+		if len(schema) == 0 {
+			tenant.CustomFields = nil
+		} else {
+			tenant.CustomFields = schema
+		}
+		return nil
+	})
+}
+
+// SetPinnedRates заменяет закрепленные курсы тенанта. Версия растет при каждом
+// изменении, в том числе при снятии закрепления, чтобы не повторяться.
+func (s *TenantService) SetPinnedRates(ctx context.Context, id string, req domain.UpdatePinnedRatesRequest) (*domain.Tenant, error) {
+	if err := req.Validate(); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrValidation, err)
+	}
+
+	return s.update(ctx, id, func(tenant *domain.Tenant) error {
+		rates := &domain.PinnedRates{
+			Base:      req.Base,
+			Values:    req.Values,
+			Version:   1,
+			UpdatedAt: clock.Now(ctx),
+		}
+		if rates.Values == nil {
+			rates.Values = make(map[domain.Currency]string)
+		}
+		if tenant.PinnedRates != nil {
+			rates.Version = tenant.PinnedRates.Version + 1
+		}
+		tenant.PinnedRates = rates
+		return nil
+	})
+}
+
+// SetFeatures меняет только переданные флаги, остальные сохраняются.
+func (s *TenantService) SetFeatures(ctx context.Context, id string, features map[string]bool) (*domain.Tenant, error) {
+	for name := range features {
+		if !slices.Contains(domain.TenantFeatures, name) {
+			return nil, fmt.Errorf("%w: unknown feature %q", ErrValidation, name)
+		}
+	}
+
+	return s.update(ctx, id, func(tenant *domain.Tenant) error {
+		merged := make(map[string]bool, len(tenant.Features)+len(features))
+		maps.Copy(merged, tenant.Features)
+		maps.Copy(merged, features)
+		tenant.Features = merged
+		return nil
+	})
+}
+
+// RotateCredentials выдает новый ключ доступа; старый перестает действовать сразу.
+func (s *TenantService) RotateCredentials(ctx context.Context, id string, req domain.RotateTenantCredentialsRequest) (*domain.TenantCredentials, error) {
+	apiKey, err := domain.NewTenantAPIKey()
+	if err != nil {
+		return nil, err
+	}
+
+	tenant, err := s.update(ctx, id, func(tenant *domain.Tenant) error {
+		if req.DatabaseURL != nil {
+			if tenant.Isolation != domain.TenantIsolationDatabase {
+				return fmt.Errorf("%w: database_url can be rotated only for tenants with a dedicated database", ErrValidation)
+			}
+			if err := validateDatabaseURL(*req.DatabaseURL); err != nil {
+				return err
+			}
+			tenant.DatabaseURL = *req.DatabaseURL
+		}
+		tenant.APIKeyHash = domain.HashAPIKey(apiKey)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &domain.TenantCredentials{Tenant: tenant, APIKey: apiKey}, nil
+}
+
+func (s *TenantService) update(ctx context.Context, id string, apply func(tenant *domain.Tenant) error) (*domain.Tenant, error) {
+	tenant, err := s.repo.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := apply(tenant); err != nil {
+		return nil, err
+	}
+	tenant.UpdatedAt = clock.Now(ctx)
+
+	if err := s.repo.Update(ctx, tenant); err != nil {
+		s.logger.ErrorContext(ctx, "failed to update tenant",
+			slog.String("tenant_id", id),
+			slog.String("error", err.Error()),
+		)
+		return nil, err
+	}
+
+	// Пул мог быть открыт со старыми учетными данными
+	s.provisioner.Disconnect(id)
+
+	s.logger.InfoContext(ctx, "tenant updated",
+		slog.String("tenant_id", id),
+		slog.String("status", string(tenant.Status)),
+	)
+
+	return tenant, nil
+}
+
+// Delete снимает тенанта с регистрации и удаляет его схему вместе с данными.
+func (s *TenantService) Delete(ctx context.Context, id string) error {
+	tenant, err := s.repo.Get(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if err := s.repo.Delete(ctx, id); err != nil {
+		return err
+	}
+
+	if err := s.provisioner.Deprovision(ctx, tenant); err != nil {
+		s.logger.ErrorContext(ctx, "failed to drop tenant storage",
+			slog.String("tenant_id", id),
+			slog.String("error", err.Error()),
+		)
+		return err
+	}
+
+	s.logger.InfoContext(ctx, "tenant deleted", slog.String("tenant_id", id))
+	return nil
+}
+
+func (s *TenantService) Usage(ctx context.Context, id string) (*domain.TenantUsage, error) {
+	tenant, err := s.repo.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return s.TenantUsage(ctx, tenant)
+}
+
+// TenantUsage считает потребление уже найденного тенанта, например тенанта запроса.
+func (s *TenantService) TenantUsage(ctx context.Context, tenant *domain.Tenant) (*domain.TenantUsage, error) {
+	tenantCtx := tenancy.WithTenant(ctx, tenant)
+	total, err := s.subs.Count(tenantCtx, domain.ListSubscriptionsQuery{})
+	if err != nil {
+		return nil, err
+	}
+	activeStatus := domain.StatusActive
+	active, err := s.subs.Count(tenantCtx, domain.ListSubscriptionsQuery{Status: &activeStatus})
+	if err != nil {
+		return nil, err
+	}
+
+	return &domain.TenantUsage{
+		TenantID:            tenant.ID,
+		Subscriptions:       total,
+		ActiveSubscriptions: active,
+		Requests:            int64(tenancy.RequestsTotal.Value(tenant.ID)),
+		Quotas:              tenant.Quotas,
+	}, nil
+}
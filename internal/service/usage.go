@@ -0,0 +1,114 @@
+package service
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"time"
+
+	"aggregator_db/internal/repository/postgres"
+	"aggregator_db/pkg/domain"
+)
+
+// maxUsageRangeDays ограничивает период одного запроса потребления.
+const maxUsageRangeDays = 366
+
+type UsageService struct {
+	repo postgres.UsageRepository
+}
+
+func NewUsageService(repo postgres.UsageRepository) *UsageService {
+	return &UsageService{repo: repo}
+}
+
+func validateUsageRange(from, to string) error {
+	start, err := time.Parse(domain.CalendarDateLayout, from)
+	if err != nil {
+		return fmt.Errorf("%w: from: expected YYYY-MM-DD", ErrValidation)
+	}
+	end, err := time.Parse(domain.CalendarDateLayout, to)
+	if err != nil {
+		return fmt.Errorf("%w: to: expected YYYY-MM-DD", ErrValidation)
+	}
+	if end.Before(start) {
+		return fmt.Errorf("%w: to must not be before from", ErrValidation)
+	}
+	if end.Sub(start) > maxUsageRangeDays*24*time.Hour {
+		return fmt.Errorf("%w: period must not exceed %d days", ErrValidation, maxUsageRangeDays)
+	}
+	return nil
+}
+
+// Usage возвращает суточные записи и итоги потребителя за период.
+func (s *UsageService) Usage(ctx context.Context, consumer, from, to string) (*domain.UsageResponse, error) {
+	if err := validateUsageRange(from, to); err != nil {
+		return nil, err
+	}
+
+	records, err := s.repo.List(ctx, domain.UsageQuery{Consumer: &consumer, From: from, To: to})
+	if err != nil {
+		return nil, err
+	}
+
+	totals := make(map[domain.UsageMetric]int64)
+	for _, record := range records {
+		totals[record.Metric] += record.Quantity
+	}
+
+	return &domain.UsageResponse{Consumer: consumer, From: from, To: to, Totals: totals, Records: records}, nil
+}
+
+func (s *UsageService) List(ctx context.Context, query domain.UsageQuery) ([]*domain.UsageRecord, error) {
+	if err := validateUsageRange(query.From, query.To); err != nil {
+		return nil, err
+	}
+	return s.repo.List(ctx, query)
+}
+
+// InvoiceLines сворачивает потребление за период в итоги по потребителю и метрике.
+func (s *UsageService) InvoiceLines(ctx context.Context, query domain.UsageQuery) ([]domain.UsageInvoiceLine, error) {
+	records, err := s.List(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	type key struct {
+		consumer string
+		metric   domain.UsageMetric
+	}
+	totals := make(map[key]int64)
+	for _, record := range records {
+		totals[key{record.Consumer, record.Metric}] += record.Quantity
+	}
+
+	lines := make([]domain.UsageInvoiceLine, 0, len(totals))
+	for k, quantity := range totals {
+		lines = append(lines, domain.UsageInvoiceLine{Consumer: k.consumer, Metric: k.metric, Quantity: quantity})
+	}
+	sort.Slice(lines, func(i, j int) bool {
+		if lines[i].Consumer != lines[j].Consumer {
+			return lines[i].Consumer < lines[j].Consumer
+		}
+		return lines[i].Metric < lines[j].Metric
+	})
+	return lines, nil
+}
+
+// UsageExportFileName - имя CSV-выгрузки потребления за период.
+func UsageExportFileName(query domain.UsageQuery) string {
+	return fmt.Sprintf("usage_%s_%s.csv", query.From, query.To)
+}
+
+// WriteInvoiceCSV пишет итоги в CSV: consumer,metric,quantity,from,to.
+func WriteInvoiceCSV(w io.Writer, query domain.UsageQuery, lines []domain.UsageInvoiceLine) error {
+	cw := csv.NewWriter(w)
+	_ = cw.Write([]string{"consumer", "metric", "quantity", "from", "to"})
+	for _, line := range lines {
+		_ = cw.Write([]string{line.Consumer, string(line.Metric), strconv.FormatInt(line.Quantity, 10), query.From, query.To})
+	}
+	cw.Flush()
+	return cw.Error()
+}
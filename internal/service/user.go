@@ -0,0 +1,177 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/mail"
+	"strings"
+	"unicode/utf8"
+
+	"aggregator_db/internal/clock"
+	"aggregator_db/internal/repository/postgres"
+	"aggregator_db/pkg/domain"
+	"github.com/google/uuid"
+)
+
+type UserService struct {
+	repo postgres.UserRepository
+	// cache - кэш расчетов по подпискам; nil, если кэш выключен
+	cache  postgres.SubscriptionCache
+	logger *slog.Logger
+}
+
+func NewUserService(repo postgres.UserRepository, cache postgres.SubscriptionCache, logger *slog.Logger) *UserService {
+	return &UserService{repo: repo, cache: cache, logger: logger}
+}
+
+// normalizeUserField обрезает пробелы; пустая строка означает отсутствие значения.
+func normalizeUserField(value *string) *string {
+	if value == nil {
+		return nil
+	}
+	trimmed := strings.TrimSpace(*value)
+	if trimmed == "" {
+		return nil
+	}
+	return &trimmed
+}
+
+func validateEmail(email *string) error {
+	if email == nil {
+		return nil
+	}
+	addr, err := mail.ParseAddress(*email)
+	if err != nil || addr.Address != *email || len(*email) > 254 {
+		return fmt.Errorf("%w: invalid email", ErrValidation)
+	}
+	return nil
+}
+
+func (s *UserService) Create(ctx context.Context, req domain.CreateUserRequest) (*domain.User, error) {
+	now := clock.Now(ctx)
+	user := &domain.User{
+		ID:        uuid.New(),
+		Email:     normalizeUserField(req.Email),
+		Name:      normalizeUserField(req.Name),
+		Role:      domain.RoleUser,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if req.ID != nil {
+		if *req.ID == uuid.Nil {
+			return nil, fmt.Errorf("%w: id must not be nil uuid", ErrValidation)
+		}
+		user.ID = *req.ID
+	}
+	if err := validateEmail(user.Email); err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.Create(ctx, user); err != nil {
+		s.logger.ErrorContext(ctx, "failed to create user",
+			slog.String("id", user.ID.String()),
+			slog.String("error", err.Error()),
+		)
+		return nil, err
+	}
+
+	s.logger.InfoContext(ctx, "user created", slog.String("id", user.ID.String()))
+	return user, nil
+}
+
+func (s *UserService) Get(ctx context.Context, id uuid.UUID) (*domain.User, error) {
+	return s.repo.GetByID(ctx, id)
+}
+
+func (s *UserService) List(ctx context.Context, query domain.ListUsersQuery) (*domain.ListUsersResponse, error) {
+	users, err := s.repo.List(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	total, err := s.repo.Count(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return &domain.ListUsersResponse{
+		Items:      users,
+		TotalCount: total,
+		Limit:      query.Limit,
+		Offset:     query.Offset,
+		HasMore:    query.Offset+len(users) < total,
+	}, nil
+}
+
+func (s *UserService) Update(ctx context.Context, id uuid.UUID, req domain.UpdateUserRequest) (*domain.User, error) {
+	user, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Email.Set {
+		user.Email = nil
+		if !req.Email.Null {
+			user.Email = normalizeUserField(&req.Email.Value)
+		}
+		if err := validateEmail(user.Email); err != nil {
+			return nil, err
+		}
+	}
+	if req.Name.Set {
+		user.Name = nil
+		if !req.Name.Null {
+			user.Name = normalizeUserField(&req.Name.Value)
+		}
+		if user.Name != nil && utf8.RuneCountInString(*user.Name) > 255 {
+			return nil, fmt.Errorf("%w: name must be at most 255 characters", ErrValidation)
+		}
+	}
+	user.UpdatedAt = clock.Now(ctx)
+
+	if err := s.repo.Update(ctx, user); err != nil {
+		s.logger.ErrorContext(ctx, "failed to update user",
+			slog.String("id", id.String()),
+			slog.String("error", err.Error()),
+		)
+		return nil, err
+	}
+	return user, nil
+}
+
+// SetRole назначает пользователю роль; действует со следующего запроса.
+func (s *UserService) SetRole(ctx context.Context, id uuid.UUID, role domain.Role) (*domain.User, error) {
+	if !role.Valid() {
+		return nil, fmt.Errorf("%w: unknown role %q", ErrValidation, role)
+	}
+	user, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	user.Role = role
+	user.UpdatedAt = clock.Now(ctx)
+	if err := s.repo.Update(ctx, user); err != nil {
+		return nil, err
+	}
+
+	s.logger.InfoContext(ctx, "user role changed",
+		slog.String("id", id.String()),
+		slog.String("role", string(role)),
+	)
+	return user, nil
+}
+
+// Delete удаляет пользователя вместе со всеми его подписками.
+func (s *UserService) Delete(ctx context.Context, id uuid.UUID) error {
+	if err := s.repo.Delete(ctx, id); err != nil {
+		return err
+	}
+	// Подписки пользователя удаляются каскадом в базе, мимо репозитория подписок
+	if s.cache != nil {
+		s.cache.InvalidateUsers(ctx, id)
+	}
+
+	s.logger.InfoContext(ctx, "user deleted", slog.String("id", id.String()))
+	return nil
+}
@@ -0,0 +1,410 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
+	"slices"
+	"sync"
+	"time"
+
+	"aggregator_db/internal/clock"
+	"aggregator_db/internal/repository/postgres"
+	"aggregator_db/internal/tenancy"
+	"aggregator_db/internal/writequeue"
+	"aggregator_db/pkg/domain"
+	"aggregator_db/pkg/metrics"
+	"github.com/google/uuid"
+)
+
+var (
+	writeQueueReplays = metrics.NewCounterVec(
+		"write_queue_replays_total",
+		"Результаты повтора отложенных записей",
+		"result",
+	)
+	writeQueueEntries = metrics.NewGaugeVec(
+		"write_queue_entries",
+		"Записи в локальной очереди по статусу",
+		"status",
+	)
+)
+
+// queuedPayload - данные отложенной записи в журнале очереди.
+type queuedPayload struct {
+	SubscriptionID uuid.UUID `json:"subscription_id"`
+	// TenantID - тенант запроса; при повторе тенант ищется заново
+	TenantID     string                             `json:"tenant_id,omitempty"`
+	Subscription *domain.Subscription               `json:"subscription,omitempty"`
+	Update       *domain.UpdateSubscriptionRequest  `json:"update,omitempty"`
+	Replace      *domain.ReplaceSubscriptionRequest `json:"replace,omitempty"`
+	// Version - версия подписки, на которой основано изменение: версия клиента
+	// или версия после повтора предыдущей отложенной записи этой подписки
+	Version *int64 `json:"version,omitempty"`
+	// After - предыдущая отложенная запись этой подписки: изменение основано на
+	// ее результате, а ее повтор передаст сюда Version
+	After *uuid.UUID `json:"after,omitempty"`
+}
+
+// WriteQueueService принимает создание и изменение подписок, пока база недоступна:
+// запись откладывается в локальную очередь и повторяется, когда база вернется.
+type WriteQueueService struct {
+	subs    *SubscriptionService
+	queue   *writequeue.Queue
+	tenants func(ctx context.Context, id string) (*domain.Tenant, error)
+	logger  *slog.Logger
+	// mu связывает запись в очереди с предыдущей записью той же подписки
+	mu sync.Mutex
+}
+
+// NewWriteQueueService - tenants ищет тенанта отложенной записи при повторе; nil, если тенантов нет.
+func NewWriteQueueService(subs *SubscriptionService, queue *writequeue.Queue, tenants func(ctx context.Context, id string) (*domain.Tenant, error), logger *slog.Logger) *WriteQueueService {
+	return &WriteQueueService{subs: subs, queue: queue, tenants: tenants, logger: logger}
+}
+
+// Create создает подписку или, если база недоступна, откладывает создание.
+// Отложенная подписка получает id сразу, но читается только после повтора.
+func (s *WriteQueueService) Create(ctx context.Context, req domain.CreateSubscriptionRequest) (*domain.Subscription, *domain.QueuedWrite, error) {
+	if err := validateCreate(req); err != nil {
+		return nil, nil, err
+	}
+
+	sub := newSubscription(req, clock.Now(ctx))
+	created, err := s.subs.create(ctx, sub)
+	if !errors.Is(err, postgres.ErrUnavailable) {
+		return created, nil, err
+	}
+	return s.enqueue(ctx, domain.QueuedWriteCreate, queuedPayload{SubscriptionID: sub.ID, Subscription: sub}, err)
+}
+
+// Update применяет частичное обновление или откладывает его.
+func (s *WriteQueueService) Update(ctx context.Context, id uuid.UUID, req domain.UpdateSubscriptionRequest) (*domain.Subscription, *domain.QueuedWrite, error) {
+	if err := validateUpdate(req); err != nil {
+		return nil, nil, err
+	}
+
+	sub, err := s.subs.Update(ctx, id, req)
+	if !errors.Is(err, postgres.ErrUnavailable) {
+		return sub, nil, err
+	}
+	return s.enqueue(ctx, domain.QueuedWriteUpdate, queuedPayload{SubscriptionID: id, Update: &req, Version: req.Version}, err)
+}
+
+// Replace заменяет подписку целиком или откладывает замену.
+func (s *WriteQueueService) Replace(ctx context.Context, id uuid.UUID, req domain.ReplaceSubscriptionRequest) (*domain.Subscription, *domain.QueuedWrite, error) {
+	if err := validatePrice(req.Price); err != nil {
+		return nil, nil, err
+	}
+	if err := validateDates(req.StartDate, req.EndDate); err != nil {
+		return nil, nil, err
+	}
+	if _, err := normalizeTags(req.Tags); err != nil {
+		return nil, nil, err
+	}
+	if _, err := normalizeNotes(req.Notes); err != nil {
+		return nil, nil, err
+	}
+	if _, err := normalizeCustomFields(ctx, req.CustomFields); err != nil {
+		return nil, nil, err
+	}
+
+	sub, err := s.subs.Replace(ctx, id, req)
+	if !errors.Is(err, postgres.ErrUnavailable) {
+		return sub, nil, err
+	}
+	return s.enqueue(ctx, domain.QueuedWriteReplace, queuedPayload{SubscriptionID: id, Replace: &req, Version: req.Version}, err)
+}
+
+// enqueue пишет запись в очередь. Если очередь не приняла запись, возвращается
+// исходная ошибка недоступности cause.
+func (s *WriteQueueService) enqueue(ctx context.Context, kind domain.QueuedWriteKind, payload queuedPayload, cause error) (*domain.Subscription, *domain.QueuedWrite, error) {
+	if tenant := tenancy.FromContext(ctx); tenant != nil {
+		payload.TenantID = tenant.ID
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	// Без версии клиента изменение основано на результате предыдущей отложенной
+	// записи подписки, если она есть: клиент получил ответ после нее
+	if kind != domain.QueuedWriteCreate && payload.Version == nil {
+		payload.After = s.lastQueued(payload.SubscriptionID)
+	}
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	entry := writequeue.Entry{ID: uuid.New(), Kind: string(kind), Payload: data, EnqueuedAt: clock.Now(ctx)}
+	if err := s.queue.Append(entry); err != nil {
+		s.logger.ErrorContext(ctx, "failed to queue write",
+			slog.String("kind", string(kind)),
+			slog.String("error", err.Error()),
+		)
+		return nil, nil, cause
+	}
+
+	s.logger.WarnContext(ctx, "database unavailable, write queued",
+		slog.String("queued_id", entry.ID.String()),
+		slog.String("kind", string(kind)),
+		slog.String("subscription_id", payload.SubscriptionID.String()),
+		slog.String("error", cause.Error()),
+	)
+	s.updateGauge()
+
+	return nil, toQueuedWrite(entry, payload), nil
+}
+
+// lastQueued возвращает последнюю ожидающую повтора запись подписки id.
+func (s *WriteQueueService) lastQueued(id uuid.UUID) *uuid.UUID {
+	pending := s.queue.Pending()
+	for i := len(pending) - 1; i >= 0; i-- {
+		var payload queuedPayload
+		if json.Unmarshal(pending[i].Payload, &payload) == nil && payload.SubscriptionID == id {
+			return &pending[i].ID
+		}
+	}
+	return nil
+}
+
+func toQueuedWrite(entry writequeue.Entry, payload queuedPayload) *domain.QueuedWrite {
+	status := domain.QueuedWritePending
+	if entry.Conflict != "" {
+		status = domain.QueuedWriteConflict
+	}
+	return &domain.QueuedWrite{
+		ID:             entry.ID,
+		Kind:           domain.QueuedWriteKind(entry.Kind),
+		Status:         status,
+		SubscriptionID: payload.SubscriptionID,
+		Subscription:   payload.Subscription,
+		Conflict:       entry.Conflict,
+		EnqueuedAt:     entry.EnqueuedAt,
+	}
+}
+
+// List возвращает отложенные записи в порядке поступления.
+func (s *WriteQueueService) List(_ context.Context) []*domain.QueuedWrite {
+	entries := s.queue.List()
+	list := make([]*domain.QueuedWrite, 0, len(entries))
+	for _, entry := range entries {
+		var payload queuedPayload
+		_ = json.Unmarshal(entry.Payload, &payload)
+		list = append(list, toQueuedWrite(entry, payload))
+	}
+	return list
+}
+
+// Backlog - число записей в очереди, в том числе с конфликтами.
+func (s *WriteQueueService) Backlog(_ context.Context) (int, error) {
+	return len(s.queue.List()), nil
+}
+
+// Discard удаляет запись из очереди без применения, например после разбора конфликта.
+func (s *WriteQueueService) Discard(ctx context.Context, id uuid.UUID) error {
+	if err := s.queue.Ack(id); err != nil {
+		return err
+	}
+	s.logger.InfoContext(ctx, "queued write discarded", slog.String("queued_id", id.String()))
+	s.updateGauge()
+	return nil
+}
+
+// Run повторяет отложенные записи раз в interval, пока не отменен ctx.
+func (s *WriteQueueService) Run(ctx context.Context, interval time.Duration) {
+	s.updateGauge()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.Replay(ctx); err != nil {
+				s.logger.Warn("failed to replay queued writes", "error", err.Error())
+			}
+		}
+	}
+}
+
+// Replay повторяет отложенные записи по порядку. На первой ошибке, не связанной
+// с конфликтом, повтор останавливается, чтобы записи не применились не по порядку.
+func (s *WriteQueueService) Replay(ctx context.Context) error {
+	defer s.updateGauge()
+
+	for _, queued := range s.queue.Pending() {
+		// Повтор предыдущей записи мог передать этой версию подписки
+		entry, err := s.queue.Get(queued.ID)
+		if errors.Is(err, writequeue.ErrEntryNotFound) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		sub, conflict, err := s.apply(ctx, entry)
+		if err != nil {
+			writeQueueReplays.Inc("error")
+			return err
+		}
+
+		if conflict != "" {
+			if err := s.queue.MarkConflict(entry.ID, conflict); err != nil {
+				return err
+			}
+			writeQueueReplays.Inc("conflict")
+			s.logger.WarnContext(ctx, "queued write conflicts with current data",
+				slog.String("queued_id", entry.ID.String()),
+				slog.String("kind", entry.Kind),
+				slog.String("conflict", conflict),
+			)
+			continue
+		}
+
+		if err := s.passVersion(entry.ID, sub); err != nil {
+			return err
+		}
+		if err := s.queue.Ack(entry.ID); err != nil {
+			return err
+		}
+		writeQueueReplays.Inc("applied")
+		s.logger.InfoContext(ctx, "queued write applied",
+			slog.String("queued_id", entry.ID.String()),
+			slog.String("kind", entry.Kind),
+		)
+	}
+	return nil
+}
+
+// apply применяет запись и возвращает подписку после нее или причину конфликта,
+// если ее применять нельзя. Изменение конфликтует, если подписку успели удалить
+// или изменить мимо очереди после версии, на которой оно основано; без версии -
+// после постановки в очередь.
+func (s *WriteQueueService) apply(ctx context.Context, entry writequeue.Entry) (*domain.Subscription, string, error) {
+	var payload queuedPayload
+	if err := json.Unmarshal(entry.Payload, &payload); err != nil {
+		return nil, fmt.Sprintf("invalid queued payload: %v", err), nil
+	}
+
+	if payload.TenantID != "" {
+		tenant, err := s.resolveTenant(ctx, payload.TenantID)
+		if errors.Is(err, postgres.ErrTenantNotFound) {
+			return nil, err.Error(), nil
+		}
+		if err != nil {
+			return nil, "", err
+		}
+		ctx = tenancy.WithTenant(ctx, tenant)
+	}
+
+	current, err := s.subs.repo.GetByID(ctx, payload.SubscriptionID)
+	if err != nil && !errors.Is(err, postgres.ErrNotFound) {
+		return nil, "", err
+	}
+	exists := err == nil
+
+	var sub *domain.Subscription
+	switch domain.QueuedWriteKind(entry.Kind) {
+	case domain.QueuedWriteCreate:
+		if payload.Subscription == nil {
+			return nil, "invalid queued payload: subscription is missing", nil
+		}
+		if exists {
+			// Соединение могло оборваться уже после вставки
+			if sameSubscription(current, payload.Subscription) {
+				return current, "", nil
+			}
+			return nil, "subscription with this id already exists", nil
+		}
+		sub, err = s.subs.create(ctx, payload.Subscription)
+	case domain.QueuedWriteUpdate, domain.QueuedWriteReplace:
+		if !exists {
+			return nil, "subscription not found", nil
+		}
+		switch {
+		case payload.Version != nil:
+			if current.Version != *payload.Version {
+				return nil, "subscription was modified after the write was queued", nil
+			}
+		case payload.After != nil:
+			// Примененная предыдущая запись передала бы сюда версию
+			return nil, "previous queued write to this subscription was not applied", nil
+		case current.UpdatedAt.After(entry.EnqueuedAt):
+			return nil, "subscription was modified after the write was queued", nil
+		}
+		if payload.Update != nil {
+			sub, err = s.subs.Update(ctx, payload.SubscriptionID, *payload.Update)
+		} else if payload.Replace != nil {
+			sub, err = s.subs.Replace(ctx, payload.SubscriptionID, *payload.Replace)
+		} else {
+			return nil, "invalid queued payload: change is missing", nil
+		}
+	default:
+		return nil, fmt.Sprintf("unknown queued write kind %q", entry.Kind), nil
+	}
+
+	if errors.Is(err, ErrValidation) || errors.Is(err, ErrQuotaExceeded) || errors.Is(err, postgres.ErrNotFound) ||
+		errors.Is(err, postgres.ErrVersionConflict) || errors.Is(err, postgres.ErrAlreadyExists) {
+		return nil, err.Error(), nil
+	}
+	return sub, "", err
+}
+
+// passVersion передает версию подписки после повтора записи id следующей
+// отложенной записи этой подписки, основанной на ее результате. Версия
+// сохраняется в журнале до подтверждения записи id и переживает перезапуск.
+func (s *WriteQueueService) passVersion(id uuid.UUID, sub *domain.Subscription) error {
+	if sub == nil {
+		return nil
+	}
+	for _, entry := range s.queue.Pending() {
+		var payload queuedPayload
+		if json.Unmarshal(entry.Payload, &payload) != nil || payload.After == nil || *payload.After != id {
+			continue
+		}
+		version := sub.Version
+		payload.Version, payload.After = &version, nil
+		data, err := json.Marshal(payload)
+		if err != nil {
+			return err
+		}
+		return s.queue.SetPayload(entry.ID, data)
+	}
+	return nil
+}
+
+func (s *WriteQueueService) resolveTenant(ctx context.Context, id string) (*domain.Tenant, error) {
+	if id == domain.SandboxTenantID {
+		return domain.SandboxTenant(), nil
+	}
+	if s.tenants == nil {
+		return nil, fmt.Errorf("%w: %s", postgres.ErrTenantNotFound, id)
+	}
+	return s.tenants(ctx, id)
+}
+
+// sameSubscription сравнивает сохраненную подписку с отложенной по полям из запроса.
+func sameSubscription(a, b *domain.Subscription) bool {
+	sameEnd := (a.EndDate == nil && b.EndDate == nil) ||
+		(a.EndDate != nil && b.EndDate != nil && *a.EndDate == *b.EndDate)
+	sameNotes := (a.Notes == nil && b.Notes == nil) ||
+		(a.Notes != nil && b.Notes != nil && *a.Notes == *b.Notes)
+	return sameNotes && a.ServiceName == b.ServiceName && a.Price == b.Price && a.UserID == b.UserID &&
+		a.StartDate == b.StartDate && sameEnd && a.AutoRenew == b.AutoRenew && a.BillingCycle == b.BillingCycle &&
+		slices.Equal(a.Tags, b.Tags) && maps.Equal(a.CustomFields, b.CustomFields)
+}
+
+func (s *WriteQueueService) updateGauge() {
+	pending := 0
+	entries := s.queue.List()
+	for _, entry := range entries {
+		if entry.Conflict == "" {
+			pending++
+		}
+	}
+	writeQueueEntries.Set(float64(pending), string(domain.QueuedWritePending))
+	writeQueueEntries.Set(float64(len(entries)-pending), string(domain.QueuedWriteConflict))
+}
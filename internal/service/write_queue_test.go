@@ -0,0 +1,355 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net"
+	"path/filepath"
+	"testing"
+
+	"aggregator_db/internal/exchange"
+	"aggregator_db/internal/repository/memory"
+	"aggregator_db/internal/repository/postgres"
+	"aggregator_db/internal/tenancy"
+	"aggregator_db/internal/writequeue"
+	"aggregator_db/pkg/domain"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// flakyRepo имитирует недоступную базу: пока down, запись и чтение подписок падают.
+// afterUpdate вызывается после каждого успешного изменения.
+type flakyRepo struct {
+	postgres.SubscriptionRepository
+	down        bool
+	afterUpdate func()
+}
+
+func (r *flakyRepo) Create(ctx context.Context, sub *domain.Subscription) error {
+	if r.down {
+		return postgres.ErrUnavailable
+	}
+	return r.SubscriptionRepository.Create(ctx, sub)
+}
+
+func (r *flakyRepo) GetByID(ctx context.Context, id uuid.UUID) (*domain.Subscription, error) {
+	if r.down {
+		return nil, postgres.ErrUnavailable
+	}
+	return r.SubscriptionRepository.GetByID(ctx, id)
+}
+
+func (r *flakyRepo) Update(ctx context.Context, sub *domain.Subscription) error {
+	if r.down {
+		return postgres.ErrUnavailable
+	}
+	if err := r.SubscriptionRepository.Update(ctx, sub); err != nil {
+		return err
+	}
+	if r.afterUpdate != nil {
+		r.afterUpdate()
+	}
+	return nil
+}
+
+// downDB - база, к которой не удается подключиться: транзакция не начинается.
+type downDB struct {
+	postgres.DB
+}
+
+func (downDB) Begin(context.Context) (pgx.Tx, error) {
+	return nil, &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}
+}
+
+func newTestWriteQueue(t *testing.T) (*WriteQueueService, *SubscriptionService, *flakyRepo, *writequeue.Queue) {
+	return newTestWriteQueueTx(t, memory.NewTransactor())
+}
+
+func newTestWriteQueueTx(t *testing.T, tx postgres.Transactor) (*WriteQueueService, *SubscriptionService, *flakyRepo, *writequeue.Queue) {
+	t.Helper()
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	repo := &flakyRepo{SubscriptionRepository: memory.NewSubscriptionRepository()}
+	subs := NewSubscriptionService(repo, tx, memory.NewServiceAliasRepository(), &recordingPublisher{}, exchange.NewStaticProvider(domain.DefaultCurrency, nil), logger)
+
+	queue, err := writequeue.Open(filepath.Join(t.TempDir(), "writes.log"), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = queue.Close() })
+	return NewWriteQueueService(subs, queue, nil, logger), subs, repo, queue
+}
+
+func TestWriteQueueCreateReplay(t *testing.T) {
+	ctx := context.Background()
+	writes, subs, repo, queue := newTestWriteQueue(t)
+
+	repo.down = true
+	req := domain.CreateSubscriptionRequest{ServiceName: "Netflix", Price: domain.NewMoney(59900, domain.DefaultCurrency), UserID: uuid.New(), StartDate: "01-2025"}
+	created, queued, err := writes.Create(ctx, req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if created != nil || queued == nil || queued.Kind != domain.QueuedWriteCreate || queued.Status != domain.QueuedWritePending {
+		t.Fatalf("expected queued create, got %+v %+v", created, queued)
+	}
+
+	// Пока база недоступна, повтор ничего не теряет
+	if err := writes.Replay(ctx); !errors.Is(err, postgres.ErrUnavailable) {
+		t.Fatalf("got %v, want ErrUnavailable", err)
+	}
+	if len(queue.Pending()) != 1 {
+		t.Fatal("entry must stay queued while the database is down")
+	}
+
+	repo.down = false
+	if err := writes.Replay(ctx); err != nil {
+		t.Fatal(err)
+	}
+	sub, err := subs.GetByID(ctx, queued.SubscriptionID)
+	if err != nil {
+		t.Fatalf("queued subscription was not created: %v", err)
+	}
+	if sub.ServiceName != "Netflix" || sub.Price != req.Price {
+		t.Errorf("unexpected subscription %+v", sub)
+	}
+	if len(queue.List()) != 0 {
+		t.Error("applied entry must be removed")
+	}
+
+	// Валидация не откладывается
+	req.Price = domain.Money{}
+	if _, _, err := writes.Create(ctx, req); !errors.Is(err, ErrValidation) {
+		t.Errorf("got %v, want ErrValidation", err)
+	}
+}
+
+func TestWriteQueueCreateWithoutTx(t *testing.T) {
+	ctx := context.Background()
+	unit := postgres.NewUnitOfWork(downDB{}, postgres.RetryPolicy{}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	writes, _, _, queue := newTestWriteQueueTx(t, unit)
+
+	// Создание идет в единице работы: база недоступна уже при открытии транзакции
+	req := domain.CreateSubscriptionRequest{ServiceName: "Netflix", Price: domain.NewMoney(59900, domain.DefaultCurrency), UserID: uuid.New(), StartDate: "01-2025"}
+	created, queued, err := writes.Create(ctx, req)
+	if err != nil {
+		t.Fatalf("create must be queued, got %v", err)
+	}
+	if created != nil || queued == nil || queued.Kind != domain.QueuedWriteCreate {
+		t.Fatalf("expected queued create, got %+v %+v", created, queued)
+	}
+	if len(queue.Pending()) != 1 {
+		t.Error("create must be queued")
+	}
+}
+
+func TestWriteQueueUpdateConflict(t *testing.T) {
+	ctx := context.Background()
+	writes, subs, repo, queue := newTestWriteQueue(t)
+
+	sub, _, err := writes.Create(ctx, domain.CreateSubscriptionRequest{ServiceName: "Spotify", Price: domain.NewMoney(29900, domain.DefaultCurrency), UserID: uuid.New(), StartDate: "01-2025"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	repo.down = true
+	patch := domain.UpdateSubscriptionRequest{ServiceName: domain.Optional[string]{Set: true, Value: "Spotify Family"}}
+	if _, queued, err := writes.Update(ctx, sub.ID, patch); err != nil || queued == nil {
+		t.Fatalf("expected queued update, got %+v, %v", queued, err)
+	}
+	clearEnd := domain.UpdateSubscriptionRequest{EndDate: domain.Optional[string]{Set: true, Null: true}}
+	if _, _, err := writes.Update(ctx, sub.ID, clearEnd); err != nil {
+		t.Fatal(err)
+	}
+
+	// Подписку изменили напрямую после постановки записей в очередь
+	repo.down = false
+	if _, err := subs.Update(ctx, sub.ID, domain.UpdateSubscriptionRequest{AutoRenew: domain.Optional[bool]{Set: true, Value: true}}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := writes.Replay(ctx); err != nil {
+		t.Fatal(err)
+	}
+	list := writes.List(ctx)
+	if len(list) != 2 {
+		t.Fatalf("got %d entries, want 2 conflicts", len(list))
+	}
+	for _, queued := range list {
+		if queued.Status != domain.QueuedWriteConflict || queued.Conflict == "" {
+			t.Errorf("expected conflict, got %+v", queued)
+		}
+	}
+	current, err := subs.GetByID(ctx, sub.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if current.ServiceName != "Spotify" {
+		t.Errorf("conflicting update must not be applied, got %q", current.ServiceName)
+	}
+
+	if err := writes.Discard(ctx, list[0].ID); err != nil {
+		t.Fatal(err)
+	}
+	if len(queue.List()) != 1 {
+		t.Error("discarded entry must be removed")
+	}
+}
+
+func TestWriteQueueUpdateReplay(t *testing.T) {
+	ctx := context.Background()
+	writes, subs, repo, _ := newTestWriteQueue(t)
+
+	end := "12-2025"
+	sub, _, err := writes.Create(ctx, domain.CreateSubscriptionRequest{ServiceName: "Ivi", Price: domain.NewMoney(39900, domain.DefaultCurrency), UserID: uuid.New(), StartDate: "01-2025", EndDate: &end})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	repo.down = true
+	// Запрос тенанта песочницы повторяется в ее схеме
+	sandboxCtx := tenancy.WithTenant(ctx, domain.SandboxTenant())
+	if _, _, err := writes.Update(sandboxCtx, sub.ID, domain.UpdateSubscriptionRequest{EndDate: domain.Optional[string]{Set: true, Null: true}}); err != nil {
+		t.Fatal(err)
+	}
+
+	repo.down = false
+	if err := writes.Replay(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if list := writes.List(ctx); len(list) != 0 {
+		t.Fatalf("unexpected entries left: %+v", list)
+	}
+	current, err := subs.GetByID(ctx, sub.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if current.EndDate != nil {
+		t.Errorf("null end_date must survive the queue, got %q", *current.EndDate)
+	}
+}
+
+func TestWriteQueueSeveralUpdates(t *testing.T) {
+	ctx := context.Background()
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	path := filepath.Join(t.TempDir(), "writes.log")
+	repo := &flakyRepo{SubscriptionRepository: memory.NewSubscriptionRepository()}
+	subs := NewSubscriptionService(repo, memory.NewTransactor(), memory.NewServiceAliasRepository(), &recordingPublisher{}, exchange.NewStaticProvider(domain.DefaultCurrency, nil), logger)
+	open := func() (*WriteQueueService, *writequeue.Queue) {
+		queue, err := writequeue.Open(path, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { _ = queue.Close() })
+		return NewWriteQueueService(subs, queue, nil, logger), queue
+	}
+	writes, queue := open()
+
+	repo.down = true
+	created, queuedCreate, err := writes.Create(ctx, domain.CreateSubscriptionRequest{ServiceName: "Okko", Price: domain.NewMoney(19900, domain.DefaultCurrency), UserID: uuid.New(), StartDate: "01-2025"})
+	if err != nil || created != nil || queuedCreate == nil {
+		t.Fatalf("expected queued create, got %+v %+v %v", created, queuedCreate, err)
+	}
+	id := queuedCreate.SubscriptionID
+	rename := domain.UpdateSubscriptionRequest{ServiceName: domain.Optional[string]{Set: true, Value: "Okko Premium"}}
+	reprice := domain.UpdateSubscriptionRequest{Price: domain.Optional[domain.Money]{Set: true, Value: domain.NewMoney(29900, domain.DefaultCurrency)}}
+	for _, req := range []domain.UpdateSubscriptionRequest{rename, reprice} {
+		if _, queued, err := writes.Update(ctx, id, req); err != nil || queued == nil {
+			t.Fatalf("expected queued update, got %+v, %v", queued, err)
+		}
+	}
+
+	// База снова падает после первого изменения, а процесс перезапускается:
+	// второе изменение основано на первом, а не на подписке до очереди
+	repo.down = false
+	repo.afterUpdate = func() { repo.down = true }
+	if err := writes.Replay(ctx); !errors.Is(err, postgres.ErrUnavailable) {
+		t.Fatalf("got %v, want ErrUnavailable", err)
+	}
+	if len(queue.Pending()) != 1 {
+		t.Fatalf("got %d pending entries, want 1", len(queue.Pending()))
+	}
+	_ = queue.Close()
+	writes, _ = open()
+
+	repo.down, repo.afterUpdate = false, nil
+	if err := writes.Replay(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if list := writes.List(ctx); len(list) != 0 {
+		t.Fatalf("queued writes of one subscription must not conflict with each other: %+v", list)
+	}
+	sub, err := subs.GetByID(ctx, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sub.ServiceName != "Okko Premium" || sub.Price != reprice.Price.Value || sub.Version != 3 {
+		t.Errorf("unexpected subscription %+v", sub)
+	}
+
+	// Изменение мимо очереди между отложенными записями - конфликт
+	repo.down = true
+	if _, _, err := writes.Update(ctx, id, rename); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := writes.Update(ctx, id, reprice); err != nil {
+		t.Fatal(err)
+	}
+	repo.down = false
+	repo.afterUpdate = func() {
+		repo.afterUpdate = nil
+		if _, err := subs.Update(ctx, id, domain.UpdateSubscriptionRequest{AutoRenew: domain.Optional[bool]{Set: true, Value: true}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := writes.Replay(ctx); err != nil {
+		t.Fatal(err)
+	}
+	list := writes.List(ctx)
+	if len(list) != 1 || list[0].Status != domain.QueuedWriteConflict {
+		t.Fatalf("expected the second write to conflict, got %+v", list)
+	}
+}
+
+func TestWriteQueueClientVersion(t *testing.T) {
+	ctx := context.Background()
+	writes, subs, repo, _ := newTestWriteQueue(t)
+
+	sub, _, err := writes.Create(ctx, domain.CreateSubscriptionRequest{ServiceName: "Kion", Price: domain.NewMoney(24900, domain.DefaultCurrency), UserID: uuid.New(), StartDate: "01-2025"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Подписку изменили до сбоя, но после того как клиент ее прочитал
+	seen := sub.Version
+	if _, err := subs.Update(ctx, sub.ID, domain.UpdateSubscriptionRequest{AutoRenew: domain.Optional[bool]{Set: true, Value: true}}); err != nil {
+		t.Fatal(err)
+	}
+
+	repo.down = true
+	stale := domain.UpdateSubscriptionRequest{ServiceName: domain.Optional[string]{Set: true, Value: "Kion+"}, Version: &seen}
+	if _, _, err := writes.Update(ctx, sub.ID, stale); err != nil {
+		t.Fatal(err)
+	}
+	current := seen + 1
+	fresh := domain.ReplaceSubscriptionRequest{ServiceName: "Kion Max", Price: domain.NewMoney(34900, domain.DefaultCurrency), StartDate: "01-2025", Version: &current}
+	if _, _, err := writes.Replace(ctx, sub.ID, fresh); err != nil {
+		t.Fatal(err)
+	}
+
+	repo.down = false
+	if err := writes.Replay(ctx); err != nil {
+		t.Fatal(err)
+	}
+	list := writes.List(ctx)
+	if len(list) != 1 || list[0].Kind != domain.QueuedWriteUpdate || list[0].Status != domain.QueuedWriteConflict {
+		t.Fatalf("expected only the stale update to conflict, got %+v", list)
+	}
+	got, err := subs.GetByID(ctx, sub.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.ServiceName != "Kion Max" {
+		t.Errorf("replace with the current version must be applied, got %q", got.ServiceName)
+	}
+}
@@ -0,0 +1,232 @@
+package shadow
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"math/rand/v2"
+	"net/http"
+	"reflect"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"aggregator_db/pkg/httpclient"
+	"aggregator_db/pkg/metrics"
+)
+
+// Header помечает повторенный запрос, чтобы теневое развертывание могло
+// отличить его от настоящего (например, не учитывать в квотах).
+const Header = "X-Shadow-Request"
+
+// maxDiffs - сколько расхождений попадает в лог по одному запросу.
+const maxDiffs = 10
+
+var replays = metrics.NewCounterVec(
+	"shadow_requests_total",
+	"Повторы запросов на теневое развертывание по результату: match, mismatch, error или dropped",
+	"result",
+)
+
+// Request - копия запроса, которую можно повторить после ответа клиенту.
+type Request struct {
+	Method string
+	Target string
+	Header http.Header
+	Route  string
+}
+
+// Response - ответ основного развертывания.
+type Response struct {
+	Status int
+	Body   []byte
+}
+
+// Shadower повторяет долю запросов на теневое развертывание и сравнивает ответы.
+// Повтор идет в фоне и не влияет на ответ клиенту; если все слоты заняты, запрос
+// не повторяется.
+type Shadower struct {
+	baseURL string
+	percent float64
+	ignore  []string
+	client  *httpclient.Client
+	slots   chan struct{}
+	timeout time.Duration
+	logger  *slog.Logger
+	sample  func() float64
+}
+
+// Options - настройки повтора. Ignore - поля JSON, которые отличаются всегда
+// (время ответа, сгенерированные ID) и не сравниваются.
+type Options struct {
+	Percent     float64
+	Ignore      []string
+	Concurrency int
+	Timeout     time.Duration
+}
+
+func New(baseURL string, opts Options, client *httpclient.Client, logger *slog.Logger) *Shadower {
+	return &Shadower{
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		percent: opts.Percent,
+		ignore:  opts.Ignore,
+		client:  client,
+		slots:   make(chan struct{}, opts.Concurrency),
+		timeout: opts.Timeout,
+		logger:  logger,
+		sample:  func() float64 { return rand.Float64() * 100 },
+	}
+}
+
+// Sampled решает, повторять ли очередной запрос.
+func (s *Shadower) Sampled() bool {
+	return s.sample() < s.percent
+}
+
+// Replay повторяет запрос в фоне и пишет в лог расхождения ответов.
+func (s *Shadower) Replay(req Request, primary Response) {
+	select {
+	case s.slots <- struct{}{}:
+	default:
+		replays.Inc("dropped")
+		return
+	}
+
+	go func() {
+		defer func() { <-s.slots }()
+		s.compare(req, primary)
+	}()
+}
+
+func (s *Shadower) compare(req Request, primary Response) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
+	defer cancel()
+
+	shadow, err := s.send(ctx, req)
+	if err != nil {
+		replays.Inc("error")
+		s.logger.Warn("shadow request failed",
+			slog.String("route", req.Route),
+			slog.String("target", req.Target),
+			slog.String("error", err.Error()),
+		)
+		return
+	}
+
+	diffs := Compare(primary, *shadow, s.ignore)
+	if len(diffs) == 0 {
+		replays.Inc("match")
+		return
+	}
+	replays.Inc("mismatch")
+	if len(diffs) > maxDiffs {
+		diffs = append(diffs[:maxDiffs], fmt.Sprintf("... and %d more", len(diffs)-maxDiffs))
+	}
+	s.logger.Warn("shadow response differs",
+		slog.String("route", req.Route),
+		slog.String("target", req.Target),
+		slog.Any("diffs", diffs),
+	)
+}
+
+func (s *Shadower) send(ctx context.Context, req Request) (*Response, error) {
+	httpReq, err := http.NewRequestWithContext(ctx, req.Method, s.baseURL+req.Target, nil)
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header = req.Header.Clone()
+	// Ответ нужен без сжатия, чтобы сравнивать тела
+	httpReq.Header.Del("Accept-Encoding")
+	httpReq.Header.Set(Header, "1")
+
+	resp, err := s.client.Do(httpReq)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	return &Response{Status: resp.StatusCode, Body: body}, nil
+}
+
+// Compare возвращает расхождения ответов: статус и отличающиеся пути JSON
+// ($.items[0].price). Тела не в JSON сравниваются побайтно.
+func Compare(primary, shadow Response, ignore []string) []string {
+	var diffs []string
+	if primary.Status != shadow.Status {
+		diffs = append(diffs, fmt.Sprintf("status: %d != %d", primary.Status, shadow.Status))
+	}
+
+	var a, b any
+	if json.Unmarshal(primary.Body, &a) != nil || json.Unmarshal(shadow.Body, &b) != nil {
+		if !bytes.Equal(primary.Body, shadow.Body) {
+			diffs = append(diffs, "body: not equal")
+		}
+		return diffs
+	}
+	return append(diffs, diffValues("$", a, b, ignore)...)
+}
+
+func diffValues(path string, a, b any, ignore []string) []string {
+	switch a := a.(type) {
+	case map[string]any:
+		b, ok := b.(map[string]any)
+		if !ok {
+			return []string{path + ": type differs"}
+		}
+		keys := make(map[string]bool, len(a)+len(b))
+		for key := range a {
+			keys[key] = true
+		}
+		for key := range b {
+			keys[key] = true
+		}
+		sorted := make([]string, 0, len(keys))
+		for key := range keys {
+			if !slices.Contains(ignore, key) {
+				sorted = append(sorted, key)
+			}
+		}
+		sort.Strings(sorted)
+
+		var diffs []string
+		for _, key := range sorted {
+			av, inA := a[key]
+			bv, inB := b[key]
+			switch {
+			case !inB:
+				diffs = append(diffs, path+"."+key+": missing in shadow")
+			case !inA:
+				diffs = append(diffs, path+"."+key+": only in shadow")
+			default:
+				diffs = append(diffs, diffValues(path+"."+key, av, bv, ignore)...)
+			}
+		}
+		return diffs
+	case []any:
+		b, ok := b.([]any)
+		if !ok {
+			return []string{path + ": type differs"}
+		}
+		if len(a) != len(b) {
+			return []string{fmt.Sprintf("%s: length %d != %d", path, len(a), len(b))}
+		}
+		var diffs []string
+		for i := range a {
+			diffs = append(diffs, diffValues(fmt.Sprintf("%s[%d]", path, i), a[i], b[i], ignore)...)
+		}
+		return diffs
+	default:
+		if !reflect.DeepEqual(a, b) {
+			return []string{fmt.Sprintf("%s: %#v != %#v", path, a, b)}
+		}
+		return nil
+	}
+}
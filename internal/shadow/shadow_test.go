@@ -0,0 +1,73 @@
+package shadow
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+
+	"aggregator_db/pkg/httpclient"
+)
+
+func TestCompare(t *testing.T) {
+	primary := Response{Status: 200, Body: []byte(`{"total": 100, "items": [{"id": 1, "price": "9.99"}], "checked_at": "a", "currency": "RUB"}`)}
+	cases := []struct {
+		name   string
+		shadow Response
+		want   []string
+	}{
+		{name: "equal", shadow: Response{Status: 200, Body: []byte(`{"currency": "RUB", "items": [{"price": "9.99", "id": 1}], "total": 100, "checked_at": "b"}`)}},
+		{name: "changed value", shadow: Response{Status: 200, Body: []byte(`{"total": 100, "items": [{"id": 1, "price": "10.00"}], "currency": "USD"}`)},
+			want: []string{`$.currency: "RUB" != "USD"`, `$.items[0].price: "9.99" != "10.00"`}},
+		{name: "shape", shadow: Response{Status: 500, Body: []byte(`{"total": "100", "items": [], "note": "x", "currency": "RUB"}`)},
+			want: []string{"status: 200 != 500", "$.items: length 1 != 0", "$.note: only in shadow", `$.total: 100 != "100"`}},
+		{name: "not json", shadow: Response{Status: 200, Body: []byte("oops")}, want: []string{"body: not equal"}},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Compare(primary, tt.shadow, []string{"checked_at"}); !slices.Equal(got, tt.want) {
+				t.Errorf("diffs = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReplay(t *testing.T) {
+	received := make(chan *http.Request, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received <- r
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"total": 101}`))
+	}))
+	defer server.Close()
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	shadower := New(server.URL+"/", Options{Percent: 50, Concurrency: 1, Timeout: time.Second},
+		httpclient.New(httpclient.DefaultConfig("shadow"), logger), logger)
+	shadower.sample = func() float64 { return 49.9 }
+	if !shadower.Sampled() {
+		t.Fatal("49.9 is below 50 percent")
+	}
+
+	before := replays.Value("mismatch")
+	header := http.Header{"X-Tenant-Id": {"acme"}, "Accept-Encoding": {"gzip"}}
+	shadower.Replay(Request{Method: http.MethodGet, Target: "/api/v1/subscriptions/calculate?user_id=1", Header: header},
+		Response{Status: 200, Body: []byte(`{"total": 100}`)})
+
+	r := <-received
+	if r.URL.RequestURI() != "/api/v1/subscriptions/calculate?user_id=1" || r.Header.Get("X-Tenant-ID") != "acme" || r.Header.Get(Header) != "1" {
+		t.Errorf("shadow request %s, headers %v", r.URL, r.Header)
+	}
+	if header.Get(Header) != "" {
+		t.Error("replay modified the primary request headers")
+	}
+
+	// Слот освобождается после сравнения
+	shadower.slots <- struct{}{}
+	if got := replays.Value("mismatch"); got != before+1 {
+		t.Errorf("mismatches = %v, want %v", got, before+1)
+	}
+}
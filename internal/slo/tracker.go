@@ -0,0 +1,233 @@
+package slo
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"aggregator_db/pkg/domain"
+	"aggregator_db/pkg/metrics"
+)
+
+// horizon - самое длинное окно, за которое хранятся запросы.
+const horizon = 6 * time.Hour
+
+// reportWindows - окна, которые показывает статус, от короткого к длинному.
+var reportWindows = []time.Duration{5 * time.Minute, 30 * time.Minute, time.Hour, 6 * time.Hour}
+
+var (
+	sloRequests = metrics.NewCounterVec(
+		"slo_requests_total",
+		"Запросы маршрутов с SLO по результату: good, error (5xx) или slow",
+		"route", "result",
+	)
+	sloBurnRate = metrics.NewGaugeVec(
+		"slo_burn_rate",
+		"Скорость сгорания бюджета ошибок на момент последней проверки",
+		"route", "objective", "window",
+	)
+)
+
+// bucket - запросы маршрута за одну минуту.
+type bucket struct {
+	minute int64
+	total  int64
+	errors int64
+	slow   int64
+}
+
+type route struct {
+	slo     domain.SLO
+	buckets []bucket
+}
+
+type alertKey struct {
+	route     string
+	objective domain.SLOObjective
+	severity  domain.SLOAlertSeverity
+}
+
+// Tracker считает запросы маршрутов с SLO поминутно за последние 6 часов
+// и оценивает скорость сгорания бюджета ошибок. Данные у каждой реплики свои.
+type Tracker struct {
+	mu     sync.Mutex
+	routes map[string]*route
+	order  []string
+	rules  []domain.BurnRateRule
+	firing map[alertKey]bool
+}
+
+func NewTracker(slos []domain.SLO, rules []domain.BurnRateRule) *Tracker {
+	t := &Tracker{
+		routes: make(map[string]*route, len(slos)),
+		rules:  rules,
+		firing: make(map[alertKey]bool),
+	}
+	for _, slo := range slos {
+		t.routes[slo.Route] = &route{slo: slo, buckets: make([]bucket, int(horizon/time.Minute))}
+		t.order = append(t.order, slo.Route)
+	}
+	return t
+}
+
+// Observe учитывает ответ маршрута key ("METHOD /path"); маршруты без целей пропускаются.
+func (t *Tracker) Observe(key string, status int, duration time.Duration, now time.Time) {
+	r, ok := t.routes[key]
+	if !ok {
+		return
+	}
+
+	failed := status >= 500
+	slow := r.slo.LatencyTarget > 0 && duration > r.slo.LatencyThreshold
+
+	t.mu.Lock()
+	minute := now.Unix() / 60
+	b := &r.buckets[minute%int64(len(r.buckets))]
+	if b.minute != minute {
+		*b = bucket{minute: minute}
+	}
+	b.total++
+	if failed {
+		b.errors++
+	}
+	if slow {
+		b.slow++
+	}
+	t.mu.Unlock()
+
+	switch {
+	case failed:
+		sloRequests.Inc(key, "error")
+	case slow:
+		sloRequests.Inc(key, "slow")
+	default:
+		sloRequests.Inc(key, "good")
+	}
+}
+
+// window суммирует запросы за последние window минут, включая текущую.
+func (r *route) window(window time.Duration, now time.Time) (total, errors, slow int64) {
+	minute := now.Unix() / 60
+	from := minute - int64(window/time.Minute)
+	for _, b := range r.buckets {
+		if b.minute > from && b.minute <= minute {
+			total += b.total
+			errors += b.errors
+			slow += b.slow
+		}
+	}
+	return total, errors, slow
+}
+
+func (r *route) objectives() []domain.SLOObjective {
+	var objectives []domain.SLOObjective
+	if r.slo.AvailabilityTarget > 0 {
+		objectives = append(objectives, domain.SLOAvailability)
+	}
+	if r.slo.LatencyTarget > 0 {
+		objectives = append(objectives, domain.SLOLatency)
+	}
+	return objectives
+}
+
+// burnRate возвращает число запросов, плохих ответов и скорость сгорания цели за окно.
+func (r *route) burnRate(objective domain.SLOObjective, window time.Duration, now time.Time) domain.SLOWindow {
+	total, errors, slow := r.window(window, now)
+	bad := errors
+	if objective == domain.SLOLatency {
+		bad = slow
+	}
+
+	result := domain.SLOWindow{Window: FormatWindow(window), Requests: total, Bad: bad}
+	if total > 0 {
+		budget := (100 - r.slo.Target(objective)) / 100
+		result.BurnRate = float64(bad) / float64(total) / budget
+	}
+	return result
+}
+
+// burning сообщает, превышен ли порог правила в обоих окнах.
+func (r *route) burning(objective domain.SLOObjective, rule domain.BurnRateRule, now time.Time) (long, short domain.SLOWindow, ok bool) {
+	long = r.burnRate(objective, rule.LongWindow, now)
+	short = r.burnRate(objective, rule.ShortWindow, now)
+	return long, short, long.BurnRate > rule.Threshold && short.BurnRate > rule.Threshold
+}
+
+// Status возвращает состояние всех целей в порядке конфигурации и обновляет метрику slo_burn_rate.
+func (t *Tracker) Status(now time.Time) []domain.SLOStatus {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	statuses := make([]domain.SLOStatus, 0, len(t.order))
+	for _, key := range t.order {
+		r := t.routes[key]
+		status := domain.SLOStatus{Route: key, LatencyThresholdMS: r.slo.LatencyThreshold.Milliseconds()}
+		for _, objective := range r.objectives() {
+			objectiveStatus := domain.SLOObjectiveStatus{
+				Objective: objective,
+				Target:    r.slo.Target(objective),
+				Windows:   make([]domain.SLOWindow, 0, len(reportWindows)),
+				Alerts:    make([]domain.SLOAlertSeverity, 0),
+			}
+			for _, window := range reportWindows {
+				w := r.burnRate(objective, window, now)
+				sloBurnRate.Set(w.BurnRate, key, string(objective), w.Window)
+				objectiveStatus.Windows = append(objectiveStatus.Windows, w)
+			}
+			for _, rule := range t.rules {
+				if _, _, ok := r.burning(objective, rule, now); ok {
+					objectiveStatus.Alerts = append(objectiveStatus.Alerts, rule.Severity)
+				}
+			}
+			status.Objectives = append(status.Objectives, objectiveStatus)
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses
+}
+
+// Evaluate возвращает оповещения, которые начали срабатывать с прошлой проверки.
+// Пока правило срабатывает, оповещение не повторяется; после восстановления
+// следующее превышение даст новое.
+func (t *Tracker) Evaluate(now time.Time) []domain.SLOBurnAlert {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var alerts []domain.SLOBurnAlert
+	for _, key := range t.order {
+		r := t.routes[key]
+		for _, objective := range r.objectives() {
+			for _, rule := range t.rules {
+				long, short, ok := r.burning(objective, rule, now)
+				k := alertKey{route: key, objective: objective, severity: rule.Severity}
+				if ok && !t.firing[k] {
+					alerts = append(alerts, domain.SLOBurnAlert{
+						Route:         key,
+						Objective:     objective,
+						Severity:      rule.Severity,
+						Target:        r.slo.Target(objective),
+						Threshold:     rule.Threshold,
+						LongWindow:    long.Window,
+						LongBurnRate:  long.BurnRate,
+						ShortWindow:   short.Window,
+						ShortBurnRate: short.BurnRate,
+					})
+				}
+				t.firing[k] = ok
+			}
+		}
+	}
+	return alerts
+}
+
+// FormatWindow записывает окно коротко: 5m, 1h, 6h.
+func FormatWindow(window time.Duration) string {
+	s := window.String()
+	if strings.HasSuffix(s, "m0s") {
+		s = s[:len(s)-2]
+	}
+	if strings.HasSuffix(s, "h0m") {
+		s = s[:len(s)-2]
+	}
+	return s
+}
@@ -0,0 +1,96 @@
+package slo
+
+import (
+	"testing"
+	"time"
+
+	"aggregator_db/pkg/domain"
+)
+
+func TestTrackerBurnRate(t *testing.T) {
+	const route = "GET /api/v1/subscriptions/calculate"
+	tracker := NewTracker([]domain.SLO{
+		{Route: route, AvailabilityTarget: 99.9, LatencyThreshold: 300 * time.Millisecond, LatencyTarget: 90},
+	}, domain.DefaultBurnRateRules(14.4, 6))
+
+	start := time.Date(2025, 7, 1, 10, 0, 0, 0, time.UTC)
+	// Час без ошибок, затем 5 минут, в которые падает каждый пятый запрос
+	for minute := 0; minute < 60; minute++ {
+		at := start.Add(time.Duration(minute) * time.Minute)
+		for i := 0; i < 10; i++ {
+			tracker.Observe(route, 200, 100*time.Millisecond, at)
+		}
+	}
+	tracker.Observe("GET /api/v1/users", 500, time.Second, start)
+
+	now := start.Add(time.Hour)
+	if alerts := tracker.Evaluate(now); len(alerts) != 0 {
+		t.Fatalf("healthy route fired %+v", alerts)
+	}
+
+	for minute := 60; minute < 65; minute++ {
+		at := start.Add(time.Duration(minute) * time.Minute)
+		for i := 0; i < 10; i++ {
+			status := 200
+			if i%5 == 0 {
+				status = 503
+			}
+			tracker.Observe(route, status, 100*time.Millisecond, at)
+		}
+	}
+	now = start.Add(64 * time.Minute)
+
+	alerts := tracker.Evaluate(now)
+	// За 1h: 10 ошибок из 600 (1.7%) при бюджете 0.1% - 16.7; за 5m: 10 из 50 - 200.
+	// Правило ticket срабатывает тоже: за 6h - 15.4, за 30m - 33.3
+	if len(alerts) != 2 || alerts[0].Severity != domain.SLOPage || alerts[1].Severity != domain.SLOTicket {
+		t.Fatalf("alerts = %+v", alerts)
+	}
+	if alerts[0].Objective != domain.SLOAvailability || alerts[0].LongWindow != "1h" || alerts[0].ShortWindow != "5m" || alerts[0].ShortBurnRate < 199 {
+		t.Errorf("alert = %+v", alerts[0])
+	}
+	if again := tracker.Evaluate(now); len(again) != 0 {
+		t.Errorf("firing alert repeated: %+v", again)
+	}
+
+	statuses := tracker.Status(now)
+	if len(statuses) != 1 || len(statuses[0].Objectives) != 2 || statuses[0].LatencyThresholdMS != 300 {
+		t.Fatalf("statuses = %+v", statuses)
+	}
+	availability := statuses[0].Objectives[0]
+	if len(availability.Alerts) != 2 || availability.Alerts[0] != domain.SLOPage {
+		t.Errorf("availability alerts = %v", availability.Alerts)
+	}
+	if w := availability.Windows[2]; w.Window != "1h" || w.Requests != 600 || w.Bad != 10 {
+		t.Errorf("1h window = %+v", w)
+	}
+	if latency := statuses[0].Objectives[1]; len(latency.Alerts) != 0 || latency.Windows[3].Bad != 0 {
+		t.Errorf("latency = %+v", latency)
+	}
+
+	// Через 10 минут без ошибок окно 5m остывает и page снимается; ticket держится по окну 30m
+	later := now.Add(10 * time.Minute)
+	for minute := 0; minute < 10; minute++ {
+		tracker.Observe(route, 200, 100*time.Millisecond, now.Add(time.Duration(minute+1)*time.Minute))
+	}
+	if alerts := tracker.Evaluate(later); len(alerts) != 0 {
+		t.Fatalf("recovered route fired %+v", alerts)
+	}
+	if status := tracker.Status(later); len(status[0].Objectives[0].Alerts) != 1 || status[0].Objectives[0].Alerts[0] != domain.SLOTicket {
+		t.Errorf("recovered alerts = %v", status[0].Objectives[0].Alerts)
+	}
+}
+
+func TestFormatWindow(t *testing.T) {
+	for window, want := range map[time.Duration]string{
+		5 * time.Minute:  "5m",
+		30 * time.Minute: "30m",
+		time.Hour:        "1h",
+		6 * time.Hour:    "6h",
+		90 * time.Minute: "1h30m",
+	} {
+		if got := FormatWindow(window); got != want {
+			t.Errorf("FormatWindow(%s) = %s, want %s", window, got, want)
+		}
+	}
+}
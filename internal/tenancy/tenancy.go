@@ -0,0 +1,26 @@
+// Package tenancy передает текущего тенанта через context.Context от HTTP-слоя
+// до репозиториев.
+package tenancy
+
+import (
+	"context"
+
+	"aggregator_db/pkg/domain"
+	"aggregator_db/pkg/metrics"
+)
+
+type contextKey struct{}
+
+func WithTenant(ctx context.Context, tenant *domain.Tenant) context.Context {
+	return context.WithValue(ctx, contextKey{}, tenant)
+}
+
+// FromContext возвращает тенанта запроса или nil для запросов без тенанта:
+// они работают с общей схемой public.
+func FromContext(ctx context.Context) *domain.Tenant {
+	tenant, _ := ctx.Value(contextKey{}).(*domain.Tenant)
+	return tenant
+}
+
+// RequestsTotal считает запросы тенантов; по нему строится usage в админском API.
+var RequestsTotal = metrics.NewCounterVec("tenant_requests_total", "HTTP requests per tenant", "tenant")
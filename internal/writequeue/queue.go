@@ -0,0 +1,314 @@
+// Package writequeue - локальная очередь записей на диске. Записи добавляются
+// в журнал JSON-строк с fsync после каждой, поэтому переживают перезапуск процесса.
+package writequeue
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrFull          = errors.New("write queue is full")
+	ErrEntryNotFound = errors.New("queued write not found")
+)
+
+// Entry - запись в очереди. Payload разбирает владелец очереди по Kind.
+type Entry struct {
+	ID         uuid.UUID       `json:"id"`
+	Kind       string          `json:"kind"`
+	Payload    json.RawMessage `json:"payload"`
+	EnqueuedAt time.Time       `json:"enqueued_at"`
+	// Conflict - почему запись не применилась при повторе; такая запись ждет разбора и не повторяется
+	Conflict string `json:"conflict,omitempty"`
+}
+
+// record - строка журнала: добавление записи, подтверждение, конфликт или новые данные записи.
+type record struct {
+	Op      string          `json:"op"`
+	Entry   *Entry          `json:"entry,omitempty"`
+	ID      uuid.UUID       `json:"id,omitempty"`
+	Reason  string          `json:"reason,omitempty"`
+	Payload json.RawMessage `json:"payload,omitempty"`
+}
+
+const (
+	opEnqueue  = "enqueue"
+	opAck      = "ack"
+	opConflict = "conflict"
+	opPayload  = "payload"
+)
+
+type Queue struct {
+	mu         sync.Mutex
+	path       string
+	file       *os.File
+	entries    []*Entry
+	maxEntries int
+}
+
+// Open читает журнал path, переписывает его без подтвержденных записей и
+// открывает на дозапись. maxEntries ограничивает размер очереди.
+func Open(path string, maxEntries int) (*Queue, error) {
+	entries, err := load(path)
+	if err != nil {
+		return nil, err
+	}
+
+	q := &Queue{path: path, entries: entries, maxEntries: maxEntries}
+	if err := q.compact(); err != nil {
+		return nil, err
+	}
+	return q, nil
+}
+
+func load(path string) ([]*Entry, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []*Entry
+	index := make(map[uuid.UUID]*Entry)
+	lines := bytes.Split(data, []byte("\n"))
+	for i, line := range lines {
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+		var rec record
+		if err := json.Unmarshal(line, &rec); err != nil {
+			// Оборванная последняя строка - запись, не подтвержденная клиенту
+			if i == len(lines)-1 {
+				break
+			}
+			return nil, fmt.Errorf("write queue %s: line %d: %w", path, i+1, err)
+		}
+
+		switch rec.Op {
+		case opEnqueue:
+			if rec.Entry != nil {
+				entries = append(entries, rec.Entry)
+				index[rec.Entry.ID] = rec.Entry
+			}
+		case opAck:
+			delete(index, rec.ID)
+		case opConflict:
+			if entry, ok := index[rec.ID]; ok {
+				entry.Conflict = rec.Reason
+			}
+		case opPayload:
+			if entry, ok := index[rec.ID]; ok {
+				entry.Payload = rec.Payload
+			}
+		}
+	}
+
+	live := entries[:0]
+	for _, entry := range entries {
+		if _, ok := index[entry.ID]; ok {
+			live = append(live, entry)
+		}
+	}
+	return live, nil
+}
+
+// compact переписывает журнал текущими записями через временный файл.
+func (q *Queue) compact() error {
+	if q.file != nil {
+		if err := q.file.Close(); err != nil {
+			return err
+		}
+		q.file = nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(q.path), 0o755); err != nil {
+		return err
+	}
+	tmp := q.path + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(f)
+	for _, entry := range q.entries {
+		if err := writeRecord(w, record{Op: opEnqueue, Entry: entry}); err != nil {
+			_ = f.Close()
+			return err
+		}
+		if entry.Conflict != "" {
+			if err := writeRecord(w, record{Op: opConflict, ID: entry.ID, Reason: entry.Conflict}); err != nil {
+				_ = f.Close()
+				return err
+			}
+		}
+	}
+	if err := w.Flush(); err != nil {
+		_ = f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		_ = f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, q.path); err != nil {
+		return err
+	}
+
+	q.file, err = os.OpenFile(q.path, os.O_APPEND|os.O_WRONLY, 0o600)
+	return err
+}
+
+func writeRecord(w interface{ Write([]byte) (int, error) }, rec record) error {
+	line, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(line, '\n'))
+	return err
+}
+
+// append пишет строку в журнал и дожидается fsync.
+func (q *Queue) append(rec record) error {
+	if err := writeRecord(q.file, rec); err != nil {
+		return err
+	}
+	return q.file.Sync()
+}
+
+// Append добавляет запись; она считается принятой только после успешного возврата.
+func (q *Queue) Append(entry Entry) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.maxEntries > 0 && len(q.entries) >= q.maxEntries {
+		return ErrFull
+	}
+	if err := q.append(record{Op: opEnqueue, Entry: &entry}); err != nil {
+		return err
+	}
+	q.entries = append(q.entries, &entry)
+	return nil
+}
+
+// Ack удаляет примененную или отброшенную запись. Опустевший журнал обрезается.
+func (q *Queue) Ack(id uuid.UUID) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	i := q.find(id)
+	if i < 0 {
+		return ErrEntryNotFound
+	}
+	if err := q.append(record{Op: opAck, ID: id}); err != nil {
+		return err
+	}
+	q.entries = append(q.entries[:i], q.entries[i+1:]...)
+
+	if len(q.entries) == 0 {
+		return q.compact()
+	}
+	return nil
+}
+
+// MarkConflict помечает запись конфликтной: она остается в очереди до разбора.
+func (q *Queue) MarkConflict(id uuid.UUID, reason string) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	i := q.find(id)
+	if i < 0 {
+		return ErrEntryNotFound
+	}
+	if err := q.append(record{Op: opConflict, ID: id, Reason: reason}); err != nil {
+		return err
+	}
+	q.entries[i].Conflict = reason
+	return nil
+}
+
+// SetPayload заменяет данные записи, которая еще ждет повтора.
+func (q *Queue) SetPayload(id uuid.UUID, payload json.RawMessage) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	i := q.find(id)
+	if i < 0 {
+		return ErrEntryNotFound
+	}
+	if err := q.append(record{Op: opPayload, ID: id, Payload: payload}); err != nil {
+		return err
+	}
+	q.entries[i].Payload = payload
+	return nil
+}
+
+func (q *Queue) find(id uuid.UUID) int {
+	for i, entry := range q.entries {
+		if entry.ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// Get возвращает копию записи по id.
+func (q *Queue) Get(id uuid.UUID) (Entry, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	i := q.find(id)
+	if i < 0 {
+		return Entry{}, ErrEntryNotFound
+	}
+	return *q.entries[i], nil
+}
+
+// List возвращает копии всех записей в порядке добавления.
+func (q *Queue) List() []Entry {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	list := make([]Entry, len(q.entries))
+	for i, entry := range q.entries {
+		list[i] = *entry
+	}
+	return list
+}
+
+// Pending возвращает записи, ожидающие повтора (без конфликтных).
+func (q *Queue) Pending() []Entry {
+	list := q.List()
+	pending := list[:0]
+	for _, entry := range list {
+		if entry.Conflict == "" {
+			pending = append(pending, entry)
+		}
+	}
+	return pending
+}
+
+func (q *Queue) Close() error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.file == nil {
+		return nil
+	}
+	err := q.file.Close()
+	q.file = nil
+	return err
+}
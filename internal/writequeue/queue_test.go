@@ -0,0 +1,139 @@
+package writequeue
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func newEntry(kind string) Entry {
+	return Entry{ID: uuid.New(), Kind: kind, Payload: json.RawMessage(`{"n":1}`), EnqueuedAt: time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)}
+}
+
+func TestQueueSurvivesReopen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "writes.log")
+	q, err := Open(path, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	first, second, third := newEntry("create"), newEntry("update"), newEntry("create")
+	for _, entry := range []Entry{first, second, third} {
+		if err := q.Append(entry); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := q.Ack(first.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := q.MarkConflict(third.ID, "subscription was modified"); err != nil {
+		t.Fatal(err)
+	}
+	if err := q.SetPayload(second.ID, json.RawMessage(`{"n":2}`)); err != nil {
+		t.Fatal(err)
+	}
+	if err := q.SetPayload(first.ID, json.RawMessage(`{"n":2}`)); !errors.Is(err, ErrEntryNotFound) {
+		t.Fatalf("got %v, want ErrEntryNotFound", err)
+	}
+	if err := q.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	q, err = Open(path, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer q.Close()
+
+	list := q.List()
+	if len(list) != 2 || list[0].ID != second.ID || list[1].ID != third.ID {
+		t.Fatalf("unexpected entries after reopen: %+v", list)
+	}
+	if list[1].Conflict != "subscription was modified" {
+		t.Errorf("conflict reason lost: %q", list[1].Conflict)
+	}
+	if string(list[0].Payload) != `{"n":2}` || !list[0].EnqueuedAt.Equal(second.EnqueuedAt) {
+		t.Errorf("entry changed after reopen: %+v", list[0])
+	}
+
+	pending := q.Pending()
+	if len(pending) != 1 || pending[0].ID != second.ID {
+		t.Errorf("conflicts must not be pending: %+v", pending)
+	}
+}
+
+func TestQueueTruncatedTail(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "writes.log")
+	q, err := Open(path, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	entry := newEntry("create")
+	if err := q.Append(entry); err != nil {
+		t.Fatal(err)
+	}
+	_ = q.Close()
+
+	// Процесс упал посреди записи следующей строки
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = f.WriteString(`{"op":"enqueue","entry":{"id":"`)
+	_ = f.Close()
+
+	q, err = Open(path, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer q.Close()
+	if list := q.List(); len(list) != 1 || list[0].ID != entry.ID {
+		t.Fatalf("unexpected entries: %+v", list)
+	}
+	// После открытия журнал переписан, и новые строки не склеиваются с обрывком
+	if err := q.Append(newEntry("update")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := load(path); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestQueueLimitAndCompaction(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "writes.log")
+	q, err := Open(path, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer q.Close()
+
+	entry := newEntry("create")
+	if err := q.Append(entry); err != nil {
+		t.Fatal(err)
+	}
+	if err := q.Append(newEntry("create")); !errors.Is(err, ErrFull) {
+		t.Fatalf("got %v, want ErrFull", err)
+	}
+	if err := q.Ack(entry.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := q.Ack(entry.ID); !errors.Is(err, ErrEntryNotFound) {
+		t.Fatalf("got %v, want ErrEntryNotFound", err)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Size() != 0 {
+		t.Errorf("empty queue must truncate the log, size %d", info.Size())
+	}
+	if err := q.Append(newEntry("create")); err != nil {
+		t.Fatal(err)
+	}
+}
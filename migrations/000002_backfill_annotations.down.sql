@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS idx_subscriptions_is_backfilled;
+
+ALTER TABLE subscriptions
+    DROP COLUMN IF EXISTS backfill_note,
+    DROP COLUMN IF EXISTS exclude_from_new_analytics,
+    DROP COLUMN IF EXISTS is_backfilled;
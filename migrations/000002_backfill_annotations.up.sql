@@ -0,0 +1,6 @@
+ALTER TABLE subscriptions
+    ADD COLUMN IF NOT EXISTS is_backfilled BOOLEAN NOT NULL DEFAULT FALSE,
+    ADD COLUMN IF NOT EXISTS exclude_from_new_analytics BOOLEAN NOT NULL DEFAULT FALSE,
+    ADD COLUMN IF NOT EXISTS backfill_note TEXT;
+
+CREATE INDEX IF NOT EXISTS idx_subscriptions_is_backfilled ON subscriptions(is_backfilled) WHERE is_backfilled;
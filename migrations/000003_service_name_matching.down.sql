@@ -0,0 +1,6 @@
+DROP TABLE IF EXISTS service_aliases;
+
+DROP INDEX IF EXISTS idx_subscriptions_service_key;
+
+ALTER TABLE subscriptions
+    DROP COLUMN IF EXISTS service_key;
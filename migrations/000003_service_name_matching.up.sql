@@ -0,0 +1,34 @@
+ALTER TABLE subscriptions
+    ADD COLUMN IF NOT EXISTS service_key TEXT NOT NULL DEFAULT '';
+
+-- То же преобразование, что и domain.ServiceKey
+UPDATE subscriptions
+SET service_key = regexp_replace(
+    translate(
+        replace(replace(replace(replace(replace(replace(replace(replace(
+            lower(service_name),
+            'щ', 'shch'), 'ж', 'zh'), 'х', 'kh'), 'ц', 'ts'),
+            'ч', 'ch'), 'ш', 'sh'), 'ю', 'yu'), 'я', 'ya'),
+        'абвгдеёзийклмнопрстуфыэъь',
+        'abvgdeeziyklmnoprstufye'
+    ),
+    '[^a-z0-9]', '', 'g'
+);
+
+CREATE INDEX IF NOT EXISTS idx_subscriptions_service_key ON subscriptions(service_key);
+
+CREATE TABLE IF NOT EXISTS service_aliases (
+    alias_key TEXT PRIMARY KEY,
+    alias TEXT NOT NULL,
+    canonical_key TEXT NOT NULL,
+    canonical_name TEXT NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_service_aliases_canonical_key ON service_aliases(canonical_key);
+
+INSERT INTO service_aliases (alias_key, alias, canonical_key, canonical_name) VALUES
+    ('yandeksplyus', 'Яндекс Плюс', 'yandexplus', 'Yandex Plus'),
+    ('netfliks', 'Нетфликс', 'netflix', 'Netflix'),
+    ('spotifay', 'Спотифай', 'spotify', 'Spotify')
+ON CONFLICT (alias_key) DO NOTHING;
@@ -0,0 +1,6 @@
+DROP TABLE IF EXISTS subscription_status_changes;
+
+DROP INDEX IF EXISTS idx_subscriptions_status;
+
+ALTER TABLE subscriptions
+    DROP COLUMN IF EXISTS status;
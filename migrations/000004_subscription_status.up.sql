@@ -0,0 +1,17 @@
+ALTER TABLE subscriptions
+    ADD COLUMN IF NOT EXISTS status VARCHAR(16) NOT NULL DEFAULT 'active'
+        CHECK (status IN ('active', 'paused', 'cancelled', 'expired'));
+
+CREATE INDEX IF NOT EXISTS idx_subscriptions_status ON subscriptions(status);
+
+-- История статусов: статус действует с первого числа месяца effective_from
+CREATE TABLE IF NOT EXISTS subscription_status_changes (
+    id BIGSERIAL PRIMARY KEY,
+    subscription_id UUID NOT NULL REFERENCES subscriptions(id) ON DELETE CASCADE,
+    status VARCHAR(16) NOT NULL CHECK (status IN ('active', 'paused', 'cancelled', 'expired')),
+    effective_from DATE NOT NULL,
+    changed_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_subscription_status_changes_subscription
+    ON subscription_status_changes(subscription_id, effective_from);
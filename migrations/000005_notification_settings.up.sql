@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS user_notification_settings (
+    user_id UUID PRIMARY KEY,
+    spend_alerts BOOLEAN NOT NULL DEFAULT FALSE,
+    threshold_percent INTEGER CHECK (threshold_percent > 0),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_notification_settings_spend_alerts
+    ON user_notification_settings(user_id) WHERE spend_alerts;
@@ -0,0 +1,9 @@
+-- Реестр тенантов всегда живет в public: эта же миграция применяется
+-- к схемам тенантов, и там она ничего не создает.
+CREATE TABLE IF NOT EXISTS public.tenants (
+    id VARCHAR(31) PRIMARY KEY,
+    isolation VARCHAR(16) NOT NULL CHECK (isolation IN ('schema', 'database')),
+    schema_name VARCHAR(63) NOT NULL,
+    database_url TEXT,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
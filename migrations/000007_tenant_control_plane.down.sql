@@ -0,0 +1,6 @@
+ALTER TABLE public.tenants
+    DROP COLUMN IF EXISTS status,
+    DROP COLUMN IF EXISTS max_subscriptions,
+    DROP COLUMN IF EXISTS features,
+    DROP COLUMN IF EXISTS api_key_hash,
+    DROP COLUMN IF EXISTS updated_at;
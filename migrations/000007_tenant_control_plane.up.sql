@@ -0,0 +1,7 @@
+-- IF NOT EXISTS: миграция применяется и к схемам тенантов, где public.tenants уже изменена.
+ALTER TABLE public.tenants
+    ADD COLUMN IF NOT EXISTS status VARCHAR(16) NOT NULL DEFAULT 'active' CHECK (status IN ('active', 'suspended')),
+    ADD COLUMN IF NOT EXISTS max_subscriptions INTEGER CHECK (max_subscriptions >= 0),
+    ADD COLUMN IF NOT EXISTS features JSONB NOT NULL DEFAULT '{}',
+    ADD COLUMN IF NOT EXISTS api_key_hash VARCHAR(64),
+    ADD COLUMN IF NOT EXISTS updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW();
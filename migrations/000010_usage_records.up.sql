@@ -0,0 +1,10 @@
+-- Журнал потребления API для выставления счетов; как и реестр тенантов, живет в public.
+CREATE TABLE IF NOT EXISTS public.usage_records (
+    consumer VARCHAR(63) NOT NULL,
+    metric VARCHAR(32) NOT NULL,
+    day DATE NOT NULL,
+    quantity BIGINT NOT NULL CHECK (quantity >= 0),
+    PRIMARY KEY (consumer, metric, day)
+);
+
+CREATE INDEX IF NOT EXISTS idx_usage_records_day ON public.usage_records(day);
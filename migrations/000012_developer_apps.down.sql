@@ -0,0 +1 @@
+DROP TABLE IF EXISTS public.developer_apps;
@@ -0,0 +1,10 @@
+-- Приложения портала разработчиков; общие для всех тенантов, поэтому в public.
+CREATE TABLE IF NOT EXISTS public.developer_apps (
+    id UUID PRIMARY KEY,
+    name VARCHAR(100) NOT NULL,
+    contact_email VARCHAR(254) NOT NULL,
+    rate_limit_per_minute INTEGER NOT NULL CHECK (rate_limit_per_minute > 0),
+    secret_hash CHAR(64) NOT NULL UNIQUE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
@@ -0,0 +1 @@
+ALTER TABLE public.developer_apps DROP COLUMN IF EXISTS sandbox;
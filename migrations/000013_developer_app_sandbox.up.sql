@@ -0,0 +1,2 @@
+-- Ключи, выданные порталом, работают в песочнице; боевой доступ включает администратор.
+ALTER TABLE public.developer_apps ADD COLUMN IF NOT EXISTS sandbox BOOLEAN NOT NULL DEFAULT TRUE;
@@ -0,0 +1,8 @@
+-- Копейки округляются до целых единиц; валюта теряется
+ALTER TABLE subscriptions ADD COLUMN IF NOT EXISTS price INTEGER CHECK (price >= 0);
+
+UPDATE subscriptions SET price = ((price_minor + 50) / 100)::int;
+
+ALTER TABLE subscriptions ALTER COLUMN price SET NOT NULL;
+ALTER TABLE subscriptions DROP COLUMN IF EXISTS currency;
+ALTER TABLE subscriptions DROP COLUMN IF EXISTS price_minor;
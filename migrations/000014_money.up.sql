@@ -0,0 +1,8 @@
+-- Цена хранится в минорных единицах валюты (копейках, центах)
+ALTER TABLE subscriptions ADD COLUMN IF NOT EXISTS price_minor BIGINT CHECK (price_minor >= 0);
+ALTER TABLE subscriptions ADD COLUMN IF NOT EXISTS currency CHAR(3) NOT NULL DEFAULT 'RUB';
+
+UPDATE subscriptions SET price_minor = price::bigint * 100;
+
+ALTER TABLE subscriptions ALTER COLUMN price_minor SET NOT NULL;
+ALTER TABLE subscriptions DROP COLUMN price;
@@ -0,0 +1,20 @@
+-- Выгрузки с доставкой на почту; общие для всех тенантов, поэтому в public.
+CREATE TABLE IF NOT EXISTS public.export_jobs (
+    id UUID PRIMARY KEY,
+    kind VARCHAR(32) NOT NULL,
+    params JSONB NOT NULL,
+    email VARCHAR(254) NOT NULL,
+    delivery VARCHAR(16) NOT NULL CHECK (delivery IN ('attachment', 'link')),
+    status VARCHAR(16) NOT NULL CHECK (status IN ('pending', 'processing', 'delivered', 'failed')),
+    attempts INTEGER NOT NULL DEFAULT 0,
+    error TEXT,
+    file_name VARCHAR(255) NOT NULL DEFAULT '',
+    content BYTEA,
+    download_token_hash CHAR(64),
+    expires_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    delivered_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX IF NOT EXISTS idx_export_jobs_status ON public.export_jobs (status, updated_at);
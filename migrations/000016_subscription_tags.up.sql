@@ -0,0 +1,4 @@
+-- Метки подписок (work, family, trial); фильтр по меткам - tags @> ARRAY[...]
+ALTER TABLE subscriptions ADD COLUMN IF NOT EXISTS tags TEXT[] NOT NULL DEFAULT '{}';
+
+CREATE INDEX IF NOT EXISTS idx_subscriptions_tags ON subscriptions USING GIN (tags);
@@ -0,0 +1,7 @@
+-- Заметки к подписке и поиск по названию и заметкам (?q=): ILIKE по выражению ниже ускоряет триграммный индекс
+CREATE EXTENSION IF NOT EXISTS pg_trgm WITH SCHEMA public;
+
+ALTER TABLE subscriptions ADD COLUMN IF NOT EXISTS notes TEXT;
+
+CREATE INDEX IF NOT EXISTS idx_subscriptions_search ON subscriptions
+    USING GIN ((service_name || ' ' || COALESCE(notes, '')) gin_trgm_ops);
@@ -0,0 +1,18 @@
+-- Скидки на подписку: месяцы окна хранятся первым числом месяца, end_month включительно
+CREATE TABLE IF NOT EXISTS subscription_discounts (
+    id UUID PRIMARY KEY,
+    subscription_id UUID NOT NULL REFERENCES subscriptions(id) ON DELETE CASCADE,
+    kind VARCHAR(16) NOT NULL CHECK (kind IN ('percent', 'fixed')),
+    percent INTEGER CHECK (percent BETWEEN 1 AND 100),
+    amount_minor BIGINT CHECK (amount_minor > 0),
+    currency CHAR(3),
+    start_month DATE NOT NULL,
+    end_month DATE,
+    code VARCHAR(64),
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    CHECK ((kind = 'percent' AND percent IS NOT NULL) OR (kind = 'fixed' AND amount_minor IS NOT NULL AND currency IS NOT NULL)),
+    CHECK (end_month IS NULL OR end_month >= start_month)
+);
+
+CREATE INDEX IF NOT EXISTS idx_subscription_discounts_subscription
+    ON subscription_discounts(subscription_id, start_month);
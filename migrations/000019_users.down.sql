@@ -0,0 +1,3 @@
+ALTER TABLE subscriptions DROP CONSTRAINT IF EXISTS fk_subscriptions_user;
+
+DROP TABLE IF EXISTS users;
@@ -0,0 +1,20 @@
+CREATE TABLE IF NOT EXISTS users (
+    id UUID PRIMARY KEY,
+    email VARCHAR(254),
+    name VARCHAR(255),
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_email ON users (LOWER(email)) WHERE email IS NOT NULL;
+
+-- Пользователи, которые до сих пор существовали только как user_id в подписках
+INSERT INTO users (id, created_at, updated_at)
+SELECT user_id, MIN(created_at), MIN(created_at)
+FROM subscriptions
+GROUP BY user_id
+ON CONFLICT (id) DO NOTHING;
+
+ALTER TABLE subscriptions DROP CONSTRAINT IF EXISTS fk_subscriptions_user;
+ALTER TABLE subscriptions
+    ADD CONSTRAINT fk_subscriptions_user FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE;
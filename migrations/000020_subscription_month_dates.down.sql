@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_subscriptions_months;
+
+ALTER TABLE subscriptions
+    DROP COLUMN IF EXISTS start_month,
+    DROP COLUMN IF EXISTS end_month;
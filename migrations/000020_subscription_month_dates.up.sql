@@ -0,0 +1,13 @@
+-- Месяцы подписки в виде дат (первое число месяца) для аналитических запросов.
+-- TO_DATE не IMMUTABLE, поэтому дата собирается из частей строки MM-YYYY.
+ALTER TABLE subscriptions
+    ADD COLUMN IF NOT EXISTS start_month DATE GENERATED ALWAYS AS (
+        make_date(split_part(start_date, '-', 2)::int, split_part(start_date, '-', 1)::int, 1)
+    ) STORED,
+    ADD COLUMN IF NOT EXISTS end_month DATE GENERATED ALWAYS AS (
+        CASE WHEN end_date IS NOT NULL
+            THEN make_date(split_part(end_date, '-', 2)::int, split_part(end_date, '-', 1)::int, 1)
+        END
+    ) STORED;
+
+CREATE INDEX IF NOT EXISTS idx_subscriptions_months ON subscriptions(start_month, end_month);
@@ -0,0 +1,13 @@
+-- Конверты бюджета: месячный лимит трат пользователя на категорию (метку) подписок
+CREATE TABLE IF NOT EXISTS budgets (
+    id UUID PRIMARY KEY,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    category VARCHAR(32) NOT NULL,
+    limit_minor BIGINT NOT NULL CHECK (limit_minor > 0),
+    currency CHAR(3) NOT NULL,
+    alert_percent INTEGER NOT NULL CHECK (alert_percent BETWEEN 1 AND 100),
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_budgets_user_category ON budgets(user_id, category);
@@ -0,0 +1,9 @@
+-- Ключи внутренних сервисов; общие для всех тенантов, поэтому в public.
+CREATE TABLE IF NOT EXISTS public.api_keys (
+    id UUID PRIMARY KEY,
+    name VARCHAR(100) NOT NULL,
+    scope VARCHAR(16) NOT NULL CHECK (scope IN ('read', 'read_write')),
+    secret_hash CHAR(64) NOT NULL UNIQUE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    revoked_at TIMESTAMP WITH TIME ZONE
+);
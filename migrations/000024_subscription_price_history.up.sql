@@ -0,0 +1,18 @@
+-- История цен: цена price_minor за billing_cycle действует с changed_at до следующей записи
+CREATE TABLE IF NOT EXISTS subscription_price_history (
+    id BIGSERIAL PRIMARY KEY,
+    subscription_id UUID NOT NULL REFERENCES subscriptions(id) ON DELETE CASCADE,
+    price_minor BIGINT NOT NULL,
+    currency CHAR(3) NOT NULL,
+    billing_cycle VARCHAR(16) NOT NULL,
+    changed_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_subscription_price_history_subscription
+    ON subscription_price_history(subscription_id, changed_at);
+
+-- Для существующих подписок известна только текущая цена: считаем, что она действует с создания
+INSERT INTO subscription_price_history (subscription_id, price_minor, currency, billing_cycle, changed_at)
+SELECT s.id, s.price_minor, s.currency, s.billing_cycle, s.created_at
+FROM subscriptions s
+WHERE NOT EXISTS (SELECT 1 FROM subscription_price_history h WHERE h.subscription_id = s.id);
@@ -0,0 +1,3 @@
+-- Роль пользователя для проверки доступа (RBAC_ENABLED)
+ALTER TABLE users
+    ADD COLUMN IF NOT EXISTS role VARCHAR(16) NOT NULL DEFAULT 'user' CHECK (role IN ('user', 'admin'));
@@ -0,0 +1,11 @@
+-- Найденные фоновой задачей группы подписок пользователя на один сервис (по service_key).
+-- seen_at обновляется при каждом запуске; группы, не найденные в последнем запуске, удаляются
+CREATE TABLE IF NOT EXISTS subscription_duplicates (
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    service_key VARCHAR(255) NOT NULL,
+    service_name VARCHAR(255) NOT NULL,
+    subscription_ids UUID[] NOT NULL,
+    detected_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    seen_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (user_id, service_key)
+);
@@ -0,0 +1,14 @@
+-- Подсказки администраторам по правилам фоновой проверки; общие для всех тенантов, поэтому в public.
+CREATE TABLE IF NOT EXISTS public.admin_nudges (
+    id UUID PRIMARY KEY,
+    key VARCHAR(255) NOT NULL UNIQUE,
+    kind VARCHAR(32) NOT NULL,
+    tenant_id VARCHAR(64),
+    user_id UUID,
+    message TEXT NOT NULL,
+    metrics JSONB NOT NULL DEFAULT '{}',
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    dismissed_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX IF NOT EXISTS idx_admin_nudges_created_at ON public.admin_nudges(created_at);
@@ -0,0 +1,3 @@
+-- Регион, последним записавший строку: при двунаправленной репликации между регионами
+-- по нему реплика отличает свои изменения от пришедших из другого региона
+ALTER TABLE subscriptions ADD COLUMN IF NOT EXISTS region VARCHAR(32) NOT NULL DEFAULT '';
@@ -0,0 +1,15 @@
+-- Журнал исправлений данных подписок (см. POST /admin/data-issues/repair).
+-- Без внешнего ключа: запись аудита переживает удаление подписки.
+CREATE TABLE IF NOT EXISTS data_repairs (
+    id UUID PRIMARY KEY,
+    subscription_id UUID NOT NULL,
+    user_id UUID NOT NULL,
+    kind VARCHAR(32) NOT NULL,
+    fix VARCHAR(32) NOT NULL,
+    field VARCHAR(32) NOT NULL,
+    before_value TEXT,
+    after_value TEXT,
+    applied_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_data_repairs_applied_at ON data_repairs(applied_at);
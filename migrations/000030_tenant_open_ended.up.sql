@@ -0,0 +1,3 @@
+-- IF NOT EXISTS: миграция применяется и к схемам тенантов, где public.tenants уже изменена.
+ALTER TABLE public.tenants
+    ADD COLUMN IF NOT EXISTS open_ended JSONB;
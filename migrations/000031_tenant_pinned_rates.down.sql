@@ -0,0 +1,2 @@
+ALTER TABLE public.tenants
+    DROP COLUMN IF EXISTS pinned_rates;
@@ -0,0 +1,4 @@
+ALTER TABLE subscriptions
+    DROP COLUMN IF EXISTS start_on,
+    DROP COLUMN IF EXISTS end_on,
+    DROP COLUMN IF EXISTS price_amount;
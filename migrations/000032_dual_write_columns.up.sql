@@ -0,0 +1,9 @@
+-- Новые колонки для перехода по схеме blue/green (см. MIGRATION_DATES и MIGRATION_MONEY):
+-- месяцы подписки датами вместо строк MM-YYYY и цена десятичной суммой в единицах
+-- валюты вместо минорных единиц. start_month/end_month вычисляются из строк и
+-- пропадут вместе с ними, поэтому новые колонки обычные. Пока фаза off, колонки
+-- пустые; после переключения старые удаляются отдельной миграцией.
+ALTER TABLE subscriptions
+    ADD COLUMN IF NOT EXISTS start_on DATE,
+    ADD COLUMN IF NOT EXISTS end_on DATE,
+    ADD COLUMN IF NOT EXISTS price_amount NUMERIC(28, 8) CHECK (price_amount >= 0);
@@ -0,0 +1,16 @@
+-- Журнал изменений данных (см. GET /admin/audit). Пишется в транзакции изменения
+-- в той же схеме, что и данные; без внешних ключей, чтобы запись переживала удаление строки.
+CREATE TABLE IF NOT EXISTS audit_log (
+    id UUID PRIMARY KEY,
+    entity VARCHAR(64) NOT NULL,
+    entity_id UUID NOT NULL,
+    action VARCHAR(16) NOT NULL,
+    actor VARCHAR(255) NOT NULL,
+    request_id VARCHAR(128),
+    before JSONB,
+    after JSONB,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_audit_log_entity ON audit_log(entity, entity_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_audit_log_created_at ON audit_log(created_at);
@@ -0,0 +1,3 @@
+-- Версия подписки для оптимистичной блокировки: каждое изменение увеличивает ее на 1,
+-- а PUT и PATCH с устаревшей версией (If-Match или version в теле) получают 409.
+ALTER TABLE subscriptions ADD COLUMN IF NOT EXISTS version BIGINT NOT NULL DEFAULT 1;
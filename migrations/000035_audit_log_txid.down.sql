@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_audit_log_entity_txid;
+ALTER TABLE audit_log DROP COLUMN IF EXISTS txid;
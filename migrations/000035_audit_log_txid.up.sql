@@ -0,0 +1,6 @@
+-- Транзакция, записавшая изменение: лента изменений подписок (GET /subscriptions/changes)
+-- отдает записи только завершившихся транзакций по порядку txid, поэтому не
+-- пропускает изменение, закоммиченное позже более нового.
+ALTER TABLE audit_log ADD COLUMN IF NOT EXISTS txid xid8 NOT NULL DEFAULT pg_current_xact_id();
+
+CREATE INDEX IF NOT EXISTS idx_audit_log_entity_txid ON audit_log(entity, txid, id);
@@ -0,0 +1,6 @@
+ALTER TABLE public.tenants
+    DROP COLUMN IF EXISTS field_visibility;
+
+UPDATE users SET role = 'user' WHERE role = 'support';
+ALTER TABLE users DROP CONSTRAINT IF EXISTS users_role_check;
+ALTER TABLE users ADD CONSTRAINT users_role_check CHECK (role IN ('user', 'admin'));
@@ -0,0 +1,7 @@
+-- Роль support: читает данные всех пользователей, но не видит скрытые от нее поля
+ALTER TABLE users DROP CONSTRAINT IF EXISTS users_role_check;
+ALTER TABLE users ADD CONSTRAINT users_role_check CHECK (role IN ('user', 'admin', 'support'));
+
+-- IF NOT EXISTS: миграция применяется и к схемам тенантов, где public.tenants уже изменена.
+ALTER TABLE public.tenants
+    ADD COLUMN IF NOT EXISTS field_visibility JSONB;
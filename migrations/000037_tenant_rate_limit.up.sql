@@ -0,0 +1,3 @@
+-- IF NOT EXISTS: миграция применяется и к схемам тенантов, где public.tenants уже изменена.
+ALTER TABLE public.tenants
+    ADD COLUMN IF NOT EXISTS requests_per_minute INTEGER CHECK (requests_per_minute > 0);
@@ -0,0 +1,7 @@
+DROP TRIGGER IF EXISTS monthly_totals_stale ON subscription_status_changes;
+DROP TRIGGER IF EXISTS monthly_totals_stale ON subscription_discounts;
+DROP TRIGGER IF EXISTS monthly_totals_stale ON subscriptions;
+DROP FUNCTION IF EXISTS mark_monthly_totals_stale();
+DROP TABLE IF EXISTS subscription_monthly_totals_state;
+DROP TABLE IF EXISTS subscription_monthly_totals_stale;
+DROP TABLE IF EXISTS subscription_monthly_totals;
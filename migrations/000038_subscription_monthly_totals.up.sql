@@ -0,0 +1,79 @@
+-- Помесячные итоги подписок по пользователю, сервису и валюте для расчета стоимости
+-- и аналитики без разбора всех подписок на месяцы (см. MONTHLY_TOTALS_ENABLED).
+-- Стоимость хранится в долях 1/84 минорной единицы до округления, как в расчете:
+-- units - все месяцы, billable_units - без месяцев на паузе и после отмены,
+-- discount_units - на сколько их уменьшают скидки.
+CREATE TABLE IF NOT EXISTS subscription_monthly_totals (
+    user_id UUID NOT NULL,
+    service_key TEXT NOT NULL,
+    service_name VARCHAR(255) NOT NULL,
+    currency CHAR(3) NOT NULL,
+    month DATE NOT NULL,
+    units BIGINT NOT NULL,
+    billable_units BIGINT NOT NULL,
+    discount_units BIGINT NOT NULL,
+    billable_discount_units BIGINT NOT NULL,
+    billable_months INTEGER NOT NULL,
+    PRIMARY KEY (user_id, month, service_key, service_name, currency)
+);
+
+CREATE INDEX IF NOT EXISTS idx_subscription_monthly_totals_month ON subscription_monthly_totals(month);
+
+-- Пользователи, чьи итоги устарели: их стоимость считается по подпискам, пока
+-- фоновая задача не пересчитает итоги. Отметки ставят триггеры ниже в транзакции
+-- изменения, поэтому чтения не видят итогов без изменений, которые уже видны в подписках.
+CREATE TABLE IF NOT EXISTS subscription_monthly_totals_stale (
+    user_id UUID PRIMARY KEY,
+    marked_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+-- through - последний месяц, за который посчитаны итоги; NULL - итогов еще нет.
+-- Более поздние месяцы всегда считаются по подпискам.
+CREATE TABLE IF NOT EXISTS subscription_monthly_totals_state (
+    id BOOLEAN PRIMARY KEY DEFAULT TRUE CHECK (id),
+    through DATE
+);
+
+INSERT INTO subscription_monthly_totals_state (id, through) VALUES (TRUE, NULL) ON CONFLICT (id) DO NOTHING;
+
+-- ON CONFLICT DO UPDATE блокирует отметку до конца транзакции: пересчет, начатый
+-- раньше, не снимет отметку, поставленную изменением, которого он не видел.
+-- search_path фиксируется при создании, чтобы функция в схеме тенанта писала в его таблицы.
+CREATE OR REPLACE FUNCTION mark_monthly_totals_stale() RETURNS trigger AS $$
+DECLARE
+    users UUID[];
+BEGIN
+    IF TG_TABLE_NAME = 'subscriptions' THEN
+        IF TG_OP <> 'DELETE' THEN
+            users := users || NEW.user_id;
+        END IF;
+        IF TG_OP <> 'INSERT' THEN
+            users := users || OLD.user_id;
+        END IF;
+    ELSE
+        IF TG_OP <> 'DELETE' THEN
+            users := users || ARRAY(SELECT user_id FROM subscriptions WHERE id = NEW.subscription_id);
+        END IF;
+        IF TG_OP <> 'INSERT' THEN
+            users := users || ARRAY(SELECT user_id FROM subscriptions WHERE id = OLD.subscription_id);
+        END IF;
+    END IF;
+
+    INSERT INTO subscription_monthly_totals_stale (user_id)
+    SELECT DISTINCT u FROM unnest(users) AS u
+    ON CONFLICT (user_id) DO UPDATE SET marked_at = NOW();
+    RETURN NULL;
+END
+$$ LANGUAGE plpgsql SET search_path FROM CURRENT;
+
+DROP TRIGGER IF EXISTS monthly_totals_stale ON subscriptions;
+CREATE TRIGGER monthly_totals_stale AFTER INSERT OR UPDATE OR DELETE ON subscriptions
+    FOR EACH ROW EXECUTE FUNCTION mark_monthly_totals_stale();
+
+DROP TRIGGER IF EXISTS monthly_totals_stale ON subscription_discounts;
+CREATE TRIGGER monthly_totals_stale AFTER INSERT OR UPDATE OR DELETE ON subscription_discounts
+    FOR EACH ROW EXECUTE FUNCTION mark_monthly_totals_stale();
+
+DROP TRIGGER IF EXISTS monthly_totals_stale ON subscription_status_changes;
+CREATE TRIGGER monthly_totals_stale AFTER INSERT OR UPDATE OR DELETE ON subscription_status_changes
+    FOR EACH ROW EXECUTE FUNCTION mark_monthly_totals_stale();
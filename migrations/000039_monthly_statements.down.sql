@@ -0,0 +1,10 @@
+DROP INDEX IF EXISTS public.idx_export_jobs_statements;
+DELETE FROM public.export_jobs WHERE delivery = 'webhook';
+ALTER TABLE public.export_jobs DROP CONSTRAINT IF EXISTS export_jobs_delivery_check;
+ALTER TABLE public.export_jobs
+    ADD CONSTRAINT export_jobs_delivery_check CHECK (delivery IN ('attachment', 'link'));
+
+DROP TABLE IF EXISTS statement_closings;
+
+DROP INDEX IF EXISTS idx_user_notification_settings_statements;
+ALTER TABLE user_notification_settings DROP COLUMN IF EXISTS statement_channel;
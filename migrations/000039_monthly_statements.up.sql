@@ -0,0 +1,22 @@
+-- Канал ежемесячной выписки: none - не отправлять, email - письмом с CSV, webhook - событием statement.monthly
+ALTER TABLE user_notification_settings
+    ADD COLUMN IF NOT EXISTS statement_channel VARCHAR(16) NOT NULL DEFAULT 'none'
+        CHECK (statement_channel IN ('none', 'email', 'webhook'));
+
+CREATE INDEX IF NOT EXISTS idx_user_notification_settings_statements
+    ON user_notification_settings(user_id) WHERE statement_channel <> 'none';
+
+-- Закрытые месяцы: выписки за них поставлены в очередь выгрузок
+CREATE TABLE IF NOT EXISTS statement_closings (
+    month DATE PRIMARY KEY,
+    statements INTEGER NOT NULL,
+    closed_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+-- Выписки доставляются очередью выгрузок, в том числе событием
+ALTER TABLE public.export_jobs DROP CONSTRAINT IF EXISTS export_jobs_delivery_check;
+ALTER TABLE public.export_jobs
+    ADD CONSTRAINT export_jobs_delivery_check CHECK (delivery IN ('attachment', 'link', 'webhook'));
+
+CREATE INDEX IF NOT EXISTS idx_export_jobs_statements
+    ON public.export_jobs ((params->>'user_id'), created_at) WHERE kind = 'statement';
@@ -0,0 +1,5 @@
+-- Нечеткий поиск по названию (?q=...&match=fuzzy): оператор % из pg_trgm идет по этому индексу
+CREATE EXTENSION IF NOT EXISTS pg_trgm WITH SCHEMA public;
+
+CREATE INDEX IF NOT EXISTS idx_subscriptions_service_name_trgm ON subscriptions
+    USING GIN (service_name gin_trgm_ops);
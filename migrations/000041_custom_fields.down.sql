@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS idx_subscriptions_custom_fields;
+
+ALTER TABLE subscriptions
+    DROP COLUMN IF EXISTS custom_fields;
+
+ALTER TABLE public.tenants
+    DROP COLUMN IF EXISTS custom_fields;
@@ -0,0 +1,11 @@
+-- Пользовательские поля: схема хранится у тенанта, значения - у подписки
+-- IF NOT EXISTS: миграция применяется и к схемам тенантов, где public.tenants уже изменена.
+ALTER TABLE public.tenants
+    ADD COLUMN IF NOT EXISTS custom_fields JSONB;
+
+ALTER TABLE subscriptions
+    ADD COLUMN IF NOT EXISTS custom_fields JSONB NOT NULL DEFAULT '{}';
+
+-- Фильтр ?custom_field=name:value: оператор @> идет по этому индексу
+CREATE INDEX IF NOT EXISTS idx_subscriptions_custom_fields ON subscriptions
+    USING GIN (custom_fields jsonb_path_ops);
@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS alerts;
+
+ALTER TABLE user_notification_settings
+    DROP COLUMN IF EXISTS escalation;
@@ -0,0 +1,25 @@
+-- Цепочка эскалации критических уведомлений: шаги по порядку, пусто - без эскалации
+ALTER TABLE user_notification_settings
+    ADD COLUMN IF NOT EXISTS escalation JSONB NOT NULL DEFAULT '[]';
+
+-- Критические уведомления, которые ждут подтверждения. id - ID исходного события,
+-- steps - цепочка эскалации на момент уведомления
+CREATE TABLE IF NOT EXISTS alerts (
+    id UUID PRIMARY KEY,
+    user_id UUID NOT NULL,
+    type VARCHAR(64) NOT NULL,
+    status VARCHAR(16) NOT NULL DEFAULT 'open' CHECK (status IN ('open', 'acknowledged')),
+    data JSONB NOT NULL,
+    steps JSONB NOT NULL,
+    step INTEGER NOT NULL DEFAULT 0,
+    next_escalation_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    escalated_at TIMESTAMP WITH TIME ZONE,
+    acknowledged_at TIMESTAMP WITH TIME ZONE,
+    acknowledged_by UUID
+);
+
+CREATE INDEX IF NOT EXISTS idx_alerts_user ON alerts(user_id, created_at DESC);
+
+-- Задача эскалации выбирает только открытые уведомления с наступившим шагом
+CREATE INDEX IF NOT EXISTS idx_alerts_due ON alerts(next_escalation_at) WHERE status = 'open';
@@ -0,0 +1,9 @@
+// Package migrations встраивает SQL-миграции в бинарник, поэтому сервису не
+// нужен каталог migrations/ рядом с собой. Файлы в формате golang-migrate:
+// NNNNNN_name.up.sql и NNNNNN_name.down.sql.
+package migrations
+
+import "embed"
+
+//go:embed *.sql
+var FS embed.FS
@@ -0,0 +1,228 @@
+// Package api - клиент HTTP API подписок для других сервисов. Запросы и ответы -
+// типы pkg/domain, поэтому клиенту не нужны gin и pgx.
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"aggregator_db/pkg/domain"
+	"github.com/google/uuid"
+)
+
+// APIKeyHeader - заголовок ключа внутреннего сервиса (см. cmd/apikey).
+const APIKeyHeader = "X-API-Key"
+
+// ErrQueued - база сервиса недоступна, и запись отложена до ее восстановления (ответ 202).
+var ErrQueued = errors.New("api: write queued")
+
+// Error - ответ API с ошибкой: Status - код HTTP, Code и Error - тело ответа.
+type Error struct {
+	Status int
+	domain.ErrorResponse
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("api: %d %s: %s", e.Status, e.Code, e.ErrorResponse.Error)
+}
+
+type freshnessKey struct{}
+
+// WithFreshness запрашивает для чтений клиента с ctx свежесть данных (параметр
+// freshness): domain.FreshnessStrong - без отставания реплики, ценой скорости.
+func WithFreshness(ctx context.Context, freshness domain.Freshness) context.Context {
+	return context.WithValue(ctx, freshnessKey{}, freshness)
+}
+
+// Client вызывает /api/v1 сервиса подписок по адресу baseURL.
+type Client struct {
+	baseURL string
+	apiKey  string
+	http    *http.Client
+}
+
+// NewClient создает клиент; apiKey - ключ внутреннего сервиса, httpClient nil -
+// http.DefaultClient. Повторов клиент не делает: их политику выбирает вызывающий.
+func NewClient(baseURL, apiKey string, httpClient *http.Client) *Client {
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	return &Client{baseURL: strings.TrimSuffix(baseURL, "/") + "/api/v1", apiKey: apiKey, http: httpClient}
+}
+
+func (c *Client) CreateSubscription(ctx context.Context, req domain.CreateSubscriptionRequest) (*domain.Subscription, error) {
+	var sub domain.Subscription
+	if err := c.do(ctx, http.MethodPost, "/subscriptions", nil, req, &sub); err != nil {
+		return nil, err
+	}
+	return &sub, nil
+}
+
+func (c *Client) GetSubscription(ctx context.Context, id uuid.UUID) (*domain.Subscription, error) {
+	var sub domain.Subscription
+	if err := c.do(ctx, http.MethodGet, "/subscriptions/"+id.String(), nil, nil, &sub); err != nil {
+		return nil, err
+	}
+	return &sub, nil
+}
+
+func (c *Client) ListSubscriptions(ctx context.Context, query domain.ListSubscriptionsQuery) (*domain.ListSubscriptionsResponse, error) {
+	params := url.Values{}
+	setUserID(params, query.UserID)
+	setString(params, "service_name", query.ServiceName)
+	if query.Status != nil {
+		params.Set("status", string(*query.Status))
+	}
+	if query.Limit > 0 {
+		params.Set("limit", strconv.Itoa(query.Limit))
+	}
+	if query.Offset > 0 {
+		params.Set("offset", strconv.Itoa(query.Offset))
+	}
+	for _, tag := range query.Tags {
+		params.Add("tag", tag)
+	}
+	if query.Q != "" {
+		params.Set("q", query.Q)
+	}
+	if query.Match != "" {
+		params.Set("match", string(query.Match))
+	}
+	if query.MinPrice != "" {
+		params.Set("min_price", query.MinPrice)
+	}
+	if query.MaxPrice != "" {
+		params.Set("max_price", query.MaxPrice)
+	}
+	if query.Currency != "" {
+		params.Set("currency", string(query.Currency))
+	}
+	if query.ActiveIn != "" {
+		params.Set("active_in", query.ActiveIn)
+	}
+	for _, filter := range query.CustomField {
+		params.Add("custom_field", filter)
+	}
+	if query.Snapshot != "" {
+		params.Set("snapshot", query.Snapshot)
+	}
+	if query.Cursor != "" {
+		params.Set("cursor", query.Cursor)
+	}
+	if query.SortBy != "" {
+		params.Set("sort_by", string(query.SortBy))
+	}
+	if query.Order != "" {
+		params.Set("order", string(query.Order))
+	}
+
+	var resp domain.ListSubscriptionsResponse
+	if err := c.do(ctx, http.MethodGet, "/subscriptions", params, nil, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+func (c *Client) CalculateTotal(ctx context.Context, req domain.CalculateTotalRequest) (*domain.CalculateTotalResponse, error) {
+	params := url.Values{}
+	setUserID(params, req.UserID)
+	setString(params, "service_name", req.ServiceName)
+	params.Set("start_period", req.StartPeriod)
+	params.Set("end_period", req.EndPeriod)
+	if req.GroupBy != "" {
+		params.Set("group_by", req.GroupBy)
+	}
+	if req.ExcludeInactive {
+		params.Set("exclude_inactive", "true")
+	}
+	if req.Currency != "" {
+		params.Set("currency", string(req.Currency))
+	}
+	if req.TargetCurrency != "" {
+		params.Set("target_currency", string(req.TargetCurrency))
+	}
+	for _, tag := range req.Tags {
+		params.Add("tag", tag)
+	}
+	if req.IncludeItems {
+		params.Set("include_items", "true")
+	}
+
+	var resp domain.CalculateTotalResponse
+	if err := c.do(ctx, http.MethodGet, "/subscriptions/calculate", params, nil, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+func setUserID(params url.Values, userID *uuid.UUID) {
+	if userID != nil {
+		params.Set("user_id", userID.String())
+	}
+}
+
+func setString(params url.Values, key string, value *string) {
+	if value != nil {
+		params.Set(key, *value)
+	}
+}
+
+// do отправляет body в JSON и разбирает успешный ответ в out, а ошибку - в *Error.
+func (c *Client) do(ctx context.Context, method, path string, params url.Values, body, out any) error {
+	if freshness, _ := ctx.Value(freshnessKey{}).(domain.Freshness); freshness != "" && method == http.MethodGet {
+		if params == nil {
+			params = url.Values{}
+		}
+		params.Set("freshness", string(freshness))
+	}
+	target := c.baseURL + path
+	if len(params) > 0 {
+		target += "?" + params.Encode()
+	}
+
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, target, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.apiKey != "" {
+		req.Header.Set(APIKeyHeader, c.apiKey)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		apiErr := &Error{Status: resp.StatusCode}
+		if err := json.NewDecoder(resp.Body).Decode(&apiErr.ErrorResponse); err != nil {
+			apiErr.ErrorResponse.Error = http.StatusText(resp.StatusCode)
+		}
+		return apiErr
+	}
+	if resp.StatusCode == http.StatusAccepted && method != http.MethodGet {
+		return ErrQueued
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
@@ -0,0 +1,120 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"go/parser"
+	"go/token"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"aggregator_db/pkg/domain"
+	"github.com/google/uuid"
+)
+
+func TestClient(t *testing.T) {
+	userID := uuid.New()
+	var got *http.Request
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/api/v1/subscriptions/calculate":
+			_ = json.NewEncoder(w).Encode(domain.CalculateTotalResponse{TotalCost: domain.Money{Amount: 40000, Currency: domain.DefaultCurrency}})
+		case "/api/v1/subscriptions":
+			w.WriteHeader(http.StatusAccepted)
+			_ = json.NewEncoder(w).Encode(domain.QueuedWrite{})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			_ = json.NewEncoder(w).Encode(domain.ErrorResponse{Code: domain.CodeSubscriptionNotFound, Error: "subscription not found"})
+		}
+	}))
+	defer server.Close()
+	client := NewClient(server.URL+"/", "sk_service_test", server.Client())
+	ctx := context.Background()
+
+	total, err := client.CalculateTotal(ctx, domain.CalculateTotalRequest{
+		UserID:      &userID,
+		StartPeriod: "01-2025",
+		EndPeriod:   "12-2025",
+		Tags:        []string{"work", "trial"},
+	})
+	if err != nil {
+		t.Fatalf("CalculateTotal() error = %v", err)
+	}
+	if total.TotalCost.Amount != 40000 {
+		t.Errorf("total = %+v", total.TotalCost)
+	}
+	wantQuery := "end_period=12-2025&start_period=01-2025&tag=work&tag=trial&user_id=" + userID.String()
+	if got.URL.RawQuery != wantQuery {
+		t.Errorf("query = %q, want %q", got.URL.RawQuery, wantQuery)
+	}
+	if key := got.Header.Get(APIKeyHeader); key != "sk_service_test" {
+		t.Errorf("%s = %q", APIKeyHeader, key)
+	}
+
+	_, err = client.GetSubscription(WithFreshness(ctx, domain.FreshnessStrong), uuid.New())
+	if got.URL.RawQuery != "freshness=strong" {
+		t.Errorf("query = %q, want freshness=strong", got.URL.RawQuery)
+	}
+	var apiErr *Error
+	if !errors.As(err, &apiErr) || apiErr.Status != http.StatusNotFound || apiErr.Code != domain.CodeSubscriptionNotFound {
+		t.Errorf("GetSubscription() error = %v, want 404 %s", err, domain.CodeSubscriptionNotFound)
+	}
+
+	_, err = client.CreateSubscription(ctx, domain.CreateSubscriptionRequest{ServiceName: "Netflix", UserID: userID, StartDate: "01-2025"})
+	if !errors.Is(err, ErrQueued) {
+		t.Errorf("CreateSubscription() error = %v, want ErrQueued", err)
+	}
+	if ct := got.Header.Get("Content-Type"); ct != "application/json" {
+		t.Errorf("Content-Type = %q", ct)
+	}
+}
+
+// TestPublicPackagesImports следит, чтобы пакеты для других сервисов не тянули
+// gin, pgx и внутренние пакеты этого сервиса.
+func TestPublicPackagesImports(t *testing.T) {
+	const module = "aggregator_db/"
+	forbidden := []string{"github.com/gin-gonic/", "github.com/jackc/", module + "internal/"}
+
+	seen := map[string]bool{}
+	queue := []string{"pkg/api", "pkg/domain", "pkg/store", "pkg/events"}
+	for len(queue) > 0 {
+		dir := queue[0]
+		queue = queue[1:]
+		if seen[dir] {
+			continue
+		}
+		seen[dir] = true
+
+		files, err := filepath.Glob(filepath.Join("..", "..", dir, "*.go"))
+		if err != nil || len(files) == 0 {
+			t.Fatalf("no Go files in %s: %v", dir, err)
+		}
+		for _, file := range files {
+			if strings.HasSuffix(file, "_test.go") {
+				continue
+			}
+			parsed, err := parser.ParseFile(token.NewFileSet(), file, nil, parser.ImportsOnly)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, spec := range parsed.Imports {
+				path, _ := strconv.Unquote(spec.Path.Value)
+				for _, prefix := range forbidden {
+					if strings.HasPrefix(path, prefix) {
+						t.Errorf("%s imports %s", file, path)
+					}
+				}
+				if local, ok := strings.CutPrefix(path, module); ok {
+					queue = append(queue, local)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package domain
+
+import (
+	"encoding/json"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// MaxEscalationSteps ограничивает длину цепочки эскалации.
+const MaxEscalationSteps = 5
+
+// AlertChannel - канал шага эскалации: email - письмом на адрес получателя,
+// webhook - событием alert.escalated.
+type AlertChannel string
+
+const (
+	AlertEmail   AlertChannel = "email"
+	AlertWebhook AlertChannel = "webhook"
+)
+
+// EscalationStep - шаг цепочки эскалации: если критическое уведомление не
+// подтвердили за AfterHours часов после предыдущего шага (для первого - после
+// самого уведомления), оно уходит в Channel получателю UserID.
+type EscalationStep struct {
+	AfterHours int          `json:"after_hours" binding:"min=1,max=168" example:"4"`
+	Channel    AlertChannel `json:"channel" binding:"required,oneof=email webhook" example:"email"`
+	// UserID - кому эскалировать; пусто - владельцу уведомления
+	UserID *uuid.UUID `json:"user_id,omitempty" example:"9b2c1d3e-4f5a-4b6c-8d7e-0f1a2b3c4d5e"`
+}
+
+// AlertStatus - состояние критического уведомления.
+type AlertStatus string
+
+const (
+	AlertOpen         AlertStatus = "open"
+	AlertAcknowledged AlertStatus = "acknowledged"
+)
+
+// Alert - критическое уведомление, которое ждет подтверждения. ID совпадает с
+// ID исходного события, Steps - цепочка эскалации на момент уведомления: ее
+// изменение в настройках не затрагивает уже открытые уведомления.
+type Alert struct {
+	ID     uuid.UUID   `json:"id" example:"3f1c2b7a-9d8e-4f6a-b5c4-1e2d3a4b5c6d"`
+	UserID uuid.UUID   `json:"user_id" example:"60601fee-2bf1-4721-ae6f-7636e79a0cba"`
+	Type   string      `json:"type" example:"budget.exceeded"`
+	Status AlertStatus `json:"status" example:"open"`
+	// Data - данные исходного события
+	Data  json.RawMessage  `json:"data" swaggertype:"object"`
+	Steps []EscalationStep `json:"steps"`
+	// Step - сколько шагов эскалации уже выполнено
+	Step int `json:"step" example:"1"`
+	// NextEscalationAt пуст, если уведомление подтверждено или шаги закончились
+	NextEscalationAt *time.Time `json:"next_escalation_at,omitempty" example:"2025-10-23T19:04:05Z"`
+	CreatedAt        time.Time  `json:"created_at" example:"2025-10-23T15:04:05Z"`
+	EscalatedAt      *time.Time `json:"escalated_at,omitempty" example:"2025-10-23T19:04:05Z"`
+	AcknowledgedAt   *time.Time `json:"acknowledged_at,omitempty" example:"2025-10-23T20:00:00Z"`
+	// AcknowledgedBy пуст, если подтвердили без проверки доступа или ключом сервиса
+	AcknowledgedBy *uuid.UUID `json:"acknowledged_by,omitempty" example:"60601fee-2bf1-4721-ae6f-7636e79a0cba"`
+}
+
+// CriticalAlertTypes - события, которые ждут подтверждения и эскалируются.
+var CriticalAlertTypes = []string{"budget.exceeded"}
+
+// IsCriticalAlert сообщает, что событие типа eventType - критическое уведомление.
+func IsCriticalAlert(eventType string) bool {
+	return slices.Contains(CriticalAlertTypes, eventType)
+}
+
+// Recipient возвращает получателя шага step.
+func (a *Alert) Recipient(step EscalationStep) uuid.UUID {
+	if step.UserID != nil {
+		return *step.UserID
+	}
+	return a.UserID
+}
+
+// Notifies сообщает, что пользователь userID - владелец уведомления или
+// получатель одного из его шагов: такие пользователи могут его подтвердить.
+func (a *Alert) Notifies(userID uuid.UUID) bool {
+	if a.UserID == userID {
+		return true
+	}
+	return slices.ContainsFunc(a.Steps, func(step EscalationStep) bool { return a.Recipient(step) == userID })
+}
+
+// NextEscalation возвращает время следующего шага после шага, выполненного в at,
+// или nil, если шагов больше нет.
+func (a *Alert) NextEscalation(at time.Time) *time.Time {
+	if a.Step >= len(a.Steps) {
+		return nil
+	}
+	next := at.Add(time.Duration(a.Steps[a.Step].AfterHours) * time.Hour)
+	return &next
+}
+
+// AlertEscalation - данные события alert.escalated: уведомление не подтвердили
+// вовремя, и шаг Step цепочки отправил его получателю RecipientID.
+type AlertEscalation struct {
+	AlertID     uuid.UUID       `json:"alert_id"`
+	UserID      uuid.UUID       `json:"user_id"`
+	RecipientID uuid.UUID       `json:"recipient_id"`
+	Type        string          `json:"type"`
+	Step        int             `json:"step"`
+	CreatedAt   time.Time       `json:"created_at"`
+	Data        json.RawMessage `json:"data" swaggertype:"object"`
+}
@@ -0,0 +1,60 @@
+package domain
+
+import (
+	"math"
+
+	"github.com/google/uuid"
+)
+
+// YearOverYearRequest - сравнение трат по месяцам года Year с предыдущим годом.
+type YearOverYearRequest struct {
+	Year        int        `form:"year" binding:"required,min=1901,max=9999" example:"2025"`
+	UserID      *uuid.UUID `form:"-"`
+	ServiceName *string    `form:"service_name"`
+	ServiceKeys []string   `form:"-" swaggerignore:"true"`
+	// Currency - валюта сравнения, по умолчанию RUB; подписки в других валютах не учитываются
+	Currency Currency `form:"currency" example:"RUB"`
+	Tags     []string `form:"tag"`
+}
+
+// YearOverYearUnits - стоимость месяца Month (1-12) в году запроса и в предыдущем
+// в долях 1/ProrationDenominator минорной единицы.
+type YearOverYearUnits struct {
+	Month    int
+	Current  int64
+	Previous int64
+}
+
+// SpendComparison - траты за период и их изменение относительно того же периода год назад.
+type SpendComparison struct {
+	Current  Money `json:"current"`
+	Previous Money `json:"previous"`
+	Delta    Money `json:"delta"`
+	// DeltaPercent - изменение в процентах с точностью 0.1; null, если год назад трат не было
+	DeltaPercent *float64 `json:"delta_percent" example:"12.5"`
+}
+
+type YearOverYearMonth struct {
+	Month int `json:"month" example:"7"`
+	SpendComparison
+}
+
+type YearOverYearResponse struct {
+	Year         int                 `json:"year" example:"2025"`
+	PreviousYear int                 `json:"previous_year" example:"2024"`
+	Months       []YearOverYearMonth `json:"months"`
+	Total        SpendComparison     `json:"total"`
+}
+
+// CompareSpend считает абсолютное и относительное изменение трат; суммы в одной валюте.
+func CompareSpend(current, previous Money) SpendComparison {
+	// Разница наследует формат вывода current
+	delta := current
+	delta.Amount -= previous.Amount
+	comparison := SpendComparison{Current: current, Previous: previous, Delta: delta}
+	if previous.Amount != 0 {
+		percent := math.Round(float64(comparison.Delta.Amount)*1000/float64(previous.Amount)) / 10
+		comparison.DeltaPercent = &percent
+	}
+	return comparison
+}
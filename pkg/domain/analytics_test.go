@@ -0,0 +1,34 @@
+package domain
+
+import "testing"
+
+func TestCompareSpend(t *testing.T) {
+	rub := func(amount int64) Money { return NewMoney(amount, DefaultCurrency) }
+
+	cases := []struct {
+		name     string
+		current  Money
+		previous Money
+		delta    int64
+		percent  *float64
+	}{
+		{name: "growth", current: rub(45000), previous: rub(40000), delta: 5000, percent: percentPtr(12.5)},
+		{name: "decline", current: rub(20000), previous: rub(30000), delta: -10000, percent: percentPtr(-33.3)},
+		{name: "no previous spend", current: rub(10000), previous: rub(0), delta: 10000},
+		{name: "no spend", current: rub(0), previous: rub(0)},
+	}
+	for _, tc := range cases {
+		got := CompareSpend(tc.current, tc.previous)
+		if got.Delta != rub(tc.delta) {
+			t.Errorf("%s: delta = %+v, want %d", tc.name, got.Delta, tc.delta)
+		}
+		switch {
+		case tc.percent == nil && got.DeltaPercent != nil:
+			t.Errorf("%s: delta_percent = %v, want null", tc.name, *got.DeltaPercent)
+		case tc.percent != nil && (got.DeltaPercent == nil || *got.DeltaPercent != *tc.percent):
+			t.Errorf("%s: delta_percent = %v, want %v", tc.name, got.DeltaPercent, *tc.percent)
+		}
+	}
+}
+
+func percentPtr(v float64) *float64 { return &v }
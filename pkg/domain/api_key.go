@@ -0,0 +1,72 @@
+package domain
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ServiceAPIKeyPrefix отличает ключи внутренних сервисов от ключей приложений
+// портала разработчиков, которые передаются в том же заголовке X-API-Key.
+const ServiceAPIKeyPrefix = "sk_service_"
+
+// APIKeyScope - права ключа сервиса.
+type APIKeyScope string
+
+const (
+	// APIKeyScopeRead разрешает только чтение: GET, HEAD и OPTIONS
+	APIKeyScopeRead      APIKeyScope = "read"
+	APIKeyScopeReadWrite APIKeyScope = "read_write"
+)
+
+// Allows сообщает, разрешен ли ключу с этими правами запрос с методом method.
+func (s APIKeyScope) Allows(method string) bool {
+	switch s {
+	case APIKeyScopeReadWrite:
+		return true
+	case APIKeyScopeRead:
+		return method == http.MethodGet || method == http.MethodHead || method == http.MethodOptions
+	}
+	return false
+}
+
+// APIKey - ключ для вызовов API внутренними сервисами (агрегаторами, отчетами)
+// без входа от имени пользователя.
+type APIKey struct {
+	ID    uuid.UUID   `json:"id"`
+	Name  string      `json:"name" example:"monthly-aggregator"`
+	Scope APIKeyScope `json:"scope" example:"read"`
+	// SecretHash - sha256 ключа, сам ключ не хранится
+	SecretHash string     `json:"-"`
+	CreatedAt  time.Time  `json:"created_at" example:"2025-10-23T15:04:05Z"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty" example:"2025-11-01T09:00:00Z"`
+}
+
+type CreateAPIKeyRequest struct {
+	Name  string      `json:"name" binding:"required,max=100" example:"monthly-aggregator"`
+	Scope APIKeyScope `json:"scope" binding:"required,oneof=read read_write" example:"read"`
+}
+
+// APIKeyCredentials - ответ с ключом сервиса. Ключ показывается только при выпуске.
+type APIKeyCredentials struct {
+	Key    *APIKey `json:"key"`
+	APIKey string  `json:"api_key" example:"sk_service_5f2b9c..."`
+}
+
+// IsServiceAPIKey сообщает, что ключ из X-API-Key выпущен для внутреннего сервиса.
+func IsServiceAPIKey(key string) bool {
+	return strings.HasPrefix(key, ServiceAPIKeyPrefix)
+}
+
+// NewServiceAPIKey генерирует ключ внутреннего сервиса.
+func NewServiceAPIKey() (string, error) {
+	buf := make([]byte, 24)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return ServiceAPIKeyPrefix + hex.EncodeToString(buf), nil
+}
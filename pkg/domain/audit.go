@@ -0,0 +1,91 @@
+package domain
+
+import (
+	"bytes"
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// AuditAction - вид изменения строки в журнале.
+type AuditAction string
+
+const (
+	AuditCreate AuditAction = "create"
+	AuditUpdate AuditAction = "update"
+	AuditDelete AuditAction = "delete"
+)
+
+// Сущности журнала изменений - таблицы, строки которых в нем записаны.
+const (
+	AuditSubscriptions = "subscriptions"
+	AuditDiscounts     = "subscription_discounts"
+	AuditUsers         = "users"
+	AuditBudgets       = "budgets"
+)
+
+// AuditEntry - запись журнала изменений: строка Entity с ID EntityID до и после
+// изменения. Before = null у созданной строки, After = null у удаленной.
+type AuditEntry struct {
+	ID       uuid.UUID   `json:"id"`
+	Entity   string      `json:"entity" example:"subscriptions"`
+	EntityID uuid.UUID   `json:"entity_id" example:"123e4567-e89b-12d3-a456-426614174000"`
+	Action   AuditAction `json:"action" example:"update"`
+	// Actor - кто изменил: user:<id>, api_key:<имя>, app:<id>, admin, anonymous или system
+	Actor     string          `json:"actor" example:"user:60601fee-2bf1-4721-ae6f-7636e79a0cba"`
+	RequestID *string         `json:"request_id,omitempty" example:"5d3c6a0e-2f1b-4c9e-8a7d-0b1e2f3a4c5d"`
+	Before    json.RawMessage `json:"before" swaggertype:"object"`
+	After     json.RawMessage `json:"after" swaggertype:"object"`
+	CreatedAt time.Time       `json:"created_at" example:"2025-10-23T15:04:05Z"`
+}
+
+// ListAuditQuery - фильтры журнала изменений; объединяются через AND.
+type ListAuditQuery struct {
+	Entity    string      `form:"entity" binding:"omitempty,oneof=subscriptions subscription_discounts users budgets"`
+	EntityID  string      `form:"entity_id" example:"123e4567-e89b-12d3-a456-426614174000"`
+	Action    AuditAction `form:"action" binding:"omitempty,oneof=create update delete"`
+	Actor     string      `form:"actor" example:"admin"`
+	RequestID string      `form:"request_id"`
+	// From и To - границы времени изменения в RFC 3339, To не включается
+	From   string `form:"from" example:"2025-10-01T00:00:00Z"`
+	To     string `form:"to" example:"2025-11-01T00:00:00Z"`
+	Limit  int    `form:"limit,default=100" binding:"min=1,max=1000"`
+	Offset int    `form:"offset" binding:"min=0"`
+	// Разобранные EntityID, From и To заполняет сервис
+	EntityUUID *uuid.UUID `form:"-" swaggerignore:"true"`
+	Since      *time.Time `form:"-" swaggerignore:"true"`
+	Until      *time.Time `form:"-" swaggerignore:"true"`
+}
+
+// NewAuditEntries сравнивает снимки строк ids до и после изменения и возвращает
+// записи журнала для изменившихся строк. Строки, которых нет ни до, ни после,
+// и строки без изменений в журнал не попадают.
+func NewAuditEntries(entity string, ids []uuid.UUID, before, after map[uuid.UUID]json.RawMessage) []*AuditEntry {
+	entries := make([]*AuditEntry, 0, len(ids))
+	seen := make(map[uuid.UUID]bool, len(ids))
+	for _, id := range ids {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+
+		old, existed := before[id]
+		updated, exists := after[id]
+		entry := &AuditEntry{ID: uuid.New(), Entity: entity, EntityID: id, Before: old, After: updated}
+		switch {
+		case !existed && !exists:
+			continue
+		case !existed:
+			entry.Action = AuditCreate
+		case !exists:
+			entry.Action = AuditDelete
+		case bytes.Equal(old, updated):
+			continue
+		default:
+			entry.Action = AuditUpdate
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
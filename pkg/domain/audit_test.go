@@ -0,0 +1,37 @@
+package domain
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestNewAuditEntries(t *testing.T) {
+	created, updated, deleted, unchanged, missing := uuid.New(), uuid.New(), uuid.New(), uuid.New(), uuid.New()
+	before := map[uuid.UUID]json.RawMessage{
+		updated:   json.RawMessage(`{"price_minor": 39900}`),
+		deleted:   json.RawMessage(`{"price_minor": 100}`),
+		unchanged: json.RawMessage(`{"price_minor": 500}`),
+	}
+	after := map[uuid.UUID]json.RawMessage{
+		created:   json.RawMessage(`{"price_minor": 1}`),
+		updated:   json.RawMessage(`{"price_minor": 44900}`),
+		unchanged: json.RawMessage(`{"price_minor": 500}`),
+	}
+
+	// Повторный ID дает одну запись
+	entries := NewAuditEntries(AuditSubscriptions, []uuid.UUID{created, updated, deleted, unchanged, missing, updated}, before, after)
+	want := map[uuid.UUID]AuditAction{created: AuditCreate, updated: AuditUpdate, deleted: AuditDelete}
+	if len(entries) != len(want) {
+		t.Fatalf("got %d entries, want %d", len(entries), len(want))
+	}
+	for _, entry := range entries {
+		if entry.Entity != AuditSubscriptions || entry.Action != want[entry.EntityID] {
+			t.Errorf("entry %s: %s %s, want %s", entry.EntityID, entry.Entity, entry.Action, want[entry.EntityID])
+		}
+		if (entry.Before == nil) != (entry.Action == AuditCreate) || (entry.After == nil) != (entry.Action == AuditDelete) {
+			t.Errorf("entry %s: before %s, after %s", entry.Action, entry.Before, entry.After)
+		}
+	}
+}
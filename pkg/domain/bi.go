@@ -0,0 +1,29 @@
+package domain
+
+// DefaultTenantScope - tenant_id сводок основной базы, без тенанта.
+const DefaultTenantScope = "default"
+
+// DailyTotals - данные события bi.daily_totals: сводка тенанта за день для внешних
+// BI-систем. Сводки за один день и тенант имеют одинаковый ID события, поэтому
+// повторная доставка не дублирует строку в хранилище получателя.
+type DailyTotals struct {
+	TenantID string `json:"tenant_id" example:"acme"`
+	// Date - день сводки (UTC), YYYY-MM-DD
+	Date string `json:"date" example:"2025-07-14"`
+	// Subscriptions - число подписок всего и по статусам
+	Subscriptions int                        `json:"subscriptions" example:"120"`
+	ByStatus      map[SubscriptionStatus]int `json:"by_status"`
+	// Month - текущий месяц, MM-YYYY; MonthlySpend - стоимость подписок за него
+	// по валютам десятичной строкой, как в Money
+	Month        string              `json:"month" example:"07-2025"`
+	MonthlySpend map[Currency]string `json:"monthly_spend"`
+}
+
+// NewMonthlySpend переводит итоги в десятичные суммы по валютам.
+func NewMonthlySpend(totals Totals) map[Currency]string {
+	spend := make(map[Currency]string, len(totals))
+	for _, money := range totals.List() {
+		spend[money.Currency] = money.FormatAmount()
+	}
+	return spend
+}
@@ -0,0 +1,115 @@
+package domain
+
+import "time"
+
+// BillingCycle - период, за который списывается Price.
+type BillingCycle string
+
+const (
+	CycleWeekly  BillingCycle = "weekly"
+	CycleMonthly BillingCycle = "monthly"
+	CycleYearly  BillingCycle = "yearly"
+)
+
+// ProrationDenominator - знаменатель долей при пересчете цены на месяц. 84 делится
+// и на 12 (месяцев в году), и на 7 (дней в неделе), поэтому пересчет идет в целых
+// числах и одинаково считается в SQL и в Go.
+const ProrationDenominator = 84
+
+// OrDefault возвращает monthly для пустого значения: подписки до появления поля были ежемесячными.
+func (c BillingCycle) OrDefault() BillingCycle {
+	if c == "" {
+		return CycleMonthly
+	}
+	return c
+}
+
+// Valid сообщает, известен ли цикл.
+func (c BillingCycle) Valid() bool {
+	switch c {
+	case CycleWeekly, CycleMonthly, CycleYearly:
+		return true
+	}
+	return false
+}
+
+// ProratedMonthCharge возвращает стоимость месяца month в долях 1/ProrationDenominator
+// минорной единицы цены:
+// годовой план - 1/12 цены, недельный - цена за каждый день месяца по 1/7.
+func ProratedMonthCharge(price int64, cycle BillingCycle, month time.Time) int64 {
+	switch cycle.OrDefault() {
+	case CycleYearly:
+		return price * ProrationDenominator / 12
+	case CycleWeekly:
+		days := int64(month.AddDate(0, 1, -1).Day())
+		return price * days * ProrationDenominator / 7
+	default:
+		return price * ProrationDenominator
+	}
+}
+
+// MonthlyRate - средняя стоимость месяца в долях 1/ProrationDenominator, не зависящая
+// от длины конкретного месяца (52 недели в году). Нужна для сравнения цен разных планов.
+func MonthlyRate(price int64, cycle BillingCycle) int64 {
+	switch cycle.OrDefault() {
+	case CycleYearly:
+		return price * ProrationDenominator / 12
+	case CycleWeekly:
+		return price * 52 * ProrationDenominator / 12
+	default:
+		return price * ProrationDenominator
+	}
+}
+
+// RoundProrated переводит сумму долей в минорные единицы с округлением половины вверх.
+func RoundProrated(units int64) int64 {
+	return RoundProratedWith(units, RoundHalfUp)
+}
+
+// RoundProratedWith переводит сумму долей в минорные единицы по правилу округления тенанта.
+func RoundProratedWith(units int64, mode RoundingMode) int64 {
+	return RoundDiv(units, ProrationDenominator, mode)
+}
+
+// ChargeDates возвращает дни фактических списаний подписки в месяце month:
+// ежемесячная - одно в день BillingDay, годовая - только в месяцы годовщины
+// start_date, недельная - каждые 7 дней от первого списания.
+func ChargeDates(sub *Subscription, month time.Time) ([]time.Time, error) {
+	active, err := activeInMonth(sub, month)
+	if err != nil || !active {
+		return nil, err
+	}
+
+	start, err := ParsePeriod(sub.StartDate)
+	if err != nil {
+		return nil, err
+	}
+
+	switch sub.BillingCycle.OrDefault() {
+	case CycleYearly:
+		if (MonthsBetween(start, month)-1)%12 != 0 {
+			return nil, nil
+		}
+		return []time.Time{BillingDay(sub, month)}, nil
+	case CycleWeekly:
+		next := month.AddDate(0, 1, 0)
+		first := BillingDay(sub, start)
+		var dates []time.Time
+		// Первое списание месяца - ближайшее к его началу из ряда first + 7k
+		offset := int(month.Sub(first).Hours()/24) % 7
+		day := month
+		if month.After(first) {
+			if offset != 0 {
+				day = month.AddDate(0, 0, 7-offset)
+			}
+		} else {
+			day = first
+		}
+		for ; day.Before(next); day = day.AddDate(0, 0, 7) {
+			dates = append(dates, day)
+		}
+		return dates, nil
+	default:
+		return []time.Time{BillingDay(sub, month)}, nil
+	}
+}
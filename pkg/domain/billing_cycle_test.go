@@ -0,0 +1,108 @@
+package domain
+
+import (
+	"testing"
+	"time"
+)
+
+func TestProratedMonthCharge(t *testing.T) {
+	feb, _ := ParsePeriod("02-2025")
+	jan, _ := ParsePeriod("01-2025")
+
+	cases := []struct {
+		name  string
+		price int64
+		cycle BillingCycle
+		month time.Time
+		want  int64
+	}{
+		{name: "default is monthly", price: 400, month: feb, want: 400},
+		{name: "yearly", price: 1200, cycle: CycleYearly, month: feb, want: 100},
+		{name: "weekly in february", price: 70, cycle: CycleWeekly, month: feb, want: 280},
+		{name: "weekly in january", price: 70, cycle: CycleWeekly, month: jan, want: 310},
+	}
+	for _, tc := range cases {
+		if got := RoundProrated(ProratedMonthCharge(tc.price, tc.cycle, tc.month)); got != tc.want {
+			t.Errorf("%s: got %d, want %d", tc.name, got, tc.want)
+		}
+	}
+
+	// Доли складываются без потерь: полгода годового плана - ровно половина цены
+	var units int64
+	for month := jan; month.Month() <= time.June; month = month.AddDate(0, 1, 0) {
+		units += ProratedMonthCharge(999, CycleYearly, month)
+	}
+	if got := RoundProrated(units); got != 500 {
+		t.Errorf("half a year of 999/year = %d, want 500", got)
+	}
+}
+
+func TestChargeDates(t *testing.T) {
+	created := time.Date(2024, 1, 3, 12, 0, 0, 0, time.UTC)
+
+	cases := []struct {
+		name  string
+		sub   *Subscription
+		month string
+		want  []string
+	}{
+		{
+			name:  "monthly",
+			sub:   &Subscription{StartDate: "01-2024", CreatedAt: created},
+			month: "02-2025",
+			want:  []string{"2025-02-03"},
+		},
+		{
+			name:  "yearly anniversary",
+			sub:   &Subscription{StartDate: "03-2024", BillingCycle: CycleYearly, CreatedAt: created},
+			month: "03-2025",
+			want:  []string{"2025-03-03"},
+		},
+		{
+			name:  "yearly between anniversaries",
+			sub:   &Subscription{StartDate: "03-2024", BillingCycle: CycleYearly, CreatedAt: created},
+			month: "04-2025",
+		},
+		{
+			name:  "weekly first month",
+			sub:   &Subscription{StartDate: "01-2024", BillingCycle: CycleWeekly, CreatedAt: created},
+			month: "01-2024",
+			want:  []string{"2024-01-03", "2024-01-10", "2024-01-17", "2024-01-24", "2024-01-31"},
+		},
+		{
+			name:  "weekly continues across months",
+			sub:   &Subscription{StartDate: "01-2024", BillingCycle: CycleWeekly, CreatedAt: created},
+			month: "02-2024",
+			want:  []string{"2024-02-07", "2024-02-14", "2024-02-21", "2024-02-28"},
+		},
+		{
+			name:  "not started",
+			sub:   &Subscription{StartDate: "05-2025", CreatedAt: created},
+			month: "04-2025",
+		},
+	}
+	for _, tc := range cases {
+		month, err := ParsePeriod(tc.month)
+		if err != nil {
+			t.Fatal(err)
+		}
+		dates, err := ChargeDates(tc.sub, month)
+		if err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		got := make([]string, len(dates))
+		for i, d := range dates {
+			got[i] = d.Format(CalendarDateLayout)
+		}
+		if len(got) != len(tc.want) {
+			t.Errorf("%s: got %v, want %v", tc.name, got, tc.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != tc.want[i] {
+				t.Errorf("%s: got %v, want %v", tc.name, got, tc.want)
+				break
+			}
+		}
+	}
+}
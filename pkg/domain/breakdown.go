@@ -0,0 +1,91 @@
+package domain
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// BreakdownStreamContentType - формат потоковой разбивки: по строке JSON на месяц
+// и завершающая строка BreakdownStreamEnd.
+const BreakdownStreamContentType = "application/x-ndjson"
+
+var ErrInvalidContinuation = errors.New("invalid continuation token")
+
+// CalculateBreakdownRequest - помесячная разбивка стоимости. Фильтры те же, что у
+// расчета стоимости, и при продолжении по токену должны совпадать с первым запросом.
+type CalculateBreakdownRequest struct {
+	CalculateTotalRequest
+	// Limit - сколько месяцев вернуть за запрос
+	Limit int `form:"limit,default=12" binding:"min=1,max=120"`
+	// Continuation - токен из next_continuation предыдущего ответа
+	Continuation string `form:"continuation"`
+}
+
+// MonthBreakdown - стоимость одного месяца; суммы округляются помесячно,
+// поэтому сумма месяцев может отличаться от итога расчета на копейки.
+type MonthBreakdown struct {
+	Month            string                 `json:"month" example:"07-2025"`
+	TotalCost        Money                  `json:"total_cost"`
+	ByClassification map[BillingClass]Money `json:"by_classification"`
+	// Items - оплаченные месяцы подписок, из которых сложилась сумма месяца (include_items=true)
+	Items []TotalItem `json:"items,omitempty"`
+}
+
+type CalculateBreakdownResponse struct {
+	Months []MonthBreakdown `json:"months"`
+	// NextContinuation - токен следующей страницы; пуст, если месяцы периода закончились
+	NextContinuation string `json:"next_continuation,omitempty" example:"MDgtMjAyNS5hMWIyYzNkNGU1ZjY3ODkw"`
+}
+
+// BreakdownStreamEnd - последняя строка потоковой разбивки. Если поток оборвался
+// с ошибкой, NextContinuation продолжает его с первого неотправленного месяца.
+type BreakdownStreamEnd struct {
+	NextContinuation string `json:"next_continuation,omitempty"`
+	Error            string `json:"error,omitempty"`
+}
+
+// Fingerprint - отпечаток фильтров расчета; токен продолжения действует
+// только с теми же фильтрами и периодом.
+func (r CalculateTotalRequest) Fingerprint() string {
+	parts := []string{r.StartPeriod, r.EndPeriod, string(r.Currency), string(r.TargetCurrency),
+		strconv.FormatBool(r.ExcludeInactive), strings.Join(r.Tags, ",")}
+	if r.UserID != nil {
+		parts = append(parts, r.UserID.String())
+	} else {
+		parts = append(parts, "")
+	}
+	if r.ServiceName != nil {
+		parts = append(parts, *r.ServiceName)
+	}
+
+	sum := sha256.Sum256([]byte(strings.Join(parts, "\x00")))
+	return hex.EncodeToString(sum[:8])
+}
+
+// EncodeContinuation - токен продолжения разбивки с месяца month.
+func EncodeContinuation(month time.Time, fingerprint string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(FormatPeriod(month) + "." + fingerprint))
+}
+
+// DecodeContinuation возвращает месяц, с которого продолжается разбивка,
+// проверяя, что токен выдан для запроса с тем же отпечатком.
+func DecodeContinuation(token, fingerprint string) (time.Time, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return time.Time{}, ErrInvalidContinuation
+	}
+	month, tokenFingerprint, ok := strings.Cut(string(raw), ".")
+	if !ok || tokenFingerprint != fingerprint {
+		return time.Time{}, ErrInvalidContinuation
+	}
+	from, err := ParsePeriod(month)
+	if err != nil {
+		return time.Time{}, ErrInvalidContinuation
+	}
+	return from, nil
+}
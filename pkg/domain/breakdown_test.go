@@ -0,0 +1,40 @@
+package domain
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestContinuation(t *testing.T) {
+	userID := uuid.MustParse("60601fee-2bf1-4721-ae6f-7636e79a0cba")
+	req := CalculateTotalRequest{UserID: &userID, StartPeriod: "01-2015", EndPeriod: "12-2024", Currency: DefaultCurrency}
+	fingerprint := req.Fingerprint()
+
+	month, _ := ParsePeriod("03-2016")
+	token := EncodeContinuation(month, fingerprint)
+	got, err := DecodeContinuation(token, fingerprint)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !got.Equal(month) {
+		t.Errorf("DecodeContinuation = %s, want 03-2016", FormatPeriod(got))
+	}
+
+	// Токен нельзя применить к запросу с другими фильтрами
+	other := req
+	other.Tags = []string{"work"}
+	if other.Fingerprint() == fingerprint {
+		t.Fatal("fingerprint does not depend on tags")
+	}
+	if _, err := DecodeContinuation(token, other.Fingerprint()); !errors.Is(err, ErrInvalidContinuation) {
+		t.Errorf("token with other filters: %v, want ErrInvalidContinuation", err)
+	}
+
+	for _, token := range []string{"", "not base64!", EncodeContinuation(month, "")[:4]} {
+		if _, err := DecodeContinuation(token, fingerprint); !errors.Is(err, ErrInvalidContinuation) {
+			t.Errorf("DecodeContinuation(%q) = %v, want ErrInvalidContinuation", token, err)
+		}
+	}
+}
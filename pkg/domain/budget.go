@@ -0,0 +1,145 @@
+package domain
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// DefaultBudgetAlertPercent - порог предупреждения, если он не задан при создании конверта.
+const DefaultBudgetAlertPercent = 80
+
+// BudgetState - состояние конверта за месяц: в пределах лимита, порог предупреждения
+// достигнут или лимит превышен.
+type BudgetState string
+
+const (
+	BudgetOK       BudgetState = "ok"
+	BudgetWarning  BudgetState = "warning"
+	BudgetExceeded BudgetState = "exceeded"
+)
+
+var budgetStateRank = map[BudgetState]int{BudgetOK: 0, BudgetWarning: 1, BudgetExceeded: 2}
+
+// Budget - конверт бюджета: месячный лимит трат пользователя на категорию подписок.
+// В категорию входят подписки пользователя с меткой Category.
+type Budget struct {
+	ID           uuid.UUID `json:"id" example:"7c9e6679-7425-40de-944b-e07fc1f90ae7"`
+	UserID       uuid.UUID `json:"user_id" example:"60601fee-2bf1-4721-ae6f-7636e79a0cba"`
+	Category     string    `json:"category" example:"streaming"`
+	Limit        Money     `json:"limit"`
+	AlertPercent int       `json:"alert_percent" example:"80"`
+	CreatedAt    time.Time `json:"created_at" example:"2025-10-23T15:04:05Z"`
+	UpdatedAt    time.Time `json:"updated_at" example:"2025-10-23T15:04:05Z"`
+}
+
+type CreateBudgetRequest struct {
+	UserID uuid.UUID `json:"user_id" binding:"required" example:"60601fee-2bf1-4721-ae6f-7636e79a0cba"`
+	// Category - метка подписок, правила как у tags
+	Category string `json:"category" binding:"required" example:"streaming"`
+	Limit    Money  `json:"limit"`
+	// AlertPercent - доля лимита, после которой конверт переходит в warning (по умолчанию 80)
+	AlertPercent *int `json:"alert_percent,omitempty" binding:"omitempty,min=1,max=100" example:"80"`
+}
+
+// UpdateBudgetRequest - частичное обновление (PATCH): не переданные поля не меняются.
+type UpdateBudgetRequest struct {
+	Category     *string `json:"category,omitempty" example:"cloud"`
+	Limit        *Money  `json:"limit,omitempty"`
+	AlertPercent *int    `json:"alert_percent,omitempty" binding:"omitempty,min=1,max=100" example:"90"`
+}
+
+type BudgetStatusQuery struct {
+	// Month по умолчанию текущий
+	Month string `form:"month" example:"07-2025"`
+}
+
+// BudgetUsage - траты за месяц относительно лимита.
+type BudgetUsage struct {
+	Limit Money `json:"limit"`
+	Spent Money `json:"spent"`
+	// Balance - остаток лимита, отрицательный при перерасходе
+	Balance            Money       `json:"balance"`
+	UtilizationPercent float64     `json:"utilization_percent" example:"85.5"`
+	State              BudgetState `json:"state" example:"warning"`
+}
+
+// NewBudgetUsage сравнивает траты spent с лимитом limit в той же валюте. Процент
+// округляется до 0.1; warning начинается с alertPercent, exceeded - выше лимита.
+func NewBudgetUsage(limit, spent Money, alertPercent int) BudgetUsage {
+	// Остаток наследует формат вывода лимита
+	balance := limit
+	balance.Amount -= spent.Amount
+	usage := BudgetUsage{Limit: limit, Spent: spent, Balance: balance, State: BudgetOK}
+	if limit.Amount > 0 {
+		usage.UtilizationPercent = math.Round(float64(spent.Amount)*1000/float64(limit.Amount)) / 10
+	}
+	switch {
+	case spent.Amount > limit.Amount:
+		usage.State = BudgetExceeded
+	case spent.Amount*100 >= limit.Amount*int64(alertPercent):
+		usage.State = BudgetWarning
+	}
+	return usage
+}
+
+type BudgetEnvelope struct {
+	Budget *Budget `json:"budget"`
+	BudgetUsage
+}
+
+// BudgetStatusResponse - сводка по всем конвертам пользователя за месяц.
+type BudgetStatusResponse struct {
+	UserID    uuid.UUID        `json:"user_id" example:"60601fee-2bf1-4721-ae6f-7636e79a0cba"`
+	Month     string           `json:"month" example:"07-2025"`
+	Envelopes []BudgetEnvelope `json:"envelopes"`
+	// Totals - суммы конвертов по валютам; state - худшее состояние конверта в валюте
+	Totals []BudgetUsage `json:"totals"`
+}
+
+// SummarizeBudgets складывает лимиты и траты конвертов по валютам. Подписка с
+// метками нескольких категорий учитывается в каждом своем конверте.
+func SummarizeBudgets(envelopes []BudgetEnvelope) []BudgetUsage {
+	byCurrency := make(map[Currency]*BudgetUsage)
+	for _, envelope := range envelopes {
+		currency := envelope.Limit.Currency
+		total, ok := byCurrency[currency]
+		if !ok {
+			zero := envelope.Limit
+			zero.Amount = 0
+			total = &BudgetUsage{Limit: zero, Spent: zero, State: BudgetOK}
+			byCurrency[currency] = total
+		}
+		total.Limit.Amount += envelope.Limit.Amount
+		total.Spent.Amount += envelope.Spent.Amount
+		if budgetStateRank[envelope.State] > budgetStateRank[total.State] {
+			total.State = envelope.State
+		}
+	}
+
+	totals := make([]BudgetUsage, 0, len(byCurrency))
+	for _, total := range byCurrency {
+		usage := NewBudgetUsage(total.Limit, total.Spent, 100)
+		usage.State = total.State
+		totals = append(totals, usage)
+	}
+	sort.Slice(totals, func(i, j int) bool {
+		return totals[i].Limit.Currency < totals[j].Limit.Currency
+	})
+	return totals
+}
+
+// BudgetAlert - данные событий budget.warning и budget.exceeded.
+type BudgetAlert struct {
+	BudgetID           uuid.UUID   `json:"budget_id"`
+	UserID             uuid.UUID   `json:"user_id"`
+	Category           string      `json:"category" example:"streaming"`
+	Month              string      `json:"month" example:"07-2025"`
+	Limit              Money       `json:"limit"`
+	Spent              Money       `json:"spent"`
+	UtilizationPercent float64     `json:"utilization_percent" example:"85.5"`
+	AlertPercent       int         `json:"alert_percent" example:"80"`
+	State              BudgetState `json:"state" example:"warning"`
+}
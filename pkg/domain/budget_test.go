@@ -0,0 +1,54 @@
+package domain
+
+import "testing"
+
+func TestNewBudgetUsage(t *testing.T) {
+	limit := NewMoney(100000, DefaultCurrency)
+	cases := []struct {
+		spent   int64
+		percent float64
+		state   BudgetState
+	}{
+		{0, 0, BudgetOK},
+		{79999, 80, BudgetOK},
+		{80000, 80, BudgetWarning},
+		{100000, 100, BudgetWarning},
+		{100001, 100, BudgetExceeded},
+		{125050, 125.1, BudgetExceeded},
+	}
+	for _, tc := range cases {
+		usage := NewBudgetUsage(limit, NewMoney(tc.spent, DefaultCurrency), DefaultBudgetAlertPercent)
+		if usage.State != tc.state || usage.UtilizationPercent != tc.percent {
+			t.Errorf("spent %d: state %s, %.1f%%; want %s, %.1f%%", tc.spent, usage.State, usage.UtilizationPercent, tc.state, tc.percent)
+		}
+		if usage.Balance.Amount != limit.Amount-tc.spent {
+			t.Errorf("spent %d: balance %d", tc.spent, usage.Balance.Amount)
+		}
+	}
+}
+
+func TestSummarizeBudgets(t *testing.T) {
+	envelope := func(limit, spent int64, currency Currency) BudgetEnvelope {
+		return BudgetEnvelope{BudgetUsage: NewBudgetUsage(NewMoney(limit, currency), NewMoney(spent, currency), DefaultBudgetAlertPercent)}
+	}
+	totals := SummarizeBudgets([]BudgetEnvelope{
+		envelope(100000, 20000, "RUB"),
+		envelope(50000, 60000, "RUB"),
+		envelope(1000, 900, "USD"),
+	})
+	if len(totals) != 2 {
+		t.Fatalf("got %d totals, want 2", len(totals))
+	}
+
+	rub, usd := totals[0], totals[1]
+	if rub.Limit.Amount != 150000 || rub.Spent.Amount != 80000 || rub.Balance.Amount != 70000 || rub.UtilizationPercent != 53.3 {
+		t.Errorf("RUB total = %+v", rub)
+	}
+	// Итог в пределах лимита, но один из конвертов превышен
+	if rub.State != BudgetExceeded {
+		t.Errorf("RUB state = %s, want exceeded", rub.State)
+	}
+	if usd.Limit.Currency != "USD" || usd.State != BudgetWarning {
+		t.Errorf("USD total = %+v", usd)
+	}
+}
@@ -0,0 +1,105 @@
+package domain
+
+import (
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// CalendarDateLayout - формат дня в календаре списаний.
+const CalendarDateLayout = "2006-01-02"
+
+type CalendarCharge struct {
+	SubscriptionID uuid.UUID `json:"subscription_id"`
+	ServiceName    string    `json:"service_name" example:"Yandex Plus"`
+	Amount         Money     `json:"amount"`
+}
+
+type CalendarDay struct {
+	Date string `json:"date" example:"2025-07-15"`
+	// Total - суммы списаний дня по валютам
+	Total   Totals           `json:"total" swaggertype:"array,object"`
+	Charges []CalendarCharge `json:"charges"`
+}
+
+type BillingCalendarResponse struct {
+	UserID uuid.UUID     `json:"user_id"`
+	Month  string        `json:"month" example:"07-2025"`
+	Total  Totals        `json:"total" swaggertype:"array,object"`
+	Days   []CalendarDay `json:"days"`
+}
+
+// BillingDay возвращает день списания подписки в месяце month (первое число месяца, UTC).
+// Якорем служит день created_at; если в месяце столько дней нет (31 число в апреле,
+// 29-31 в феврале), списание переносится на последний день месяца.
+func BillingDay(sub *Subscription, month time.Time) time.Time {
+	anchor := sub.CreatedAt.UTC().Day()
+	lastDay := month.AddDate(0, 1, -1).Day()
+	if anchor > lastDay {
+		anchor = lastDay
+	}
+	return time.Date(month.Year(), month.Month(), anchor, 0, 0, 0, 0, time.UTC)
+}
+
+// BuildBillingCalendar раскладывает списания подписок по дням месяца month (см. ChargeDates).
+// В ответ попадают все дни месяца, в том числе без списаний.
+func BuildBillingCalendar(userID uuid.UUID, subs []*Subscription, month time.Time) (*BillingCalendarResponse, error) {
+	lastDay := month.AddDate(0, 1, -1).Day()
+	days := make([]CalendarDay, lastDay)
+	for i := range days {
+		days[i] = CalendarDay{
+			Date:    month.AddDate(0, 0, i).Format(CalendarDateLayout),
+			Total:   make(Totals),
+			Charges: make([]CalendarCharge, 0),
+		}
+	}
+
+	calendar := &BillingCalendarResponse{UserID: userID, Month: FormatPeriod(month), Total: make(Totals)}
+	for _, sub := range subs {
+		dates, err := ChargeDates(sub, month)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, date := range dates {
+			day := &days[date.Day()-1]
+			day.Charges = append(day.Charges, CalendarCharge{
+				SubscriptionID: sub.ID,
+				ServiceName:    sub.ServiceName,
+				Amount:         sub.Price,
+			})
+			day.Total.Add(sub.Price)
+			calendar.Total.Add(sub.Price)
+		}
+	}
+
+	for i := range days {
+		sort.Slice(days[i].Charges, func(a, b int) bool {
+			return days[i].Charges[a].ServiceName < days[i].Charges[b].ServiceName
+		})
+	}
+	calendar.Days = days
+
+	return calendar, nil
+}
+
+func activeInMonth(sub *Subscription, month time.Time) (bool, error) {
+	start, err := ParsePeriod(sub.StartDate)
+	if err != nil {
+		return false, err
+	}
+	if start.After(month) {
+		return false, nil
+	}
+	if sub.EndDate != nil {
+		end, err := ParsePeriod(*sub.EndDate)
+		if err != nil {
+			return false, err
+		}
+		if end.Before(month) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
@@ -0,0 +1,26 @@
+package domain
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBillingDayClampsToMonthEnd(t *testing.T) {
+	sub := &Subscription{CreatedAt: time.Date(2025, 1, 31, 18, 30, 0, 0, time.UTC)}
+
+	cases := map[string]string{
+		"01-2025": "2025-01-31",
+		"02-2025": "2025-02-28",
+		"02-2024": "2024-02-29",
+		"04-2025": "2025-04-30",
+	}
+	for month, want := range cases {
+		m, err := ParsePeriod(month)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := BillingDay(sub, m).Format(CalendarDateLayout); got != want {
+			t.Errorf("BillingDay(%s) = %s, want %s", month, got, want)
+		}
+	}
+}
@@ -0,0 +1,50 @@
+package domain
+
+// ChangeKind - вид изменения API в журнале изменений.
+type ChangeKind string
+
+const (
+	ChangeAdded      ChangeKind = "added"
+	ChangeChanged    ChangeKind = "changed"
+	ChangeDeprecated ChangeKind = "deprecated"
+	ChangeRemoved    ChangeKind = "removed"
+	ChangeFixed      ChangeKind = "fixed"
+)
+
+// APIChange - одно изменение API. Endpoints - затронутые ручки в виде
+// "GET /api/v1/subscriptions/{id}". У устаревших (deprecated) ручек Sunset -
+// день удаления; Param - устаревший параметр запроса, если устарела не вся ручка.
+type APIChange struct {
+	Kind        ChangeKind `json:"kind" example:"deprecated"`
+	Description string     `json:"description" example:"Параметр userId заменен на user_id"`
+	Endpoints   []string   `json:"endpoints" example:"GET /api/v1/subscriptions"`
+	Breaking    bool       `json:"breaking" example:"false"`
+	Param       string     `json:"param,omitempty" example:"userId"`
+	Sunset      string     `json:"sunset,omitempty" example:"2027-03-01"`
+}
+
+// ChangelogEntry - версия API и ее изменения. Breaking = true, если среди
+// изменений есть несовместимые.
+type ChangelogEntry struct {
+	Version  string      `json:"version" example:"1.4.0"`
+	Date     string      `json:"date" example:"2025-07-14"`
+	Summary  string      `json:"summary" example:"Бюджеты по категориям"`
+	Breaking bool        `json:"breaking" example:"false"`
+	Changes  []APIChange `json:"changes"`
+}
+
+// Changelog - журнал изменений API, новые версии сверху.
+type Changelog struct {
+	CurrentVersion string           `json:"current_version" example:"1.14.0"`
+	Entries        []ChangelogEntry `json:"entries"`
+}
+
+// ChangelogQuery - фильтры журнала изменений API.
+type ChangelogQuery struct {
+	// Since - последняя известная клиенту версия: в ответе только более новые
+	Since string `form:"since" example:"1.10.0"`
+	// Breaking оставляет только версии с несовместимыми изменениями
+	Breaking bool `form:"breaking"`
+	// Endpoint оставляет изменения одной ручки, например "GET /api/v1/subscriptions"
+	Endpoint string `form:"endpoint" example:"GET /api/v1/subscriptions"`
+}
@@ -0,0 +1,73 @@
+package domain
+
+import (
+	"encoding/base64"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// SubscriptionChange - изменение подписки из журнала изменений. Version - версия
+// после изменения, у удаления ее нет.
+type SubscriptionChange struct {
+	SubscriptionID uuid.UUID   `json:"subscription_id" example:"123e4567-e89b-12d3-a456-426614174000"`
+	UserID         uuid.UUID   `json:"user_id" example:"60601fee-2bf1-4721-ae6f-7636e79a0cba"`
+	Action         AuditAction `json:"action" example:"update"`
+	Version        *int64      `json:"version,omitempty" example:"4"`
+	ChangedAt      time.Time   `json:"changed_at" example:"2025-10-23T15:04:05Z"`
+}
+
+// SubscriptionChanges - страница ленты изменений. Cursor передается в since
+// следующего запроса; он меняется, даже если изменений нет.
+type SubscriptionChanges struct {
+	Changes []SubscriptionChange `json:"changes"`
+	Cursor  string               `json:"cursor" example:"ODg0MTIuMDAwMDAwMDAtMDAwMC0wMDAwLTAwMDAtMDAwMDAwMDAwMDAw"`
+	// HasMore - страница заполнена целиком, следующую стоит запросить сразу
+	HasMore bool `json:"has_more" example:"false"`
+}
+
+// SubscriptionChangesQuery - запрос ленты изменений. Since - курсор предыдущего
+// ответа; без него лента начинается с текущего момента. Wait - сколько ждать
+// изменений, если их пока нет, например 30s.
+type SubscriptionChangesQuery struct {
+	UserID *uuid.UUID `form:"-"`
+	Since  string     `form:"since"`
+	Wait   string     `form:"wait" example:"30s"`
+	Limit  int        `form:"limit,default=100" binding:"min=1,max=500"`
+
+	// After и WaitFor заполняет сервис из Since и Wait
+	After   *ChangeCursor `form:"-" swaggerignore:"true"`
+	WaitFor time.Duration `form:"-" swaggerignore:"true"`
+}
+
+// ChangeCursor - позиция в журнале изменений: транзакция и запись в ней.
+// Лента отдает записи строго после курсора.
+type ChangeCursor struct {
+	TxID    uint64
+	EntryID uuid.UUID
+}
+
+func (c ChangeCursor) Encode() string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.FormatUint(c.TxID, 10) + "." + c.EntryID.String()))
+}
+
+func DecodeChangeCursor(token string) (ChangeCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return ChangeCursor{}, ErrInvalidContinuation
+	}
+	txID, entryID, ok := strings.Cut(string(raw), ".")
+	if !ok {
+		return ChangeCursor{}, ErrInvalidContinuation
+	}
+	var cursor ChangeCursor
+	if cursor.TxID, err = strconv.ParseUint(txID, 10, 64); err != nil {
+		return ChangeCursor{}, ErrInvalidContinuation
+	}
+	if cursor.EntryID, err = uuid.Parse(entryID); err != nil {
+		return ChangeCursor{}, ErrInvalidContinuation
+	}
+	return cursor, nil
+}
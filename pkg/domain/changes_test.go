@@ -0,0 +1,32 @@
+package domain
+
+import (
+	"encoding/base64"
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestChangeCursor(t *testing.T) {
+	cursor := ChangeCursor{TxID: 88412, EntryID: uuid.New()}
+	decoded, err := DecodeChangeCursor(cursor.Encode())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if decoded != cursor {
+		t.Errorf("decoded = %+v, want %+v", decoded, cursor)
+	}
+
+	encode := func(s string) string { return base64.RawURLEncoding.EncodeToString([]byte(s)) }
+	for _, token := range []string{
+		"not base64!",
+		encode("88412"),
+		encode("-1." + uuid.NewString()),
+		encode("88412.not-a-uuid"),
+	} {
+		if _, err := DecodeChangeCursor(token); !errors.Is(err, ErrInvalidContinuation) {
+			t.Errorf("DecodeChangeCursor(%q) error = %v", token, err)
+		}
+	}
+}
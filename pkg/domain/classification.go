@@ -0,0 +1,239 @@
+package domain
+
+import (
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// BillingClass - классификация оплаченного месяца по истории подписок пользователя на сервис.
+type BillingClass string
+
+const (
+	// BillingNew - первый месяц первой подписки на сервис или возврат после перерыва.
+	BillingNew BillingClass = "new"
+	// BillingRenewal - продление без смены цены, в том числе каждый следующий месяц подписки.
+	BillingRenewal BillingClass = "renewal"
+	// BillingUpgraded и BillingDowngraded - первый месяц подписки, сменившей предыдущую
+	// без перерыва, с более высокой или более низкой ценой.
+	BillingUpgraded   BillingClass = "upgraded"
+	BillingDowngraded BillingClass = "downgraded"
+)
+
+var BillingClasses = []BillingClass{BillingNew, BillingRenewal, BillingUpgraded, BillingDowngraded}
+
+type BilledMonth struct {
+	SubscriptionID uuid.UUID `json:"subscription_id"`
+	UserID         uuid.UUID `json:"user_id"`
+	ServiceName    string    `json:"service_name"`
+	Month          string    `json:"month" example:"07-2025"`
+	// Price - цена в целых единицах валюты, оставлена для совместимости; точная цена - PriceAmount
+	Price       int          `json:"price" example:"400"`
+	PriceAmount Money        `json:"price_amount"`
+	Class       BillingClass `json:"class" example:"new"`
+	// Charge - стоимость месяца с учетом BillingCycle в долях 1/ProrationDenominator
+	// минорной единицы валюты PriceAmount
+	Charge int64 `json:"-"`
+}
+
+// MaxTotalItems ограничивает число позиций include_items в одном ответе.
+const MaxTotalItems = 5000
+
+// TotalItem - оплаченный месяц подписки, вошедший в сумму расчета (include_items).
+// Cost - стоимость месяца за вычетом скидок в валюте подписки, округленная отдельно:
+// сумма позиций может отличаться от итога на доли копейки каждой и не пересчитывается
+// в target_currency.
+type TotalItem struct {
+	SubscriptionID uuid.UUID    `json:"subscription_id"`
+	UserID         uuid.UUID    `json:"user_id"`
+	ServiceName    string       `json:"service_name" example:"Netflix"`
+	Month          string       `json:"month" example:"07-2025"`
+	Class          BillingClass `json:"class" example:"renewal"`
+	Price          Money        `json:"price"`
+	Cost           Money        `json:"cost"`
+}
+
+// TotalItems раскладывает оплаченные месяцы в позиции по месяцу, пользователю и
+// сервису; суммы округляются и выводятся по format.
+func TotalItems(months []BilledMonth, format MoneyFormat) []TotalItem {
+	items := make([]TotalItem, len(months))
+	for i, m := range months {
+		cost := NewMoney(RoundProratedWith(m.Charge, format.Rounding), m.PriceAmount.Currency)
+		items[i] = TotalItem{
+			SubscriptionID: m.SubscriptionID,
+			UserID:         m.UserID,
+			ServiceName:    m.ServiceName,
+			Month:          m.Month,
+			Class:          m.Class,
+			Price:          format.Apply(m.PriceAmount),
+			Cost:           format.Apply(cost),
+		}
+	}
+	sort.SliceStable(items, func(i, j int) bool {
+		a, b := items[i], items[j]
+		switch {
+		case a.Month != b.Month:
+			return periodLess(a.Month, b.Month)
+		case a.UserID != b.UserID:
+			return a.UserID.String() < b.UserID.String()
+		case a.ServiceName != b.ServiceName:
+			return a.ServiceName < b.ServiceName
+		}
+		return a.SubscriptionID.String() < b.SubscriptionID.String()
+	})
+	return items
+}
+
+// ClassifyBilledMonths раскладывает подписки на оплаченные месяцы периода [from, to]
+// и классифицирует каждый месяц. history должна содержать и подписки до from,
+// иначе первая подписка в периоде будет ошибочно считаться новой.
+// Подписки группируются по пользователю и ключу сервиса (ServiceKey),
+// aliases сводит ключи алиасов к каноническим и может быть nil.
+func ClassifyBilledMonths(history []*Subscription, from, to time.Time, aliases map[string]string) ([]BilledMonth, error) {
+	type group struct {
+		userID uuid.UUID
+		key    string
+	}
+
+	groups := make(map[group][]*Subscription)
+	order := make([]group, 0)
+	for _, sub := range history {
+		key := ServiceKey(sub.ServiceName)
+		if canonical, ok := aliases[key]; ok {
+			key = canonical
+		}
+		g := group{userID: sub.UserID, key: key}
+		if _, ok := groups[g]; !ok {
+			order = append(order, g)
+		}
+		groups[g] = append(groups[g], sub)
+	}
+
+	months := make([]BilledMonth, 0)
+	for _, g := range order {
+		subs := groups[g]
+		sort.SliceStable(subs, func(i, j int) bool {
+			if subs[i].StartDate != subs[j].StartDate {
+				return periodLess(subs[i].StartDate, subs[j].StartDate)
+			}
+			return subs[i].CreatedAt.Before(subs[j].CreatedAt)
+		})
+
+		var prev *Subscription
+		for _, sub := range subs {
+			start, err := ParsePeriod(sub.StartDate)
+			if err != nil {
+				return nil, err
+			}
+			end := to
+			if sub.EndDate != nil {
+				subEnd, err := ParsePeriod(*sub.EndDate)
+				if err != nil {
+					return nil, err
+				}
+				if subEnd.Before(end) {
+					end = subEnd
+				}
+			}
+
+			first, err := classifyStart(prev, sub, start)
+			if err != nil {
+				return nil, err
+			}
+
+			for month := start; !month.After(end); month = month.AddDate(0, 1, 0) {
+				if month.Before(from) {
+					continue
+				}
+				class := BillingRenewal
+				if month.Equal(start) {
+					class = first
+				}
+				months = append(months, BilledMonth{
+					SubscriptionID: sub.ID,
+					UserID:         sub.UserID,
+					ServiceName:    sub.ServiceName,
+					Month:          FormatPeriod(month),
+					Price:          sub.Price.Major(),
+					PriceAmount:    sub.Price,
+					Class:          class,
+					Charge:         ProratedMonthCharge(sub.Price.Amount, sub.BillingCycle, month),
+				})
+			}
+			prev = sub
+		}
+	}
+
+	return months, nil
+}
+
+// ClassifyStart возвращает класс первого месяца подписки sub с учетом истории.
+// history может как содержать sub, так и нет.
+func ClassifyStart(history []*Subscription, sub *Subscription, aliases map[string]string) (BillingClass, error) {
+	start, err := ParsePeriod(sub.StartDate)
+	if err != nil {
+		return "", err
+	}
+
+	subs := make([]*Subscription, 0, len(history)+1)
+	for _, h := range history {
+		if h.ID != sub.ID {
+			subs = append(subs, h)
+		}
+	}
+	subs = append(subs, sub)
+
+	months, err := ClassifyBilledMonths(subs, start, start, aliases)
+	if err != nil {
+		return "", err
+	}
+	for _, m := range months {
+		if m.SubscriptionID == sub.ID {
+			return m.Class, nil
+		}
+	}
+	return BillingNew, nil
+}
+
+func classifyStart(prev, sub *Subscription, start time.Time) (BillingClass, error) {
+	if prev == nil {
+		return BillingNew, nil
+	}
+
+	// Перерыв хотя бы в месяц означает возврат пользователя, а не продление
+	if prev.EndDate != nil {
+		prevEnd, err := ParsePeriod(*prev.EndDate)
+		if err != nil {
+			return "", err
+		}
+		if start.After(prevEnd.AddDate(0, 1, 0)) {
+			return BillingNew, nil
+		}
+	}
+
+	// Цены в разных валютах несравнимы, смена валюты считается продлением
+	if sub.Price.Currency != prev.Price.Currency {
+		return BillingRenewal, nil
+	}
+
+	// Планы с разными циклами сравниваются по средней стоимости месяца
+	rate, prevRate := MonthlyRate(sub.Price.Amount, sub.BillingCycle), MonthlyRate(prev.Price.Amount, prev.BillingCycle)
+	switch {
+	case rate > prevRate:
+		return BillingUpgraded, nil
+	case rate < prevRate:
+		return BillingDowngraded, nil
+	default:
+		return BillingRenewal, nil
+	}
+}
+
+func periodLess(a, b string) bool {
+	ta, errA := ParsePeriod(a)
+	tb, errB := ParsePeriod(b)
+	if errA != nil || errB != nil {
+		return a < b
+	}
+	return ta.Before(tb)
+}
@@ -0,0 +1,71 @@
+package domain
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestClassifyBilledMonths(t *testing.T) {
+	user := uuid.New()
+	period := func(v string) *string { return &v }
+
+	trial := &Subscription{ID: uuid.New(), UserID: user, ServiceName: "Yandex Plus", Price: rub(200), StartDate: "01-2025", EndDate: period("02-2025")}
+	upgrade := &Subscription{ID: uuid.New(), UserID: user, ServiceName: "Яндекс Плюс", Price: rub(400), StartDate: "03-2025", EndDate: period("04-2025")}
+	downgrade := &Subscription{ID: uuid.New(), UserID: user, ServiceName: "yandex plus", Price: rub(300), StartDate: "05-2025", EndDate: period("05-2025")}
+	comeback := &Subscription{ID: uuid.New(), UserID: user, ServiceName: "Yandex Plus", Price: rub(300), StartDate: "08-2025"}
+	aliases := map[string]string{"yandeksplyus": "yandexplus"}
+
+	from, _ := ParsePeriod("02-2025")
+	to, _ := ParsePeriod("09-2025")
+	months, err := ClassifyBilledMonths([]*Subscription{comeback, downgrade, upgrade, trial}, from, to, aliases)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []struct {
+		month string
+		class BillingClass
+	}{
+		{"02-2025", BillingRenewal},
+		{"03-2025", BillingUpgraded},
+		{"04-2025", BillingRenewal},
+		{"05-2025", BillingDowngraded},
+		{"08-2025", BillingNew},
+		{"09-2025", BillingRenewal},
+	}
+	if len(months) != len(want) {
+		t.Fatalf("got %d months, want %d: %+v", len(months), len(want), months)
+	}
+	for i, w := range want {
+		if months[i].Month != w.month || months[i].Class != w.class {
+			t.Errorf("month %d = %s/%s, want %s/%s", i, months[i].Month, months[i].Class, w.month, w.class)
+		}
+	}
+
+	class, err := ClassifyStart([]*Subscription{trial}, upgrade, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if class != BillingNew {
+		t.Errorf("without aliases different spellings must not share history, got %s", class)
+	}
+}
+
+func TestTotalItems(t *testing.T) {
+	user := uuid.New()
+	half := int64(ProrationDenominator / 2)
+	months := []BilledMonth{
+		{SubscriptionID: uuid.New(), UserID: user, ServiceName: "Spotify", Month: "01-2025", PriceAmount: rub(300), Class: BillingRenewal, Charge: 30000 * ProrationDenominator},
+		// Месяцы сортируются по дате, а не по строке MM-YYYY
+		{SubscriptionID: uuid.New(), UserID: user, ServiceName: "Netflix", Month: "12-2024", PriceAmount: rub(599), Class: BillingNew, Charge: 59900*ProrationDenominator + half},
+	}
+
+	items := TotalItems(months, DefaultMoneyFormat)
+	if len(items) != 2 || items[0].Month != "12-2024" || items[1].Month != "01-2025" {
+		t.Fatalf("unexpected items order %+v", items)
+	}
+	if items[0].Cost.Amount != 59901 || items[0].Price.Amount != 59900 || items[0].SubscriptionID != months[1].SubscriptionID {
+		t.Errorf("unexpected item %+v", items[0])
+	}
+}
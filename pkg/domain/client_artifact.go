@@ -0,0 +1,32 @@
+package domain
+
+// ClientArtifactKind - вид файла для сборки клиентов API.
+type ClientArtifactKind string
+
+const (
+	// ClientArtifactSpec - спецификация OpenAPI, из которой собираются клиенты
+	ClientArtifactSpec ClientArtifactKind = "spec"
+	// ClientArtifactConfig - конфигурация openapi-generator для одного языка
+	ClientArtifactConfig ClientArtifactKind = "config"
+	// ClientArtifactScript - скрипт, собирающий пакеты из спецификации и конфигураций
+	ClientArtifactScript ClientArtifactKind = "script"
+	// ClientArtifactPackage - готовый пакет клиента, собранный вместе с образом сервиса
+	ClientArtifactPackage ClientArtifactKind = "package"
+)
+
+// ClientArtifact - файл, который можно скачать по /api/v1/clients/{name}.
+type ClientArtifact struct {
+	Name        string             `json:"name" example:"typescript.tgz"`
+	Kind        ClientArtifactKind `json:"kind" example:"package"`
+	Language    string             `json:"language,omitempty" example:"typescript"`
+	ContentType string             `json:"content_type" example:"application/gzip"`
+	Size        int                `json:"size" example:"48213"`
+	SHA256      string             `json:"sha256" example:"9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"`
+}
+
+// ClientArtifacts - файлы клиентов API. Все они собраны из спецификации этой же
+// сборки сервиса; APIVersion - версия API из журнала изменений и версия пакетов.
+type ClientArtifacts struct {
+	APIVersion string           `json:"api_version" example:"1.10.0"`
+	Artifacts  []ClientArtifact `json:"artifacts"`
+}
@@ -0,0 +1,205 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// CustomFieldType - тип значения пользовательского поля тенанта.
+type CustomFieldType string
+
+const (
+	CustomFieldString  CustomFieldType = "string"
+	CustomFieldNumber  CustomFieldType = "number"
+	CustomFieldBoolean CustomFieldType = "boolean"
+)
+
+const (
+	// MaxCustomFields ограничивает число полей в схеме тенанта.
+	MaxCustomFields = 50
+	// maxCustomStringLength - предел длины строки, если поле не задает свой
+	maxCustomStringLength = 500
+)
+
+var (
+	ErrInvalidCustomField = errors.New("invalid custom field")
+
+	customFieldNamePattern = regexp.MustCompile(`^[a-z][a-z0-9_]{0,39}$`)
+)
+
+// CustomFieldDefinition - поле, которое тенант добавил подпискам: имя, тип и
+// правила проверки значения.
+type CustomFieldDefinition struct {
+	// Name - ключ в custom_fields: строчные латинские буквы, цифры и "_"
+	Name string          `json:"name" example:"po_number"`
+	Type CustomFieldType `json:"type" example:"string"`
+	// Required - поле обязательно при создании и изменении подписки
+	Required    bool   `json:"required,omitempty" example:"true"`
+	Description string `json:"description,omitempty" example:"Номер заказа на закупку"`
+	// Pattern - регулярное выражение, которому соответствует строка целиком
+	Pattern string `json:"pattern,omitempty" example:"^PO-[0-9]{6}$"`
+	// Values - допустимые значения строки; пусто - любые
+	Values []string `json:"values,omitempty" example:"platform,payments"`
+	// MaxLength - предел длины строки в символах, по умолчанию 500
+	MaxLength int `json:"max_length,omitempty" example:"64"`
+	// Min и Max - границы числа включительно
+	Min *float64 `json:"min,omitempty" example:"0"`
+	Max *float64 `json:"max,omitempty"`
+}
+
+// CustomFieldSchema - реестр пользовательских полей тенанта.
+type CustomFieldSchema []CustomFieldDefinition
+
+// CustomFieldValues - значения пользовательских полей подписки по именам.
+type CustomFieldValues map[string]any
+
+// Validate проверяет схему: имена, типы и правила, применимые к типу поля.
+func (s CustomFieldSchema) Validate() error {
+	if len(s) > MaxCustomFields {
+		return fmt.Errorf("at most %d custom fields", MaxCustomFields)
+	}
+	seen := make(map[string]bool, len(s))
+	for _, field := range s {
+		if !customFieldNamePattern.MatchString(field.Name) {
+			return fmt.Errorf("invalid custom field name %q, expected up to 40 lowercase latin letters, digits or '_'", field.Name)
+		}
+		if seen[field.Name] {
+			return fmt.Errorf("duplicate custom field %q", field.Name)
+		}
+		seen[field.Name] = true
+
+		isString := field.Type == CustomFieldString
+		switch {
+		case field.Type != CustomFieldString && field.Type != CustomFieldNumber && field.Type != CustomFieldBoolean:
+			return fmt.Errorf("custom field %s: type must be string, number or boolean", field.Name)
+		case !isString && (field.Pattern != "" || len(field.Values) > 0 || field.MaxLength != 0):
+			return fmt.Errorf("custom field %s: pattern, values and max_length apply only to strings", field.Name)
+		case field.Type != CustomFieldNumber && (field.Min != nil || field.Max != nil):
+			return fmt.Errorf("custom field %s: min and max apply only to numbers", field.Name)
+		case field.MaxLength < 0:
+			return fmt.Errorf("custom field %s: max_length must not be negative", field.Name)
+		case field.Min != nil && field.Max != nil && *field.Min > *field.Max:
+			return fmt.Errorf("custom field %s: min must not be greater than max", field.Name)
+		}
+		if field.Pattern != "" {
+			if _, err := regexp.Compile(field.Pattern); err != nil {
+				return fmt.Errorf("custom field %s: pattern: %w", field.Name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// Field возвращает определение поля name.
+func (s CustomFieldSchema) Field(name string) (CustomFieldDefinition, bool) {
+	i := slices.IndexFunc(s, func(field CustomFieldDefinition) bool { return field.Name == name })
+	if i < 0 {
+		return CustomFieldDefinition{}, false
+	}
+	return s[i], true
+}
+
+// Normalize проверяет значения подписки по схеме и возвращает их без null:
+// null, как и отсутствующий ключ, означает, что поле не задано. Неизвестные поля,
+// значения не того типа и незаданные обязательные поля - ErrInvalidCustomField.
+func (s CustomFieldSchema) Normalize(values CustomFieldValues) (CustomFieldValues, error) {
+	normalized := make(CustomFieldValues, len(values))
+	for name, value := range values {
+		if value == nil {
+			continue
+		}
+		field, ok := s.Field(name)
+		if !ok {
+			return nil, fmt.Errorf("%w %q: not defined for the tenant", ErrInvalidCustomField, name)
+		}
+		if err := field.check(value); err != nil {
+			return nil, err
+		}
+		normalized[name] = value
+	}
+	for _, field := range s {
+		if _, ok := normalized[field.Name]; field.Required && !ok {
+			return nil, fmt.Errorf("%w %q: required", ErrInvalidCustomField, field.Name)
+		}
+	}
+	return normalized, nil
+}
+
+// check проверяет значение поля после разбора JSON: число приходит как float64.
+func (f CustomFieldDefinition) check(value any) error {
+	switch f.Type {
+	case CustomFieldString:
+		s, ok := value.(string)
+		if !ok {
+			return fmt.Errorf("%w %q: expected a string", ErrInvalidCustomField, f.Name)
+		}
+		limit := f.MaxLength
+		if limit == 0 {
+			limit = maxCustomStringLength
+		}
+		if utf8.RuneCountInString(s) > limit {
+			return fmt.Errorf("%w %q: must be at most %d characters", ErrInvalidCustomField, f.Name, limit)
+		}
+		if len(f.Values) > 0 && !slices.Contains(f.Values, s) {
+			return fmt.Errorf("%w %q: must be one of %s", ErrInvalidCustomField, f.Name, strings.Join(f.Values, ", "))
+		}
+		if f.Pattern != "" && !regexp.MustCompile(`^(?:`+f.Pattern+`)$`).MatchString(s) {
+			return fmt.Errorf("%w %q: must match %s", ErrInvalidCustomField, f.Name, f.Pattern)
+		}
+	case CustomFieldNumber:
+		n, ok := value.(float64)
+		if !ok {
+			return fmt.Errorf("%w %q: expected a number", ErrInvalidCustomField, f.Name)
+		}
+		if (f.Min != nil && n < *f.Min) || (f.Max != nil && n > *f.Max) {
+			return fmt.Errorf("%w %q: out of range", ErrInvalidCustomField, f.Name)
+		}
+	case CustomFieldBoolean:
+		if _, ok := value.(bool); !ok {
+			return fmt.Errorf("%w %q: expected a boolean", ErrInvalidCustomField, f.Name)
+		}
+	}
+	return nil
+}
+
+// ParseFilters разбирает фильтры списка custom_field=name:value в
+// значения типов полей схемы. Значение не проверяется правилами поля: фильтр по
+// значению, которого не может быть, просто ничего не находит.
+func (s CustomFieldSchema) ParseFilters(filters []string) (CustomFieldValues, error) {
+	if len(filters) == 0 {
+		return nil, nil
+	}
+	values := make(CustomFieldValues, len(filters))
+	for _, filter := range filters {
+		name, raw, ok := strings.Cut(filter, ":")
+		if !ok {
+			return nil, fmt.Errorf("%w filter %q, expected name:value", ErrInvalidCustomField, filter)
+		}
+		field, ok := s.Field(name)
+		if !ok {
+			return nil, fmt.Errorf("%w %q: not defined for the tenant", ErrInvalidCustomField, name)
+		}
+		switch field.Type {
+		case CustomFieldNumber:
+			n, err := strconv.ParseFloat(raw, 64)
+			if err != nil {
+				return nil, fmt.Errorf("%w %q: filter value %q is not a number", ErrInvalidCustomField, name, raw)
+			}
+			values[name] = n
+		case CustomFieldBoolean:
+			b, err := strconv.ParseBool(raw)
+			if err != nil {
+				return nil, fmt.Errorf("%w %q: filter value %q is not a boolean", ErrInvalidCustomField, name, raw)
+			}
+			values[name] = b
+		default:
+			values[name] = raw
+		}
+	}
+	return values, nil
+}
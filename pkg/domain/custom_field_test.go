@@ -0,0 +1,67 @@
+package domain
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestCustomFieldSchema(t *testing.T) {
+	zero := 0.0
+	schema := CustomFieldSchema{
+		{Name: "po_number", Type: CustomFieldString, Required: true, Pattern: "PO-[0-9]{6}"},
+		{Name: "cost_center", Type: CustomFieldString, Values: []string{"platform", "payments"}},
+		{Name: "seats", Type: CustomFieldNumber, Min: &zero},
+		{Name: "shared", Type: CustomFieldBoolean},
+	}
+	if err := schema.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	values, err := schema.Normalize(CustomFieldValues{"po_number": "PO-000042", "seats": 12.0, "shared": nil})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (CustomFieldValues{"po_number": "PO-000042", "seats": 12.0}); !reflect.DeepEqual(values, want) {
+		t.Errorf("normalized = %v, want %v", values, want)
+	}
+
+	for name, values := range map[string]CustomFieldValues{
+		"missing required": {"seats": 1.0},
+		"unknown field":    {"po_number": "PO-000042", "owner": "alice"},
+		"pattern":          {"po_number": "PO-42"},
+		"partial pattern":  {"po_number": "xPO-000042"},
+		"not in values":    {"po_number": "PO-000042", "cost_center": "sales"},
+		"below min":        {"po_number": "PO-000042", "seats": -1.0},
+		"wrong type":       {"po_number": "PO-000042", "shared": "yes"},
+	} {
+		if _, err := schema.Normalize(values); !errors.Is(err, ErrInvalidCustomField) {
+			t.Errorf("%s: err = %v, want ErrInvalidCustomField", name, err)
+		}
+	}
+
+	filters, err := schema.ParseFilters([]string{"seats:12", "shared:true", "po_number:PO-000042:A"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (CustomFieldValues{"seats": 12.0, "shared": true, "po_number": "PO-000042:A"}); !reflect.DeepEqual(filters, want) {
+		t.Errorf("filters = %v, want %v", filters, want)
+	}
+	for _, filter := range []string{"seats", "seats:many", "owner:alice"} {
+		if _, err := schema.ParseFilters([]string{filter}); err == nil {
+			t.Errorf("ParseFilters(%q) = nil error", filter)
+		}
+	}
+
+	for name, invalid := range map[string]CustomFieldSchema{
+		"name":          {{Name: "PO Number", Type: CustomFieldString}},
+		"duplicate":     {{Name: "seats", Type: CustomFieldNumber}, {Name: "seats", Type: CustomFieldString}},
+		"type":          {{Name: "seats", Type: "integer"}},
+		"min on string": {{Name: "owner", Type: CustomFieldString, Min: &zero}},
+		"pattern":       {{Name: "owner", Type: CustomFieldString, Pattern: "("}},
+	} {
+		if err := invalid.Validate(); err == nil {
+			t.Errorf("%s: Validate() = nil error", name)
+		}
+	}
+}
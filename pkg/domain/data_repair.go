@@ -0,0 +1,250 @@
+package domain
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// DataIssueKind - вид нарушения целостности данных подписки.
+type DataIssueKind string
+
+const (
+	IssueInvalidStartDate DataIssueKind = "invalid_start_date"
+	IssueInvalidEndDate   DataIssueKind = "invalid_end_date"
+	IssueEndBeforeStart   DataIssueKind = "end_before_start"
+	IssueNegativePrice    DataIssueKind = "negative_price"
+	IssueOrphanedUser     DataIssueKind = "orphaned_user"
+)
+
+func (k DataIssueKind) Valid() bool {
+	_, ok := dataFixes[k]
+	return ok
+}
+
+// DataFix - способ исправить нарушение.
+type DataFix string
+
+const (
+	// FixNormalizeDate приводит месяц к MM-YYYY, если его удается однозначно разобрать
+	FixNormalizeDate DataFix = "normalize"
+	// FixClearEndDate делает подписку бессрочной
+	FixClearEndDate DataFix = "clear_end_date"
+	// FixEndAtStart переносит окончание на месяц начала
+	FixEndAtStart DataFix = "end_at_start"
+	FixAbsPrice   DataFix = "abs"
+	FixZeroPrice  DataFix = "zero"
+	// FixProvisionUser заводит отсутствующего пользователя
+	FixProvisionUser DataFix = "provision_user"
+)
+
+// dataFixes - какие исправления допустимы для каждого вида нарушения.
+var dataFixes = map[DataIssueKind][]DataFix{
+	IssueInvalidStartDate: {FixNormalizeDate},
+	IssueInvalidEndDate:   {FixNormalizeDate, FixClearEndDate},
+	IssueEndBeforeStart:   {FixClearEndDate, FixEndAtStart},
+	IssueNegativePrice:    {FixAbsPrice, FixZeroPrice},
+	IssueOrphanedUser:     {FixProvisionUser},
+}
+
+// DataFixes - настроенные исправления по видам нарушений (DATA_REPAIR_FIXES).
+type DataFixes map[DataIssueKind]DataFix
+
+// ParseDataFixes разбирает список вида end_before_start=clear_end_date,negative_price=abs.
+func ParseDataFixes(list string) (DataFixes, error) {
+	fixes := make(DataFixes)
+	for _, part := range strings.Split(list, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		kind, fix, ok := strings.Cut(part, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid data fix %q, expected issue=fix", part)
+		}
+		issue := DataIssueKind(strings.TrimSpace(kind))
+		if !issue.Valid() {
+			return nil, fmt.Errorf("unknown data issue %q", issue)
+		}
+		value := DataFix(strings.TrimSpace(fix))
+		if !containsFix(dataFixes[issue], value) {
+			return nil, fmt.Errorf("fix %q is not applicable to %s", value, issue)
+		}
+		fixes[issue] = value
+	}
+	return fixes, nil
+}
+
+func containsFix(fixes []DataFix, fix DataFix) bool {
+	for _, f := range fixes {
+		if f == fix {
+			return true
+		}
+	}
+	return false
+}
+
+// DataRecord - подписка в том виде, в каком она хранится, для проверки целостности.
+type DataRecord struct {
+	SubscriptionID uuid.UUID
+	UserID         uuid.UUID
+	StartDate      string
+	EndDate        *string
+	PriceMinor     int64
+	// UserExists - есть ли пользователь подписки в таблице users
+	UserExists bool
+}
+
+// DataIssue - найденное нарушение и исправление, которое к нему применится.
+type DataIssue struct {
+	SubscriptionID uuid.UUID     `json:"subscription_id" example:"123e4567-e89b-12d3-a456-426614174000"`
+	UserID         uuid.UUID     `json:"user_id" example:"60601fee-2bf1-4721-ae6f-7636e79a0cba"`
+	Kind           DataIssueKind `json:"kind" example:"end_before_start"`
+	Detail         string        `json:"detail" example:"end_date 01-2025 is before start_date 07-2025"`
+	// Fix - настроенное исправление; пусто, если оно не задано или неприменимо к этим данным
+	Fix DataFix `json:"fix,omitempty" example:"clear_end_date"`
+
+	repair *DataRepair
+}
+
+// Repair возвращает изменение, которое внесет исправление, или nil.
+func (i DataIssue) Repair() *DataRepair {
+	return i.repair
+}
+
+// DataIssueReport - результат проверки данных; после исправления - и примененные исправления.
+type DataIssueReport struct {
+	Scanned int                   `json:"scanned" example:"1200"`
+	ByKind  map[DataIssueKind]int `json:"by_kind"`
+	Issues  []DataIssue           `json:"issues"`
+	// Repaired - записи аудита примененных исправлений
+	Repaired []*DataRepair `json:"repaired,omitempty"`
+}
+
+// DataRepair - примененное исправление, запись аудита: поле Field подписки
+// изменено с Before на After.
+type DataRepair struct {
+	ID             uuid.UUID     `json:"id" example:"3f2b7c1e-8a4d-4f6b-9c2e-1d5a7b9e0f12"`
+	SubscriptionID uuid.UUID     `json:"subscription_id" example:"123e4567-e89b-12d3-a456-426614174000"`
+	UserID         uuid.UUID     `json:"user_id" example:"60601fee-2bf1-4721-ae6f-7636e79a0cba"`
+	Kind           DataIssueKind `json:"kind" example:"end_before_start"`
+	Fix            DataFix       `json:"fix" example:"clear_end_date"`
+	Field          string        `json:"field" example:"end_date"`
+	Before         *string       `json:"before,omitempty" example:"01-2025"`
+	After          *string       `json:"after,omitempty"`
+	AppliedAt      time.Time     `json:"applied_at" example:"2025-10-23T15:04:05Z"`
+}
+
+// DataRepairRequest - проверка с исправлением. Kinds ограничивает исправляемые виды
+// нарушений; по умолчанию исправляется все, для чего настроено исправление.
+type DataRepairRequest struct {
+	Kinds []DataIssueKind `json:"kinds,omitempty" binding:"omitempty,dive,oneof=invalid_start_date invalid_end_date end_before_start negative_price orphaned_user" example:"end_before_start,negative_price"`
+	// BatchSize - сколько подписок проверяется и исправляется в одной транзакции
+	BatchSize int `json:"batch_size,omitempty" binding:"omitempty,min=1,max=5000" example:"500"`
+}
+
+type ListDataRepairsQuery struct {
+	Limit  int `form:"limit,default=100" binding:"min=1,max=1000"`
+	Offset int `form:"offset" binding:"min=0"`
+}
+
+// CheckDataRecord находит нарушения в записи и планирует настроенные исправления.
+// Окончание раньше начала проверяется, только если оба месяца разбираются.
+func CheckDataRecord(rec DataRecord, fixes DataFixes) []DataIssue {
+	var issues []DataIssue
+	add := func(kind DataIssueKind, detail string, repair *DataRepair) {
+		issue := DataIssue{SubscriptionID: rec.SubscriptionID, UserID: rec.UserID, Kind: kind, Detail: detail}
+		if repair != nil {
+			repair.SubscriptionID, repair.UserID, repair.Kind = rec.SubscriptionID, rec.UserID, kind
+			issue.Fix, issue.repair = repair.Fix, repair
+		}
+		issues = append(issues, issue)
+	}
+
+	start, startErr := ParsePeriod(rec.StartDate)
+	if startErr != nil {
+		var repair *DataRepair
+		if fixes[IssueInvalidStartDate] == FixNormalizeDate {
+			if normalized, ok := NormalizePeriod(rec.StartDate); ok {
+				repair = &DataRepair{Fix: FixNormalizeDate, Field: "start_date", Before: &rec.StartDate, After: &normalized}
+			}
+		}
+		add(IssueInvalidStartDate, fmt.Sprintf("start_date %q is not MM-YYYY", rec.StartDate), repair)
+	}
+
+	if rec.EndDate != nil {
+		end, endErr := ParsePeriod(*rec.EndDate)
+		switch {
+		case endErr != nil:
+			var repair *DataRepair
+			switch fixes[IssueInvalidEndDate] {
+			case FixNormalizeDate:
+				if normalized, ok := NormalizePeriod(*rec.EndDate); ok {
+					repair = &DataRepair{Fix: FixNormalizeDate, Field: "end_date", Before: rec.EndDate, After: &normalized}
+				}
+			case FixClearEndDate:
+				repair = &DataRepair{Fix: FixClearEndDate, Field: "end_date", Before: rec.EndDate}
+			}
+			add(IssueInvalidEndDate, fmt.Sprintf("end_date %q is not MM-YYYY", *rec.EndDate), repair)
+		case startErr == nil && end.Before(start):
+			var repair *DataRepair
+			switch fixes[IssueEndBeforeStart] {
+			case FixClearEndDate:
+				repair = &DataRepair{Fix: FixClearEndDate, Field: "end_date", Before: rec.EndDate}
+			case FixEndAtStart:
+				after := rec.StartDate
+				repair = &DataRepair{Fix: FixEndAtStart, Field: "end_date", Before: rec.EndDate, After: &after}
+			}
+			add(IssueEndBeforeStart, fmt.Sprintf("end_date %s is before start_date %s", *rec.EndDate, rec.StartDate), repair)
+		}
+	}
+
+	if rec.PriceMinor < 0 {
+		var repair *DataRepair
+		before := strconv.FormatInt(rec.PriceMinor, 10)
+		switch fixes[IssueNegativePrice] {
+		case FixAbsPrice:
+			after := strconv.FormatInt(-rec.PriceMinor, 10)
+			repair = &DataRepair{Fix: FixAbsPrice, Field: "price_minor", Before: &before, After: &after}
+		case FixZeroPrice:
+			after := "0"
+			repair = &DataRepair{Fix: FixZeroPrice, Field: "price_minor", Before: &before, After: &after}
+		}
+		add(IssueNegativePrice, fmt.Sprintf("price_minor %d is negative", rec.PriceMinor), repair)
+	}
+
+	if !rec.UserExists {
+		var repair *DataRepair
+		if fixes[IssueOrphanedUser] == FixProvisionUser {
+			after := rec.UserID.String()
+			repair = &DataRepair{Fix: FixProvisionUser, Field: "user_id", After: &after}
+		}
+		add(IssueOrphanedUser, fmt.Sprintf("user %s does not exist", rec.UserID), repair)
+	}
+
+	return issues
+}
+
+var looseMonth = regexp.MustCompile(`^(\d{1,2})[-./ ](\d{4})$|^(\d{4})[-./ ](\d{1,2})$`)
+
+// NormalizePeriod приводит к MM-YYYY месяц, записанный как M-YYYY, MM/YYYY, MM.YYYY
+// или YYYY-MM. Возвращает false, если месяц не разбирается однозначно.
+func NormalizePeriod(value string) (string, bool) {
+	m := looseMonth.FindStringSubmatch(strings.TrimSpace(value))
+	if m == nil {
+		return "", false
+	}
+	month, year := m[1], m[2]
+	if month == "" {
+		year, month = m[3], m[4]
+	}
+	monthNum, _ := strconv.Atoi(month)
+	if monthNum < 1 || monthNum > 12 {
+		return "", false
+	}
+	return fmt.Sprintf("%02d-%s", monthNum, year), true
+}
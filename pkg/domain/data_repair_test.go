@@ -0,0 +1,93 @@
+package domain
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestNormalizePeriod(t *testing.T) {
+	tests := []struct {
+		value, want string
+		ok          bool
+	}{
+		{value: "7-2025", want: "07-2025", ok: true},
+		{value: "07/2025", want: "07-2025", ok: true},
+		{value: "2025-07", want: "07-2025", ok: true},
+		{value: " 12.2025 ", want: "12-2025", ok: true},
+		{value: "13-2025"},
+		{value: "July 2025"},
+	}
+	for _, tt := range tests {
+		got, ok := NormalizePeriod(tt.value)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("NormalizePeriod(%q) = %q, %v; want %q, %v", tt.value, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestParseDataFixes(t *testing.T) {
+	fixes, err := ParseDataFixes(" end_before_start=end_at_start, negative_price=abs ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fixes[IssueEndBeforeStart] != FixEndAtStart || fixes[IssueNegativePrice] != FixAbsPrice || len(fixes) != 2 {
+		t.Errorf("fixes = %v", fixes)
+	}
+
+	for _, list := range []string{"negative_price", "unknown=abs", "negative_price=clear_end_date"} {
+		if _, err := ParseDataFixes(list); err == nil {
+			t.Errorf("ParseDataFixes(%q) succeeded", list)
+		}
+	}
+}
+
+func TestCheckDataRecord(t *testing.T) {
+	fixes := DataFixes{
+		IssueInvalidStartDate: FixNormalizeDate,
+		IssueEndBeforeStart:   FixClearEndDate,
+		IssueNegativePrice:    FixZeroPrice,
+	}
+	end := func(s string) *string { return &s }
+
+	tests := []struct {
+		name string
+		rec  DataRecord
+		want map[DataIssueKind]DataFix
+	}{
+		{name: "valid", rec: DataRecord{StartDate: "07-2025", EndDate: end("12-2025"), PriceMinor: 100, UserExists: true}},
+		{
+			name: "end before start",
+			rec:  DataRecord{StartDate: "07-2025", EndDate: end("01-2025"), UserExists: true},
+			want: map[DataIssueKind]DataFix{IssueEndBeforeStart: FixClearEndDate},
+		},
+		{
+			name: "unparsable months",
+			rec:  DataRecord{StartDate: "2025-07", EndDate: end("someday"), UserExists: true},
+			want: map[DataIssueKind]DataFix{IssueInvalidStartDate: FixNormalizeDate, IssueInvalidEndDate: ""},
+		},
+		{
+			name: "negative price and orphan",
+			rec:  DataRecord{StartDate: "07-2025", PriceMinor: -500},
+			want: map[DataIssueKind]DataFix{IssueNegativePrice: FixZeroPrice, IssueOrphanedUser: ""},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.rec.SubscriptionID, tt.rec.UserID = uuid.New(), uuid.New()
+			issues := CheckDataRecord(tt.rec, fixes)
+			if len(issues) != len(tt.want) {
+				t.Fatalf("issues = %+v, want %v", issues, tt.want)
+			}
+			for _, issue := range issues {
+				fix, ok := tt.want[issue.Kind]
+				if !ok || issue.Fix != fix {
+					t.Errorf("issue %s fix %q, want %q", issue.Kind, issue.Fix, fix)
+				}
+				if repair := issue.Repair(); (repair != nil) != (fix != "") || repair != nil && repair.SubscriptionID != tt.rec.SubscriptionID {
+					t.Errorf("issue %s repair = %+v", issue.Kind, repair)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,101 @@
+package domain
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+var deprecationRoutePattern = regexp.MustCompile(`^(GET|POST|PUT|PATCH|DELETE) (/[A-Za-z0-9_{}/.-]*)$`)
+
+// Deprecation - устаревшая ручка, ее параметр запроса или поле тела запроса.
+// Route - ручка в виде "GET /api/v1/subscriptions/{id}"; без Param и Field
+// устарела вся ручка. Since - дата, с которой поверхность устарела, Sunset -
+// дата удаления. Link - описание замены; пусто - изменения ручки в журнале API.
+type Deprecation struct {
+	Route  string
+	Param  string
+	Field  string
+	Since  time.Time
+	Sunset time.Time
+	Link   string
+}
+
+// Surface - имя устаревшей поверхности для метрик: "GET /api/v1/subscriptions?userId"
+// для параметра, "PUT /api/v1/subscriptions/{id}#price" для поля тела.
+func (d Deprecation) Surface() string {
+	switch {
+	case d.Param != "":
+		return d.Route + "?" + d.Param
+	case d.Field != "":
+		return d.Route + "#" + d.Field
+	}
+	return d.Route
+}
+
+// Validate проверяет ручку и даты: удаление должно быть позже устаревания.
+func (d Deprecation) Validate() error {
+	if !deprecationRoutePattern.MatchString(d.Route) {
+		return fmt.Errorf("invalid endpoint %q, expected \"METHOD /path\"", d.Route)
+	}
+	if d.Param != "" && d.Field != "" {
+		return fmt.Errorf("%s: param and field are mutually exclusive", d.Route)
+	}
+	if d.Since.IsZero() || d.Sunset.IsZero() {
+		return fmt.Errorf("%s: since and sunset dates are required", d.Surface())
+	}
+	if !d.Sunset.After(d.Since) {
+		return fmt.Errorf("%s: sunset %s must be after %s", d.Surface(), d.Sunset.Format(time.DateOnly), d.Since.Format(time.DateOnly))
+	}
+	return nil
+}
+
+// ParseDeprecations разбирает устаревшие поверхности из строки вида
+// "GET /api/v1/subscriptions param=userId since=2025-09-01 sunset=2027-03-01;
+// PUT /api/v1/subscriptions/{id} field=price since=2026-01-12 sunset=2026-12-01 link=https://docs.example.com/price".
+func ParseDeprecations(spec string) ([]Deprecation, error) {
+	var deprecations []Deprecation
+	seen := make(map[string]bool)
+	for _, entry := range strings.Split(spec, ";") {
+		fields := strings.Fields(entry)
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) < 2 {
+			return nil, fmt.Errorf("deprecation %q: expected \"METHOD /path since=... sunset=...\"", strings.TrimSpace(entry))
+		}
+
+		deprecation := Deprecation{Route: strings.ToUpper(fields[0]) + " " + fields[1]}
+		for _, field := range fields[2:] {
+			name, value, _ := strings.Cut(field, "=")
+			var err error
+			switch name {
+			case "param":
+				deprecation.Param = value
+			case "field":
+				deprecation.Field = value
+			case "since":
+				deprecation.Since, err = time.Parse(time.DateOnly, value)
+			case "sunset":
+				deprecation.Sunset, err = time.Parse(time.DateOnly, value)
+			case "link":
+				deprecation.Link = value
+			default:
+				return nil, fmt.Errorf("deprecation %q: unknown option %q, expected param, field, since, sunset or link", deprecation.Route, name)
+			}
+			if err != nil {
+				return nil, fmt.Errorf("deprecation %q: %s: invalid date %q, expected YYYY-MM-DD", deprecation.Route, name, value)
+			}
+		}
+		if err := deprecation.Validate(); err != nil {
+			return nil, fmt.Errorf("deprecation: %w", err)
+		}
+		if seen[deprecation.Surface()] {
+			return nil, fmt.Errorf("deprecation %q: duplicate surface", deprecation.Surface())
+		}
+		seen[deprecation.Surface()] = true
+		deprecations = append(deprecations, deprecation)
+	}
+	return deprecations, nil
+}
@@ -0,0 +1,57 @@
+package domain
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseDeprecations(t *testing.T) {
+	deprecations, err := ParseDeprecations("get /api/v1/subscriptions param=userId since=2025-09-01 sunset=2027-03-01; " +
+		"PUT /api/v1/subscriptions/{id} field=price since=2026-01-12 sunset=2026-12-01 link=https://docs.example.com/price;")
+	if err != nil {
+		t.Fatal(err)
+	}
+	date := func(value string) time.Time {
+		parsed, _ := time.Parse(time.DateOnly, value)
+		return parsed
+	}
+	want := []Deprecation{
+		{Route: "GET /api/v1/subscriptions", Param: "userId", Since: date("2025-09-01"), Sunset: date("2027-03-01")},
+		{
+			Route: "PUT /api/v1/subscriptions/{id}", Field: "price", Since: date("2026-01-12"), Sunset: date("2026-12-01"),
+			Link: "https://docs.example.com/price",
+		},
+	}
+	if len(deprecations) != len(want) {
+		t.Fatalf("deprecations = %+v", deprecations)
+	}
+	for i := range want {
+		if deprecations[i] != want[i] {
+			t.Errorf("deprecation %d = %+v, want %+v", i, deprecations[i], want[i])
+		}
+	}
+	if surface := deprecations[1].Surface(); surface != "PUT /api/v1/subscriptions/{id}#price" {
+		t.Errorf("surface = %q", surface)
+	}
+
+	if deprecations, err := ParseDeprecations(" "); err != nil || len(deprecations) != 0 {
+		t.Errorf("empty spec: %v, %v", deprecations, err)
+	}
+
+	invalid := []string{
+		"GET",
+		"GET /x",
+		"GET x since=2025-01-01 sunset=2026-01-01",
+		"GET /x since=2025-01-01",
+		"GET /x since=01.01.2025 sunset=2026-01-01",
+		"GET /x since=2026-01-01 sunset=2025-01-01",
+		"GET /x param=a field=b since=2025-01-01 sunset=2026-01-01",
+		"GET /x owner=team since=2025-01-01 sunset=2026-01-01",
+		"GET /x since=2025-01-01 sunset=2026-01-01; GET /x since=2025-02-01 sunset=2026-02-01",
+	}
+	for _, spec := range invalid {
+		if _, err := ParseDeprecations(spec); err == nil {
+			t.Errorf("%q: expected error", spec)
+		}
+	}
+}
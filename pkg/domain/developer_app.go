@@ -0,0 +1,83 @@
+package domain
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// DeveloperApp - приложение стороннего разработчика, зарегистрированное через портал.
+// Приложение работает с API по ключу X-API-Key в пределах своего лимита запросов.
+type DeveloperApp struct {
+	ID                 uuid.UUID `json:"id"`
+	Name               string    `json:"name" example:"Budget Tracker"`
+	ContactEmail       string    `json:"contact_email" example:"dev@example.com"`
+	RateLimitPerMinute int       `json:"rate_limit_per_minute" example:"60"`
+	// Sandbox - запросы с ключом приложения работают с данными песочницы
+	Sandbox bool `json:"sandbox" example:"true"`
+	// SecretHash - sha256 действующего ключа, сам ключ не хранится
+	SecretHash string    `json:"-"`
+	CreatedAt  time.Time `json:"created_at" example:"2025-10-23T15:04:05Z"`
+	UpdatedAt  time.Time `json:"updated_at" example:"2025-10-23T15:04:05Z"`
+}
+
+// UsageConsumer - потребитель в учете потребления API для запросов приложения.
+func (a *DeveloperApp) UsageConsumer() string {
+	return "app_" + a.ID.String()
+}
+
+type RegisterDeveloperAppRequest struct {
+	Name         string `json:"name" binding:"required,max=100" example:"Budget Tracker"`
+	ContactEmail string `json:"contact_email" binding:"required,email,max=254" example:"dev@example.com"`
+}
+
+// DeveloperAppCredentials - ответ с ключом приложения. Ключ показывается
+// только при регистрации и ротации.
+type DeveloperAppCredentials struct {
+	App    *DeveloperApp `json:"app"`
+	APIKey string        `json:"api_key" example:"sk_sandbox_5f2b9c..."`
+}
+
+// UpdateDeveloperAppRequest - изменение приложения администратором; nil оставляет поле как есть.
+type UpdateDeveloperAppRequest struct {
+	Sandbox            *bool `json:"sandbox,omitempty" example:"false"`
+	RateLimitPerMinute *int  `json:"rate_limit_per_minute,omitempty" binding:"omitempty,min=1" example:"600"`
+}
+
+// SandboxTenantID - служебный тенант, в схему которого уходят запросы с ключами песочницы.
+// Зарезервирован: обычного тенанта с таким ID создать нельзя.
+const SandboxTenantID = "sandbox"
+
+// SandboxMaxSubscriptions ограничивает объем данных песочницы между сбросами.
+const SandboxMaxSubscriptions = 10000
+
+// SandboxTenant возвращает тенанта песочницы. В реестре тенантов он не хранится.
+func SandboxTenant() *Tenant {
+	maxSubscriptions := SandboxMaxSubscriptions
+	return &Tenant{
+		ID:         SandboxTenantID,
+		Isolation:  TenantIsolationSchema,
+		SchemaName: SandboxTenantID,
+		Status:     TenantStatusActive,
+		Quotas:     TenantQuotas{MaxSubscriptions: &maxSubscriptions},
+		Features:   map[string]bool{},
+	}
+}
+
+// RateLimitStatus - состояние лимита запросов в текущем окне.
+type RateLimitStatus struct {
+	Limit     int       `json:"limit" example:"60"`
+	Remaining int       `json:"remaining" example:"42"`
+	ResetAt   time.Time `json:"reset_at" example:"2025-10-23T15:05:00Z"`
+}
+
+// NewSandboxAPIKey генерирует ключ приложения для песочницы.
+func NewSandboxAPIKey() (string, error) {
+	buf := make([]byte, 24)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return "sk_sandbox_" + hex.EncodeToString(buf), nil
+}
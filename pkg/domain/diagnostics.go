@@ -0,0 +1,35 @@
+package domain
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// QueryPlan - план запроса, снятый EXPLAIN (ANALYZE) в отладочном режиме репозиториев.
+type QueryPlan struct {
+	SQL string `json:"sql" example:"SELECT id, service_name FROM subscriptions WHERE id = $1"`
+	// Plan - вывод EXPLAIN в формате JSON; пустой, если запрос не удалось разобрать
+	Plan       json.RawMessage `json:"plan,omitempty" swaggertype:"object"`
+	DurationMS float64         `json:"duration_ms" example:"1.42"`
+	Error      string          `json:"error,omitempty" example:"ERROR: syntax error (SQLSTATE 42601)"`
+}
+
+// RequestDiagnostics - планы запросов одного HTTP-запроса.
+type RequestDiagnostics struct {
+	TraceID string      `json:"trace_id" example:"4bf92f3577b34da6a3ce929d0e0e4736"`
+	Method  string      `json:"method" example:"GET"`
+	Path    string      `json:"path" example:"/api/v1/subscriptions"`
+	Status  int         `json:"status" example:"200"`
+	At      time.Time   `json:"at" example:"2025-10-23T15:04:05Z"`
+	Queries []QueryPlan `json:"queries"`
+}
+
+// RuntimeDump - снятые по запросу стеки горутин и профиль кучи. Файлы лежат на диске
+// реплики, которая обработала запрос.
+type RuntimeDump struct {
+	GoroutinesFile string    `json:"goroutines_file" example:"/tmp/aggregator-dumps/goroutines-20251023T150405Z.txt"`
+	HeapFile       string    `json:"heap_file" example:"/tmp/aggregator-dumps/heap-20251023T150405Z.pb.gz"`
+	Goroutines     int       `json:"goroutines" example:"42"`
+	HeapAllocBytes uint64    `json:"heap_alloc_bytes" example:"18874368"`
+	CreatedAt      time.Time `json:"created_at"`
+}
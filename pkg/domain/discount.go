@@ -0,0 +1,92 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// DiscountKind - вид скидки: процент от стоимости месяца или фиксированная сумма.
+type DiscountKind string
+
+const (
+	DiscountPercent DiscountKind = "percent"
+	DiscountFixed   DiscountKind = "fixed"
+)
+
+// Discount - скидка (промокод) на подписку, действующая с StartMonth по EndMonth
+// включительно; без EndMonth действует бессрочно. Фиксированная скидка уменьшает
+// стоимость каждого месяца в окне на Amount.
+type Discount struct {
+	ID             uuid.UUID    `json:"id" example:"5f0c2a8e-3b1d-4c6e-9a7f-2d8b1e4c6a90"`
+	SubscriptionID uuid.UUID    `json:"subscription_id" example:"123e4567-e89b-12d3-a456-426614174000"`
+	Kind           DiscountKind `json:"kind" example:"percent"`
+	Percent        *int         `json:"percent,omitempty" example:"20"`
+	Amount         *Money       `json:"amount,omitempty"`
+	StartMonth     string       `json:"start_month" example:"09-2025"`
+	EndMonth       *string      `json:"end_month,omitempty" example:"11-2025"`
+	Code           *string      `json:"code,omitempty" example:"AUTUMN20"`
+	CreatedAt      time.Time    `json:"created_at" example:"2025-10-23T15:04:05Z"`
+}
+
+type CreateDiscountRequest struct {
+	Kind DiscountKind `json:"kind" binding:"required,oneof=percent fixed" example:"percent"`
+	// Percent - от 1 до 100, только для kind=percent
+	Percent *int `json:"percent,omitempty" example:"20"`
+	// Amount - скидка на каждый месяц в валюте подписки, только для kind=fixed
+	Amount     *Money  `json:"amount,omitempty"`
+	StartMonth string  `json:"start_month" binding:"required" example:"09-2025"`
+	EndMonth   *string `json:"end_month,omitempty" example:"11-2025"`
+	Code       *string `json:"code,omitempty" binding:"omitempty,max=64" example:"AUTUMN20"`
+}
+
+// ActiveAt сообщает, действует ли скидка в месяце month.
+func (d *Discount) ActiveAt(month time.Time) (bool, error) {
+	start, err := ParsePeriod(d.StartMonth)
+	if err != nil {
+		return false, err
+	}
+	if month.Before(start) {
+		return false, nil
+	}
+	if d.EndMonth != nil {
+		end, err := ParsePeriod(*d.EndMonth)
+		if err != nil {
+			return false, err
+		}
+		if month.After(end) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// DiscountedCharge применяет к стоимости месяца charge (в долях 1/ProrationDenominator)
+// действующие в month скидки. Сначала суммируются процентные скидки (не больше 100%),
+// затем вычитаются фиксированные; стоимость не становится отрицательной.
+// Так же скидки считаются в SQL.
+func DiscountedCharge(charge int64, discounts []*Discount, month time.Time) (int64, error) {
+	var percent, fixed int64
+	for _, d := range discounts {
+		active, err := d.ActiveAt(month)
+		if err != nil {
+			return 0, err
+		}
+		if !active {
+			continue
+		}
+		switch d.Kind {
+		case DiscountPercent:
+			if d.Percent != nil {
+				percent += int64(*d.Percent)
+			}
+		case DiscountFixed:
+			if d.Amount != nil {
+				fixed += d.Amount.Amount
+			}
+		}
+	}
+
+	percent = min(percent, 100)
+	return max(charge*(100-percent)/100-fixed*ProrationDenominator, 0), nil
+}
@@ -0,0 +1,46 @@
+package domain
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDiscountedCharge(t *testing.T) {
+	aug, _ := ParsePeriod("08-2025")
+	sep, _ := ParsePeriod("09-2025")
+	dec, _ := ParsePeriod("12-2025")
+
+	percent := func(p int, start string, end *string) *Discount {
+		return &Discount{Kind: DiscountPercent, Percent: &p, StartMonth: start, EndMonth: end}
+	}
+	fixed := func(amount int64, start string) *Discount {
+		money := NewMoney(amount, DefaultCurrency)
+		return &Discount{Kind: DiscountFixed, Amount: &money, StartMonth: start}
+	}
+	nov := "11-2025"
+
+	cases := []struct {
+		name      string
+		discounts []*Discount
+		month     time.Time
+		want      int64
+	}{
+		{name: "no discounts", month: sep, want: 40000},
+		{name: "before window", discounts: []*Discount{percent(20, "09-2025", nil)}, month: aug, want: 40000},
+		{name: "percent", discounts: []*Discount{percent(20, "09-2025", &nov)}, month: sep, want: 32000},
+		{name: "after window", discounts: []*Discount{percent(20, "09-2025", &nov)}, month: dec, want: 40000},
+		{name: "percents add up", discounts: []*Discount{percent(20, "09-2025", nil), percent(30, "08-2025", nil)}, month: sep, want: 20000},
+		{name: "percents capped", discounts: []*Discount{percent(70, "09-2025", nil), percent(50, "09-2025", nil)}, month: sep, want: 0},
+		{name: "fixed after percent", discounts: []*Discount{fixed(10000, "09-2025"), percent(50, "09-2025", nil)}, month: sep, want: 10000},
+		{name: "fixed not below zero", discounts: []*Discount{fixed(50000, "09-2025")}, month: sep, want: 0},
+	}
+	for _, tc := range cases {
+		got, err := DiscountedCharge(ProratedMonthCharge(40000, CycleMonthly, tc.month), tc.discounts, tc.month)
+		if err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		if got := RoundProrated(got); got != tc.want {
+			t.Errorf("%s: got %d, want %d", tc.name, got, tc.want)
+		}
+	}
+}
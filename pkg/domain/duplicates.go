@@ -0,0 +1,147 @@
+package domain
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// RecommendationConsolidatePlans - рекомендация оставить один план из нескольких у одного сервиса.
+const RecommendationConsolidatePlans = "consolidate_plans"
+
+// DuplicateFlag - отметка фоновой задачи: у пользователя несколько активных подписок
+// на один сервис (по ServiceKey). DetectedAt - когда группа найдена впервые.
+type DuplicateFlag struct {
+	UserID          uuid.UUID
+	ServiceKey      string
+	ServiceName     string
+	SubscriptionIDs []uuid.UUID
+	DetectedAt      time.Time
+}
+
+// Consolidation - предложение объединить планы: оставить Keep, отменить Cancel.
+type Consolidation struct {
+	Keep   uuid.UUID   `json:"keep" example:"123e4567-e89b-12d3-a456-426614174000"`
+	Cancel []uuid.UUID `json:"cancel"`
+	// MonthlySavings - месячная стоимость отменяемых планов по валютам
+	MonthlySavings Totals `json:"monthly_savings" swaggertype:"array,object"`
+}
+
+type DuplicatePlans struct {
+	ServiceKey    string          `json:"service_key" example:"spotify"`
+	ServiceName   string          `json:"service_name" example:"Spotify"`
+	DetectedAt    time.Time       `json:"detected_at" example:"2025-10-23T15:04:05Z"`
+	Subscriptions []*Subscription `json:"subscriptions"`
+	Suggestion    Consolidation   `json:"suggestion"`
+}
+
+type DuplicatesResponse struct {
+	UserID     uuid.UUID        `json:"user_id" example:"60601fee-2bf1-4721-ae6f-7636e79a0cba"`
+	Duplicates []DuplicatePlans `json:"duplicates"`
+}
+
+type Recommendation struct {
+	Type            string        `json:"type" example:"consolidate_plans"`
+	Message         string        `json:"message" example:"2 active plans of Spotify: keep one and cancel 1"`
+	ServiceName     string        `json:"service_name" example:"Spotify"`
+	SubscriptionIDs []uuid.UUID   `json:"subscription_ids"`
+	Suggestion      Consolidation `json:"suggestion"`
+}
+
+type RecommendationsResponse struct {
+	UserID          uuid.UUID        `json:"user_id" example:"60601fee-2bf1-4721-ae6f-7636e79a0cba"`
+	Recommendations []Recommendation `json:"recommendations"`
+}
+
+// DetectDuplicates группирует подписки, действующие в месяце month и не на паузе или
+// после отмены, по пользователю и ключу сервиса и возвращает группы из двух и более.
+// changes - история статусов по подпискам.
+func DetectDuplicates(subs []*Subscription, changes map[uuid.UUID][]*StatusChange, month time.Time) ([]*DuplicateFlag, error) {
+	type groupKey struct {
+		userID     uuid.UUID
+		serviceKey string
+	}
+	groups := make(map[groupKey]*DuplicateFlag)
+	order := make([]groupKey, 0)
+	for _, sub := range subs {
+		active, err := activeInMonth(sub, month)
+		if err != nil {
+			return nil, err
+		}
+		if !active {
+			continue
+		}
+		status, err := StatusAt(changes[sub.ID], month)
+		if err != nil {
+			return nil, err
+		}
+		if !status.Billable() {
+			continue
+		}
+
+		key := groupKey{userID: sub.UserID, serviceKey: ServiceKey(sub.ServiceName)}
+		group, ok := groups[key]
+		if !ok {
+			group = &DuplicateFlag{UserID: sub.UserID, ServiceKey: key.serviceKey, ServiceName: sub.ServiceName}
+			groups[key] = group
+			order = append(order, key)
+		}
+		group.SubscriptionIDs = append(group.SubscriptionIDs, sub.ID)
+	}
+
+	flags := make([]*DuplicateFlag, 0)
+	for _, key := range order {
+		if group := groups[key]; len(group.SubscriptionIDs) > 1 {
+			flags = append(flags, group)
+		}
+	}
+	return flags, nil
+}
+
+// SuggestConsolidation предлагает оставить самый дорогой в пересчете на месяц план
+// (обычно он включает остальные, например семейный) и отменить прочие.
+// При равной цене остается подписка, начатая позже.
+func SuggestConsolidation(subs []*Subscription) Consolidation {
+	sorted := make([]*Subscription, len(subs))
+	copy(sorted, subs)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		ri, rj := MonthlyRate(sorted[i].Price.Amount, sorted[i].BillingCycle), MonthlyRate(sorted[j].Price.Amount, sorted[j].BillingCycle)
+		if ri != rj {
+			return ri > rj
+		}
+		si, _ := ParsePeriod(sorted[i].StartDate)
+		sj, _ := ParsePeriod(sorted[j].StartDate)
+		if !si.Equal(sj) {
+			return si.After(sj)
+		}
+		return sorted[i].CreatedAt.After(sorted[j].CreatedAt)
+	})
+
+	suggestion := Consolidation{
+		Keep:           sorted[0].ID,
+		Cancel:         make([]uuid.UUID, 0, len(sorted)-1),
+		MonthlySavings: Totals{},
+	}
+	for _, sub := range sorted[1:] {
+		suggestion.Cancel = append(suggestion.Cancel, sub.ID)
+		suggestion.MonthlySavings.Add(NewMoney(RoundProrated(MonthlyRate(sub.Price.Amount, sub.BillingCycle)), sub.Price.Currency))
+	}
+	return suggestion
+}
+
+// NewConsolidateRecommendation строит рекомендацию по найденной группе планов.
+func NewConsolidateRecommendation(plans DuplicatePlans) Recommendation {
+	ids := make([]uuid.UUID, 0, len(plans.Subscriptions))
+	for _, sub := range plans.Subscriptions {
+		ids = append(ids, sub.ID)
+	}
+	return Recommendation{
+		Type:            RecommendationConsolidatePlans,
+		Message:         fmt.Sprintf("%d active plans of %s: keep one and cancel %d", len(ids), plans.ServiceName, len(plans.Suggestion.Cancel)),
+		ServiceName:     plans.ServiceName,
+		SubscriptionIDs: ids,
+		Suggestion:      plans.Suggestion,
+	}
+}
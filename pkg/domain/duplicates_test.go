@@ -0,0 +1,53 @@
+package domain
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestDetectDuplicates(t *testing.T) {
+	alice, bob := uuid.New(), uuid.New()
+	month := time.Date(2025, 10, 1, 0, 0, 0, 0, time.UTC)
+	sub := func(user uuid.UUID, name string, amount int64, cycle BillingCycle, start string, end *string) *Subscription {
+		return &Subscription{ID: uuid.New(), UserID: user, ServiceName: name, Price: NewMoney(amount, DefaultCurrency), BillingCycle: cycle, StartDate: start, EndDate: end}
+	}
+	ended := "08-2025"
+
+	individual := sub(alice, "Spotify", 29900, CycleMonthly, "01-2025", nil)
+	family := sub(alice, "spotify ", 4990000, CycleYearly, "03-2025", nil)
+	paused := sub(alice, "Spotify", 19900, CycleMonthly, "02-2025", nil)
+	subs := []*Subscription{
+		individual,
+		family,
+		paused,
+		sub(alice, "Spotify", 29900, CycleMonthly, "01-2024", &ended),
+		sub(alice, "Netflix", 79900, CycleMonthly, "01-2025", nil),
+		sub(bob, "Spotify", 29900, CycleMonthly, "01-2025", nil),
+	}
+	changes := map[uuid.UUID][]*StatusChange{
+		paused.ID: {{SubscriptionID: paused.ID, Status: StatusPaused, EffectiveFrom: "09-2025"}},
+	}
+
+	flags, err := DetectDuplicates(subs, changes, month)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Завершенная и приостановленная подписки, другой сервис и другой пользователь не в счет
+	if len(flags) != 1 {
+		t.Fatalf("got %d groups, want 1", len(flags))
+	}
+	if flag := flags[0]; flag.UserID != alice || flag.ServiceKey != "spotify" || len(flag.SubscriptionIDs) != 2 {
+		t.Errorf("flag = %+v", flag)
+	}
+
+	// Годовой семейный план дороже в пересчете на месяц, он и остается
+	suggestion := SuggestConsolidation([]*Subscription{individual, family})
+	if suggestion.Keep != family.ID || len(suggestion.Cancel) != 1 || suggestion.Cancel[0] != individual.ID {
+		t.Errorf("suggestion = %+v", suggestion)
+	}
+	if savings := suggestion.MonthlySavings.Get(DefaultCurrency); savings.Amount != 29900 {
+		t.Errorf("monthly savings = %d, want 29900", savings.Amount)
+	}
+}
@@ -0,0 +1,79 @@
+package domain
+
+// ErrorCode - стабильный машиночитаемый код ошибки. Текст ошибки может меняться,
+// клиенты должны опираться на код.
+type ErrorCode string
+
+const (
+	// 400
+	CodeValidationFailed    ErrorCode = "VALIDATION_FAILED"
+	CodeMalformedRequest    ErrorCode = "MALFORMED_REQUEST"
+	CodeInvalidID           ErrorCode = "INVALID_ID"
+	CodeInvalidPeriod       ErrorCode = "INVALID_PERIOD"
+	CodeInvalidMoney        ErrorCode = "INVALID_MONEY"
+	CodeInvalidTag          ErrorCode = "INVALID_TAG"
+	CodeInvalidContinuation ErrorCode = "INVALID_CONTINUATION"
+	CodeFeatureUnavailable  ErrorCode = "FEATURE_UNAVAILABLE"
+
+	// 401 и 403
+	CodeUnauthorized      ErrorCode = "UNAUTHORIZED"
+	CodeInvalidAdminToken ErrorCode = "INVALID_ADMIN_TOKEN"
+	CodeAdminAPIDisabled  ErrorCode = "ADMIN_API_DISABLED"
+	CodeInvalidAPIKey     ErrorCode = "INVALID_API_KEY"
+	CodeInvalidTenantKey  ErrorCode = "INVALID_TENANT_KEY"
+	CodeUnknownUser       ErrorCode = "UNKNOWN_USER"
+	CodeAccessDenied      ErrorCode = "ACCESS_DENIED"
+	CodeAPIKeyScopeDenied ErrorCode = "API_KEY_SCOPE_DENIED"
+	CodeTenantSuspended   ErrorCode = "TENANT_SUSPENDED"
+	CodeTenantFeatureOff  ErrorCode = "TENANT_FEATURE_DISABLED"
+	CodeQuotaExceeded     ErrorCode = "QUOTA_EXCEEDED"
+	CodeTimeTravelDenied  ErrorCode = "TIME_TRAVEL_DENIED"
+	CodeRateLimitExceeded ErrorCode = "RATE_LIMIT_EXCEEDED"
+
+	// 404
+	CodeSubscriptionNotFound   ErrorCode = "SUBSCRIPTION_NOT_FOUND"
+	CodeUserNotFound           ErrorCode = "USER_NOT_FOUND"
+	CodeTenantNotFound         ErrorCode = "TENANT_NOT_FOUND"
+	CodeServiceAliasNotFound   ErrorCode = "SERVICE_ALIAS_NOT_FOUND"
+	CodeDeveloperAppNotFound   ErrorCode = "DEVELOPER_APP_NOT_FOUND"
+	CodeExportNotFound         ErrorCode = "EXPORT_NOT_FOUND"
+	CodeQueuedWriteNotFound    ErrorCode = "QUEUED_WRITE_NOT_FOUND"
+	CodeDiscountNotFound       ErrorCode = "DISCOUNT_NOT_FOUND"
+	CodeBudgetNotFound         ErrorCode = "BUDGET_NOT_FOUND"
+	CodeAPIKeyNotFound         ErrorCode = "API_KEY_NOT_FOUND"
+	CodeNudgeNotFound          ErrorCode = "NUDGE_NOT_FOUND"
+	CodeAlertNotFound          ErrorCode = "ALERT_NOT_FOUND"
+	CodeClientArtifactNotFound ErrorCode = "CLIENT_ARTIFACT_NOT_FOUND"
+	// CodeEndpointDisabled - ручка выключена конфигурацией (404 или 405)
+	CodeEndpointDisabled ErrorCode = "ENDPOINT_DISABLED"
+
+	// 409
+	CodeSubscriptionAlreadyExists ErrorCode = "SUBSCRIPTION_ALREADY_EXISTS"
+	CodeTenantAlreadyExists       ErrorCode = "TENANT_ALREADY_EXISTS"
+	CodeUserAlreadyExists         ErrorCode = "USER_ALREADY_EXISTS"
+	CodeEmailTaken                ErrorCode = "EMAIL_TAKEN"
+	CodeBudgetCategoryTaken       ErrorCode = "BUDGET_CATEGORY_TAKEN"
+	CodeVersionConflict           ErrorCode = "VERSION_CONFLICT"
+
+	// 500 и 503
+	CodeInternal                ErrorCode = "INTERNAL_ERROR"
+	CodeDatabaseUnavailable     ErrorCode = "DATABASE_UNAVAILABLE"
+	CodeExchangeRateUnavailable ErrorCode = "EXCHANGE_RATE_UNAVAILABLE"
+	CodeWriteQueueFull          ErrorCode = "WRITE_QUEUE_FULL"
+)
+
+// ErrorResponse - тело ответа с ошибкой. Details перечисляет поля запроса,
+// не прошедшие проверку, если их удалось определить.
+type ErrorResponse struct {
+	Code    ErrorCode     `json:"code" example:"VALIDATION_FAILED"`
+	Error   string        `json:"error" example:"invalid request"`
+	Details []ErrorDetail `json:"details,omitempty"`
+}
+
+// ErrorDetail - ошибка одного поля: Field - имя поля в JSON или query,
+// Rule - нарушенное правило (required, oneof, min, type).
+type ErrorDetail struct {
+	Field   string `json:"field" example:"service_name"`
+	Rule    string `json:"rule" example:"required"`
+	Message string `json:"message" example:"service_name is required"`
+}
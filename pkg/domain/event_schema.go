@@ -0,0 +1,12 @@
+package domain
+
+import "encoding/json"
+
+// EventSchema - JSON Schema данных события одной версии. Одна схема может
+// описывать несколько типов событий с одинаковыми данными.
+type EventSchema struct {
+	Name       string          `json:"name" example:"subscription.renewed"`
+	Version    int             `json:"version" example:"1"`
+	EventTypes []string        `json:"event_types" example:"subscription.renewed"`
+	Schema     json.RawMessage `json:"schema" swaggertype:"object"`
+}
@@ -0,0 +1,55 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ExperimentVariant - реализация в эксперименте: control - текущая, candidate - новая.
+type ExperimentVariant string
+
+const (
+	ExperimentControl   ExperimentVariant = "control"
+	ExperimentCandidate ExperimentVariant = "candidate"
+)
+
+// CalculateExperimentReport - ответ GET /admin/experiments/calculate: сколько
+// расчетов стоимости отдала каждая реализация и чем закончились их сравнения
+// на этой реплике с момента запуска.
+type CalculateExperimentReport struct {
+	Candidate      string    `json:"candidate" example:"monthly_totals"`
+	Percent        float64   `json:"percent" example:"10"`
+	ComparePercent float64   `json:"compare_percent" example:"100"`
+	Since          time.Time `json:"since" example:"2025-10-23T15:04:05Z"`
+	// Served - число расчетов, которые отдала каждая реализация
+	Served     map[ExperimentVariant]int64 `json:"served" swaggertype:"object,integer"`
+	Compared   int64                       `json:"compared" example:"1200"`
+	Matched    int64                       `json:"matched" example:"1198"`
+	Mismatched int64                       `json:"mismatched" example:"2"`
+	// Failed - сравнения, в которых вторая реализация вернула ошибку
+	Failed int64 `json:"failed" example:"0"`
+	// Dropped - расчеты, не сравненные из-за занятых слотов сравнения
+	Dropped int64 `json:"dropped" example:"0"`
+	// MismatchRate - доля расхождений среди завершенных сравнений
+	MismatchRate float64 `json:"mismatch_rate" example:"0.0017"`
+	// Mismatches - последние расхождения, новые сверху
+	Mismatches []CalculateMismatch `json:"mismatches"`
+}
+
+// CalculateMismatch - расчет, в котором реализации разошлись. Суммы - в минорных
+// единицах по валютам.
+type CalculateMismatch struct {
+	At       time.Time `json:"at" example:"2025-10-23T15:04:05Z"`
+	TenantID string    `json:"tenant_id,omitempty"`
+	// Method - total (CalculateTotal) или by_user (подытоги пользователей)
+	Method string            `json:"method" example:"total"`
+	Served ExperimentVariant `json:"served" example:"control"`
+	// Query - параметры расчета в виде строки запроса, чтобы его можно было повторить
+	Query string `json:"query" example:"start_period=01-2025&end_period=12-2025&user_id=60601fee-2bf1-4721-ae6f-7636e79a0cba"`
+	// UserID и DifferingUsers для by_user: первый из пользователей с расхождением и их число
+	UserID         *uuid.UUID `json:"user_id,omitempty"`
+	DifferingUsers int        `json:"differing_users,omitempty"`
+	Control        Totals     `json:"control" swaggertype:"object,integer"`
+	Candidate      Totals     `json:"candidate" swaggertype:"object,integer"`
+}
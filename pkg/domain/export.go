@@ -0,0 +1,75 @@
+package domain
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ExportKind - что выгружается; по нему задача доставки выбирает генератор файла.
+type ExportKind string
+
+const (
+	ExportUsage ExportKind = "usage"
+	// ExportStatement - ежемесячная выписка пользователя, параметры - StatementParams
+	ExportStatement ExportKind = "statement"
+)
+
+// ExportDelivery - как файл попадает к получателю: вложением, ссылкой на
+// скачивание или, для выписок, событием без файла.
+type ExportDelivery string
+
+const (
+	DeliveryAttachment ExportDelivery = "attachment"
+	DeliveryLink       ExportDelivery = "link"
+	DeliveryWebhook    ExportDelivery = "webhook"
+)
+
+type ExportStatus string
+
+const (
+	ExportPending    ExportStatus = "pending"
+	ExportProcessing ExportStatus = "processing"
+	ExportDelivered  ExportStatus = "delivered"
+	ExportFailed     ExportStatus = "failed"
+)
+
+// ExportJob - выгрузка, которую фоновая задача сформирует и отправит на почту.
+type ExportJob struct {
+	ID       uuid.UUID       `json:"id"`
+	Kind     ExportKind      `json:"kind" example:"usage"`
+	Params   json.RawMessage `json:"params" swaggertype:"object"`
+	Email    string          `json:"email" example:"billing@example.com"`
+	Delivery ExportDelivery  `json:"delivery" example:"attachment"`
+	Status   ExportStatus    `json:"status" example:"pending"`
+	Attempts int             `json:"attempts" example:"0"`
+	// Error - причина последней неудачной попытки
+	Error    *string `json:"error,omitempty"`
+	FileName string  `json:"file_name,omitempty" example:"usage_2025-10-01_2025-10-31.csv"`
+	// Content хранится только для доставки ссылкой и удаляется после ExpiresAt
+	Content           []byte     `json:"-"`
+	DownloadTokenHash string     `json:"-"`
+	ExpiresAt         *time.Time `json:"expires_at,omitempty"`
+	CreatedAt         time.Time  `json:"created_at"`
+	UpdatedAt         time.Time  `json:"updated_at"`
+	DeliveredAt       *time.Time `json:"delivered_at,omitempty"`
+}
+
+// ExportEmailQuery - параметры доставки выгрузки на почту. Без email выгрузка
+// отдается сразу в ответе.
+type ExportEmailQuery struct {
+	Email    string         `form:"email" binding:"omitempty,email,max=254"`
+	Delivery ExportDelivery `form:"delivery" binding:"omitempty,oneof=attachment link"`
+}
+
+// NewDownloadToken возвращает токен ссылки на скачивание выгрузки.
+func NewDownloadToken() (string, error) {
+	buf := make([]byte, 24)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
@@ -0,0 +1,77 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// FieldMoney - правило, скрывающее все суммы ответа (объекты {"amount", "currency"}):
+// цены подписок, итоги, лимиты бюджетов, - под каким бы ключом они ни были.
+const FieldMoney = "money"
+
+var fieldNamePattern = regexp.MustCompile(`^[a-z][a-z0-9_]*$`)
+
+// FieldVisibility - поля ответов, скрытые от ролей. Поле - JSON-ключ, который
+// убирается на любой глубине ответа, или FieldMoney. Администратор видит все поля,
+// поэтому правил для admin нет.
+type FieldVisibility map[Role][]string
+
+// DefaultFieldVisibility - правила без FIELD_VISIBILITY: поддержка видит подписки, но не суммы.
+func DefaultFieldVisibility() FieldVisibility {
+	return FieldVisibility{RoleSupport: {FieldMoney}}
+}
+
+// Hidden возвращает поля, скрытые от роли role.
+func (v FieldVisibility) Hidden(role Role) []string {
+	return v[role]
+}
+
+func (v FieldVisibility) Validate() error {
+	for role, fields := range v {
+		switch {
+		case !role.Valid():
+			return fmt.Errorf("unknown role %q", role)
+		case role == RoleAdmin:
+			return errors.New("fields cannot be hidden from admin")
+		}
+		for _, field := range fields {
+			if !fieldNamePattern.MatchString(field) {
+				return fmt.Errorf("invalid field %q for role %s, expected a JSON key like price or notes", field, role)
+			}
+		}
+	}
+	return nil
+}
+
+// ParseFieldVisibility разбирает правила FIELD_VISIBILITY:
+// "support=money,notes; user=backfill_note". Пустая строка - без правил.
+func ParseFieldVisibility(spec string) (FieldVisibility, error) {
+	visibility := FieldVisibility{}
+	for _, rule := range strings.Split(spec, ";") {
+		if rule = strings.TrimSpace(rule); rule == "" {
+			continue
+		}
+		role, list, ok := strings.Cut(rule, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid field visibility rule %q, expected role=field,field", rule)
+		}
+		role = strings.TrimSpace(role)
+		if _, ok := visibility[Role(role)]; ok {
+			return nil, fmt.Errorf("duplicate field visibility rule for role %s", role)
+		}
+		fields := []string{}
+		for _, field := range strings.Split(list, ",") {
+			if field = strings.TrimSpace(field); field != "" && !slices.Contains(fields, field) {
+				fields = append(fields, field)
+			}
+		}
+		visibility[Role(role)] = fields
+	}
+	if err := visibility.Validate(); err != nil {
+		return nil, err
+	}
+	return visibility, nil
+}
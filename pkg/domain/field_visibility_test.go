@@ -0,0 +1,38 @@
+package domain
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseFieldVisibility(t *testing.T) {
+	visibility, err := ParseFieldVisibility(" support = money, notes ,money; user=backfill_note;")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := FieldVisibility{RoleSupport: {FieldMoney, "notes"}, RoleUser: {"backfill_note"}}
+	if !reflect.DeepEqual(visibility, want) {
+		t.Errorf("visibility = %v, want %v", visibility, want)
+	}
+	if hidden := visibility.Hidden(RoleAdmin); len(hidden) != 0 {
+		t.Errorf("admin hidden = %v", hidden)
+	}
+
+	// Роль с пустым списком видит все поля, но правило задано
+	visibility, err = ParseFieldVisibility("support=")
+	if err != nil || visibility[RoleSupport] == nil || len(visibility[RoleSupport]) != 0 {
+		t.Errorf("empty rule: %v, %v", visibility, err)
+	}
+
+	for _, spec := range []string{
+		"support",
+		"admin=money",
+		"manager=money",
+		"support=Price",
+		"support=money; support=notes",
+	} {
+		if _, err := ParseFieldVisibility(spec); err == nil {
+			t.Errorf("ParseFieldVisibility(%q) = nil error", spec)
+		}
+	}
+}
@@ -0,0 +1,17 @@
+package domain
+
+// Freshness - требуемая свежесть чтения (параметр freshness): чем разрешено
+// платить за скорость ответа.
+type Freshness string
+
+const (
+	// FreshnessEventual допускает отставание реплики чтения; по умолчанию
+	FreshnessEventual Freshness = "eventual"
+	// FreshnessStrong читает основную базу: видны все подтвержденные записи
+	FreshnessStrong Freshness = "strong"
+)
+
+// Valid сообщает, что свежесть известна; пустая - значение по умолчанию.
+func (f Freshness) Valid() bool {
+	return f == "" || f == FreshnessEventual || f == FreshnessStrong
+}
@@ -0,0 +1,26 @@
+package domain
+
+// RateLimitScope - чей лимит запросов применяется к вызывающему.
+type RateLimitScope string
+
+const (
+	RateLimitScopeDeveloperApp RateLimitScope = "developer_app"
+	RateLimitScopeServiceKey   RateLimitScope = "service_key"
+	RateLimitScopeTenant       RateLimitScope = "tenant"
+)
+
+// CallerRateLimit - лимит запросов вызывающего и его остаток в текущем окне.
+type CallerRateLimit struct {
+	Scope RateLimitScope `json:"scope" example:"tenant"`
+	// ID - приложение, ключ сервиса или тенант, на которого считается лимит
+	ID string `json:"id" example:"acme"`
+	RateLimitStatus
+}
+
+// CallerLimits - квоты вызывающего и их использование. Лимиты запросов
+// считаются на каждой реплике отдельно; без аутентификации список пуст.
+type CallerLimits struct {
+	RateLimits []CallerRateLimit `json:"rate_limits"`
+	// Tenant - квоты тенанта запроса и их использование
+	Tenant *TenantUsage `json:"tenant,omitempty"`
+}
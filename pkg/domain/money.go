@@ -0,0 +1,386 @@
+package domain
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Currency - код валюты ISO 4217.
+type Currency string
+
+// DefaultCurrency - валюта цен, заданных числом, и подписок до появления валют.
+const DefaultCurrency Currency = "RUB"
+
+// currencyExponents - число знаков минорных единиц поддерживаемых валют.
+var currencyExponents = map[Currency]int{
+	"RUB": 2, "USD": 2, "EUR": 2, "GBP": 2, "KZT": 2, "BYN": 2, "CNY": 2, "TRY": 2, "JPY": 0,
+}
+
+var ErrInvalidMoney = errors.New("invalid money amount")
+
+// customCurrencyPattern - коды своих валют: три заглавные латинские буквы, как в
+// ISO 4217, потому что код хранится в CHAR(3) и входит в схемы событий.
+var customCurrencyPattern = regexp.MustCompile(`^[A-Z]{3}$`)
+
+// maxCurrencyExponent ограничивает точность своих валют: сумма хранится в int64
+// минорных единиц, и при 8 знаках (сатоши) влезает больше 90 млрд биткоинов.
+const maxCurrencyExponent = 8
+
+// RegisterCurrency добавляет валюту вне списка поддерживаемых (криптовалюту, баллы
+// программы лояльности) с exponent знаками минорных единиц. Вызывается при старте,
+// до обработки запросов: реестр валют не защищен от одновременной записи.
+func RegisterCurrency(code Currency, exponent int) error {
+	if !customCurrencyPattern.MatchString(string(code)) {
+		return fmt.Errorf("invalid currency code %q, expected three uppercase latin letters", code)
+	}
+	if exponent < 0 || exponent > maxCurrencyExponent {
+		return fmt.Errorf("invalid exponent %d for currency %s, expected 0..%d", exponent, code, maxCurrencyExponent)
+	}
+	if existing, ok := currencyExponents[code]; ok && existing != exponent {
+		return fmt.Errorf("currency %s is already registered with exponent %d", code, existing)
+	}
+	currencyExponents[code] = exponent
+	return nil
+}
+
+// RegisterCurrencies регистрирует валюты из списка вида "BTC:8,ETH:8,XPT:0".
+func RegisterCurrencies(spec string) error {
+	for _, item := range strings.Split(spec, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		code, exp, ok := strings.Cut(item, ":")
+		exponent, err := strconv.Atoi(strings.TrimSpace(exp))
+		if !ok || err != nil {
+			return fmt.Errorf("invalid currency %q, expected CODE:exponent", item)
+		}
+		if err := RegisterCurrency(Currency(strings.ToUpper(strings.TrimSpace(code))), exponent); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Valid сообщает, поддерживается ли валюта.
+func (c Currency) Valid() bool {
+	_, ok := currencyExponents[c]
+	return ok
+}
+
+// Exponent - число знаков после запятой у суммы в валюте.
+func (c Currency) Exponent() int {
+	if exp, ok := currencyExponents[c]; ok {
+		return exp
+	}
+	return 2
+}
+
+// CurrencyExponents возвращает коды зарегистрированных валют и число их знаков
+// в одинаковом порядке - для передачи в SQL массивами.
+func CurrencyExponents() ([]string, []int) {
+	codes := make([]string, 0, len(currencyExponents))
+	for code := range currencyExponents {
+		codes = append(codes, string(code))
+	}
+	sort.Strings(codes)
+	exponents := make([]int, len(codes))
+	for i, code := range codes {
+		exponents[i] = currencyExponents[Currency(code)]
+	}
+	return codes, exponents
+}
+
+// Money - сумма в минорных единицах валюты (копейках, центах). Все расчеты идут
+// в целых минорных единицах, чтобы не терять деньги на округлении.
+// В JSON сумма передается десятичной строкой: {"amount": "399.99", "currency": "RUB"}.
+type Money struct {
+	Amount   int64    `json:"amount" swaggertype:"string" example:"399.99"`
+	Currency Currency `json:"currency" example:"RUB"`
+
+	// display - правила вывода итоговой суммы тенанта, задается MoneyFormat.Apply
+	display *MoneyFormat
+}
+
+func NewMoney(amount int64, currency Currency) Money {
+	return Money{Amount: amount, Currency: currency}
+}
+
+// IsZero сообщает, что сумма не задана (нет валюты).
+func (m Money) IsZero() bool {
+	return m.Currency == ""
+}
+
+// Major возвращает сумму в целых единицах валюты с округлением половины вверх.
+// Нужна только для полей, сохраненных ради совместимости.
+func (m Money) Major() int {
+	scale := int64(1)
+	for i := 0; i < m.Currency.Exponent(); i++ {
+		scale *= 10
+	}
+	if m.Amount < 0 {
+		return -int((-m.Amount + scale/2) / scale)
+	}
+	return int((m.Amount + scale/2) / scale)
+}
+
+// FormatAmount выводит сумму десятичной строкой с числом знаков валюты.
+func (m Money) FormatAmount() string {
+	exp := m.Currency.Exponent()
+	amount := m.Amount
+	sign := ""
+	if amount < 0 {
+		sign, amount = "-", -amount
+	}
+	digits := strconv.FormatInt(amount, 10)
+	if exp == 0 {
+		return sign + digits
+	}
+	if len(digits) <= exp {
+		digits = strings.Repeat("0", exp-len(digits)+1) + digits
+	}
+	return sign + digits[:len(digits)-exp] + "." + digits[len(digits)-exp:]
+}
+
+func (m Money) String() string {
+	return m.FormatAmount() + " " + string(m.Currency)
+}
+
+// ParseMoney разбирает десятичную сумму без потери точности. Знаков после
+// запятой не может быть больше, чем у валюты.
+func ParseMoney(amount string, currency Currency) (Money, error) {
+	if !currency.Valid() {
+		return Money{}, fmt.Errorf("%w: unsupported currency %q", ErrInvalidMoney, currency)
+	}
+
+	raw := amount
+	negative := strings.HasPrefix(raw, "-")
+	raw = strings.TrimPrefix(raw, "-")
+	whole, fraction, hasFraction := strings.Cut(raw, ".")
+	exp := currency.Exponent()
+	if whole == "" || (hasFraction && fraction == "") || len(fraction) > exp || !isDigits(whole) || !isDigits(fraction) {
+		return Money{}, fmt.Errorf("%w: %q, expected a decimal with at most %d fraction digits for %s", ErrInvalidMoney, amount, exp, currency)
+	}
+
+	value, err := strconv.ParseInt(whole+fraction+strings.Repeat("0", exp-len(fraction)), 10, 64)
+	if err != nil {
+		return Money{}, fmt.Errorf("%w: %q", ErrInvalidMoney, amount)
+	}
+	if negative {
+		value = -value
+	}
+	return Money{Amount: value, Currency: currency}, nil
+}
+
+func isDigits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+func (m Money) MarshalJSON() ([]byte, error) {
+	amount := m.FormatAmount()
+	if m.display != nil {
+		amount = m.display.formatAmount(m)
+	}
+	return json.Marshal(struct {
+		Amount   string   `json:"amount"`
+		Currency Currency `json:"currency"`
+	}{amount, m.Currency})
+}
+
+// UnmarshalJSON принимает объект {"amount": "399.99", "currency": "RUB"} (amount может
+// быть и числом, currency по умолчанию DefaultCurrency), а также просто число -
+// так цену передавали до появления валют.
+func (m *Money) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+	if len(data) > 0 && data[0] != '{' {
+		amount, err := jsonAmount(data)
+		if err != nil {
+			return err
+		}
+		parsed, err := ParseMoney(amount, DefaultCurrency)
+		if err != nil {
+			return err
+		}
+		*m = parsed
+		return nil
+	}
+
+	var raw struct {
+		Amount   json.RawMessage `json:"amount"`
+		Currency Currency        `json:"currency"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	if raw.Currency == "" {
+		raw.Currency = DefaultCurrency
+	}
+	amount, err := jsonAmount(raw.Amount)
+	if err != nil {
+		return err
+	}
+	parsed, err := ParseMoney(amount, raw.Currency)
+	if err != nil {
+		return err
+	}
+	*m = parsed
+	return nil
+}
+
+// jsonAmount возвращает сумму из JSON-строки или числа как есть, без float64.
+func jsonAmount(data []byte) (string, error) {
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		return s, nil
+	}
+	var n json.Number
+	if err := json.Unmarshal(data, &n); err != nil {
+		return "", fmt.Errorf("%w: amount must be a decimal string or number", ErrInvalidMoney)
+	}
+	return n.String(), nil
+}
+
+// Totals - суммы по валютам в минорных единицах. Суммы в разных валютах
+// не складываются; в JSON это список Money, упорядоченный по валюте.
+type Totals map[Currency]int64
+
+func (t Totals) Add(m Money) {
+	t[m.Currency] += m.Amount
+}
+
+// Get возвращает сумму в валюте currency (ноль, если ее нет).
+func (t Totals) Get(currency Currency) Money {
+	return Money{Amount: t[currency], Currency: currency}
+}
+
+func (t Totals) List() []Money {
+	list := make([]Money, 0, len(t))
+	for currency, amount := range t {
+		list = append(list, Money{Amount: amount, Currency: currency})
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Currency < list[j].Currency })
+	return list
+}
+
+func (t Totals) MarshalJSON() ([]byte, error) {
+	return json.Marshal(t.List())
+}
+
+// RoundingMode - правило округления половины: при переводе долей минорной единицы
+// в минорные единицы и при округлении итогов до точности вывода.
+type RoundingMode string
+
+const (
+	// RoundHalfUp округляет половину от нуля: 0.5 -> 1, 1.5 -> 2
+	RoundHalfUp RoundingMode = "half_up"
+	// RoundHalfEven - банковское округление половины к четному: 0.5 -> 0, 1.5 -> 2
+	RoundHalfEven RoundingMode = "half_even"
+)
+
+// RoundDiv делит a на положительное b и округляет частное по правилу mode;
+// пустое правило - RoundHalfUp.
+func RoundDiv(a, b int64, mode RoundingMode) int64 {
+	q, r := a/b, a%b
+	sign := int64(1)
+	if a < 0 {
+		sign, r = -1, -r
+	}
+	switch {
+	case 2*r > b:
+		q += sign
+	case 2*r == b && (mode != RoundHalfEven || q%2 != 0):
+		q += sign
+	}
+	return q
+}
+
+// AmountUnits - в каких единицах выводятся итоговые суммы.
+type AmountUnits string
+
+const (
+	// AmountMajor - десятичная строка в единицах валюты: "399.99"
+	AmountMajor AmountUnits = "major"
+	// AmountMinor - целое число минорных единиц строкой: "39999"
+	AmountMinor AmountUnits = "minor"
+)
+
+// MoneyFormat - настройка тенанта для итоговых сумм расчетов и отчетов:
+// правило округления, точность вывода и единицы. Пустые поля - значения по умолчанию.
+type MoneyFormat struct {
+	Rounding RoundingMode `json:"rounding,omitempty" binding:"omitempty,oneof=half_up half_even" example:"half_even"`
+	// Precision - знаков после запятой в итогах; не больше, чем у валюты (по умолчанию как у валюты)
+	Precision *int        `json:"precision,omitempty" binding:"omitempty,min=0,max=4" example:"0"`
+	Units     AmountUnits `json:"units,omitempty" binding:"omitempty,oneof=major minor" example:"major"`
+}
+
+// Validate проверяет поля формата; binding проверяет то же для тел запросов.
+func (f MoneyFormat) Validate() error {
+	switch {
+	case f.Rounding != "" && f.Rounding != RoundHalfUp && f.Rounding != RoundHalfEven:
+		return fmt.Errorf("unsupported rounding %q", f.Rounding)
+	case f.Units != "" && f.Units != AmountMajor && f.Units != AmountMinor:
+		return fmt.Errorf("unsupported units %q", f.Units)
+	case f.Precision != nil && (*f.Precision < 0 || *f.Precision > 4):
+		return errors.New("precision must be between 0 and 4")
+	}
+	return nil
+}
+
+// DefaultMoneyFormat - формат без настроек тенанта: половина вверх, точность
+// валюты, суммы в единицах валюты.
+var DefaultMoneyFormat = MoneyFormat{Rounding: RoundHalfUp, Units: AmountMajor}
+
+// precision - знаков после запятой при выводе суммы в валюте currency.
+func (f MoneyFormat) precision(currency Currency) int {
+	exp := currency.Exponent()
+	if f.Precision != nil && *f.Precision < exp {
+		return *f.Precision
+	}
+	return exp
+}
+
+// Apply округляет сумму до точности формата по его правилу и помечает ее,
+// чтобы в JSON она вывелась с этой точностью и в этих единицах.
+func (f MoneyFormat) Apply(m Money) Money {
+	if drop := m.Currency.Exponent() - f.precision(m.Currency); drop > 0 {
+		scale := pow10(drop)
+		m.Amount = RoundDiv(m.Amount, scale, f.Rounding) * scale
+	}
+	m.display = &f
+	return m
+}
+
+func (f MoneyFormat) formatAmount(m Money) string {
+	if f.Units == AmountMinor {
+		return strconv.FormatInt(m.Amount, 10)
+	}
+	drop := m.Currency.Exponent() - f.precision(m.Currency)
+	if drop == 0 {
+		return m.FormatAmount()
+	}
+	// Сумма уже округлена в Apply, отбрасываются только нули
+	whole, fraction, _ := strings.Cut(m.FormatAmount(), ".")
+	if keep := len(fraction) - drop; keep > 0 {
+		return whole + "." + fraction[:keep]
+	}
+	return whole
+}
+
+func pow10(n int) int64 {
+	result := int64(1)
+	for i := 0; i < n; i++ {
+		result *= 10
+	}
+	return result
+}
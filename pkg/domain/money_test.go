@@ -0,0 +1,182 @@
+package domain
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func rub(major int64) Money {
+	return NewMoney(major*100, DefaultCurrency)
+}
+
+func TestMoneyJSON(t *testing.T) {
+	cases := []struct {
+		name string
+		in   string
+		want Money
+		out  string
+	}{
+		{name: "string amount", in: `{"amount":"399.99","currency":"RUB"}`, want: NewMoney(39999, "RUB"), out: `{"amount":"399.99","currency":"RUB"}`},
+		{name: "number amount", in: `{"amount":12.5,"currency":"USD"}`, want: NewMoney(1250, "USD"), out: `{"amount":"12.50","currency":"USD"}`},
+		{name: "default currency", in: `{"amount":"400"}`, want: rub(400), out: `{"amount":"400.00","currency":"RUB"}`},
+		{name: "legacy number", in: `400`, want: rub(400), out: `{"amount":"400.00","currency":"RUB"}`},
+		{name: "zero exponent", in: `{"amount":"1500","currency":"JPY"}`, want: NewMoney(1500, "JPY"), out: `{"amount":"1500","currency":"JPY"}`},
+		{name: "cents only", in: `{"amount":"0.05"}`, want: NewMoney(5, "RUB"), out: `{"amount":"0.05","currency":"RUB"}`},
+		{name: "negative", in: `{"amount":"-1.5"}`, want: NewMoney(-150, "RUB"), out: `{"amount":"-1.50","currency":"RUB"}`},
+	}
+	for _, tc := range cases {
+		var got Money
+		if err := json.Unmarshal([]byte(tc.in), &got); err != nil {
+			t.Errorf("%s: %v", tc.name, err)
+			continue
+		}
+		if got != tc.want {
+			t.Errorf("%s: got %+v, want %+v", tc.name, got, tc.want)
+		}
+		out, err := json.Marshal(got)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(out) != tc.out {
+			t.Errorf("%s: marshaled %s, want %s", tc.name, out, tc.out)
+		}
+	}
+}
+
+func TestMoneyJSONInvalid(t *testing.T) {
+	for _, in := range []string{
+		`{"amount":"1.999"}`,
+		`{"amount":"10.5","currency":"JPY"}`,
+		`{"amount":"1","currency":"XXX"}`,
+		`{"amount":"1e3"}`,
+		`{"amount":"abc"}`,
+		`{"amount":"1."}`,
+		`true`,
+	} {
+		var m Money
+		if err := json.Unmarshal([]byte(in), &m); !errors.Is(err, ErrInvalidMoney) {
+			t.Errorf("%s: got %v, want ErrInvalidMoney", in, err)
+		}
+	}
+}
+
+func TestMoneyMajor(t *testing.T) {
+	cases := map[Money]int{
+		NewMoney(39999, "RUB"): 400,
+		NewMoney(39949, "RUB"): 399,
+		NewMoney(39950, "RUB"): 400,
+		NewMoney(1500, "JPY"):  1500,
+		NewMoney(-150, "RUB"):  -2,
+	}
+	for m, want := range cases {
+		if got := m.Major(); got != want {
+			t.Errorf("%s: got %d, want %d", m, got, want)
+		}
+	}
+}
+
+func TestTotalsJSON(t *testing.T) {
+	totals := make(Totals)
+	totals.Add(NewMoney(100, "USD"))
+	totals.Add(rub(4))
+	totals.Add(NewMoney(1, "USD"))
+
+	out, err := json.Marshal(totals)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `[{"amount":"4.00","currency":"RUB"},{"amount":"1.01","currency":"USD"}]`
+	if string(out) != want {
+		t.Errorf("got %s, want %s", out, want)
+	}
+}
+
+func TestRoundDiv(t *testing.T) {
+	cases := []struct {
+		a, b     int64
+		halfUp   int64
+		halfEven int64
+	}{
+		{a: 5, b: 10, halfUp: 1, halfEven: 0},
+		{a: 15, b: 10, halfUp: 2, halfEven: 2},
+		{a: 25, b: 10, halfUp: 3, halfEven: 2},
+		{a: 24, b: 10, halfUp: 2, halfEven: 2},
+		{a: 26, b: 10, halfUp: 3, halfEven: 3},
+		{a: -25, b: 10, halfUp: -3, halfEven: -2},
+		{a: 126, b: ProrationDenominator, halfUp: 2, halfEven: 2},
+		{a: 42, b: ProrationDenominator, halfUp: 1, halfEven: 0},
+	}
+	for _, tc := range cases {
+		if got := RoundDiv(tc.a, tc.b, RoundHalfUp); got != tc.halfUp {
+			t.Errorf("RoundDiv(%d, %d, half_up) = %d, want %d", tc.a, tc.b, got, tc.halfUp)
+		}
+		if got := RoundDiv(tc.a, tc.b, RoundHalfEven); got != tc.halfEven {
+			t.Errorf("RoundDiv(%d, %d, half_even) = %d, want %d", tc.a, tc.b, got, tc.halfEven)
+		}
+	}
+}
+
+func TestMoneyFormat(t *testing.T) {
+	zero, one := 0, 1
+	cases := []struct {
+		name   string
+		format MoneyFormat
+		in     Money
+		out    string
+	}{
+		{name: "default", format: DefaultMoneyFormat, in: NewMoney(39999, "RUB"), out: `{"amount":"399.99","currency":"RUB"}`},
+		{name: "minor units", format: MoneyFormat{Units: AmountMinor}, in: NewMoney(39999, "RUB"), out: `{"amount":"39999","currency":"RUB"}`},
+		{name: "whole rubles", format: MoneyFormat{Precision: &zero}, in: NewMoney(39950, "RUB"), out: `{"amount":"400","currency":"RUB"}`},
+		{name: "whole rubles half even", format: MoneyFormat{Precision: &zero, Rounding: RoundHalfEven}, in: NewMoney(39850, "RUB"), out: `{"amount":"398","currency":"RUB"}`},
+		{name: "one digit", format: MoneyFormat{Precision: &one}, in: NewMoney(-12345, "USD"), out: `{"amount":"-123.5","currency":"USD"}`},
+		{name: "rounded minor units", format: MoneyFormat{Precision: &zero, Units: AmountMinor}, in: NewMoney(39950, "RUB"), out: `{"amount":"40000","currency":"RUB"}`},
+		// Точность не может быть больше, чем у валюты
+		{name: "zero exponent", format: MoneyFormat{Precision: &one}, in: NewMoney(1500, "JPY"), out: `{"amount":"1500","currency":"JPY"}`},
+	}
+	for _, tc := range cases {
+		out, err := json.Marshal(tc.format.Apply(tc.in))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(out) != tc.out {
+			t.Errorf("%s: marshaled %s, want %s", tc.name, out, tc.out)
+		}
+	}
+}
+
+func TestRegisterCurrencies(t *testing.T) {
+	if err := RegisterCurrencies("xrp:6, XMR:8"); err != nil {
+		t.Fatal(err)
+	}
+	money, err := ParseMoney("0.000001", "XRP")
+	if err != nil || money.Amount != 1 {
+		t.Errorf("ParseMoney in XRP = %v, %v, want 1 minor unit", money, err)
+	}
+
+	for _, spec := range []string{"BTC", "USDT:6", "XRP:2", "JPY:2", "ETH:12", "ETH:x"} {
+		if err := RegisterCurrencies(spec); err == nil {
+			t.Errorf("RegisterCurrencies(%q) succeeded, want error", spec)
+		}
+	}
+}
+
+func TestUpdatePinnedRatesRequestValidate(t *testing.T) {
+	req := UpdatePinnedRatesRequest{Values: map[Currency]string{"USD": "92.5"}}
+	if err := req.Validate(); err != nil || req.Base != DefaultCurrency {
+		t.Errorf("Validate() = %v with base %q, want nil with %s", err, req.Base, DefaultCurrency)
+	}
+
+	for _, values := range []map[Currency]string{
+		{"USD": "0"},
+		{"USD": "-1"},
+		{"USD": "1e3"},
+		{"RUB": "1"},
+		{"ZZZ": "1"},
+	} {
+		req := UpdatePinnedRatesRequest{Values: values}
+		if err := req.Validate(); err == nil {
+			t.Errorf("Validate(%v) succeeded, want error", values)
+		}
+	}
+}
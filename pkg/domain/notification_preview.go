@@ -0,0 +1,44 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// NotificationPreviewRequest - какое уведомление и для кого показать.
+type NotificationPreviewRequest struct {
+	Type   string    `json:"type" binding:"required,oneof=spend.weekly_change spend.monthly_change budget.warning budget.exceeded" example:"budget.warning"`
+	UserID uuid.UUID `json:"user_id" binding:"required" example:"60601fee-2bf1-4721-ae6f-7636e79a0cba"`
+}
+
+// NotificationPreview - уведомления, которые задача планировщика отправила бы
+// пользователю на реальных данных. Reasons объясняет, почему отправлять нечего.
+type NotificationPreview struct {
+	Type      string    `json:"type" example:"budget.warning"`
+	UserID    uuid.UUID `json:"user_id" example:"60601fee-2bf1-4721-ae6f-7636e79a0cba"`
+	At        time.Time `json:"at"`
+	WouldSend bool      `json:"would_send" example:"true"`
+	// Notifications - все кандидаты, в том числе те, что не прошли порог
+	Notifications []PreviewedNotification `json:"notifications"`
+	Reasons       []string                `json:"reasons"`
+}
+
+// PreviewedNotification - событие в том виде, в каком его получил бы потребитель.
+// Reason заполнен, если событие не было бы отправлено.
+type PreviewedNotification struct {
+	Event  interface{} `json:"event" swaggertype:"object"`
+	Send   bool        `json:"send" example:"true"`
+	Reason string      `json:"reason,omitempty" example:"budget is ok, not warning"`
+}
+
+// Summarize выставляет WouldSend: отправлено было бы хотя бы одно событие.
+func (p *NotificationPreview) Summarize() {
+	p.WouldSend = false
+	for _, notification := range p.Notifications {
+		if notification.Send {
+			p.WouldSend = true
+			return
+		}
+	}
+}
@@ -0,0 +1,193 @@
+package domain
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// NudgeKind - правило, по которому администратору выдана подсказка.
+type NudgeKind string
+
+const (
+	// NudgeRisingChurn - у тенанта за месяц отменили заметно больше подписок, чем за предыдущий
+	NudgeRisingChurn NudgeKind = "rising_churn"
+	// NudgeIrregularData - у пользователя подписки с подозрительными данными
+	NudgeIrregularData NudgeKind = "irregular_data"
+)
+
+var NudgeKinds = []NudgeKind{NudgeRisingChurn, NudgeIrregularData}
+
+// Проблемы данных подписки для правила irregular_data.
+const (
+	IssueZeroPrice   = "zero_price"
+	IssueFutureStart = "future_start"
+)
+
+// Nudge - подсказка администратору. Key определяет подсказку однозначно (правило,
+// тенант, пользователь, месяц): повторная проверка не создает дубль, в том числе
+// после того, как подсказку скрыли.
+type Nudge struct {
+	ID       uuid.UUID `json:"id" example:"3f1c2b7a-9d8e-4f6a-b5c4-1e2d3a4b5c6d"`
+	Key      string    `json:"key" example:"rising_churn:acme:10-2025"`
+	Kind     NudgeKind `json:"kind" example:"rising_churn"`
+	TenantID *string   `json:"tenant_id,omitempty" example:"acme"`
+	// UserID есть у подсказок о конкретном пользователе
+	UserID  *uuid.UUID `json:"user_id,omitempty" example:"60601fee-2bf1-4721-ae6f-7636e79a0cba"`
+	Message string     `json:"message" example:"12 cancellations in 10-2025, up from 4 in 09-2025"`
+	// Metrics - значения, на которых сработало правило
+	Metrics     map[string]float64 `json:"metrics"`
+	CreatedAt   time.Time          `json:"created_at" example:"2025-10-23T15:04:05Z"`
+	DismissedAt *time.Time         `json:"dismissed_at,omitempty" example:"2025-10-24T09:00:00Z"`
+}
+
+type ListNudgesQuery struct {
+	Kind NudgeKind `form:"kind" binding:"omitempty,oneof=rising_churn irregular_data" example:"rising_churn"`
+	// IncludeDismissed добавляет скрытые подсказки
+	IncludeDismissed bool `form:"include_dismissed" example:"false"`
+}
+
+// NudgeRules - включенные правила и их пороги.
+type NudgeRules struct {
+	Enabled []NudgeKind
+	// ChurnMinCancellations - меньше отмен за месяц не считается ростом оттока
+	ChurnMinCancellations int
+	// ChurnRisePercent - на сколько процентов отмен должно стать больше, чем в прошлом месяце
+	ChurnRisePercent int
+	// FutureStartMonths - насколько вперед start_date считается ошибкой ввода
+	FutureStartMonths int
+}
+
+// ParseNudgeKinds разбирает список правил через запятую.
+func ParseNudgeKinds(list string) ([]NudgeKind, error) {
+	kinds := make([]NudgeKind, 0)
+	for _, part := range strings.Split(list, ",") {
+		kind := NudgeKind(strings.TrimSpace(part))
+		if kind == "" {
+			continue
+		}
+		if !kind.Valid() {
+			return nil, fmt.Errorf("unknown nudge rule %q", kind)
+		}
+		kinds = append(kinds, kind)
+	}
+	return kinds, nil
+}
+
+func (k NudgeKind) Valid() bool {
+	for _, kind := range NudgeKinds {
+		if k == kind {
+			return true
+		}
+	}
+	return false
+}
+
+func (r NudgeRules) Has(kind NudgeKind) bool {
+	for _, enabled := range r.Enabled {
+		if enabled == kind {
+			return true
+		}
+	}
+	return false
+}
+
+// nudgeScope - часть ключа для тенанта; без тенанта - основная база.
+func nudgeScope(tenantID *string) string {
+	if tenantID == nil {
+		return "default"
+	}
+	return *tenantID
+}
+
+// CountCancellations считает отмены, вступившие в силу в месяце month.
+func CountCancellations(changes []*StatusChange, month string) int {
+	count := 0
+	for _, change := range changes {
+		if change.Status == StatusCancelled && change.EffectiveFrom == month {
+			count++
+		}
+	}
+	return count
+}
+
+// ChurnNudge возвращает подсказку, если отмен в month не меньше ChurnMinCancellations
+// и на ChurnRisePercent больше, чем в предыдущем месяце; иначе nil.
+func (r NudgeRules) ChurnNudge(tenantID *string, month, previousMonth string, current, previous int) *Nudge {
+	if current < r.ChurnMinCancellations || current*100 < previous*(100+r.ChurnRisePercent) {
+		return nil
+	}
+	return &Nudge{
+		Key:      fmt.Sprintf("%s:%s:%s", NudgeRisingChurn, nudgeScope(tenantID), month),
+		Kind:     NudgeRisingChurn,
+		TenantID: tenantID,
+		Message:  fmt.Sprintf("%d cancellations in %s, up from %d in %s", current, month, previous, previousMonth),
+		Metrics: map[string]float64{
+			"cancellations":          float64(current),
+			"previous_cancellations": float64(previous),
+		},
+	}
+}
+
+// SubscriptionIssues возвращает проблемы данных подписки на месяц month.
+func (r NudgeRules) SubscriptionIssues(sub *Subscription, month time.Time) ([]string, error) {
+	issues := make([]string, 0)
+	if sub.Price.Amount == 0 {
+		issues = append(issues, IssueZeroPrice)
+	}
+	start, err := ParsePeriod(sub.StartDate)
+	if err != nil {
+		return nil, err
+	}
+	if start.After(month.AddDate(0, r.FutureStartMonths, 0)) {
+		issues = append(issues, IssueFutureStart)
+	}
+	return issues, nil
+}
+
+// IrregularDataNudges группирует проблемы подписок по пользователям: одна подсказка
+// на пользователя в месяц, в Metrics - число подписок с каждой проблемой.
+func (r NudgeRules) IrregularDataNudges(tenantID *string, subs []*Subscription, month time.Time) ([]*Nudge, error) {
+	period := FormatPeriod(month)
+	byUser := make(map[uuid.UUID]*Nudge)
+	nudges := make([]*Nudge, 0)
+	for _, sub := range subs {
+		issues, err := r.SubscriptionIssues(sub, month)
+		if err != nil {
+			return nil, err
+		}
+		if len(issues) == 0 {
+			continue
+		}
+
+		nudge, ok := byUser[sub.UserID]
+		if !ok {
+			userID := sub.UserID
+			nudge = &Nudge{
+				Key:      fmt.Sprintf("%s:%s:%s:%s", NudgeIrregularData, nudgeScope(tenantID), userID, period),
+				Kind:     NudgeIrregularData,
+				TenantID: tenantID,
+				UserID:   &userID,
+				Metrics:  map[string]float64{},
+			}
+			byUser[sub.UserID] = nudge
+			nudges = append(nudges, nudge)
+		}
+		for _, issue := range issues {
+			nudge.Metrics[issue]++
+		}
+	}
+
+	for _, nudge := range nudges {
+		parts := make([]string, 0, len(nudge.Metrics))
+		for _, issue := range []string{IssueZeroPrice, IssueFutureStart} {
+			if n := nudge.Metrics[issue]; n > 0 {
+				parts = append(parts, fmt.Sprintf("%s: %d", issue, int(n)))
+			}
+		}
+		nudge.Message = "subscriptions with irregular data (" + strings.Join(parts, ", ") + ")"
+	}
+	return nudges, nil
+}
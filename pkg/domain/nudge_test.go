@@ -0,0 +1,60 @@
+package domain
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestNudgeRules(t *testing.T) {
+	rules := NudgeRules{Enabled: NudgeKinds, ChurnMinCancellations: 3, ChurnRisePercent: 50, FutureStartMonths: 12}
+	tenant := "acme"
+
+	churn := []struct {
+		name              string
+		current, previous int
+		want              bool
+	}{
+		{name: "below minimum", current: 2, previous: 0, want: false},
+		{name: "from zero", current: 3, previous: 0, want: true},
+		{name: "exact rise", current: 6, previous: 4, want: true},
+		{name: "small rise", current: 5, previous: 4, want: false},
+		{name: "falling", current: 3, previous: 10, want: false},
+	}
+	for _, tt := range churn {
+		t.Run(tt.name, func(t *testing.T) {
+			nudge := rules.ChurnNudge(&tenant, "10-2025", "09-2025", tt.current, tt.previous)
+			if (nudge != nil) != tt.want {
+				t.Fatalf("nudge = %+v, want %v", nudge, tt.want)
+			}
+			if nudge != nil && nudge.Key != "rising_churn:acme:10-2025" {
+				t.Errorf("key = %s", nudge.Key)
+			}
+		})
+	}
+
+	month := time.Date(2025, 10, 1, 0, 0, 0, 0, time.UTC)
+	alice, bob := uuid.New(), uuid.New()
+	subs := []*Subscription{
+		{UserID: alice, Price: NewMoney(0, DefaultCurrency), StartDate: "01-2025"},
+		{UserID: alice, Price: NewMoney(29900, DefaultCurrency), StartDate: "01-2027"},
+		{UserID: alice, Price: NewMoney(0, DefaultCurrency), StartDate: "02-2025"},
+		// Год вперед - еще нормально
+		{UserID: bob, Price: NewMoney(29900, DefaultCurrency), StartDate: "10-2026"},
+	}
+	nudges, err := rules.IrregularDataNudges(nil, subs, month)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(nudges) != 1 {
+		t.Fatalf("got %d nudges, want 1", len(nudges))
+	}
+	nudge := nudges[0]
+	if *nudge.UserID != alice || nudge.Metrics[IssueZeroPrice] != 2 || nudge.Metrics[IssueFutureStart] != 1 {
+		t.Errorf("nudge = %+v", nudge)
+	}
+	if nudge.Message != "subscriptions with irregular data (zero_price: 2, future_start: 1)" {
+		t.Errorf("message = %q", nudge.Message)
+	}
+}
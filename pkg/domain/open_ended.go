@@ -0,0 +1,84 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// OpenEndedTotal - до какого месяца бессрочные подписки входят в расчет стоимости.
+type OpenEndedTotal string
+
+const (
+	// OpenEndedToPeriodEnd - до конца запрошенного периода, в том числе будущие месяцы
+	OpenEndedToPeriodEnd OpenEndedTotal = "period_end"
+	// OpenEndedToCurrentMonth - не дальше текущего месяца: будущие месяцы не считаются
+	OpenEndedToCurrentMonth OpenEndedTotal = "current_month"
+)
+
+// OpenEndedPolicy - настройка тенанта: как считать подписки без end_date.
+// Пустые поля - значения по умолчанию.
+type OpenEndedPolicy struct {
+	// Total - для расчета стоимости, по умолчанию period_end
+	Total OpenEndedTotal `json:"total,omitempty" binding:"omitempty,oneof=period_end current_month" example:"current_month"`
+	// ForecastMonths - горизонт помесячной разбивки: сколько месяцев после текущего
+	// бессрочная подписка считается продленной; без значения - бессрочно
+	ForecastMonths *int `json:"forecast_months,omitempty" binding:"omitempty,min=0,max=1200" example:"12"`
+}
+
+// Validate проверяет поля настройки; binding проверяет то же для тел запросов.
+func (p OpenEndedPolicy) Validate() error {
+	switch {
+	case p.Total != "" && p.Total != OpenEndedToPeriodEnd && p.Total != OpenEndedToCurrentMonth:
+		return fmt.Errorf("unsupported total %q", p.Total)
+	case p.ForecastMonths != nil && (*p.ForecastMonths < 0 || *p.ForecastMonths > 1200):
+		return errors.New("forecast_months must be between 0 and 1200")
+	}
+	return nil
+}
+
+// TotalUntil - последний месяц (MM-YYYY), за который бессрочные подписки входят
+// в расчет стоимости на момент now; пусто - до конца периода.
+func (p OpenEndedPolicy) TotalUntil(now time.Time) string {
+	if p.Total == OpenEndedToCurrentMonth {
+		return FormatPeriod(now)
+	}
+	return ""
+}
+
+// ForecastUntil - последний месяц (MM-YYYY), за который бессрочные подписки входят
+// в помесячную разбивку на момент now; пусто - без ограничения.
+func (p OpenEndedPolicy) ForecastUntil(now time.Time) string {
+	if p.ForecastMonths == nil {
+		return ""
+	}
+	month := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	return FormatPeriod(month.AddDate(0, *p.ForecastMonths, 0))
+}
+
+// OpenEndedEnd - месяц, которым заканчиваются бессрочные подписки в расчете req:
+// OpenEndedUntil, если он задан, иначе конец периода.
+func (r CalculateTotalRequest) OpenEndedEnd() string {
+	if r.OpenEndedUntil != "" {
+		return r.OpenEndedUntil
+	}
+	return r.EndPeriod
+}
+
+// CapOpenEnded возвращает подписки, в которых бессрочные заканчиваются месяцем until;
+// исходные подписки не меняются. С пустым until возвращает subs как есть.
+func CapOpenEnded(subs []*Subscription, until string) []*Subscription {
+	if until == "" {
+		return subs
+	}
+	capped := make([]*Subscription, len(subs))
+	for i, sub := range subs {
+		if sub.EndDate == nil {
+			c := *sub
+			c.EndDate = &until
+			sub = &c
+		}
+		capped[i] = sub
+	}
+	return capped
+}
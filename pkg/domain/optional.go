@@ -0,0 +1,30 @@
+package domain
+
+import (
+	"bytes"
+	"encoding/json"
+)
+
+// Optional различает в JSON отсутствующее поле, явный null и значение.
+// Нужен для PATCH, где null означает "очистить", а отсутствие - "не трогать".
+type Optional[T any] struct {
+	Set   bool
+	Null  bool
+	Value T
+}
+
+func (o *Optional[T]) UnmarshalJSON(data []byte) error {
+	o.Set = true
+	if bytes.Equal(bytes.TrimSpace(data), []byte("null")) {
+		o.Null = true
+		return nil
+	}
+	return json.Unmarshal(data, &o.Value)
+}
+
+func (o Optional[T]) MarshalJSON() ([]byte, error) {
+	if !o.Set || o.Null {
+		return []byte("null"), nil
+	}
+	return json.Marshal(o.Value)
+}
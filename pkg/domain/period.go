@@ -0,0 +1,35 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// PeriodLayout - формат месяца в API и БД: MM-YYYY.
+const PeriodLayout = "01-2006"
+
+var ErrInvalidPeriod = errors.New("invalid period, expected MM-YYYY")
+
+// ParsePeriod разбирает строку MM-YYYY в первый день месяца (UTC).
+func ParsePeriod(value string) (time.Time, error) {
+	if len(value) != len(PeriodLayout) {
+		return time.Time{}, fmt.Errorf("%w: %q", ErrInvalidPeriod, value)
+	}
+
+	t, err := time.Parse(PeriodLayout, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%w: %q", ErrInvalidPeriod, value)
+	}
+
+	return t, nil
+}
+
+func FormatPeriod(t time.Time) string {
+	return t.Format(PeriodLayout)
+}
+
+// MonthsBetween возвращает количество месяцев в отрезке [from, to] включительно.
+func MonthsBetween(from, to time.Time) int {
+	return (to.Year()-from.Year())*12 + int(to.Month()) - int(from.Month()) + 1
+}
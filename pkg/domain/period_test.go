@@ -0,0 +1,25 @@
+package domain
+
+import "testing"
+
+func FuzzParsePeriod(f *testing.F) {
+	for _, seed := range []string{"07-2025", "12-1999", "13-2025", "00-2025", "7-2025", "07-25", "", "07-2025' OR 1=1--"} {
+		f.Add(seed)
+	}
+
+	f.Fuzz(func(t *testing.T, value string) {
+		parsed, err := ParsePeriod(value)
+		if err != nil {
+			return
+		}
+		if parsed.Day() != 1 {
+			t.Fatalf("ParsePeriod(%q) = %v, expected first day of month", value, parsed)
+		}
+		if got := FormatPeriod(parsed); got != value {
+			t.Fatalf("round trip mismatch: %q -> %q", value, got)
+		}
+		if MonthsBetween(parsed, parsed) != 1 {
+			t.Fatalf("MonthsBetween(%v, %v) != 1", parsed, parsed)
+		}
+	})
+}
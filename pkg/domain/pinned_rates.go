@@ -0,0 +1,73 @@
+package domain
+
+import (
+	"fmt"
+	"math/big"
+	"regexp"
+	"time"
+)
+
+// PinnedRates - курсы, закрепленные тенантом вручную: Values[c] - стоимость единицы
+// валюты c в Base десятичной строкой. Закрепленный курс заменяет курс провайдера,
+// так тенант считает подписки в валютах, которых нет у банка (криптовалюты, свои
+// валюты). Version растет при каждом изменении и попадает в source пересчета.
+type PinnedRates struct {
+	Base      Currency            `json:"base" example:"RUB"`
+	Values    map[Currency]string `json:"values"`
+	Version   int                 `json:"version" example:"3"`
+	UpdatedAt time.Time           `json:"updated_at" example:"2025-10-23T15:04:05Z"`
+}
+
+// UpdatePinnedRatesRequest полностью заменяет закрепленные курсы тенанта.
+// Без base курсы задаются в DefaultCurrency; пустой values снимает закрепление.
+type UpdatePinnedRatesRequest struct {
+	Base   Currency            `json:"base,omitempty" example:"RUB"`
+	Values map[Currency]string `json:"values"`
+}
+
+var rateDecimalPattern = regexp.MustCompile(`^[0-9]+(\.[0-9]+)?$`)
+
+// ParseRate разбирает курс - положительную десятичную дробь без потери точности.
+func ParseRate(value string) (*big.Rat, error) {
+	rate, ok := new(big.Rat).SetString(value)
+	if !rateDecimalPattern.MatchString(value) || !ok || rate.Sign() <= 0 {
+		return nil, fmt.Errorf("invalid rate %q, expected a positive decimal", value)
+	}
+	return rate, nil
+}
+
+// Validate проверяет валюты и курсы и подставляет базовую валюту по умолчанию.
+func (r *UpdatePinnedRatesRequest) Validate() error {
+	if r.Base == "" {
+		r.Base = DefaultCurrency
+	}
+	if !r.Base.Valid() {
+		return fmt.Errorf("base: unsupported currency %q", r.Base)
+	}
+	for currency, value := range r.Values {
+		if !currency.Valid() {
+			return fmt.Errorf("values: unsupported currency %q", currency)
+		}
+		if currency == r.Base {
+			return fmt.Errorf("values: %s is the base currency", currency)
+		}
+		if _, err := ParseRate(value); err != nil {
+			return fmt.Errorf("values: %s: %v", currency, err)
+		}
+	}
+	return nil
+}
+
+// Rates возвращает разобранные курсы. Курсы проверены при сохранении, поэтому
+// ошибка означает поврежденную настройку.
+func (p *PinnedRates) Rates() (map[Currency]*big.Rat, error) {
+	values := make(map[Currency]*big.Rat, len(p.Values))
+	for currency, value := range p.Values {
+		rate, err := ParseRate(value)
+		if err != nil {
+			return nil, fmt.Errorf("pinned rate %s: %w", currency, err)
+		}
+		values[currency] = rate
+	}
+	return values, nil
+}
@@ -0,0 +1,118 @@
+package domain
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// PriceChange - запись истории цены: с ChangedAt подписка стоит Price за BillingCycle.
+type PriceChange struct {
+	SubscriptionID uuid.UUID
+	Price          Money
+	BillingCycle   BillingCycle
+	ChangedAt      time.Time
+}
+
+// PricePoint - точка ступенчатого ряда: цена действует с At до следующей точки.
+type PricePoint struct {
+	At           time.Time    `json:"at" example:"2025-10-23T15:04:05Z"`
+	Price        Money        `json:"price"`
+	BillingCycle BillingCycle `json:"billing_cycle" example:"monthly"`
+	// MonthlyPrice - цена, приведенная к месяцу, чтобы точки с разными циклами были сравнимы
+	MonthlyPrice Money `json:"monthly_price"`
+}
+
+// PriceAnnotation - отметка скидки на графике: действует с From до To (не включая);
+// To нет у бессрочной скидки.
+type PriceAnnotation struct {
+	Kind         string       `json:"kind" example:"discount"`
+	DiscountID   uuid.UUID    `json:"discount_id" example:"5f0c2a8e-3b1d-4c6e-9a7f-2d8b1e4c6a90"`
+	From         time.Time    `json:"from" example:"2025-09-01T00:00:00Z"`
+	To           *time.Time   `json:"to,omitempty" example:"2025-12-01T00:00:00Z"`
+	Label        string       `json:"label" example:"AUTUMN20: -20%"`
+	DiscountKind DiscountKind `json:"discount_kind" example:"percent"`
+}
+
+type PriceHistoryResponse struct {
+	SubscriptionID uuid.UUID         `json:"subscription_id" example:"123e4567-e89b-12d3-a456-426614174000"`
+	Points         []PricePoint      `json:"points"`
+	Annotations    []PriceAnnotation `json:"annotations"`
+}
+
+// NewPriceHistory строит ряд цен по истории изменений и отметки по скидкам подписки.
+// Подряд идущие записи с той же ценой и циклом схлопываются в одну точку.
+func NewPriceHistory(subscriptionID uuid.UUID, changes []*PriceChange, discounts []*Discount) (*PriceHistoryResponse, error) {
+	sorted := make([]*PriceChange, len(changes))
+	copy(sorted, changes)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].ChangedAt.Before(sorted[j].ChangedAt)
+	})
+
+	resp := &PriceHistoryResponse{
+		SubscriptionID: subscriptionID,
+		Points:         make([]PricePoint, 0, len(sorted)),
+		Annotations:    make([]PriceAnnotation, 0, len(discounts)),
+	}
+	for _, change := range sorted {
+		cycle := change.BillingCycle.OrDefault()
+		if n := len(resp.Points); n > 0 {
+			last := resp.Points[n-1]
+			if last.Price.Amount == change.Price.Amount && last.Price.Currency == change.Price.Currency && last.BillingCycle == cycle {
+				continue
+			}
+		}
+		resp.Points = append(resp.Points, PricePoint{
+			At:           change.ChangedAt,
+			Price:        change.Price,
+			BillingCycle: cycle,
+			MonthlyPrice: NewMoney(RoundProrated(MonthlyRate(change.Price.Amount, cycle)), change.Price.Currency),
+		})
+	}
+
+	for _, discount := range discounts {
+		annotation, err := discountAnnotation(discount)
+		if err != nil {
+			return nil, err
+		}
+		resp.Annotations = append(resp.Annotations, annotation)
+	}
+	sort.SliceStable(resp.Annotations, func(i, j int) bool {
+		return resp.Annotations[i].From.Before(resp.Annotations[j].From)
+	})
+	return resp, nil
+}
+
+func discountAnnotation(discount *Discount) (PriceAnnotation, error) {
+	from, err := ParsePeriod(discount.StartMonth)
+	if err != nil {
+		return PriceAnnotation{}, err
+	}
+	annotation := PriceAnnotation{
+		Kind:         "discount",
+		DiscountID:   discount.ID,
+		From:         from,
+		DiscountKind: discount.Kind,
+	}
+	if discount.EndMonth != nil {
+		end, err := ParsePeriod(*discount.EndMonth)
+		if err != nil {
+			return PriceAnnotation{}, err
+		}
+		to := end.AddDate(0, 1, 0)
+		annotation.To = &to
+	}
+
+	switch {
+	case discount.Kind == DiscountPercent && discount.Percent != nil:
+		annotation.Label = fmt.Sprintf("-%d%%", *discount.Percent)
+	case discount.Amount != nil:
+		annotation.Label = fmt.Sprintf("-%s %s", discount.Amount.FormatAmount(), discount.Amount.Currency)
+	}
+	if discount.Code != nil {
+		annotation.Label = *discount.Code + ": " + annotation.Label
+	}
+	return annotation, nil
+}
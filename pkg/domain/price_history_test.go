@@ -0,0 +1,43 @@
+package domain
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestNewPriceHistory(t *testing.T) {
+	id := uuid.New()
+	at := time.Date(2025, 1, 15, 12, 0, 0, 0, time.UTC)
+	change := func(days int, amount int64, cycle BillingCycle) *PriceChange {
+		return &PriceChange{SubscriptionID: id, Price: NewMoney(amount, DefaultCurrency), BillingCycle: cycle, ChangedAt: at.AddDate(0, 0, days)}
+	}
+	end := "10-2025"
+	percent := 20
+
+	history, err := NewPriceHistory(id, []*PriceChange{
+		change(60, 1200000, CycleYearly),
+		change(0, 99900, ""),
+		change(30, 99900, CycleMonthly),
+	}, []*Discount{{Kind: DiscountPercent, Percent: &percent, StartMonth: "08-2025", EndMonth: &end}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Запись без смены цены и цикла не дает новой точки
+	if len(history.Points) != 2 {
+		t.Fatalf("got %d points, want 2", len(history.Points))
+	}
+	if first := history.Points[0]; !first.At.Equal(at) || first.BillingCycle != CycleMonthly || first.MonthlyPrice.Amount != 99900 {
+		t.Errorf("first point = %+v", first)
+	}
+	if second := history.Points[1]; second.BillingCycle != CycleYearly || second.MonthlyPrice.Amount != 100000 {
+		t.Errorf("yearly point = %+v", second)
+	}
+
+	annotation := history.Annotations[0]
+	if annotation.Label != "-20%" || annotation.To == nil || !annotation.To.Equal(time.Date(2025, 11, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("annotation = %+v", annotation)
+	}
+}
@@ -0,0 +1,36 @@
+package domain
+
+// ProbeStatus - результат проверки зависимости и готовности реплики.
+type ProbeStatus string
+
+const (
+	ProbeOK   ProbeStatus = "ok"
+	ProbeFail ProbeStatus = "fail"
+)
+
+// DependencyStatus - состояние одной зависимости. Required = false: зависимость
+// попадает в ответ, но ее отказ не снимает реплику с трафика.
+type DependencyStatus struct {
+	Name     string      `json:"name" example:"database"`
+	Status   ProbeStatus `json:"status" example:"ok"`
+	Required bool        `json:"required" example:"true"`
+	Error    string      `json:"error,omitempty" example:"context deadline exceeded"`
+}
+
+// Readiness - ответ /readyz: status = fail, если отказала хотя бы одна обязательная
+// зависимость.
+type Readiness struct {
+	Status       ProbeStatus        `json:"status" example:"ok"`
+	Dependencies []DependencyStatus `json:"dependencies"`
+}
+
+// NewReadiness сводит состояния зависимостей в готовность реплики.
+func NewReadiness(dependencies []DependencyStatus) *Readiness {
+	readiness := &Readiness{Status: ProbeOK, Dependencies: dependencies}
+	for _, dependency := range dependencies {
+		if dependency.Required && dependency.Status != ProbeOK {
+			readiness.Status = ProbeFail
+		}
+	}
+	return readiness
+}
@@ -0,0 +1,41 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// SubscriptionRenewal - данные события subscription.renewed.
+type SubscriptionRenewal struct {
+	SubscriptionID uuid.UUID `json:"subscription_id"`
+	UserID         uuid.UUID `json:"user_id"`
+	ServiceName    string    `json:"service_name"`
+	// Price - цена в целых единицах валюты, оставлена для совместимости
+	Price           int    `json:"price"`
+	PriceAmount     Money  `json:"price_amount"`
+	PreviousEndDate string `json:"previous_end_date" example:"09-2025"`
+	EndDate         string `json:"end_date" example:"10-2025"`
+}
+
+// RenewalEndDates возвращает последовательность новых end_date, которые подписка
+// с автопродлением должна получить к моменту now. Подписка продлевается на месяц
+// в последний день месяца end_date; пропущенные месяцы (end_date раньше текущего)
+// продлеваются догоняющим образом.
+func RenewalEndDates(endDate string, now time.Time) ([]string, error) {
+	end, err := ParsePeriod(endDate)
+	if err != nil {
+		return nil, err
+	}
+
+	now = now.UTC()
+	month := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	lastDay := now.AddDate(0, 0, 1).Month() != now.Month()
+
+	var renewals []string
+	for end.Before(month) || (end.Equal(month) && lastDay) {
+		end = end.AddDate(0, 1, 0)
+		renewals = append(renewals, FormatPeriod(end))
+	}
+	return renewals, nil
+}
@@ -0,0 +1,166 @@
+package domain
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// MigrationPhase - фаза перехода колонок подписок на новый тип без остановки:
+// off - работают только старые колонки; dual_write - пишутся обе, читаются
+// старые; dual_read - пишутся обе, читаются новые, а в строках, которые еще не
+// заполнены, старые. Переключение (удаление старых колонок) делается миграцией,
+// когда сверка не находит расхождений.
+type MigrationPhase string
+
+const (
+	MigrationOff       MigrationPhase = "off"
+	MigrationDualWrite MigrationPhase = "dual_write"
+	MigrationDualRead  MigrationPhase = "dual_read"
+)
+
+func ParseMigrationPhase(value string) (MigrationPhase, error) {
+	switch phase := MigrationPhase(value); phase {
+	case MigrationOff, MigrationDualWrite, MigrationDualRead:
+		return phase, nil
+	default:
+		return "", fmt.Errorf("unknown migration phase %q, expected off, dual_write or dual_read", value)
+	}
+}
+
+// WritesNew сообщает, что новые колонки пишутся вместе со старыми.
+func (p MigrationPhase) WritesNew() bool {
+	return p == MigrationDualWrite || p == MigrationDualRead
+}
+
+// ReadsNew сообщает, что значения читаются из новых колонок.
+func (p MigrationPhase) ReadsNew() bool {
+	return p == MigrationDualRead
+}
+
+// SchemaMigrations - фазы переходов колонок подписок. Dates - start_date/end_date
+// (строки MM-YYYY) в start_on/end_on (DATE), Money - price_minor (минорные единицы)
+// в price_amount (NUMERIC в единицах валюты).
+type SchemaMigrations struct {
+	Dates MigrationPhase `json:"dates" example:"dual_write"`
+	Money MigrationPhase `json:"money" example:"off"`
+}
+
+// ParseSchemaMigrations разбирает фазы переходов дат и цены.
+func ParseSchemaMigrations(dates, money string) (SchemaMigrations, error) {
+	var migrations SchemaMigrations
+	var err error
+	if migrations.Dates, err = ParseMigrationPhase(dates); err != nil {
+		return SchemaMigrations{}, fmt.Errorf("dates: %w", err)
+	}
+	if migrations.Money, err = ParseMigrationPhase(money); err != nil {
+		return SchemaMigrations{}, fmt.Errorf("money: %w", err)
+	}
+	return migrations, nil
+}
+
+// Enabled сообщает, что хотя бы один переход пишет новые колонки.
+func (m SchemaMigrations) Enabled() bool {
+	return m.Dates.WritesNew() || m.Money.WritesNew()
+}
+
+// DualColumnsRecord - старые и новые колонки подписки для сверки.
+type DualColumnsRecord struct {
+	SubscriptionID uuid.UUID
+	StartDate      string
+	EndDate        *string
+	PriceMinor     int64
+	Currency       Currency
+	StartOn        *time.Time
+	EndOn          *time.Time
+	// PriceAmount - NUMERIC текстом, чтобы не терять точность
+	PriceAmount *string
+}
+
+// DualColumnMismatch - новая колонка подписки не равна значению, вычисленному из
+// старой. Actual = null - строка еще не заполнена.
+type DualColumnMismatch struct {
+	SubscriptionID uuid.UUID `json:"subscription_id"`
+	Column         string    `json:"column" example:"end_on"`
+	Expected       *string   `json:"expected" example:"2025-12-01"`
+	Actual         *string   `json:"actual"`
+}
+
+// CheckDualColumns сравнивает новые колонки записи со старыми для переходов,
+// которые пишут новые колонки.
+func CheckDualColumns(rec DualColumnsRecord, migrations SchemaMigrations) []DualColumnMismatch {
+	var mismatches []DualColumnMismatch
+	add := func(column string, expected, actual *string) {
+		if (expected == nil) != (actual == nil) || (expected != nil && *expected != *actual) {
+			mismatches = append(mismatches, DualColumnMismatch{
+				SubscriptionID: rec.SubscriptionID,
+				Column:         column,
+				Expected:       expected,
+				Actual:         actual,
+			})
+		}
+	}
+
+	if migrations.Dates.WritesNew() {
+		add("start_on", periodDate(&rec.StartDate), formatDate(rec.StartOn))
+		add("end_on", periodDate(rec.EndDate), formatDate(rec.EndOn))
+	}
+	if migrations.Money.WritesNew() {
+		expected := NewMoney(rec.PriceMinor, rec.Currency).FormatAmount()
+		actual := rec.PriceAmount
+		if actual != nil {
+			// NUMERIC хранится с фиксированной точностью: 399.99000000 равно 399.99
+			if money, err := MoneyFromDecimal(*actual, rec.Currency); err == nil && money.Amount == rec.PriceMinor {
+				actual = &expected
+			}
+		}
+		add("price_amount", &expected, actual)
+	}
+	return mismatches
+}
+
+// MoneyFromDecimal разбирает сумму из NUMERIC-колонки: незначащие нули после
+// запятой отбрасываются, значащих не может быть больше, чем у валюты.
+func MoneyFromDecimal(value string, currency Currency) (Money, error) {
+	if strings.Contains(value, ".") {
+		value = strings.TrimSuffix(strings.TrimRight(value, "0"), ".")
+	}
+	return ParseMoney(value, currency)
+}
+
+// periodDate переводит месяц MM-YYYY в дату первого числа (YYYY-MM-DD); неразбираемый
+// месяц сравнивается как есть и всегда дает расхождение.
+func periodDate(period *string) *string {
+	if period == nil {
+		return nil
+	}
+	month, err := ParsePeriod(*period)
+	if err != nil {
+		return period
+	}
+	return formatDate(&month)
+}
+
+func formatDate(date *time.Time) *string {
+	if date == nil {
+		return nil
+	}
+	formatted := date.Format(time.DateOnly)
+	return &formatted
+}
+
+// SchemaMigrationReport - результат сверки новых колонок со старыми.
+// ByColumn - число расхождений по колонкам, Mismatches - первые из них.
+// *Ready = true: переход пишет новые колонки и расхождений нет, можно переключаться.
+type SchemaMigrationReport struct {
+	Phases     SchemaMigrations     `json:"phases"`
+	Scanned    int                  `json:"scanned" example:"1200"`
+	ByColumn   map[string]int       `json:"by_column"`
+	Mismatches []DualColumnMismatch `json:"mismatches"`
+	// Backfilled - сколько подписок заполнено заново; только в ответе backfill
+	Backfilled int  `json:"backfilled" example:"0"`
+	DatesReady bool `json:"dates_ready" example:"true"`
+	MoneyReady bool `json:"money_ready" example:"false"`
+}
@@ -0,0 +1,78 @@
+package domain
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestParseSchemaMigrations(t *testing.T) {
+	migrations, err := ParseSchemaMigrations("dual_write", "off")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !migrations.Dates.WritesNew() || migrations.Dates.ReadsNew() || migrations.Money.WritesNew() || !migrations.Enabled() {
+		t.Errorf("migrations = %+v", migrations)
+	}
+	if _, err := ParseSchemaMigrations("off", "read_new"); err == nil {
+		t.Error("unknown phase accepted")
+	}
+}
+
+func TestCheckDualColumns(t *testing.T) {
+	start := time.Date(2025, time.July, 1, 0, 0, 0, 0, time.UTC)
+	endDate := "12-2025"
+	amount := "399.99000000"
+	rec := DualColumnsRecord{
+		SubscriptionID: uuid.New(),
+		StartDate:      "07-2025",
+		EndDate:        &endDate,
+		PriceMinor:     39999,
+		Currency:       "RUB",
+		StartOn:        &start,
+		PriceAmount:    &amount,
+	}
+	both := SchemaMigrations{Dates: MigrationDualWrite, Money: MigrationDualRead}
+
+	mismatches := CheckDualColumns(rec, both)
+	if len(mismatches) != 1 || mismatches[0].Column != "end_on" || *mismatches[0].Expected != "2025-12-01" || mismatches[0].Actual != nil {
+		t.Fatalf("mismatches = %+v", mismatches)
+	}
+
+	end := time.Date(2025, time.December, 1, 0, 0, 0, 0, time.UTC)
+	rec.EndOn = &end
+	if mismatches := CheckDualColumns(rec, both); len(mismatches) != 0 {
+		t.Errorf("mismatches = %+v", mismatches)
+	}
+
+	rec.PriceMinor = 49999
+	mismatches = CheckDualColumns(rec, both)
+	if len(mismatches) != 1 || mismatches[0].Column != "price_amount" || *mismatches[0].Expected != "499.99" {
+		t.Errorf("mismatches = %+v", mismatches)
+	}
+	// Переходы в фазе off не сверяются
+	if mismatches := CheckDualColumns(rec, SchemaMigrations{Dates: MigrationDualWrite, Money: MigrationOff}); len(mismatches) != 0 {
+		t.Errorf("mismatches = %+v", mismatches)
+	}
+}
+
+func TestMoneyFromDecimal(t *testing.T) {
+	tests := []struct {
+		value    string
+		currency Currency
+		want     int64
+		ok       bool
+	}{
+		{value: "399.99000000", currency: "RUB", want: 39999, ok: true},
+		{value: "100.00000000", currency: "JPY", want: 100, ok: true},
+		{value: "0", currency: "USD", want: 0, ok: true},
+		{value: "1.23400000", currency: "USD"},
+	}
+	for _, tt := range tests {
+		got, err := MoneyFromDecimal(tt.value, tt.currency)
+		if (err == nil) != tt.ok || got.Amount != tt.want {
+			t.Errorf("MoneyFromDecimal(%q, %s) = %v, %v", tt.value, tt.currency, got, err)
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package domain
+
+import (
+	"strings"
+	"unicode"
+)
+
+// SearchMatch - режим поиска q по списку подписок.
+type SearchMatch string
+
+const (
+	// SearchContains - каждое слово q встречается в названии или заметках.
+	SearchContains SearchMatch = "contains"
+	// SearchFuzzy - название похоже на q целиком не меньше порога похожести.
+	SearchFuzzy SearchMatch = "fuzzy"
+)
+
+// DefaultSimilarityThreshold - порог похожести fuzzy-поиска по умолчанию, как у pg_trgm.
+const DefaultSimilarityThreshold = 0.3
+
+// TrigramSimilarity повторяет similarity из pg_trgm: доля общих триграмм слов a и b
+// среди всех их триграмм, от 0 до 1. Регистр и знаки между словами не учитываются.
+func TrigramSimilarity(a, b string) float64 {
+	ta, tb := trigrams(a), trigrams(b)
+	if len(ta) == 0 || len(tb) == 0 {
+		return 0
+	}
+	common := 0
+	for t := range ta {
+		if tb[t] {
+			common++
+		}
+	}
+	return float64(common) / float64(len(ta)+len(tb)-common)
+}
+
+// trigrams - множество триграмм слов s; слово дополняется двумя пробелами
+// в начале и одним в конце, как в pg_trgm.
+func trigrams(s string) map[string]bool {
+	set := make(map[string]bool)
+	words := strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	for _, word := range words {
+		padded := []rune("  " + word + " ")
+		for i := 0; i+3 <= len(padded); i++ {
+			set[string(padded[i:i+3])] = true
+		}
+	}
+	return set
+}
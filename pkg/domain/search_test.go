@@ -0,0 +1,24 @@
+package domain
+
+import (
+	"math"
+	"testing"
+)
+
+func TestTrigramSimilarity(t *testing.T) {
+	cases := []struct {
+		a, b string
+		want float64
+	}{
+		// Значения совпадают с SELECT similarity(a, b) в Postgres
+		{"yanex plus", "Yandex Plus", 9.0 / 14},
+		{"Netflix", "netflix", 1},
+		{"Netflix", "Spotify", 0},
+		{"", "Netflix", 0},
+	}
+	for _, c := range cases {
+		if got := TrigramSimilarity(c.a, c.b); math.Abs(got-c.want) > 1e-9 {
+			t.Errorf("TrigramSimilarity(%q, %q) = %v, want %v", c.a, c.b, got, c.want)
+		}
+	}
+}
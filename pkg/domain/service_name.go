@@ -0,0 +1,49 @@
+package domain
+
+import (
+	"strings"
+	"time"
+)
+
+// cyrillicToLatin - упрощенная транслитерация (близкая к ГОСТ 7.79, система Б).
+// Таблица продублирована в миграции 000003 для заполнения service_key.
+var cyrillicToLatin = map[rune]string{
+	'а': "a", 'б': "b", 'в': "v", 'г': "g", 'д': "d", 'е': "e", 'ё': "e",
+	'ж': "zh", 'з': "z", 'и': "i", 'й': "y", 'к': "k", 'л': "l", 'м': "m",
+	'н': "n", 'о': "o", 'п': "p", 'р': "r", 'с': "s", 'т': "t", 'у': "u",
+	'ф': "f", 'х': "kh", 'ц': "ts", 'ч': "ch", 'ш': "sh", 'щ': "shch",
+	'ъ': "", 'ы': "y", 'ь': "", 'э': "e", 'ю': "yu", 'я': "ya",
+}
+
+// ServiceKey приводит название сервиса к ключу сопоставления: нижний регистр,
+// кириллица транслитерируется, все кроме латиницы и цифр отбрасывается.
+// "Кинопоиск" и "KinoPoisk" дают один ключ; для названий, которые
+// транслитерацией не сводятся ("Яндекс Плюс" и "Yandex Plus"), нужны алиасы.
+func ServiceKey(name string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(name) {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9':
+			b.WriteRune(r)
+		default:
+			if latin, ok := cyrillicToLatin[r]; ok {
+				b.WriteString(latin)
+			}
+		}
+	}
+	return b.String()
+}
+
+// ServiceAlias сводит вариант написания сервиса к каноническому названию.
+type ServiceAlias struct {
+	Alias        string    `json:"alias" example:"Яндекс Плюс"`
+	AliasKey     string    `json:"alias_key" example:"yandeksplyus"`
+	Canonical    string    `json:"canonical" example:"Yandex Plus"`
+	CanonicalKey string    `json:"canonical_key" example:"yandexplus"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+type UpsertServiceAliasRequest struct {
+	Alias     string `json:"alias" binding:"required" example:"Яндекс Плюс"`
+	Canonical string `json:"canonical" binding:"required" example:"Yandex Plus"`
+}
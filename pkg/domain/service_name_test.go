@@ -0,0 +1,22 @@
+package domain
+
+import "testing"
+
+func TestServiceKey(t *testing.T) {
+	cases := map[string]string{
+		"Yandex Plus":      "yandexplus",
+		"yandex-plus":      "yandexplus",
+		"Яндекс Плюс":      "yandeksplyus",
+		"Кинопоиск":        "kinopoisk",
+		"KinoPoisk HD":     "kinopoiskhd",
+		"Щука & Ёжик":      "shchukaezhik",
+		"Объявления 2024!": "obyavleniya2024",
+		"":                 "",
+	}
+
+	for name, want := range cases {
+		if got := ServiceKey(name); got != want {
+			t.Errorf("ServiceKey(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
@@ -0,0 +1,167 @@
+package domain
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SLOObjective - что измеряет цель: долю ответов без ошибок или долю быстрых ответов.
+type SLOObjective string
+
+const (
+	SLOAvailability SLOObjective = "availability"
+	SLOLatency      SLOObjective = "latency"
+)
+
+// SLO - цели одного маршрута. Route - метод и шаблон пути gin, например
+// "GET /api/v1/subscriptions/:id". Нулевая цель означает, что она не задана.
+type SLO struct {
+	Route string `json:"route" example:"GET /api/v1/subscriptions/calculate"`
+	// AvailabilityTarget - процент ответов не 5xx
+	AvailabilityTarget float64 `json:"availability_target,omitempty" example:"99.9"`
+	// LatencyThreshold - граница быстрого ответа, LatencyTarget - процент ответов не дольше нее
+	LatencyThreshold time.Duration `json:"-"`
+	LatencyTarget    float64       `json:"latency_target,omitempty" example:"99"`
+}
+
+// Target возвращает целевой процент objective; 0 - цель не задана.
+func (s SLO) Target(objective SLOObjective) float64 {
+	if objective == SLOLatency {
+		return s.LatencyTarget
+	}
+	return s.AvailabilityTarget
+}
+
+// ParseSLOs разбирает цели из строки вида
+// "GET /api/v1/subscriptions/calculate availability=99.9 latency=300ms@99; POST /api/v1/subscriptions availability=99.5".
+func ParseSLOs(spec string) ([]SLO, error) {
+	var slos []SLO
+	seen := make(map[string]bool)
+	for _, entry := range strings.Split(spec, ";") {
+		fields := strings.Fields(entry)
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) < 3 {
+			return nil, fmt.Errorf("slo %q: expected \"METHOD /path objective=...\"", strings.TrimSpace(entry))
+		}
+
+		slo := SLO{Route: strings.ToUpper(fields[0]) + " " + fields[1]}
+		if !strings.HasPrefix(fields[1], "/") {
+			return nil, fmt.Errorf("slo %q: path must start with /", slo.Route)
+		}
+		if seen[slo.Route] {
+			return nil, fmt.Errorf("slo %q: duplicate route", slo.Route)
+		}
+		seen[slo.Route] = true
+
+		for _, field := range fields[2:] {
+			name, value, _ := strings.Cut(field, "=")
+			var err error
+			switch SLOObjective(name) {
+			case SLOAvailability:
+				slo.AvailabilityTarget, err = parseSLOTarget(value)
+			case SLOLatency:
+				threshold, target, ok := strings.Cut(value, "@")
+				if !ok {
+					return nil, fmt.Errorf("slo %q: latency must look like 300ms@99", slo.Route)
+				}
+				if slo.LatencyThreshold, err = time.ParseDuration(threshold); err == nil && slo.LatencyThreshold <= 0 {
+					err = fmt.Errorf("threshold must be positive")
+				}
+				if err == nil {
+					slo.LatencyTarget, err = parseSLOTarget(target)
+				}
+			default:
+				return nil, fmt.Errorf("slo %q: unknown objective %q, expected availability or latency", slo.Route, name)
+			}
+			if err != nil {
+				return nil, fmt.Errorf("slo %q: %s: %v", slo.Route, name, err)
+			}
+		}
+		slos = append(slos, slo)
+	}
+	return slos, nil
+}
+
+func parseSLOTarget(value string) (float64, error) {
+	target, err := strconv.ParseFloat(value, 64)
+	if err != nil || target <= 0 || target >= 100 {
+		return 0, fmt.Errorf("target %q must be a percent between 0 and 100 exclusive", value)
+	}
+	return target, nil
+}
+
+// SLOAlertSeverity - срочность оповещения: быстрое сгорание бюджета ошибок будит
+// дежурного, медленное ставит задачу.
+type SLOAlertSeverity string
+
+const (
+	SLOPage   SLOAlertSeverity = "page"
+	SLOTicket SLOAlertSeverity = "ticket"
+)
+
+// BurnRateRule - оповещение срабатывает, когда скорость сгорания бюджета ошибок
+// выше Threshold и в длинном, и в коротком окне: длинное отсекает всплески,
+// короткое снимает оповещение вскоре после восстановления.
+type BurnRateRule struct {
+	Severity    SLOAlertSeverity
+	Threshold   float64
+	LongWindow  time.Duration
+	ShortWindow time.Duration
+}
+
+// DefaultBurnRateRules - правила с порогами fast и slow: при 14.4 месячный бюджет
+// 30-дневного окна сгорает за 2 дня, при 6 - за 5 дней.
+func DefaultBurnRateRules(fast, slow float64) []BurnRateRule {
+	return []BurnRateRule{
+		{Severity: SLOPage, Threshold: fast, LongWindow: time.Hour, ShortWindow: 5 * time.Minute},
+		{Severity: SLOTicket, Threshold: slow, LongWindow: 6 * time.Hour, ShortWindow: 30 * time.Minute},
+	}
+}
+
+// SLOWindow - запросы маршрута за окно. BurnRate - доля плохих ответов, деленная
+// на бюджет ошибок (100 - цель): 1 означает, что бюджет расходуется ровно в срок.
+type SLOWindow struct {
+	Window   string  `json:"window" example:"1h"`
+	Requests int64   `json:"requests" example:"1200"`
+	Bad      int64   `json:"bad" example:"3"`
+	BurnRate float64 `json:"burn_rate" example:"2.5"`
+}
+
+// SLOObjectiveStatus - состояние одной цели маршрута и сработавшие правила.
+type SLOObjectiveStatus struct {
+	Objective SLOObjective       `json:"objective" example:"availability"`
+	Target    float64            `json:"target" example:"99.9"`
+	Windows   []SLOWindow        `json:"windows"`
+	Alerts    []SLOAlertSeverity `json:"alerts"`
+}
+
+// SLOStatus - цели маршрута и их текущее состояние на этой реплике.
+type SLOStatus struct {
+	Route              string               `json:"route" example:"GET /api/v1/subscriptions/calculate"`
+	LatencyThresholdMS int64                `json:"latency_threshold_ms,omitempty" example:"300"`
+	Objectives         []SLOObjectiveStatus `json:"objectives"`
+}
+
+// SLOReport - ответ GET /admin/slo.
+type SLOReport struct {
+	CheckedAt time.Time   `json:"checked_at"`
+	Routes    []SLOStatus `json:"routes"`
+}
+
+// SLOBurnAlert - данные события slo.burn_rate: цель маршрута начала сгорать
+// быстрее порога правила.
+type SLOBurnAlert struct {
+	Route         string           `json:"route"`
+	Objective     SLOObjective     `json:"objective"`
+	Severity      SLOAlertSeverity `json:"severity"`
+	Target        float64          `json:"target"`
+	Threshold     float64          `json:"threshold"`
+	LongWindow    string           `json:"long_window"`
+	LongBurnRate  float64          `json:"long_burn_rate"`
+	ShortWindow   string           `json:"short_window"`
+	ShortBurnRate float64          `json:"short_burn_rate"`
+}
@@ -0,0 +1,45 @@
+package domain
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseSLOs(t *testing.T) {
+	slos, err := ParseSLOs("get /api/v1/subscriptions/calculate availability=99.9 latency=300ms@99; POST /api/v1/subscriptions availability=99.5;")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []SLO{
+		{Route: "GET /api/v1/subscriptions/calculate", AvailabilityTarget: 99.9, LatencyThreshold: 300 * time.Millisecond, LatencyTarget: 99},
+		{Route: "POST /api/v1/subscriptions", AvailabilityTarget: 99.5},
+	}
+	if len(slos) != len(want) {
+		t.Fatalf("slos = %+v", slos)
+	}
+	for i := range want {
+		if slos[i] != want[i] {
+			t.Errorf("slo %d = %+v, want %+v", i, slos[i], want[i])
+		}
+	}
+
+	if slos, err := ParseSLOs(" "); err != nil || len(slos) != 0 {
+		t.Errorf("empty spec: %v, %v", slos, err)
+	}
+
+	invalid := []string{
+		"GET /x",
+		"GET x availability=99",
+		"GET /x availability=100",
+		"GET /x availability=abc",
+		"GET /x latency=300ms",
+		"GET /x latency=0s@99",
+		"GET /x errors=1",
+		"GET /x availability=99; GET /x availability=99.9",
+	}
+	for _, spec := range invalid {
+		if _, err := ParseSLOs(spec); err == nil {
+			t.Errorf("%q: expected error", spec)
+		}
+	}
+}
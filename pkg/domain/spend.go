@@ -0,0 +1,96 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// NotificationSettings - пользовательские настройки уведомлений.
+// ThresholdPercent nil означает порог по умолчанию из конфигурации.
+type NotificationSettings struct {
+	UserID           uuid.UUID `json:"user_id"`
+	SpendAlerts      bool      `json:"spend_alerts" example:"true"`
+	ThresholdPercent *int      `json:"threshold_percent,omitempty" example:"15"`
+	// StatementChannel - куда отправлять ежемесячную выписку
+	StatementChannel StatementChannel `json:"statement_channel" example:"email"`
+	// Escalation - цепочка эскалации критических уведомлений; пусто - без эскалации
+	Escalation []EscalationStep `json:"escalation,omitempty"`
+	// UpdatedAt пуст, пока пользователь не сохранял настройки
+	UpdatedAt *time.Time `json:"updated_at,omitempty"`
+}
+
+type UpdateNotificationSettingsRequest struct {
+	SpendAlerts      bool `json:"spend_alerts" example:"true"`
+	ThresholdPercent *int `json:"threshold_percent,omitempty" binding:"omitempty,min=1,max=1000" example:"15"`
+	// StatementChannel по умолчанию none: выписка не отправляется
+	StatementChannel StatementChannel `json:"statement_channel,omitempty" binding:"omitempty,oneof=none email webhook" example:"email"`
+	// Escalation - шаги эскалации критических уведомлений по порядку, не больше 5
+	Escalation []EscalationStep `json:"escalation,omitempty" binding:"omitempty,max=5,dive"`
+}
+
+// StatementChannel - канал ежемесячной выписки: none - не отправлять, email -
+// письмом на адрес пользователя с CSV во вложении, webhook - событием statement.monthly.
+type StatementChannel string
+
+const (
+	StatementNone    StatementChannel = "none"
+	StatementEmail   StatementChannel = "email"
+	StatementWebhook StatementChannel = "webhook"
+)
+
+type SpendPeriod string
+
+const (
+	SpendWeek  SpendPeriod = "week"
+	SpendMonth SpendPeriod = "month"
+)
+
+// SpendChange - сравнение трат пользователя за два соседних периода.
+type SpendChange struct {
+	UserID        uuid.UUID   `json:"user_id"`
+	Period        SpendPeriod `json:"period" example:"month"`
+	PreviousStart string      `json:"previous_start" example:"2025-06-01"`
+	CurrentStart  string      `json:"current_start" example:"2025-07-01"`
+	// Previous и Current - траты в целых единицах валюты, оставлены для совместимости
+	Previous int `json:"previous" example:"1300"`
+	Current  int `json:"current" example:"1700"`
+	// Траты в разных валютах сравниваются отдельно, по событию на валюту
+	Currency         Currency `json:"currency" example:"RUB"`
+	PreviousAmount   Money    `json:"previous_amount"`
+	CurrentAmount    Money    `json:"current_amount"`
+	ChangePercent    float64  `json:"change_percent" example:"30.77"`
+	ThresholdPercent int      `json:"threshold_percent" example:"20"`
+}
+
+// ChargesBetween суммирует списания с датой в [from, to) с учетом дней списания
+// (ChargeDates) и истории статусов: месяцы на паузе и после отмены не списываются.
+// Суммы возвращаются по валютам.
+func ChargesBetween(subs []*Subscription, changes map[uuid.UUID][]*StatusChange, from, to time.Time) (Totals, error) {
+	total := make(Totals)
+	for month := time.Date(from.Year(), from.Month(), 1, 0, 0, 0, 0, time.UTC); month.Before(to); month = month.AddDate(0, 1, 0) {
+		for _, sub := range subs {
+			dates, err := ChargeDates(sub, month)
+			if err != nil {
+				return nil, err
+			}
+			if len(dates) == 0 {
+				continue
+			}
+
+			status, err := StatusAt(changes[sub.ID], month)
+			if err != nil {
+				return nil, err
+			}
+			if !status.Billable() {
+				continue
+			}
+			for _, day := range dates {
+				if !day.Before(from) && day.Before(to) {
+					total.Add(sub.Price)
+				}
+			}
+		}
+	}
+	return total, nil
+}
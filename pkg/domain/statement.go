@@ -0,0 +1,153 @@
+package domain
+
+import (
+	"cmp"
+	"fmt"
+	"math"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// StatementParams - параметры выписки в очереди выгрузок. TenantID пуст для
+// основной базы.
+type StatementParams struct {
+	TenantID string    `json:"tenant_id,omitempty"`
+	UserID   uuid.UUID `json:"user_id"`
+	Month    string    `json:"month"`
+}
+
+// StatementChange - как изменились траты на сервис по сравнению с прошлым месяцем.
+type StatementChange string
+
+const (
+	StatementNew       StatementChange = "new"
+	StatementEnded     StatementChange = "ended"
+	StatementIncreased StatementChange = "increased"
+	StatementDecreased StatementChange = "decreased"
+	StatementUnchanged StatementChange = "unchanged"
+)
+
+// StatementLine - траты на сервис в одной валюте за месяц выписки и прошлый месяц.
+type StatementLine struct {
+	ServiceName string          `json:"service_name" example:"Netflix"`
+	Currency    Currency        `json:"currency" example:"RUB"`
+	Previous    Money           `json:"previous"`
+	Current     Money           `json:"current"`
+	Change      StatementChange `json:"change" example:"increased"`
+}
+
+// StatementTotal - траты в валюте за месяц выписки и прошлый месяц.
+// ChangePercent пуст, если в прошлом месяце трат в валюте не было.
+type StatementTotal struct {
+	Currency      Currency `json:"currency" example:"RUB"`
+	Previous      Money    `json:"previous"`
+	Current       Money    `json:"current"`
+	ChangePercent *float64 `json:"change_percent,omitempty" example:"12.5"`
+}
+
+// Statement - ежемесячная выписка пользователя: списания месяца по сервисам и
+// сравнение с прошлым месяцем.
+type Statement struct {
+	UserID        uuid.UUID        `json:"user_id"`
+	Month         string           `json:"month" example:"09-2025"`
+	PreviousMonth string           `json:"previous_month" example:"08-2025"`
+	Lines         []StatementLine  `json:"lines"`
+	Totals        []StatementTotal `json:"totals"`
+}
+
+// BuildStatement считает выписку за month по подпискам пользователя и истории
+// их статусов. Списания считаются как в уведомлениях о тратах (ChargesBetween):
+// по дням списания, без месяцев на паузе и после отмены.
+func BuildStatement(userID uuid.UUID, month time.Time, subs []*Subscription, changes map[uuid.UUID][]*StatusChange) (*Statement, error) {
+	month = time.Date(month.Year(), month.Month(), 1, 0, 0, 0, 0, time.UTC)
+	previous, next := month.AddDate(0, -1, 0), month.AddDate(0, 1, 0)
+
+	type key struct {
+		service  string
+		currency Currency
+	}
+	lines := make(map[key]*StatementLine)
+	line := func(service string, currency Currency) *StatementLine {
+		k := key{service, currency}
+		if lines[k] == nil {
+			lines[k] = &StatementLine{ServiceName: service, Currency: currency,
+				Previous: Money{Currency: currency}, Current: Money{Currency: currency}}
+		}
+		return lines[k]
+	}
+	for _, sub := range subs {
+		before, err := ChargesBetween([]*Subscription{sub}, changes, previous, month)
+		if err != nil {
+			return nil, err
+		}
+		current, err := ChargesBetween([]*Subscription{sub}, changes, month, next)
+		if err != nil {
+			return nil, err
+		}
+		for currency, amount := range before {
+			line(sub.ServiceName, currency).Previous.Amount += amount
+		}
+		for currency, amount := range current {
+			line(sub.ServiceName, currency).Current.Amount += amount
+		}
+	}
+
+	statement := &Statement{
+		UserID:        userID,
+		Month:         FormatPeriod(month),
+		PreviousMonth: FormatPeriod(previous),
+		Lines:         make([]StatementLine, 0, len(lines)),
+		Totals:        make([]StatementTotal, 0),
+	}
+	totals := make(map[Currency]*StatementTotal)
+	for _, l := range lines {
+		if l.Previous.Amount == 0 && l.Current.Amount == 0 {
+			continue
+		}
+		l.Change = statementChange(l.Previous.Amount, l.Current.Amount)
+		statement.Lines = append(statement.Lines, *l)
+
+		total := totals[l.Currency]
+		if total == nil {
+			total = &StatementTotal{Currency: l.Currency, Previous: Money{Currency: l.Currency}, Current: Money{Currency: l.Currency}}
+			totals[l.Currency] = total
+		}
+		total.Previous.Amount += l.Previous.Amount
+		total.Current.Amount += l.Current.Amount
+	}
+	slices.SortFunc(statement.Lines, func(a, b StatementLine) int {
+		return cmp.Or(cmp.Compare(a.ServiceName, b.ServiceName), cmp.Compare(a.Currency, b.Currency))
+	})
+
+	for _, total := range totals {
+		if total.Previous.Amount != 0 {
+			percent := math.Round(float64(total.Current.Amount-total.Previous.Amount)/float64(total.Previous.Amount)*10000) / 100
+			total.ChangePercent = &percent
+		}
+		statement.Totals = append(statement.Totals, *total)
+	}
+	slices.SortFunc(statement.Totals, func(a, b StatementTotal) int { return cmp.Compare(a.Currency, b.Currency) })
+	return statement, nil
+}
+
+func statementChange(previous, current int64) StatementChange {
+	switch {
+	case previous == 0:
+		return StatementNew
+	case current == 0:
+		return StatementEnded
+	case current > previous:
+		return StatementIncreased
+	case current < previous:
+		return StatementDecreased
+	default:
+		return StatementUnchanged
+	}
+}
+
+// StatementFileName - имя CSV-файла выписки за месяц month.
+func StatementFileName(month time.Time) string {
+	return fmt.Sprintf("statement_%s.csv", month.Format("2006-01"))
+}
@@ -0,0 +1,86 @@
+package domain
+
+import (
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type SubscriptionStatus string
+
+const (
+	StatusActive    SubscriptionStatus = "active"
+	StatusPaused    SubscriptionStatus = "paused"
+	StatusCancelled SubscriptionStatus = "cancelled"
+	StatusExpired   SubscriptionStatus = "expired"
+)
+
+// statusTransitions - допустимые переходы; cancelled и expired конечные.
+var statusTransitions = map[SubscriptionStatus][]SubscriptionStatus{
+	StatusActive: {StatusPaused, StatusCancelled, StatusExpired},
+	StatusPaused: {StatusActive, StatusCancelled, StatusExpired},
+}
+
+func (s SubscriptionStatus) CanTransitionTo(next SubscriptionStatus) bool {
+	for _, allowed := range statusTransitions[s] {
+		if allowed == next {
+			return true
+		}
+	}
+	return false
+}
+
+// Billable сообщает, списываются ли деньги за месяц в этом статусе.
+// Месяцы в паузе и после отмены в расчет не входят.
+func (s SubscriptionStatus) Billable() bool {
+	return s != StatusPaused && s != StatusCancelled
+}
+
+// StatusChange - запись истории статусов: статус действует с месяца EffectiveFrom.
+type StatusChange struct {
+	SubscriptionID uuid.UUID          `json:"subscription_id"`
+	Status         SubscriptionStatus `json:"status" example:"paused"`
+	EffectiveFrom  string             `json:"effective_from" example:"08-2025"`
+	ChangedAt      time.Time          `json:"changed_at"`
+}
+
+type ChangeStatusRequest struct {
+	Status SubscriptionStatus `json:"status" binding:"required,oneof=active paused cancelled expired" example:"paused"`
+	// EffectiveFrom по умолчанию - текущий месяц (или месяц начала, если подписка еще не началась)
+	EffectiveFrom *string `json:"effective_from,omitempty" example:"08-2025"`
+}
+
+// CancelSubscriptionRequest - отмена подписки. EffectiveMonth - последний
+// оплачиваемый месяц, он становится end_date; статус cancelled действует со следующего.
+type CancelSubscriptionRequest struct {
+	// EffectiveMonth по умолчанию - текущий месяц (или месяц начала, если подписка еще не началась)
+	EffectiveMonth *string `json:"effective_month,omitempty" example:"09-2025"`
+	Reason         *string `json:"reason,omitempty" binding:"omitempty,max=500" example:"too expensive"`
+}
+
+// StatusAt возвращает статус подписки в месяце month по истории изменений.
+// До первого изменения подписка считается активной.
+func StatusAt(changes []*StatusChange, month time.Time) (SubscriptionStatus, error) {
+	sorted := make([]*StatusChange, len(changes))
+	copy(sorted, changes)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].EffectiveFrom != sorted[j].EffectiveFrom {
+			return periodLess(sorted[i].EffectiveFrom, sorted[j].EffectiveFrom)
+		}
+		return sorted[i].ChangedAt.Before(sorted[j].ChangedAt)
+	})
+
+	status := StatusActive
+	for _, change := range sorted {
+		from, err := ParsePeriod(change.EffectiveFrom)
+		if err != nil {
+			return "", err
+		}
+		if from.After(month) {
+			break
+		}
+		status = change.Status
+	}
+	return status, nil
+}
@@ -0,0 +1,402 @@
+package domain
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type Subscription struct {
+	ID          uuid.UUID `json:"id" example:"123e4567-e89b-12d3-a456-426614174000"`
+	ServiceName string    `json:"service_name" example:"Yandex Plus" binding:"required"`
+	Price       Money     `json:"price"`
+	// BillingCycle - за какой период списывается Price
+	BillingCycle BillingCycle `json:"billing_cycle" example:"monthly"`
+	UserID       uuid.UUID    `json:"user_id" example:"60601fee-2bf1-4721-ae6f-7636e79a0cba" binding:"required"`
+	StartDate    string       `json:"start_date" example:"07-2025" binding:"required"`
+	EndDate      *string      `json:"end_date,omitempty" example:"12-2025"`
+	// AutoRenew - в конце месяца end_date продлевается на месяц
+	AutoRenew bool      `json:"auto_renew" example:"false"`
+	CreatedAt time.Time `json:"created_at" example:"2025-10-23T15:04:05Z"`
+	UpdatedAt time.Time `json:"updated_at" example:"2025-10-23T15:04:05Z"`
+
+	Status       SubscriptionStatus `json:"status" example:"active"`
+	CancelledAt  *time.Time         `json:"cancelled_at,omitempty" example:"2025-10-23T15:04:05Z"`
+	CancelReason *string            `json:"cancel_reason,omitempty" example:"too expensive"`
+
+	Backfilled              bool    `json:"backfilled" example:"false"`
+	ExcludeFromNewAnalytics bool    `json:"exclude_from_new_analytics" example:"false"`
+	BackfillNote            *string `json:"backfill_note,omitempty" example:"migrated from legacy billing"`
+	// Tags - метки для группировки подписок, в нижнем регистре и без повторов
+	Tags []string `json:"tags" example:"work,trial"`
+	// Notes - произвольная заметка пользователя, участвует в поиске ?q=
+	Notes *string `json:"notes,omitempty" example:"VPN, оплачиваю через PayPal"`
+	// CustomFields - значения пользовательских полей тенанта (Tenant.CustomFields)
+	CustomFields CustomFieldValues `json:"custom_fields,omitempty" swaggertype:"object"`
+	// Region - регион, последним записавший подписку; пуст без REGION
+	Region string `json:"region,omitempty" example:"eu-central"`
+	// Version растет с каждым изменением; PUT и PATCH с устаревшей версией получают 409
+	Version int64 `json:"version" example:"3"`
+}
+
+type CreateSubscriptionRequest struct {
+	// ID задает клиент, который создает подписки у себя и синхронизирует их позже;
+	// без него ID назначает сервис. Занятый ID дает 409
+	ID          *uuid.UUID `json:"id,omitempty" example:"0b6f1c1e-8a4d-4c55-9a3e-2f1d7c9b5e10"`
+	ServiceName string     `json:"service_name" binding:"required" example:"Yandex Plus"`
+	// Price - объект Money; число трактуется как сумма в рублях
+	Price Money `json:"price"`
+	// BillingCycle по умолчанию monthly
+	BillingCycle BillingCycle `json:"billing_cycle,omitempty" binding:"omitempty,oneof=weekly monthly yearly" example:"monthly"`
+	UserID       uuid.UUID    `json:"user_id" binding:"required" example:"60601fee-2bf1-4721-ae6f-7636e79a0cba"`
+	StartDate    string       `json:"start_date" binding:"required" example:"07-2025"`
+	EndDate      *string      `json:"end_date,omitempty" example:"12-2025"`
+	AutoRenew    bool         `json:"auto_renew" example:"true"`
+	Tags         []string     `json:"tags,omitempty" example:"work,trial"`
+	Notes        *string      `json:"notes,omitempty" example:"VPN, оплачиваю через PayPal"`
+	// CustomFields - значения пользовательских полей тенанта
+	CustomFields CustomFieldValues `json:"custom_fields,omitempty" swaggertype:"object"`
+}
+
+// BulkCreateItemResult - результат для одного элемента массового создания.
+// Index совпадает с позицией элемента в запросе.
+type BulkCreateItemResult struct {
+	Index        int           `json:"index" example:"0"`
+	Subscription *Subscription `json:"subscription,omitempty"`
+	Error        string        `json:"error,omitempty" example:"start_date: invalid period, expected MM-YYYY"`
+}
+
+type BulkCreateResponse struct {
+	Created int                    `json:"created" example:"2"`
+	Items   []BulkCreateItemResult `json:"items"`
+}
+
+// BackfillSubscriptionRequest - админский режим загрузки исторических данных
+// с произвольными created_at/updated_at. Status по умолчанию active; cancelled и
+// expired - только для подписок, закончившихся до текущего месяца.
+type BackfillSubscriptionRequest struct {
+	ServiceName             string              `json:"service_name" binding:"required" example:"Yandex Plus"`
+	Price                   Money               `json:"price"`
+	BillingCycle            BillingCycle        `json:"billing_cycle,omitempty" binding:"omitempty,oneof=weekly monthly yearly" example:"monthly"`
+	UserID                  uuid.UUID           `json:"user_id" binding:"required" example:"60601fee-2bf1-4721-ae6f-7636e79a0cba"`
+	StartDate               string              `json:"start_date" binding:"required" example:"07-2021"`
+	EndDate                 *string             `json:"end_date,omitempty" example:"12-2022"`
+	CreatedAt               time.Time           `json:"created_at" binding:"required" example:"2021-07-01T00:00:00Z"`
+	UpdatedAt               *time.Time          `json:"updated_at,omitempty" example:"2022-12-31T00:00:00Z"`
+	ExcludeFromNewAnalytics *bool               `json:"exclude_from_new_analytics,omitempty" example:"true"`
+	Note                    *string             `json:"note,omitempty" example:"migrated from legacy billing"`
+	Tags                    []string            `json:"tags,omitempty" example:"work"`
+	Status                  *SubscriptionStatus `json:"status,omitempty" binding:"omitempty,oneof=active cancelled expired" example:"cancelled"`
+}
+
+// ReplaceSubscriptionRequest - полная замена подписки (PUT).
+// Отсутствующий end_date делает подписку бессрочной.
+type ReplaceSubscriptionRequest struct {
+	ServiceName  string       `json:"service_name" binding:"required" example:"Yandex Plus"`
+	Price        Money        `json:"price"`
+	BillingCycle BillingCycle `json:"billing_cycle,omitempty" binding:"omitempty,oneof=weekly monthly yearly" example:"monthly"`
+	StartDate    string       `json:"start_date" binding:"required" example:"07-2025"`
+	EndDate      *string      `json:"end_date,omitempty" example:"12-2025"`
+	AutoRenew    bool         `json:"auto_renew" example:"true"`
+	Tags         []string     `json:"tags,omitempty" example:"work,trial"`
+	Notes        *string      `json:"notes,omitempty" example:"VPN, оплачиваю через PayPal"`
+	// CustomFields заменяет значения пользовательских полей целиком
+	CustomFields CustomFieldValues `json:"custom_fields,omitempty" swaggertype:"object"`
+	// Version - версия, которую видел клиент; то же, что If-Match
+	Version *int64 `json:"version,omitempty" example:"3"`
+}
+
+// UpdateSubscriptionRequest - частичное обновление (PATCH): отсутствующее поле
+// не меняется, null в end_date снимает дату окончания.
+type UpdateSubscriptionRequest struct {
+	ServiceName  Optional[string]       `json:"service_name" swaggertype:"string" example:"Yandex Plus"`
+	Price        Optional[Money]        `json:"price" swaggertype:"object"`
+	BillingCycle Optional[BillingCycle] `json:"billing_cycle" swaggertype:"string" example:"yearly"`
+	StartDate    Optional[string]       `json:"start_date" swaggertype:"string" example:"07-2025"`
+	EndDate      Optional[string]       `json:"end_date" swaggertype:"string" example:"12-2025"`
+	AutoRenew    Optional[bool]         `json:"auto_renew" swaggertype:"boolean" example:"true"`
+	// Tags заменяет метки целиком; пустой список или null снимает все метки
+	Tags Optional[[]string] `json:"tags" swaggertype:"array,string" example:"work,family"`
+	// Notes: null или пустая строка удаляет заметку
+	Notes Optional[string] `json:"notes" swaggertype:"string" example:"VPN, оплачиваю через PayPal"`
+	// CustomFields заменяет значения пользовательских полей целиком; null снимает все
+	CustomFields Optional[CustomFieldValues] `json:"custom_fields" swaggertype:"object"`
+	// Version - версия, которую видел клиент; то же, что If-Match
+	Version *int64 `json:"version,omitempty" example:"3"`
+}
+
+// MarshalJSON выводит только переданные поля, чтобы запрос разбирался обратно без изменений.
+func (r UpdateSubscriptionRequest) MarshalJSON() ([]byte, error) {
+	fields := make(map[string]any)
+	if r.ServiceName.Set {
+		fields["service_name"] = r.ServiceName
+	}
+	if r.Price.Set {
+		fields["price"] = r.Price
+	}
+	if r.BillingCycle.Set {
+		fields["billing_cycle"] = r.BillingCycle
+	}
+	if r.StartDate.Set {
+		fields["start_date"] = r.StartDate
+	}
+	if r.EndDate.Set {
+		fields["end_date"] = r.EndDate
+	}
+	if r.AutoRenew.Set {
+		fields["auto_renew"] = r.AutoRenew
+	}
+	if r.Tags.Set {
+		fields["tags"] = r.Tags
+	}
+	if r.Notes.Set {
+		fields["notes"] = r.Notes
+	}
+	if r.CustomFields.Set {
+		fields["custom_fields"] = r.CustomFields
+	}
+	if r.Version != nil {
+		fields["version"] = *r.Version
+	}
+	return json.Marshal(fields)
+}
+
+// UserID разбирается на уровне HTTP-хендлера, поэтому исключен из form-биндинга.
+type ListSubscriptionsQuery struct {
+	UserID      *uuid.UUID `form:"-"`
+	ServiceName *string    `form:"service_name"`
+	// ServiceKeys заполняет сервис: ключи всех написаний ServiceName с учетом алиасов
+	ServiceKeys []string            `form:"-" swaggerignore:"true"`
+	Status      *SubscriptionStatus `form:"status" binding:"omitempty,oneof=active paused cancelled expired"`
+	Limit       int                 `form:"limit,default=100" binding:"min=1,max=100"`
+	Offset      int                 `form:"offset" binding:"min=0"`
+	// Tags отбирает подписки со всеми указанными метками (?tag=work&tag=trial)
+	Tags []string `form:"tag"`
+	// Q - поиск без учета регистра по названию и заметкам; каждое слово должно встретиться
+	Q string `form:"q" example:"vpn paypal"`
+	// Match - режим поиска q: contains (по умолчанию) или fuzzy - похожесть q на название
+	// по триграммам, находит названия с опечатками ("yanex plus" - "Yandex Plus")
+	Match SearchMatch `form:"match" binding:"omitempty,oneof=contains fuzzy" example:"fuzzy"`
+	// MinPrice и MaxPrice оставляют подписки с ценой за цикл оплаты в этих границах
+	// включительно, в единицах валюты Currency ("1000", "9.99")
+	MinPrice string `form:"min_price" example:"1000"`
+	MaxPrice string `form:"max_price" example:"5000"`
+	// Currency оставляет подписки в этой валюте; с min_price или max_price по умолчанию RUB
+	Currency Currency `form:"currency" example:"RUB"`
+	// MinPriceMinor и MaxPriceMinor заполняет сервис: границы цены в минорных единицах Currency
+	MinPriceMinor *int64 `form:"-" swaggerignore:"true"`
+	MaxPriceMinor *int64 `form:"-" swaggerignore:"true"`
+	// ActiveIn оставляет подписки, действующие в этом месяце: начавшиеся не позже
+	// него и без даты окончания или закончившиеся не раньше
+	ActiveIn string `form:"active_in" example:"10-2025"`
+	// ActiveMonth заполняет сервис из ActiveIn: первое число месяца
+	ActiveMonth *time.Time `form:"-" swaggerignore:"true"`
+	// CustomField отбирает подписки со значениями пользовательских полей:
+	// ?custom_field=owner_team:payments&custom_field=po_number:PO-000123
+	CustomField []string `form:"custom_field"`
+	// CustomFieldFilter заполняет сервис из CustomField: значения в типах полей тенанта
+	CustomFieldFilter CustomFieldValues `form:"-" swaggerignore:"true"`
+	// Snapshot - токен снимка из ответа первой страницы: страницы с ним не видят
+	// подписки, созданные после нее
+	Snapshot string `form:"snapshot"`
+	// CreatedBefore заполняет сервис из Snapshot: подписки, созданные не позже
+	CreatedBefore *time.Time `form:"-" swaggerignore:"true"`
+	// Cursor - next_cursor из ответа предыдущей страницы: страница начинается сразу
+	// после ее последней подписки. В отличие от offset не замедляется на дальних
+	// страницах и не сдвигается от добавленных и удаленных подписок; вместе с offset не передается
+	Cursor string `form:"cursor"`
+	// After заполняет сервис из Cursor: подписки, идущие в списке после него
+	After *ListCursor `form:"-" swaggerignore:"true"`
+	// SortBy - поле сортировки, по умолчанию created_at; price сравнивает суммы в
+	// минорных единицах без пересчета валют, start_date - месяцы начала
+	SortBy ListSortField `form:"sort_by" binding:"omitempty,oneof=price start_date service_name created_at" example:"price"`
+	// Order - направление сортировки: по умолчанию desc для created_at и asc для остальных полей
+	Order SortOrder `form:"order" binding:"omitempty,oneof=asc desc" example:"asc"`
+}
+
+// ListSortField - поле, по которому сортируется список подписок.
+type ListSortField string
+
+const (
+	SortByCreatedAt   ListSortField = "created_at"
+	SortByPrice       ListSortField = "price"
+	SortByStartDate   ListSortField = "start_date"
+	SortByServiceName ListSortField = "service_name"
+)
+
+// ListSortFields - допустимые значения sort_by.
+var ListSortFields = []ListSortField{SortByCreatedAt, SortByPrice, SortByStartDate, SortByServiceName}
+
+type SortOrder string
+
+const (
+	SortAsc  SortOrder = "asc"
+	SortDesc SortOrder = "desc"
+)
+
+// Sort возвращает поле и направление сортировки с учетом значений по умолчанию.
+// Подписки с равными значениями поля всегда идут по id.
+func (q ListSubscriptionsQuery) Sort() (ListSortField, SortOrder) {
+	field, order := q.SortBy, q.Order
+	if field == "" {
+		field = SortByCreatedAt
+	}
+	if order == "" {
+		order = SortAsc
+		if field == SortByCreatedAt {
+			order = SortDesc
+		}
+	}
+	return field, order
+}
+
+// DefaultSort сообщает, что список идет в порядке по умолчанию - новые сверху.
+// Курсор (Cursor) продолжает только такой порядок.
+func (q ListSubscriptionsQuery) DefaultSort() bool {
+	field, order := q.Sort()
+	return field == SortByCreatedAt && order == SortDesc
+}
+
+type ListSubscriptionsResponse struct {
+	Items      []*Subscription `json:"items"`
+	TotalCount int             `json:"total_count" example:"42"`
+	Limit      int             `json:"limit" example:"100"`
+	Offset     int             `json:"offset" example:"0"`
+	HasMore    bool            `json:"has_more" example:"false"`
+	// Snapshot передается в snapshot следующих страниц; в ответе с snapshot он тот же
+	Snapshot string `json:"snapshot" example:"MjAyNS0xMC0yM1QxNTowNDowNS4xMjM0NTZa"`
+	// NextCursor передается в cursor следующей страницы; на последней странице пуст
+	NextCursor string `json:"next_cursor,omitempty" example:"MjAyNS0xMC0yM1QxNTowNDowNS4xMjM0NTZaXzNmYTg1ZjY0LTU3MTctNDU2Mi1iM2ZjLTJjOTYzZjY2YWZhNg"`
+	// DataAsOf - момент, на который актуальны данные: с freshness=eventual может отставать от записи
+	DataAsOf time.Time `json:"data_as_of" example:"2025-10-23T15:04:05Z"`
+}
+
+// EncodeListSnapshot - токен снимка списка подписок на момент at.
+func EncodeListSnapshot(at time.Time) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(at.UTC().Format(time.RFC3339Nano)))
+}
+
+// DecodeListSnapshot возвращает момент снимка списка подписок.
+func DecodeListSnapshot(token string) (time.Time, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return time.Time{}, ErrInvalidContinuation
+	}
+	at, err := time.Parse(time.RFC3339Nano, string(raw))
+	if err != nil {
+		return time.Time{}, ErrInvalidContinuation
+	}
+	return at, nil
+}
+
+// DeleteSubscriptionsFilter - условия массового удаления; фильтры объединяются через AND.
+type DeleteSubscriptionsFilter struct {
+	UserID      *uuid.UUID `json:"user_id,omitempty" example:"60601fee-2bf1-4721-ae6f-7636e79a0cba"`
+	ServiceName *string    `json:"service_name,omitempty" example:"Yandex Plus"`
+	// EndedBefore отбирает подписки с end_date строго раньше указанного месяца
+	EndedBefore *string  `json:"ended_before,omitempty" example:"01-2025"`
+	ServiceKeys []string `json:"-" swaggerignore:"true"`
+}
+
+type DeleteSubscriptionsResponse struct {
+	Deleted int `json:"deleted" example:"3"`
+}
+
+type CalculateTotalRequest struct {
+	UserID *uuid.UUID `form:"-"`
+	// UserIDs считает сумму нескольких пользователей и подытог каждого; несовместим с UserID
+	UserIDs     []uuid.UUID `form:"-" swaggerignore:"true"`
+	ServiceName *string     `form:"service_name"`
+	ServiceKeys []string    `form:"-" swaggerignore:"true"`
+	StartPeriod string      `form:"start_period" binding:"required" example:"01-2025"`
+	EndPeriod   string      `form:"end_period" binding:"required" example:"12-2025"`
+	// GroupBy=classification добавляет в ответ разбивку по классам оплаченных месяцев
+	GroupBy string `form:"group_by" binding:"omitempty,oneof=classification"`
+	// ExcludeInactive исключает месяцы, в которые подписка была на паузе или отменена
+	ExcludeInactive bool `form:"exclude_inactive"`
+	// Currency - валюта суммы, по умолчанию RUB; подписки в других валютах не учитываются
+	Currency Currency `form:"currency" example:"RUB"`
+	// TargetCurrency пересчитывает подписки во всех валютах по текущему курсу; несовместим с Currency
+	TargetCurrency Currency `form:"target_currency" example:"USD"`
+	// Tags учитывает только подписки со всеми указанными метками
+	Tags []string `form:"tag"`
+	// Rounding - правило округления долей тенанта, задается сервисом
+	Rounding RoundingMode `form:"-" swaggerignore:"true"`
+	// OpenEndedUntil - месяц, которым заканчиваются бессрочные подписки (пусто - концом
+	// периода), задается сервисом по OpenEndedPolicy тенанта
+	OpenEndedUntil string `form:"-" swaggerignore:"true"`
+	// IncludeItems добавляет в ответ оплаченные месяцы подписок, из которых сложилась
+	// сумма; на сумму не влияет, поэтому не входит в ключ кеша расчета
+	IncludeItems bool `form:"include_items" json:"-"`
+}
+
+type CalculateTotalResponse struct {
+	TotalCost        Money                  `json:"total_cost"`
+	ByClassification map[BillingClass]Money `json:"by_classification,omitempty"`
+	// ByUser - подытоги пользователей из user_ids в порядке запроса
+	ByUser []UserTotal `json:"by_user,omitempty"`
+	// Conversion заполняется, если сумма пересчитана в target_currency
+	Conversion *CurrencyConversion `json:"conversion,omitempty"`
+	// Items - оплаченные месяцы подписок, из которых сложилась сумма (include_items=true)
+	Items []TotalItem `json:"items,omitempty"`
+	// DataAsOf - момент, на который актуальны данные: с freshness=eventual может отставать от записи
+	DataAsOf time.Time `json:"data_as_of" example:"2025-10-23T15:04:05Z"`
+}
+
+// MaxCalculateUsers ограничивает число пользователей в user_ids одного расчета.
+const MaxCalculateUsers = 100
+
+// UserTotal - стоимость подписок одного пользователя из многопользовательского расчета.
+type UserTotal struct {
+	UserID    uuid.UUID `json:"user_id" example:"60601fee-2bf1-4721-ae6f-7636e79a0cba"`
+	TotalCost Money     `json:"total_cost"`
+}
+
+// CurrencyConversion - по каким курсам пересчитана сумма.
+type CurrencyConversion struct {
+	Source    string `json:"source" example:"cbr"`
+	RatesDate string `json:"rates_date,omitempty" example:"2025-10-23"`
+	// Totals - суммы до пересчета по исходным валютам
+	Totals Totals `json:"totals" swaggertype:"array,object"`
+	// Rates - стоимость единицы исходной валюты в целевой
+	Rates map[Currency]string `json:"rates"`
+}
+
+type SuccessResponse struct {
+	Message string `json:"message" example:"success"`
+}
+
+// ListCursor - позиция в списке подписок, отсортированном по created_at по
+// убыванию и по id: последняя подписка предыдущей страницы.
+type ListCursor struct {
+	CreatedAt time.Time
+	ID        uuid.UUID
+}
+
+// Encode возвращает непрозрачный токен курсора.
+func (c ListCursor) Encode() string {
+	return base64.RawURLEncoding.EncodeToString([]byte(c.CreatedAt.UTC().Format(time.RFC3339Nano) + "_" + c.ID.String()))
+}
+
+// DecodeListCursor разбирает токен, выданный ListCursor.Encode.
+func DecodeListCursor(token string) (ListCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return ListCursor{}, ErrInvalidContinuation
+	}
+	createdAt, id, ok := strings.Cut(string(raw), "_")
+	if !ok {
+		return ListCursor{}, ErrInvalidContinuation
+	}
+	var cursor ListCursor
+	if cursor.CreatedAt, err = time.Parse(time.RFC3339Nano, createdAt); err != nil {
+		return ListCursor{}, ErrInvalidContinuation
+	}
+	if cursor.ID, err = uuid.Parse(id); err != nil {
+		return ListCursor{}, ErrInvalidContinuation
+	}
+	return cursor, nil
+}
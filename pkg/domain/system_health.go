@@ -0,0 +1,77 @@
+package domain
+
+import "time"
+
+// HealthStatus - состояние составляющей оценки и системы в целом.
+type HealthStatus string
+
+const (
+	HealthOK       HealthStatus = "ok"
+	HealthDegraded HealthStatus = "degraded"
+	HealthCritical HealthStatus = "critical"
+)
+
+var healthStatusRank = map[HealthStatus]int{HealthOK: 0, HealthDegraded: 1, HealthCritical: 2}
+
+// HealthComponent - один показатель: значение, оценка от 0 до 100 и состояние по ней.
+type HealthComponent struct {
+	Name   string       `json:"name" example:"db_latency"`
+	Status HealthStatus `json:"status" example:"ok"`
+	Score  int          `json:"score" example:"100"`
+	Value  float64      `json:"value" example:"3.2"`
+	Unit   string       `json:"unit" example:"ms"`
+	Detail string       `json:"detail,omitempty" example:"ping took 3.2ms"`
+}
+
+// SystemHealth - сводная оценка для дашбордов: score - среднее оценок составляющих,
+// status - худшее из их состояний.
+type SystemHealth struct {
+	Score      int               `json:"score" example:"92"`
+	Status     HealthStatus      `json:"status" example:"degraded"`
+	CheckedAt  time.Time         `json:"checked_at"`
+	Components []HealthComponent `json:"components"`
+}
+
+// NewSystemHealth сводит составляющие в общую оценку.
+func NewSystemHealth(components []HealthComponent, now time.Time) *SystemHealth {
+	health := &SystemHealth{Score: 100, Status: HealthOK, CheckedAt: now, Components: components}
+	if len(components) == 0 {
+		return health
+	}
+	sum := 0
+	for _, component := range components {
+		sum += component.Score
+		if healthStatusRank[component.Status] > healthStatusRank[health.Status] {
+			health.Status = component.Status
+		}
+	}
+	health.Score = sum / len(components)
+	return health
+}
+
+// HealthThresholds - границы оценки показателя: до Warn оценка 100, к Critical
+// линейно падает до 0. Если Critical меньше Warn, плохо низкое значение (доля
+// попаданий в кэш), иначе высокое (задержка, очередь).
+type HealthThresholds struct {
+	Warn     float64
+	Critical float64
+}
+
+// Component оценивает значение value показателя name.
+func (t HealthThresholds) Component(name string, value float64, unit string) HealthComponent {
+	component := HealthComponent{Name: name, Value: value, Unit: unit}
+	// (value - Warn) / (Critical - Warn) - доля пути от Warn к Critical в обоих направлениях
+	progress := 0.0
+	if t.Critical != t.Warn {
+		progress = (value - t.Warn) / (t.Critical - t.Warn)
+	}
+	switch {
+	case progress <= 0:
+		component.Score, component.Status = 100, HealthOK
+	case progress >= 1:
+		component.Score, component.Status = 0, HealthCritical
+	default:
+		component.Score, component.Status = int(100*(1-progress)), HealthDegraded
+	}
+	return component
+}
@@ -0,0 +1,44 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	// MaxTags ограничивает число меток одной подписки.
+	MaxTags      = 20
+	maxTagLength = 32
+)
+
+var (
+	ErrInvalidTag = errors.New("invalid tag")
+
+	tagPattern = regexp.MustCompile(`^[\p{Ll}\p{N}][\p{Ll}\p{N}_-]*$`)
+)
+
+// NormalizeTags приводит метки к нижнему регистру, убирает пробелы по краям и повторы
+// и сортирует их. Метка - буквы, цифры, "_" и "-", не длиннее 32 символов.
+func NormalizeTags(tags []string) ([]string, error) {
+	normalized := make([]string, 0, len(tags))
+	seen := make(map[string]bool, len(tags))
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if utf8.RuneCountInString(tag) > maxTagLength || !tagPattern.MatchString(tag) {
+			return nil, fmt.Errorf("%w %q: expected up to %d letters, digits, '_' or '-'", ErrInvalidTag, tag, maxTagLength)
+		}
+		if !seen[tag] {
+			seen[tag] = true
+			normalized = append(normalized, tag)
+		}
+	}
+	if len(normalized) > MaxTags {
+		return nil, fmt.Errorf("%w: at most %d tags per subscription", ErrInvalidTag, MaxTags)
+	}
+	sort.Strings(normalized)
+	return normalized, nil
+}
@@ -0,0 +1,38 @@
+package domain
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestNormalizeTags(t *testing.T) {
+	got, err := NormalizeTags([]string{" Work", "trial", "work", "Семья", "plan-2025", "home_office"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"home_office", "plan-2025", "trial", "work", "семья"}
+	if !slices.Equal(got, want) {
+		t.Errorf("NormalizeTags = %q, want %q", got, want)
+	}
+
+	if got, err := NormalizeTags(nil); err != nil || got == nil || len(got) != 0 {
+		t.Errorf("NormalizeTags(nil) = %#v, %v, want empty list", got, err)
+	}
+
+	invalid := []string{"", "  ", "work trial", "-work", "tag,other", strings.Repeat("a", 33)}
+	for _, tag := range invalid {
+		if _, err := NormalizeTags([]string{tag}); !errors.Is(err, ErrInvalidTag) {
+			t.Errorf("NormalizeTags(%q) = %v, want ErrInvalidTag", tag, err)
+		}
+	}
+
+	many := make([]string, MaxTags+1)
+	for i := range many {
+		many[i] = strings.Repeat("t", i+1)
+	}
+	if _, err := NormalizeTags(many); !errors.Is(err, ErrInvalidTag) {
+		t.Errorf("expected ErrInvalidTag for %d tags, got %v", len(many), err)
+	}
+}
@@ -0,0 +1,43 @@
+package domain
+
+// UsageMetric - тарифицируемая единица потребления API.
+type UsageMetric string
+
+const (
+	UsageAPIRequests       UsageMetric = "api_requests"
+	UsageExportedRows      UsageMetric = "exported_rows"
+	UsageWebhookDeliveries UsageMetric = "webhook_deliveries"
+)
+
+// DefaultUsageConsumer - потребитель для запросов без тенанта.
+const DefaultUsageConsumer = "default"
+
+// UsageRecord - потребление за сутки (UTC) по одной метрике.
+type UsageRecord struct {
+	Consumer string      `json:"consumer" example:"acme"`
+	Metric   UsageMetric `json:"metric" example:"api_requests"`
+	Day      string      `json:"day" example:"2025-10-23"`
+	Quantity int64       `json:"quantity" example:"1520"`
+}
+
+// UsageQuery - период в днях включительно, формат YYYY-MM-DD.
+type UsageQuery struct {
+	Consumer *string `form:"consumer" json:"consumer,omitempty"`
+	From     string  `form:"from" json:"from" binding:"required" example:"2025-10-01"`
+	To       string  `form:"to" json:"to" binding:"required" example:"2025-10-31"`
+}
+
+type UsageResponse struct {
+	Consumer string                `json:"consumer" example:"acme"`
+	From     string                `json:"from" example:"2025-10-01"`
+	To       string                `json:"to" example:"2025-10-31"`
+	Totals   map[UsageMetric]int64 `json:"totals"`
+	Records  []*UsageRecord        `json:"records"`
+}
+
+// UsageInvoiceLine - строка выгрузки для выставления счета: итог потребителя по метрике за период.
+type UsageInvoiceLine struct {
+	Consumer string      `json:"consumer"`
+	Metric   UsageMetric `json:"metric"`
+	Quantity int64       `json:"quantity"`
+}
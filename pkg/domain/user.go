@@ -0,0 +1,77 @@
+package domain
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Role - роль пользователя: user видит и меняет только свои данные, admin - данные
+// любого пользователя и сводные ручки по всем пользователям, support читает данные
+// любого пользователя без полей, скрытых от нее (см. FieldVisibility), и меняет только свои.
+type Role string
+
+const (
+	RoleUser    Role = "user"
+	RoleAdmin   Role = "admin"
+	RoleSupport Role = "support"
+)
+
+// Valid сообщает, что роль известна.
+func (r Role) Valid() bool {
+	return r == RoleUser || r == RoleAdmin || r == RoleSupport
+}
+
+// User - владелец подписок. Пользователь заводится явно через /users или
+// автоматически при создании первой подписки с новым user_id.
+type User struct {
+	ID        uuid.UUID `json:"id" example:"60601fee-2bf1-4721-ae6f-7636e79a0cba"`
+	Email     *string   `json:"email,omitempty" example:"user@example.com"`
+	Name      *string   `json:"name,omitempty" example:"Иван Петров"`
+	Role      Role      `json:"role" example:"user"`
+	CreatedAt time.Time `json:"created_at" example:"2025-10-23T15:04:05Z"`
+	UpdatedAt time.Time `json:"updated_at" example:"2025-10-23T15:04:05Z"`
+}
+
+type CreateUserRequest struct {
+	// ID по умолчанию генерируется; задается, чтобы перенести пользователя из внешней системы
+	ID    *uuid.UUID `json:"id,omitempty" example:"60601fee-2bf1-4721-ae6f-7636e79a0cba"`
+	Email *string    `json:"email,omitempty" binding:"omitempty,email,max=254" example:"user@example.com"`
+	Name  *string    `json:"name,omitempty" binding:"omitempty,max=255" example:"Иван Петров"`
+}
+
+// UpdateUserRequest - частичное обновление (PATCH): null или пустая строка очищает поле.
+type UpdateUserRequest struct {
+	Email Optional[string] `json:"email" swaggertype:"string" example:"user@example.com"`
+	Name  Optional[string] `json:"name" swaggertype:"string" example:"Иван Петров"`
+}
+
+// MarshalJSON выводит только переданные поля, как и UpdateSubscriptionRequest.
+func (r UpdateUserRequest) MarshalJSON() ([]byte, error) {
+	fields := make(map[string]any)
+	if r.Email.Set {
+		fields["email"] = r.Email
+	}
+	if r.Name.Set {
+		fields["name"] = r.Name
+	}
+	return json.Marshal(fields)
+}
+
+type SetUserRoleRequest struct {
+	Role Role `json:"role" binding:"required,oneof=user admin support" example:"admin"`
+}
+
+type ListUsersQuery struct {
+	Limit  int `form:"limit,default=100" binding:"min=1,max=100"`
+	Offset int `form:"offset" binding:"min=0"`
+}
+
+type ListUsersResponse struct {
+	Items      []*User `json:"items"`
+	TotalCount int     `json:"total_count" example:"42"`
+	Limit      int     `json:"limit" example:"100"`
+	Offset     int     `json:"offset" example:"0"`
+	HasMore    bool    `json:"has_more" example:"false"`
+}
@@ -0,0 +1,36 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// QueuedWriteKind - какая запись отложена: создание, частичное (PATCH) или полное (PUT) обновление.
+type QueuedWriteKind string
+
+const (
+	QueuedWriteCreate  QueuedWriteKind = "create"
+	QueuedWriteUpdate  QueuedWriteKind = "update"
+	QueuedWriteReplace QueuedWriteKind = "replace"
+)
+
+type QueuedWriteStatus string
+
+const (
+	QueuedWritePending  QueuedWriteStatus = "pending"
+	QueuedWriteConflict QueuedWriteStatus = "conflict"
+)
+
+// QueuedWrite - запись, принятая при недоступной базе и ожидающая повтора.
+type QueuedWrite struct {
+	ID             uuid.UUID         `json:"id" example:"8f14e45f-ceea-467f-a0e6-1a2b3c4d5e6f"`
+	Kind           QueuedWriteKind   `json:"kind" example:"create"`
+	Status         QueuedWriteStatus `json:"status" example:"pending"`
+	SubscriptionID uuid.UUID         `json:"subscription_id" example:"123e4567-e89b-12d3-a456-426614174000"`
+	// Subscription - подписка, которая будет создана при повторе (только для create)
+	Subscription *Subscription `json:"subscription,omitempty"`
+	// Conflict - почему запись не применилась; такие записи не повторяются и ждут разбора
+	Conflict   string    `json:"conflict,omitempty" example:"subscription was modified after the write was queued"`
+	EnqueuedAt time.Time `json:"enqueued_at" example:"2025-10-23T15:04:05Z"`
+}
@@ -0,0 +1,291 @@
+package errortracker
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	"aggregator_db/pkg/httpclient"
+	"aggregator_db/pkg/metrics"
+	"github.com/google/uuid"
+)
+
+// queueSize - сколько отчетов ждут отправки; сверх этого новые отбрасываются,
+// чтобы лавина ошибок не копила память и не тормозила запросы.
+const queueSize = 100
+
+var reports = metrics.NewCounterVec(
+	"error_tracker_reports_total",
+	"Отчеты об ошибках по результату отправки: sent, failed или dropped",
+	"result",
+)
+
+// Config - подключение к трекеру ошибок. DSN в формате Sentry:
+// https://<key>@<host>/<project_id>.
+type Config struct {
+	DSN         string
+	Environment string
+	Release     string
+}
+
+// Client отправляет отчеты в Sentry-совместимый трекер (Sentry, GlitchTip) в фоне.
+type Client struct {
+	endpoint    string
+	auth        string
+	environment string
+	release     string
+	serverName  string
+	http        *httpclient.Client
+	logger      *slog.Logger
+	queue       chan *Event
+	done        chan struct{}
+
+	mu     sync.RWMutex
+	closed bool
+}
+
+// New разбирает DSN и запускает отправку. Логгер клиента не должен сам сообщать
+// об ошибках в трекер, иначе неудачная отправка породит новый отчет.
+func New(cfg Config, client *httpclient.Client, logger *slog.Logger) (*Client, error) {
+	endpoint, key, err := parseDSN(cfg.DSN)
+	if err != nil {
+		return nil, err
+	}
+	serverName, _ := os.Hostname()
+
+	c := &Client{
+		endpoint:    endpoint,
+		auth:        fmt.Sprintf("Sentry sentry_version=7, sentry_client=aggregator_db/1.0, sentry_key=%s", key),
+		environment: cfg.Environment,
+		release:     cfg.Release,
+		serverName:  serverName,
+		http:        client,
+		logger:      logger,
+		queue:       make(chan *Event, queueSize),
+		done:        make(chan struct{}),
+	}
+	go c.run()
+	return c, nil
+}
+
+// parseDSN возвращает адрес приема событий проекта и публичный ключ.
+func parseDSN(dsn string) (endpoint, key string, err error) {
+	u, err := url.Parse(dsn)
+	if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+		return "", "", fmt.Errorf("invalid error tracker DSN: expected https://<key>@<host>/<project_id>")
+	}
+	key = u.User.Username()
+	path := strings.Trim(u.Path, "/")
+	slash := strings.LastIndex(path, "/")
+	project := path[slash+1:]
+	if key == "" || project == "" {
+		return "", "", fmt.Errorf("invalid error tracker DSN: key and project id are required")
+	}
+
+	prefix := ""
+	if slash >= 0 {
+		prefix = "/" + path[:slash]
+	}
+	return fmt.Sprintf("%s://%s%s/api/%s/store/", u.Scheme, u.Host, prefix, project), key, nil
+}
+
+// Event - отчет об ошибке в формате Sentry store API.
+type Event struct {
+	EventID     string            `json:"event_id"`
+	Timestamp   time.Time         `json:"timestamp"`
+	Level       string            `json:"level"`
+	Platform    string            `json:"platform"`
+	Logger      string            `json:"logger,omitempty"`
+	ServerName  string            `json:"server_name,omitempty"`
+	Environment string            `json:"environment,omitempty"`
+	Release     string            `json:"release,omitempty"`
+	Message     string            `json:"message,omitempty"`
+	Exception   *Exceptions       `json:"exception,omitempty"`
+	Tags        map[string]string `json:"tags,omitempty"`
+	Extra       map[string]any    `json:"extra,omitempty"`
+	User        *User             `json:"user,omitempty"`
+	Request     *Request          `json:"request,omitempty"`
+}
+
+type Exceptions struct {
+	Values []Exception `json:"values"`
+}
+
+type Exception struct {
+	Type       string      `json:"type"`
+	Value      string      `json:"value"`
+	Stacktrace *Stacktrace `json:"stacktrace,omitempty"`
+}
+
+type Stacktrace struct {
+	Frames []Frame `json:"frames"`
+}
+
+type Frame struct {
+	Function string `json:"function"`
+	Filename string `json:"filename"`
+	Lineno   int    `json:"lineno"`
+	InApp    bool   `json:"in_app"`
+}
+
+type User struct {
+	ID string `json:"id"`
+}
+
+type Request struct {
+	Method string `json:"method,omitempty"`
+	URL    string `json:"url,omitempty"`
+}
+
+// CaptureError сообщает об ошибке err; logger - источник отчета (http, slog).
+func (c *Client) CaptureError(ctx context.Context, logger string, err error, extra map[string]any) {
+	c.capture(ctx, &Event{
+		Level:     "error",
+		Logger:    logger,
+		Exception: &Exceptions{Values: []Exception{{Type: fmt.Sprintf("%T", err), Value: err.Error()}}},
+		Extra:     extra,
+	})
+}
+
+// CaptureMessage сообщает об ошибке без значения error, например о записи лога.
+func (c *Client) CaptureMessage(ctx context.Context, logger, message string, extra map[string]any) {
+	c.capture(ctx, &Event{Level: "error", Logger: logger, Message: message, Extra: extra})
+}
+
+// CapturePanic сообщает о панике со стеком вызывающего: вызывается из recover,
+// пока стек паники еще не размотан.
+func (c *Client) CapturePanic(ctx context.Context, recovered any) {
+	c.capture(ctx, &Event{
+		Level:  "fatal",
+		Logger: "panic",
+		Exception: &Exceptions{Values: []Exception{{
+			Type:       "panic",
+			Value:      fmt.Sprint(recovered),
+			Stacktrace: &Stacktrace{Frames: callerFrames(3)},
+		}}},
+	})
+}
+
+// callerFrames возвращает стек от вызывающего skip уровней выше, в порядке Sentry:
+// от внешнего вызова к месту ошибки.
+func callerFrames(skip int) []Frame {
+	pcs := make([]uintptr, 64)
+	n := runtime.Callers(skip, pcs)
+	iter := runtime.CallersFrames(pcs[:n])
+
+	var frames []Frame
+	for {
+		frame, more := iter.Next()
+		frames = append(frames, Frame{
+			Function: frame.Function,
+			Filename: frame.File,
+			Lineno:   frame.Line,
+			InApp:    strings.HasPrefix(frame.Function, "aggregator_db/"),
+		})
+		if !more {
+			break
+		}
+	}
+	// Кадры обработки паники (recover, runtime.gopanic) отчету не нужны
+	for i, frame := range frames {
+		if frame.Function == "runtime.gopanic" {
+			frames = frames[i+1:]
+			break
+		}
+	}
+	for i, j := 0, len(frames)-1; i < j; i, j = i+1, j-1 {
+		frames[i], frames[j] = frames[j], frames[i]
+	}
+	return frames
+}
+
+// capture дополняет отчет данными запроса и ставит в очередь. Отчет
+// помечает область запроса, чтобы middleware не отправил второй о том же ответе 500.
+func (c *Client) capture(ctx context.Context, event *Event) {
+	event.EventID = strings.ReplaceAll(uuid.NewString(), "-", "")
+	event.Timestamp = time.Now().UTC()
+	event.Platform = "go"
+	event.ServerName = c.serverName
+	event.Environment = c.environment
+	event.Release = c.release
+	if scope := ScopeFromContext(ctx); scope != nil {
+		scope.apply(event)
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if c.closed {
+		reports.Inc("dropped")
+		return
+	}
+	select {
+	case c.queue <- event:
+	default:
+		reports.Inc("dropped")
+	}
+}
+
+func (c *Client) run() {
+	defer close(c.done)
+	for event := range c.queue {
+		if err := c.send(event); err != nil {
+			reports.Inc("failed")
+			c.logger.Warn("failed to send error report", "error", err.Error())
+			continue
+		}
+		reports.Inc("sent")
+	}
+}
+
+func (c *Client) send(event *Event) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Sentry-Auth", c.auth)
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("error tracker responded with status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// Close отправляет накопленные отчеты и ждет завершения, но не дольше ctx.
+func (c *Client) Close(ctx context.Context) {
+	c.mu.Lock()
+	if !c.closed {
+		c.closed = true
+		close(c.queue)
+	}
+	c.mu.Unlock()
+
+	select {
+	case <-c.done:
+	case <-ctx.Done():
+	}
+}
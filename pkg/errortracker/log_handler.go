@@ -0,0 +1,60 @@
+package errortracker
+
+import (
+	"context"
+	"log/slog"
+)
+
+// LogHandler передает записи лога уровня Error в трекер и дальше в next.
+// Так ошибки, которые сервисы уже пишут в лог, попадают в трекер с данными запроса.
+type LogHandler struct {
+	next   slog.Handler
+	client *Client
+	attrs  []slog.Attr
+}
+
+func NewLogHandler(next slog.Handler, client *Client) *LogHandler {
+	return &LogHandler{next: next, client: client}
+}
+
+func (h *LogHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.next.Enabled(ctx, level)
+}
+
+func (h *LogHandler) Handle(ctx context.Context, record slog.Record) error {
+	if record.Level >= slog.LevelError {
+		extra := make(map[string]any, len(h.attrs)+record.NumAttrs())
+		for _, attr := range h.attrs {
+			extra[attr.Key] = attrValue(attr.Value)
+		}
+		record.Attrs(func(attr slog.Attr) bool {
+			extra[attr.Key] = attrValue(attr.Value)
+			return true
+		})
+		h.client.CaptureMessage(ctx, "slog", record.Message, extra)
+	}
+	return h.next.Handle(ctx, record)
+}
+
+func (h *LogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	merged := make([]slog.Attr, 0, len(h.attrs)+len(attrs))
+	merged = append(merged, h.attrs...)
+	merged = append(merged, attrs...)
+	return &LogHandler{next: h.next.WithAttrs(attrs), client: h.client, attrs: merged}
+}
+
+func (h *LogHandler) WithGroup(name string) slog.Handler {
+	return &LogHandler{next: h.next.WithGroup(name), client: h.client, attrs: h.attrs}
+}
+
+// attrValue приводит значение атрибута к виду, который сохранится в JSON: ошибки
+// и длительности становятся строками.
+func attrValue(value slog.Value) any {
+	value = value.Resolve()
+	switch value.Kind() {
+	case slog.KindString, slog.KindInt64, slog.KindUint64, slog.KindFloat64, slog.KindBool:
+		return value.Any()
+	default:
+		return value.String()
+	}
+}
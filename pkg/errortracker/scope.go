@@ -0,0 +1,64 @@
+package errortracker
+
+import (
+	"context"
+	"sync"
+)
+
+type scopeKey struct{}
+
+// Scope - данные запроса, которые прикладываются ко всем его отчетам.
+type Scope struct {
+	mu        sync.Mutex
+	requestID string
+	userID    string
+	method    string
+	route     string
+	url       string
+	reported  bool
+}
+
+func NewScope(requestID, method, route, url string) *Scope {
+	return &Scope{requestID: requestID, method: method, route: route, url: url}
+}
+
+func WithScope(ctx context.Context, scope *Scope) context.Context {
+	return context.WithValue(ctx, scopeKey{}, scope)
+}
+
+func ScopeFromContext(ctx context.Context) *Scope {
+	scope, _ := ctx.Value(scopeKey{}).(*Scope)
+	return scope
+}
+
+// SetUser задает пользователя запроса, когда он становится известен.
+func (s *Scope) SetUser(id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.userID = id
+}
+
+// Reported сообщает, был ли по запросу хотя бы один отчет.
+func (s *Scope) Reported() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.reported
+}
+
+func (s *Scope) apply(event *Event) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.reported = true
+	if event.Tags == nil {
+		event.Tags = make(map[string]string)
+	}
+	event.Tags["request_id"] = s.requestID
+	if s.route != "" {
+		event.Tags["route"] = s.method + " " + s.route
+	}
+	if s.userID != "" {
+		event.User = &User{ID: s.userID}
+	}
+	event.Request = &Request{Method: s.method, URL: s.url}
+}
@@ -0,0 +1,103 @@
+package events
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"aggregator_db/pkg/httpclient"
+	"aggregator_db/pkg/region"
+	"aggregator_db/pkg/webhookclient"
+	"github.com/google/uuid"
+)
+
+// Event - доменное событие для внешних потребителей (маркетинг, BI).
+type Event struct {
+	ID         uuid.UUID `json:"id"`
+	Type       string    `json:"type"`
+	OccurredAt time.Time `json:"occurred_at"`
+	// SchemaVersion - версия схемы данных из реестра (GET /api/v1/event-schemas)
+	SchemaVersion int `json:"schema_version,omitempty"`
+	// Region - регион, в котором произошло событие; пуст без REGION
+	Region string      `json:"region,omitempty"`
+	Data   interface{} `json:"data"`
+}
+
+func New(eventType string, data interface{}) Event {
+	return Event{
+		ID:         uuid.New(),
+		Type:       eventType,
+		OccurredAt: time.Now().UTC(),
+		Region:     region.Current(),
+		Data:       data,
+	}
+}
+
+type Publisher interface {
+	Publish(ctx context.Context, event Event) error
+}
+
+// logPublisher пишет события в лог; используется, когда вебхук не настроен.
+type logPublisher struct {
+	logger *slog.Logger
+}
+
+func NewLogPublisher(logger *slog.Logger) Publisher {
+	return &logPublisher{logger: logger}
+}
+
+func (p *logPublisher) Publish(ctx context.Context, event Event) error {
+	p.logger.InfoContext(ctx, "event published",
+		slog.String("event_id", event.ID.String()),
+		slog.String("type", event.Type),
+		slog.Any("data", event.Data),
+	)
+	return nil
+}
+
+// webhookPublisher отправляет событие POST-запросом в JSON.
+// ID события передается как Idempotency-Key, поэтому клиент может безопасно повторять доставку.
+// С секретом тело подписывается HMAC (см. pkg/webhookclient).
+type webhookPublisher struct {
+	client *httpclient.Client
+	url    string
+	secret []byte
+}
+
+func NewWebhookPublisher(client *httpclient.Client, url, secret string) Publisher {
+	return &webhookPublisher{client: client, url: url, secret: []byte(secret)}
+}
+
+func (p *webhookPublisher) Publish(ctx context.Context, event Event) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(webhookclient.IDHeader, event.ID.String())
+	if len(p.secret) > 0 {
+		req.Header.Set(webhookclient.SignatureHeader, webhookclient.Sign(p.secret, time.Now(), body))
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		return fmt.Errorf("event webhook responded with status %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,350 @@
+package events
+
+import (
+	"context"
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"aggregator_db/pkg/domain"
+	"github.com/google/uuid"
+)
+
+// Схемы данных событий лежат в schemas/<name>.v<version>.json. Новая версия
+// добавляется отдельным файлом и должна быть совместима с предыдущей.
+//
+//go:embed schemas/*.json
+var schemaFiles embed.FS
+
+var (
+	ErrUnknownEventType = errors.New("no schema for event type")
+	ErrSchemaViolation  = errors.New("event data does not match schema")
+)
+
+var schemaFileName = regexp.MustCompile(`^([a-z_.]+)\.v([0-9]+)\.json$`)
+
+// Schema - подмножество JSON Schema, которого хватает для данных событий:
+// type, properties, required, additionalProperties, enum, format, pattern, minimum.
+type Schema struct {
+	Type                 string             `json:"type,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	AdditionalProperties *bool              `json:"additionalProperties,omitempty"`
+	Enum                 []interface{}      `json:"enum,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Pattern              string             `json:"pattern,omitempty"`
+	Minimum              *float64           `json:"minimum,omitempty"`
+	EventTypes           []string           `json:"x-event-types,omitempty"`
+
+	pattern *regexp.Regexp
+}
+
+func (s *Schema) compile() error {
+	if s.Pattern != "" {
+		re, err := regexp.Compile(s.Pattern)
+		if err != nil {
+			return err
+		}
+		s.pattern = re
+	}
+	for _, prop := range s.Properties {
+		if err := prop.compile(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Validate проверяет значение, разобранное encoding/json в interface{}.
+func (s *Schema) Validate(value interface{}) error {
+	return s.validate("$", value)
+}
+
+func (s *Schema) validate(at string, value interface{}) error {
+	switch s.Type {
+	case "object":
+		obj, ok := value.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("%s: expected object", at)
+		}
+		for _, name := range s.Required {
+			if _, ok := obj[name]; !ok {
+				return fmt.Errorf("%s.%s: required", at, name)
+			}
+		}
+		for name, field := range obj {
+			prop, ok := s.Properties[name]
+			if !ok {
+				if s.AdditionalProperties != nil && !*s.AdditionalProperties {
+					return fmt.Errorf("%s.%s: not allowed", at, name)
+				}
+				continue
+			}
+			if err := prop.validate(at+"."+name, field); err != nil {
+				return err
+			}
+		}
+		return nil
+	case "string":
+		str, ok := value.(string)
+		if !ok {
+			return fmt.Errorf("%s: expected string", at)
+		}
+		if s.pattern != nil && !s.pattern.MatchString(str) {
+			return fmt.Errorf("%s: %q does not match %s", at, str, s.Pattern)
+		}
+		if err := validateFormat(s.Format, str); err != nil {
+			return fmt.Errorf("%s: %w", at, err)
+		}
+	case "integer", "number":
+		num, ok := value.(float64)
+		if !ok || (s.Type == "integer" && num != math.Trunc(num)) {
+			return fmt.Errorf("%s: expected %s", at, s.Type)
+		}
+		if s.Minimum != nil && num < *s.Minimum {
+			return fmt.Errorf("%s: %v is less than %v", at, num, *s.Minimum)
+		}
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			return fmt.Errorf("%s: expected boolean", at)
+		}
+	}
+
+	if len(s.Enum) > 0 {
+		for _, allowed := range s.Enum {
+			if allowed == value {
+				return nil
+			}
+		}
+		return fmt.Errorf("%s: %v is not one of %v", at, value, s.Enum)
+	}
+	return nil
+}
+
+func validateFormat(format, value string) error {
+	var err error
+	switch format {
+	case "uuid":
+		_, err = uuid.Parse(value)
+	case "date":
+		_, err = time.Parse(time.DateOnly, value)
+	case "date-time":
+		_, err = time.Parse(time.RFC3339, value)
+	}
+	if err != nil {
+		return fmt.Errorf("%q is not a valid %s", value, format)
+	}
+	return nil
+}
+
+// CheckCompatibility сравнивает новую версию схемы с предыдущей и возвращает
+// нарушения совместимости: данные новой версии должны читаться потребителями
+// старой, а старые данные - оставаться валидными для новой. Поэтому поля нельзя
+// удалять, менять их тип и делать обязательность строже, а список enum - сужать.
+func CheckCompatibility(previous, next *Schema) []string {
+	var problems []string
+	checkCompatibility("$", previous, next, &problems)
+	return problems
+}
+
+func checkCompatibility(at string, previous, next *Schema, problems *[]string) {
+	if previous.Type != next.Type {
+		*problems = append(*problems, fmt.Sprintf("%s: type changed from %s to %s", at, previous.Type, next.Type))
+		return
+	}
+
+	for _, value := range previous.Enum {
+		if len(next.Enum) > 0 && !containsValue(next.Enum, value) {
+			*problems = append(*problems, fmt.Sprintf("%s: enum value %v removed", at, value))
+		}
+	}
+
+	for name, prop := range previous.Properties {
+		nextProp, ok := next.Properties[name]
+		if !ok {
+			*problems = append(*problems, fmt.Sprintf("%s.%s: property removed", at, name))
+			continue
+		}
+		checkCompatibility(at+"."+name, prop, nextProp, problems)
+	}
+
+	for _, name := range next.Required {
+		if !containsString(previous.Required, name) {
+			*problems = append(*problems, fmt.Sprintf("%s.%s: became required", at, name))
+		}
+	}
+	for _, name := range previous.Required {
+		if !containsString(next.Required, name) {
+			*problems = append(*problems, fmt.Sprintf("%s.%s: no longer required", at, name))
+		}
+	}
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func containsValue(values []interface{}, value interface{}) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+type versionedSchema struct {
+	info   domain.EventSchema
+	schema *Schema
+}
+
+// Registry - реестр схем событий со всеми версиями.
+type Registry struct {
+	schemas []*versionedSchema
+	// latest - последняя версия схемы для каждого типа события
+	latest map[string]*versionedSchema
+}
+
+// LoadRegistry читает встроенные схемы и проверяет совместимость соседних версий.
+func LoadRegistry() (*Registry, error) {
+	entries, err := schemaFiles.ReadDir("schemas")
+	if err != nil {
+		return nil, err
+	}
+
+	byName := make(map[string][]*versionedSchema)
+	for _, entry := range entries {
+		match := schemaFileName.FindStringSubmatch(entry.Name())
+		if match == nil {
+			return nil, fmt.Errorf("schema file %s: expected <name>.v<version>.json", entry.Name())
+		}
+		version, _ := strconv.Atoi(match[2])
+
+		raw, err := schemaFiles.ReadFile(path.Join("schemas", entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		var schema Schema
+		if err := json.Unmarshal(raw, &schema); err != nil {
+			return nil, fmt.Errorf("schema %s: %w", entry.Name(), err)
+		}
+		if err := schema.compile(); err != nil {
+			return nil, fmt.Errorf("schema %s: %w", entry.Name(), err)
+		}
+
+		byName[match[1]] = append(byName[match[1]], &versionedSchema{
+			info: domain.EventSchema{
+				Name:       match[1],
+				Version:    version,
+				EventTypes: schema.EventTypes,
+				Schema:     raw,
+			},
+			schema: &schema,
+		})
+	}
+
+	return newRegistry(byName)
+}
+
+func newRegistry(byName map[string][]*versionedSchema) (*Registry, error) {
+	registry := &Registry{latest: make(map[string]*versionedSchema)}
+
+	names := make([]string, 0, len(byName))
+	for name := range byName {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		versions := byName[name]
+		sort.Slice(versions, func(i, j int) bool { return versions[i].info.Version < versions[j].info.Version })
+
+		for i, v := range versions {
+			if v.info.Version != i+1 {
+				return nil, fmt.Errorf("schema %s: versions must go 1, 2, ... without gaps", name)
+			}
+			if i > 0 {
+				if problems := CheckCompatibility(versions[i-1].schema, v.schema); len(problems) > 0 {
+					return nil, fmt.Errorf("schema %s v%d is incompatible with v%d: %s",
+						name, v.info.Version, i, strings.Join(problems, "; "))
+				}
+			}
+			registry.schemas = append(registry.schemas, v)
+		}
+
+		for _, eventType := range versions[len(versions)-1].info.EventTypes {
+			if other, ok := registry.latest[eventType]; ok {
+				return nil, fmt.Errorf("event type %s is described by both %s and %s", eventType, other.info.Name, name)
+			}
+			registry.latest[eventType] = versions[len(versions)-1]
+		}
+	}
+	return registry, nil
+}
+
+// List возвращает все версии схем; с eventType - только схемы этого типа события.
+func (r *Registry) List(eventType string) []domain.EventSchema {
+	result := make([]domain.EventSchema, 0, len(r.schemas))
+	for _, s := range r.schemas {
+		if eventType == "" || containsString(s.info.EventTypes, eventType) {
+			result = append(result, s.info)
+		}
+	}
+	return result
+}
+
+// Validate проверяет данные события по последней версии схемы его типа
+// и возвращает эту версию.
+func (r *Registry) Validate(event Event) (int, error) {
+	schema, ok := r.latest[event.Type]
+	if !ok {
+		return 0, fmt.Errorf("%w: %s", ErrUnknownEventType, event.Type)
+	}
+
+	raw, err := json.Marshal(event.Data)
+	if err != nil {
+		return 0, err
+	}
+	var data interface{}
+	if err := json.Unmarshal(raw, &data); err != nil {
+		return 0, err
+	}
+
+	if err := schema.schema.Validate(data); err != nil {
+		return 0, fmt.Errorf("%w: %s v%d: %v", ErrSchemaViolation, schema.info.Name, schema.info.Version, err)
+	}
+	return schema.info.Version, nil
+}
+
+// validatingPublisher не выпускает события, не прошедшие проверку схемой,
+// и проставляет версию схемы в конверт.
+type validatingPublisher struct {
+	next     Publisher
+	registry *Registry
+}
+
+func NewValidatingPublisher(next Publisher, registry *Registry) Publisher {
+	return &validatingPublisher{next: next, registry: registry}
+}
+
+func (p *validatingPublisher) Publish(ctx context.Context, event Event) error {
+	version, err := p.registry.Validate(event)
+	if err != nil {
+		return err
+	}
+	event.SchemaVersion = version
+	return p.next.Publish(ctx, event)
+}
@@ -0,0 +1,162 @@
+package events
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"aggregator_db/pkg/domain"
+	"github.com/google/uuid"
+)
+
+func TestRegistryValidatesPublishedEvents(t *testing.T) {
+	registry, err := LoadRegistry()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	id := uuid.New()
+	price := domain.NewMoney(89999, domain.DefaultCurrency)
+	month := domain.BilledMonth{SubscriptionID: id, UserID: id, ServiceName: "Netflix", Month: "07-2025", Price: 900, PriceAmount: price, Class: domain.BillingNew}
+	valid := []Event{
+		New("subscription.new", month),
+		New("subscription.downgraded", &month),
+		New("subscription.renewed", domain.SubscriptionRenewal{SubscriptionID: id, UserID: id, ServiceName: "Netflix", Price: 900, PriceAmount: price, PreviousEndDate: "09-2025", EndDate: "10-2025"}),
+		New("spend.weekly_change", &domain.SpendChange{UserID: id, Period: domain.SpendWeek, PreviousStart: "2025-07-07", CurrentStart: "2025-07-14", Previous: 900, Current: 400,
+			Currency: domain.DefaultCurrency, PreviousAmount: price, CurrentAmount: domain.NewMoney(40000, domain.DefaultCurrency), ChangePercent: -55.56, ThresholdPercent: 20}),
+		// Данные по v1, без сумм в минорных единицах, остаются валидными
+		New("subscription.renewed", map[string]interface{}{"subscription_id": id, "user_id": id, "service_name": "x", "price": 1, "previous_end_date": "09-2025", "end_date": "10-2025"}),
+	}
+	for _, event := range valid {
+		if version, err := registry.Validate(event); err != nil || version != 2 {
+			t.Errorf("%s: version %d, error %v", event.Type, version, err)
+		}
+	}
+
+	invalid := map[string]Event{
+		"unknown class":  New("subscription.new", domain.BilledMonth{SubscriptionID: id, UserID: id, Month: "07-2025", Class: "gold"}),
+		"bad period":     New("subscription.renewed", domain.SubscriptionRenewal{SubscriptionID: id, UserID: id, PreviousEndDate: "2025-09", EndDate: "10-2025"}),
+		"missing field":  New("spend.monthly_change", map[string]interface{}{"user_id": id}),
+		"extra field":    New("subscription.renewed", map[string]interface{}{"subscription_id": id, "user_id": id, "service_name": "x", "price": 1, "previous_end_date": "09-2025", "end_date": "10-2025", "note": "x"}),
+		"bad amount":     New("subscription.new", map[string]interface{}{"subscription_id": id, "user_id": id, "service_name": "x", "month": "07-2025", "price": 1, "class": "new", "price_amount": map[string]string{"amount": "1,50", "currency": "RUB"}}),
+		"not an integer": New("subscription.renewed", map[string]interface{}{"subscription_id": id, "user_id": id, "service_name": "x", "price": 1.5, "previous_end_date": "09-2025", "end_date": "10-2025"}),
+	}
+	for name, event := range invalid {
+		if _, err := registry.Validate(event); !errors.Is(err, ErrSchemaViolation) {
+			t.Errorf("%s: got %v, want schema violation", name, err)
+		}
+	}
+
+	alert := domain.BudgetAlert{BudgetID: id, UserID: id, Category: "streaming", Month: "07-2025", Limit: price, Spent: price,
+		UtilizationPercent: 100, AlertPercent: domain.DefaultBudgetAlertPercent, State: domain.BudgetWarning}
+	if version, err := registry.Validate(New("budget.warning", alert)); err != nil || version != 1 {
+		t.Errorf("budget.warning: version %d, error %v", version, err)
+	}
+	alert.State = domain.BudgetOK
+	if _, err := registry.Validate(New("budget.exceeded", alert)); !errors.Is(err, ErrSchemaViolation) {
+		t.Errorf("budget alert in state ok: got %v, want schema violation", err)
+	}
+
+	burn := domain.SLOBurnAlert{Route: "GET /api/v1/subscriptions/calculate", Objective: domain.SLOLatency, Severity: domain.SLOPage,
+		Target: 99, Threshold: 14.4, LongWindow: "1h", LongBurnRate: 20, ShortWindow: "5m", ShortBurnRate: 31.5}
+	if version, err := registry.Validate(New("slo.burn_rate", burn)); err != nil || version != 1 {
+		t.Errorf("slo.burn_rate: version %d, error %v", version, err)
+	}
+	burn.Route = "/api/v1/subscriptions/calculate"
+	if _, err := registry.Validate(New("slo.burn_rate", burn)); !errors.Is(err, ErrSchemaViolation) {
+		t.Errorf("slo alert without method: got %v, want schema violation", err)
+	}
+
+	daily := domain.DailyTotals{TenantID: "default", Date: "2025-07-14", Subscriptions: 3, Month: "07-2025",
+		ByStatus:     map[domain.SubscriptionStatus]int{domain.StatusActive: 2, domain.StatusPaused: 0, domain.StatusCancelled: 1, domain.StatusExpired: 0},
+		MonthlySpend: map[domain.Currency]string{domain.DefaultCurrency: "899.99"}}
+	if version, err := registry.Validate(New("bi.daily_totals", daily)); err != nil || version != 1 {
+		t.Errorf("bi.daily_totals: version %d, error %v", version, err)
+	}
+	daily.ByStatus = map[domain.SubscriptionStatus]int{domain.StatusActive: 2}
+	if _, err := registry.Validate(New("bi.daily_totals", daily)); !errors.Is(err, ErrSchemaViolation) {
+		t.Errorf("daily totals without all statuses: got %v, want schema violation", err)
+	}
+
+	statement := domain.Statement{UserID: id, Month: "07-2025", PreviousMonth: "06-2025",
+		Lines:  []domain.StatementLine{{ServiceName: "Netflix", Currency: domain.DefaultCurrency, Previous: price, Current: price, Change: domain.StatementUnchanged}},
+		Totals: []domain.StatementTotal{{Currency: domain.DefaultCurrency, Previous: price, Current: price}}}
+	if version, err := registry.Validate(New("statement.monthly", statement)); err != nil || version != 1 {
+		t.Errorf("statement.monthly: version %d, error %v", version, err)
+	}
+	statement.Month = "2025-07"
+	if _, err := registry.Validate(New("statement.monthly", statement)); !errors.Is(err, ErrSchemaViolation) {
+		t.Errorf("statement with bad month: got %v, want schema violation", err)
+	}
+
+	escalation := domain.AlertEscalation{AlertID: uuid.New(), UserID: id, RecipientID: uuid.New(), Type: "budget.exceeded",
+		Step: 1, CreatedAt: time.Date(2025, 10, 23, 15, 4, 5, 0, time.UTC), Data: json.RawMessage(`{"category":"streaming"}`)}
+	if version, err := registry.Validate(New("alert.escalated", escalation)); err != nil || version != 1 {
+		t.Errorf("alert.escalated: version %d, error %v", version, err)
+	}
+	escalation.Step = 0
+	if _, err := registry.Validate(New("alert.escalated", escalation)); !errors.Is(err, ErrSchemaViolation) {
+		t.Errorf("escalation without step: got %v, want schema violation", err)
+	}
+
+	if _, err := registry.Validate(New("subscription.archived", month)); !errors.Is(err, ErrUnknownEventType) {
+		t.Errorf("unknown event type: got %v", err)
+	}
+}
+
+func TestCheckCompatibility(t *testing.T) {
+	const v1 = `{"type": "object", "required": ["id", "status"], "properties": {
+		"id": {"type": "string"}, "status": {"type": "string", "enum": ["active", "paused"]}}}`
+
+	cases := []struct {
+		name     string
+		next     string
+		problems []string
+	}{
+		{
+			name: "optional field and enum value added",
+			next: `{"type": "object", "required": ["id", "status"], "properties": {
+				"id": {"type": "string"}, "status": {"type": "string", "enum": ["active", "paused", "expired"]}, "note": {"type": "string"}}}`,
+		},
+		{
+			name: "breaking changes",
+			next: `{"type": "object", "required": ["id", "note"], "properties": {
+				"id": {"type": "integer"}, "note": {"type": "string"}}}`,
+			problems: []string{"$.id: type changed", "$.status: property removed", "$.note: became required", "$.status: no longer required"},
+		},
+		{
+			name: "enum narrowed",
+			next: `{"type": "object", "required": ["id", "status"], "properties": {
+				"id": {"type": "string"}, "status": {"type": "string", "enum": ["active"]}}}`,
+			problems: []string{"$.status: enum value paused removed"},
+		},
+	}
+
+	var previous Schema
+	if err := json.Unmarshal([]byte(v1), &previous); err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range cases {
+		var next Schema
+		if err := json.Unmarshal([]byte(tc.next), &next); err != nil {
+			t.Fatal(err)
+		}
+
+		problems := CheckCompatibility(&previous, &next)
+		if len(problems) != len(tc.problems) {
+			t.Errorf("%s: got %v, want %d problems", tc.name, problems, len(tc.problems))
+			continue
+		}
+		for _, want := range tc.problems {
+			found := false
+			for _, got := range problems {
+				found = found || strings.HasPrefix(got, want)
+			}
+			if !found {
+				t.Errorf("%s: %q not reported in %v", tc.name, want, problems)
+			}
+		}
+	}
+}
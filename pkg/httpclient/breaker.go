@@ -0,0 +1,98 @@
+package httpclient
+
+import (
+	"sync"
+	"time"
+)
+
+type breakerState int
+
+const (
+	stateClosed breakerState = iota
+	stateOpen
+	stateHalfOpen
+)
+
+type breaker struct {
+	mu          sync.Mutex
+	state       breakerState
+	failures    int
+	threshold   int
+	openTimeout time.Duration
+	openedAt    time.Time
+}
+
+func newBreaker(threshold int, openTimeout time.Duration) *breaker {
+	return &breaker{threshold: threshold, openTimeout: openTimeout}
+}
+
+func (b *breaker) allow() bool {
+	if b.threshold <= 0 {
+		return true
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case stateOpen:
+		if time.Since(b.openedAt) < b.openTimeout {
+			return false
+		}
+		// Пропускаем один пробный запрос
+		b.state = stateHalfOpen
+		return true
+	case stateHalfOpen:
+		return false
+	default:
+		return true
+	}
+}
+
+// retryAfter - сколько осталось до пробного запроса; 0, если breaker закрыт.
+func (b *breaker) retryAfter() time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case stateOpen:
+		return max(b.openTimeout-time.Since(b.openedAt), 0)
+	case stateHalfOpen:
+		// Пробный запрос еще не завершился
+		return time.Second
+	default:
+		return 0
+	}
+}
+
+func (b *breaker) success() {
+	b.mu.Lock()
+	b.state = stateClosed
+	b.failures = 0
+	b.mu.Unlock()
+}
+
+// cancel учитывает запрос, отмененный вызывающим кодом: счетчик ошибок не
+// меняется, а отмененный пробный запрос можно повторить сразу.
+func (b *breaker) cancel() {
+	b.mu.Lock()
+	if b.state == stateHalfOpen {
+		b.state = stateOpen
+	}
+	b.mu.Unlock()
+}
+
+func (b *breaker) failure() {
+	if b.threshold <= 0 {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failures++
+	if b.state == stateHalfOpen || b.failures >= b.threshold {
+		b.state = stateOpen
+		b.openedAt = time.Now()
+	}
+}
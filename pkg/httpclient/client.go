@@ -0,0 +1,258 @@
+package httpclient
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"aggregator_db/pkg/metrics"
+	"aggregator_db/pkg/tracing"
+)
+
+var (
+	requestDuration = metrics.NewHistogramVec(
+		"http_client_request_duration_seconds",
+		"Длительность исходящих HTTP-запросов",
+		nil,
+		"client", "method", "status",
+	)
+	requestRetries = metrics.NewCounterVec(
+		"http_client_retries_total",
+		"Количество повторов исходящих HTTP-запросов",
+		"client",
+	)
+	breakerRejections = metrics.NewCounterVec(
+		"http_client_circuit_open_total",
+		"Количество запросов, отклоненных открытым circuit breaker",
+		"client",
+	)
+)
+
+var ErrCircuitOpen = errors.New("circuit breaker is open")
+
+type Config struct {
+	// Name используется как метка в метриках и логах.
+	Name string
+
+	Timeout      time.Duration
+	MaxRetries   int
+	RetryBackoff time.Duration
+	// MaxBackoff ограничивает паузу перед повтором, в том числе из Retry-After; 0 - без ограничения.
+	MaxBackoff time.Duration
+
+	// Breaker открывается после FailureThreshold подряд неудачных запросов
+	// и пропускает пробный запрос через OpenTimeout.
+	FailureThreshold int
+	OpenTimeout      time.Duration
+}
+
+func DefaultConfig(name string) Config {
+	return Config{
+		Name:             name,
+		Timeout:          10 * time.Second,
+		MaxRetries:       2,
+		RetryBackoff:     200 * time.Millisecond,
+		MaxBackoff:       5 * time.Second,
+		FailureThreshold: 5,
+		OpenTimeout:      30 * time.Second,
+	}
+}
+
+// Client - общий исходящий HTTP-клиент для вебхуков, провайдеров курсов и интеграций.
+type Client struct {
+	cfg     Config
+	http    *http.Client
+	breaker *breaker
+	logger  *slog.Logger
+}
+
+func New(cfg Config, logger *slog.Logger) *Client {
+	transport := &http.Transport{
+		Proxy: http.ProxyFromEnvironment,
+		DialContext: (&net.Dialer{
+			Timeout:   5 * time.Second,
+			KeepAlive: 30 * time.Second,
+		}).DialContext,
+		MaxIdleConns:          100,
+		MaxIdleConnsPerHost:   10,
+		IdleConnTimeout:       90 * time.Second,
+		TLSHandshakeTimeout:   5 * time.Second,
+		ResponseHeaderTimeout: cfg.Timeout,
+	}
+
+	return &Client{
+		cfg: cfg,
+		http: &http.Client{
+			Timeout:   cfg.Timeout,
+			Transport: transport,
+		},
+		breaker: newBreaker(cfg.FailureThreshold, cfg.OpenTimeout),
+		logger:  logger,
+	}
+}
+
+// RetryAfter сообщает, через сколько открытый circuit breaker пропустит запрос; 0, если он закрыт.
+func (c *Client) RetryAfter() time.Duration {
+	return c.breaker.retryAfter()
+}
+
+// Do выполняет запрос с повторами. Тело запроса буферизуется,
+// чтобы его можно было отправить повторно. Если пауза перед повтором не
+// укладывается в дедлайн контекста, возвращается последний ответ.
+func (c *Client) Do(req *http.Request) (*http.Response, error) {
+	ctx, span := tracing.StartSpan(req.Context(), "http.client "+c.cfg.Name,
+		slog.String("http.method", req.Method),
+		slog.String("http.url", req.URL.String()),
+	)
+	defer span.End()
+
+	var body []byte
+	if req.Body != nil {
+		var err error
+		body, err = io.ReadAll(req.Body)
+		_ = req.Body.Close()
+		if err != nil {
+			span.RecordError(err)
+			return nil, fmt.Errorf("read request body: %w", err)
+		}
+	}
+
+	backoff := c.cfg.RetryBackoff
+	for attempt := 0; ; attempt++ {
+		if !c.breaker.allow() {
+			breakerRejections.Inc(c.cfg.Name)
+			span.RecordError(ErrCircuitOpen)
+			return nil, ErrCircuitOpen
+		}
+
+		attemptReq := req.Clone(ctx)
+		if body != nil {
+			attemptReq.Body = io.NopCloser(bytes.NewReader(body))
+			attemptReq.ContentLength = int64(len(body))
+		}
+		attemptReq.Header.Set(tracing.TraceparentHeader, tracing.FormatTraceparent(tracing.SpanContextFromContext(ctx)))
+
+		start := time.Now()
+		resp, err := c.http.Do(attemptReq)
+		requestDuration.Observe(time.Since(start).Seconds(), c.cfg.Name, req.Method, statusLabel(resp, err))
+
+		retryable := isRetryable(resp, err)
+		switch {
+		case errors.Is(err, context.Canceled):
+			// Запрос отменил вызывающий код, о состоянии сервиса это ничего не говорит
+			c.breaker.cancel()
+		case retryable:
+			c.breaker.failure()
+		default:
+			c.breaker.success()
+		}
+
+		wait := c.retryWait(ctx, backoff, resp)
+		if !retryable || attempt >= c.cfg.MaxRetries || !idempotent(req) || wait < 0 {
+			if err != nil {
+				span.RecordError(err)
+			} else {
+				span.SetAttributes(slog.Int("http.status_code", resp.StatusCode))
+			}
+			return resp, err
+		}
+
+		if resp != nil {
+			_, _ = io.Copy(io.Discard, resp.Body)
+			_ = resp.Body.Close()
+		}
+
+		requestRetries.Inc(c.cfg.Name)
+		c.logger.WarnContext(ctx, "retrying outbound request",
+			slog.String("client", c.cfg.Name),
+			slog.String("url", req.URL.String()),
+			slog.Int("attempt", attempt+1),
+		)
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(wait):
+		}
+		backoff *= 2
+	}
+}
+
+// retryWait - пауза перед повтором: backoff или Retry-After ответа, не больше
+// MaxBackoff. Отрицательна, если пауза не укладывается в дедлайн контекста.
+func (c *Client) retryWait(ctx context.Context, backoff time.Duration, resp *http.Response) time.Duration {
+	wait := backoff
+	if resp != nil {
+		if retryAfter := parseRetryAfter(resp.Header.Get("Retry-After")); retryAfter > 0 {
+			wait = retryAfter
+		}
+	}
+	if c.cfg.MaxBackoff > 0 {
+		wait = min(wait, c.cfg.MaxBackoff)
+	}
+	if deadline, ok := ctx.Deadline(); ok && wait >= time.Until(deadline) {
+		return -1
+	}
+	return wait
+}
+
+func (c *Client) Get(ctx context.Context, url string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	return c.Do(req)
+}
+
+func (c *Client) Post(ctx context.Context, url, contentType string, body []byte) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", contentType)
+	return c.Do(req)
+}
+
+func isRetryable(resp *http.Response, err error) bool {
+	if err != nil {
+		return !errors.Is(err, context.Canceled)
+	}
+	return resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= http.StatusInternalServerError
+}
+
+// idempotent: POST/PATCH повторяются только при наличии Idempotency-Key,
+// который проставляет вызывающий код.
+func idempotent(req *http.Request) bool {
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return req.Header.Get("Idempotency-Key") != ""
+}
+
+func statusLabel(resp *http.Response, err error) string {
+	if err != nil {
+		return "error"
+	}
+	return strconv.Itoa(resp.StatusCode)
+}
+
+func parseRetryAfter(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		return time.Duration(seconds) * time.Second
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		return time.Until(at)
+	}
+	return 0
+}
@@ -0,0 +1,247 @@
+package httpclient
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func newTestClient(cfg Config) *Client {
+	return New(cfg, slog.New(slog.NewTextHandler(io.Discard, nil)))
+}
+
+func testConfig() Config {
+	cfg := DefaultConfig("test")
+	cfg.RetryBackoff = time.Millisecond
+	cfg.FailureThreshold = 0
+	return cfg
+}
+
+func TestClientRetriesIdempotentOnly(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	client := newTestClient(testConfig())
+	tests := []struct {
+		name   string
+		method string
+		key    string
+		want   int32
+	}{
+		{name: "get", method: http.MethodGet, want: 3},
+		{name: "put", method: http.MethodPut, want: 3},
+		{name: "post", method: http.MethodPost, want: 1},
+		{name: "post with idempotency key", method: http.MethodPost, key: "k-1", want: 3},
+		{name: "patch", method: http.MethodPatch, want: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls.Store(0)
+			req, err := http.NewRequest(tt.method, server.URL, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.key != "" {
+				req.Header.Set("Idempotency-Key", tt.key)
+			}
+			resp, err := client.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			_ = resp.Body.Close()
+			if resp.StatusCode != http.StatusServiceUnavailable {
+				t.Errorf("status = %d, want %d", resp.StatusCode, http.StatusServiceUnavailable)
+			}
+			if got := calls.Load(); got != tt.want {
+				t.Errorf("calls = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestClientRetryAfterCapped(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) == 1 {
+			w.Header().Set("Retry-After", "60")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	t.Run("max backoff", func(t *testing.T) {
+		calls.Store(0)
+		cfg := testConfig()
+		cfg.MaxBackoff = 20 * time.Millisecond
+		client := newTestClient(cfg)
+
+		start := time.Now()
+		resp, err := client.Get(context.Background(), server.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_ = resp.Body.Close()
+		if resp.StatusCode != http.StatusOK || calls.Load() != 2 {
+			t.Errorf("status = %d after %d calls, want 200 after 2", resp.StatusCode, calls.Load())
+		}
+		if elapsed := time.Since(start); elapsed > time.Second {
+			t.Errorf("waited %s, Retry-After is not capped", elapsed)
+		}
+	})
+
+	t.Run("context deadline", func(t *testing.T) {
+		calls.Store(0)
+		cfg := testConfig()
+		cfg.MaxBackoff = 0
+		client := newTestClient(cfg)
+
+		// Пауза из Retry-After не укладывается в дедлайн: повтора нет, возвращается ответ 429
+		ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+		defer cancel()
+		start := time.Now()
+		resp, err := client.Get(ctx, server.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_ = resp.Body.Close()
+		if resp.StatusCode != http.StatusTooManyRequests || calls.Load() != 1 {
+			t.Errorf("status = %d after %d calls, want 429 after 1", resp.StatusCode, calls.Load())
+		}
+		if elapsed := time.Since(start); elapsed > 250*time.Millisecond {
+			t.Errorf("waited %s for a retry that cannot fit the deadline", elapsed)
+		}
+	})
+}
+
+func TestClientBreaker(t *testing.T) {
+	var healthy atomic.Bool
+	var probe func() error
+	var probeErr error
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !healthy.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		if probe != nil {
+			probeErr = probe()
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	cfg := testConfig()
+	cfg.MaxRetries = 0
+	cfg.FailureThreshold = 2
+	cfg.OpenTimeout = 50 * time.Millisecond
+	client := newTestClient(cfg)
+	get := func() (int, error) {
+		resp, err := client.Get(context.Background(), server.URL)
+		if err != nil {
+			return 0, err
+		}
+		_ = resp.Body.Close()
+		return resp.StatusCode, nil
+	}
+
+	for i := 0; i < cfg.FailureThreshold; i++ {
+		if status, err := get(); err != nil || status != http.StatusServiceUnavailable {
+			t.Fatalf("request %d: status %d, error %v", i+1, status, err)
+		}
+	}
+	if _, err := get(); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("open breaker: error = %v, want %v", err, ErrCircuitOpen)
+	}
+	if client.RetryAfter() <= 0 {
+		t.Error("open breaker: RetryAfter should be positive")
+	}
+
+	// Через OpenTimeout проходит один пробный запрос, остальные отклоняются, пока он не завершится
+	time.Sleep(cfg.OpenTimeout)
+	healthy.Store(true)
+	probe = func() error {
+		_, err := get()
+		return err
+	}
+	if status, err := get(); err != nil || status != http.StatusOK {
+		t.Fatalf("probe: status %d, error %v", status, err)
+	}
+	if !errors.Is(probeErr, ErrCircuitOpen) {
+		t.Errorf("half-open breaker: error = %v, want %v", probeErr, ErrCircuitOpen)
+	}
+
+	probe = nil
+	if client.RetryAfter() != 0 {
+		t.Errorf("closed breaker: RetryAfter = %s, want 0", client.RetryAfter())
+	}
+	if status, err := get(); err != nil || status != http.StatusOK {
+		t.Errorf("closed breaker: status %d, error %v", status, err)
+	}
+}
+
+func TestClientCanceledRequestKeepsBreaker(t *testing.T) {
+	started := make(chan struct{}, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/slow":
+			started <- struct{}{}
+			<-r.Context().Done()
+		case "/fail":
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+
+	cfg := testConfig()
+	cfg.MaxRetries = 0
+	cfg.FailureThreshold = 1
+	cfg.OpenTimeout = 20 * time.Millisecond
+	client := newTestClient(cfg)
+	canceled := func() error {
+		ctx, cancel := context.WithCancel(context.Background())
+		go func() {
+			<-started
+			cancel()
+		}()
+		_, err := client.Get(ctx, server.URL+"/slow")
+		return err
+	}
+
+	if err := canceled(); !errors.Is(err, context.Canceled) {
+		t.Fatalf("error = %v, want %v", err, context.Canceled)
+	}
+	if client.RetryAfter() != 0 {
+		t.Fatal("canceled request opened the breaker")
+	}
+
+	resp, err := client.Get(context.Background(), server.URL+"/fail")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = resp.Body.Close()
+	time.Sleep(cfg.OpenTimeout)
+
+	// Отмененный пробный запрос не закрывает breaker, но следующий запрос снова пробный
+	if err := canceled(); !errors.Is(err, context.Canceled) {
+		t.Fatalf("probe: error = %v, want %v", err, context.Canceled)
+	}
+	resp, err = client.Get(context.Background(), server.URL+"/fail")
+	if err != nil {
+		t.Fatalf("probe after a canceled one: %v", err)
+	}
+	_ = resp.Body.Close()
+	if _, err := client.Get(context.Background(), server.URL); !errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("failed probe: error = %v, want %v", err, ErrCircuitOpen)
+	}
+}
@@ -3,6 +3,8 @@ package logger
 import (
 	"log/slog"
 	"os"
+
+	"aggregator_db/pkg/tracing"
 )
 
 func New(level string) *slog.Logger {
@@ -25,6 +27,7 @@ func New(level string) *slog.Logger {
 		Level: logLevel,
 	}
 
-	handler := slog.NewJSONHandler(os.Stdout, opts)
+	// Строки лога запроса получают его метки: маршрут, тенант и хэш ключа API
+	handler := tracing.NewLabelsHandler(slog.NewJSONHandler(os.Stdout, opts))
 	return slog.New(handler)
 }
@@ -0,0 +1,74 @@
+package mailer
+
+import (
+	"bytes"
+	"encoding/base64"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/mail"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBuild(t *testing.T) {
+	csv := []byte(strings.Repeat("consumer,metric,quantity\n", 10))
+	raw, err := Build("exports@example.com", Message{
+		To:          "admin@example.com",
+		Subject:     "Выгрузка готова",
+		Body:        "Файл во вложении",
+		Attachments: []Attachment{{Name: "usage.csv", ContentType: "text/csv", Data: csv}},
+	}, time.Date(2025, 10, 1, 12, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	msg, err := mail.ReadMessage(bytes.NewReader(raw))
+	if err != nil {
+		t.Fatal(err)
+	}
+	subject, err := new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject"))
+	if err != nil || subject != "Выгрузка готова" {
+		t.Errorf("subject = %q, %v", subject, err)
+	}
+
+	_, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	reader := multipart.NewReader(msg.Body, params["boundary"])
+
+	var parts []string
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		// multipart.Reader декодирует quoted-printable, base64 остается закодированным
+		data, _ := io.ReadAll(part)
+		parts = append(parts, part.FileName()+":"+string(data))
+	}
+	if len(parts) != 2 || !strings.HasPrefix(parts[1], "usage.csv:") {
+		t.Fatalf("unexpected parts: %q", parts)
+	}
+	lines := strings.Split(strings.TrimSpace(strings.TrimPrefix(parts[1], "usage.csv:")), "\r\n")
+	for _, line := range lines {
+		if len(line) > 76 {
+			t.Errorf("base64 line longer than 76 chars: %d", len(line))
+		}
+	}
+	decoded, err := base64.StdEncoding.DecodeString(strings.Join(lines, ""))
+	if err != nil || !bytes.Equal(decoded, csv) {
+		t.Errorf("attachment does not round-trip: %v", err)
+	}
+}
+
+func TestBuildRejectsHeaderInjection(t *testing.T) {
+	if _, err := Build("a@example.com", Message{To: "b@example.com\r\nBcc: c@example.com"}, time.Now()); err == nil {
+		t.Error("expected an error for a line break in To")
+	}
+}
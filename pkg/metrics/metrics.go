@@ -0,0 +1,366 @@
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// DefaultBuckets - границы гистограммы по умолчанию (в секундах).
+var DefaultBuckets = []float64{0.001, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+type collector interface {
+	write(w io.Writer, constLabels []string)
+}
+
+// Registry хранит метрики и отдает их в текстовом формате Prometheus.
+type Registry struct {
+	mu         sync.Mutex
+	collectors []collector
+	names      map[string]collector
+	// constLabels - пары имя/значение, добавляемые ко всем рядам (например, region)
+	constLabels []string
+}
+
+func NewRegistry() *Registry {
+	return &Registry{names: make(map[string]collector)}
+}
+
+// Default - реестр процесса, используется пакетными функциями.
+var Default = NewRegistry()
+
+func (r *Registry) register(name string, c collector) collector {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if existing, ok := r.names[name]; ok {
+		return existing
+	}
+	r.names[name] = c
+	r.collectors = append(r.collectors, c)
+	return c
+}
+
+// SetConstLabels задает метки, которые добавляются ко всем рядам реестра: пары имя, значение.
+func (r *Registry) SetConstLabels(pairs ...string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.constLabels = append([]string(nil), pairs...)
+}
+
+// SetConstLabels задает метки всех рядов реестра процесса.
+func SetConstLabels(pairs ...string) {
+	Default.SetConstLabels(pairs...)
+}
+
+func (r *Registry) Write(w io.Writer) {
+	r.mu.Lock()
+	collectors := make([]collector, len(r.collectors))
+	copy(collectors, r.collectors)
+	constLabels := r.constLabels
+	r.mu.Unlock()
+
+	for _, c := range collectors {
+		c.write(w, constLabels)
+	}
+}
+
+func (r *Registry) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		r.Write(w)
+	})
+}
+
+func Handler() http.Handler {
+	return Default.Handler()
+}
+
+type labelSet struct {
+	names []string
+}
+
+func (l labelSet) key(values []string) string {
+	if len(values) != len(l.names) {
+		panic(fmt.Sprintf("metrics: expected %d label values, got %d", len(l.names), len(values)))
+	}
+	return strings.Join(values, "\xff")
+}
+
+func (l labelSet) format(values []string, extra ...string) string {
+	pairs := make([]string, 0, len(values)+len(extra)/2)
+	for i, name := range l.names {
+		pairs = append(pairs, fmt.Sprintf("%s=%q", name, values[i]))
+	}
+	for i := 0; i+1 < len(extra); i += 2 {
+		pairs = append(pairs, fmt.Sprintf("%s=%q", extra[i], extra[i+1]))
+	}
+	if len(pairs) == 0 {
+		return ""
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+// CounterVec - монотонно растущий счетчик с набором меток.
+type CounterVec struct {
+	name   string
+	help   string
+	labels labelSet
+
+	mu     sync.RWMutex
+	values map[string]*counterValue
+}
+
+type counterValue struct {
+	labels []string
+	bits   atomic.Uint64
+}
+
+func (v *counterValue) add(delta float64) {
+	for {
+		old := v.bits.Load()
+		next := math.Float64bits(math.Float64frombits(old) + delta)
+		if v.bits.CompareAndSwap(old, next) {
+			return
+		}
+	}
+}
+
+func (r *Registry) NewCounterVec(name, help string, labels ...string) *CounterVec {
+	c := &CounterVec{
+		name:   name,
+		help:   help,
+		labels: labelSet{names: labels},
+		values: make(map[string]*counterValue),
+	}
+	return r.register(name, c).(*CounterVec)
+}
+
+func NewCounterVec(name, help string, labels ...string) *CounterVec {
+	return Default.NewCounterVec(name, help, labels...)
+}
+
+func (c *CounterVec) get(values []string) *counterValue {
+	key := c.labels.key(values)
+
+	c.mu.RLock()
+	v, ok := c.values[key]
+	c.mu.RUnlock()
+	if ok {
+		return v
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if v, ok = c.values[key]; ok {
+		return v
+	}
+	v = &counterValue{labels: append([]string(nil), values...)}
+	c.values[key] = v
+	return v
+}
+
+func (c *CounterVec) Inc(values ...string) {
+	c.get(values).add(1)
+}
+
+func (c *CounterVec) Add(delta float64, values ...string) {
+	if delta < 0 {
+		return
+	}
+	c.get(values).add(delta)
+}
+
+// Value возвращает текущее значение счетчика для набора меток.
+func (c *CounterVec) Value(values ...string) float64 {
+	key := c.labels.key(values)
+
+	c.mu.RLock()
+	v, ok := c.values[key]
+	c.mu.RUnlock()
+	if !ok {
+		return 0
+	}
+	return math.Float64frombits(v.bits.Load())
+}
+
+func (c *CounterVec) write(w io.Writer, constLabels []string) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s counter\n", c.name, c.help, c.name)
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	for _, key := range sortedKeys(c.values) {
+		v := c.values[key]
+		fmt.Fprintf(w, "%s%s %g\n", c.name, c.labels.format(v.labels, constLabels...), math.Float64frombits(v.bits.Load()))
+	}
+}
+
+// GaugeVec - значение, которое может как расти, так и уменьшаться.
+type GaugeVec struct {
+	name   string
+	help   string
+	labels labelSet
+
+	mu     sync.RWMutex
+	values map[string]*gaugeValue
+}
+
+type gaugeValue struct {
+	labels []string
+	value  float64
+}
+
+func (r *Registry) NewGaugeVec(name, help string, labels ...string) *GaugeVec {
+	g := &GaugeVec{
+		name:   name,
+		help:   help,
+		labels: labelSet{names: labels},
+		values: make(map[string]*gaugeValue),
+	}
+	return r.register(name, g).(*GaugeVec)
+}
+
+func NewGaugeVec(name, help string, labels ...string) *GaugeVec {
+	return Default.NewGaugeVec(name, help, labels...)
+}
+
+func (g *GaugeVec) Set(value float64, values ...string) {
+	key := g.labels.key(values)
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	v, ok := g.values[key]
+	if !ok {
+		v = &gaugeValue{labels: append([]string(nil), values...)}
+		g.values[key] = v
+	}
+	v.value = value
+}
+
+func (g *GaugeVec) write(w io.Writer, constLabels []string) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s gauge\n", g.name, g.help, g.name)
+
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	for _, key := range sortedKeys(g.values) {
+		v := g.values[key]
+		fmt.Fprintf(w, "%s%s %g\n", g.name, g.labels.format(v.labels, constLabels...), v.value)
+	}
+}
+
+// HistogramVec - распределение наблюдений по корзинам.
+type HistogramVec struct {
+	name    string
+	help    string
+	labels  labelSet
+	buckets []float64
+
+	mu     sync.RWMutex
+	values map[string]*histogramValue
+}
+
+type histogramValue struct {
+	mu     sync.Mutex
+	labels []string
+	counts []uint64
+	count  uint64
+	sum    float64
+}
+
+func (r *Registry) NewHistogramVec(name, help string, buckets []float64, labels ...string) *HistogramVec {
+	if len(buckets) == 0 {
+		buckets = DefaultBuckets
+	}
+	sorted := append([]float64(nil), buckets...)
+	sort.Float64s(sorted)
+
+	h := &HistogramVec{
+		name:    name,
+		help:    help,
+		labels:  labelSet{names: labels},
+		buckets: sorted,
+		values:  make(map[string]*histogramValue),
+	}
+	return r.register(name, h).(*HistogramVec)
+}
+
+func NewHistogramVec(name, help string, buckets []float64, labels ...string) *HistogramVec {
+	return Default.NewHistogramVec(name, help, buckets, labels...)
+}
+
+func (h *HistogramVec) Observe(value float64, values ...string) {
+	key := h.labels.key(values)
+
+	h.mu.RLock()
+	v, ok := h.values[key]
+	h.mu.RUnlock()
+	if !ok {
+		h.mu.Lock()
+		if v, ok = h.values[key]; !ok {
+			v = &histogramValue{
+				labels: append([]string(nil), values...),
+				counts: make([]uint64, len(h.buckets)),
+			}
+			h.values[key] = v
+		}
+		h.mu.Unlock()
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	for i, bound := range h.buckets {
+		if value <= bound {
+			v.counts[i]++
+		}
+	}
+	v.count++
+	v.sum += value
+}
+
+// Snapshot возвращает количество наблюдений и их сумму для набора меток.
+func (h *HistogramVec) Snapshot(values ...string) (count uint64, sum float64) {
+	key := h.labels.key(values)
+
+	h.mu.RLock()
+	v, ok := h.values[key]
+	h.mu.RUnlock()
+	if !ok {
+		return 0, 0
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	return v.count, v.sum
+}
+
+func (h *HistogramVec) write(w io.Writer, constLabels []string) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s histogram\n", h.name, h.help, h.name)
+
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	for _, key := range sortedKeys(h.values) {
+		v := h.values[key]
+		v.mu.Lock()
+		for i, bound := range h.buckets {
+			fmt.Fprintf(w, "%s_bucket%s %d\n", h.name, h.labels.format(v.labels, append([]string{"le", fmt.Sprintf("%g", bound)}, constLabels...)...), v.counts[i])
+		}
+		fmt.Fprintf(w, "%s_bucket%s %d\n", h.name, h.labels.format(v.labels, append([]string{"le", "+Inf"}, constLabels...)...), v.count)
+		fmt.Fprintf(w, "%s_sum%s %g\n", h.name, h.labels.format(v.labels, constLabels...), v.sum)
+		fmt.Fprintf(w, "%s_count%s %d\n", h.name, h.labels.format(v.labels, constLabels...), v.count)
+		v.mu.Unlock()
+	}
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,80 @@
+// Package region - регион развертывания для двунаправленной репликации между регионами.
+// Каждая запись и событие помечаются регионом, который их записал, чтобы реплики
+// могли отличить свои изменения от пришедших из другого региона.
+package region
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+
+	"aggregator_db/pkg/domain"
+)
+
+var current atomic.Value
+
+// Set задает регион процесса; вызывается при старте.
+func Set(name string) {
+	current.Store(name)
+}
+
+// Current возвращает регион процесса; пустая строка - регион не настроен.
+func Current() string {
+	name, _ := current.Load().(string)
+	return name
+}
+
+type originKey struct{}
+
+// WithOrigin помечает записи в контексте регионом name вместо региона процесса:
+// так применяется изменение, пришедшее из другого региона.
+func WithOrigin(ctx context.Context, name string) context.Context {
+	return context.WithValue(ctx, originKey{}, name)
+}
+
+// FromContext возвращает регион, которым помечаются записи в контексте.
+func FromContext(ctx context.Context) string {
+	if name, ok := ctx.Value(originKey{}).(string); ok {
+		return name
+	}
+	return Current()
+}
+
+// Resolver - хук разрешения конфликта: local - подписка в этом регионе, incoming -
+// версия из другого региона. Возвращает версию, которая должна остаться.
+type Resolver func(local, incoming *domain.Subscription) *domain.Subscription
+
+// Политики разрешения конфликтов (REGION_CONFLICT_POLICY).
+const (
+	PolicyLastWriterWins = "last_writer_wins"
+	PolicyLocalWins      = "local_wins"
+)
+
+// LastWriterWins оставляет версию с более поздним updated_at; при равенстве -
+// версию региона, имя которого меньше, чтобы все регионы выбрали одно и то же.
+func LastWriterWins(local, incoming *domain.Subscription) *domain.Subscription {
+	if incoming.UpdatedAt.After(local.UpdatedAt) {
+		return incoming
+	}
+	if incoming.UpdatedAt.Equal(local.UpdatedAt) && incoming.Region < local.Region {
+		return incoming
+	}
+	return local
+}
+
+// LocalWins не применяет входящие изменения существующих подписок.
+func LocalWins(local, _ *domain.Subscription) *domain.Subscription {
+	return local
+}
+
+// ResolverFor возвращает хук для политики REGION_CONFLICT_POLICY.
+func ResolverFor(policy string) (Resolver, error) {
+	switch policy {
+	case PolicyLastWriterWins:
+		return LastWriterWins, nil
+	case PolicyLocalWins:
+		return LocalWins, nil
+	default:
+		return nil, fmt.Errorf("unknown conflict policy %q", policy)
+	}
+}
@@ -0,0 +1,26 @@
+// Package store - интерфейсы хранилищ подписок и пользователей и их ошибки.
+// Реализации (internal/repository/postgres, internal/repository/memory) остаются
+// внутренними, поэтому пакет не тянет за собой pgx: другие сервисы могут
+// принимать эти интерфейсы и подставлять свои реализации.
+package store
+
+import (
+	"context"
+	"errors"
+)
+
+// Transactor выполняет несколько вызовов репозиториев одной транзакцией.
+type Transactor interface {
+	// WithTx вызывает fn в транзакции: вызовы репозиториев с контекстом fn идут
+	// в нее, а собственные транзакции репозиториев становятся точками сохранения.
+	// Ошибка или паника fn откатывает все, вложенный WithTx присоединяется к внешнему.
+	// После временной ошибки базы fn может быть вызвана повторно, поэтому вне базы
+	// она ничего не меняет: события и уведомления отправляются после WithTx.
+	WithTx(ctx context.Context, fn func(ctx context.Context) error) error
+	// Lock держит блокировку key до конца единицы работы ctx: единицы работы с
+	// одним key выполняются по очереди. Вне единицы работы возвращает ErrNoTx.
+	Lock(ctx context.Context, key string) error
+}
+
+// ErrNoTx - операции нужна единица работы, а ее нет в контексте.
+var ErrNoTx = errors.New("no transaction in context")
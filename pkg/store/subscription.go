@@ -0,0 +1,63 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"aggregator_db/pkg/domain"
+	"github.com/google/uuid"
+)
+
+var (
+	ErrNotFound         = errors.New("subscription not found")
+	ErrAlreadyExists    = errors.New("subscription already exists")
+	ErrDiscountNotFound = errors.New("discount not found")
+	// ErrVersionConflict - подписку изменили после того, как ее прочитал клиент или сервис
+	ErrVersionConflict = errors.New("subscription version conflict")
+)
+
+// SubscriptionRepository хранит подписки, их историю статусов, скидки и историю цены.
+type SubscriptionRepository interface {
+	Create(ctx context.Context, sub *domain.Subscription) error
+	CreateBatch(ctx context.Context, subs []*domain.Subscription) error
+	// Upsert записывает подписку целиком, включая статус, создавая ее при отсутствии;
+	// так применяются изменения, пришедшие из другого региона (см. region.WithOrigin).
+	Upsert(ctx context.Context, sub *domain.Subscription) error
+	GetByID(ctx context.Context, id uuid.UUID) (*domain.Subscription, error)
+	// Update записывает подписку, если ее версия все еще sub.Version, и увеличивает
+	// версию; иначе возвращает ErrVersionConflict. Версию увеличивает любое изменение.
+	Update(ctx context.Context, sub *domain.Subscription) error
+	Delete(ctx context.Context, id uuid.UUID) error
+	DeleteByFilter(ctx context.Context, filter domain.DeleteSubscriptionsFilter) (int, error)
+	List(ctx context.Context, query domain.ListSubscriptionsQuery) ([]*domain.Subscription, error)
+	Count(ctx context.Context, query domain.ListSubscriptionsQuery) (int, error)
+	CalculateTotal(ctx context.Context, req domain.CalculateTotalRequest) (domain.Totals, error)
+	// CalculateTotalByUser считает то же, что CalculateTotal, отдельно для каждого
+	// пользователя; пользователей без стоимости в периоде в ответе нет.
+	CalculateTotalByUser(ctx context.Context, req domain.CalculateTotalRequest) (map[uuid.UUID]domain.Totals, error)
+	// ChangeStatus меняет текущий статус и пишет запись в историю статусов.
+	ChangeStatus(ctx context.Context, change *domain.StatusChange) error
+	ListStatusChanges(ctx context.Context, subscriptionIDs []uuid.UUID) ([]*domain.StatusChange, error)
+	// ListRenewable возвращает активные подписки с auto_renew и end_date в диапазоне [from, to].
+	ListRenewable(ctx context.Context, from, to string) ([]*domain.Subscription, error)
+	// Renew переносит end_date с previousEnd на endDate. Если end_date уже изменился
+	// (подписку отредактировали или продлил другой экземпляр), возвращает ErrNotFound.
+	Renew(ctx context.Context, id uuid.UUID, previousEnd, endDate string, renewedAt time.Time) error
+	// Cancel сохраняет end_date и поля отмены подписки и пишет change в историю статусов;
+	// версия проверяется, как в Update.
+	Cancel(ctx context.Context, sub *domain.Subscription, change *domain.StatusChange) error
+	// ListHistory возвращает все подписки под фильтр req, начавшиеся не позже EndPeriod,
+	// включая закончившиеся до StartPeriod: они нужны для классификации месяцев.
+	ListHistory(ctx context.Context, req domain.CalculateTotalRequest) ([]*domain.Subscription, error)
+	// YearOverYear возвращает стоимость каждого месяца года req.Year и предыдущего
+	// с учетом скидок; всегда 12 строк по порядку месяцев.
+	YearOverYear(ctx context.Context, req domain.YearOverYearRequest) ([]domain.YearOverYearUnits, error)
+	CreateDiscount(ctx context.Context, discount *domain.Discount) error
+	ListDiscounts(ctx context.Context, subscriptionIDs []uuid.UUID) ([]*domain.Discount, error)
+	// DeleteDiscount удаляет скидку подписки; чужая или несуществующая скидка - ErrDiscountNotFound.
+	DeleteDiscount(ctx context.Context, subscriptionID, id uuid.UUID) error
+	// ListPriceHistory возвращает историю цены подписки по времени изменения.
+	// Create, CreateBatch и Update пишут в нее цену, если она изменилась.
+	ListPriceHistory(ctx context.Context, subscriptionID uuid.UUID) ([]*domain.PriceChange, error)
+}
@@ -0,0 +1,25 @@
+package store
+
+import (
+	"context"
+	"errors"
+
+	"aggregator_db/pkg/domain"
+	"github.com/google/uuid"
+)
+
+var (
+	ErrUserNotFound      = errors.New("user not found")
+	ErrUserAlreadyExists = errors.New("user already exists")
+	ErrUserEmailTaken    = errors.New("email is already used by another user")
+)
+
+type UserRepository interface {
+	Create(ctx context.Context, user *domain.User) error
+	GetByID(ctx context.Context, id uuid.UUID) (*domain.User, error)
+	List(ctx context.Context, query domain.ListUsersQuery) ([]*domain.User, error)
+	Count(ctx context.Context) (int, error)
+	Update(ctx context.Context, user *domain.User) error
+	// Delete удаляет пользователя вместе с его подписками (ON DELETE CASCADE).
+	Delete(ctx context.Context, id uuid.UUID) error
+}
@@ -0,0 +1,107 @@
+package tracing
+
+import (
+	"context"
+	"log/slog"
+	"slices"
+	"sync"
+)
+
+// Метки запроса, которые middleware заполняют по мере его разбора.
+const (
+	LabelRoute  = "route"
+	LabelTenant = "tenant"
+	LabelAPIKey = "api_key"
+)
+
+// Labels - метки одного запроса (маршрут, тенант, хэш ключа API), общие для
+// всех его спанов, строк лога и метрик. Заполняются middleware после разбора
+// заголовков, поэтому спаны, открытые раньше, получают их при завершении.
+type Labels struct {
+	mu     sync.Mutex
+	keys   []string
+	values []string
+}
+
+// Set задает метку; пустое значение метку не меняет.
+func (l *Labels) Set(key, value string) {
+	if l == nil || value == "" {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if i := slices.Index(l.keys, key); i >= 0 {
+		l.values[i] = value
+		return
+	}
+	l.keys = append(l.keys, key)
+	l.values = append(l.values, value)
+}
+
+// Get возвращает значение метки или пустую строку.
+func (l *Labels) Get(key string) string {
+	if l == nil {
+		return ""
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if i := slices.Index(l.keys, key); i >= 0 {
+		return l.values[i]
+	}
+	return ""
+}
+
+// Attrs возвращает метки как атрибуты в порядке их появления.
+func (l *Labels) Attrs() []slog.Attr {
+	if l == nil {
+		return nil
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	attrs := make([]slog.Attr, len(l.keys))
+	for i, key := range l.keys {
+		attrs[i] = slog.String(key, l.values[i])
+	}
+	return attrs
+}
+
+type labelsKey struct{}
+
+// ContextWithLabels кладет метки запроса в контекст.
+func ContextWithLabels(ctx context.Context, labels *Labels) context.Context {
+	return context.WithValue(ctx, labelsKey{}, labels)
+}
+
+// LabelsFromContext возвращает метки запроса; вне запроса - nil, методы Labels
+// с ним ничего не делают.
+func LabelsFromContext(ctx context.Context) *Labels {
+	labels, _ := ctx.Value(labelsKey{}).(*Labels)
+	return labels
+}
+
+// LabelsHandler добавляет метки запроса из контекста к строкам лога, записанным
+// с контекстом (InfoContext и т.п.).
+type LabelsHandler struct {
+	slog.Handler
+}
+
+// NewLabelsHandler оборачивает next.
+func NewLabelsHandler(next slog.Handler) *LabelsHandler {
+	return &LabelsHandler{Handler: next}
+}
+
+func (h *LabelsHandler) Handle(ctx context.Context, record slog.Record) error {
+	if attrs := LabelsFromContext(ctx).Attrs(); len(attrs) > 0 {
+		record = record.Clone()
+		record.AddAttrs(attrs...)
+	}
+	return h.Handler.Handle(ctx, record)
+}
+
+func (h *LabelsHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &LabelsHandler{Handler: h.Handler.WithAttrs(attrs)}
+}
+
+func (h *LabelsHandler) WithGroup(name string) slog.Handler {
+	return &LabelsHandler{Handler: h.Handler.WithGroup(name)}
+}
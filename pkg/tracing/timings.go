@@ -0,0 +1,50 @@
+package tracing
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Timings суммирует длительность завершенных спанов одного запроса по именам.
+// Спан попадает в Timings, если контекст содержал их при StartSpan.
+type Timings struct {
+	mu        sync.Mutex
+	durations map[string]time.Duration
+	counts    map[string]int
+}
+
+type timingsKey struct{}
+
+func WithTimings(ctx context.Context) (context.Context, *Timings) {
+	t := &Timings{durations: make(map[string]time.Duration), counts: make(map[string]int)}
+	return context.WithValue(ctx, timingsKey{}, t), t
+}
+
+func TimingsFromContext(ctx context.Context) *Timings {
+	t, _ := ctx.Value(timingsKey{}).(*Timings)
+	return t
+}
+
+func (t *Timings) add(name string, d time.Duration) {
+	t.mu.Lock()
+	t.durations[name] += d
+	t.counts[name]++
+	t.mu.Unlock()
+}
+
+// Sum возвращает суммарную длительность и число спанов, для которых match(name) истинно.
+func (t *Timings) Sum(match func(name string) bool) (time.Duration, int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var total time.Duration
+	count := 0
+	for name, d := range t.durations {
+		if match(name) {
+			total += d
+			count += t.counts[name]
+		}
+	}
+	return total, count
+}
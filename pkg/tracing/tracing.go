@@ -0,0 +1,207 @@
+package tracing
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+)
+
+type TraceID [16]byte
+
+func (t TraceID) String() string { return hex.EncodeToString(t[:]) }
+
+func (t TraceID) IsValid() bool { return t != TraceID{} }
+
+type SpanID [8]byte
+
+func (s SpanID) String() string { return hex.EncodeToString(s[:]) }
+
+func (s SpanID) IsValid() bool { return s != SpanID{} }
+
+// Span - единица трассировки. Завершенные спаны передаются экспортеру.
+type Span struct {
+	Name     string
+	TraceID  TraceID
+	SpanID   SpanID
+	ParentID SpanID
+	Start    time.Time
+	Duration time.Duration
+	Err      error
+
+	mu      sync.Mutex
+	attrs   []slog.Attr
+	ended   bool
+	timings *Timings
+	// labels - метки запроса, добавляются к атрибутам при завершении
+	labels *Labels
+}
+
+func (s *Span) SetAttributes(attrs ...slog.Attr) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	s.attrs = append(s.attrs, attrs...)
+	s.mu.Unlock()
+}
+
+func (s *Span) Attributes() []slog.Attr {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]slog.Attr(nil), s.attrs...)
+}
+
+func (s *Span) RecordError(err error) {
+	if s == nil || err == nil {
+		return
+	}
+	s.mu.Lock()
+	s.Err = err
+	s.mu.Unlock()
+}
+
+func (s *Span) End() {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	if s.ended {
+		s.mu.Unlock()
+		return
+	}
+	s.ended = true
+	s.Duration = time.Since(s.Start)
+	s.attrs = append(s.attrs, s.labels.Attrs()...)
+	s.mu.Unlock()
+
+	if s.timings != nil {
+		s.timings.add(s.Name, s.Duration)
+	}
+
+	exporterMu.RLock()
+	export := exporter
+	exporterMu.RUnlock()
+	if export != nil {
+		export(s)
+	}
+}
+
+// Exporter получает каждый завершенный спан.
+type Exporter func(span *Span)
+
+var (
+	exporterMu sync.RWMutex
+	exporter   Exporter
+)
+
+func SetExporter(e Exporter) {
+	exporterMu.Lock()
+	exporter = e
+	exporterMu.Unlock()
+}
+
+// NewLogExporter пишет спаны в лог на уровне debug.
+func NewLogExporter(logger *slog.Logger) Exporter {
+	return func(span *Span) {
+		attrs := []slog.Attr{
+			slog.String("span", span.Name),
+			slog.String("trace_id", span.TraceID.String()),
+			slog.String("span_id", span.SpanID.String()),
+			slog.Duration("duration", span.Duration),
+		}
+		if span.ParentID.IsValid() {
+			attrs = append(attrs, slog.String("parent_id", span.ParentID.String()))
+		}
+		if span.Err != nil {
+			attrs = append(attrs, slog.String("error", span.Err.Error()))
+		}
+		attrs = append(attrs, span.Attributes()...)
+		logger.LogAttrs(context.Background(), slog.LevelDebug, "span finished", attrs...)
+	}
+}
+
+type spanContextKey struct{}
+
+type SpanContext struct {
+	TraceID TraceID
+	SpanID  SpanID
+}
+
+func (sc SpanContext) IsValid() bool {
+	return sc.TraceID.IsValid() && sc.SpanID.IsValid()
+}
+
+func ContextWithSpanContext(ctx context.Context, sc SpanContext) context.Context {
+	return context.WithValue(ctx, spanContextKey{}, sc)
+}
+
+func SpanContextFromContext(ctx context.Context) SpanContext {
+	sc, _ := ctx.Value(spanContextKey{}).(SpanContext)
+	return sc
+}
+
+// StartSpan открывает дочерний спан текущего контекста или новый трейс.
+func StartSpan(ctx context.Context, name string, attrs ...slog.Attr) (context.Context, *Span) {
+	parent := SpanContextFromContext(ctx)
+
+	span := &Span{
+		Name:    name,
+		SpanID:  newSpanID(),
+		Start:   time.Now(),
+		attrs:   attrs,
+		timings: TimingsFromContext(ctx),
+		labels:  LabelsFromContext(ctx),
+	}
+	if parent.IsValid() {
+		span.TraceID = parent.TraceID
+		span.ParentID = parent.SpanID
+	} else {
+		span.TraceID = newTraceID()
+	}
+
+	return ContextWithSpanContext(ctx, SpanContext{TraceID: span.TraceID, SpanID: span.SpanID}), span
+}
+
+const TraceparentHeader = "traceparent"
+
+// FormatTraceparent сериализует контекст в заголовок W3C traceparent.
+func FormatTraceparent(sc SpanContext) string {
+	return fmt.Sprintf("00-%s-%s-01", sc.TraceID, sc.SpanID)
+}
+
+// ParseTraceparent разбирает заголовок W3C traceparent.
+func ParseTraceparent(header string) (SpanContext, bool) {
+	parts := strings.Split(strings.TrimSpace(header), "-")
+	if len(parts) != 4 || len(parts[1]) != 32 || len(parts[2]) != 16 {
+		return SpanContext{}, false
+	}
+
+	var sc SpanContext
+	if _, err := hex.Decode(sc.TraceID[:], []byte(parts[1])); err != nil {
+		return SpanContext{}, false
+	}
+	if _, err := hex.Decode(sc.SpanID[:], []byte(parts[2])); err != nil {
+		return SpanContext{}, false
+	}
+	if !sc.IsValid() {
+		return SpanContext{}, false
+	}
+	return sc, true
+}
+
+func newTraceID() TraceID {
+	var id TraceID
+	_, _ = rand.Read(id[:])
+	return id
+}
+
+func newSpanID() SpanID {
+	var id SpanID
+	_, _ = rand.Read(id[:])
+	return id
+}
@@ -0,0 +1,55 @@
+package webhookclient
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Store запоминает обработанные доставки. Реализация для нескольких экземпляров
+// потребителя должна быть общей (Redis, таблица с уникальным ключом).
+type Store interface {
+	// Claim атомарно помечает событие как обрабатываемое; false - событие уже было
+	Claim(ctx context.Context, eventID string) (bool, error)
+	// Release снимает отметку, если обработка не удалась, чтобы повторная доставка прошла
+	Release(ctx context.Context, eventID string) error
+}
+
+// MemoryStore - Store в памяти процесса. Отметки хранятся ttl: сервис повторяет
+// доставку недолго, поэтому ttl в несколько часов достаточно.
+type MemoryStore struct {
+	mu   sync.Mutex
+	ttl  time.Duration
+	seen map[string]time.Time
+	now  func() time.Time
+}
+
+func NewMemoryStore(ttl time.Duration) *MemoryStore {
+	return &MemoryStore{ttl: ttl, seen: make(map[string]time.Time), now: time.Now}
+}
+
+func (s *MemoryStore) Claim(_ context.Context, eventID string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	for id, at := range s.seen {
+		if now.Sub(at) > s.ttl {
+			delete(s.seen, id)
+		}
+	}
+
+	if _, ok := s.seen[eventID]; ok {
+		return false, nil
+	}
+	s.seen[eventID] = now
+	return true, nil
+}
+
+func (s *MemoryStore) Release(_ context.Context, eventID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.seen, eventID)
+	return nil
+}
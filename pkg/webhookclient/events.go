@@ -0,0 +1,114 @@
+package webhookclient
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Типы событий, которые публикует сервис.
+const (
+	TypeSubscriptionNew        = "subscription.new"
+	TypeSubscriptionRenewal    = "subscription.renewal"
+	TypeSubscriptionUpgraded   = "subscription.upgraded"
+	TypeSubscriptionDowngraded = "subscription.downgraded"
+	TypeSubscriptionRenewed    = "subscription.renewed"
+	TypeSpendWeeklyChange      = "spend.weekly_change"
+	TypeSpendMonthlyChange     = "spend.monthly_change"
+)
+
+var ErrUnknownEventType = errors.New("unknown event type")
+
+// Event - конверт доставки. Data разбирается в типизированную структуру через Decode.
+type Event struct {
+	ID         uuid.UUID `json:"id"`
+	Type       string    `json:"type"`
+	OccurredAt time.Time `json:"occurred_at"`
+	// SchemaVersion - версия схемы данных; новые версии совместимы с прежними
+	SchemaVersion int             `json:"schema_version"`
+	Data          json.RawMessage `json:"data"`
+}
+
+// Money - точная сумма: десятичная строка в валюте Currency (ISO 4217), например "399.99" RUB.
+// Строку стоит разбирать десятичным типом, а не float64.
+type Money struct {
+	Amount   string `json:"amount"`
+	Currency string `json:"currency"`
+}
+
+// SubscriptionClassified - данные событий subscription.new, .renewal, .upgraded и .downgraded:
+// класс первого месяца новой подписки.
+type SubscriptionClassified struct {
+	SubscriptionID uuid.UUID `json:"subscription_id"`
+	UserID         uuid.UUID `json:"user_id"`
+	ServiceName    string    `json:"service_name"`
+	Month          string    `json:"month"`
+	// Price - цена в целых единицах валюты; точная цена - PriceAmount (схема v2)
+	Price       int    `json:"price"`
+	PriceAmount *Money `json:"price_amount,omitempty"`
+	Class       string `json:"class"`
+}
+
+// SubscriptionRenewed - данные события subscription.renewed.
+type SubscriptionRenewed struct {
+	SubscriptionID  uuid.UUID `json:"subscription_id"`
+	UserID          uuid.UUID `json:"user_id"`
+	ServiceName     string    `json:"service_name"`
+	Price           int       `json:"price"`
+	PriceAmount     *Money    `json:"price_amount,omitempty"`
+	PreviousEndDate string    `json:"previous_end_date"`
+	EndDate         string    `json:"end_date"`
+}
+
+// SpendChanged - данные событий spend.weekly_change и spend.monthly_change.
+type SpendChanged struct {
+	UserID        uuid.UUID `json:"user_id"`
+	Period        string    `json:"period"`
+	PreviousStart string    `json:"previous_start"`
+	CurrentStart  string    `json:"current_start"`
+	Previous      int       `json:"previous"`
+	Current       int       `json:"current"`
+	// Currency, PreviousAmount и CurrentAmount появились в схеме v2: траты сравниваются по валютам
+	Currency         string  `json:"currency,omitempty"`
+	PreviousAmount   *Money  `json:"previous_amount,omitempty"`
+	CurrentAmount    *Money  `json:"current_amount,omitempty"`
+	ChangePercent    float64 `json:"change_percent"`
+	ThresholdPercent int     `json:"threshold_percent"`
+}
+
+// Parse разбирает тело доставки.
+func Parse(body []byte) (*Event, error) {
+	var event Event
+	if err := json.Unmarshal(body, &event); err != nil {
+		return nil, fmt.Errorf("parse event: %w", err)
+	}
+	if event.ID == uuid.Nil || event.Type == "" {
+		return nil, errors.New("parse event: id and type are required")
+	}
+	return &event, nil
+}
+
+// Decode возвращает данные события как *SubscriptionClassified, *SubscriptionRenewed
+// или *SpendChanged. Для неизвестного типа возвращается ErrUnknownEventType: потребителю
+// стоит подтверждать такие события, чтобы новые типы не ломали доставку.
+func (e *Event) Decode() (any, error) {
+	var payload any
+	switch e.Type {
+	case TypeSubscriptionNew, TypeSubscriptionRenewal, TypeSubscriptionUpgraded, TypeSubscriptionDowngraded:
+		payload = &SubscriptionClassified{}
+	case TypeSubscriptionRenewed:
+		payload = &SubscriptionRenewed{}
+	case TypeSpendWeeklyChange, TypeSpendMonthlyChange:
+		payload = &SpendChanged{}
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnknownEventType, e.Type)
+	}
+
+	if err := json.Unmarshal(e.Data, payload); err != nil {
+		return nil, fmt.Errorf("decode %s: %w", e.Type, err)
+	}
+	return payload, nil
+}
@@ -0,0 +1,94 @@
+package webhookclient
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"time"
+)
+
+// maxBodyBytes ограничивает размер тела доставки.
+const maxBodyBytes = 1 << 20
+
+// HandlerFunc обрабатывает событие; payload - результат Event.Decode.
+// Ошибка возвращает доставке статус 500, и сервис повторит ее.
+type HandlerFunc func(ctx context.Context, event *Event, payload any) error
+
+// Handler - http.Handler для приема вебхуков: проверяет подпись, отбрасывает
+// повторные доставки и передает событие в OnEvent. Неизвестные типы событий
+// подтверждаются без вызова OnEvent.
+type Handler struct {
+	Secret []byte
+	// Store по умолчанию - MemoryStore на 24 часа
+	Store Store
+	// Tolerance по умолчанию - DefaultTolerance
+	Tolerance time.Duration
+	OnEvent   HandlerFunc
+}
+
+func NewHandler(secret []byte, onEvent HandlerFunc) *Handler {
+	return &Handler{
+		Secret:    secret,
+		Store:     NewMemoryStore(24 * time.Hour),
+		Tolerance: DefaultTolerance,
+		OnEvent:   onEvent,
+	}
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxBodyBytes))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	tolerance := h.Tolerance
+	if tolerance == 0 {
+		tolerance = DefaultTolerance
+	}
+	if err := Verify(h.Secret, r.Header.Get(SignatureHeader), body, tolerance, time.Now()); err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	event, err := Parse(body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	payload, err := event.Decode()
+	if errors.Is(err, ErrUnknownEventType) {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	ctx := r.Context()
+	id := event.ID.String()
+	first, err := h.Store.Claim(ctx, id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if !first {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	if err := h.OnEvent(ctx, event, payload); err != nil {
+		_ = h.Store.Release(ctx, id)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
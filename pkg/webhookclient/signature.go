@@ -0,0 +1,94 @@
+// Package webhookclient - помощник для потребителей вебхуков сервиса подписок:
+// проверка HMAC-подписи, защита от повторной доставки и разбор событий
+// в типизированные структуры.
+package webhookclient
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// SignatureHeader содержит подпись доставки в формате t=<unix>,v1=<hex>
+	SignatureHeader = "X-Webhook-Signature"
+	// IDHeader содержит ID события; повторные доставки приходят с тем же ID
+	IDHeader = "Idempotency-Key"
+	// DefaultTolerance - допустимое расхождение времени подписи и часов потребителя
+	DefaultTolerance = 5 * time.Minute
+)
+
+var (
+	ErrMissingSignature = errors.New("missing webhook signature")
+	ErrInvalidSignature = errors.New("invalid webhook signature")
+	ErrSignatureExpired = errors.New("webhook signature timestamp is outside tolerance")
+)
+
+// Sign подписывает тело доставки: HMAC-SHA256 от "<unix>.<body>".
+// Время входит в подпись, поэтому перехваченную доставку нельзя переиграть позже tolerance.
+func Sign(secret []byte, timestamp time.Time, body []byte) string {
+	unix := strconv.FormatInt(timestamp.Unix(), 10)
+	return "t=" + unix + ",v1=" + hex.EncodeToString(mac(secret, unix, body))
+}
+
+// Verify проверяет заголовок подписи для тела body. Подпись старше или новее
+// now на tolerance отклоняется.
+func Verify(secret []byte, header string, body []byte, tolerance time.Duration, now time.Time) error {
+	if header == "" {
+		return ErrMissingSignature
+	}
+
+	var unix string
+	var signatures [][]byte
+	for _, part := range strings.Split(header, ",") {
+		key, value, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			return ErrInvalidSignature
+		}
+		switch key {
+		case "t":
+			unix = value
+		case "v1":
+			// Во время ротации секрета подписей может быть несколько
+			sig, err := hex.DecodeString(value)
+			if err != nil {
+				return ErrInvalidSignature
+			}
+			signatures = append(signatures, sig)
+		}
+	}
+
+	seconds, err := strconv.ParseInt(unix, 10, 64)
+	if err != nil || len(signatures) == 0 {
+		return ErrInvalidSignature
+	}
+
+	expected := mac(secret, unix, body)
+	valid := false
+	for _, sig := range signatures {
+		if hmac.Equal(sig, expected) {
+			valid = true
+			break
+		}
+	}
+	if !valid {
+		return ErrInvalidSignature
+	}
+
+	if skew := now.Sub(time.Unix(seconds, 0)); skew > tolerance || skew < -tolerance {
+		return ErrSignatureExpired
+	}
+	return nil
+}
+
+func mac(secret []byte, unix string, body []byte) []byte {
+	h := hmac.New(sha256.New, secret)
+	h.Write([]byte(unix))
+	h.Write([]byte("."))
+	h.Write(body)
+	return h.Sum(nil)
+}
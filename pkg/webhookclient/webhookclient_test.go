@@ -0,0 +1,135 @@
+package webhookclient
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"aggregator_db/pkg/domain"
+	"github.com/google/uuid"
+)
+
+var secret = []byte("whsec_test")
+
+func TestVerify(t *testing.T) {
+	now := time.Date(2025, 10, 23, 15, 4, 5, 0, time.UTC)
+	body := []byte(`{"id":"1"}`)
+	header := Sign(secret, now, body)
+	// Во время ротации сервис может прислать подписи старым и новым секретом
+	_, current, _ := strings.Cut(header, ",")
+	rotated := Sign([]byte("whsec_old"), now, body) + "," + current
+
+	cases := []struct {
+		name   string
+		secret []byte
+		header string
+		body   []byte
+		now    time.Time
+		want   error
+	}{
+		{name: "valid", secret: secret, header: header, body: body, now: now.Add(time.Minute)},
+		{name: "rotated secret", secret: secret, header: rotated, body: body, now: now},
+		{name: "missing", secret: secret, body: body, now: now, want: ErrMissingSignature},
+		{name: "wrong secret", secret: []byte("other"), header: header, body: body, now: now, want: ErrInvalidSignature},
+		{name: "tampered body", secret: secret, header: header, body: []byte(`{"id":"2"}`), now: now, want: ErrInvalidSignature},
+		{name: "malformed", secret: secret, header: "v1", body: body, now: now, want: ErrInvalidSignature},
+		{name: "replayed later", secret: secret, header: header, body: body, now: now.Add(10 * time.Minute), want: ErrSignatureExpired},
+	}
+	for _, tc := range cases {
+		if err := Verify(tc.secret, tc.header, tc.body, DefaultTolerance, tc.now); !errors.Is(err, tc.want) {
+			t.Errorf("%s: got %v, want %v", tc.name, err, tc.want)
+		}
+	}
+}
+
+func TestHandler(t *testing.T) {
+	calls := 0
+	fail := true
+	handler := NewHandler(secret, func(_ context.Context, event *Event, payload any) error {
+		calls++
+		if _, ok := payload.(*SubscriptionRenewed); !ok {
+			t.Errorf("payload of %s is %T", event.Type, payload)
+		}
+		if fail {
+			fail = false
+			return errors.New("temporary failure")
+		}
+		return nil
+	})
+
+	deliver := func(event map[string]any) int {
+		body, _ := json.Marshal(event)
+		req := httptest.NewRequest(http.MethodPost, "/webhooks", bytes.NewReader(body))
+		req.Header.Set(SignatureHeader, Sign(secret, time.Now(), body))
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	renewed := map[string]any{"id": uuid.NewString(), "type": TypeSubscriptionRenewed, "occurred_at": time.Now(), "data": map[string]any{"end_date": "10-2025"}}
+	if code := deliver(renewed); code != http.StatusInternalServerError {
+		t.Errorf("failed handler: status %d, want 500", code)
+	}
+	if code := deliver(renewed); code != http.StatusNoContent {
+		t.Errorf("redelivery after failure: status %d, want 204", code)
+	}
+	if code := deliver(renewed); code != http.StatusNoContent || calls != 2 {
+		t.Errorf("duplicate delivery: status %d, handler calls %d, want 204 and 2", code, calls)
+	}
+
+	unknown := map[string]any{"id": uuid.NewString(), "type": "subscription.archived", "data": map[string]any{}}
+	if code := deliver(unknown); code != http.StatusNoContent || calls != 2 {
+		t.Errorf("unknown type: status %d, handler calls %d", code, calls)
+	}
+}
+
+// Типы пакета повторяют данные событий из internal/domain: любое расхождение
+// полей между публикатором и потребителем ломает этот тест.
+func TestPayloadsMatchPublisher(t *testing.T) {
+	id := uuid.New()
+	price := domain.NewMoney(89999, domain.DefaultCurrency)
+	cases := []struct {
+		eventType string
+		data      any
+	}{
+		{TypeSubscriptionUpgraded, domain.BilledMonth{SubscriptionID: id, UserID: id, ServiceName: "Netflix", Month: "07-2025", Price: 900, PriceAmount: price, Class: domain.BillingUpgraded}},
+		{TypeSubscriptionRenewed, domain.SubscriptionRenewal{SubscriptionID: id, UserID: id, ServiceName: "Netflix", Price: 900, PriceAmount: price, PreviousEndDate: "09-2025", EndDate: "10-2025"}},
+		{TypeSpendMonthlyChange, domain.SpendChange{UserID: id, Period: domain.SpendMonth, PreviousStart: "2025-06-01", CurrentStart: "2025-07-01", Previous: 400, Current: 1300,
+			Currency: domain.DefaultCurrency, PreviousAmount: domain.NewMoney(40000, domain.DefaultCurrency), CurrentAmount: domain.NewMoney(129999, domain.DefaultCurrency), ChangePercent: 225, ThresholdPercent: 20}},
+	}
+
+	for _, tc := range cases {
+		published, err := json.Marshal(tc.data)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		event := &Event{ID: id, Type: tc.eventType, Data: published}
+		payload, err := event.Decode()
+		if err != nil {
+			t.Fatalf("%s: %v", tc.eventType, err)
+		}
+
+		// Поля, которых нет у потребителя, потерялись бы при разборе
+		dec := json.NewDecoder(bytes.NewReader(published))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(reflect.New(reflect.TypeOf(payload).Elem()).Interface()); err != nil {
+			t.Errorf("%s: publisher sends fields the client does not know: %v", tc.eventType, err)
+		}
+
+		var want, got map[string]any
+		consumed, _ := json.Marshal(payload)
+		_ = json.Unmarshal(published, &want)
+		_ = json.Unmarshal(consumed, &got)
+		if !reflect.DeepEqual(want, got) {
+			t.Errorf("%s: client round-trip differs\npublished: %s\nconsumed:  %s", tc.eventType, published, consumed)
+		}
+	}
+}